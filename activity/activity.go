@@ -20,7 +20,7 @@ type ActivityStats struct {
 
 var ccs *ActivityStats
 
-func StartActivity(server string, cfg *conf.ActivityConfig, dbCfg *conf.DBConfig) {
+func StartActivity(ctx context.Context, server string, cfg *conf.ActivityConfig, dbCfg *conf.DBConfig) {
 	if server != basedef.SERVER_POLY_BRIDGE {
 		panic("StartStartActivity Only runs on bridge server")
 	}
@@ -28,7 +28,7 @@ func StartActivity(server string, cfg *conf.ActivityConfig, dbCfg *conf.DBConfig
 		panic("Invalid Activity config")
 	}
 	dao := bridgedao.NewBridgeDao(dbCfg, false)
-	ctx, cancel := context.WithCancel(context.Background())
+	ctx, cancel := context.WithCancel(ctx)
 	ccs = &ActivityStats{dao: dao, cfg: cfg, Context: ctx, cancel: cancel}
 	ccs.Start()
 }
@@ -40,8 +40,8 @@ func StopActivity() {
 }
 
 func (this *ActivityStats) run(interval int64, f func() error) {
-	this.wg.Add(1)
 	ticker := time.NewTicker(time.Second * time.Duration(interval))
+	defer ticker.Stop()
 	for {
 		select {
 		case <-ticker.C:
@@ -50,10 +50,21 @@ func (this *ActivityStats) run(interval int64, f func() error) {
 				logs.Error("stats run error%s", err)
 			}
 		case <-this.Done():
-			break
+			return
 		}
 	}
-	this.wg.Done()
+}
+
+// sleep waits for d, it returns false if the activity is stopped before
+func (this *ActivityStats) sleep(d time.Duration) bool {
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+	select {
+	case <-timer.C:
+		return true
+	case <-this.Done():
+		return false
+	}
 }
 
 func (this *ActivityStats) Start() {
@@ -64,6 +75,7 @@ func (this *ActivityStats) Start() {
 		logs.Error("ActivityConfig config err")
 		return
 	}
+	this.wg.Add(2)
 	go this.StartAirDrop()
 	go this.StartTokenPrice()
 	logs.Info("ActivityStats ...")
@@ -8,76 +8,80 @@ import (
 	"math/big"
 	"poly-bridge/basedef"
 	"poly-bridge/models"
+	"poly-bridge/utils/cursor"
 	"strconv"
 	"time"
 )
 
 func (this *ActivityStats) StartAirDrop() {
+	defer this.wg.Done()
 	timeNow := time.Now().Unix()
 	if timeNow < this.cfg.AirDropStartTime {
-		time.Sleep(time.Second * time.Duration(this.cfg.AirDropStartTime-timeNow))
+		if !this.sleep(time.Second * time.Duration(this.cfg.AirDropStartTime-timeNow)) {
+			return
+		}
 	}
 	if timeNow > this.cfg.AirDropEndTime {
 		logs.Info("AirDrop arrive endTime!!!!")
 		return
 	}
 	logs.Info("start AirDropInfoStats")
-	go this.run(this.cfg.AirDropInfoInterval, this.AirDropInfoStats)
+	this.run(this.cfg.AirDropInfoInterval, this.AirDropInfoStats)
 }
 
 func (this *ActivityStats) StartTokenPrice() {
+	defer this.wg.Done()
 	timeNow := time.Now().Unix()
 	if timeNow < this.cfg.TokenPriceStartTime {
-		time.Sleep(time.Second * time.Duration(this.cfg.TokenPriceStartTime-timeNow))
+		if !this.sleep(time.Second * time.Duration(this.cfg.TokenPriceStartTime-timeNow)) {
+			return
+		}
 	}
 	if timeNow > this.cfg.TokenPriceEndTime {
 		logs.Info("TokenPrice arrive endTime!!!!")
 		return
 	}
 	logs.Info("start TokenPriceAvgStats")
-	go this.run(this.cfg.TokenPriceAvgInterval, this.TokenPriceAvgStats)
+	this.run(this.cfg.TokenPriceAvgInterval, this.TokenPriceAvgStats)
 }
 
+// AirDropInfoStats adds the src transactions of the airdrop chains to the airdrop info in (time, id) order,
+// the rows within the listening lag are left to the next run so that a late saved one is not skipped
 func (this *ActivityStats) AirDropInfoStats() (err error) {
 	timeNow := time.Now().Unix()
 	if timeNow > this.cfg.AirDropEndTime+this.cfg.AirDropInfoInterval {
 		return fmt.Errorf("END AirDropEndTime")
 	}
-	count, err := this.dao.GetAirDropInfoCount()
+	it, err := this.dao.NewIterator("activity_airdrop", 10)
 	if err != nil {
-		logs.Error("AirDropInfoStats GetAirDropInfoCount err:", err)
 		return err
 	}
-	if count == 0 {
-		err = this.initialization()
+	minId := int64(0)
+	if !it.Saved() {
+		// the airdrop info was counted by id before the cursor, go on after the last src tx counted
+		maxSrcTxId, err := this.dao.GetMaxSrcIdInAirDrop()
 		if err != nil {
-			return
+			return err
+		}
+		it.Seek(cursor.Position{Time: uint64(this.cfg.AirDropStartTime)})
+		if maxSrcTxId > 0 {
+			maxSrcTx, err := this.dao.GetSrcTxWithId(maxSrcTxId)
+			if err != nil {
+				return fmt.Errorf("GetSrcTxWithId err: %v", err)
+			}
+			it.Seek(cursor.Position{Time: maxSrcTx.Time, Id: maxSrcTx.Id})
+			minId = maxSrcTxId
 		}
 	}
-	lastSrcTx, err := this.dao.GetLastSrcTx()
-	if err != nil || lastSrcTx == nil {
-		return fmt.Errorf("GetLastSrcTx err: %v", err)
-	}
-	maxSrcTxId, err := this.dao.GetMaxSrcIdInAirDrop()
-	if err != nil {
-		return err
-	}
-	gapId := lastSrcTx.Id - maxSrcTxId
-	if gapId <= 0 {
-		return
-	}
-	txCount, err := this.dao.GetSrcCountWithIdAndChains(maxSrcTxId, lastSrcTx.Id, this.GetAirDropChain())
-	if err != nil {
-		return err
-	}
-	if txCount == 0 {
-		return
-	}
-	for i := int64(0); i <= txCount-1/10; i++ {
-		srcTxs, err := this.dao.GetSrcTxsWithIdAndChains(maxSrcTxId, lastSrcTx.Id, this.GetAirDropChain(), 10, int(i*10))
+	it.Settle(uint64(timeNow - cursor.Lag))
+	for {
+		srcTxs, err := this.dao.GetSrcTxsWithChains(it, minId, this.GetAirDropChain())
 		if err != nil {
 			return err
 		}
+		if len(srcTxs) == 0 {
+			break
+		}
 		txHashes := make([]string, 0)
 		for _, srcTx := range srcTxs {
 			txHashes = append(txHashes, srcTx.Hash)
@@ -133,24 +137,14 @@ func (this *ActivityStats) AirDropInfoStats() (err error) {
 				return fmt.Errorf("GetAirDropByUser err:", err)
 			}
 		}
+		err = it.Save()
+		if err != nil {
+			return err
+		}
 	}
 	return nil
 }
 
-func (this *ActivityStats) initialization() error {
-	srcTxs, err := this.dao.GetSrcTxsWithTimeAndChains(this.cfg.AirDropStartTime, this.GetAirDropChain(), 1)
-	if err != nil {
-		return err
-	}
-	if len(srcTxs) == 0 || srcTxs[0] == nil {
-		logs.Info("AirDropInfoStats first GetSrcTxsWithTimeAndChains len(srcTxs) is zero")
-		return fmt.Errorf("AirDropInfoStats first GetSrcTxsWithTimeAndChains len(srcTxs) is zero")
-	}
-	airDropInfo := this.fillAirDropInfo(srcTxs[0])
-	err = this.dao.SaveAirDrop(airDropInfo)
-	return err
-}
-
 func (this *ActivityStats) fillAirDropInfo(srcTx *models.SrcTransaction) *models.AirDropInfo {
 	airDropInfo := &models.AirDropInfo{
 		User:        srcTx.User,
@@ -247,7 +241,7 @@ func (this *ActivityStats) TokenPriceAvgStats() (err error) {
 
 func (this *ActivityStats) GetAirDropChain() []uint64 {
 	chain := make([]uint64, 0)
-	for _, v := range basedef.ETH_CHAINS {
+	for _, v := range basedef.EthChains() {
 		chain = append(chain, v)
 	}
 	chain = append(chain, basedef.STARCOIN_CROSSCHAIN_ID, basedef.ONT_CROSSCHAIN_ID, basedef.NEO_CROSSCHAIN_ID, basedef.NEO3_CROSSCHAIN_ID)
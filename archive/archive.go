@@ -0,0 +1,305 @@
+/*
+ * Copyright (C) 2020 The poly network Authors
+ * This file is part of The poly network library.
+ *
+ * The  poly network  is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU Lesser General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * The  poly network  is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU Lesser General Public License for more details.
+ * You should have received a copy of the GNU Lesser General Public License
+ * along with The poly network .  If not, see <http://www.gnu.org/licenses/>.
+ */
+
+package archive
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"poly-bridge/basedef"
+	"poly-bridge/conf"
+	"poly-bridge/models"
+	"poly-bridge/utils/database"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/beego/beego/v2/core/logs"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+const (
+	defaultAge      = 180 * 24 * 60 * 60
+	defaultInterval = 60 * 60
+	defaultBatch    = 100
+)
+
+const (
+	srcLeg = iota
+	polyLeg
+	dstLeg
+)
+
+// table is moved to its archived_ table with the cross chain txs, its rows are matched by the key column
+// with the hashes of a leg
+type table struct {
+	model interface{}
+	key   string
+	leg   int
+}
+
+// tables are ordered as the foreign keys, the rows are copied in order and removed in the reverse order
+var tables = []*table{
+	{model: &models.WrapperTransaction{}, key: "hash", leg: srcLeg},
+	{model: &models.SrcTransaction{}, key: "hash", leg: srcLeg},
+	{model: &models.SrcTransfer{}, key: "tx_hash", leg: srcLeg},
+	{model: &models.SrcSwap{}, key: "tx_hash", leg: srcLeg},
+	{model: &models.PolyTransaction{}, key: "hash", leg: polyLeg},
+	{model: &models.DstTransaction{}, key: "hash", leg: dstLeg},
+	{model: &models.DstTransfer{}, key: "tx_hash", leg: dstLeg},
+	{model: &models.DstSwap{}, key: "tx_hash", leg: dstLeg},
+	{model: &models.CrossChainTx{}, key: "src_hash", leg: srcLeg},
+}
+
+// Models are the models having archived_ tables
+func Models() []interface{} {
+	values := make([]interface{}, 0, len(tables))
+	for _, table := range tables {
+		values = append(values, table.model)
+	}
+	return values
+}
+
+// Archiver moves the finished cross chain txs older than the configured age, with all their legs, from the
+// main tables to the archived_ tables, so that the hot tables keep the recent txs only. The lookups by hash fall
+// back to the archive with transactions.FindCrossChainTx.
+type Archiver struct {
+	context.Context
+	cancel   context.CancelFunc
+	cfg      *conf.ArchiveConfig
+	db       *gorm.DB
+	exporter *Exporter
+	wg       sync.WaitGroup
+}
+
+var archiver *Archiver
+
+func StartArchive(ctx context.Context, config *conf.Config) {
+	if config.Server != basedef.SERVER_POLY_BRIDGE {
+		panic("Archive Only runs on bridge server")
+	}
+	if config.ArchiveConfig == nil {
+		panic("Invalid Archive config")
+	}
+	db, err := database.Open(config.DBConfig)
+	if err != nil {
+		panic(err)
+	}
+	archiver, err = NewArchiver(ctx, config.ArchiveConfig, db)
+	if err != nil {
+		panic(err)
+	}
+	archiver.Start()
+}
+
+func StopArchive() {
+	if archiver != nil {
+		archiver.Stop()
+	}
+}
+
+func NewArchiver(ctx context.Context, cfg *conf.ArchiveConfig, db *gorm.DB) (*Archiver, error) {
+	var exporter *Exporter
+	if cfg.Dir != "" {
+		var err error
+		exporter, err = NewExporter(cfg.Dir)
+		if err != nil {
+			return nil, err
+		}
+	}
+	ctx, cancel := context.WithCancel(ctx)
+	return &Archiver{
+		Context:  ctx,
+		cancel:   cancel,
+		cfg:      cfg,
+		db:       db,
+		exporter: exporter,
+	}, nil
+}
+
+func (this *Archiver) Start() {
+	logs.Info("start archive")
+	this.wg.Add(1)
+	go this.run()
+}
+
+func (this *Archiver) Stop() {
+	logs.Info("Stopping archive")
+	this.cancel()
+	this.wg.Wait()
+}
+
+func (this *Archiver) run() {
+	defer this.wg.Done()
+	interval := this.cfg.Interval
+	if interval <= 0 {
+		interval = defaultInterval
+	}
+	ticker := time.NewTicker(time.Second * time.Duration(interval))
+	defer ticker.Stop()
+	for {
+		for this.Err() == nil {
+			n, err := this.Archive(time.Now().Unix())
+			if err != nil {
+				logs.Error("archive cross chain txs err: %v", err)
+				break
+			}
+			if n == 0 {
+				break
+			}
+			logs.Info("archived %d cross chain txs", n)
+		}
+		select {
+		case <-ticker.C:
+		case <-this.Done():
+			return
+		}
+	}
+}
+
+// Archive moves one batch of the txs finished before now minus the age, it returns the number of txs moved
+func (this *Archiver) Archive(now int64) (int, error) {
+	age := this.cfg.Age
+	if age <= 0 {
+		age = defaultAge
+	}
+	batch := this.cfg.Batch
+	if batch <= 0 {
+		batch = defaultBatch
+	}
+	counted, err := this.countedIds()
+	if err != nil {
+		return 0, err
+	}
+	crossChainTxs := make([]*models.CrossChainTx, 0)
+	err = this.db.Where("time < ? and poly_hash != '' and dst_hash != ''", now-age).
+		Where("src_hash in (?)", this.db.Model(&models.SrcTransaction{}).Select("hash").Where("id <= ?", counted.src)).
+		Where("poly_hash in (?)", this.db.Model(&models.PolyTransaction{}).Select("hash").Where("id <= ?", counted.poly)).
+		Where("dst_hash in (?)", this.db.Model(&models.DstTransaction{}).Select("hash").Where("id <= ?", counted.dst)).
+		Where("src_hash not in (?)", this.db.Model(&models.SrcTransfer{}).Select("tx_hash").Where("id > ?", counted.srcTransfer)).
+		Where("dst_hash not in (?)", this.db.Model(&models.DstTransfer{}).Select("tx_hash").Where("id > ?", counted.dstTransfer)).
+		Order("time asc").Order("src_hash asc").Limit(batch).Find(&crossChainTxs).Error
+	if err != nil || len(crossChainTxs) == 0 {
+		return 0, err
+	}
+	err = this.db.Transaction(func(tx *gorm.DB) error {
+		if err := copyCrossChainTxs(tx, crossChainTxs); err != nil {
+			return err
+		}
+		if this.exporter != nil {
+			if err := this.exporter.Export(tx, crossChainTxs); err != nil {
+				return err
+			}
+		}
+		return removeCrossChainTxs(tx, crossChainTxs)
+	})
+	if err != nil {
+		return 0, err
+	}
+	return len(crossChainTxs), nil
+}
+
+// counted are the ids up to which the rows are counted by the incremental statistics of crosschainstats
+type counted struct {
+	src         int64
+	poly        int64
+	dst         int64
+	srcTransfer int64
+	dstTransfer int64
+}
+
+// countedIds finds the smallest id checkpoints of the statistics, the rows after them are not archived until they are
+// counted. The chain statistics not computed yet hold all the rows, the tables of the token statistics may be empty.
+func (this *Archiver) countedIds() (*counted, error) {
+	ids := &counted{src: math.MaxInt64, poly: math.MaxInt64, dst: math.MaxInt64, srcTransfer: math.MaxInt64, dstTransfer: math.MaxInt64}
+	for _, item := range []struct {
+		id     *int64
+		column string
+		table  string
+		empty  int64
+		where  string
+		args   []interface{}
+	}{
+		{&ids.src, "last_out_check_id", "chain_statistics", 0, "chain_id != ?", []interface{}{basedef.POLY_CROSSCHAIN_ID}},
+		{&ids.dst, "last_in_check_id", "chain_statistics", 0, "chain_id != ?", []interface{}{basedef.POLY_CROSSCHAIN_ID}},
+		{&ids.poly, "last_in_check_id", "chain_statistics", 0, "chain_id = ?", []interface{}{basedef.POLY_CROSSCHAIN_ID}},
+		{&ids.srcTransfer, "last_out_check_id", "token_statistics", math.MaxInt64, "", nil},
+		{&ids.dstTransfer, "last_in_check_id", "token_statistics", math.MaxInt64, "", nil},
+		{&ids.srcTransfer, "last_check_id", "asset_statistics", math.MaxInt64, "", nil},
+		{&ids.srcTransfer, "stats_update_time", "token_basics", math.MaxInt64, "name in (select token_basic_name from tokens)", nil},
+	} {
+		query := this.db.Table(item.table).Select("coalesce(min(?), ?)", clause.Column{Name: item.column}, item.empty)
+		if item.where != "" {
+			query = query.Where(item.where, item.args...)
+		}
+		var id int64
+		if err := query.Scan(&id).Error; err != nil {
+			return nil, fmt.Errorf("find the checkpoint of %s err: %w", item.table, err)
+		}
+		if id < *item.id {
+			*item.id = id
+		}
+	}
+	return ids, nil
+}
+
+func legHashes(crossChainTxs []*models.CrossChainTx) [][]string {
+	hashes := make([][]string, dstLeg+1)
+	for _, crossChainTx := range crossChainTxs {
+		hashes[srcLeg] = append(hashes[srcLeg], crossChainTx.SrcHash)
+		hashes[polyLeg] = append(hashes[polyLeg], crossChainTx.PolyHash)
+		hashes[dstLeg] = append(hashes[dstLeg], crossChainTx.DstHash)
+	}
+	return hashes
+}
+
+// copyCrossChainTxs copies the rows by the column names, the columns added by the migrations are not in the
+// same order in the main tables
+func copyCrossChainTxs(tx *gorm.DB, crossChainTxs []*models.CrossChainTx) error {
+	hashes := legHashes(crossChainTxs)
+	for _, table := range tables {
+		stmt := &gorm.Statement{DB: tx}
+		if err := stmt.Parse(table.model); err != nil {
+			return err
+		}
+		columns := make([]string, 0, len(stmt.Schema.DBNames))
+		for _, name := range stmt.Schema.DBNames {
+			columns = append(columns, stmt.Quote(clause.Column{Name: name}))
+		}
+		sql := fmt.Sprintf("INSERT INTO %s (%s) SELECT %s FROM %s WHERE %s IN ?",
+			stmt.Quote(clause.Table{Name: database.ArchivePrefix + stmt.Schema.Table}), strings.Join(columns, ","),
+			strings.Join(columns, ","), stmt.Quote(clause.Table{Name: stmt.Schema.Table}), stmt.Quote(clause.Column{Name: table.key}))
+		if err := tx.Exec(sql, hashes[table.leg]).Error; err != nil {
+			return fmt.Errorf("archive %s err: %w", stmt.Schema.Table, err)
+		}
+	}
+	return nil
+}
+
+func removeCrossChainTxs(tx *gorm.DB, crossChainTxs []*models.CrossChainTx) error {
+	hashes := legHashes(crossChainTxs)
+	for i := len(tables) - 1; i >= 0; i-- {
+		table := tables[i]
+		err := tx.Where("? in ?", clause.Column{Name: table.key}, hashes[table.leg]).Delete(table.model).Error
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
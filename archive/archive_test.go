@@ -0,0 +1,170 @@
+package archive
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"poly-bridge/basedef"
+	"poly-bridge/conf"
+	"poly-bridge/models"
+	"poly-bridge/utils/database"
+	"poly-bridge/utils/dbtest"
+	"poly-bridge/utils/transactions"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"gorm.io/gorm"
+)
+
+// openArchiveDB opens the test db with the archived_ tables
+func openArchiveDB(t *testing.T) *gorm.DB {
+	db := dbtest.Open(t, append(Models(), &models.TokenBasic{}, &models.Token{}, &models.ChainStatistic{}, &models.TokenStatistic{},
+		&models.AssetStatistic{})...)
+	archived, err := database.Archive(db)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := archived.AutoMigrate(Models()...); err != nil {
+		t.Fatal(err)
+	}
+	return db
+}
+
+func createCrossChainTx(t *testing.T, db *gorm.DB, hash string, tt uint64, finished bool) {
+	crossChainTx := &models.CrossChainTx{SrcHash: "src" + hash, SrcChainId: basedef.ETHEREUM_CROSSCHAIN_ID, WrapperId: 1, Time: tt}
+	rows := []interface{}{
+		&models.WrapperTransaction{Hash: "src" + hash, Time: tt, FeeAmount: models.NewBigIntFromInt(1), PaidGas: models.NewBigIntFromInt(0)},
+		&models.SrcTransaction{Hash: "src" + hash, Key: "key" + hash, Time: tt, Fee: models.NewBigIntFromInt(0),
+			SrcTransfer: &models.SrcTransfer{TxHash: "src" + hash, Time: tt, Amount: models.NewBigIntFromInt(100)}},
+	}
+	if finished {
+		crossChainTx.PolyHash, crossChainTx.DstHash = "poly"+hash, "dst"+hash
+		rows = append(rows,
+			&models.PolyTransaction{Hash: "poly" + hash, SrcHash: "src" + hash, Time: tt, Fee: models.NewBigIntFromInt(0)},
+			&models.DstTransaction{Hash: "dst" + hash, PolyHash: "poly" + hash, Time: tt, Fee: models.NewBigIntFromInt(0),
+				DstTransfer: &models.DstTransfer{TxHash: "dst" + hash, Time: tt, Amount: models.NewBigIntFromInt(100)}},
+		)
+	}
+	rows = append(rows, crossChainTx)
+	for _, row := range rows {
+		if err := db.Create(row).Error; err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+func count(t *testing.T, db *gorm.DB, model interface{}) int64 {
+	var counter int64
+	if err := db.Model(model).Count(&counter).Error; err != nil {
+		t.Fatal(err)
+	}
+	return counter
+}
+
+// countStatistics saves the checkpoints of the chain statistics at id and of the token statistics at transferId
+func countStatistics(t *testing.T, db *gorm.DB, id, transferId int64) {
+	rows := []interface{}{
+		&models.ChainStatistic{Id: 1, ChainId: basedef.ETHEREUM_CROSSCHAIN_ID, LastInCheckId: id, LastOutCheckId: id},
+		&models.ChainStatistic{Id: 2, ChainId: basedef.POLY_CROSSCHAIN_ID, LastInCheckId: id, LastOutCheckId: id},
+		&models.TokenStatistic{Id: 1, Hash: "token", ChainId: basedef.ETHEREUM_CROSSCHAIN_ID, LastInCheckId: transferId, LastOutCheckId: transferId},
+	}
+	for _, row := range rows {
+		if err := db.Save(row).Error; err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+func TestArchive(t *testing.T) {
+	db := openArchiveDB(t)
+	createCrossChainTx(t, db, "01", 1000, true)
+	createCrossChainTx(t, db, "02", 1000, false)
+	createCrossChainTx(t, db, "03", 5000, true)
+
+	dir := t.TempDir()
+	archiver, err := NewArchiver(context.Background(), &conf.ArchiveConfig{Age: 2000, Batch: 10, Dir: dir}, db)
+	assert.NoError(t, err)
+	// the txs not counted by the statistics yet are kept
+	n, err := archiver.Archive(4000)
+	assert.NoError(t, err)
+	assert.Equal(t, 0, n)
+	countStatistics(t, db, 0, 100)
+	n, err = archiver.Archive(4000)
+	assert.NoError(t, err)
+	assert.Equal(t, 0, n)
+	countStatistics(t, db, 100, 0)
+	n, err = archiver.Archive(4000)
+	assert.NoError(t, err)
+	assert.Equal(t, 0, n)
+	countStatistics(t, db, 100, 100)
+	n, err = archiver.Archive(4000)
+	assert.NoError(t, err)
+	assert.Equal(t, 1, n)
+	n, err = archiver.Archive(4000)
+	assert.NoError(t, err)
+	assert.Equal(t, 0, n)
+
+	// the unfinished and the recent txs are kept
+	archived, err := database.Archive(db)
+	assert.NoError(t, err)
+	for _, model := range Models() {
+		switch model.(type) {
+		case *models.SrcSwap, *models.DstSwap:
+			continue
+		case *models.WrapperTransaction, *models.SrcTransaction, *models.SrcTransfer, *models.CrossChainTx:
+			assert.Equal(t, int64(2), count(t, db, model))
+		default:
+			assert.Equal(t, int64(1), count(t, db, model))
+		}
+		assert.Equal(t, int64(1), count(t, archived, model))
+	}
+
+	// the lookups fall back to the archive
+	crossChainTx, legs, err := transactions.FindCrossChainTx(db, "dst_hash = ?", "dst01")
+	assert.NoError(t, err)
+	assert.Equal(t, "src01", crossChainTx.SrcHash)
+	relation, err := transactions.GetCrossChainTxRelationIn(db, legs, crossChainTx)
+	assert.NoError(t, err)
+	assert.Equal(t, "src01", relation.WrapperTransaction.Hash)
+	assert.Equal(t, "src01", relation.SrcTransaction.SrcTransfer.TxHash)
+	assert.Equal(t, "poly01", relation.PolyTransaction.Hash)
+	assert.Equal(t, "dst01", relation.DstTransaction.DstTransfer.TxHash)
+	crossChainTx, _, err = transactions.FindCrossChainTx(db, "src_hash = ?", "src04")
+	assert.NoError(t, err)
+	assert.Nil(t, crossChainTx)
+	crossChainTx, _, err = transactions.FindCrossChainTxByDstHash(db, "dst01", "standard = ?", 0)
+	assert.NoError(t, err)
+	assert.Equal(t, "src01", crossChainTx.SrcHash)
+
+	// a dst leg not linked in cross_chain_txs yet is found by its poly hash
+	rows := []interface{}{
+		&models.PolyTransaction{Hash: "poly02", SrcHash: "src02", Time: 1000, Fee: models.NewBigIntFromInt(0)},
+		&models.DstTransaction{Hash: "dst02", PolyHash: "poly02", Time: 1000, Fee: models.NewBigIntFromInt(0)},
+	}
+	for _, row := range rows {
+		assert.NoError(t, db.Create(row).Error)
+	}
+	assert.NoError(t, db.Model(&models.CrossChainTx{}).Where("src_hash = ?", "src02").Update("poly_hash", "poly02").Error)
+	crossChainTx, _, err = transactions.FindCrossChainTxByDstHash(db, "dst02", "")
+	assert.NoError(t, err)
+	assert.Equal(t, "src02", crossChainTx.SrcHash)
+	crossChainTx, _, err = transactions.FindCrossChainTxByDstHash(db, "dst05", "")
+	assert.NoError(t, err)
+	assert.Nil(t, crossChainTx)
+
+	file, err := os.Open(filepath.Join(dir, "cross_chain_txs_1970-01.jsonl"))
+	assert.NoError(t, err)
+	defer file.Close()
+	scanner := bufio.NewScanner(file)
+	archivedTxs := make([]*ArchivedTx, 0)
+	for scanner.Scan() {
+		archivedTx := new(ArchivedTx)
+		assert.NoError(t, json.Unmarshal(scanner.Bytes(), archivedTx))
+		archivedTxs = append(archivedTxs, archivedTx)
+	}
+	assert.Len(t, archivedTxs, 1)
+	assert.Equal(t, "dst01", archivedTxs[0].DstTransaction.DstTransfer.TxHash)
+	assert.Equal(t, "100", archivedTxs[0].SrcTransaction.SrcTransfer.Amount.String())
+}
@@ -0,0 +1,145 @@
+/*
+ * Copyright (C) 2020 The poly network Authors
+ * This file is part of The poly network library.
+ *
+ * The  poly network  is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU Lesser General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * The  poly network  is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU Lesser General Public License for more details.
+ * You should have received a copy of the GNU Lesser General Public License
+ * along with The poly network .  If not, see <http://www.gnu.org/licenses/>.
+ */
+
+package archive
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"poly-bridge/models"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// ArchivedTx is a line of the export files, the cross chain tx with all its legs
+type ArchivedTx struct {
+	CrossChainTx       *models.CrossChainTx
+	WrapperTransaction *models.WrapperTransaction `json:",omitempty"`
+	SrcTransaction     *models.SrcTransaction     `json:",omitempty"`
+	PolyTransaction    *models.PolyTransaction    `json:",omitempty"`
+	DstTransaction     *models.DstTransaction     `json:",omitempty"`
+}
+
+// Exporter appends the archived txs to monthly jsonl files by their time, <dir>/cross_chain_txs_<yyyy-mm>.jsonl.
+// A tx is exported before its db transaction commits, so it may be exported again if the commit fails.
+type Exporter struct {
+	dir string
+}
+
+func NewExporter(dir string) (*Exporter, error) {
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return nil, err
+	}
+	return &Exporter{dir: dir}, nil
+}
+
+func (exporter *Exporter) Path(tt uint64) string {
+	month := time.Unix(int64(tt), 0).UTC().Format("2006-01")
+	return filepath.Join(exporter.dir, "cross_chain_txs_"+month+".jsonl")
+}
+
+func (exporter *Exporter) Export(db *gorm.DB, crossChainTxs []*models.CrossChainTx) error {
+	archivedTxs, err := loadArchivedTxs(db, crossChainTxs)
+	if err != nil {
+		return err
+	}
+	bufs := make(map[string]*bytes.Buffer)
+	paths := make([]string, 0)
+	for _, archivedTx := range archivedTxs {
+		path := exporter.Path(archivedTx.CrossChainTx.Time)
+		buf, ok := bufs[path]
+		if !ok {
+			buf = new(bytes.Buffer)
+			bufs[path] = buf
+			paths = append(paths, path)
+		}
+		if err := json.NewEncoder(buf).Encode(archivedTx); err != nil {
+			return err
+		}
+	}
+	for _, path := range paths {
+		if err := appendFile(path, bufs[path].Bytes()); err != nil {
+			return fmt.Errorf("export %s err: %w", path, err)
+		}
+	}
+	return nil
+}
+
+func appendFile(path string, data []byte) error {
+	file, err := os.OpenFile(path, os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0644)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+	if _, err := file.Write(data); err != nil {
+		return err
+	}
+	return file.Sync()
+}
+
+func loadArchivedTxs(db *gorm.DB, crossChainTxs []*models.CrossChainTx) ([]*ArchivedTx, error) {
+	hashes := legHashes(crossChainTxs)
+	wrapperTransactions := make([]*models.WrapperTransaction, 0)
+	if err := db.Where("hash in ?", hashes[srcLeg]).Find(&wrapperTransactions).Error; err != nil {
+		return nil, err
+	}
+	srcTransactions := make([]*models.SrcTransaction, 0)
+	err := db.Where("hash in ?", hashes[srcLeg]).Preload("SrcTransfer").Preload("SrcSwap").Find(&srcTransactions).Error
+	if err != nil {
+		return nil, err
+	}
+	polyTransactions := make([]*models.PolyTransaction, 0)
+	if err := db.Where("hash in ?", hashes[polyLeg]).Find(&polyTransactions).Error; err != nil {
+		return nil, err
+	}
+	dstTransactions := make([]*models.DstTransaction, 0)
+	err = db.Where("hash in ?", hashes[dstLeg]).Preload("DstTransfer").Preload("DstSwap").Find(&dstTransactions).Error
+	if err != nil {
+		return nil, err
+	}
+	hash2WrapperTransactions := make(map[string]*models.WrapperTransaction)
+	for _, wrapperTransaction := range wrapperTransactions {
+		hash2WrapperTransactions[wrapperTransaction.Hash] = wrapperTransaction
+	}
+	hash2SrcTransactions := make(map[string]*models.SrcTransaction)
+	for _, srcTransaction := range srcTransactions {
+		hash2SrcTransactions[srcTransaction.Hash] = srcTransaction
+	}
+	hash2PolyTransactions := make(map[string]*models.PolyTransaction)
+	for _, polyTransaction := range polyTransactions {
+		hash2PolyTransactions[polyTransaction.Hash] = polyTransaction
+	}
+	hash2DstTransactions := make(map[string]*models.DstTransaction)
+	for _, dstTransaction := range dstTransactions {
+		hash2DstTransactions[dstTransaction.Hash] = dstTransaction
+	}
+	archivedTxs := make([]*ArchivedTx, 0, len(crossChainTxs))
+	for _, crossChainTx := range crossChainTxs {
+		archivedTxs = append(archivedTxs, &ArchivedTx{
+			CrossChainTx:       crossChainTx,
+			WrapperTransaction: hash2WrapperTransactions[crossChainTx.SrcHash],
+			SrcTransaction:     hash2SrcTransactions[crossChainTx.SrcHash],
+			PolyTransaction:    hash2PolyTransactions[crossChainTx.PolyHash],
+			DstTransaction:     hash2DstTransactions[crossChainTx.DstHash],
+		})
+	}
+	return archivedTxs, nil
+}
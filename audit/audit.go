@@ -0,0 +1,493 @@
+/*
+ * Copyright (C) 2020 The poly network Authors
+ * This file is part of The poly network library.
+ *
+ * The  poly network  is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU Lesser General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * The  poly network  is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU Lesser General Public License for more details.
+ * You should have received a copy of the GNU Lesser General Public License
+ * along with The poly network .  If not, see <http://www.gnu.org/licenses/>.
+ */
+
+package audit
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math/big"
+	"poly-bridge/basedef"
+	"poly-bridge/common"
+	"poly-bridge/conf"
+	"poly-bridge/models"
+	"poly-bridge/utils/cursor"
+	"poly-bridge/utils/database"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/beego/beego/v2/core/logs"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+const (
+	defaultInterval = 10 * 60
+	defaultBatch    = 200
+	cursorName      = "transfer_audit"
+	maxAlarmLines   = 20
+)
+
+// Auditor reconciles what was released on the destination with what was locked on the source. It walks the dst
+// transactions by (time, id), compares the released amount with the locked amount in the destination precision and
+// flags the mismatches, the orphan unlocks and the double executions. The transfers are summed by token pair in
+// transfer_audit_totals.
+type Auditor struct {
+	context.Context
+	cancel context.CancelFunc
+	cfg    *conf.AuditConfig
+	db     *gorm.DB
+	wg     sync.WaitGroup
+}
+
+var auditor *Auditor
+
+func StartAudit(ctx context.Context, config *conf.Config) {
+	if config.Server != basedef.SERVER_POLY_BRIDGE {
+		panic("Audit Only runs on bridge server")
+	}
+	if config.AuditConfig == nil {
+		panic("Invalid Audit config")
+	}
+	db, err := database.Open(config.DBConfig)
+	if err != nil {
+		panic(err)
+	}
+	auditor = NewAuditor(ctx, config.AuditConfig, db)
+	auditor.Start()
+}
+
+func StopAudit() {
+	if auditor != nil {
+		auditor.Stop()
+	}
+}
+
+func NewAuditor(ctx context.Context, cfg *conf.AuditConfig, db *gorm.DB) *Auditor {
+	ctx, cancel := context.WithCancel(ctx)
+	return &Auditor{
+		Context: ctx,
+		cancel:  cancel,
+		cfg:     cfg,
+		db:      db,
+	}
+}
+
+func (this *Auditor) Start() {
+	logs.Info("start audit")
+	this.wg.Add(1)
+	go this.run()
+}
+
+func (this *Auditor) Stop() {
+	logs.Info("Stopping audit")
+	this.cancel()
+	this.wg.Wait()
+}
+
+func (this *Auditor) run() {
+	defer this.wg.Done()
+	interval := this.cfg.Interval
+	if interval <= 0 {
+		interval = defaultInterval
+	}
+	ticker := time.NewTicker(time.Second * time.Duration(interval))
+	defer ticker.Stop()
+	for {
+		for this.Err() == nil {
+			n, err := this.Audit(time.Now().Unix())
+			if err != nil {
+				logs.Error("audit transfers err: %v", err)
+				break
+			}
+			if n == 0 {
+				break
+			}
+			logs.Info("audited %d transfers", n)
+		}
+		select {
+		case <-ticker.C:
+		case <-this.Done():
+			return
+		}
+	}
+}
+
+// Audit checks one batch of the dst transactions saved before now minus cursor.Lag, so that the other legs have
+// arrived, and returns the number of transactions checked. The findings, the totals and the cursor are saved together.
+func (this *Auditor) Audit(now int64) (int, error) {
+	batch := this.cfg.Batch
+	if batch <= 0 {
+		batch = defaultBatch
+	}
+	n := 0
+	var findings []*models.TransferAuditFinding
+	err := this.db.Transaction(func(tx *gorm.DB) error {
+		it, err := cursor.NewIterator(tx, cursorName, batch)
+		if err != nil {
+			return err
+		}
+		it.Settle(uint64(now - cursor.Lag))
+		dstTransactions := make([]*models.DstTransaction, 0)
+		n, err = it.Next(tx.Preload("DstTransfer").Where("standard = ?", 0), &dstTransactions)
+		if err != nil || n == 0 {
+			return err
+		}
+		findings, err = newReconciler(tx, now).reconcile(dstTransactions)
+		if err != nil {
+			return err
+		}
+		return it.Save()
+	})
+	if err != nil {
+		return 0, err
+	}
+	if len(findings) > 0 {
+		this.alarm(findings)
+	}
+	return n, nil
+}
+
+func (this *Auditor) alarm(findings []*models.TransferAuditFinding) {
+	for _, finding := range findings {
+		logs.Warn("transfer audit %s: dst %s src %s, %s", finding.Kind, finding.DstHash, finding.SrcHash, finding.Detail)
+	}
+	if this.cfg.DingUrl == "" {
+		return
+	}
+	title := fmt.Sprintf("Transfer audit found %d issues", len(findings))
+	body := fmt.Sprintf("## %s\n", title)
+	for i, finding := range findings {
+		if i == maxAlarmLines {
+			body += fmt.Sprintf("- and %d more\n", len(findings)-i)
+			break
+		}
+		body += fmt.Sprintf("- %s %s->%s dst: %s %s\n", finding.Kind, basedef.GetChainName(finding.SrcChainId),
+			basedef.GetChainName(finding.DstChainId), finding.DstHash, finding.Detail)
+	}
+	if err := common.PostDingCard(title, body, []map[string]string{}, this.cfg.DingUrl); err != nil {
+		logs.Error("post transfer audit alarm err: %v", err)
+	}
+}
+
+type totalKey struct {
+	srcChainId uint64
+	srcAsset   string
+	dstChainId uint64
+	dstAsset   string
+}
+
+type tokenKey struct {
+	chainId uint64
+	hash    string
+}
+
+// reconciler checks a batch of dst transactions in the db transaction of the batch, the tokens and the token maps are
+// loaded once per batch
+type reconciler struct {
+	tx        *gorm.DB
+	now       int64
+	tokens    map[tokenKey]*models.Token
+	tokenMaps map[totalKey]bool
+	totals    map[totalKey]*models.TransferAuditTotal
+}
+
+func newReconciler(tx *gorm.DB, now int64) *reconciler {
+	return &reconciler{
+		tx:        tx,
+		now:       now,
+		tokens:    make(map[tokenKey]*models.Token),
+		tokenMaps: make(map[totalKey]bool),
+		totals:    make(map[totalKey]*models.TransferAuditTotal),
+	}
+}
+
+// reconcile saves the findings and the totals of the dst transactions and returns the new findings
+func (r *reconciler) reconcile(dstTransactions []*models.DstTransaction) ([]*models.TransferAuditFinding, error) {
+	polyHashes := make([]string, 0, len(dstTransactions))
+	dstHashes := make([]string, 0, len(dstTransactions))
+	for _, dstTransaction := range dstTransactions {
+		if dstTransaction.DstTransfer == nil {
+			continue
+		}
+		dstHashes = append(dstHashes, dstTransaction.Hash)
+		if dstTransaction.PolyHash != "" {
+			polyHashes = append(polyHashes, dstTransaction.PolyHash)
+		}
+	}
+	if len(dstHashes) == 0 {
+		return nil, nil
+	}
+	crossChainTxs := make([]*models.CrossChainTx, 0)
+	if err := r.tx.Where("poly_hash in ?", polyHashes).Find(&crossChainTxs).Error; err != nil {
+		return nil, err
+	}
+	poly2CrossChainTxs := make(map[string]*models.CrossChainTx, len(crossChainTxs))
+	srcHashes := make([]string, 0, len(crossChainTxs))
+	for _, crossChainTx := range crossChainTxs {
+		poly2CrossChainTxs[crossChainTx.PolyHash] = crossChainTx
+		srcHashes = append(srcHashes, crossChainTx.SrcHash)
+	}
+	srcTransfers := make([]*models.SrcTransfer, 0)
+	if err := r.tx.Where("tx_hash in ?", srcHashes).Find(&srcTransfers).Error; err != nil {
+		return nil, err
+	}
+	hash2SrcTransfers := make(map[string]*models.SrcTransfer, len(srcTransfers))
+	for _, srcTransfer := range srcTransfers {
+		hash2SrcTransfers[srcTransfer.TxHash] = srcTransfer
+	}
+	// the first dst transaction of a poly hash is the execution, the later ones are executed again
+	executions := make([]*models.DstTransaction, 0)
+	err := r.tx.Select("id", "hash", "poly_hash").Where("poly_hash in ?", polyHashes).Order("id asc").Find(&executions).Error
+	if err != nil {
+		return nil, err
+	}
+	firstExecutions := make(map[string]string, len(executions))
+	for _, execution := range executions {
+		if _, ok := firstExecutions[execution.PolyHash]; !ok {
+			firstExecutions[execution.PolyHash] = execution.Hash
+		}
+	}
+
+	findings := make([]*models.TransferAuditFinding, 0)
+	for _, dstTransaction := range dstTransactions {
+		dstTransfer := dstTransaction.DstTransfer
+		if dstTransfer == nil {
+			continue
+		}
+		var srcTransfer *models.SrcTransfer
+		if crossChainTx, ok := poly2CrossChainTxs[dstTransaction.PolyHash]; ok && dstTransaction.PolyHash != "" {
+			srcTransfer = hash2SrcTransfers[crossChainTx.SrcHash]
+		}
+		if srcTransfer == nil {
+			finding := r.newFinding(models.TransferAuditOrphan, dstTransaction, nil, nil, "no lock on the source")
+			finding.SrcChainId = dstTransaction.SrcChainId
+			findings = append(findings, finding)
+			r.addTotal(totalKey{dstTransaction.SrcChainId, "", dstTransfer.ChainId, dstTransfer.Asset}, nil, nil, dstTransfer.Amount)
+			continue
+		}
+		executed := false
+		if first := firstExecutions[dstTransaction.PolyHash]; first != dstTransaction.Hash {
+			executed = true
+			findings = append(findings, r.newFinding(models.TransferAuditDoubleExecution, dstTransaction, srcTransfer, nil,
+				fmt.Sprintf("executed before by %s", first)))
+		}
+		expected, detail, err := r.expect(srcTransfer, dstTransfer)
+		if err != nil {
+			return nil, err
+		}
+		if detail == "" && expected.Cmp(&dstTransfer.Amount.Int) != 0 {
+			detail = fmt.Sprintf("released %s, expected %s", dstTransfer.Amount.String(), expected.String())
+		}
+		if detail != "" {
+			findings = append(findings, r.newFinding(models.TransferAuditMismatch, dstTransaction, srcTransfer, expected, detail))
+		}
+		key := totalKey{srcTransfer.ChainId, srcTransfer.Asset, dstTransfer.ChainId, dstTransfer.Asset}
+		if executed {
+			// the lock is counted by the first execution
+			r.addTotal(key, nil, nil, dstTransfer.Amount)
+		} else {
+			r.addTotal(key, srcTransfer.Amount, expected, dstTransfer.Amount)
+		}
+	}
+	if err := r.saveTotals(); err != nil {
+		return nil, err
+	}
+	return r.saveFindings(dstHashes, findings)
+}
+
+// expect converts the locked amount to the precision of the released token, the detail tells why it cannot be
+// compared with the released amount
+func (r *reconciler) expect(srcTransfer *models.SrcTransfer, dstTransfer *models.DstTransfer) (*big.Int, string, error) {
+	if srcTransfer.DstChainId != dstTransfer.ChainId {
+		return nil, fmt.Sprintf("released on chain %d, locked for chain %d", dstTransfer.ChainId, srcTransfer.DstChainId), nil
+	}
+	mapped, err := r.mapped(totalKey{srcTransfer.ChainId, srcTransfer.Asset, dstTransfer.ChainId, dstTransfer.Asset})
+	if err != nil {
+		return nil, "", err
+	}
+	if !mapped {
+		return nil, fmt.Sprintf("released asset %s is not mapped from %s", dstTransfer.Asset, srcTransfer.Asset), nil
+	}
+	srcToken, err := r.token(srcTransfer.ChainId, srcTransfer.Asset)
+	if err != nil {
+		return nil, "", err
+	}
+	dstToken, err := r.token(dstTransfer.ChainId, dstTransfer.Asset)
+	if err != nil {
+		return nil, "", err
+	}
+	if srcToken == nil || dstToken == nil {
+		return nil, "unknown token precision", nil
+	}
+	return convert(&srcTransfer.Amount.Int, srcToken.Precision, dstToken.Precision), "", nil
+}
+
+// convert scales amount from the src precision to the dst precision, the digits below the dst precision are dropped
+func convert(amount *big.Int, srcPrecision, dstPrecision uint64) *big.Int {
+	ten := big.NewInt(10)
+	if dstPrecision >= srcPrecision {
+		scale := new(big.Int).Exp(ten, new(big.Int).SetUint64(dstPrecision-srcPrecision), nil)
+		return new(big.Int).Mul(amount, scale)
+	}
+	scale := new(big.Int).Exp(ten, new(big.Int).SetUint64(srcPrecision-dstPrecision), nil)
+	return new(big.Int).Quo(amount, scale)
+}
+
+func (r *reconciler) token(chainId uint64, hash string) (*models.Token, error) {
+	key := tokenKey{chainId, hash}
+	if token, ok := r.tokens[key]; ok {
+		return token, nil
+	}
+	token := new(models.Token)
+	err := r.tx.Where("chain_id = ? and hash = ?", chainId, hash).First(token).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		token = nil
+	} else if err != nil {
+		return nil, err
+	}
+	r.tokens[key] = token
+	return token, nil
+}
+
+func (r *reconciler) mapped(key totalKey) (bool, error) {
+	if mapped, ok := r.tokenMaps[key]; ok {
+		return mapped, nil
+	}
+	tokenMaps := make([]*models.TokenMap, 0)
+	err := r.tx.Where("src_chain_id = ? and src_token_hash = ? and dst_chain_id = ?", key.srcChainId, key.srcAsset, key.dstChainId).
+		Find(&tokenMaps).Error
+	if err != nil {
+		return false, err
+	}
+	mapped := false
+	for _, tokenMap := range tokenMaps {
+		if strings.EqualFold(tokenMap.DstTokenHash, key.dstAsset) {
+			mapped = true
+			break
+		}
+	}
+	r.tokenMaps[key] = mapped
+	return mapped, nil
+}
+
+func (r *reconciler) newFinding(kind string, dstTransaction *models.DstTransaction, srcTransfer *models.SrcTransfer,
+	expected *big.Int, detail string) *models.TransferAuditFinding {
+	dstTransfer := dstTransaction.DstTransfer
+	finding := &models.TransferAuditFinding{
+		Kind:           kind,
+		DstHash:        dstTransaction.Hash,
+		PolyHash:       dstTransaction.PolyHash,
+		DstChainId:     dstTransfer.ChainId,
+		DstAsset:       dstTransfer.Asset,
+		SrcAmount:      models.NewBigIntFromInt(0),
+		ExpectedAmount: models.NewBigIntFromInt(0),
+		DstAmount:      dstTransfer.Amount,
+		Detail:         detail,
+		Time:           dstTransaction.Time,
+		CreateTime:     r.now,
+	}
+	if srcTransfer != nil {
+		finding.SrcHash = srcTransfer.TxHash
+		finding.SrcChainId = srcTransfer.ChainId
+		finding.SrcAsset = srcTransfer.Asset
+		finding.SrcAmount = srcTransfer.Amount
+	}
+	if expected != nil {
+		finding.ExpectedAmount = models.NewBigInt(expected)
+	}
+	return finding
+}
+
+func (r *reconciler) addTotal(key totalKey, in *models.BigInt, expected *big.Int, out *models.BigInt) {
+	total, ok := r.totals[key]
+	if !ok {
+		total = &models.TransferAuditTotal{
+			SrcChainId:     key.srcChainId,
+			SrcAsset:       key.srcAsset,
+			DstChainId:     key.dstChainId,
+			DstAsset:       key.dstAsset,
+			InAmount:       models.NewBigIntFromInt(0),
+			ExpectedAmount: models.NewBigIntFromInt(0),
+			OutAmount:      models.NewBigIntFromInt(0),
+		}
+		r.totals[key] = total
+	}
+	if in != nil {
+		total.InCounter++
+		total.InAmount.Add(&total.InAmount.Int, &in.Int)
+	}
+	if expected != nil {
+		total.ExpectedAmount.Add(&total.ExpectedAmount.Int, expected)
+	}
+	total.OutCounter++
+	total.OutAmount.Add(&total.OutAmount.Int, &out.Int)
+}
+
+// saveTotals adds the sums of the batch to the saved totals
+func (r *reconciler) saveTotals() error {
+	for key, total := range r.totals {
+		saved := new(models.TransferAuditTotal)
+		err := r.tx.Where("src_chain_id = ? and src_asset = ? and dst_chain_id = ? and dst_asset = ?",
+			key.srcChainId, key.srcAsset, key.dstChainId, key.dstAsset).First(saved).Error
+		if err == nil {
+			total.Id = saved.Id
+			total.InCounter += saved.InCounter
+			total.InAmount.Add(&total.InAmount.Int, &saved.InAmount.Int)
+			total.ExpectedAmount.Add(&total.ExpectedAmount.Int, &saved.ExpectedAmount.Int)
+			total.OutCounter += saved.OutCounter
+			total.OutAmount.Add(&total.OutAmount.Int, &saved.OutAmount.Int)
+		} else if !errors.Is(err, gorm.ErrRecordNotFound) {
+			return err
+		}
+		total.UpdateTime = r.now
+		if err := r.tx.Save(total).Error; err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// saveFindings adds the findings not saved by a previous scan of the same transactions and returns them
+func (r *reconciler) saveFindings(dstHashes []string, findings []*models.TransferAuditFinding) ([]*models.TransferAuditFinding, error) {
+	if len(findings) == 0 {
+		return nil, nil
+	}
+	saved := make([]*models.TransferAuditFinding, 0)
+	if err := r.tx.Select("kind", "dst_hash").Where("dst_hash in ?", dstHashes).Find(&saved).Error; err != nil {
+		return nil, err
+	}
+	existed := make(map[string]bool, len(saved))
+	for _, finding := range saved {
+		existed[finding.Kind+":"+finding.DstHash] = true
+	}
+	newFindings := make([]*models.TransferAuditFinding, 0, len(findings))
+	for _, finding := range findings {
+		if !existed[finding.Kind+":"+finding.DstHash] {
+			newFindings = append(newFindings, finding)
+		}
+	}
+	if len(newFindings) == 0 {
+		return nil, nil
+	}
+	if err := r.tx.Clauses(clause.OnConflict{DoNothing: true}).Create(newFindings).Error; err != nil {
+		return nil, err
+	}
+	return newFindings, nil
+}
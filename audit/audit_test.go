@@ -0,0 +1,137 @@
+package audit
+
+import (
+	"context"
+	"math/big"
+	"poly-bridge/basedef"
+	"poly-bridge/conf"
+	"poly-bridge/models"
+	"poly-bridge/utils/dbtest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"gorm.io/gorm"
+)
+
+// openAuditDB opens the test db with the tokens of the audited usdt pair
+func openAuditDB(t *testing.T) *gorm.DB {
+	db := dbtest.Open(t, &models.TokenBasic{}, &models.Token{}, &models.TokenMap{}, &models.SrcTransfer{}, &models.CrossChainTx{},
+		&models.DstTransaction{}, &models.DstTransfer{}, &models.ScanCursor{}, &models.ScanRetry{}, &models.TransferAuditFinding{}, &models.TransferAuditTotal{})
+	rows := []interface{}{
+		&models.Token{Hash: "usdt", ChainId: basedef.ETHEREUM_CROSSCHAIN_ID, Precision: 6},
+		&models.Token{Hash: "busdt", ChainId: basedef.BSC_CROSSCHAIN_ID, Precision: 18},
+		&models.TokenMap{SrcChainId: basedef.ETHEREUM_CROSSCHAIN_ID, SrcTokenHash: "usdt", DstChainId: basedef.BSC_CROSSCHAIN_ID, DstTokenHash: "busdt"},
+	}
+	for _, row := range rows {
+		if err := db.Create(row).Error; err != nil {
+			t.Fatal(err)
+		}
+	}
+	return db
+}
+
+func amount(value string) *models.BigInt {
+	x, _ := new(big.Int).SetString(value, 10)
+	return models.NewBigInt(x)
+}
+
+func createTransfer(t *testing.T, db *gorm.DB, hash string, tt uint64, locked string, released string) {
+	rows := []interface{}{
+		&models.SrcTransfer{TxHash: "src" + hash, ChainId: basedef.ETHEREUM_CROSSCHAIN_ID, Time: tt, Asset: "usdt",
+			Amount: amount(locked), DstChainId: basedef.BSC_CROSSCHAIN_ID},
+		&models.CrossChainTx{SrcHash: "src" + hash, SrcChainId: basedef.ETHEREUM_CROSSCHAIN_ID, DstChainId: basedef.BSC_CROSSCHAIN_ID,
+			Time: tt, PolyHash: "poly" + hash, DstHash: "dst" + hash},
+	}
+	for _, row := range rows {
+		if err := db.Create(row).Error; err != nil {
+			t.Fatal(err)
+		}
+	}
+	createDstTransaction(t, db, "dst"+hash, "poly"+hash, tt, released)
+}
+
+func createDstTransaction(t *testing.T, db *gorm.DB, hash string, polyHash string, tt uint64, released string) {
+	dstTransaction := &models.DstTransaction{Hash: hash, ChainId: basedef.BSC_CROSSCHAIN_ID, SrcChainId: basedef.ETHEREUM_CROSSCHAIN_ID,
+		Time: tt, PolyHash: polyHash, Fee: models.NewBigIntFromInt(0),
+		DstTransfer: &models.DstTransfer{TxHash: hash, ChainId: basedef.BSC_CROSSCHAIN_ID, Time: tt, Asset: "busdt", Amount: amount(released)}}
+	if err := db.Create(dstTransaction).Error; err != nil {
+		t.Fatal(err)
+	}
+}
+
+func findings(t *testing.T, db *gorm.DB) map[string]*models.TransferAuditFinding {
+	rows := make([]*models.TransferAuditFinding, 0)
+	if err := db.Find(&rows).Error; err != nil {
+		t.Fatal(err)
+	}
+	kinds := make(map[string]*models.TransferAuditFinding, len(rows))
+	for _, row := range rows {
+		kinds[row.Kind+":"+row.DstHash] = row
+	}
+	return kinds
+}
+
+func TestConvert(t *testing.T) {
+	assert.Equal(t, "1000000000000000000", convert(big.NewInt(1000000), 6, 18).String())
+	assert.Equal(t, "1000000", convert(&amount("1000000999999999999").Int, 18, 6).String())
+	assert.Equal(t, "5", convert(big.NewInt(5), 8, 8).String())
+}
+
+func TestAudit(t *testing.T) {
+	db := openAuditDB(t)
+	createTransfer(t, db, "01", 1000, "1000000", "1000000000000000000")
+	createTransfer(t, db, "02", 1100, "1000000", "2000000000000000000")
+	createDstTransaction(t, db, "dst03", "poly03", 1200, "3000000000000000000")
+	createDstTransaction(t, db, "dst04", "poly01", 1300, "1000000000000000000")
+	createTransfer(t, db, "05", 9000, "1000000", "9000000000000000000")
+
+	auditor := NewAuditor(context.Background(), &conf.AuditConfig{Batch: 2}, db)
+	now := int64(9000)
+	n, err := auditor.Audit(now)
+	assert.NoError(t, err)
+	assert.Equal(t, 2, n)
+	n, err = auditor.Audit(now)
+	assert.NoError(t, err)
+	assert.Equal(t, 2, n)
+	n, err = auditor.Audit(now)
+	assert.NoError(t, err)
+	assert.Equal(t, 0, n, "the recent transfer is left to the next run")
+
+	kinds := findings(t, db)
+	assert.Len(t, kinds, 3)
+	if mismatch := kinds[models.TransferAuditMismatch+":dst02"]; assert.NotNil(t, mismatch) {
+		assert.Equal(t, "src02", mismatch.SrcHash)
+		assert.Equal(t, "1000000000000000000", mismatch.ExpectedAmount.String())
+		assert.Equal(t, "2000000000000000000", mismatch.DstAmount.String())
+	}
+	if orphan := kinds[models.TransferAuditOrphan+":dst03"]; assert.NotNil(t, orphan) {
+		assert.Equal(t, "", orphan.SrcHash)
+		assert.Equal(t, uint64(basedef.ETHEREUM_CROSSCHAIN_ID), orphan.SrcChainId)
+	}
+	if double := kinds[models.TransferAuditDoubleExecution+":dst04"]; assert.NotNil(t, double) {
+		assert.Equal(t, "src01", double.SrcHash)
+		assert.Equal(t, "executed before by dst01", double.Detail)
+	}
+
+	totals := make([]*models.TransferAuditTotal, 0)
+	assert.NoError(t, db.Order("src_asset asc").Find(&totals).Error)
+	if assert.Len(t, totals, 2) {
+		assert.Equal(t, "", totals[0].SrcAsset)
+		assert.Equal(t, int64(0), totals[0].InCounter)
+		assert.Equal(t, int64(1), totals[0].OutCounter)
+		assert.Equal(t, "3000000000000000000", totals[0].OutAmount.String())
+		assert.Equal(t, "usdt", totals[1].SrcAsset)
+		assert.Equal(t, int64(2), totals[1].InCounter)
+		assert.Equal(t, "2000000", totals[1].InAmount.String())
+		assert.Equal(t, "2000000000000000000", totals[1].ExpectedAmount.String())
+		assert.Equal(t, int64(3), totals[1].OutCounter)
+		assert.Equal(t, "4000000000000000000", totals[1].OutAmount.String())
+	}
+
+	n, err = auditor.Audit(now + 10000)
+	assert.NoError(t, err)
+	assert.Equal(t, 1, n)
+	kinds = findings(t, db)
+	assert.Len(t, kinds, 4)
+	assert.NotNil(t, kinds[models.TransferAuditMismatch+":dst05"])
+}
@@ -0,0 +1,308 @@
+/*
+ * Copyright (C) 2020 The poly network Authors
+ * This file is part of The poly network library.
+ *
+ * The  poly network  is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU Lesser General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * The  poly network  is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU Lesser General Public License for more details.
+ * You should have received a copy of the GNU Lesser General Public License
+ * along with The poly network .  If not, see <http://www.gnu.org/licenses/>.
+ */
+
+package backfill
+
+import (
+	"context"
+	"fmt"
+	"poly-bridge/basedef"
+	"poly-bridge/conf"
+	"poly-bridge/crosschaindao/bridgedao"
+	"poly-bridge/crosschainlisten"
+	"poly-bridge/models"
+	"runtime/debug"
+	"sync"
+	"time"
+
+	"github.com/beego/beego/v2/core/logs"
+)
+
+const (
+	defaultJobSlot     = 10
+	defaultBatchLength = 100
+	defaultConcurrency = 4
+	retrySlot          = 5
+)
+
+// Backfill re-ingests historical height ranges of a chain. The jobs and their progress are kept in the
+// backfill_jobs table, the live listener's Chain.Height is never touched.
+type Backfill struct {
+	context.Context
+	cancel  context.CancelFunc
+	cfg     *conf.BackfillConfig
+	config  *conf.Config
+	dao     *bridgedao.BridgeDao
+	wg      sync.WaitGroup
+	mutex   sync.Mutex
+	running map[int64]bool
+	handles map[uint64]crosschainlisten.ChainHandle
+}
+
+var bf *Backfill
+
+func StartBackfill(ctx context.Context, config *conf.Config) {
+	if config.Server != basedef.SERVER_POLY_BRIDGE {
+		panic("Backfill Only runs on bridge server")
+	}
+	if config.BackfillConfig == nil {
+		panic("Invalid Backfill config")
+	}
+	dao := bridgedao.NewBridgeDao(config.DBConfig, false)
+	if config.EventSinkConfig != nil {
+		dao.EnableEventOutbox()
+	}
+	bf = NewBackfill(ctx, config, dao)
+	bf.Start()
+}
+
+func StopBackfill() {
+	if bf != nil {
+		bf.Stop()
+	}
+}
+
+func NewBackfill(ctx context.Context, config *conf.Config, dao *bridgedao.BridgeDao) *Backfill {
+	cfg := config.BackfillConfig
+	if cfg == nil {
+		cfg = &conf.BackfillConfig{}
+	}
+	ctx, cancel := context.WithCancel(ctx)
+	return &Backfill{
+		Context: ctx,
+		cancel:  cancel,
+		cfg:     cfg,
+		config:  config,
+		dao:     dao,
+		running: make(map[int64]bool),
+		handles: make(map[uint64]crosschainlisten.ChainHandle),
+	}
+}
+
+func (this *Backfill) Start() {
+	logs.Info("start backfill")
+	this.wg.Add(1)
+	go this.run()
+}
+
+// Stop cancels the running jobs and waits for the in-flight ranges to be committed
+func (this *Backfill) Stop() {
+	logs.Info("Stopping backfill")
+	this.cancel()
+	this.wg.Wait()
+}
+
+func (this *Backfill) run() {
+	defer this.wg.Done()
+	slot := this.cfg.JobSlot
+	if slot <= 0 {
+		slot = defaultJobSlot
+	}
+	ticker := time.NewTicker(time.Second * time.Duration(slot))
+	defer ticker.Stop()
+	for {
+		this.pickJobs()
+		select {
+		case <-ticker.C:
+		case <-this.Done():
+			return
+		}
+	}
+}
+
+func (this *Backfill) pickJobs() {
+	jobs, err := this.dao.GetBackfillJobs(basedef.BACKFILL_RUNNING)
+	if err != nil {
+		logs.Error("backfill get running jobs err: %v", err)
+		return
+	}
+	for _, job := range jobs {
+		if !this.acquire(job.Id) {
+			continue
+		}
+		this.wg.Add(1)
+		go func(job *models.BackfillJob) {
+			defer this.wg.Done()
+			defer this.release(job.Id)
+			this.RunJob(job)
+		}(job)
+	}
+}
+
+func (this *Backfill) acquire(id int64) bool {
+	this.mutex.Lock()
+	defer this.mutex.Unlock()
+	if this.running[id] {
+		return false
+	}
+	this.running[id] = true
+	return true
+}
+
+func (this *Backfill) release(id int64) {
+	this.mutex.Lock()
+	defer this.mutex.Unlock()
+	delete(this.running, id)
+}
+
+func (this *Backfill) getHandle(chainId uint64) (crosschainlisten.ChainHandle, error) {
+	this.mutex.Lock()
+	defer this.mutex.Unlock()
+	if handle, ok := this.handles[chainId]; ok {
+		return handle, nil
+	}
+	chainListenConfig := this.config.GetChainListenConfig(chainId)
+	if chainListenConfig == nil {
+		return nil, fmt.Errorf("chain %d is not configured", chainId)
+	}
+	handle := crosschainlisten.NewChainHandle(this.Context, chainListenConfig)
+	if handle == nil {
+		return nil, fmt.Errorf("chain %d handler is invalid", chainId)
+	}
+	if _, ok := handle.(crosschainlisten.EventCursorHandle); ok {
+		return nil, fmt.Errorf("chain %d fetches events by sequence number, backfill by height is not supported", chainId)
+	}
+	this.handles[chainId] = handle
+	return handle, nil
+}
+
+// RunJob handles the job from its saved height until it is done, paused or the backfill is stopped
+func (this *Backfill) RunJob(job *models.BackfillJob) {
+	defer func() {
+		if r := recover(); r != nil {
+			logs.Error("backfill job %d, recover info: %s", job.Id, string(debug.Stack()))
+		}
+	}()
+	handle, err := this.getHandle(job.ChainId)
+	if err != nil {
+		logs.Error("backfill job %d err: %v", job.Id, err)
+		if err := this.dao.UpdateBackfillJobStatus(job.Id, basedef.BACKFILL_PAUSED, err.Error()); err != nil {
+			logs.Error("backfill job %d update status err: %v", job.Id, err)
+		}
+		return
+	}
+	batchLength, concurrency := job.BatchLength, job.Concurrency
+	if batchLength == 0 {
+		batchLength = this.cfg.BatchLength
+	}
+	if batchLength == 0 {
+		batchLength = defaultBatchLength
+	}
+	if concurrency == 0 {
+		concurrency = this.cfg.Concurrency
+	}
+	if concurrency == 0 {
+		concurrency = defaultConcurrency
+	}
+	logs.Info("backfill job %d, chain: %s, range: [%d, %d], height: %d", job.Id, handle.GetChainName(), job.StartHeight, job.EndHeight, job.Height)
+
+	// the replay keeps the ranges committed above job.Height, a failed span only fetches its failed ranges again
+	listen := crosschainlisten.NewReplayListen(this.Context, handle, this.dao, this.config)
+	for job.Height < job.EndHeight && this.Err() == nil {
+		current, err := this.dao.GetBackfillJob(job.Id)
+		if err != nil {
+			logs.Error("backfill job %d get status err: %v", job.Id, err)
+			return
+		}
+		if current.Status != basedef.BACKFILL_RUNNING {
+			logs.Info("backfill job %d is %s at height %d", job.Id, current.Status, job.Height)
+			return
+		}
+		end := job.Height + batchLength*concurrency
+		if end > job.EndHeight {
+			end = job.EndHeight
+		}
+		height := listen.ReplayRange(job.Height, end, batchLength)
+		if height != end {
+			errMsg := fmt.Sprintf("range [%d, %d] committed to %d", job.Height+1, end, height)
+			logs.Error("backfill job %d %s", job.Id, errMsg)
+			if err := this.dao.UpdateBackfillJobHeight(job.Id, height, errMsg); err != nil {
+				logs.Error("backfill job %d save error err: %v", job.Id, err)
+			} else {
+				job.Height = height
+			}
+			select {
+			case <-time.After(time.Second * retrySlot):
+			case <-this.Done():
+			}
+			continue
+		}
+		if err := this.dao.UpdateBackfillJobHeight(job.Id, end, ""); err != nil {
+			logs.Error("backfill job %d save height %d err: %v", job.Id, end, err)
+			return
+		}
+		job.Height = end
+	}
+	if job.Height >= job.EndHeight {
+		if err := this.dao.UpdateBackfillJobStatus(job.Id, basedef.BACKFILL_DONE, ""); err != nil {
+			logs.Error("backfill job %d update status err: %v", job.Id, err)
+			return
+		}
+		logs.Info("backfill job %d done, chain: %s, range: [%d, %d]", job.Id, handle.GetChainName(), job.StartHeight, job.EndHeight)
+	}
+}
+
+// AddJob validates and saves a new running job, it is picked up by the backfill service
+func AddJob(dao *bridgedao.BridgeDao, req *models.BackfillJobReq) (*models.BackfillJob, error) {
+	if req.StartHeight == 0 || req.EndHeight < req.StartHeight {
+		return nil, fmt.Errorf("invalid range [%d, %d]", req.StartHeight, req.EndHeight)
+	}
+	if basedef.GetChainFamily(req.ChainId) == "" {
+		return nil, fmt.Errorf("unknown chain %d", req.ChainId)
+	}
+	now := time.Now().Unix()
+	job := &models.BackfillJob{
+		ChainId:     req.ChainId,
+		StartHeight: req.StartHeight,
+		EndHeight:   req.EndHeight,
+		Height:      req.StartHeight - 1,
+		BatchLength: req.BatchLength,
+		Concurrency: req.Concurrency,
+		Status:      basedef.BACKFILL_RUNNING,
+		CreateTime:  now,
+		UpdateTime:  now,
+	}
+	if err := dao.AddBackfillJob(job); err != nil {
+		return nil, err
+	}
+	return job, nil
+}
+
+// ResumeJob marks a paused job as running, it continues from its saved height
+func ResumeJob(dao *bridgedao.BridgeDao, id int64) (*models.BackfillJob, error) {
+	return setJobStatus(dao, id, basedef.BACKFILL_PAUSED, basedef.BACKFILL_RUNNING)
+}
+
+// PauseJob marks a running job as paused, the runner stops after the range in flight
+func PauseJob(dao *bridgedao.BridgeDao, id int64) (*models.BackfillJob, error) {
+	return setJobStatus(dao, id, basedef.BACKFILL_RUNNING, basedef.BACKFILL_PAUSED)
+}
+
+func setJobStatus(dao *bridgedao.BridgeDao, id int64, from, to string) (*models.BackfillJob, error) {
+	job, err := dao.GetBackfillJob(id)
+	if err != nil {
+		return nil, err
+	}
+	if job.Status != from {
+		return nil, fmt.Errorf("job %d is %s", id, job.Status)
+	}
+	if err = dao.UpdateBackfillJobStatus(id, to, job.Error); err != nil {
+		return nil, err
+	}
+	job.Status = to
+	return job, nil
+}
@@ -0,0 +1,123 @@
+/*
+ * Copyright (C) 2020 The poly network Authors
+ * This file is part of The poly network library.
+ *
+ * The  poly network  is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU Lesser General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * The  poly network  is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU Lesser General Public License for more details.
+ * You should have received a copy of the GNU Lesser General Public License
+ * along with The poly network .  If not, see <http://www.gnu.org/licenses/>.
+ */
+
+package backfill
+
+import (
+	"crypto/subtle"
+	"encoding/json"
+	"fmt"
+	"net"
+	"poly-bridge/models"
+
+	"github.com/beego/beego/v2/core/logs"
+	"github.com/beego/beego/v2/server/web"
+)
+
+func GetRouter() web.LinkNamespace {
+	ns := web.NSNamespace("/backfill",
+		web.NSRouter("/start/", &BackfillController{}, "post:Start"),
+		web.NSRouter("/resume/", &BackfillController{}, "post:Resume"),
+		web.NSRouter("/pause/", &BackfillController{}, "post:Pause"),
+		web.NSRouter("/jobs/", &BackfillController{}, "post:Jobs"),
+	)
+	return ns
+}
+
+type BackfillController struct {
+	web.Controller
+}
+
+// Prepare only lets the admin requests carrying the api token in the X-Api-Token header through,
+// and only from the loopback address unless AllowRemote is set
+func (c *BackfillController) Prepare() {
+	if bf == nil {
+		c.errorRsp(400, "backfill is not running!")
+		return
+	}
+	if !bf.cfg.AllowRemote {
+		ip := net.ParseIP(c.Ctx.Input.IP())
+		if ip == nil || !ip.IsLoopback() {
+			c.errorRsp(403, "forbidden!")
+			return
+		}
+	}
+	token := c.Ctx.Input.Header("X-Api-Token")
+	if len(token) == 0 || len(bf.cfg.ApiToken) == 0 || subtle.ConstantTimeCompare([]byte(token), []byte(bf.cfg.ApiToken)) != 1 {
+		c.errorRsp(401, "unauthorized!")
+	}
+}
+
+func (c *BackfillController) errorRsp(status int, message string) {
+	c.Data["json"] = models.MakeErrorRsp(message)
+	c.Ctx.ResponseWriter.WriteHeader(status)
+	c.ServeJSON()
+	c.StopRun()
+}
+
+func (c *BackfillController) Start() {
+	var backfillJobReq models.BackfillJobReq
+	if err := json.Unmarshal(c.Ctx.Input.RequestBody, &backfillJobReq); err != nil {
+		c.errorRsp(400, "request parameter is invalid!")
+	}
+	job, err := AddJob(bf.dao, &backfillJobReq)
+	if err != nil {
+		c.errorRsp(400, fmt.Sprintf("add backfill job err: %v", err))
+	}
+	logs.Info("backfill job %d added: %+v", job.Id, backfillJobReq)
+	c.Data["json"] = models.MakeBackfillJobRsp(job)
+	c.ServeJSON()
+}
+
+func (c *BackfillController) Resume() {
+	var backfillJobIdReq models.BackfillJobIdReq
+	if err := json.Unmarshal(c.Ctx.Input.RequestBody, &backfillJobIdReq); err != nil {
+		c.errorRsp(400, "request parameter is invalid!")
+	}
+	job, err := ResumeJob(bf.dao, backfillJobIdReq.Id)
+	if err != nil {
+		c.errorRsp(400, fmt.Sprintf("resume backfill job err: %v", err))
+	}
+	c.Data["json"] = models.MakeBackfillJobRsp(job)
+	c.ServeJSON()
+}
+
+func (c *BackfillController) Pause() {
+	var backfillJobIdReq models.BackfillJobIdReq
+	if err := json.Unmarshal(c.Ctx.Input.RequestBody, &backfillJobIdReq); err != nil {
+		c.errorRsp(400, "request parameter is invalid!")
+	}
+	job, err := PauseJob(bf.dao, backfillJobIdReq.Id)
+	if err != nil {
+		c.errorRsp(400, fmt.Sprintf("pause backfill job err: %v", err))
+	}
+	c.Data["json"] = models.MakeBackfillJobRsp(job)
+	c.ServeJSON()
+}
+
+func (c *BackfillController) Jobs() {
+	var backfillJobsReq models.BackfillJobsReq
+	if err := json.Unmarshal(c.Ctx.Input.RequestBody, &backfillJobsReq); err != nil {
+		c.errorRsp(400, "request parameter is invalid!")
+	}
+	jobs, err := bf.dao.GetBackfillJobs(backfillJobsReq.Status)
+	if err != nil {
+		c.errorRsp(400, fmt.Sprintf("get backfill jobs err: %v", err))
+	}
+	c.Data["json"] = models.MakeBackfillJobsRsp(jobs)
+	c.ServeJSON()
+}
@@ -0,0 +1,97 @@
+/*
+ * Copyright (C) 2020 The poly network Authors
+ * This file is part of The poly network library.
+ *
+ * The  poly network  is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU Lesser General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * The  poly network  is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU Lesser General Public License for more details.
+ * You should have received a copy of the GNU Lesser General Public License
+ * along with The poly network .  If not, see <http://www.gnu.org/licenses/>.
+ */
+
+package basedef
+
+import "sync"
+
+const (
+	CHAIN_FAMILY_POLY     = "poly"
+	CHAIN_FAMILY_EVM      = "evm"
+	CHAIN_FAMILY_O3       = "o3"
+	CHAIN_FAMILY_NEO      = "neo"
+	CHAIN_FAMILY_NEO3     = "neo3"
+	CHAIN_FAMILY_ONTOLOGY = "ontology"
+	CHAIN_FAMILY_SWITCHEO = "switcheo"
+	CHAIN_FAMILY_ZILLIQA  = "zilliqa"
+	CHAIN_FAMILY_STARCOIN = "starcoin"
+	CHAIN_FAMILY_RIPPLE   = "ripple"
+	CHAIN_FAMILY_APTOS    = "aptos"
+)
+
+var (
+	chainFamilies = make(map[uint64]string)
+	// chainFamiliesLock guards chainFamilies and ETH_CHAINS, which are registered again on a config reload
+	// while the listeners read them
+	chainFamiliesLock sync.RWMutex
+)
+
+// RegisterChainFamily binds a chain id to a chain family, chains registered as evm are added to ETH_CHAINS
+func RegisterChainFamily(chainId uint64, family string) {
+	chainFamiliesLock.Lock()
+	defer chainFamiliesLock.Unlock()
+	chainFamilies[chainId] = family
+	if family == CHAIN_FAMILY_EVM && !isETHChain(chainId) {
+		// copied, so a slice returned by EthChains is never changed
+		chains := make([]uint64, len(ETH_CHAINS), len(ETH_CHAINS)+1)
+		copy(chains, ETH_CHAINS)
+		ETH_CHAINS = append(chains, chainId)
+	}
+}
+
+// EthChains returns the evm chains, the registered ones included
+func EthChains() []uint64 {
+	chainFamiliesLock.RLock()
+	defer chainFamiliesLock.RUnlock()
+	return ETH_CHAINS
+}
+
+// GetChainFamily returns the family registered for the chain, or the built-in family of the known chains
+func GetChainFamily(chainId uint64) string {
+	chainFamiliesLock.RLock()
+	family, ok := chainFamilies[chainId]
+	chainFamiliesLock.RUnlock()
+	if ok {
+		return family
+	}
+	switch chainId {
+	case POLY_CROSSCHAIN_ID:
+		return CHAIN_FAMILY_POLY
+	case O3_CROSSCHAIN_ID:
+		return CHAIN_FAMILY_O3
+	case NEO_CROSSCHAIN_ID:
+		return CHAIN_FAMILY_NEO
+	case NEO3_CROSSCHAIN_ID:
+		return CHAIN_FAMILY_NEO3
+	case ONT_CROSSCHAIN_ID:
+		return CHAIN_FAMILY_ONTOLOGY
+	case SWITCHEO_CROSSCHAIN_ID:
+		return CHAIN_FAMILY_SWITCHEO
+	case ZILLIQA_CROSSCHAIN_ID:
+		return CHAIN_FAMILY_ZILLIQA
+	case STARCOIN_CROSSCHAIN_ID:
+		return CHAIN_FAMILY_STARCOIN
+	case RIPPLE_CROSSCHAIN_ID:
+		return CHAIN_FAMILY_RIPPLE
+	case APTOS_CROSSCHAIN_ID:
+		return CHAIN_FAMILY_APTOS
+	}
+	if IsETHChain(chainId) {
+		return CHAIN_FAMILY_EVM
+	}
+	return ""
+}
@@ -0,0 +1,29 @@
+package basedef
+
+import (
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRegisterChainFamily(t *testing.T) {
+	chains := EthChains()
+	var wg sync.WaitGroup
+	for i := uint64(0); i < 10; i++ {
+		wg.Add(2)
+		go func(chainId uint64) {
+			defer wg.Done()
+			RegisterChainFamily(chainId, CHAIN_FAMILY_EVM)
+		}(100000 + i)
+		go func(chainId uint64) {
+			defer wg.Done()
+			IsETHChain(chainId)
+			GetChainFamily(chainId)
+		}(100000 + i)
+	}
+	wg.Wait()
+	assert.Len(t, EthChains(), len(chains)+10)
+	assert.Equal(t, CHAIN_FAMILY_EVM, GetChainFamily(100005))
+	assert.NotContains(t, chains, uint64(100005), "a slice returned earlier is not changed")
+}
@@ -61,6 +61,42 @@ const (
 	StatusOk = "OK"
 )
 
+const (
+	BACKFILL_RUNNING = "running"
+	BACKFILL_PAUSED  = "paused"
+	BACKFILL_DONE    = "done"
+)
+
+const (
+	EVENT_SRC_LOCK    = "src_lock"
+	EVENT_POLY_RELAY  = "poly_relay"
+	EVENT_DST_UNLOCK  = "dst_unlock"
+	EVENT_WRAPPER_FEE = "wrapper_fee"
+)
+
+const (
+	OUTBOX_PENDING = "pending"
+	OUTBOX_FAILED  = "failed"
+)
+
+const (
+	DELIVERY_PENDING   = "pending"
+	DELIVERY_DELIVERED = "delivered"
+	DELIVERY_FAILED    = "failed"
+)
+
+const (
+	DIALECT_MYSQL    = "mysql"
+	DIALECT_POSTGRES = "postgres"
+	DIALECT_SQLITE   = "sqlite"
+)
+
+const (
+	SINK_FILE    = "file"
+	SINK_WEBHOOK = "webhook"
+	SINK_REDIS   = "redis"
+)
+
 const (
 	Chain_Status_All_Nodes_No_Growth        = "All Nodes No Growth"
 	Chain_Status_All_Nodes_Unavaiable       = "All Nodes Unavailable"
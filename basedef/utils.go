@@ -396,6 +396,12 @@ func Has0xPrefix(str string) bool {
 }
 
 func IsETHChain(chainId uint64) bool {
+	chainFamiliesLock.RLock()
+	defer chainFamiliesLock.RUnlock()
+	return isETHChain(chainId)
+}
+
+func isETHChain(chainId uint64) bool {
 	for _, v := range ETH_CHAINS {
 		if chainId == v {
 			return true
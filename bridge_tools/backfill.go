@@ -0,0 +1,100 @@
+/*
+ * Copyright (C) 2020 The poly network Authors
+ * This file is part of The poly network library.
+ *
+ * The  poly network  is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU Lesser General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * The  poly network  is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU Lesser General Public License for more details.
+ * You should have received a copy of the GNU Lesser General Public License
+ * along with The poly network .  If not, see <http://www.gnu.org/licenses/>.
+ */
+
+package main
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"os/signal"
+	"poly-bridge/backfill"
+	"poly-bridge/basedef"
+	"poly-bridge/conf"
+	"poly-bridge/crosschaindao/bridgedao"
+	"poly-bridge/models"
+	"strconv"
+	"syscall"
+)
+
+const (
+	BACKFILL = "backfill"
+)
+
+// backfillJobs manages the backfill jobs, the action is read from BACKFILL_ACTION:
+//
+//	start:  add a job of BR_CHAIN for [BR_HEIGHT, END_HEIGHT], BATCH_LENGTH and CONCURRENCY are optional
+//	resume: resume the paused job BACKFILL_JOB
+//	pause:  pause the running job BACKFILL_JOB
+//	jobs:   list the jobs, filtered by BACKFILL_STATUS if set
+//	run:    run the job BACKFILL_JOB in this process until it is done, paused or interrupted
+func backfillJobs(config *conf.Config) {
+	basedef.ConfirmEnv(config.Env)
+	dao := bridgedao.NewBridgeDao(config.DBConfig, false)
+	id, _ := strconv.ParseInt(os.Getenv("BACKFILL_JOB"), 10, 64)
+	action := os.Getenv("BACKFILL_ACTION")
+	switch action {
+	case "start":
+		req := &models.BackfillJobReq{}
+		req.ChainId, _ = strconv.ParseUint(os.Getenv("BR_CHAIN"), 10, 64)
+		req.StartHeight, _ = strconv.ParseUint(os.Getenv("BR_HEIGHT"), 10, 64)
+		req.EndHeight, _ = strconv.ParseUint(os.Getenv("END_HEIGHT"), 10, 64)
+		req.BatchLength, _ = strconv.ParseUint(os.Getenv("BATCH_LENGTH"), 10, 64)
+		req.Concurrency, _ = strconv.ParseUint(os.Getenv("CONCURRENCY"), 10, 64)
+		job, err := backfill.AddJob(dao, req)
+		checkError(err, "Adding backfill job")
+		printBackfillJobs(job)
+	case "resume":
+		job, err := backfill.ResumeJob(dao, id)
+		checkError(err, "Resuming backfill job")
+		printBackfillJobs(job)
+	case "pause":
+		job, err := backfill.PauseJob(dao, id)
+		checkError(err, "Pausing backfill job")
+		printBackfillJobs(job)
+	case "jobs":
+		jobs, err := dao.GetBackfillJobs(os.Getenv("BACKFILL_STATUS"))
+		checkError(err, "Getting backfill jobs")
+		printBackfillJobs(jobs...)
+	case "run":
+		job, err := dao.GetBackfillJob(id)
+		checkError(err, "Getting backfill job")
+		if job.Status != basedef.BACKFILL_RUNNING {
+			panic(fmt.Sprintf("job %d is %s", id, job.Status))
+		}
+		ctx, cancel := context.WithCancel(context.Background())
+		sc := make(chan os.Signal, 1)
+		signal.Notify(sc, syscall.SIGINT, syscall.SIGTERM)
+		go func() {
+			<-sc
+			cancel()
+		}()
+		backfill.NewBackfill(ctx, config, dao).RunJob(job)
+		job, err = dao.GetBackfillJob(id)
+		checkError(err, "Getting backfill job")
+		printBackfillJobs(job)
+	default:
+		fmt.Println("Available backfill actions: start, resume, pause, jobs, run")
+	}
+}
+
+func printBackfillJobs(jobs ...*models.BackfillJob) {
+	for _, job := range jobs {
+		fmt.Printf("job %d chain %d range [%d, %d] height %d status %s error %s\n",
+			job.Id, job.ChainId, job.StartHeight, job.EndHeight, job.Height, job.Status, job.Error)
+	}
+}
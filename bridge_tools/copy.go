@@ -0,0 +1,124 @@
+/*
+ * Copyright (C) 2020 The poly network Authors
+ * This file is part of The poly network library.
+ *
+ * The  poly network  is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU Lesser General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * The  poly network  is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU Lesser General Public License for more details.
+ * You should have received a copy of the GNU Lesser General Public License
+ * along with The poly network .  If not, see <http://www.gnu.org/licenses/>.
+ */
+
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"poly-bridge/basedef"
+	"poly-bridge/conf"
+	"poly-bridge/tablecopy"
+	"poly-bridge/utils/database"
+	"strings"
+
+	"github.com/beego/beego/v2/core/logs"
+	"github.com/urfave/cli"
+)
+
+var (
+	copyConfigFlag = cli.StringFlag{
+		Name:  "copyconfig",
+		Usage: "copy config `<path>`, the Src and Dst db configs",
+		Value: "./copy.json",
+	}
+	copyTablesFlag = cli.StringFlag{
+		Name:  "tables",
+		Usage: "comma separated `<tables>` to copy, all for all the known tables",
+	}
+	copyJobFlag = cli.StringFlag{
+		Name:  "job",
+		Usage: "`<name>` the progress is saved by in the destination",
+		Value: "copy",
+	}
+	copyBatchFlag = cli.IntFlag{
+		Name:  "batch",
+		Usage: "`<rows>` copied and compared in one batch",
+		Value: 1000,
+	}
+	copyDryRunFlag = cli.BoolFlag{
+		Name:  "dryrun",
+		Usage: "compare the tables without writing the destination",
+	}
+	copyResumeFlag = cli.BoolFlag{
+		Name:  "resume",
+		Usage: "go on after the last batch saved by the job",
+	}
+
+	copyCommand = cli.Command{
+		Name:   "copy",
+		Usage:  "copy tables between two databases, the batches are verified by the checksums of the rows",
+		Flags:  []cli.Flag{copyConfigFlag, copyTablesFlag, copyJobFlag, copyBatchFlag, copyDryRunFlag, copyResumeFlag},
+		Action: copyTables,
+	}
+)
+
+type CopyConfig struct {
+	Src *conf.DBConfig
+	Dst *conf.DBConfig
+}
+
+// copyTables copies the tables one by one, a table which fails is reported and the next one goes on.
+// It exits with an error if a table failed or disagrees.
+func copyTables(ctx *cli.Context) error {
+	fileContent, err := basedef.ReadFile(ctx.String(getFlagName(copyConfigFlag)))
+	checkError(err, "Reading copy config")
+	config := &CopyConfig{}
+	err = json.Unmarshal(fileContent, config)
+	checkError(err, "Parsing copy config")
+	if config.Src == nil || config.Dst == nil {
+		return cli.NewExitError("Invalid copy config, missing Src or Dst db config", 1)
+	}
+	src, err := database.Open(config.Src)
+	checkError(err, "Connecting to src db")
+	dst, err := database.Open(config.Dst)
+	checkError(err, "Connecting to dst db")
+
+	known, err := tablecopy.Tables(src)
+	checkError(err, "Parsing tables")
+	tables := strings.Split(ctx.String(getFlagName(copyTablesFlag)), ",")
+	if len(tables) == 1 && tables[0] == "all" {
+		tables = known
+	} else if len(tables) == 1 && tables[0] == "" {
+		return cli.NewExitError(fmt.Sprintf("Missing -tables, the known tables are %s", strings.Join(known, ",")), 1)
+	}
+	copier := tablecopy.NewCopier(src, dst, tablecopy.Options{
+		Job:    ctx.String(getFlagName(copyJobFlag)),
+		Batch:  ctx.Int(getFlagName(copyBatchFlag)),
+		DryRun: ctx.Bool(getFlagName(copyDryRunFlag)),
+		Resume: ctx.Bool(getFlagName(copyResumeFlag)),
+	})
+	failed := make([]string, 0)
+	for _, table := range tables {
+		table = strings.TrimSpace(table)
+		logs.Info("Copying table %s", table)
+		report, err := copier.Copy(table)
+		if err != nil {
+			fmt.Printf("table %s failed after %d rows: %v, resume with -resume\n", table, report.Rows, err)
+			failed = append(failed, table)
+			continue
+		}
+		fmt.Print(report.String())
+		if !report.Agreed() {
+			failed = append(failed, table)
+		}
+	}
+	if len(failed) > 0 {
+		return cli.NewExitError(fmt.Sprintf("tables failed or disagree: %s", strings.Join(failed, ",")), 1)
+	}
+	return nil
+}
@@ -21,43 +21,18 @@ import (
 	"poly-bridge/bridge_tools/conf"
 	serverconf "poly-bridge/conf"
 	"poly-bridge/crosschaindao"
-	"poly-bridge/models"
+	"poly-bridge/migration"
+	"poly-bridge/utils/database"
 	"strings"
-
-	"gorm.io/driver/mysql"
-	"gorm.io/gorm"
-	"gorm.io/gorm/logger"
 )
 
 func startDeploy(cfg *conf.DeployConfig, servercfg *serverconf.Config) {
 	dbCfg := cfg.DBConfig
-	Logger := logger.Default
-	if dbCfg.Debug == true {
-		Logger = Logger.LogMode(logger.Info)
-	}
-	db, err := gorm.Open(mysql.Open(dbCfg.User+":"+dbCfg.Password+"@tcp("+dbCfg.URL+")/"+
-		dbCfg.Scheme+"?charset=utf8"), &gorm.Config{Logger: Logger})
+	db, err := database.Open(dbCfg)
 	if err != nil {
 		panic(err)
 	}
-	err = db.Debug().AutoMigrate(
-		&models.ChainFee{},
-		&models.Chain{},
-		&models.DstSwap{},
-		&models.DstTransaction{},
-		&models.DstTransfer{},
-		&models.NFTProfile{},
-		&models.PolyTransaction{},
-		&models.PriceMarket{},
-		&models.SrcSwap{},
-		&models.SrcTransaction{},
-		&models.SrcTransfer{},
-		&models.TimeStatistic{},
-		&models.TokenBasic{},
-		&models.TokenMap{},
-		&models.Token{},
-		&models.WrapperTransaction{},
-	)
+	_, err = migration.Up(db.Debug(), 0)
 	if err != nil {
 		panic(err)
 	}
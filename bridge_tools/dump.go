@@ -22,19 +22,11 @@ import (
 	"poly-bridge/bridge_tools/conf"
 	serverconf "poly-bridge/conf"
 	"poly-bridge/models"
-
-	"gorm.io/driver/mysql"
-	"gorm.io/gorm"
-	"gorm.io/gorm/logger"
+	"poly-bridge/utils/database"
 )
 
 func dumpStatus(dbCfg *serverconf.DBConfig) {
-	Logger := logger.Default
-	if dbCfg.Debug == true {
-		Logger = Logger.LogMode(logger.Info)
-	}
-	db, err := gorm.Open(mysql.Open(dbCfg.User+":"+dbCfg.Password+"@tcp("+dbCfg.URL+")/"+
-		dbCfg.Scheme+"?charset=utf8"), &gorm.Config{Logger: Logger})
+	db, err := database.Open(dbCfg)
 	if err != nil {
 		panic(err)
 	}
@@ -85,12 +77,7 @@ func dumpStatus(dbCfg *serverconf.DBConfig) {
 }
 
 func dumpAffectedRows(cfg *conf.UpdateConfig, dbCfg *serverconf.DBConfig) {
-	Logger := logger.Default
-	if dbCfg.Debug == true {
-		Logger = Logger.LogMode(logger.Info)
-	}
-	db, err := gorm.Open(mysql.Open(dbCfg.User+":"+dbCfg.Password+"@tcp("+dbCfg.URL+")/"+
-		dbCfg.Scheme+"?charset=utf8"), &gorm.Config{Logger: Logger})
+	db, err := database.Open(dbCfg)
 	if err != nil {
 		panic(err)
 	}
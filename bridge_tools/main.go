@@ -100,7 +100,10 @@ func setupApp() *cli.App {
 		dyingTokensFlag,
 		dyingTokensRisingRateFlag,
 	}
-	app.Commands = []cli.Command{}
+	app.Commands = []cli.Command{
+		migrateCommand,
+		copyCommand,
+	}
 	app.Before = func(context *cli.Context) error {
 		runtime.GOMAXPROCS(runtime.NumCPU())
 		return nil
@@ -174,7 +177,11 @@ func startServer(ctx *cli.Context) {
 			fmt.Printf("startServer - read config failed!")
 			return
 		}
-		cacheRedis.Init()
+		// the dying tokens are marked in the redis shared with the http servers
+		cache, err := cacheRedis.GetRedisClient(serverConfig.RedisConfig)
+		if err != nil {
+			panic(err)
+		}
 		tokenBasicName := ctx.GlobalString(getFlagName(dyingTokensFlag))
 		if tokenBasicName == "" {
 			fmt.Println("please input token name, i.e. -tokenbasicname ETH")
@@ -185,7 +192,7 @@ func startServer(ctx *cli.Context) {
 			fmt.Println("please input rate for dying token, i.e. -rate 5")
 			return
 		}
-		SetDyingToken(tokenBasicName, dyingTokensRisingRate)
+		SetDyingToken(cache, tokenBasicName, dyingTokensRisingRate)
 	} else if cmd == 8 {
 		configServerFile := ctx.GlobalString(getFlagName(configServerPathFlag))
 		serverConfig := serverconf.NewConfig(configServerFile)
@@ -193,13 +200,16 @@ func startServer(ctx *cli.Context) {
 			fmt.Printf("startServer - read config failed!")
 			return
 		}
-		cacheRedis.Init()
+		cache, err := cacheRedis.GetRedisClient(serverConfig.RedisConfig)
+		if err != nil {
+			panic(err)
+		}
 		tokenBasicName := ctx.GlobalString(getFlagName(dyingTokensFlag))
 		if tokenBasicName == "" {
 			fmt.Println("please input token name, i.e. -tokenbasicname ETH")
 			return
 		}
-		RemoveDyingToken(tokenBasicName)
+		RemoveDyingToken(cache, tokenBasicName)
 	}
 }
 
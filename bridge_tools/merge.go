@@ -5,7 +5,6 @@ import (
 	"fmt"
 	"github.com/beego/beego/v2/core/logs"
 	"github.com/cosmos/cosmos-sdk/types"
-	"gorm.io/driver/mysql"
 	"gorm.io/gorm"
 	"gorm.io/gorm/logger"
 	"os"
@@ -13,6 +12,8 @@ import (
 	"poly-bridge/conf"
 	"poly-bridge/crosschaindao/explorerdao"
 	"poly-bridge/models"
+	"poly-bridge/tablecopy"
+	"poly-bridge/utils/database"
 	"reflect"
 	"strings"
 	"time"
@@ -100,8 +101,7 @@ func merge() {
 	conn := func(cfg *conf.DBConfig) *gorm.DB {
 		Logger := logger.Default
 		Logger = Logger.LogMode(logger.Info)
-		db, err := gorm.Open(mysql.Open(cfg.User+":"+cfg.Password+"@tcp("+cfg.URL+")/"+
-			cfg.Scheme+"?charset=utf8"), &gorm.Config{Logger: Logger})
+		db, err := database.OpenWithLogger(cfg, Logger)
 		checkError(err, "Connecting to db")
 		return db
 	}
@@ -382,84 +382,15 @@ func migrateExplorerDstTransactions(exp, db *gorm.DB) {
 	countTables("tchain_tx", "dst_transactions", exp, db)
 }
 
-func migrateTableInBatches(orderKey string, src, db *gorm.DB, table string, model func() interface{}, query func(*gorm.DB) *gorm.DB) {
-	logs.Info("Runnign migrate table in batch %s", table)
-	selectNum := 1000
-	count := 0
-	order := fmt.Sprintf("%s asc", orderKey)
-	for {
-		logs.Info("%s %d", table, count)
-		entries := model()
-		res := query(src).Limit(selectNum).Offset(selectNum * count).Order(order).Find(entries)
-		checkError(res.Error, "Fetch src_transactions")
-		if res.RowsAffected > 0 {
-			err := db.Save(entries).Error
-			checkError(err, "Save src_transactions")
-			count++
-			time.Sleep(time.Second * 1)
-		} else {
-			break
-		}
-	}
-	countTables(table, table, src, db)
-}
-
+// migrateBridgeTxs copies the bridge txs with tablecopy, a failed step is resumed after the last batch copied
 func migrateBridgeTxs(bri, db *gorm.DB) {
-	{
-		model := func() interface{} { return &[]*models.SrcTransaction{} }
-		query := func(tx *gorm.DB) *gorm.DB {
-			return tx
-		}
-		migrateTableInBatches("time", bri, db, "src_transactions", model, query)
-	}
-	{
-		model := func() interface{} { return &[]*models.PolyTransaction{} }
-		query := func(tx *gorm.DB) *gorm.DB {
-			return tx
-		}
-		migrateTableInBatches("time", bri, db, "poly_transactions", model, query)
-	}
-	{
-		model := func() interface{} { return &[]*models.DstTransaction{} }
-		query := func(tx *gorm.DB) *gorm.DB {
-			return tx
-		}
-		migrateTableInBatches("time", bri, db, "dst_transactions", model, query)
-	}
-	{
-		model := func() interface{} { return &[]*models.WrapperTransaction{} }
-		query := func(tx *gorm.DB) *gorm.DB {
-			return tx
-		}
-		migrateTableInBatches("time", bri, db, "wrapper_transactions", model, query)
-	}
-	{
-		model := func() interface{} { return &[]*models.SrcTransfer{} }
-		query := func(tx *gorm.DB) *gorm.DB {
-			return tx
-		}
-		migrateTableInBatches("time", bri, db, "src_transfers", model, query)
-	}
-	{
-		model := func() interface{} { return &[]*models.DstTransfer{} }
-		query := func(tx *gorm.DB) *gorm.DB {
-			return tx
-		}
-		migrateTableInBatches("time", bri, db, "dst_transfers", model, query)
-	}
-	{
-		model := func() interface{} { return &[]*models.SrcSwap{} }
-		query := func(tx *gorm.DB) *gorm.DB {
-			return tx
-		}
-		migrateTableInBatches("time", bri, db, "src_swaps", model, query)
-	}
-	{
-		model := func() interface{} { return &[]*models.DstSwap{} }
-		query := func(tx *gorm.DB) *gorm.DB {
-			return tx
-		}
-		migrateTableInBatches("time", bri, db, "dst_swaps", model, query)
+	copier := tablecopy.NewCopier(bri, db, tablecopy.Options{Job: "merge", Resume: true})
+	tables := []string{"src_transactions", "poly_transactions", "dst_transactions", "wrapper_transactions",
+		"src_transfers", "dst_transfers", "src_swaps", "dst_swaps"}
+	for _, table := range tables {
+		report, err := copier.Copy(table)
+		checkError(err, "Copying "+table)
+		logs.Info(report.String())
 	}
 }
 
@@ -23,8 +23,6 @@ import (
 	"fmt"
 	"github.com/beego/beego/v2/core/logs"
 	"github.com/urfave/cli"
-	"gorm.io/driver/mysql"
-	"gorm.io/gorm"
 	"gorm.io/gorm/logger"
 	"os"
 	"poly-bridge/basedef"
@@ -35,6 +33,7 @@ import (
 	"poly-bridge/crosschainlisten"
 	"poly-bridge/crosschainlisten/aptoslisten"
 	"poly-bridge/models"
+	"poly-bridge/utils/database"
 	"strconv"
 	"strings"
 	"time"
@@ -58,14 +57,12 @@ func executeMethod(method string, ctx *cli.Context) {
 	switch method {
 	case FETCH_BLOCK:
 		fetchBlock(config)
+	case BACKFILL:
+		backfillJobs(config)
 	case "initcoinmarketid":
 		initcoinmarketid(config)
-	case "migrateLockTokenStatisticTable":
-		migrateLockTokenStatisticTable(config)
 	case "updateZilliqaPolyOldData":
 		updateZilliqaPolyOldData(config)
-	case "updateRippleTables":
-		updateRippleTables(config)
 	case "airdrop":
 		toolsmethod.AirDropNft(config)
 	case "createaccount":
@@ -74,11 +71,9 @@ func executeMethod(method string, ctx *cli.Context) {
 		toolsmethod.AirDrop(config)
 	case "updateAirDropAmount":
 		toolsmethod.UpdateAirDropAmount(config)
-	case "updateNeo3WrapperUserAndDstUser":
-		updateNeo3WrapperTransactions(config)
 
 	default:
-		fmt.Printf("Available methods: \n %s", strings.Join([]string{FETCH_BLOCK}, "\n"))
+		fmt.Printf("Available methods: \n %s", strings.Join([]string{FETCH_BLOCK, BACKFILL}, "\n"))
 	}
 }
 
@@ -157,7 +152,7 @@ func fetchBlock(config *conf.Config) {
 	var handle crosschainlisten.ChainHandle
 	for _, cfg := range config.ChainListenConfig {
 		if int(cfg.ChainId) == chain {
-			handle = crosschainlisten.NewChainHandle(cfg)
+			handle = crosschainlisten.NewChainHandle(context.Background(), cfg)
 			break
 		}
 	}
@@ -191,7 +186,7 @@ func fetchBlock(config *conf.Config) {
 
 func fetchAptosEvents(handle crosschainlisten.ChainHandle, dao crosschaindao.CrossChainDao, sourceSeq, dstSeq uint64, save bool) error {
 	if aptos, ok := handle.(*aptoslisten.AptosChainListen); ok {
-		wrapperTransactions, srcTransactions, polyTransactions, dstTransactions, _, _, err := aptos.HandleEvent(nil, sourceSeq, dstSeq, 1)
+		wrapperTransactions, srcTransactions, polyTransactions, dstTransactions, _, _, err := aptos.HandleEvent(sourceSeq, dstSeq, 1)
 		if err != nil {
 			logs.Error("aptos HandleEvent", "err", err)
 			return err
@@ -222,13 +217,8 @@ func fetchAptosEvents(handle crosschainlisten.ChainHandle, dao crosschaindao.Cro
 }
 
 func initcoinmarketid(config *conf.Config) {
-	Logger := logger.Default
 	dbCfg := config.DBConfig
-	if dbCfg.Debug == true {
-		Logger = Logger.LogMode(logger.Info)
-	}
-	db, err := gorm.Open(mysql.Open(dbCfg.User+":"+dbCfg.Password+"@tcp("+dbCfg.URL+")/"+
-		dbCfg.Scheme+"?charset=utf8"), &gorm.Config{Logger: Logger})
+	db, err := database.Open(dbCfg)
 
 	var coinmarketsdk *coinmarketcap.CoinMarketCapSdk
 	for _, coinconfig := range config.CoinPriceListenConfig {
@@ -265,23 +255,6 @@ func initcoinmarketid(config *conf.Config) {
 	}
 }
 
-func migrateLockTokenStatisticTable(config *conf.Config) {
-	Logger := logger.Default
-	dbCfg := config.DBConfig
-	if dbCfg.Debug == true {
-		Logger = Logger.LogMode(logger.Info)
-	}
-	db, err := gorm.Open(mysql.Open(dbCfg.User+":"+dbCfg.Password+"@tcp("+dbCfg.URL+")/"+
-		dbCfg.Scheme+"?charset=utf8"), &gorm.Config{Logger: Logger})
-	if err != nil {
-		logs.Error("Open mysql err", err)
-	}
-	err = db.Debug().AutoMigrate(
-		&models.LockTokenStatistic{},
-	)
-	checkError(err, "Creating tables")
-}
-
 func updateZilliqaPolyOldData(config *conf.Config) {
 	tt, err := strconv.ParseInt(os.Getenv("END_TIME"), 10, 64)
 	if err != nil {
@@ -297,8 +270,7 @@ func updateZilliqaPolyOldData(config *conf.Config) {
 	if dbCfg.Debug == true {
 		Logger = Logger.LogMode(logger.Warn)
 	}
-	db, err := gorm.Open(mysql.Open(dbCfg.User+":"+dbCfg.Password+"@tcp("+dbCfg.URL+")/"+
-		dbCfg.Scheme+"?charset=utf8"), &gorm.Config{Logger: Logger})
+	db, err := database.OpenWithLogger(dbCfg, Logger)
 	if err != nil {
 		panic(fmt.Sprintf("db err,%v", err))
 	}
@@ -342,137 +314,3 @@ func updateZilliqaPolyOldData(config *conf.Config) {
 		}
 	}
 }
-
-func updateRippleTables(config *conf.Config) {
-	Logger := logger.Default
-	dbCfg := config.DBConfig
-	if dbCfg.Debug == true {
-		Logger = Logger.LogMode(logger.Info)
-	}
-	db, err := gorm.Open(mysql.Open(dbCfg.User+":"+dbCfg.Password+"@tcp("+dbCfg.URL+")/"+
-		dbCfg.Scheme+"?charset=utf8"), &gorm.Config{Logger: Logger})
-	if err != nil {
-		logs.Error("updateRippleTables: Open mysql err", err)
-		return
-	}
-	{ //PolyTransaction dst_sequence
-		var num int
-		db.Raw("select count(*) from information_schema.columns where table_name = ? and column_name = ?", "poly_transactions", "dst_sequence").Scan(&num)
-		fmt.Println("num", num)
-
-		err = db.Debug().Migrator().AddColumn(&models.PolyTransaction{}, "dst_sequence")
-		if err != nil {
-			logs.Error("table PolyTransaction AddColumn dst_sequence err", err)
-			panic("table PolyTransaction AddColumn dst_sequence err")
-		}
-	}
-	{ //DstTransaction sequence
-		var num int
-		res := db.Debug().Raw("select count(*) from information_schema.columns where table_name = ? and column_name = ?", "dst_transactions", "sequence").Scan(&num)
-		if res.Error != nil {
-			panic("dst_transactions Raw num err")
-		}
-		if num == 0 {
-			logs.Info("dst_transactions not exist sequence")
-			err = db.Debug().Migrator().AddColumn(&models.DstTransaction{}, "sequence")
-			if err != nil {
-				logs.Error("table DstTransaction AddColumn sequence err", err)
-				panic("table DstTransaction AddColumn sequence err")
-			}
-		}
-	}
-	{ //wrapper_details
-		res := db.Debug().Migrator().HasTable(&models.WrapperDetail{})
-		if !res {
-			logs.Info("wrapper_details not exist")
-			err := db.Debug().AutoMigrate(&models.WrapperDetail{})
-			if err != nil {
-				logs.Error("table WrapperDetail AutoMigrate err", err)
-				panic("table WrapperDetail AutoMigrate err")
-			}
-		}
-	}
-	{ //poly_details
-		res := db.Debug().Migrator().HasTable(&models.PolyDetail{})
-		if !res {
-			logs.Info("poly_details not exist")
-			err := db.Debug().AutoMigrate(&models.PolyDetail{})
-			if err != nil {
-				logs.Error("table PolyDetail AutoMigrate err", err)
-				panic("table PolyDetail AutoMigrate err")
-			}
-		}
-	}
-}
-
-type wrapperUsers struct {
-	Id      int
-	Ids     int
-	User    string
-	DstUser string
-	Userf   string
-	Usert   string
-}
-
-func updateNeo3WrapperTransactions(config *conf.Config) {
-	Id, err := strconv.ParseInt(os.Getenv("END_wrapper_id"), 10, 64)
-	if err != nil {
-		panic(fmt.Sprintf("ParseInt err,%v", err))
-	}
-	chainId, err := strconv.Atoi(os.Getenv("CHAINID"))
-	if err != nil {
-		panic(fmt.Sprintf("ParseInt err,%v", err))
-	}
-	Logger := logger.Default
-	dbCfg := config.DBConfig
-	if dbCfg.Debug == true {
-		Logger = Logger.LogMode(logger.Warn)
-	}
-	db, err := gorm.Open(mysql.Open(dbCfg.User+":"+dbCfg.Password+"@tcp("+dbCfg.URL+")/"+
-		dbCfg.Scheme+"?charset=utf8"), &gorm.Config{Logger: Logger})
-	if err != nil {
-		panic(fmt.Sprintf("db err,%v", err))
-	}
-	srcs := make([]*wrapperUsers, 0)
-	err = db.Raw("select a.id,a.`user`, a.dst_user,b.id  AS ids ,b.`from` AS userf, b.dst_user AS usert from wrapper_transactions a INNER JOIN src_transfers b ON a.hash = b.tx_hash  where  a.id < ? and a.`src_chain_id` = ?", Id, chainId).
-		Find(&srcs).Error
-	if err != nil {
-		panic(fmt.Sprintf("Find srcs err,%v", err))
-	}
-	if len(srcs) > 0 {
-		for _, v := range srcs {
-			if v.Userf == v.User && v.Usert == v.DstUser {
-				continue
-			}
-			if v.Userf == basedef.HexStringReverse(v.User) && v.Usert == basedef.HexStringReverse(v.DstUser) {
-				err = db.Exec("update wrapper_transactions set `user`=?, dst_user=? where id=?", v.Userf, v.Usert, v.Id).
-					Error
-				if err != nil {
-					panic(fmt.Sprintf("update wrapper_transaction err,%v", err))
-				} else {
-					logs.Info("success update wrapper tx id: ", v.Id)
-				}
-			} else if v.Usert == "" {
-				err = db.Exec("update wrapper_transactions set `user`=?, dst_user=? where id=?", v.Userf, basedef.HexStringReverse(v.DstUser), v.Id).
-					Error
-				if err != nil {
-					panic(fmt.Sprintf("update wrapper_transaction err,%v", err))
-				} else {
-					logs.Info("success update wrapper tx id: ", v.Id)
-				}
-				err = db.Exec("update src_transfers set dst_user=? where id=?", basedef.HexStringReverse(v.DstUser), v.Ids).
-					Error
-				if err != nil {
-					panic(fmt.Sprintf("update wrapper_transaction err,%v", err))
-				} else {
-					logs.Info("success update src transfer tx id: ", v.Id)
-				}
-			} else {
-				panic(fmt.Sprintf("invalid tx err,%v, wrapper tx id, %v ", err, v.Id))
-			}
-		}
-	} else {
-		panic(fmt.Sprintf("no tx found"))
-	}
-
-}
@@ -0,0 +1,107 @@
+/*
+ * Copyright (C) 2020 The poly network Authors
+ * This file is part of The poly network library.
+ *
+ * The  poly network  is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU Lesser General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * The  poly network  is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU Lesser General Public License for more details.
+ * You should have received a copy of the GNU Lesser General Public License
+ * along with The poly network .  If not, see <http://www.gnu.org/licenses/>.
+ */
+
+package main
+
+import (
+	"fmt"
+	"poly-bridge/conf"
+	"poly-bridge/migration"
+	"poly-bridge/utils/database"
+	"time"
+
+	"github.com/urfave/cli"
+	"gorm.io/gorm"
+)
+
+var (
+	migrateTargetFlag = cli.Int64Flag{
+		Name:  "to",
+		Usage: "apply the migrations up to version `<version>`, 0 for all of them",
+		Value: 0,
+	}
+	migrateStepsFlag = cli.IntFlag{
+		Name:  "steps",
+		Usage: "revert the latest `<steps>` applied migrations",
+		Value: 1,
+	}
+
+	migrateCommand = cli.Command{
+		Name:  "migrate",
+		Usage: "manage the schema migrations of the bridge database configured by -cliconfig",
+		Subcommands: []cli.Command{
+			{
+				Name:   "status",
+				Usage:  "list the migrations and whether they are applied",
+				Action: migrateStatus,
+			},
+			{
+				Name:   "up",
+				Usage:  "apply the pending migrations",
+				Flags:  []cli.Flag{migrateTargetFlag},
+				Action: migrateUp,
+			},
+			{
+				Name:   "down",
+				Usage:  "revert the latest applied migrations",
+				Flags:  []cli.Flag{migrateStepsFlag},
+				Action: migrateDown,
+			},
+		},
+	}
+)
+
+func migrateDB(ctx *cli.Context) *gorm.DB {
+	configFile := ctx.GlobalString(getFlagName(configPathFlag))
+	config := conf.NewConfig(configFile)
+	if config == nil {
+		panic("read config failed")
+	}
+	db, err := database.Open(config.DBConfig)
+	checkError(err, "Opening database")
+	return db
+}
+
+func migrateStatus(ctx *cli.Context) {
+	status, err := migration.Status(migrateDB(ctx))
+	checkError(err, "Getting migration status")
+	for _, item := range status {
+		appliedAt := "pending"
+		if item.Applied {
+			appliedAt = "applied at " + time.Unix(item.AppliedAt, 0).Format(time.RFC3339)
+		}
+		fmt.Printf("%d %s %s\n", item.Version, item.Name, appliedAt)
+	}
+}
+
+func migrateUp(ctx *cli.Context) {
+	done, err := migration.Up(migrateDB(ctx), ctx.Int64(getFlagName(migrateTargetFlag)))
+	printMigrations("applied", done)
+	checkError(err, "Applying migrations")
+}
+
+func migrateDown(ctx *cli.Context) {
+	done, err := migration.Down(migrateDB(ctx), ctx.Int(getFlagName(migrateStepsFlag)))
+	printMigrations("reverted", done)
+	checkError(err, "Reverting migrations")
+}
+
+func printMigrations(action string, migrations []*migration.Migration) {
+	for _, item := range migrations {
+		fmt.Printf("%s %d %s\n", action, item.Version, item.Name)
+	}
+}
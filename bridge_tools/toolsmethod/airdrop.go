@@ -2,22 +2,15 @@ package toolsmethod
 
 import (
 	"fmt"
-	"gorm.io/driver/mysql"
-	"gorm.io/gorm"
-	"gorm.io/gorm/logger"
 	"poly-bridge/conf"
 	"poly-bridge/models"
+	"poly-bridge/utils/database"
 	"time"
 )
 
 func AirDrop(cfg *conf.Config) {
-	Logger := logger.Default
 	dbCfg := cfg.DBConfig
-	if dbCfg.Debug == true {
-		Logger = Logger.LogMode(logger.Info)
-	}
-	db, err := gorm.Open(mysql.Open(dbCfg.User+":"+dbCfg.Password+"@tcp("+dbCfg.URL+")/"+
-		dbCfg.Scheme+"?charset=utf8"), &gorm.Config{Logger: Logger})
+	db, err := database.Open(dbCfg)
 	if err != nil {
 		panic(fmt.Sprintf("database err", err))
 	}
@@ -64,13 +57,8 @@ func AirDrop(cfg *conf.Config) {
 }
 
 func UpdateAirDropAmount(cfg *conf.Config) {
-	Logger := logger.Default
 	dbCfg := cfg.DBConfig
-	if dbCfg.Debug == true {
-		Logger = Logger.LogMode(logger.Info)
-	}
-	db, err := gorm.Open(mysql.Open(dbCfg.User+":"+dbCfg.Password+"@tcp("+dbCfg.URL+")/"+
-		dbCfg.Scheme+"?charset=utf8"), &gorm.Config{Logger: Logger})
+	db, err := database.Open(dbCfg)
 	if err != nil {
 		panic(fmt.Sprintf("database err", err))
 	}
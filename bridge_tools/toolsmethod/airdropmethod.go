@@ -2,11 +2,9 @@ package toolsmethod
 
 import (
 	"fmt"
-	"gorm.io/driver/mysql"
-	"gorm.io/gorm"
-	"gorm.io/gorm/logger"
 	"os"
 	"poly-bridge/conf"
+	"poly-bridge/utils/database"
 	"strconv"
 )
 
@@ -28,14 +26,9 @@ func AirDropNft(cfg *conf.Config) {
 	if runflag == "" {
 		panic(fmt.Sprintf("runflag is null "))
 	}
-	Logger := logger.Default
 	dbCfg := cfg.DBConfig
-	if dbCfg.Debug == true {
-		Logger = Logger.LogMode(logger.Info)
-	}
 
-	db, err := gorm.Open(mysql.Open(dbCfg.User+":"+dbCfg.Password+"@tcp("+dbCfg.URL+")/"+
-		dbCfg.Scheme+"?charset=utf8"), &gorm.Config{Logger: Logger})
+	db, err := database.Open(dbCfg)
 	if err != nil {
 		panic(fmt.Sprintf("database err", err))
 	}
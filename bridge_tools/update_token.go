@@ -19,26 +19,19 @@ package main
 
 import (
 	"fmt"
-	"gorm.io/driver/mysql"
-	"gorm.io/gorm"
-	"gorm.io/gorm/logger"
 	"poly-bridge/bridge_tools/conf"
 	"poly-bridge/cacheRedis"
 	serverconf "poly-bridge/conf"
 	"poly-bridge/crosschaindao"
 	"poly-bridge/models"
+	"poly-bridge/utils/database"
 	"strings"
 	"time"
 )
 
 func startUpdateToken(cfg *conf.DeployConfig, servercfg *serverconf.Config) {
 	dbCfg := cfg.DBConfig
-	Logger := logger.Default
-	if dbCfg.Debug == true {
-		Logger = Logger.LogMode(logger.Info)
-	}
-	db, err := gorm.Open(mysql.Open(dbCfg.User+":"+dbCfg.Password+"@tcp("+dbCfg.URL+")/"+
-		dbCfg.Scheme+"?charset=utf8"), &gorm.Config{Logger: Logger})
+	db, err := database.Open(dbCfg)
 	if err != nil {
 		panic(err)
 	}
@@ -68,16 +61,16 @@ func startUpdateToken(cfg *conf.DeployConfig, servercfg *serverconf.Config) {
 	dao.AddChains(cfg.Chains, cfg.ChainFees)
 }
 
-func SetDyingToken(tokenBasicName string, proxyFee int) {
-	if ok, err := cacheRedis.Redis.Set(cacheRedis.MarkTokenAsDying+tokenBasicName, proxyFee, 24*time.Hour); err == nil && ok {
+func SetDyingToken(cache cacheRedis.Cache, tokenBasicName string, proxyFee int) {
+	if ok, err := cache.Set(cacheRedis.MarkTokenAsDying+tokenBasicName, proxyFee, 24*time.Hour); err == nil && ok {
 		fmt.Printf("set dying token successfully, %v : %v", tokenBasicName, proxyFee)
 	} else {
 		panic(err)
 	}
 }
 
-func RemoveDyingToken(tokenBasicName string) {
-	if _, err := cacheRedis.Redis.Del(cacheRedis.MarkTokenAsDying + tokenBasicName); err == nil {
+func RemoveDyingToken(cache cacheRedis.Cache, tokenBasicName string) {
+	if _, err := cache.Del(cacheRedis.MarkTokenAsDying + tokenBasicName); err == nil {
 		fmt.Printf("remove dying token successfully, %v ", tokenBasicName)
 	} else {
 		panic(err)
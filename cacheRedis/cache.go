@@ -0,0 +1,189 @@
+package cacheRedis
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math/big"
+	"poly-bridge/conf"
+	"poly-bridge/models"
+	"strconv"
+	"time"
+)
+
+// ErrNotFound is returned by Get for a missing or expired key
+var ErrNotFound = errors.New("cache key not found")
+
+// Cache is the key value store of the servers, RedisCache shares it between the instances and MemoryCache keeps it
+// in the process for the single node deployments and the tests.
+type Cache interface {
+	Get(key string) (string, error)
+	// Set sets key to value, it never expires if expiration is 0
+	Set(key string, value interface{}, expiration time.Duration) (bool, error)
+	Del(key string) (int64, error)
+	Unlink(key string) (int64, error)
+	Exists(key string) (bool, error)
+	Expire(key string, expiration time.Duration) (bool, error)
+	// Lock sets key to value only if it is not set, it returns false if the lock is held by another owner.
+	// The lock is released by UnLock or when it expires.
+	Lock(key string, value interface{}, expiration time.Duration) (bool, error)
+	// UnLock releases the lock of key only if it is still held by the owner value, it returns 0 otherwise
+	UnLock(key string, value interface{}) (int64, error)
+	RPush(key string, value ...interface{}) error
+	// LRange returns the elements of the list from start to stop included, the negative indexes count from the end
+	LRange(key string, start, stop int64) ([]string, error)
+	Publish(channel string, message interface{}) error
+	Subscribe(channels ...string) Subscription
+	// Close releases the connections of the cache, it is not used after
+	Close() error
+}
+
+// Message is a message published to a channel
+type Message struct {
+	Channel string
+	Payload string
+}
+
+// Subscription receives the messages of the subscribed channels until it is closed
+type Subscription interface {
+	Channel() <-chan *Message
+	Close() error
+}
+
+// NewCache connects to the redis of redisConfig, the cache is kept in the process if no redis address is configured
+func NewCache(redisConfig *conf.RedisConfig) (Cache, error) {
+	if redisConfig == nil || redisConfig.Addr == "" {
+		return NewMemoryCache(), nil
+	}
+	return GetRedisClient(redisConfig)
+}
+
+func SetCrossTxCounter(cache Cache, counter int64, expiration time.Duration) (err error) {
+	if _, err = cache.Set(_CrossTxCounter, counter, expiration); err != nil {
+		err = errors.New(err.Error() + "add SetCrossTxCounter")
+	}
+	return
+}
+
+func GetCrossTxCounter(cache Cache) (counter int64, err error) {
+	resp, err := cache.Get(_CrossTxCounter)
+	if err != nil {
+		err = errors.New(err.Error() + "cache GetCrossTxCounter")
+		return
+	}
+	count, err := strconv.Atoi(resp)
+	counter = int64(count)
+	if err != nil {
+		err = errors.New(err.Error() + "cache GetCrossTxCounter Atoi")
+	}
+	return
+}
+
+func SetAllTransferResp(cache Cache, resp *models.AllTransferStatisticResp) (err error) {
+	jsons, err := json.Marshal(resp)
+	if err != nil {
+		return
+	}
+	if _, err = cache.Set(_TransferStatisticResp, string(jsons), time.Second*60); err != nil {
+		err = errors.New(err.Error() + "add SetAllTransferResp")
+	}
+	return
+}
+
+func GetAllTransferResp(cache Cache) (*models.AllTransferStatisticResp, error) {
+	jsons, err := cache.Get(_TransferStatisticResp)
+	if err != nil {
+		err = errors.New(err.Error() + "cache GetAllTransferResp")
+		return nil, err
+	}
+	resp := new(models.AllTransferStatisticResp)
+	err = json.Unmarshal([]byte(jsons), resp)
+	if err != nil {
+		err = errors.New(err.Error() + "cache GetAllTransferResp")
+		return nil, err
+	}
+	return resp, nil
+}
+
+func SetTokenBalance(cache Cache, srcChainId, dstChainId uint64, dstTokenHash string, tokenBalance *big.Int) (err error) {
+	key := formatTokenBalanceKey(_ShortTokenBalance, srcChainId, dstChainId, dstTokenHash)
+	if _, err = cache.Set(key, tokenBalance.String(), time.Second*2); err != nil {
+		err = errors.New(err.Error() + "add SetTokenBalance")
+	}
+	return
+}
+
+func GetTokenBalance(cache Cache, srcChainId, dstChainId uint64, dstTokenHash string) (*big.Int, error) {
+	key := formatTokenBalanceKey(_ShortTokenBalance, srcChainId, dstChainId, dstTokenHash)
+	resp, err := cache.Get(key)
+	if err != nil {
+		err = errors.New(err.Error() + "cache GetTokenBalance")
+		return big.NewInt(0), err
+	}
+	balance, result := new(big.Int).SetString(resp, 10)
+	if !result {
+		return big.NewInt(0), errors.New("GetTokenBalance SetString err")
+	}
+	return balance, nil
+}
+
+func SetLongTokenBalance(cache Cache, srcChainId, dstChainId uint64, dstTokenHash string, tokenBalance *big.Int) (err error) {
+	key := formatTokenBalanceKey(_LongTokenBalance, srcChainId, dstChainId, dstTokenHash)
+	if _, err = cache.Set(key, tokenBalance.String(), time.Hour*72); err != nil {
+		err = errors.New(err.Error() + "add SetLongTokenBalance")
+	}
+	return
+}
+
+func GetLongTokenBalance(cache Cache, srcChainId, dstChainId uint64, dstTokenHash string) (*big.Int, error) {
+	key := formatTokenBalanceKey(_LongTokenBalance, srcChainId, dstChainId, dstTokenHash)
+	resp, err := cache.Get(key)
+	if err != nil {
+		err = errors.New(err.Error() + "cache GetLongTokenBalance")
+		return big.NewInt(0), err
+	}
+	balance, result := new(big.Int).SetString(resp, 10)
+	if !result {
+		return big.NewInt(0), errors.New("GetLongTokenBalance SetString err")
+	}
+	return balance, nil
+}
+
+func formatTokenBalanceKey(_key string, srcChainId, dstChainId uint64, dstTokenHash string) string {
+	key := fmt.Sprintf("%s_%d_%d_%s", _key, srcChainId, dstChainId, dstTokenHash)
+	return key
+}
+
+func GetManualTx(cache Cache, polyhash string) (string, error) {
+	resp, err := cache.Get(_GetManualTxData + polyhash)
+	if err != nil {
+		err = errors.New(err.Error() + "cache GetManualTx")
+		return "", err
+	}
+	return resp, nil
+}
+
+func SetManualTx(cache Cache, polyhash string, manualTx string) (err error) {
+	if _, err = cache.Set(_GetManualTxData+polyhash, manualTx, time.Second*1); err != nil {
+		err = errors.New(err.Error() + "cache SetManualTx")
+	}
+	return
+}
+
+func SetChainTvl(cache Cache, chain uint64, amount string) (err error) {
+	key := _ChainTVLAmount + fmt.Sprintf("%v", chain)
+	if _, err = cache.Set(key, amount, time.Second*10); err != nil {
+		err = errors.New(err.Error() + "cache SetChainTvl")
+	}
+	return
+}
+
+func GetChainTvl(cache Cache, chain uint64) (amount string, err error) {
+	key := _ChainTVLAmount + fmt.Sprintf("%v", chain)
+	resp, err := cache.Get(key)
+	if err != nil {
+		err = errors.New(err.Error() + "cache GetChainTvl")
+		return "0", err
+	}
+	return resp, nil
+}
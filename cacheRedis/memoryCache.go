@@ -0,0 +1,271 @@
+package cacheRedis
+
+import (
+	"fmt"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/beego/beego/v2/core/logs"
+)
+
+const memorySubscriptionBuffer = 100
+
+// MemoryCache is the Cache kept in the process, the values are stored as strings like redis does
+type MemoryCache struct {
+	mutex         sync.Mutex
+	entries       map[string]*memoryEntry
+	subscriptions map[string]map[*memorySubscription]bool
+}
+
+type memoryEntry struct {
+	value    string
+	list     []string
+	isList   bool
+	expireAt time.Time
+}
+
+func NewMemoryCache() *MemoryCache {
+	return &MemoryCache{
+		entries:       make(map[string]*memoryEntry),
+		subscriptions: make(map[string]map[*memorySubscription]bool),
+	}
+}
+
+// entry returns the live entry of key, the expired one is removed
+func (c *MemoryCache) entry(key string) *memoryEntry {
+	entry, ok := c.entries[key]
+	if !ok {
+		return nil
+	}
+	if !entry.expireAt.IsZero() && !time.Now().Before(entry.expireAt) {
+		delete(c.entries, key)
+		return nil
+	}
+	return entry
+}
+
+func (c *MemoryCache) set(key string, value interface{}, expiration time.Duration) {
+	entry := &memoryEntry{value: format(value)}
+	if expiration > 0 {
+		entry.expireAt = time.Now().Add(expiration)
+	}
+	c.entries[key] = entry
+}
+
+func (c *MemoryCache) Get(key string) (string, error) {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	entry := c.entry(key)
+	if entry == nil {
+		return "", ErrNotFound
+	}
+	if entry.isList {
+		return "", fmt.Errorf("key %s holds a list", key)
+	}
+	return entry.value, nil
+}
+
+func (c *MemoryCache) Set(key string, value interface{}, expiration time.Duration) (bool, error) {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	c.set(key, value, expiration)
+	return true, nil
+}
+
+func (c *MemoryCache) Unlink(key string) (int64, error) {
+	return c.Del(key)
+}
+
+func (c *MemoryCache) Del(key string) (int64, error) {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	if c.entry(key) == nil {
+		return 0, nil
+	}
+	delete(c.entries, key)
+	return 1, nil
+}
+
+func (c *MemoryCache) Exists(key string) (bool, error) {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	return c.entry(key) != nil, nil
+}
+
+func (c *MemoryCache) Expire(key string, expiration time.Duration) (bool, error) {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	entry := c.entry(key)
+	if entry == nil {
+		return false, nil
+	}
+	entry.expireAt = time.Now().Add(expiration)
+	return true, nil
+}
+
+func (c *MemoryCache) Lock(key string, value interface{}, expiration time.Duration) (bool, error) {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	if c.entry(key) != nil {
+		return false, nil
+	}
+	c.set(key, value, expiration)
+	return true, nil
+}
+
+func (c *MemoryCache) UnLock(key string, value interface{}) (int64, error) {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	entry := c.entry(key)
+	if entry == nil || entry.isList || entry.value != format(value) {
+		return 0, nil
+	}
+	delete(c.entries, key)
+	return 1, nil
+}
+
+// Close closes the subscriptions, the entries are dropped with the cache
+func (c *MemoryCache) Close() error {
+	c.mutex.Lock()
+	subscriptions := make(map[*memorySubscription]bool)
+	for _, channelSubscriptions := range c.subscriptions {
+		for subscription := range channelSubscriptions {
+			subscriptions[subscription] = true
+		}
+	}
+	c.mutex.Unlock()
+	for subscription := range subscriptions {
+		subscription.Close()
+	}
+	return nil
+}
+
+func (c *MemoryCache) RPush(key string, value ...interface{}) error {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	entry := c.entry(key)
+	if entry == nil {
+		entry = &memoryEntry{isList: true}
+		c.entries[key] = entry
+	}
+	if !entry.isList {
+		return fmt.Errorf("key %s does not hold a list", key)
+	}
+	for _, v := range value {
+		entry.list = append(entry.list, format(v))
+	}
+	return nil
+}
+
+func (c *MemoryCache) LRange(key string, start, stop int64) ([]string, error) {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	entry := c.entry(key)
+	if entry == nil {
+		return []string{}, nil
+	}
+	if !entry.isList {
+		return nil, fmt.Errorf("key %s does not hold a list", key)
+	}
+	length := int64(len(entry.list))
+	if start < 0 {
+		start += length
+	}
+	if stop < 0 {
+		stop += length
+	}
+	if start < 0 {
+		start = 0
+	}
+	if stop >= length {
+		stop = length - 1
+	}
+	if start > stop {
+		return []string{}, nil
+	}
+	return append([]string{}, entry.list[start:stop+1]...), nil
+}
+
+// Publish delivers the message to the subscriptions of this process, it is dropped for a subscription whose buffer is full
+func (c *MemoryCache) Publish(channel string, message interface{}) error {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	for subscription := range c.subscriptions[channel] {
+		select {
+		case subscription.messages <- &Message{Channel: channel, Payload: format(message)}:
+		default:
+			logs.Warn("memory cache subscription of %s is full, the message is dropped", channel)
+		}
+	}
+	return nil
+}
+
+func (c *MemoryCache) Subscribe(channels ...string) Subscription {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	subscription := &memorySubscription{
+		cache:    c,
+		channels: channels,
+		messages: make(chan *Message, memorySubscriptionBuffer),
+	}
+	for _, channel := range channels {
+		if c.subscriptions[channel] == nil {
+			c.subscriptions[channel] = make(map[*memorySubscription]bool)
+		}
+		c.subscriptions[channel][subscription] = true
+	}
+	return subscription
+}
+
+type memorySubscription struct {
+	cache    *MemoryCache
+	channels []string
+	messages chan *Message
+	closed   bool
+}
+
+func (s *memorySubscription) Channel() <-chan *Message {
+	return s.messages
+}
+
+func (s *memorySubscription) Close() error {
+	s.cache.mutex.Lock()
+	defer s.cache.mutex.Unlock()
+	if s.closed {
+		return nil
+	}
+	s.closed = true
+	for _, channel := range s.channels {
+		delete(s.cache.subscriptions[channel], s)
+	}
+	close(s.messages)
+	return nil
+}
+
+// format converts the value to the string stored by redis
+func format(value interface{}) string {
+	switch v := value.(type) {
+	case string:
+		return v
+	case []byte:
+		return string(v)
+	case bool:
+		if v {
+			return "1"
+		}
+		return "0"
+	case int:
+		return strconv.Itoa(v)
+	case int64:
+		return strconv.FormatInt(v, 10)
+	case uint64:
+		return strconv.FormatUint(v, 10)
+	case float64:
+		return strconv.FormatFloat(v, 'f', -1, 64)
+	case nil:
+		return ""
+	default:
+		return fmt.Sprint(v)
+	}
+}
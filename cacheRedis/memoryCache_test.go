@@ -0,0 +1,105 @@
+package cacheRedis
+
+import (
+	"math/big"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestMemoryCacheGetSet(t *testing.T) {
+	cache := NewMemoryCache()
+	_, err := cache.Get("key")
+	assert.Equal(t, ErrNotFound, err)
+
+	_, err = cache.Set("key", 10, 0)
+	assert.NoError(t, err)
+	value, err := cache.Get("key")
+	assert.NoError(t, err)
+	assert.Equal(t, "10", value)
+
+	_, err = cache.Set("short", true, time.Millisecond*50)
+	assert.NoError(t, err)
+	existed, _ := cache.Exists("short")
+	assert.True(t, existed)
+	time.Sleep(time.Millisecond * 100)
+	existed, _ = cache.Exists("short")
+	assert.False(t, existed)
+
+	expired, _ := cache.Expire("key", time.Millisecond*50)
+	assert.True(t, expired)
+	time.Sleep(time.Millisecond * 100)
+	_, err = cache.Get("key")
+	assert.Equal(t, ErrNotFound, err)
+
+	cnt, _ := cache.Del("key")
+	assert.Equal(t, int64(0), cnt)
+}
+
+func TestMemoryCacheLock(t *testing.T) {
+	cache := NewMemoryCache()
+	locked, _ := cache.Lock("lock", "a", time.Second)
+	assert.True(t, locked)
+	locked, _ = cache.Lock("lock", "b", time.Second)
+	assert.False(t, locked, "the lock is held")
+	cnt, _ := cache.UnLock("lock", "b")
+	assert.Equal(t, int64(0), cnt, "the lock is held by another owner")
+	cnt, _ = cache.UnLock("lock", "a")
+	assert.Equal(t, int64(1), cnt)
+	locked, _ = cache.Lock("lock", "b", time.Millisecond*50)
+	assert.True(t, locked)
+	time.Sleep(time.Millisecond * 100)
+	locked, _ = cache.Lock("lock", "c", time.Second)
+	assert.True(t, locked, "the lock expired")
+}
+
+func TestMemoryCacheList(t *testing.T) {
+	cache := NewMemoryCache()
+	assert.NoError(t, cache.RPush("list", "a", 1, uint64(2)))
+	assert.NoError(t, cache.RPush("list", "b"))
+	values, err := cache.LRange("list", 0, -1)
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"a", "1", "2", "b"}, values)
+	values, _ = cache.LRange("list", -2, 10)
+	assert.Equal(t, []string{"2", "b"}, values)
+	values, _ = cache.LRange("list", 3, 1)
+	assert.Empty(t, values)
+	values, _ = cache.LRange("missing", 0, -1)
+	assert.Empty(t, values)
+
+	cache.Set("key", "value", 0)
+	assert.Error(t, cache.RPush("key", "a"))
+	_, err = cache.Get("list")
+	assert.Error(t, err)
+}
+
+func TestMemoryCachePubSub(t *testing.T) {
+	cache := NewMemoryCache()
+	subscription := cache.Subscribe("channel")
+	assert.NoError(t, cache.Publish("other", "dropped"))
+	assert.NoError(t, cache.Publish("channel", "message"))
+	select {
+	case message := <-subscription.Channel():
+		assert.Equal(t, &Message{Channel: "channel", Payload: "message"}, message)
+	case <-time.After(time.Second):
+		t.Fatal("no message received")
+	}
+	assert.NoError(t, subscription.Close())
+	_, ok := <-subscription.Channel()
+	assert.False(t, ok, "the channel is closed")
+	assert.NoError(t, cache.Publish("channel", "message"))
+	assert.NoError(t, subscription.Close())
+}
+
+func TestTokenBalance(t *testing.T) {
+	cache := NewMemoryCache()
+	_, err := GetTokenBalance(cache, 2, 6, "hash")
+	assert.Error(t, err)
+	assert.NoError(t, SetTokenBalance(cache, 2, 6, "hash", big.NewInt(100)))
+	balance, err := GetTokenBalance(cache, 2, 6, "hash")
+	assert.NoError(t, err)
+	assert.Equal(t, int64(100), balance.Int64())
+	_, err = GetLongTokenBalance(cache, 2, 6, "hash")
+	assert.Error(t, err)
+}
@@ -1,16 +1,10 @@
 package cacheRedis
 
 import (
-	"encoding/json"
 	"errors"
-	"fmt"
 	"github.com/beego/beego/v2/core/logs"
 	goredis "github.com/go-redis/redis"
-	"math/big"
 	"poly-bridge/conf"
-	"poly-bridge/models"
-	"strconv"
-	"sync"
 	"time"
 )
 
@@ -36,25 +30,15 @@ const (
 	RelayerAccountStatusAlarmPrefix = "RelayerAccountStatusAlarmPrefix_"
 	_ChainTVLAmount                 = "ChainTVLAmount_"
 	MarkTokenAsDying                = "MarkTokenAsDying_"
+	TxStatusChannel                 = "TxStatusChannel"
 )
 
+// RedisCache is the Cache kept in redis
 type RedisCache struct {
 	c      *goredis.Client
 	config *conf.RedisConfig
 }
 
-var Redis *RedisCache
-var mutex sync.Mutex
-
-func Init() {
-	redisConfig := conf.GlobalConfig.RedisConfig
-	redisCache, err := GetRedisClient(redisConfig)
-	if err != nil {
-		panic("redis Init panic")
-	}
-	Redis = redisCache
-}
-
 func GetRedisClient(redisConfig *conf.RedisConfig) (*RedisCache, error) {
 	if redisConfig.DialTimeout <= 0 || redisConfig.ReadTimeout <= 0 || redisConfig.WriteTimeout <= 0 {
 		return &RedisCache{
@@ -78,100 +62,11 @@ func GetRedisClient(redisConfig *conf.RedisConfig) (*RedisCache, error) {
 	}, nil
 }
 
-func (r *RedisCache) SetCrossTxCounter(counter int64) (err error) {
-	key := _CrossTxCounter
-	if _, err = r.c.Set(key, counter, r.config.Expiration*time.Second).Result(); err != nil {
-		err = errors.New(err.Error() + "add SetCrossTxCounter")
-	}
-	return
-}
-func (r *RedisCache) GetCrossTxCounter() (counter int64, err error) {
-	key := _CrossTxCounter
-	resp, err := r.c.Get(key).Result()
-	if err != nil {
-		err = errors.New(err.Error() + "cache GetCrossTxCounter")
-		return
-	}
-	count, err := strconv.Atoi(resp)
-	counter = int64(count)
-	if err != nil {
-		err = errors.New(err.Error() + "cache GetCrossTxCounter Atoi")
-	}
-	return
-}
-
-func (r *RedisCache) SetAllTransferResp(resp *models.AllTransferStatisticResp) (err error) {
-	key := _TransferStatisticResp
-	jsons, err := json.Marshal(resp)
-	if _, err = r.c.Set(key, string(jsons), time.Second*60).Result(); err != nil {
-		err = errors.New(err.Error() + "add SetAllTransferResp")
-	}
-	return
-}
-func (r *RedisCache) GetAllTransferResp() (*models.AllTransferStatisticResp, error) {
-	key := _TransferStatisticResp
-	jsons, err := r.c.Get(key).Result()
-	if err != nil {
-		err = errors.New(err.Error() + "cache GetAllTransferResp")
-		return nil, err
-	}
-	resp := new(models.AllTransferStatisticResp)
-	err = json.Unmarshal([]byte(jsons), resp)
-	if err != nil {
-		err = errors.New(err.Error() + "cache GetAllTransferResp")
-		return nil, err
-	}
-	return resp, nil
-}
-func (r *RedisCache) SetTokenBalance(srcChainId, dstChainId uint64, dstTokenHash string, tokenBalance *big.Int) (err error) {
-	key := formatTokenBalanceKey(_ShortTokenBalance, srcChainId, dstChainId, dstTokenHash)
-	value := tokenBalance.String()
-	if _, err = r.c.Set(key, value, time.Second*2).Result(); err != nil {
-		err = errors.New(err.Error() + "add SetTokenBalance")
-	}
-	return
-}
-func (r *RedisCache) GetTokenBalance(srcChainId, dstChainId uint64, dstTokenHash string) (*big.Int, error) {
-	key := formatTokenBalanceKey(_ShortTokenBalance, srcChainId, dstChainId, dstTokenHash)
-	resp, err := r.c.Get(key).Result()
-	if err != nil {
-		err = errors.New(err.Error() + "cache GetTokenBalance")
-		return big.NewInt(0), err
-	}
-	balance, result := new(big.Int).SetString(resp, 10)
-	if !result {
-		return big.NewInt(0), errors.New("GetTokenBalance SetString err")
-	}
-	return balance, nil
-}
-func (r *RedisCache) SetLongTokenBalance(srcChainId, dstChainId uint64, dstTokenHash string, tokenBalance *big.Int) (err error) {
-	key := formatTokenBalanceKey(_LongTokenBalance, srcChainId, dstChainId, dstTokenHash)
-	value := tokenBalance.String()
-	if _, err = r.c.Set(key, value, time.Hour*72).Result(); err != nil {
-		err = errors.New(err.Error() + "add SetLongTokenBalance")
-	}
-	return
-}
-func (r *RedisCache) GetLongTokenBalance(srcChainId, dstChainId uint64, dstTokenHash string) (*big.Int, error) {
-	key := formatTokenBalanceKey(_LongTokenBalance, srcChainId, dstChainId, dstTokenHash)
-	resp, err := r.c.Get(key).Result()
-	if err != nil {
-		err = errors.New(err.Error() + "cache GetLongTokenBalance")
-		return big.NewInt(0), err
-	}
-	balance, result := new(big.Int).SetString(resp, 10)
-	if !result {
-		return big.NewInt(0), errors.New("GetLongTokenBalance SetString err")
-	}
-	return balance, nil
-}
-func formatTokenBalanceKey(_key string, srcChainId, dstChainId uint64, dstTokenHash string) string {
-	key := fmt.Sprintf("%s_%d_%d_%s", _key, srcChainId, dstChainId, dstTokenHash)
-	return key
-}
-
 func (r *RedisCache) Get(key string) (string, error) {
 	res, err := r.c.Get(key).Result()
+	if err == goredis.Nil {
+		return "", ErrNotFound
+	}
 	if err != nil {
 		logs.Error("Get key %s err: %s", key, err)
 		return "", err
@@ -227,8 +122,6 @@ func (r *RedisCache) Expire(key string, expiration time.Duration) (bool, error)
 }
 
 func (r *RedisCache) Lock(key string, value interface{}, expiration time.Duration) (bool, error) {
-	mutex.Lock()
-	defer mutex.Unlock()
 	isSet, err := r.c.SetNX(key, value, expiration).Result()
 	if err != nil {
 		logs.Error("Lock err:%s", err)
@@ -237,32 +130,24 @@ func (r *RedisCache) Lock(key string, value interface{}, expiration time.Duratio
 	return isSet, nil
 }
 
-func (r *RedisCache) UnLock(key string) (int64, error) {
-	mutex.Lock()
-	defer mutex.Unlock()
-	cnt, err := r.c.Del(key).Result()
+// unlockScript deletes the lock only if it still has the value of its owner, so that a lock expired and taken by
+// another owner is not released
+var unlockScript = goredis.NewScript(`
+if redis.call("get", KEYS[1]) == ARGV[1] then
+	return redis.call("del", KEYS[1])
+end
+return 0`)
+
+func (r *RedisCache) UnLock(key string, value interface{}) (int64, error) {
+	cnt, err := unlockScript.Run(r.c, []string{key}, value).Int64()
 	if err != nil {
 		logs.Error("UnLock err:%s", err)
 		return 0, err
 	}
 	return cnt, nil
 }
-func (r *RedisCache) GetManualTx(polyhash string) (string, error) {
-	key := _GetManualTxData + polyhash
-	resp, err := r.c.Get(key).Result()
-	if err != nil {
-		err = errors.New(err.Error() + "cache GetManualTx")
-		return "", err
-	}
-	return resp, nil
-}
-func (r *RedisCache) SetManualTx(polyhash string, manualTx string) (err error) {
-	key := _GetManualTxData + polyhash
-	value := manualTx
-	if _, err = r.c.Set(key, value, time.Second*1).Result(); err != nil {
-		err = errors.New(err.Error() + "cache SetManualTx")
-	}
-	return
+func (r *RedisCache) Close() error {
+	return r.c.Close()
 }
 
 func (r *RedisCache) RPush(key string, value ...interface{}) error {
@@ -282,21 +167,43 @@ func (r *RedisCache) LRange(key string, start, stop int64) ([]string, error) {
 	}
 }
 
-func (r *RedisCache) SetChainTvl(chain uint64, amount string) (err error) {
-	key := _ChainTVLAmount + fmt.Sprintf("%v", chain)
-	value := amount
-	if _, err = r.c.Set(key, value, time.Second*10).Result(); err != nil {
-		err = errors.New(err.Error() + "cache SetChainTvl")
+func (r *RedisCache) XAdd(stream string, maxLen int64, values map[string]interface{}) error {
+	if _, err := r.c.XAdd(&goredis.XAddArgs{Stream: stream, MaxLenApprox: maxLen, Values: values}).Result(); err != nil {
+		logs.Error("Redis XAdd[stream:%s] err: %s", stream, err)
+		return err
 	}
-	return
+	return nil
 }
 
-func (r *RedisCache) GetChainTvl(chain uint64) (amount string, err error) {
-	key := _ChainTVLAmount + fmt.Sprintf("%v", chain)
-	resp, err := r.c.Get(key).Result()
-	if err != nil {
-		err = errors.New(err.Error() + "cache GetChainTvl")
-		return "0", err
+func (r *RedisCache) Publish(channel string, message interface{}) error {
+	if _, err := r.c.Publish(channel, message).Result(); err != nil {
+		logs.Error("Redis Publish[channel:%s] err: %s", channel, err)
+		return err
 	}
-	return resp, nil
+	return nil
+}
+
+func (r *RedisCache) Subscribe(channels ...string) Subscription {
+	pubsub := r.c.Subscribe(channels...)
+	messages := make(chan *Message)
+	go func() {
+		defer close(messages)
+		for message := range pubsub.Channel() {
+			messages <- &Message{Channel: message.Channel, Payload: message.Payload}
+		}
+	}()
+	return &redisSubscription{pubsub: pubsub, messages: messages}
+}
+
+type redisSubscription struct {
+	pubsub   *goredis.PubSub
+	messages chan *Message
+}
+
+func (s *redisSubscription) Channel() <-chan *Message {
+	return s.messages
+}
+
+func (s *redisSubscription) Close() error {
+	return s.pubsub.Close()
 }
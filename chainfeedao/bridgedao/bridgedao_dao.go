@@ -20,13 +20,12 @@ package bridgedao
 import (
 	"fmt"
 	"github.com/polynetwork/bridge-common/metrics"
-	"gorm.io/driver/mysql"
 	"gorm.io/gorm"
-	"gorm.io/gorm/logger"
 	"math/big"
 	"poly-bridge/basedef"
 	"poly-bridge/conf"
 	"poly-bridge/models"
+	"poly-bridge/utils/database"
 	"poly-bridge/utils/decimal"
 )
 
@@ -39,12 +38,7 @@ func NewBridgeDao(dbCfg *conf.DBConfig) *BridgeDao {
 	swapDao := &BridgeDao{
 		dbCfg: dbCfg,
 	}
-	Logger := logger.Default
-	if dbCfg.Debug == true {
-		Logger = Logger.LogMode(logger.Info)
-	}
-	db, err := gorm.Open(mysql.Open(dbCfg.User+":"+dbCfg.Password+"@tcp("+dbCfg.URL+")/"+
-		dbCfg.Scheme+"?charset=utf8"), &gorm.Config{Logger: Logger})
+	db, err := database.Open(dbCfg)
 	if err != nil {
 		panic(err)
 	}
@@ -82,6 +76,43 @@ func (dao *BridgeDao) SaveFees(fees []*models.ChainFee) error {
 	return nil
 }
 
+func (dao *BridgeDao) SaveFeeHistory(fees []*models.ChainFee, expired int64) error {
+	history := make([]*models.ChainFeeHistory, 0, len(fees))
+	names := make([]string, 0, len(fees))
+	for _, fee := range fees {
+		if fee.Ind == 1 {
+			names = append(names, fee.TokenBasicName)
+		}
+	}
+	if len(names) > 0 {
+		tokenBasics := make([]*models.TokenBasic, 0)
+		res := dao.db.Where("name in ?", names).Find(&tokenBasics)
+		if res.Error != nil {
+			return res.Error
+		}
+		prices := make(map[string]int64, len(tokenBasics))
+		for _, tokenBasic := range tokenBasics {
+			prices[tokenBasic.Name] = tokenBasic.Price
+		}
+		for _, fee := range fees {
+			if fee.Ind == 1 {
+				history = append(history, models.NewChainFeeHistory(fee, prices[fee.TokenBasicName]))
+			}
+		}
+		res = dao.db.Create(history)
+		if res.Error != nil {
+			return res.Error
+		}
+	}
+	if expired > 0 {
+		res := dao.db.Where("time < ?", expired).Delete(&models.ChainFeeHistory{})
+		if res.Error != nil {
+			return res.Error
+		}
+	}
+	return nil
+}
+
 func (dao *BridgeDao) Name() string {
 	return basedef.SERVER_POLY_BRIDGE
 }
@@ -0,0 +1,68 @@
+package bridgedao
+
+import (
+	"poly-bridge/basedef"
+	"poly-bridge/models"
+	"poly-bridge/utils/dbtest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSaveFeeHistory(t *testing.T) {
+	dao := NewBridgeDao(dbtest.Config(t, "polyswap.db"))
+	err := dao.db.AutoMigrate(&models.TokenBasic{}, &models.ChainFeeHistory{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = dao.db.Create(&models.TokenBasic{Name: "ETH", Precision: 18, Price: 300000000000}).Error
+	if err != nil {
+		t.Fatal(err)
+	}
+	fees := []*models.ChainFee{
+		{
+			ChainId:        basedef.ETHEREUM_CROSSCHAIN_ID,
+			TokenBasicName: "ETH",
+			MaxFee:         models.NewBigIntFromInt(3),
+			MinFee:         models.NewBigIntFromInt(1),
+			ProxyFee:       models.NewBigIntFromInt(2),
+			BaseFee:        models.NewBigIntFromInt(10),
+			PriorityFee:    models.NewBigIntFromInt(1),
+			MaxFeePerGas:   models.NewBigIntFromInt(11),
+			Ind:            1,
+			Time:           1000,
+		},
+		{
+			ChainId:        basedef.BSC_CROSSCHAIN_ID,
+			TokenBasicName: "BNB",
+			MaxFee:         models.NewBigIntFromInt(3),
+			MinFee:         models.NewBigIntFromInt(1),
+			ProxyFee:       models.NewBigIntFromInt(2),
+			Time:           900,
+		},
+	}
+	assert.NoError(t, dao.SaveFeeHistory(fees, 0))
+	fees[0].Time = 2000
+	fees[0].ProxyFee = models.NewBigIntFromInt(4)
+	assert.NoError(t, dao.SaveFeeHistory(fees, 0))
+
+	history := make([]*models.ChainFeeHistory, 0)
+	dao.db.Order("time asc").Find(&history)
+	if assert.Len(t, history, 2) {
+		// the fees not updated in the round are not snapshotted
+		assert.Equal(t, basedef.ETHEREUM_CROSSCHAIN_ID, history[0].ChainId)
+		assert.Equal(t, int64(300000000000), history[0].Price)
+		assert.Equal(t, "2", history[0].ProxyFee.String())
+		assert.Equal(t, "11", history[0].MaxFeePerGas.String())
+		assert.Equal(t, "4", history[1].ProxyFee.String())
+	}
+
+	fees[0].Time = 3000
+	assert.NoError(t, dao.SaveFeeHistory(fees, 1500))
+	history = make([]*models.ChainFeeHistory, 0)
+	dao.db.Order("time asc").Find(&history)
+	if assert.Len(t, history, 2) {
+		assert.Equal(t, int64(2000), history[0].Time)
+		assert.Equal(t, int64(3000), history[1].Time)
+	}
+}
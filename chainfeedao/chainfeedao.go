@@ -29,6 +29,8 @@ import (
 type ChainFeeDao interface {
 	GetFees() ([]*models.ChainFee, error)
 	SaveFees(fees []*models.ChainFee) error
+	// SaveFeeHistory appends the updated fees to chain_fee_history and deletes the snapshots taken before expired, if not 0
+	SaveFeeHistory(fees []*models.ChainFee, expired int64) error
 	Name() string
 }
 
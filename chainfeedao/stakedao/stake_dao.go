@@ -51,6 +51,10 @@ func (dao *StakeDao) SaveFees(fees []*models.ChainFee) error {
 	return nil
 }
 
+func (dao *StakeDao) SaveFeeHistory(fees []*models.ChainFee, expired int64) error {
+	return nil
+}
+
 func (dao *StakeDao) Name() string {
 	return basedef.SERVER_STAKE
 }
@@ -1,6 +1,7 @@
 package aptosfee
 
 import (
+	"context"
 	"math/big"
 	"poly-bridge/basedef"
 	"poly-bridge/chainsdk"
@@ -12,11 +13,11 @@ type AptosFee struct {
 	aptosSdk *chainsdk.AptosSdkPro
 }
 
-func NewAptosFee(aptosCfg *conf.FeeListenConfig, feeUpdateSlot int64) *AptosFee {
+func NewAptosFee(ctx context.Context, aptosCfg *conf.FeeListenConfig, feeUpdateSlot int64) *AptosFee {
 	aptosFee := &AptosFee{}
 	aptosFee.aptosCfg = aptosCfg
 	urls := aptosCfg.GetNodesUrl()
-	sdk := chainsdk.NewAptosSdkPro(urls, uint64(feeUpdateSlot), aptosCfg.ChainId)
+	sdk := chainsdk.NewAptosSdkProWithContext(ctx, urls, uint64(feeUpdateSlot), aptosCfg.ChainId)
 	aptosFee.aptosSdk = sdk
 	return aptosFee
 }
@@ -18,6 +18,7 @@
 package main
 
 import (
+	"context"
 	"encoding/json"
 	"fmt"
 	"github.com/beego/beego/v2/core/logs"
@@ -26,6 +27,7 @@ import (
 	"os/signal"
 	"poly-bridge/chainfeelisten"
 	"poly-bridge/conf"
+	"poly-bridge/migration"
 	"runtime"
 	"strings"
 	"syscall"
@@ -104,7 +106,10 @@ func startServer(ctx *cli.Context) {
 		conf, _ := json.Marshal(config)
 		logs.Info("%s\n", string(conf))
 	}
-	chainfeelisten.StartFeeListen(config.Server, config.FeeUpdateSlot, config.FeeListenConfig, config.DBConfig)
+	if err := migration.CheckSchema(config.DBConfig); err != nil {
+		panic(err)
+	}
+	chainfeelisten.StartFeeListen(context.Background(), config.Server, config.FeeUpdateSlot, config.FeeListenConfig, config.FeeHistoryConfig, config.DBConfig)
 }
 
 func waitSignal() os.Signal {
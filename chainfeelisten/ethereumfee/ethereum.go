@@ -18,31 +18,58 @@
 package ethereumfee
 
 import (
+	"context"
+	"fmt"
 	"math/big"
 	"poly-bridge/basedef"
 	"poly-bridge/chainsdk"
 	"poly-bridge/conf"
+	"poly-bridge/utils/fee"
+	"sort"
+
+	"github.com/beego/beego/v2/core/logs"
+)
+
+const (
+	defaultFeeHistoryBlocks      = 20
+	defaultPriorityFeePercentile = 50
 )
 
 type EthereumFee struct {
 	ethCfg *conf.FeeListenConfig
 	ethSdk *chainsdk.EthereumSdkPro
+
+	baseFee      *big.Int
+	priorityFee  *big.Int
+	maxFeePerGas *big.Int
+	l1DataFee    *big.Int
 }
 
-func NewEthereumFee(ethCfg *conf.FeeListenConfig, feeUpdateSlot int64) *EthereumFee {
+func NewEthereumFee(ctx context.Context, ethCfg *conf.FeeListenConfig, feeUpdateSlot int64) *EthereumFee {
 	ethereumFee := &EthereumFee{}
 	ethereumFee.ethCfg = ethCfg
 	//
 	urls := ethCfg.GetNodesUrl()
-	sdk := chainsdk.NewEthereumSdkPro(urls, uint64(feeUpdateSlot), ethCfg.ChainId)
+	sdk := chainsdk.NewEthereumSdkProWithContext(ctx, urls, uint64(feeUpdateSlot), ethCfg.ChainId)
 	ethereumFee.ethSdk = sdk
 	return ethereumFee
 }
 
 func (this *EthereumFee) GetFee() (*big.Int, *big.Int, *big.Int, error) {
-	gasPrice, err := this.ethSdk.SuggestGasPrice()
-	if err != nil {
-		return nil, nil, nil, err
+	this.baseFee, this.priorityFee, this.maxFeePerGas, this.l1DataFee = nil, nil, nil, nil
+	var gasPrice *big.Int
+	var err error
+	if this.ethCfg.Eip1559 {
+		gasPrice, err = this.getMaxFeePerGas()
+		if err != nil {
+			logs.Error("chain %d get max fee per gas err: %v, fall back to gas price", this.GetChainId(), err)
+		}
+	}
+	if gasPrice == nil {
+		gasPrice, err = this.ethSdk.SuggestGasPrice()
+		if err != nil {
+			return nil, nil, nil, err
+		}
 	}
 
 	// astar average price is 60 Gwei while node returns 1 Gwei
@@ -62,9 +89,88 @@ func (this *EthereumFee) GetFee() (*big.Int, *big.Int, *big.Int, error) {
 	proxyFee = new(big.Int).Div(proxyFee, new(big.Int).SetInt64(100))
 	minFee := new(big.Int).Mul(gasPrice, new(big.Int).SetInt64(this.ethCfg.MinFee))
 	minFee = new(big.Int).Div(minFee, new(big.Int).SetInt64(100))
+	if strategy, cfg := fee.GetL1FeeStrategy(this.GetChainId()); strategy != nil {
+		this.l1DataFee, err = strategy.L1DataFee(this.ethSdk, cfg)
+		if err != nil {
+			return nil, nil, nil, fmt.Errorf("get %s l1 data fee err: %v", strategy.Name(), err)
+		}
+	}
 	return minFee, gasPrice, proxyFee, nil
 }
 
+// GetL1DataFee returns the L1 data fee the last GetFee measured by the L1 fee strategy of the chain, nil if it has none
+func (this *EthereumFee) GetL1DataFee() *big.Int {
+	return this.l1DataFee
+}
+
+// GetFeeComponents returns the EIP-1559 components the last GetFee priced from, all nil if it used the gas price
+func (this *EthereumFee) GetFeeComponents() (baseFee, priorityFee, maxFeePerGas *big.Int) {
+	return this.baseFee, this.priorityFee, this.maxFeePerGas
+}
+
+// getMaxFeePerGas predicts the base fee of the next block and the priority fee from eth_feeHistory
+func (this *EthereumFee) getMaxFeePerGas() (*big.Int, error) {
+	blocks := this.ethCfg.FeeHistoryBlocks
+	if blocks == 0 {
+		blocks = defaultFeeHistoryBlocks
+	}
+	percentile := this.ethCfg.PriorityFeePercentile
+	if percentile <= 0 {
+		percentile = defaultPriorityFeePercentile
+	}
+	history, err := this.ethSdk.FeeHistory(blocks, []float64{percentile})
+	if err != nil {
+		return nil, err
+	}
+	baseFee, priorityFee, err := PredictFees(history)
+	if err != nil {
+		return nil, err
+	}
+	this.baseFee, this.priorityFee = baseFee, priorityFee
+	this.maxFeePerGas = new(big.Int).Add(baseFee, priorityFee)
+	logs.Info("chain %d predicted base fee: %s, priority fee: %s", this.GetChainId(), baseFee.String(), priorityFee.String())
+	return this.maxFeePerGas, nil
+}
+
+// PredictFees returns the base fee of the next block and the median of the priority fees at the requested percentile
+func PredictFees(history *chainsdk.FeeHistory) (baseFee, priorityFee *big.Int, err error) {
+	if history == nil || len(history.BaseFeePerGas) == 0 {
+		return nil, nil, fmt.Errorf("no base fee in fee history")
+	}
+	last := len(history.BaseFeePerGas) - 1
+	if history.BaseFeePerGas[last] == nil || history.BaseFeePerGas[last].ToInt().Sign() <= 0 {
+		return nil, nil, fmt.Errorf("chain does not have base fee")
+	}
+	if len(history.BaseFeePerGas) > len(history.GasUsedRatio) {
+		// the node gives the base fee of the next block as the extra last entry
+		baseFee = new(big.Int).Set(history.BaseFeePerGas[last].ToInt())
+	} else {
+		baseFee = NextBaseFee(history.BaseFeePerGas[last].ToInt(), history.GasUsedRatio[last])
+	}
+	rewards := make([]*big.Int, 0, len(history.Reward))
+	for _, reward := range history.Reward {
+		if len(reward) > 0 && reward[0] != nil {
+			rewards = append(rewards, reward[0].ToInt())
+		}
+	}
+	priorityFee = big.NewInt(0)
+	if len(rewards) > 0 {
+		sort.Slice(rewards, func(i, j int) bool {
+			return rewards[i].Cmp(rewards[j]) < 0
+		})
+		priorityFee = new(big.Int).Set(rewards[len(rewards)/2])
+	}
+	return baseFee, priorityFee, nil
+}
+
+// NextBaseFee follows EIP-1559, the base fee moves by up to 1/8 as the gas used is above or below half of the gas limit
+func NextBaseFee(baseFee *big.Int, gasUsedRatio float64) *big.Int {
+	const precision = 1000000
+	delta := new(big.Int).Mul(baseFee, big.NewInt(int64((gasUsedRatio-0.5)*2*precision)))
+	delta = delta.Quo(delta, big.NewInt(8*precision))
+	return new(big.Int).Add(baseFee, delta)
+}
+
 func (this *EthereumFee) GetChainId() uint64 {
 	return this.ethCfg.ChainId
 }
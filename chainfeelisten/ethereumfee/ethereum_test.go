@@ -0,0 +1,43 @@
+package ethereumfee
+
+import (
+	"math/big"
+	"poly-bridge/chainsdk"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/stretchr/testify/assert"
+)
+
+func hexBig(value int64) *hexutil.Big {
+	return (*hexutil.Big)(big.NewInt(value))
+}
+
+func TestPredictFees(t *testing.T) {
+	history := &chainsdk.FeeHistory{
+		BaseFeePerGas: []*hexutil.Big{hexBig(100), hexBig(110), hexBig(120)},
+		GasUsedRatio:  []float64{0.9, 0.8},
+		Reward:        [][]*hexutil.Big{{hexBig(5)}, {hexBig(1)}},
+	}
+	baseFee, priorityFee, err := PredictFees(history)
+	assert.NoError(t, err)
+	assert.Equal(t, int64(120), baseFee.Int64())
+	assert.Equal(t, int64(5), priorityFee.Int64())
+
+	// without the next block entry the base fee follows the gas used of the last block
+	history.BaseFeePerGas = history.BaseFeePerGas[:2]
+	history.Reward = append(history.Reward, []*hexutil.Big{hexBig(3)})
+	baseFee, priorityFee, err = PredictFees(history)
+	assert.NoError(t, err)
+	assert.Equal(t, int64(118), baseFee.Int64())
+	assert.Equal(t, int64(3), priorityFee.Int64())
+
+	_, _, err = PredictFees(&chainsdk.FeeHistory{BaseFeePerGas: []*hexutil.Big{hexBig(0)}})
+	assert.Error(t, err)
+}
+
+func TestNextBaseFee(t *testing.T) {
+	assert.Equal(t, int64(1125), NextBaseFee(big.NewInt(1000), 1).Int64())
+	assert.Equal(t, int64(1000), NextBaseFee(big.NewInt(1000), 0.5).Int64())
+	assert.Equal(t, int64(875), NextBaseFee(big.NewInt(1000), 0).Int64())
+}
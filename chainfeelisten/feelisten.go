@@ -18,12 +18,14 @@
 package chainfeelisten
 
 import (
+	"context"
 	"math/big"
 	"poly-bridge/chainfeelisten/aptosfee"
 	"poly-bridge/chainfeelisten/ripplefee"
 	"poly-bridge/chainfeelisten/starcoinfee"
 	"runtime/debug"
 	"strings"
+	"sync"
 	"time"
 
 	"poly-bridge/basedef"
@@ -41,23 +43,28 @@ import (
 )
 
 var feeListen *FeeListen
+var feeListenCancel context.CancelFunc
 var listenFeeCfgs []*conf.FeeListenConfig
 
-func StartFeeListen(server string, feeUpdateSlot int64, feeListenCfgs []*conf.FeeListenConfig, dbCfg *conf.DBConfig) {
+func StartFeeListen(ctx context.Context, server string, feeUpdateSlot int64, feeListenCfgs []*conf.FeeListenConfig, feeHistoryCfg *conf.FeeHistoryConfig, dbCfg *conf.DBConfig) {
 	dao := chainfeedao.NewChainFeeDao(server, dbCfg)
 	if dao == nil {
 		panic("server is not valid")
 	}
+	ctx, feeListenCancel = context.WithCancel(ctx)
 	chainFees := make([]ChainFee, 0)
 	for _, cfg := range feeListenCfgs {
-		chainFee := NewChainFee(cfg, feeUpdateSlot)
+		chainFee := NewChainFee(ctx, cfg, feeUpdateSlot)
 		if chainFee == nil {
 			panic("chain fee is not valid")
 		}
 		chainFees = append(chainFees, chainFee)
 	}
 	listenFeeCfgs = feeListenCfgs
-	feeListen = NewFeeListen(feeUpdateSlot, chainFees, dao)
+	feeListen = NewFeeListen(ctx, feeUpdateSlot, chainFees, dao)
+	if feeHistoryCfg != nil {
+		feeListen.feeHistoryRetention = feeHistoryCfg.Retention
+	}
 	feeListen.Start()
 }
 
@@ -65,6 +72,9 @@ func StopFeeListen() {
 	if feeListen != nil {
 		feeListen.Stop()
 	}
+	if feeListenCancel != nil {
+		feeListenCancel()
+	}
 }
 
 type ChainFee interface {
@@ -73,50 +83,77 @@ type ChainFee interface {
 	Name() string
 }
 
-func NewChainFee(cfg *conf.FeeListenConfig, feeUpdateSlot int64) ChainFee {
-	switch cfg.ChainId {
-	case basedef.ETHEREUM_CROSSCHAIN_ID, basedef.BSC_CROSSCHAIN_ID, basedef.HECO_CROSSCHAIN_ID, basedef.OK_CROSSCHAIN_ID,
-		basedef.MATIC_CROSSCHAIN_ID, basedef.PLT_CROSSCHAIN_ID, basedef.ARBITRUM_CROSSCHAIN_ID, basedef.XDAI_CROSSCHAIN_ID,
-		basedef.FANTOM_CROSSCHAIN_ID, basedef.AVAX_CROSSCHAIN_ID, basedef.OPTIMISTIC_CROSSCHAIN_ID, basedef.METIS_CROSSCHAIN_ID,
-		basedef.BOBA_CROSSCHAIN_ID, basedef.RINKEBY_CROSSCHAIN_ID, basedef.OASIS_CROSSCHAIN_ID, basedef.HARMONY_CROSSCHAIN_ID,
-		basedef.KCC_CROSSCHAIN_ID, basedef.BYTOM_CROSSCHAIN_ID, basedef.HSC_CROSSCHAIN_ID, basedef.KAVA_CROSSCHAIN_ID,
-		basedef.CUBE_CROSSCHAIN_ID, basedef.ZKSYNC_CROSSCHAIN_ID, basedef.CELO_CROSSCHAIN_ID, basedef.CLOVER_CROSSCHAIN_ID,
-		basedef.CONFLUX_CROSSCHAIN_ID, basedef.ASTAR_CROSSCHAIN_ID, basedef.BRISE_CROSSCHAIN_ID, basedef.DEXIT_CROSSCHAIN_ID,
-		basedef.CLOUDTX_CROSSCHAIN_ID:
-		return ethereumfee.NewEthereumFee(cfg, feeUpdateSlot)
-	case basedef.NEO_CROSSCHAIN_ID:
-		return neofee.NewNeoFee(cfg, feeUpdateSlot)
-	case basedef.NEO3_CROSSCHAIN_ID:
-		return neo3fee.NewNeo3Fee(cfg, feeUpdateSlot)
-	case basedef.ONT_CROSSCHAIN_ID:
-		return ontologyfee.NewOntologyFee(cfg, feeUpdateSlot)
-	case basedef.SWITCHEO_CROSSCHAIN_ID:
-		return switcheofee.NewSwitcheoFee(cfg, feeUpdateSlot)
-	case basedef.ZILLIQA_CROSSCHAIN_ID:
-		return zilliqafee.NewZilliqaFee(cfg, feeUpdateSlot)
-	case basedef.STARCOIN_CROSSCHAIN_ID:
-		return starcoinfee.NewStarcoinFee(cfg, feeUpdateSlot)
-	case basedef.RIPPLE_CROSSCHAIN_ID:
-		return ripplefee.NewRippleFee(cfg, feeUpdateSlot)
-	case basedef.APTOS_CROSSCHAIN_ID:
-		return aptosfee.NewAptosFee(cfg, feeUpdateSlot)
-	default:
+// FeeComponents is implemented by the chain fees priced from EIP-1559 base and priority fees,
+// the components of the last GetFee are saved with the chain fee
+type FeeComponents interface {
+	GetFeeComponents() (baseFee, priorityFee, maxFeePerGas *big.Int)
+}
+
+// L1DataFee is implemented by the chain fees of rollups which measure the L1 data fee with their L1 fee strategy
+type L1DataFee interface {
+	GetL1DataFee() *big.Int
+}
+
+type ChainFeeFactory func(ctx context.Context, cfg *conf.FeeListenConfig, feeUpdateSlot int64) ChainFee
+
+var chainFeeFactories = map[string]ChainFeeFactory{
+	basedef.CHAIN_FAMILY_EVM: func(ctx context.Context, cfg *conf.FeeListenConfig, feeUpdateSlot int64) ChainFee {
+		return ethereumfee.NewEthereumFee(ctx, cfg, feeUpdateSlot)
+	},
+	basedef.CHAIN_FAMILY_NEO: func(ctx context.Context, cfg *conf.FeeListenConfig, feeUpdateSlot int64) ChainFee {
+		return neofee.NewNeoFee(ctx, cfg, feeUpdateSlot)
+	},
+	basedef.CHAIN_FAMILY_NEO3: func(ctx context.Context, cfg *conf.FeeListenConfig, feeUpdateSlot int64) ChainFee {
+		return neo3fee.NewNeo3Fee(ctx, cfg, feeUpdateSlot)
+	},
+	basedef.CHAIN_FAMILY_ONTOLOGY: func(ctx context.Context, cfg *conf.FeeListenConfig, feeUpdateSlot int64) ChainFee {
+		return ontologyfee.NewOntologyFee(ctx, cfg, feeUpdateSlot)
+	},
+	basedef.CHAIN_FAMILY_SWITCHEO: func(ctx context.Context, cfg *conf.FeeListenConfig, feeUpdateSlot int64) ChainFee {
+		return switcheofee.NewSwitcheoFee(ctx, cfg, feeUpdateSlot)
+	},
+	basedef.CHAIN_FAMILY_ZILLIQA: func(ctx context.Context, cfg *conf.FeeListenConfig, feeUpdateSlot int64) ChainFee {
+		return zilliqafee.NewZilliqaFee(ctx, cfg, feeUpdateSlot)
+	},
+	basedef.CHAIN_FAMILY_STARCOIN: func(ctx context.Context, cfg *conf.FeeListenConfig, feeUpdateSlot int64) ChainFee {
+		return starcoinfee.NewStarcoinFee(ctx, cfg, feeUpdateSlot)
+	},
+	basedef.CHAIN_FAMILY_RIPPLE: func(ctx context.Context, cfg *conf.FeeListenConfig, feeUpdateSlot int64) ChainFee {
+		return ripplefee.NewRippleFee(ctx, cfg, feeUpdateSlot)
+	},
+	basedef.CHAIN_FAMILY_APTOS: func(ctx context.Context, cfg *conf.FeeListenConfig, feeUpdateSlot int64) ChainFee {
+		return aptosfee.NewAptosFee(ctx, cfg, feeUpdateSlot)
+	},
+}
+
+// RegisterChainFee registers the chain fee factory of a chain family
+func RegisterChainFee(family string, factory ChainFeeFactory) {
+	chainFeeFactories[family] = factory
+}
+
+func NewChainFee(ctx context.Context, cfg *conf.FeeListenConfig, feeUpdateSlot int64) ChainFee {
+	factory, ok := chainFeeFactories[basedef.GetChainFamily(cfg.ChainId)]
+	if !ok {
 		return nil
 	}
+	return factory(ctx, cfg, feeUpdateSlot)
 }
 
 type FeeListen struct {
-	feeUpdateSlot int64
-	fees          map[uint64]ChainFee
-	db            chainfeedao.ChainFeeDao
-	exit          chan bool
+	feeUpdateSlot       int64
+	feeHistoryRetention int64
+	fees                map[uint64]ChainFee
+	db                  chainfeedao.ChainFeeDao
+	ctx                 context.Context
+	cancel              context.CancelFunc
+	wg                  sync.WaitGroup
 }
 
-func NewFeeListen(feeUpdateSlot int64, fees []ChainFee, db chainfeedao.ChainFeeDao) *FeeListen {
+func NewFeeListen(ctx context.Context, feeUpdateSlot int64, fees []ChainFee, db chainfeedao.ChainFeeDao) *FeeListen {
 	feeListen := &FeeListen{}
 	feeListen.feeUpdateSlot = feeUpdateSlot
 	feeListen.db = db
-	feeListen.exit = make(chan bool, 0)
+	feeListen.ctx, feeListen.cancel = context.WithCancel(ctx)
 	feeListen.fees = make(map[uint64]ChainFee)
 	for _, fee := range fees {
 		feeListen.fees[fee.GetChainId()] = fee
@@ -134,16 +171,22 @@ func NewFeeListen(feeUpdateSlot int64, fees []ChainFee, db chainfeedao.ChainFeeD
 	if err != nil {
 		panic(err)
 	}
+	feeListen.saveFeeHistory(chainFees)
 	return feeListen
 }
 
 func (fl *FeeListen) Start() {
 	logs.Info("start chain fee listen.")
-	go fl.ListenFee()
+	fl.wg.Add(1)
+	go func() {
+		defer fl.wg.Done()
+		fl.ListenFee()
+	}()
 }
 
 func (fl *FeeListen) Stop() {
-	fl.exit <- true
+	fl.cancel()
+	fl.wg.Wait()
 	logs.Info("stop chain fee listen.")
 }
 
@@ -151,10 +194,13 @@ func (fl *FeeListen) ListenFee() {
 	for {
 		exit := fl.listenFee()
 		if exit {
-			close(fl.exit)
 			break
 		}
-		time.Sleep(time.Second * 5)
+		select {
+		case <-time.After(time.Second * 5):
+		case <-fl.ctx.Done():
+			return
+		}
 	}
 }
 
@@ -168,6 +214,7 @@ func (fl *FeeListen) listenFee() (exit bool) {
 
 	logs.Debug("fee listen, chain: %s, dao: %s......", fl.GetChainFees(), fl.db.Name())
 	ticker := time.NewTicker(time.Minute)
+	defer ticker.Stop()
 	for {
 		select {
 		case <-ticker.C:
@@ -176,7 +223,7 @@ func (fl *FeeListen) listenFee() (exit bool) {
 				continue
 			}
 			counter := 0
-			for counter < 5 {
+			for counter < 5 && fl.ctx.Err() == nil {
 				time.Sleep(time.Second * 5)
 				counter++
 				logs.Info("do fee update at time: %s", time.Now().Format("2006-01-02 15:04:05"))
@@ -196,9 +243,10 @@ func (fl *FeeListen) listenFee() (exit bool) {
 					logs.Error("save fees err: %v", err)
 					continue
 				}
+				fl.saveFeeHistory(chainFees)
 				break
 			}
-		case <-fl.exit:
+		case <-fl.ctx.Done():
 			logs.Info("fee listen exit, chain: %s, dao: %s......", fl.GetChainFees(), fl.db.Name())
 			return true
 		}
@@ -226,6 +274,21 @@ func (fl *FeeListen) updateChainFees(chainFees []*models.ChainFee) error {
 		fee.MinFee = models.NewBigInt(minFee)
 		fee.MaxFee = models.NewBigInt(maxFee)
 		fee.ProxyFee = models.NewBigInt(proxyFee)
+		fee.BaseFee, fee.PriorityFee, fee.MaxFeePerGas = models.NewBigIntFromInt(0), models.NewBigIntFromInt(0), models.NewBigIntFromInt(0)
+		if components, ok := query.(FeeComponents); ok {
+			baseFee, priorityFee, maxFeePerGas := components.GetFeeComponents()
+			if maxFeePerGas != nil {
+				fee.BaseFee = models.NewBigInt(baseFee)
+				fee.PriorityFee = models.NewBigInt(priorityFee)
+				fee.MaxFeePerGas = models.NewBigInt(maxFeePerGas)
+			}
+		}
+		fee.L1DataFee = models.NewBigIntFromInt(0)
+		if l1, ok := query.(L1DataFee); ok {
+			if l1DataFee := l1.GetL1DataFee(); l1DataFee != nil {
+				fee.L1DataFee = models.NewBigInt(l1DataFee)
+			}
+		}
 		fee.Time = time.Now().Unix()
 		fee.Ind = 1
 	}
@@ -237,6 +300,17 @@ func (fl *FeeListen) updateChainFees(chainFees []*models.ChainFee) error {
 	return nil
 }
 
+// saveFeeHistory appends the updated fees to the fee history, a failure is only logged since the fees are already saved
+func (fl *FeeListen) saveFeeHistory(chainFees []*models.ChainFee) {
+	var expired int64
+	if fl.feeHistoryRetention > 0 {
+		expired = time.Now().Unix() - fl.feeHistoryRetention
+	}
+	if err := fl.db.SaveFeeHistory(chainFees, expired); err != nil {
+		logs.Error("save fee history err: %v", err)
+	}
+}
+
 func (fl *FeeListen) GetChainFees() string {
 	fees := make([]string, 0)
 	for _, fee := range fl.fees {
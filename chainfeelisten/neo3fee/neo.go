@@ -18,6 +18,7 @@
 package neo3fee
 
 import (
+	"context"
 	"math/big"
 	"poly-bridge/basedef"
 	"poly-bridge/chainsdk"
@@ -29,12 +30,12 @@ type Neo3Fee struct {
 	neoSdk *chainsdk.Neo3SdkPro
 }
 
-func NewNeo3Fee(neoCfg *conf.FeeListenConfig, feeUpdateSlot int64) *Neo3Fee {
+func NewNeo3Fee(ctx context.Context, neoCfg *conf.FeeListenConfig, feeUpdateSlot int64) *Neo3Fee {
 	Neo3Fee := &Neo3Fee{}
 	Neo3Fee.neoCfg = neoCfg
 	//
 	urls := neoCfg.GetNodesUrl()
-	sdk := chainsdk.NewNeo3SdkPro(urls, uint64(feeUpdateSlot), neoCfg.ChainId)
+	sdk := chainsdk.NewNeo3SdkProWithContext(ctx, urls, uint64(feeUpdateSlot), neoCfg.ChainId)
 	Neo3Fee.neoSdk = sdk
 	return Neo3Fee
 }
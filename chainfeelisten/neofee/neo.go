@@ -18,6 +18,7 @@
 package neofee
 
 import (
+	"context"
 	"math/big"
 	"poly-bridge/basedef"
 	"poly-bridge/chainsdk"
@@ -29,12 +30,12 @@ type NeoFee struct {
 	neoSdk *chainsdk.NeoSdkPro
 }
 
-func NewNeoFee(neoCfg *conf.FeeListenConfig, feeUpdateSlot int64) *NeoFee {
+func NewNeoFee(ctx context.Context, neoCfg *conf.FeeListenConfig, feeUpdateSlot int64) *NeoFee {
 	neoFee := &NeoFee{}
 	neoFee.neoCfg = neoCfg
 	//
 	urls := neoCfg.GetNodesUrl()
-	sdk := chainsdk.NewNeoSdkPro(urls, uint64(feeUpdateSlot), neoCfg.ChainId)
+	sdk := chainsdk.NewNeoSdkProWithContext(ctx, urls, uint64(feeUpdateSlot), neoCfg.ChainId)
 	neoFee.neoSdk = sdk
 	return neoFee
 }
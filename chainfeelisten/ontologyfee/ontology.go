@@ -18,6 +18,7 @@
 package ontologyfee
 
 import (
+	"context"
 	"math/big"
 	"poly-bridge/basedef"
 	"poly-bridge/chainsdk"
@@ -29,12 +30,12 @@ type OntologyFee struct {
 	ontologySdk *chainsdk.OntologySdkPro
 }
 
-func NewOntologyFee(ontologyCfg *conf.FeeListenConfig, feeUpdateSlot int64) *OntologyFee {
+func NewOntologyFee(ctx context.Context, ontologyCfg *conf.FeeListenConfig, feeUpdateSlot int64) *OntologyFee {
 	ontologyFee := &OntologyFee{}
 	ontologyFee.ontologyCfg = ontologyCfg
 	//
 	urls := ontologyCfg.GetNodesUrl()
-	sdk := chainsdk.NewOntologySdkPro(urls, uint64(feeUpdateSlot), ontologyCfg.ChainId)
+	sdk := chainsdk.NewOntologySdkProWithContext(ctx, urls, uint64(feeUpdateSlot), ontologyCfg.ChainId)
 	ontologyFee.ontologySdk = sdk
 	return ontologyFee
 }
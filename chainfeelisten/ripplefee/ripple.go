@@ -1,6 +1,7 @@
 package ripplefee
 
 import (
+	"context"
 	"math/big"
 	"poly-bridge/basedef"
 	"poly-bridge/chainsdk"
@@ -12,11 +13,11 @@ type RippleFee struct {
 	rippleSdk *chainsdk.RippleSdkPro
 }
 
-func NewRippleFee(rippleCfg *conf.FeeListenConfig, feeUpdateSlot int64) *RippleFee {
+func NewRippleFee(ctx context.Context, rippleCfg *conf.FeeListenConfig, feeUpdateSlot int64) *RippleFee {
 	RippleFee := &RippleFee{}
 	RippleFee.rippleCfg = rippleCfg
 	urls := rippleCfg.GetNodesUrl()
-	sdk := chainsdk.NewRippleSdkPro(urls, uint64(feeUpdateSlot), rippleCfg.ChainId)
+	sdk := chainsdk.NewRippleSdkProWithContext(ctx, urls, uint64(feeUpdateSlot), rippleCfg.ChainId)
 	RippleFee.rippleSdk = sdk
 	return RippleFee
 }
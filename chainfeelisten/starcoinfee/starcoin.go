@@ -1,6 +1,7 @@
 package starcoinfee
 
 import (
+	"context"
 	"math/big"
 	"poly-bridge/basedef"
 	"poly-bridge/chainsdk"
@@ -12,11 +13,11 @@ type StarcoinFee struct {
 	starcoinSdk *chainsdk.StarcoinSdkPro
 }
 
-func NewStarcoinFee(starcoinCfg *conf.FeeListenConfig, feeUpdateSlot int64) *StarcoinFee {
+func NewStarcoinFee(ctx context.Context, starcoinCfg *conf.FeeListenConfig, feeUpdateSlot int64) *StarcoinFee {
 	StarcoinFee := &StarcoinFee{}
 	StarcoinFee.starcoinCfg = starcoinCfg
 	urls := starcoinCfg.GetNodesUrl()
-	sdk := chainsdk.NewStarcoinSdkPro(urls, uint64(feeUpdateSlot), starcoinCfg.ChainId)
+	sdk := chainsdk.NewStarcoinSdkProWithContext(ctx, urls, uint64(feeUpdateSlot), starcoinCfg.ChainId)
 	StarcoinFee.starcoinSdk = sdk
 	return StarcoinFee
 }
@@ -18,6 +18,7 @@
 package switcheofee
 
 import (
+	"context"
 	"math/big"
 	"poly-bridge/basedef"
 	"poly-bridge/chainsdk"
@@ -29,11 +30,11 @@ type SwitcheoFee struct {
 	swthSdk *chainsdk.SwitcheoSdkPro
 }
 
-func NewSwitcheoFee(swthCfg *conf.FeeListenConfig, feeUpdateSlot int64) *SwitcheoFee {
+func NewSwitcheoFee(ctx context.Context, swthCfg *conf.FeeListenConfig, feeUpdateSlot int64) *SwitcheoFee {
 	switcheoFee := &SwitcheoFee{}
 	switcheoFee.swthCfg = swthCfg
 	urls := swthCfg.GetNodesUrl()
-	sdk := chainsdk.NewSwitcheoSdkPro(urls, uint64(feeUpdateSlot), swthCfg.ChainId)
+	sdk := chainsdk.NewSwitcheoSdkProWithContext(ctx, urls, uint64(feeUpdateSlot), swthCfg.ChainId)
 	switcheoFee.swthSdk = sdk
 	return switcheoFee
 }
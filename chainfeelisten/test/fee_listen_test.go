@@ -1,6 +1,7 @@
 package test
 
 import (
+	"context"
 	"fmt"
 	"os"
 	"poly-bridge/basedef"
@@ -27,9 +28,9 @@ func TestListenFee(t *testing.T) {
 	feeListenCfgs := config.FeeListenConfig
 	chainFees := make([]chainfeelisten.ChainFee, 0)
 	for _, cfg := range feeListenCfgs {
-		chainFee := chainfeelisten.NewChainFee(cfg, config.FeeUpdateSlot)
+		chainFee := chainfeelisten.NewChainFee(context.Background(), cfg, config.FeeUpdateSlot)
 		chainFees = append(chainFees, chainFee)
 	}
-	feeListen := chainfeelisten.NewFeeListen(config.FeeUpdateSlot, chainFees, dao)
+	feeListen := chainfeelisten.NewFeeListen(context.Background(), config.FeeUpdateSlot, chainFees, dao)
 	feeListen.ListenFee()
 }
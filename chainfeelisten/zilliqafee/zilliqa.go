@@ -1,6 +1,7 @@
 package zilliqafee
 
 import (
+	"context"
 	"math/big"
 	"poly-bridge/basedef"
 	"poly-bridge/chainsdk"
@@ -13,11 +14,11 @@ type ZilliqaFee struct {
 	zilSdk *chainsdk.ZilliqaSdkPro
 }
 
-func NewZilliqaFee(zilCfg *conf.FeeListenConfig, feeUpdateSlot int64) *ZilliqaFee {
+func NewZilliqaFee(ctx context.Context, zilCfg *conf.FeeListenConfig, feeUpdateSlot int64) *ZilliqaFee {
 	zilFee := &ZilliqaFee{}
 	zilFee.zilCfg = zilCfg
 	urls := zilCfg.GetNodesUrl()
-	sdk := chainsdk.NewZilliqaSdkPro(urls, uint64(feeUpdateSlot), zilCfg.ChainId)
+	sdk := chainsdk.NewZilliqaSdkProWithContext(ctx, urls, uint64(feeUpdateSlot), zilCfg.ChainId)
 	zilFee.zilSdk = sdk
 	return zilFee
 }
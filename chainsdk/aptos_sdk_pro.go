@@ -34,18 +34,23 @@ type AptosSdkPro struct {
 }
 
 func NewAptosSdkPro(urls []string, slot uint64, id uint64) *AptosSdkPro {
+	return NewAptosSdkProWithContext(context.Background(), urls, slot, id)
+}
+
+// NewAptosSdkProWithContext creates the sdk pool, the node selection routine exits when ctx is done
+func NewAptosSdkProWithContext(ctx context.Context, urls []string, slot uint64, id uint64) *AptosSdkPro {
 	infos := make(map[string]*AptosInfo, len(urls))
 	for _, url := range urls {
 		infos[url] = NewAptosInfo(url)
 	}
-	pro := &AptosSdkPro{infos: infos, selectionSlot: slot, id: id, ctx: context.Background()}
+	pro := &AptosSdkPro{infos: infos, selectionSlot: slot, id: id, ctx: ctx}
 	pro.selection()
 	go pro.NodeSelection()
 	return pro
 }
 
 func (pro *AptosSdkPro) NodeSelection() {
-	for {
+	for pro.ctx.Err() == nil {
 		pro.nodeSelection()
 	}
 }
@@ -58,10 +63,13 @@ func (pro *AptosSdkPro) nodeSelection() {
 	}()
 	logs.Debug("node selection of chain : %d......", pro.id)
 	ticker := time.NewTicker(time.Second * time.Duration(pro.selectionSlot) * 30)
+	defer ticker.Stop()
 	for {
 		select {
 		case <-ticker.C:
 			pro.selection()
+		case <-pro.ctx.Done():
+			return
 		}
 	}
 }
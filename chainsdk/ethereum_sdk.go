@@ -92,6 +92,24 @@ func (s *EthereumSdk) GetHeaderByNumber(number uint64) (*types.Header, error) {
 	return header, err
 }
 
+// GetBlockHashByNumber returns the hash and parent hash of given block number
+func (s *EthereumSdk) GetBlockHashByNumber(number uint64) (hash string, parentHash string, err error) {
+	type Header struct {
+		Hash       string `json:"hash"`
+		ParentHash string `json:"parentHash"`
+	}
+
+	header := &Header{}
+	err = s.rpcClient.CallContext(context.Background(), header, "eth_getBlockByNumber", toBlockNumArg(new(big.Int).SetUint64(number)), false)
+	if err != nil {
+		return "", "", err
+	}
+	if header.Hash == "" {
+		return "", "", fmt.Errorf("block %d not found", number)
+	}
+	return header.Hash, header.ParentHash, nil
+}
+
 // GetBlockTimeByNumber returns the timestamp of given block number
 func (s *EthereumSdk) GetBlockTimeByNumber(chainId, number uint64) (timestamp uint64, err error) {
 	type Header struct {
@@ -186,6 +204,27 @@ func (s *EthereumSdk) SuggestGasPrice() (*big.Int, error) {
 	return gasPrice, err
 }
 
+// FeeHistory is the result of eth_feeHistory, BaseFeePerGas has an extra last entry which is the base fee of the next block
+type FeeHistory struct {
+	OldestBlock   *hexutil.Big     `json:"oldestBlock"`
+	BaseFeePerGas []*hexutil.Big   `json:"baseFeePerGas"`
+	GasUsedRatio  []float64        `json:"gasUsedRatio"`
+	Reward        [][]*hexutil.Big `json:"reward"`
+}
+
+func (s *EthereumSdk) FeeHistory(blocks uint64, rewardPercentiles []float64) (*FeeHistory, error) {
+	history := new(FeeHistory)
+	err := s.rpcClient.CallContext(context.Background(), history, "eth_feeHistory", hexutil.EncodeUint64(blocks), "latest", rewardPercentiles)
+	if err != nil {
+		return nil, err
+	}
+	return history, nil
+}
+
+func (s *EthereumSdk) CallContract(ctx context.Context, msg ethereum.CallMsg, blockNumber *big.Int) ([]byte, error) {
+	return s.rawClient.CallContract(ctx, msg, blockNumber)
+}
+
 func (s *EthereumSdk) EstimateGas(msg ethereum.CallMsg) (uint64, error) {
 	gasLimit, err := s.rawClient.EstimateGas(context.Background(), msg)
 	for err != nil {
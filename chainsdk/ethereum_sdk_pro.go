@@ -18,6 +18,7 @@
 package chainsdk
 
 import (
+	"context"
 	"encoding/hex"
 	"fmt"
 	"math"
@@ -38,6 +39,7 @@ import (
 type EthereumInfo struct {
 	sdk          *EthereumSdk
 	latestHeight uint64
+	quarantine   int64 // unix time until which the node is left out after disagreeing with the quorum
 }
 
 func NewEthereumInfo(url string) *EthereumInfo {
@@ -52,6 +54,7 @@ func NewEthereumInfo(url string) *EthereumInfo {
 }
 
 type EthereumSdkPro struct {
+	ctx           context.Context
 	infos         map[string]*EthereumInfo
 	selectionSlot uint64
 	id            uint64
@@ -59,18 +62,23 @@ type EthereumSdkPro struct {
 }
 
 func NewEthereumSdkPro(urls []string, slot uint64, id uint64) *EthereumSdkPro {
+	return NewEthereumSdkProWithContext(context.Background(), urls, slot, id)
+}
+
+// NewEthereumSdkProWithContext creates the sdk pool, the node selection routine exits when ctx is done
+func NewEthereumSdkProWithContext(ctx context.Context, urls []string, slot uint64, id uint64) *EthereumSdkPro {
 	infos := make(map[string]*EthereumInfo, len(urls))
 	for _, url := range urls {
 		infos[url] = NewEthereumInfo(url)
 	}
-	pro := &EthereumSdkPro{infos: infos, selectionSlot: slot, id: id}
+	pro := &EthereumSdkPro{infos: infos, selectionSlot: slot, id: id, ctx: ctx}
 	pro.selection()
 	go pro.NodeSelection()
 	return pro
 }
 
 func (pro *EthereumSdkPro) NodeSelection() {
-	for {
+	for pro.ctx.Err() == nil {
 		pro.nodeSelection()
 	}
 }
@@ -83,10 +91,13 @@ func (pro *EthereumSdkPro) nodeSelection() {
 	}()
 	logs.Debug("node selection of chain : %d......", pro.id)
 	ticker := time.NewTicker(time.Second * time.Duration(pro.selectionSlot))
+	defer ticker.Stop()
 	for {
 		select {
 		case <-ticker.C:
 			pro.selection()
+		case <-pro.ctx.Done():
+			return
 		}
 	}
 }
@@ -128,9 +139,10 @@ func (pro *EthereumSdkPro) GetLatest() *EthereumInfo {
 		pro.mutex.Unlock()
 	}()
 	height := uint64(0)
+	now := time.Now().Unix()
 	var latestInfo *EthereumInfo = nil
 	for _, info := range pro.infos {
-		if info != nil && info.latestHeight > height {
+		if info != nil && info.quarantine <= now && info.latestHeight > height {
 			height = info.latestHeight
 			latestInfo = info
 		}
@@ -214,6 +226,30 @@ func (pro *EthereumSdkPro) GetBlockTimeByNumber(number uint64) (uint64, error) {
 	return 0, fmt.Errorf("all node is not working")
 }
 
+func (pro *EthereumSdkPro) GetBlockHashByNumber(number uint64) (string, string, error) {
+	info := pro.GetLatest()
+	if info == nil {
+		return "", "", fmt.Errorf("all node is not working")
+	}
+	flag := 0
+	for info != nil {
+		hash, parentHash, err := info.sdk.GetBlockHashByNumber(number)
+		if err != nil {
+			flag++
+			if flag > 3 {
+				logs.Error("GetBlockHashByNumber_chain:%v,node:%v,eth_getBlockByNumber err %v", pro.id, info.sdk.url, err)
+				flag = 0
+				time.Sleep(time.Second)
+			}
+			info.latestHeight = 0
+			info = pro.GetLatest()
+		} else {
+			return hash, parentHash, nil
+		}
+	}
+	return "", "", fmt.Errorf("all node is not working")
+}
+
 func (pro *EthereumSdkPro) GetTransactionByHash(hash common.Hash) (*types.Transaction, error) {
 	info := pro.GetLatest()
 	if info == nil {
@@ -336,6 +372,42 @@ func (pro *EthereumSdkPro) SuggestGasPrice() (*big.Int, error) {
 	return nil, fmt.Errorf("all node is not working")
 }
 
+func (pro *EthereumSdkPro) FeeHistory(blocks uint64, rewardPercentiles []float64) (*FeeHistory, error) {
+	info := pro.GetLatest()
+	if info == nil {
+		return nil, fmt.Errorf("all node is not working")
+	}
+
+	for info != nil {
+		history, err := info.sdk.FeeHistory(blocks, rewardPercentiles)
+		if err != nil {
+			info.latestHeight = 0
+			info = pro.GetLatest()
+		} else {
+			return history, nil
+		}
+	}
+	return nil, fmt.Errorf("all node is not working")
+}
+
+func (pro *EthereumSdkPro) CallContract(ctx context.Context, msg ethereum.CallMsg, blockNumber *big.Int) ([]byte, error) {
+	info := pro.GetLatest()
+	if info == nil {
+		return nil, fmt.Errorf("all node is not working")
+	}
+
+	for info != nil {
+		result, err := info.sdk.CallContract(ctx, msg, blockNumber)
+		if err != nil {
+			info.latestHeight = 0
+			info = pro.GetLatest()
+		} else {
+			return result, nil
+		}
+	}
+	return nil, fmt.Errorf("all node is not working")
+}
+
 func (pro *EthereumSdkPro) EstimateGas(msg ethereum.CallMsg) (uint64, error) {
 	info := pro.GetLatest()
 	if info == nil {
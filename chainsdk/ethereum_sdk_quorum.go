@@ -0,0 +1,224 @@
+/*
+ * Copyright (C) 2020 The poly network Authors
+ * This file is part of The poly network library.
+ *
+ * The  poly network  is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU Lesser General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * The  poly network  is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU Lesser General Public License for more details.
+ * You should have received a copy of the GNU Lesser General Public License
+ * along with The poly network .  If not, see <http://www.gnu.org/licenses/>.
+ */
+
+package chainsdk
+
+import (
+	"crypto/sha256"
+	"encoding/binary"
+	"encoding/hex"
+	"fmt"
+	"math/big"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/beego/beego/v2/core/logs"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+)
+
+// QuorumError is returned when fewer than quorum nodes agree, Groups > 1 means the nodes returned different results
+type QuorumError struct {
+	Chain  uint64
+	Quorum int
+	Agreed int
+	Nodes  int
+	Groups int
+}
+
+func (err *QuorumError) Error() string {
+	return fmt.Sprintf("chain %d no quorum of %d nodes, %d of %d nodes agreed in %d groups", err.Chain, err.Quorum, err.Agreed, err.Nodes, err.Groups)
+}
+
+func (err *QuorumError) Mismatch() bool {
+	return err.Groups > 1
+}
+
+type quorumResult struct {
+	url    string
+	digest string
+	value  interface{}
+}
+
+// pickQuorum returns the digest agreed by at least quorum results and the urls of the results disagreeing with it
+func pickQuorum(results []*quorumResult, quorum int) (string, []string, bool) {
+	counts := make(map[string]int)
+	for _, result := range results {
+		counts[result.digest]++
+	}
+	digest, agreed := "", 0
+	for d, count := range counts {
+		if count > agreed || (count == agreed && d < digest) {
+			digest, agreed = d, count
+		}
+	}
+	if agreed < quorum {
+		return "", nil, false
+	}
+	dissenters := make([]string, 0)
+	for _, result := range results {
+		if result.digest != digest {
+			dissenters = append(dissenters, result.url)
+		}
+	}
+	sort.Strings(dissenters)
+	return digest, dissenters, true
+}
+
+// healthyInfos returns the nodes not in quarantine whose latest height reached height
+func (pro *EthereumSdkPro) healthyInfos(height uint64) map[string]*EthereumInfo {
+	pro.mutex.Lock()
+	defer pro.mutex.Unlock()
+	now := time.Now().Unix()
+	infos := make(map[string]*EthereumInfo)
+	for url, info := range pro.infos {
+		if info != nil && info.quarantine <= now && info.latestHeight >= height {
+			infos[url] = info
+		}
+	}
+	return infos
+}
+
+func (pro *EthereumSdkPro) Quarantine(url string, duration time.Duration) {
+	pro.mutex.Lock()
+	defer pro.mutex.Unlock()
+	if info, ok := pro.infos[url]; ok {
+		info.quarantine = time.Now().Add(duration).Unix()
+		logs.Error("chain %d node %s is quarantined for %v", pro.id, url, duration)
+	}
+}
+
+// quorum runs fetch on the healthy nodes concurrently and returns the value agreed by at least quorum of them,
+// the nodes disagreeing are quarantined and returned.
+func (pro *EthereumSdkPro) quorum(height uint64, quorum int, quarantine time.Duration, fetch func(info *EthereumInfo) (string, interface{}, error)) (interface{}, []string, error) {
+	infos := pro.healthyInfos(height)
+	results := make([]*quorumResult, 0, len(infos))
+	var mutex sync.Mutex
+	var wg sync.WaitGroup
+	for url, info := range infos {
+		wg.Add(1)
+		go func(url string, info *EthereumInfo) {
+			defer wg.Done()
+			digest, value, err := fetch(info)
+			if err != nil {
+				logs.Warn("chain %d quorum fetch from %s err: %v", pro.id, url, err)
+				return
+			}
+			mutex.Lock()
+			results = append(results, &quorumResult{url: url, digest: digest, value: value})
+			mutex.Unlock()
+		}(url, info)
+	}
+	wg.Wait()
+	digest, dissenters, ok := pickQuorum(results, quorum)
+	if !ok {
+		groups := make(map[string]int)
+		agreed := 0
+		for _, result := range results {
+			groups[result.digest]++
+			if groups[result.digest] > agreed {
+				agreed = groups[result.digest]
+			}
+		}
+		return nil, nil, &QuorumError{Chain: pro.id, Quorum: quorum, Agreed: agreed, Nodes: len(infos), Groups: len(groups)}
+	}
+	for _, url := range dissenters {
+		pro.Quarantine(url, quarantine)
+	}
+	for _, result := range results {
+		if result.digest == digest {
+			return result.value, dissenters, nil
+		}
+	}
+	return nil, dissenters, nil
+}
+
+// QuorumLatestHeight returns the highest height reached by at least quorum healthy nodes
+func (pro *EthereumSdkPro) QuorumLatestHeight(quorum int) (uint64, error) {
+	infos := pro.healthyInfos(1)
+	heights := make([]uint64, 0, len(infos))
+	pro.mutex.Lock()
+	for _, info := range infos {
+		heights = append(heights, info.latestHeight)
+	}
+	pro.mutex.Unlock()
+	if len(heights) < quorum || quorum <= 0 {
+		return 0, &QuorumError{Chain: pro.id, Quorum: quorum, Agreed: len(heights), Nodes: len(heights), Groups: 1}
+	}
+	sort.Slice(heights, func(i, j int) bool { return heights[i] > heights[j] })
+	return heights[quorum-1], nil
+}
+
+// QuorumBlockHash returns the hash and parent hash of the block agreed by at least quorum nodes
+func (pro *EthereumSdkPro) QuorumBlockHash(number uint64, quorum int, quarantine time.Duration) (string, string, []string, error) {
+	value, dissenters, err := pro.quorum(number, quorum, quarantine, func(info *EthereumInfo) (string, interface{}, error) {
+		hash, parentHash, err := info.sdk.GetBlockHashByNumber(number)
+		if err != nil {
+			return "", nil, err
+		}
+		return hash + parentHash, [2]string{hash, parentHash}, nil
+	})
+	if err != nil {
+		return "", "", dissenters, err
+	}
+	hashes := value.([2]string)
+	return hashes[0], hashes[1], dissenters, nil
+}
+
+// QuorumFilterLog returns the logs of the range agreed by at least quorum nodes
+func (pro *EthereumSdkPro) QuorumFilterLog(fromBlock *big.Int, toBlock *big.Int, addresses []common.Address, quorum int, quarantine time.Duration) ([]types.Log, []string, error) {
+	value, dissenters, err := pro.quorum(toBlock.Uint64(), quorum, quarantine, func(info *EthereumInfo) (string, interface{}, error) {
+		contractLogs, err := info.sdk.FilterLog(fromBlock, toBlock, addresses)
+		if err != nil {
+			return "", nil, err
+		}
+		return logsDigest(contractLogs), contractLogs, nil
+	})
+	if err != nil {
+		return nil, dissenters, err
+	}
+	return value.([]types.Log), dissenters, nil
+}
+
+func logsDigest(contractLogs []types.Log) string {
+	sorted := make([]types.Log, len(contractLogs))
+	copy(sorted, contractLogs)
+	sort.Slice(sorted, func(i, j int) bool {
+		if sorted[i].BlockNumber != sorted[j].BlockNumber {
+			return sorted[i].BlockNumber < sorted[j].BlockNumber
+		}
+		return sorted[i].Index < sorted[j].Index
+	})
+	hasher := sha256.New()
+	number := make([]byte, 8)
+	for _, log := range sorted {
+		hasher.Write(log.BlockHash.Bytes())
+		hasher.Write(log.TxHash.Bytes())
+		binary.BigEndian.PutUint64(number, uint64(log.Index))
+		hasher.Write(number)
+		hasher.Write(log.Address.Bytes())
+		for _, topic := range log.Topics {
+			hasher.Write(topic.Bytes())
+		}
+		hasher.Write(log.Data)
+		if log.Removed {
+			hasher.Write([]byte{1})
+		}
+	}
+	return hex.EncodeToString(hasher.Sum(nil))
+}
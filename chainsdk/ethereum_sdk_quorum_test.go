@@ -0,0 +1,35 @@
+package chainsdk
+
+import (
+	"testing"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestPickQuorum(t *testing.T) {
+	results := []*quorumResult{
+		{url: "a", digest: "x"},
+		{url: "b", digest: "x"},
+		{url: "c", digest: "y"},
+	}
+	digest, dissenters, ok := pickQuorum(results, 2)
+	assert.True(t, ok)
+	assert.Equal(t, "x", digest)
+	assert.Equal(t, []string{"c"}, dissenters)
+
+	_, _, ok = pickQuorum(results, 3)
+	assert.False(t, ok)
+}
+
+func TestLogsDigest(t *testing.T) {
+	log1 := types.Log{BlockNumber: 1, Index: 0, TxHash: common.HexToHash("0x01"), Data: []byte{1}}
+	log2 := types.Log{BlockNumber: 2, Index: 3, TxHash: common.HexToHash("0x02"), Data: []byte{2}}
+	assert.Equal(t, logsDigest([]types.Log{log1, log2}), logsDigest([]types.Log{log2, log1}))
+
+	forged := log2
+	forged.Data = []byte{3}
+	assert.NotEqual(t, logsDigest([]types.Log{log1, log2}), logsDigest([]types.Log{log1, forged}))
+	assert.NotEqual(t, logsDigest([]types.Log{log1, log2}), logsDigest([]types.Log{log1}))
+}
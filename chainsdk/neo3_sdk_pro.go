@@ -18,6 +18,7 @@
 package chainsdk
 
 import (
+	"context"
 	"fmt"
 	"math/big"
 	"runtime/debug"
@@ -43,6 +44,7 @@ func NewNeo3Info(url string) *Neo3Info {
 }
 
 type Neo3SdkPro struct {
+	ctx           context.Context
 	infos         map[string]*Neo3Info
 	selectionSlot uint64
 	id            uint64
@@ -50,18 +52,23 @@ type Neo3SdkPro struct {
 }
 
 func NewNeo3SdkPro(urls []string, slot uint64, id uint64) *Neo3SdkPro {
+	return NewNeo3SdkProWithContext(context.Background(), urls, slot, id)
+}
+
+// NewNeo3SdkProWithContext creates the sdk pool, the node selection routine exits when ctx is done
+func NewNeo3SdkProWithContext(ctx context.Context, urls []string, slot uint64, id uint64) *Neo3SdkPro {
 	infos := make(map[string]*Neo3Info, len(urls))
 	for _, url := range urls {
 		infos[url] = NewNeo3Info(url)
 	}
-	pro := &Neo3SdkPro{infos: infos, selectionSlot: slot, id: id}
+	pro := &Neo3SdkPro{infos: infos, selectionSlot: slot, id: id, ctx: ctx}
 	pro.selection()
 	go pro.NodeSelection()
 	return pro
 }
 
 func (pro *Neo3SdkPro) NodeSelection() {
-	for {
+	for pro.ctx.Err() == nil {
 		pro.nodeSelection()
 	}
 }
@@ -74,10 +81,13 @@ func (pro *Neo3SdkPro) nodeSelection() {
 	}()
 	logs.Debug("node selection of chain : %d......", pro.id)
 	ticker := time.NewTicker(time.Second * time.Duration(pro.selectionSlot))
+	defer ticker.Stop()
 	for {
 		select {
 		case <-ticker.C:
 			pro.selection()
+		case <-pro.ctx.Done():
+			return
 		}
 	}
 }
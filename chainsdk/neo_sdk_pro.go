@@ -18,6 +18,7 @@
 package chainsdk
 
 import (
+	"context"
 	"fmt"
 	"github.com/beego/beego/v2/core/logs"
 	"github.com/joeqian10/neo-gogogo/rpc/models"
@@ -42,6 +43,7 @@ func NewNeoInfo(url string) *NeoInfo {
 }
 
 type NeoSdkPro struct {
+	ctx           context.Context
 	infos         map[string]*NeoInfo
 	selectionSlot uint64
 	id            uint64
@@ -49,18 +51,23 @@ type NeoSdkPro struct {
 }
 
 func NewNeoSdkPro(urls []string, slot uint64, id uint64) *NeoSdkPro {
+	return NewNeoSdkProWithContext(context.Background(), urls, slot, id)
+}
+
+// NewNeoSdkProWithContext creates the sdk pool, the node selection routine exits when ctx is done
+func NewNeoSdkProWithContext(ctx context.Context, urls []string, slot uint64, id uint64) *NeoSdkPro {
 	infos := make(map[string]*NeoInfo, len(urls))
 	for _, url := range urls {
 		infos[url] = NewNeoInfo(url)
 	}
-	pro := &NeoSdkPro{infos: infos, selectionSlot: slot, id: id}
+	pro := &NeoSdkPro{infos: infos, selectionSlot: slot, id: id, ctx: ctx}
 	pro.selection()
 	go pro.NodeSelection()
 	return pro
 }
 
 func (pro *NeoSdkPro) NodeSelection() {
-	for {
+	for pro.ctx.Err() == nil {
 		pro.nodeSelection()
 	}
 }
@@ -73,10 +80,13 @@ func (pro *NeoSdkPro) nodeSelection() {
 	}()
 	logs.Debug("node selection of chain : %d......", pro.id)
 	ticker := time.NewTicker(time.Second * time.Duration(pro.selectionSlot))
+	defer ticker.Stop()
 	for {
 		select {
 		case <-ticker.C:
 			pro.selection()
+		case <-pro.ctx.Done():
+			return
 		}
 	}
 }
@@ -18,6 +18,7 @@
 package chainsdk
 
 import (
+	"context"
 	"fmt"
 	"github.com/beego/beego/v2/core/logs"
 	"github.com/ontio/ontology-go-sdk"
@@ -45,6 +46,7 @@ func NewOntologyInfo(url string) *OntologyInfo {
 }
 
 type OntologySdkPro struct {
+	ctx           context.Context
 	infos         map[string]*OntologyInfo
 	selectionSlot uint64
 	id            uint64
@@ -52,18 +54,23 @@ type OntologySdkPro struct {
 }
 
 func NewOntologySdkPro(urls []string, slot uint64, id uint64) *OntologySdkPro {
+	return NewOntologySdkProWithContext(context.Background(), urls, slot, id)
+}
+
+// NewOntologySdkProWithContext creates the sdk pool, the node selection routine exits when ctx is done
+func NewOntologySdkProWithContext(ctx context.Context, urls []string, slot uint64, id uint64) *OntologySdkPro {
 	infos := make(map[string]*OntologyInfo, len(urls))
 	for _, url := range urls {
 		infos[url] = NewOntologyInfo(url)
 	}
-	pro := &OntologySdkPro{infos: infos, selectionSlot: slot, id: id}
+	pro := &OntologySdkPro{infos: infos, selectionSlot: slot, id: id, ctx: ctx}
 	pro.selection()
 	go pro.NodeSelection()
 	return pro
 }
 
 func (pro *OntologySdkPro) NodeSelection() {
-	for {
+	for pro.ctx.Err() == nil {
 		pro.nodeSelection()
 	}
 }
@@ -76,10 +83,13 @@ func (pro *OntologySdkPro) nodeSelection() {
 	}()
 	logs.Debug("node selection of chain : %d......", pro.id)
 	ticker := time.NewTicker(time.Second * time.Duration(pro.selectionSlot))
+	defer ticker.Stop()
 	for {
 		select {
 		case <-ticker.C:
 			pro.selection()
+		case <-pro.ctx.Done():
+			return
 		}
 	}
 }
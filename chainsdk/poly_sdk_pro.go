@@ -18,6 +18,7 @@
 package chainsdk
 
 import (
+	"context"
 	"fmt"
 	"github.com/beego/beego/v2/core/logs"
 	"github.com/polynetwork/poly-go-sdk/common"
@@ -41,6 +42,7 @@ func NewPolyInfo(url string) *PolyInfo {
 }
 
 type PolySDKPro struct {
+	ctx           context.Context
 	infos         map[string]*PolyInfo
 	selectionSlot uint64
 	id            uint64
@@ -48,18 +50,23 @@ type PolySDKPro struct {
 }
 
 func NewPolySDKPro(urls []string, slot uint64, id uint64) *PolySDKPro {
+	return NewPolySDKProWithContext(context.Background(), urls, slot, id)
+}
+
+// NewPolySDKProWithContext creates the sdk pool, the node selection routine exits when ctx is done
+func NewPolySDKProWithContext(ctx context.Context, urls []string, slot uint64, id uint64) *PolySDKPro {
 	infos := make(map[string]*PolyInfo, len(urls))
 	for _, url := range urls {
 		infos[url] = NewPolyInfo(url)
 	}
-	pro := &PolySDKPro{infos: infos, selectionSlot: slot, id: id}
+	pro := &PolySDKPro{infos: infos, selectionSlot: slot, id: id, ctx: ctx}
 	pro.selection()
 	go pro.NodeSelection()
 	return pro
 }
 
 func (pro *PolySDKPro) NodeSelection() {
-	for {
+	for pro.ctx.Err() == nil {
 		pro.nodeSelection()
 	}
 }
@@ -72,10 +79,13 @@ func (pro *PolySDKPro) nodeSelection() {
 	}()
 	logs.Debug("node selection of chain : %d......", pro.id)
 	ticker := time.NewTicker(time.Second * time.Duration(pro.selectionSlot))
+	defer ticker.Stop()
 	for {
 		select {
 		case <-ticker.C:
 			pro.selection()
+		case <-pro.ctx.Done():
+			return
 		}
 	}
 }
@@ -1,6 +1,7 @@
 package chainsdk
 
 import (
+	"context"
 	"fmt"
 	"github.com/beego/beego/v2/core/logs"
 	"github.com/rubblelabs/ripple/websockets"
@@ -30,6 +31,7 @@ func (info *RippleInfo) GetLastHeight() (uint64, error) {
 }
 
 type RippleSdkPro struct {
+	ctx           context.Context
 	infos         map[string]*RippleInfo
 	selectionSlot uint64
 	id            uint64
@@ -37,11 +39,16 @@ type RippleSdkPro struct {
 }
 
 func NewRippleSdkPro(urls []string, slot uint64, id uint64) *RippleSdkPro {
+	return NewRippleSdkProWithContext(context.Background(), urls, slot, id)
+}
+
+// NewRippleSdkProWithContext creates the sdk pool, the node selection routine exits when ctx is done
+func NewRippleSdkProWithContext(ctx context.Context, urls []string, slot uint64, id uint64) *RippleSdkPro {
 	infos := make(map[string]*RippleInfo, len(urls))
 	for _, url := range urls {
 		infos[url] = NewRippleInfo(url)
 	}
-	pro := &RippleSdkPro{infos: infos, selectionSlot: slot, id: id}
+	pro := &RippleSdkPro{infos: infos, selectionSlot: slot, id: id, ctx: ctx}
 	pro.selection()
 	go pro.NodeSelection()
 	return pro
@@ -60,7 +67,7 @@ func (pro *RippleSdkPro) selection() {
 }
 
 func (pro *RippleSdkPro) NodeSelection() {
-	for {
+	for pro.ctx.Err() == nil {
 		pro.nodeSelection()
 	}
 }
@@ -73,10 +80,13 @@ func (pro *RippleSdkPro) nodeSelection() {
 	}()
 	logs.Debug("node selection of chain : %d......", pro.id)
 	ticker := time.NewTicker(time.Second * time.Duration(pro.selectionSlot))
+	defer ticker.Stop()
 	for {
 		select {
 		case <-ticker.C:
 			pro.selection()
+		case <-pro.ctx.Done():
+			return
 		}
 	}
 }
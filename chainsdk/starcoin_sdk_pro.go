@@ -1,6 +1,7 @@
 package chainsdk
 
 import (
+	"context"
 	"fmt"
 	"github.com/beego/beego/v2/core/logs"
 	"github.com/starcoinorg/starcoin-go/client"
@@ -24,6 +25,7 @@ func NewStarcoinInfo(url string) *StarcoinInfo {
 }
 
 type StarcoinSdkPro struct {
+	ctx           context.Context
 	infos         map[string]*StarcoinInfo
 	selectionSlot uint64
 	id            uint64
@@ -31,18 +33,23 @@ type StarcoinSdkPro struct {
 }
 
 func NewStarcoinSdkPro(urls []string, slot uint64, id uint64) *StarcoinSdkPro {
+	return NewStarcoinSdkProWithContext(context.Background(), urls, slot, id)
+}
+
+// NewStarcoinSdkProWithContext creates the sdk pool, the node selection routine exits when ctx is done
+func NewStarcoinSdkProWithContext(ctx context.Context, urls []string, slot uint64, id uint64) *StarcoinSdkPro {
 	infos := make(map[string]*StarcoinInfo, len(urls))
 	for _, url := range urls {
 		infos[url] = NewStarcoinInfo(url)
 	}
-	pro := &StarcoinSdkPro{infos: infos, selectionSlot: slot, id: id}
+	pro := &StarcoinSdkPro{infos: infos, selectionSlot: slot, id: id, ctx: ctx}
 	pro.selection()
 	go pro.NodeSelection()
 	return pro
 }
 
 func (pro *StarcoinSdkPro) NodeSelection() {
-	for {
+	for pro.ctx.Err() == nil {
 		pro.nodeSelection()
 	}
 }
@@ -55,10 +62,13 @@ func (pro *StarcoinSdkPro) nodeSelection() {
 	}()
 	logs.Debug("node selection of chain : %d......", pro.id)
 	ticker := time.NewTicker(time.Second * time.Duration(pro.selectionSlot))
+	defer ticker.Stop()
 	for {
 		select {
 		case <-ticker.C:
 			pro.selection()
+		case <-pro.ctx.Done():
+			return
 		}
 	}
 }
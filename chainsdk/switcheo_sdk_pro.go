@@ -1,6 +1,7 @@
 package chainsdk
 
 import (
+	"context"
 	"fmt"
 	"runtime/debug"
 	"sync"
@@ -24,6 +25,7 @@ func NewSwitcheoInfo(url string) *SwitcheoInfo {
 }
 
 type SwitcheoSdkPro struct {
+	ctx           context.Context
 	infos         map[string]*SwitcheoInfo
 	selectionSlot uint64
 	id            uint64
@@ -31,11 +33,16 @@ type SwitcheoSdkPro struct {
 }
 
 func NewSwitcheoSdkPro(urls []string, slot uint64, id uint64) *SwitcheoSdkPro {
+	return NewSwitcheoSdkProWithContext(context.Background(), urls, slot, id)
+}
+
+// NewSwitcheoSdkProWithContext creates the sdk pool, the node selection routine exits when ctx is done
+func NewSwitcheoSdkProWithContext(ctx context.Context, urls []string, slot uint64, id uint64) *SwitcheoSdkPro {
 	infos := make(map[string]*SwitcheoInfo, len(urls))
 	for _, url := range urls {
 		infos[url] = NewSwitcheoInfo(url)
 	}
-	pro := &SwitcheoSdkPro{infos: infos, selectionSlot: slot, id: id}
+	pro := &SwitcheoSdkPro{infos: infos, selectionSlot: slot, id: id, ctx: ctx}
 	pro.selection()
 	go pro.NodeSelection()
 	return pro
@@ -84,7 +91,7 @@ func (pro *SwitcheoSdkPro) selection() {
 }
 
 func (pro *SwitcheoSdkPro) NodeSelection() {
-	for {
+	for pro.ctx.Err() == nil {
 		pro.nodeSelection()
 	}
 }
@@ -97,10 +104,13 @@ func (pro *SwitcheoSdkPro) nodeSelection() {
 	}()
 	logs.Debug("node selection of chain : %d......", pro.id)
 	ticker := time.NewTicker(time.Second * time.Duration(pro.selectionSlot))
+	defer ticker.Stop()
 	for {
 		select {
 		case <-ticker.C:
 			pro.selection()
+		case <-pro.ctx.Done():
+			return
 		}
 	}
 }
@@ -1,6 +1,7 @@
 package chainsdk
 
 import (
+	"context"
 	"fmt"
 	"github.com/beego/beego/v2/core/logs"
 	"math/big"
@@ -27,6 +28,7 @@ func (info *ZilliqaInfo) GetLastHeight() (uint64, error) {
 }
 
 type ZilliqaSdkPro struct {
+	ctx           context.Context
 	infos         map[string]*ZilliqaInfo
 	selectionSlot uint64
 	id            uint64
@@ -34,11 +36,16 @@ type ZilliqaSdkPro struct {
 }
 
 func NewZilliqaSdkPro(urls []string, slot uint64, id uint64) *ZilliqaSdkPro {
+	return NewZilliqaSdkProWithContext(context.Background(), urls, slot, id)
+}
+
+// NewZilliqaSdkProWithContext creates the sdk pool, the node selection routine exits when ctx is done
+func NewZilliqaSdkProWithContext(ctx context.Context, urls []string, slot uint64, id uint64) *ZilliqaSdkPro {
 	infos := make(map[string]*ZilliqaInfo, len(urls))
 	for _, url := range urls {
 		infos[url] = NewZilliqaInfo(url)
 	}
-	pro := &ZilliqaSdkPro{infos: infos, selectionSlot: slot, id: id}
+	pro := &ZilliqaSdkPro{infos: infos, selectionSlot: slot, id: id, ctx: ctx}
 	pro.selection()
 	go pro.NodeSelection()
 	return pro
@@ -57,7 +64,7 @@ func (pro *ZilliqaSdkPro) selection() {
 }
 
 func (pro *ZilliqaSdkPro) NodeSelection() {
-	for {
+	for pro.ctx.Err() == nil {
 		pro.nodeSelection()
 	}
 }
@@ -70,10 +77,13 @@ func (pro *ZilliqaSdkPro) nodeSelection() {
 	}()
 	logs.Debug("node selection of chain : %d......", pro.id)
 	ticker := time.NewTicker(time.Second * time.Duration(pro.selectionSlot))
+	defer ticker.Stop()
 	for {
 		select {
 		case <-ticker.C:
 			pro.selection()
+		case <-pro.ctx.Done():
+			return
 		}
 	}
 }
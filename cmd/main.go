@@ -18,15 +18,20 @@
 package main
 
 import (
+	"context"
 	"fmt"
 	"os"
 	"os/signal"
 	"poly-bridge/cacheRedis"
 	"runtime"
+	"sync"
 	"syscall"
 
 	"github.com/polynetwork/bridge-common/metrics"
 	"poly-bridge/activity"
+	"poly-bridge/archive"
+	"poly-bridge/audit"
+	"poly-bridge/backfill"
 	"poly-bridge/basedef"
 	"poly-bridge/chainfeelisten"
 	"poly-bridge/coinpricelisten"
@@ -35,6 +40,9 @@ import (
 	"poly-bridge/crosschaineffect"
 	"poly-bridge/crosschainlisten"
 	"poly-bridge/crosschainstats"
+	"poly-bridge/eventsink"
+	"poly-bridge/migration"
+	"poly-bridge/webhook"
 
 	"github.com/beego/beego/v2/core/logs"
 	"github.com/beego/beego/v2/server/web"
@@ -58,6 +66,12 @@ func setupApp() *cli.App {
 	return app
 }
 
+var (
+	serverCancel  context.CancelFunc
+	serverCache   cacheRedis.Cache
+	metricsServer sync.Once
+)
+
 func StartServer(ctx *cli.Context) {
 	for true {
 		startServer(ctx)
@@ -79,28 +93,52 @@ func startServer(ctx *cli.Context) {
 		return
 	}
 	logs.SetLogger(logs.AdapterFile, fmt.Sprintf(`{"filename":"%s"}`, config.ServerLogFile))
+	serverCtx, cancel := context.WithCancel(context.Background())
+	serverCancel = cancel
 
 	//{
 	//	conf, _ := json.Marshal(config)
 	//	logs.Info("%s\n", string(conf))
 	//}
 	//initialize redis
-	cacheRedis.Init()
+	cache, err := cacheRedis.NewCache(config.RedisConfig)
+	if err != nil {
+		panic(err)
+	}
+	serverCache = cache
 
 	metrics.Init("bridge")
 	basedef.ConfirmEnv(config.Env)
-	common.SetupChainsSDK(config)
+	if err := migration.CheckSchema(config.DBConfig); err != nil {
+		panic(err)
+	}
+	common.SetupChainsSDKWithContext(serverCtx, config)
 	if config.Backup {
-		crosschainlisten.StartCrossChainListen(config)
-		crosschainlisten.StartCrossChainListenPatch(config)
+		crosschainlisten.StartCrossChainListen(serverCtx, config, cache)
+		crosschainlisten.StartCrossChainListenPatch(serverCtx, config)
 		return
 	}
-	crosschainlisten.StartCrossChainListen(config)
-	coinpricelisten.StartCoinPriceListen(config.Server, config.CoinPriceUpdateSlot, config.CoinPriceListenConfig, config.DBConfig)
-	chainfeelisten.StartFeeListen(config.Server, config.FeeUpdateSlot, config.FeeListenConfig, config.DBConfig)
-	crosschaineffect.StartCrossChainEffect(config.Server, config.EventEffectConfig, config.DBConfig, config.RedisConfig)
-	crosschainstats.StartCrossChainStats(config.Server, config.StatsConfig, config.DBConfig, config.IPPortConfig, config.ChainListenConfig)
-	activity.StartActivity(config.Server, config.ActivityConfig, config.DBConfig)
+	crosschainlisten.StartCrossChainListen(serverCtx, config, cache)
+	coinpricelisten.StartCoinPriceListen(serverCtx, config.Server, config.CoinPriceUpdateSlot, config.CoinPriceListenConfig, config.DBConfig)
+	chainfeelisten.StartFeeListen(serverCtx, config.Server, config.FeeUpdateSlot, config.FeeListenConfig, config.FeeHistoryConfig, config.DBConfig)
+	crosschaineffect.StartCrossChainEffect(serverCtx, config.Server, config.EventEffectConfig, config.DBConfig, config.RedisConfig, cache)
+	crosschainstats.StartCrossChainStats(serverCtx, config.Server, config.StatsConfig, config.DBConfig, config.IPPortConfig, config.ChainListenConfig)
+	activity.StartActivity(serverCtx, config.Server, config.ActivityConfig, config.DBConfig)
+	if config.BackfillConfig != nil {
+		backfill.StartBackfill(serverCtx, config)
+	}
+	if config.EventSinkConfig != nil {
+		eventsink.StartEventSink(serverCtx, config)
+	}
+	if config.WebhookConfig != nil {
+		webhook.StartWebhook(serverCtx, config)
+	}
+	if config.ArchiveConfig != nil {
+		archive.StartArchive(serverCtx, config)
+	}
+	if config.AuditConfig != nil {
+		audit.StartAudit(serverCtx, config)
+	}
 
 	metricConfig := config.MetricConfig
 	if metricConfig == nil {
@@ -117,7 +155,14 @@ func startServer(ctx *cli.Context) {
 	web.BConfig.AppName = "bridge-server"
 	web.BConfig.CopyRequestBody = true
 	web.BConfig.EnableErrorsRender = false
-	go web.Run()
+	// the metrics server keeps running across reloads
+	metricsServer.Do(func() {
+		// the backfill admin endpoints are only served with an api token configured
+		if config.BackfillConfig != nil && config.BackfillConfig.ApiToken != "" {
+			web.AddNamespace(web.NewNamespace("/v1", backfill.GetRouter()))
+		}
+		go web.Run()
+	})
 }
 
 func waitSignal() os.Signal {
@@ -143,6 +188,21 @@ func stopServer() {
 	chainfeelisten.StopFeeListen()
 	crosschaineffect.StopCrossChainEffect()
 	crosschainstats.StopCrossChainStats()
+	activity.StopActivity()
+	backfill.StopBackfill()
+	eventsink.StopEventSink()
+	webhook.StopWebhook()
+	archive.StopArchive()
+	audit.StopAudit()
+	if serverCancel != nil {
+		serverCancel()
+	}
+	if serverCache != nil {
+		if err := serverCache.Close(); err != nil {
+			logs.Error("close cache err: %v", err)
+		}
+		serverCache = nil
+	}
 }
 
 func main() {
@@ -19,12 +19,11 @@ package bridgedao
 
 import (
 	"fmt"
-	"gorm.io/driver/mysql"
 	"gorm.io/gorm"
-	"gorm.io/gorm/logger"
 	"poly-bridge/basedef"
 	"poly-bridge/conf"
 	"poly-bridge/models"
+	"poly-bridge/utils/database"
 )
 
 type BridgeDao struct {
@@ -36,12 +35,7 @@ func NewBridgeDao(dbCfg *conf.DBConfig) *BridgeDao {
 	swapDao := &BridgeDao{
 		dbCfg: dbCfg,
 	}
-	Logger := logger.Default
-	if dbCfg.Debug == true {
-		Logger = Logger.LogMode(logger.Info)
-	}
-	db, err := gorm.Open(mysql.Open(dbCfg.User+":"+dbCfg.Password+"@tcp("+dbCfg.URL+")/"+
-		dbCfg.Scheme+"?charset=utf8"), &gorm.Config{Logger: Logger})
+	db, err := database.Open(dbCfg)
 	if err != nil {
 		panic(err)
 	}
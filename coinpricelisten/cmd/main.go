@@ -18,6 +18,7 @@
 package main
 
 import (
+	"context"
 	"encoding/json"
 	"fmt"
 	"github.com/beego/beego/v2/core/logs"
@@ -26,6 +27,7 @@ import (
 	"os/signal"
 	"poly-bridge/coinpricelisten"
 	"poly-bridge/conf"
+	"poly-bridge/migration"
 	"runtime"
 	"strings"
 	"syscall"
@@ -104,7 +106,10 @@ func startServer(ctx *cli.Context) {
 		conf, _ := json.Marshal(config)
 		logs.Info("%s\n", string(conf))
 	}
-	coinpricelisten.StartCoinPriceListen(config.Server, config.CoinPriceUpdateSlot, config.CoinPriceListenConfig, config.DBConfig)
+	if err := migration.CheckSchema(config.DBConfig); err != nil {
+		panic(err)
+	}
+	coinpricelisten.StartCoinPriceListen(context.Background(), config.Server, config.CoinPriceUpdateSlot, config.CoinPriceListenConfig, config.DBConfig)
 }
 
 func waitSignal() os.Signal {
@@ -18,6 +18,7 @@
 package coinpricelisten
 
 import (
+	"context"
 	"github.com/beego/beego/v2/core/logs"
 	"math/big"
 	"poly-bridge/basedef"
@@ -32,12 +33,13 @@ import (
 	"poly-bridge/models"
 	"runtime/debug"
 	"strings"
+	"sync"
 	"time"
 )
 
 var cpListen *CoinPriceListen
 
-func StartCoinPriceListen(server string, priceUpdateSlot int64, coinPricecfg []*conf.CoinPriceListenConfig, dbCfg *conf.DBConfig) {
+func StartCoinPriceListen(ctx context.Context, server string, priceUpdateSlot int64, coinPricecfg []*conf.CoinPriceListenConfig, dbCfg *conf.DBConfig) {
 	dao := coinpricedao.NewCoinPriceDao(server, dbCfg)
 	if dao == nil {
 		panic("server is not valid")
@@ -50,7 +52,7 @@ func StartCoinPriceListen(server string, priceUpdateSlot int64, coinPricecfg []*
 		}
 		priceMarkets = append(priceMarkets, priceMarket)
 	}
-	cpListen = NewCoinPriceListen(priceUpdateSlot, priceMarkets, dao)
+	cpListen = NewCoinPriceListen(ctx, priceUpdateSlot, priceMarkets, dao)
 	cpListen.Start()
 }
 
@@ -88,14 +90,16 @@ type CoinPriceListen struct {
 	priceUpdateSlot int64
 	priceMarket     map[string]PriceMarket
 	db              coinpricedao.CoinPriceDao
-	exit            chan bool
+	ctx             context.Context
+	cancel          context.CancelFunc
+	wg              sync.WaitGroup
 }
 
-func NewCoinPriceListen(priceUpdateSlot int64, priceMarkets []PriceMarket, db coinpricedao.CoinPriceDao) *CoinPriceListen {
+func NewCoinPriceListen(ctx context.Context, priceUpdateSlot int64, priceMarkets []PriceMarket, db coinpricedao.CoinPriceDao) *CoinPriceListen {
 	cpListen := &CoinPriceListen{}
 	cpListen.priceUpdateSlot = priceUpdateSlot
 	cpListen.db = db
-	cpListen.exit = make(chan bool, 0)
+	cpListen.ctx, cpListen.cancel = context.WithCancel(ctx)
 	cpListen.priceMarket = make(map[string]PriceMarket)
 	for _, market := range priceMarkets {
 		cpListen.priceMarket[market.GetMarketName()] = market
@@ -122,11 +126,16 @@ func (cpl *CoinPriceListen) RegisterPriceQuery(priceMarket PriceMarket) {
 
 func (cpl *CoinPriceListen) Start() {
 	logs.Info("start coin price listen.")
-	go cpl.ListenPrice()
+	cpl.wg.Add(1)
+	go func() {
+		defer cpl.wg.Done()
+		cpl.ListenPrice()
+	}()
 }
 
 func (cpl *CoinPriceListen) Stop() {
-	cpl.exit <- true
+	cpl.cancel()
+	cpl.wg.Wait()
 	logs.Info("stop coin price listen.")
 }
 
@@ -134,10 +143,13 @@ func (cpl *CoinPriceListen) ListenPrice() {
 	for {
 		exit := cpl.listenPrice()
 		if exit {
-			close(cpl.exit)
 			break
 		}
-		time.Sleep(time.Second * 5)
+		select {
+		case <-time.After(time.Second * 5):
+		case <-cpl.ctx.Done():
+			return
+		}
 	}
 }
 
@@ -151,6 +163,7 @@ func (cpl *CoinPriceListen) listenPrice() (exit bool) {
 
 	logs.Debug("coin price listen, market: %s, dao: %s......", cpl.GetPriceMarket(), cpl.db.Name())
 	ticker := time.NewTicker(time.Minute)
+	defer ticker.Stop()
 	for {
 		select {
 		case <-ticker.C:
@@ -159,7 +172,7 @@ func (cpl *CoinPriceListen) listenPrice() (exit bool) {
 				continue
 			}
 			counter := 0
-			for counter < 5 {
+			for counter < 5 && cpl.ctx.Err() == nil {
 				logs.Info("do price update at time: %s", time.Now().Format("2006-01-02 15:04:05"))
 				time.Sleep(time.Second * 5)
 				counter++
@@ -180,7 +193,7 @@ func (cpl *CoinPriceListen) listenPrice() (exit bool) {
 				}
 				break
 			}
-		case <-cpl.exit:
+		case <-cpl.ctx.Done():
 			logs.Info("coin price listen exit, market: %s, dao: %s......", cpl.GetPriceMarket(), cpl.db.Name())
 			return true
 		}
@@ -1,6 +1,7 @@
 package test
 
 import (
+	"context"
 	"fmt"
 	"os"
 	"poly-bridge/basedef"
@@ -30,7 +31,7 @@ func TestListenCoinPrice(t *testing.T) {
 		priceMarket := coinpricelisten.NewPriceMarket(cfg)
 		priceMarkets = append(priceMarkets, priceMarket)
 	}
-	_ = coinpricelisten.NewCoinPriceListen(config.CoinPriceUpdateSlot, priceMarkets, dao)
+	_ = coinpricelisten.NewCoinPriceListen(context.Background(), config.CoinPriceUpdateSlot, priceMarkets, dao)
 	//	cpListen.ListenPrice()
 }
 
@@ -54,6 +55,6 @@ func TestListenSelfCoinPrice(t *testing.T) {
 		priceMarket := coinpricelisten.NewPriceMarket(cfg)
 		priceMarkets = append(priceMarkets, priceMarket)
 	}
-	cpListen := coinpricelisten.NewCoinPriceListen(config.CoinPriceUpdateSlot, priceMarkets, dao)
+	cpListen := coinpricelisten.NewCoinPriceListen(context.Background(), config.CoinPriceUpdateSlot, priceMarkets, dao)
 	cpListen.ListenPrice()
 }
@@ -1,6 +1,7 @@
 package common
 
 import (
+	"context"
 	"fmt"
 	"github.com/ethereum/go-ethereum/common"
 	"math/big"
@@ -52,6 +53,7 @@ var (
 	dexitSdk      *chainsdk.EthereumSdkPro
 	cloudtxSdk    *chainsdk.EthereumSdkPro
 	config        *conf.Config
+	sdkCtx        context.Context
 )
 
 func GetSdk(chainId uint64) interface{} {
@@ -59,10 +61,16 @@ func GetSdk(chainId uint64) interface{} {
 }
 
 func SetupChainsSDK(cfg *conf.Config) {
+	SetupChainsSDKWithContext(context.Background(), cfg)
+}
+
+// SetupChainsSDKWithContext creates the chain sdks, their node selection routines exit when ctx is done
+func SetupChainsSDKWithContext(ctx context.Context, cfg *conf.Config) {
 	if cfg == nil {
 		panic("Missing config")
 	}
 	config = cfg
+	sdkCtx = ctx
 	newChainSdks(cfg)
 }
 
@@ -74,7 +82,7 @@ func newChainSdks(config *conf.Config) {
 			panic("chain is invalid")
 		}
 		urls := ethereumConfig.GetNodesUrl()
-		ethereumSdk = chainsdk.NewEthereumSdkPro(urls, ethereumConfig.ListenSlot, ethereumConfig.ChainId)
+		ethereumSdk = chainsdk.NewEthereumSdkProWithContext(sdkCtx, urls, ethereumConfig.ListenSlot, ethereumConfig.ChainId)
 		sdkMap[basedef.ETHEREUM_CROSSCHAIN_ID] = ethereumSdk
 	}
 	{
@@ -83,7 +91,7 @@ func newChainSdks(config *conf.Config) {
 			panic("chain is invalid")
 		}
 		urls := maticConfig.GetNodesUrl()
-		maticSdk = chainsdk.NewEthereumSdkPro(urls, maticConfig.ListenSlot, maticConfig.ChainId)
+		maticSdk = chainsdk.NewEthereumSdkProWithContext(sdkCtx, urls, maticConfig.ListenSlot, maticConfig.ChainId)
 		sdkMap[basedef.MATIC_CROSSCHAIN_ID] = maticSdk
 	}
 	{
@@ -92,7 +100,7 @@ func newChainSdks(config *conf.Config) {
 			panic("chain is invalid")
 		}
 		urls := bscConfig.GetNodesUrl()
-		bscSdk = chainsdk.NewEthereumSdkPro(urls, bscConfig.ListenSlot, bscConfig.ChainId)
+		bscSdk = chainsdk.NewEthereumSdkProWithContext(sdkCtx, urls, bscConfig.ListenSlot, bscConfig.ChainId)
 		sdkMap[basedef.BSC_CROSSCHAIN_ID] = bscSdk
 	}
 	{
@@ -101,7 +109,7 @@ func newChainSdks(config *conf.Config) {
 			panic("chain is invalid")
 		}
 		urls := hecoConfig.GetNodesUrl()
-		hecoSdk = chainsdk.NewEthereumSdkPro(urls, hecoConfig.ListenSlot, hecoConfig.ChainId)
+		hecoSdk = chainsdk.NewEthereumSdkProWithContext(sdkCtx, urls, hecoConfig.ListenSlot, hecoConfig.ChainId)
 		sdkMap[basedef.HECO_CROSSCHAIN_ID] = hecoSdk
 	}
 	{
@@ -110,7 +118,7 @@ func newChainSdks(config *conf.Config) {
 			panic("chain is invalid")
 		}
 		urls := okConfig.GetNodesUrl()
-		okSdk = chainsdk.NewEthereumSdkPro(urls, okConfig.ListenSlot, okConfig.ChainId)
+		okSdk = chainsdk.NewEthereumSdkProWithContext(sdkCtx, urls, okConfig.ListenSlot, okConfig.ChainId)
 		sdkMap[basedef.OK_CROSSCHAIN_ID] = okSdk
 	}
 	{
@@ -119,7 +127,7 @@ func newChainSdks(config *conf.Config) {
 			panic("chain is invalid")
 		}
 		urls := neoConfig.GetNodesUrl()
-		neoSdk = chainsdk.NewNeoSdkPro(urls, neoConfig.ListenSlot, neoConfig.ChainId)
+		neoSdk = chainsdk.NewNeoSdkProWithContext(sdkCtx, urls, neoConfig.ListenSlot, neoConfig.ChainId)
 		sdkMap[basedef.NEO_CROSSCHAIN_ID] = neoSdk
 	}
 	{
@@ -129,7 +137,7 @@ func newChainSdks(config *conf.Config) {
 				panic("chain is invalid")
 			}
 			urls := neo3Config.GetNodesUrl()
-			neo3Sdk = chainsdk.NewNeo3SdkPro(urls, neo3Config.ListenSlot, neo3Config.ChainId)
+			neo3Sdk = chainsdk.NewNeo3SdkProWithContext(sdkCtx, urls, neo3Config.ListenSlot, neo3Config.ChainId)
 			sdkMap[basedef.NEO3_CROSSCHAIN_ID] = neo3Sdk
 		}
 	}
@@ -139,7 +147,7 @@ func newChainSdks(config *conf.Config) {
 			panic("chain is invalid")
 		}
 		urls := ontConfig.GetNodesUrl()
-		ontologySdk = chainsdk.NewOntologySdkPro(urls, ontConfig.ListenSlot, ontConfig.ChainId)
+		ontologySdk = chainsdk.NewOntologySdkProWithContext(sdkCtx, urls, ontConfig.ListenSlot, ontConfig.ChainId)
 		sdkMap[basedef.ONT_CROSSCHAIN_ID] = ontologySdk
 	}
 	if basedef.ENV == basedef.MAINNET {
@@ -148,14 +156,14 @@ func newChainSdks(config *conf.Config) {
 			panic("swth chain is invalid")
 		}
 		urls := swthConfig.GetNodesUrl()
-		swthSdk = chainsdk.NewSwitcheoSdkPro(urls, swthConfig.ListenSlot, swthConfig.ChainId)
+		swthSdk = chainsdk.NewSwitcheoSdkProWithContext(sdkCtx, urls, swthConfig.ListenSlot, swthConfig.ChainId)
 		sdkMap[basedef.SWITCHEO_CROSSCHAIN_ID] = swthSdk
 	}
 	{
 		conf := config.GetChainListenConfig(basedef.PLT_CROSSCHAIN_ID)
 		if conf != nil {
 			urls := conf.GetNodesUrl()
-			pltSdk = chainsdk.NewEthereumSdkPro(urls, conf.ListenSlot, conf.ChainId)
+			pltSdk = chainsdk.NewEthereumSdkProWithContext(sdkCtx, urls, conf.ListenSlot, conf.ChainId)
 			sdkMap[basedef.PLT_CROSSCHAIN_ID] = pltSdk
 		} else {
 			logs.Error("Missing plt chain sdk config")
@@ -167,7 +175,7 @@ func newChainSdks(config *conf.Config) {
 			panic("chain is invalid")
 		}
 		urls := arbitrumConfig.GetNodesUrl()
-		arbitrumSdk = chainsdk.NewEthereumSdkPro(urls, arbitrumConfig.ListenSlot, arbitrumConfig.ChainId)
+		arbitrumSdk = chainsdk.NewEthereumSdkProWithContext(sdkCtx, urls, arbitrumConfig.ListenSlot, arbitrumConfig.ChainId)
 		sdkMap[basedef.ARBITRUM_CROSSCHAIN_ID] = arbitrumSdk
 	}
 	{
@@ -176,7 +184,7 @@ func newChainSdks(config *conf.Config) {
 			panic("chain:XDAI is invalid")
 		}
 		urls := xdaiConfig.GetNodesUrl()
-		xdaiSdk = chainsdk.NewEthereumSdkPro(urls, xdaiConfig.ListenSlot, xdaiConfig.ChainId)
+		xdaiSdk = chainsdk.NewEthereumSdkProWithContext(sdkCtx, urls, xdaiConfig.ListenSlot, xdaiConfig.ChainId)
 		sdkMap[basedef.XDAI_CROSSCHAIN_ID] = xdaiSdk
 	}
 	{
@@ -185,7 +193,7 @@ func newChainSdks(config *conf.Config) {
 			panic("zilliqa GetChainListenConfig chain is invalid")
 		}
 		urls := zilliqaCfg.GetNodesUrl()
-		zilliqaSdk = chainsdk.NewZilliqaSdkPro(urls, zilliqaCfg.ListenSlot, zilliqaCfg.ChainId)
+		zilliqaSdk = chainsdk.NewZilliqaSdkProWithContext(sdkCtx, urls, zilliqaCfg.ListenSlot, zilliqaCfg.ChainId)
 		sdkMap[basedef.ZILLIQA_CROSSCHAIN_ID] = zilliqaSdk
 	}
 	{
@@ -194,7 +202,7 @@ func newChainSdks(config *conf.Config) {
 			panic("chain is invalid")
 		}
 		urls := fantomConfig.GetNodesUrl()
-		fantomSdk = chainsdk.NewEthereumSdkPro(urls, fantomConfig.ListenSlot, fantomConfig.ChainId)
+		fantomSdk = chainsdk.NewEthereumSdkProWithContext(sdkCtx, urls, fantomConfig.ListenSlot, fantomConfig.ChainId)
 		sdkMap[basedef.FANTOM_CROSSCHAIN_ID] = fantomSdk
 	}
 	{
@@ -203,7 +211,7 @@ func newChainSdks(config *conf.Config) {
 			panic("chain is invalid")
 		}
 		urls := avaxConfig.GetNodesUrl()
-		avaxSdk = chainsdk.NewEthereumSdkPro(urls, avaxConfig.ListenSlot, avaxConfig.ChainId)
+		avaxSdk = chainsdk.NewEthereumSdkProWithContext(sdkCtx, urls, avaxConfig.ListenSlot, avaxConfig.ChainId)
 		sdkMap[basedef.AVAX_CROSSCHAIN_ID] = avaxSdk
 	}
 	{
@@ -212,7 +220,7 @@ func newChainSdks(config *conf.Config) {
 			panic("chain is invalid")
 		}
 		urls := optimisticConfig.GetNodesUrl()
-		optimisticSdk = chainsdk.NewEthereumSdkPro(urls, optimisticConfig.ListenSlot, optimisticConfig.ChainId)
+		optimisticSdk = chainsdk.NewEthereumSdkProWithContext(sdkCtx, urls, optimisticConfig.ListenSlot, optimisticConfig.ChainId)
 		sdkMap[basedef.OPTIMISTIC_CROSSCHAIN_ID] = optimisticSdk
 	}
 	{
@@ -221,7 +229,7 @@ func newChainSdks(config *conf.Config) {
 			panic("metis chain is invalid")
 		}
 		urls := metisConfig.GetNodesUrl()
-		metisSdk = chainsdk.NewEthereumSdkPro(urls, metisConfig.ListenSlot, metisConfig.ChainId)
+		metisSdk = chainsdk.NewEthereumSdkProWithContext(sdkCtx, urls, metisConfig.ListenSlot, metisConfig.ChainId)
 		sdkMap[basedef.METIS_CROSSCHAIN_ID] = metisSdk
 	}
 	{
@@ -230,7 +238,7 @@ func newChainSdks(config *conf.Config) {
 			panic("boba chain is invalid")
 		}
 		urls := bobaConfig.GetNodesUrl()
-		bobaSdk = chainsdk.NewEthereumSdkPro(urls, bobaConfig.ListenSlot, bobaConfig.ChainId)
+		bobaSdk = chainsdk.NewEthereumSdkProWithContext(sdkCtx, urls, bobaConfig.ListenSlot, bobaConfig.ChainId)
 		sdkMap[basedef.BOBA_CROSSCHAIN_ID] = bobaSdk
 	}
 	{
@@ -239,7 +247,7 @@ func newChainSdks(config *conf.Config) {
 			panic("starcoin chain is invalid")
 		}
 		urls := starcoinConfig.GetNodesUrl()
-		starcoinSdk = chainsdk.NewStarcoinSdkPro(urls, starcoinConfig.ListenSlot, starcoinConfig.ChainId)
+		starcoinSdk = chainsdk.NewStarcoinSdkProWithContext(sdkCtx, urls, starcoinConfig.ListenSlot, starcoinConfig.ChainId)
 		sdkMap[basedef.STARCOIN_CROSSCHAIN_ID] = starcoinSdk
 	}
 	if basedef.ENV == basedef.TESTNET {
@@ -249,7 +257,7 @@ func newChainSdks(config *conf.Config) {
 				panic("rinkeby chain is invalid")
 			}
 			urls := rinkebyConfig.GetNodesUrl()
-			rinkebySdk = chainsdk.NewEthereumSdkPro(urls, rinkebyConfig.ListenSlot, rinkebyConfig.ChainId)
+			rinkebySdk = chainsdk.NewEthereumSdkProWithContext(sdkCtx, urls, rinkebyConfig.ListenSlot, rinkebyConfig.ChainId)
 			sdkMap[basedef.RINKEBY_CROSSCHAIN_ID] = rinkebySdk
 		}
 	}
@@ -259,7 +267,7 @@ func newChainSdks(config *conf.Config) {
 			panic("oasis chain is invalid")
 		}
 		urls := chainConfig.GetNodesUrl()
-		oasisSdk = chainsdk.NewEthereumSdkPro(urls, chainConfig.ListenSlot, chainConfig.ChainId)
+		oasisSdk = chainsdk.NewEthereumSdkProWithContext(sdkCtx, urls, chainConfig.ListenSlot, chainConfig.ChainId)
 		sdkMap[basedef.OASIS_CROSSCHAIN_ID] = oasisSdk
 	}
 	{
@@ -268,7 +276,7 @@ func newChainSdks(config *conf.Config) {
 			panic("harmony chain is invalid")
 		}
 		urls := chainConfig.GetNodesUrl()
-		harmonySdk = chainsdk.NewEthereumSdkPro(urls, chainConfig.ListenSlot, chainConfig.ChainId)
+		harmonySdk = chainsdk.NewEthereumSdkProWithContext(sdkCtx, urls, chainConfig.ListenSlot, chainConfig.ChainId)
 		sdkMap[basedef.HARMONY_CROSSCHAIN_ID] = harmonySdk
 	}
 	{
@@ -277,7 +285,7 @@ func newChainSdks(config *conf.Config) {
 			panic("kcc chain is invalid")
 		}
 		urls := chainConfig.GetNodesUrl()
-		kccSdk = chainsdk.NewEthereumSdkPro(urls, chainConfig.ListenSlot, chainConfig.ChainId)
+		kccSdk = chainsdk.NewEthereumSdkProWithContext(sdkCtx, urls, chainConfig.ListenSlot, chainConfig.ChainId)
 		sdkMap[basedef.KCC_CROSSCHAIN_ID] = kccSdk
 	}
 	{
@@ -286,7 +294,7 @@ func newChainSdks(config *conf.Config) {
 			panic("bytom chain is invalid")
 		}
 		urls := bytomConfig.GetNodesUrl()
-		bytomSdk = chainsdk.NewEthereumSdkPro(urls, bytomConfig.ListenSlot, bytomConfig.ChainId)
+		bytomSdk = chainsdk.NewEthereumSdkProWithContext(sdkCtx, urls, bytomConfig.ListenSlot, bytomConfig.ChainId)
 		sdkMap[basedef.BYTOM_CROSSCHAIN_ID] = bytomSdk
 	}
 	{
@@ -295,7 +303,7 @@ func newChainSdks(config *conf.Config) {
 			panic("chain HSC is invalid")
 		}
 		urls := chainConfig.GetNodesUrl()
-		hscSdk = chainsdk.NewEthereumSdkPro(urls, chainConfig.ListenSlot, chainConfig.ChainId)
+		hscSdk = chainsdk.NewEthereumSdkProWithContext(sdkCtx, urls, chainConfig.ListenSlot, chainConfig.ChainId)
 		sdkMap[basedef.HSC_CROSSCHAIN_ID] = hscSdk
 	}
 	{
@@ -304,7 +312,7 @@ func newChainSdks(config *conf.Config) {
 			panic("kava chain is invalid")
 		}
 		urls := kavaConfig.GetNodesUrl()
-		kavaSdk = chainsdk.NewEthereumSdkPro(urls, kavaConfig.ListenSlot, kavaConfig.ChainId)
+		kavaSdk = chainsdk.NewEthereumSdkProWithContext(sdkCtx, urls, kavaConfig.ListenSlot, kavaConfig.ChainId)
 		sdkMap[basedef.KAVA_CROSSCHAIN_ID] = kavaSdk
 	}
 	{
@@ -313,7 +321,7 @@ func newChainSdks(config *conf.Config) {
 			panic("cube chain is invalid")
 		}
 		urls := cubeConfig.GetNodesUrl()
-		cubeSdk = chainsdk.NewEthereumSdkPro(urls, cubeConfig.ListenSlot, cubeConfig.ChainId)
+		cubeSdk = chainsdk.NewEthereumSdkProWithContext(sdkCtx, urls, cubeConfig.ListenSlot, cubeConfig.ChainId)
 		sdkMap[basedef.CUBE_CROSSCHAIN_ID] = cubeSdk
 	}
 	//{
@@ -322,7 +330,7 @@ func newChainSdks(config *conf.Config) {
 	//		panic("zkSync chain is invalid")
 	//	}
 	//	urls := chainConfig.GetNodesUrl()
-	//	zkSyncSdk = chainsdk.NewEthereumSdkPro(urls, chainConfig.ListenSlot, chainConfig.ChainId)
+	//	zkSyncSdk = chainsdk.NewEthereumSdkProWithContext(sdkCtx, urls, chainConfig.ListenSlot, chainConfig.ChainId)
 	//	sdkMap[basedef.ZKSYNC_CROSSCHAIN_ID] = zkSyncSdk
 	//}
 	{
@@ -331,7 +339,7 @@ func newChainSdks(config *conf.Config) {
 			panic("celo chain is invalid")
 		}
 		urls := chainConfig.GetNodesUrl()
-		celoSdk = chainsdk.NewEthereumSdkPro(urls, chainConfig.ListenSlot, chainConfig.ChainId)
+		celoSdk = chainsdk.NewEthereumSdkProWithContext(sdkCtx, urls, chainConfig.ListenSlot, chainConfig.ChainId)
 		sdkMap[basedef.CELO_CROSSCHAIN_ID] = celoSdk
 	}
 	{
@@ -340,7 +348,7 @@ func newChainSdks(config *conf.Config) {
 			panic("clover chain is invalid")
 		}
 		urls := chainConfig.GetNodesUrl()
-		cloverSdk = chainsdk.NewEthereumSdkPro(urls, chainConfig.ListenSlot, chainConfig.ChainId)
+		cloverSdk = chainsdk.NewEthereumSdkProWithContext(sdkCtx, urls, chainConfig.ListenSlot, chainConfig.ChainId)
 		sdkMap[basedef.CLOVER_CROSSCHAIN_ID] = cloverSdk
 	}
 	{
@@ -349,7 +357,7 @@ func newChainSdks(config *conf.Config) {
 			panic("conflux chain is invalid")
 		}
 		urls := chainConfig.GetNodesUrl()
-		confluxSdk = chainsdk.NewEthereumSdkPro(urls, chainConfig.ListenSlot, chainConfig.ChainId)
+		confluxSdk = chainsdk.NewEthereumSdkProWithContext(sdkCtx, urls, chainConfig.ListenSlot, chainConfig.ChainId)
 		sdkMap[basedef.CONFLUX_CROSSCHAIN_ID] = confluxSdk
 	}
 	{
@@ -358,7 +366,7 @@ func newChainSdks(config *conf.Config) {
 			panic("ripple chain is invalid")
 		}
 		urls := cfg.GetNodesUrl()
-		rippleSdk = chainsdk.NewRippleSdkPro(urls, cfg.ListenSlot, cfg.ChainId)
+		rippleSdk = chainsdk.NewRippleSdkProWithContext(sdkCtx, urls, cfg.ListenSlot, cfg.ChainId)
 		sdkMap[basedef.RIPPLE_CROSSCHAIN_ID] = rippleSdk
 	}
 	{
@@ -367,7 +375,7 @@ func newChainSdks(config *conf.Config) {
 			panic("astar chain is invalid")
 		}
 		urls := chainConfig.GetNodesUrl()
-		astarSdk = chainsdk.NewEthereumSdkPro(urls, chainConfig.ListenSlot, chainConfig.ChainId)
+		astarSdk = chainsdk.NewEthereumSdkProWithContext(sdkCtx, urls, chainConfig.ListenSlot, chainConfig.ChainId)
 		sdkMap[basedef.ASTAR_CROSSCHAIN_ID] = astarSdk
 	}
 	{
@@ -376,7 +384,7 @@ func newChainSdks(config *conf.Config) {
 			panic("aptos chain is invalid")
 		}
 		urls := aptosConfig.GetNodesUrl()
-		aptosSdk = chainsdk.NewAptosSdkPro(urls, aptosConfig.ListenSlot, aptosConfig.ChainId)
+		aptosSdk = chainsdk.NewAptosSdkProWithContext(sdkCtx, urls, aptosConfig.ListenSlot, aptosConfig.ChainId)
 		sdkMap[basedef.APTOS_CROSSCHAIN_ID] = aptosSdk
 	}
 	{
@@ -385,7 +393,7 @@ func newChainSdks(config *conf.Config) {
 			panic("brise chain is invalid")
 		}
 		urls := chainConfig.GetNodesUrl()
-		briseSdk = chainsdk.NewEthereumSdkPro(urls, chainConfig.ListenSlot, chainConfig.ChainId)
+		briseSdk = chainsdk.NewEthereumSdkProWithContext(sdkCtx, urls, chainConfig.ListenSlot, chainConfig.ChainId)
 		sdkMap[basedef.BRISE_CROSSCHAIN_ID] = briseSdk
 	}
 	{
@@ -394,7 +402,7 @@ func newChainSdks(config *conf.Config) {
 			panic("dexit chain is invalid")
 		}
 		urls := chainConfig.GetNodesUrl()
-		dexitSdk = chainsdk.NewEthereumSdkPro(urls, chainConfig.ListenSlot, chainConfig.ChainId)
+		dexitSdk = chainsdk.NewEthereumSdkProWithContext(sdkCtx, urls, chainConfig.ListenSlot, chainConfig.ChainId)
 		sdkMap[basedef.DEXIT_CROSSCHAIN_ID] = dexitSdk
 	}
 	{
@@ -403,7 +411,7 @@ func newChainSdks(config *conf.Config) {
 			panic("cloudtx chain is invalid")
 		}
 		urls := chainConfig.GetNodesUrl()
-		cloudtxSdk = chainsdk.NewEthereumSdkPro(urls, chainConfig.ListenSlot, chainConfig.ChainId)
+		cloudtxSdk = chainsdk.NewEthereumSdkProWithContext(sdkCtx, urls, chainConfig.ListenSlot, chainConfig.ChainId)
 		sdkMap[basedef.CLOUDTX_CROSSCHAIN_ID] = cloudtxSdk
 	}
 }
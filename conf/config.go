@@ -46,11 +46,18 @@ func GetFlagName(flag cli.Flag) string {
 }
 
 type DBConfig struct {
+	Dialect  string // mysql (default), postgres or sqlite, Scheme is the database file of sqlite
 	URL      string
 	User     string
 	Password string
 	Scheme   string
 	Debug    bool
+	SSLMode  string // sslmode of postgres, disable by default
+	// Replicas are the read replicas of URL with the same user, password and scheme (the database files for sqlite),
+	// the read only queries of the API servers go to them
+	Replicas []string
+	// MaxReplicaLag is the replication lag in seconds over which a replica is skipped, 30 by default
+	MaxReplicaLag int64
 }
 
 type RedisConfig struct {
@@ -66,6 +73,14 @@ type RedisConfig struct {
 	Expiration   time.Duration `json:"expiration"`
 }
 
+// GetExpiration is the expiration of the cached counters, they never expire without a redis config
+func (cfg *RedisConfig) GetExpiration() time.Duration {
+	if cfg == nil {
+		return 0
+	}
+	return cfg.Expiration * time.Second
+}
+
 type ExpConfig struct {
 	URL      string
 	User     string
@@ -94,11 +109,15 @@ type ChainNodes struct {
 type ChainListenConfig struct {
 	ChainName                     string
 	ChainId                       uint64
+	ChainFamily                   string // evm, neo, neo3, ontology, ripple, starcoin, aptos, zilliqa, switcheo, o3 or poly
 	ListenSlot                    uint64
 	Defer                         uint64
 	BatchSize                     uint64
 	MinBatchLength                uint64
 	MaxBatchLength                uint64
+	ReorgDepth                    uint64 // number of processed heights kept for reorg detection, 0 for default
+	Quorum                        uint64 // number of nodes that must agree on heights, block hashes and logs, 0 or 1 disables the quorum mode
+	QuarantineSlot                uint64 // seconds a node disagreeing with the quorum is left out, 0 for default
 	CrossChainEventCreationNumber string
 	ExecuteTxEventCreationNumber  string
 	Nodes                         []*Restful
@@ -141,6 +160,10 @@ type RelayerConfig struct {
 	RelayAccountConfig []*RelayAccountConfig
 }
 
+func (cfg *ChainListenConfig) GetChainFamily() string {
+	return basedef.GetChainFamily(cfg.ChainId)
+}
+
 func (cfg *ChainListenConfig) GetNodesUrl() []string {
 	urls := make([]string, 0)
 	for _, node := range cfg.Nodes {
@@ -203,6 +226,14 @@ type FeeListenConfig struct {
 	GasLimit      int64
 	EthL1GasLimit int64
 	NftRatio      int64
+
+	Eip1559               bool    //fees are priced from the eth_feeHistory base and priority fees instead of the gas price
+	FeeHistoryBlocks      uint64  //blocks read by eth_feeHistory, 20 if 0
+	PriorityFeePercentile float64 //percentile of the priority fees paid in each block, 50 if 0
+	SafetyMargin          int64   //percent getfee adds to the proxy fee priced from the predicted max fee per gas
+
+	L1FeeStrategy  string //static, optimism, arbitrum or zksync L1 data fee of a rollup, static if empty and EthL1GasLimit is set
+	L1CalldataSize int64  //calldata bytes of a cross chain tx priced by the optimism and arbitrum strategies, 1024 if 0
 }
 
 func (cfg *FeeListenConfig) GetNodesUrl() []string {
@@ -295,6 +326,72 @@ type OperationConfig struct {
 	ApiToken string //Operation api token
 }
 
+type BackfillConfig struct {
+	ApiToken    string //admin api token of the backfill endpoints, sent in the X-Api-Token header
+	AllowRemote bool   //serve the backfill endpoints to other hosts than the loopback address
+	JobSlot     int64  //interval in seconds to pick up running jobs
+	BatchLength uint64 //default block range handled by one task
+	Concurrency uint64 //default number of concurrent tasks of a job
+}
+
+type EventSinkConfig struct {
+	Sinks       []*SinkConfig
+	Interval    int64 //interval in seconds to poll the outbox
+	BatchSize   int   //max outbox events delivered in one round
+	MaxAttempts int   //failed deliveries before an event is parked, 0 retries forever
+}
+
+type SinkConfig struct {
+	Type        string            //file, webhook or redis
+	Path        string            //file: jsonl file the events are appended to
+	Url         string            //webhook: endpoint receiving a json array of events
+	Headers     map[string]string //webhook: extra request headers
+	Timeout     int64             //webhook: request timeout in seconds
+	Stream      string            //redis: stream the events are added to
+	MaxLen      int64             //redis: approximate max length of the stream
+	RedisConfig *RedisConfig      //redis: defaults to the RedisConfig of the server
+}
+
+type WebhookConfig struct {
+	ApiToken        string //token required in the X-Api-Token header to add subscriptions, subscribing is disabled without it
+	AllowPrivateUrl bool   //accept callback urls on loopback or private addresses, for local tests only
+	Interval        int64  //interval in seconds to poll the pending deliveries
+	BatchSize       int    //max deliveries sent in one round, also the page size of the subscriptions matched in one query
+	Workers         int    //max concurrent callback requests
+	MaxAttempts     int    //failed attempts before a delivery is given up
+	Timeout         int64  //callback request timeout in seconds
+}
+
+type ArchiveConfig struct {
+	Age      int64  //finished txs older than it in seconds are moved to the archived_ tables
+	Interval int64  //interval in seconds between the archive rounds
+	Batch    int    //max txs moved in one db transaction
+	Dir      string //the archived txs are also exported to <Dir>/cross_chain_txs_<yyyy-mm>.jsonl, none if empty
+}
+
+type AuditConfig struct {
+	Interval int64  //interval in seconds between the audit rounds
+	Batch    int    //max dst transactions checked in one db transaction
+	DingUrl  string //the new findings are posted to it, no alarm if empty
+}
+
+type FeeHistoryConfig struct {
+	Retention int64 //seconds the chain fee snapshots are kept, forever if 0
+}
+
+type FeeQuoteConfig struct {
+	Key       string //HMAC key signing the quote ids returned by getfee, no quote is issued if empty
+	Expiry    int64  //seconds a quote is honored by the fee check after it is issued
+	Retention int64  //seconds an expired quote is kept for the late fee checks, 86400 if 0
+	Interval  int64  //min seconds between two quotes issued to a client ip, 10 if 0
+}
+
+type TxStreamConfig struct {
+	Channel   string //redis channel of the tx status changes
+	Heartbeat int64  //interval in seconds of the keepalive comments sent to the stream clients
+	MaxHashes int    //max source hashes a stream client can watch
+}
+
 type Config struct {
 	Server                string
 	Env                   string
@@ -323,6 +420,14 @@ type Config struct {
 	RelayUrl              string
 	ActivityConfig        *ActivityConfig
 	OperationConfig       *OperationConfig
+	BackfillConfig        *BackfillConfig
+	EventSinkConfig       *EventSinkConfig
+	WebhookConfig         *WebhookConfig
+	TxStreamConfig        *TxStreamConfig
+	ArchiveConfig         *ArchiveConfig
+	AuditConfig           *AuditConfig
+	FeeQuoteConfig        *FeeQuoteConfig
+	FeeHistoryConfig      *FeeHistoryConfig
 }
 
 func (cfg *Config) GetChainListenConfig(chainId uint64) *ChainListenConfig {
@@ -375,6 +480,9 @@ func NewConfig(filePath string) *Config {
 			listenConfig.Nodes = chainNode.Nodes
 			listenConfig.ExtendNodes = chainNode.ExtendNodes
 		}
+		if listenConfig.ChainFamily != "" {
+			basedef.RegisterChainFamily(listenConfig.ChainId, listenConfig.ChainFamily)
+		}
 	}
 	for _, listenConfig := range config.FeeListenConfig {
 		if chainNode, ok := chainNodeMap[listenConfig.ChainId]; ok {
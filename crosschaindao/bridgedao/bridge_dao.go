@@ -18,18 +18,19 @@
 package bridgedao
 
 import (
+	"encoding/json"
 	"errors"
 	"fmt"
 	"github.com/beego/beego/v2/core/logs"
-	"gorm.io/driver/mysql"
 	"gorm.io/gorm"
-	"gorm.io/gorm/logger"
+	"gorm.io/gorm/clause"
 	"math/big"
 	"poly-bridge/basedef"
 	"poly-bridge/conf"
 	serverconf "poly-bridge/conf"
 	"poly-bridge/models"
-	"poly-bridge/utils/decimal"
+	"poly-bridge/utils/cursor"
+	"poly-bridge/utils/database"
 	"poly-bridge/utils/fee"
 	"strings"
 	"time"
@@ -39,6 +40,7 @@ type BridgeDao struct {
 	dbCfg  *conf.DBConfig
 	db     *gorm.DB
 	backup bool
+	outbox bool
 }
 
 func NewBridgeDao(dbCfg *conf.DBConfig, backup bool) *BridgeDao {
@@ -46,12 +48,7 @@ func NewBridgeDao(dbCfg *conf.DBConfig, backup bool) *BridgeDao {
 		dbCfg:  dbCfg,
 		backup: backup,
 	}
-	Logger := logger.Default
-	if dbCfg.Debug == true {
-		Logger = Logger.LogMode(logger.Info)
-	}
-	db, err := gorm.Open(mysql.Open(dbCfg.User+":"+dbCfg.Password+"@tcp("+dbCfg.URL+")/"+
-		dbCfg.Scheme+"?charset=utf8"), &gorm.Config{Logger: Logger})
+	db, err := database.Open(dbCfg)
 	if err != nil {
 		panic(err)
 	}
@@ -61,64 +58,10 @@ func NewBridgeDao(dbCfg *conf.DBConfig, backup bool) *BridgeDao {
 
 func (dao *BridgeDao) UpdateEvents(wrapperTransactions []*models.WrapperTransaction, srcTransactions []*models.SrcTransaction, polyTransactions []*models.PolyTransaction, dstTransactions []*models.DstTransaction, wrapperDetails []*models.WrapperDetail, polyDetails []*models.PolyDetail) error {
 	if !dao.backup {
-		if wrapperTransactions != nil && len(wrapperTransactions) > 0 {
-			res := dao.db.Save(wrapperTransactions)
-			if res.Error != nil {
-				return res.Error
-			}
-		}
-		if srcTransactions != nil && len(srcTransactions) > 0 {
-			res := dao.db.Save(srcTransactions)
-			if res.Error != nil {
-				return res.Error
-			}
-			for _, v := range srcTransactions {
-				if v.SrcTransfer != nil && v.SrcTransfer.TxHash != "" {
-					res := dao.db.
-						Table("src_transfers").
-						Where("tx_hash = ?", v.SrcTransfer.TxHash).
-						Updates(v.SrcTransfer)
-					if res.Error != nil {
-						return res.Error
-					}
-				}
-			}
-		}
-		if polyTransactions != nil && len(polyTransactions) > 0 {
-			res := dao.db.Save(polyTransactions)
-			if res.Error != nil {
-				return res.Error
-			}
-		}
-		if dstTransactions != nil && len(dstTransactions) > 0 {
-			res := dao.db.Save(dstTransactions)
-			if res.Error != nil {
-				return res.Error
-			}
-			for _, v := range dstTransactions {
-				if v.DstTransfer != nil && v.DstTransfer.TxHash != "" {
-					res := dao.db.Table("dst_transfers").
-						Where("tx_hash = ?", v.DstTransfer.TxHash).
-						Updates(v.DstTransfer)
-					if res.Error != nil {
-						return res.Error
-					}
-				}
-			}
-		}
-		if wrapperDetails != nil && len(wrapperDetails) > 0 {
-			res := dao.db.Save(wrapperDetails)
-			if res.Error != nil {
-				return res.Error
-			}
-		}
-		if polyDetails != nil && len(polyDetails) > 0 {
-			res := dao.db.Save(polyDetails)
-			if res.Error != nil {
-				return res.Error
-			}
-		}
-		return nil
+		// the events, the cross chain txs and the outbox are written together
+		return dao.db.Transaction(func(tx *gorm.DB) error {
+			return dao.updateEvents(tx, wrapperTransactions, srcTransactions, polyTransactions, dstTransactions, wrapperDetails, polyDetails)
+		})
 	} else {
 		if wrapperTransactions != nil && len(wrapperTransactions) > 0 {
 			for _, wrapperTransaction := range wrapperTransactions {
@@ -146,6 +89,9 @@ func (dao *BridgeDao) UpdateEvents(wrapperTransactions []*models.WrapperTransact
 			}
 		}
 		if dstTransactions != nil && len(dstTransactions) > 0 {
+			if err := MatchRippleDstTransactions(dao.db, dstTransactions); err != nil {
+				return err
+			}
 			res := dao.db.Save(dstTransactions)
 			if res.Error != nil {
 				return res.Error
@@ -159,20 +105,154 @@ func (dao *BridgeDao) UpdateEvents(wrapperTransactions []*models.WrapperTransact
 				}
 			}
 		}
-		return nil
+		return UpdateCrossChainTxs(dao.db, wrapperTransactions, srcTransactions, nil, dstTransactions)
+	}
+}
+
+func (dao *BridgeDao) updateEvents(db *gorm.DB, wrapperTransactions []*models.WrapperTransaction, srcTransactions []*models.SrcTransaction, polyTransactions []*models.PolyTransaction, dstTransactions []*models.DstTransaction, wrapperDetails []*models.WrapperDetail, polyDetails []*models.PolyDetail) error {
+	if wrapperTransactions != nil && len(wrapperTransactions) > 0 {
+		res := db.Save(wrapperTransactions)
+		if res.Error != nil {
+			return res.Error
+		}
+	}
+	if srcTransactions != nil && len(srcTransactions) > 0 {
+		res := db.Save(srcTransactions)
+		if res.Error != nil {
+			return res.Error
+		}
+		for _, v := range srcTransactions {
+			if v.SrcTransfer != nil && v.SrcTransfer.TxHash != "" {
+				res := db.
+					Table("src_transfers").
+					Where("tx_hash = ?", v.SrcTransfer.TxHash).
+					Updates(v.SrcTransfer)
+				if res.Error != nil {
+					return res.Error
+				}
+			}
+		}
+	}
+	if polyTransactions != nil && len(polyTransactions) > 0 {
+		res := db.Save(polyTransactions)
+		if res.Error != nil {
+			return res.Error
+		}
+	}
+	if dstTransactions != nil && len(dstTransactions) > 0 {
+		if err := MatchRippleDstTransactions(db, dstTransactions); err != nil {
+			return err
+		}
+		res := db.Save(dstTransactions)
+		if res.Error != nil {
+			return res.Error
+		}
+		for _, v := range dstTransactions {
+			if v.DstTransfer != nil && v.DstTransfer.TxHash != "" {
+				res := db.Table("dst_transfers").
+					Where("tx_hash = ?", v.DstTransfer.TxHash).
+					Updates(v.DstTransfer)
+				if res.Error != nil {
+					return res.Error
+				}
+			}
+		}
+	}
+	if wrapperDetails != nil && len(wrapperDetails) > 0 {
+		res := db.Save(wrapperDetails)
+		if res.Error != nil {
+			return res.Error
+		}
 	}
+	if polyDetails != nil && len(polyDetails) > 0 {
+		res := db.Save(polyDetails)
+		if res.Error != nil {
+			return res.Error
+		}
+	}
+	if err := UpdateCrossChainTxs(db, wrapperTransactions, srcTransactions, polyTransactions, dstTransactions); err != nil {
+		return err
+	}
+	if dao.outbox {
+		return addEventOutbox(db, wrapperTransactions, srcTransactions, polyTransactions, dstTransactions)
+	}
+	return nil
 }
 
 func (dao *BridgeDao) RemoveEvents(srcHashes []string, polyHashes []string, dstHashes []string) error {
-	dao.db.Where("`tx_hash` in ?", srcHashes).Delete(&models.SrcTransfer{})
-	dao.db.Where("`hash` in ?", srcHashes).Delete(&models.SrcTransaction{})
-	dao.db.Where("`hash` in ?", srcHashes).Delete(&models.WrapperTransaction{})
+	return dao.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Where("tx_hash in ?", srcHashes).Delete(&models.SrcTransfer{}).Error; err != nil {
+			return err
+		}
+		if err := tx.Where("hash in ?", srcHashes).Delete(&models.SrcTransaction{}).Error; err != nil {
+			return err
+		}
+		if err := tx.Where("hash in ?", srcHashes).Delete(&models.WrapperTransaction{}).Error; err != nil {
+			return err
+		}
+		if err := tx.Where("hash in ?", polyHashes).Delete(&models.PolyTransaction{}).Error; err != nil {
+			return err
+		}
+		if err := tx.Where("tx_hash in ?", dstHashes).Delete(&models.DstTransfer{}).Error; err != nil {
+			return err
+		}
+		if err := tx.Where("hash in ?", dstHashes).Delete(&models.DstTransaction{}).Error; err != nil {
+			return err
+		}
+		if err := tx.Where("src_hash in ?", srcHashes).Delete(&models.CrossChainTx{}).Error; err != nil {
+			return err
+		}
+		err := tx.Model(&models.CrossChainTx{}).Where("poly_hash in ?", polyHashes).
+			Updates(map[string]interface{}{"poly_hash": "", "dst_sequence": 0, "dst_hash": "", "dst_height": 0}).Error
+		if err != nil {
+			return err
+		}
+		return tx.Model(&models.CrossChainTx{}).Where("dst_hash in ?", dstHashes).
+			Updates(map[string]interface{}{"dst_hash": "", "dst_height": 0}).Error
+	})
+}
+
+// RemoveEventsAbove removes the events the listener of the chain saved above the height, the orphaned blocks of a reorg
+func (dao *BridgeDao) RemoveEventsAbove(chainId uint64, above uint64) error {
+	var srcHashes, wrapperHashes, polyHashes, dstHashes []string
+	if err := dao.db.Model(&models.SrcTransaction{}).Where("chain_id = ? and height > ?", chainId, above).Pluck("hash", &srcHashes).Error; err != nil {
+		return err
+	}
+	if err := dao.db.Model(&models.WrapperTransaction{}).Where("src_chain_id = ? and block_height > ?", chainId, above).Pluck("hash", &wrapperHashes).Error; err != nil {
+		return err
+	}
+	if err := dao.db.Model(&models.PolyTransaction{}).Where("chain_id = ? and height > ?", chainId, above).Pluck("hash", &polyHashes).Error; err != nil {
+		return err
+	}
+	if err := dao.db.Model(&models.DstTransaction{}).Where("chain_id = ? and height > ?", chainId, above).Pluck("hash", &dstHashes).Error; err != nil {
+		return err
+	}
+	return dao.RemoveEvents(append(srcHashes, wrapperHashes...), polyHashes, dstHashes)
+}
 
-	dao.db.Where("`hash` in ?", polyHashes).Delete(&models.PolyTransaction{})
+func (dao *BridgeDao) SaveBlockHashes(chainId uint64, hashes []*models.BlockHash, pruneBelow uint64) error {
+	return dao.db.Transaction(func(tx *gorm.DB) error {
+		if len(hashes) > 0 {
+			err := tx.Clauses(clause.OnConflict{
+				Columns:   []clause.Column{{Name: "chain_id"}, {Name: "height"}},
+				DoUpdates: clause.AssignmentColumns([]string{"hash", "parent_hash"}),
+			}).Create(hashes).Error
+			if err != nil {
+				return err
+			}
+		}
+		return tx.Where("chain_id = ? and height < ?", chainId, pruneBelow).Delete(&models.BlockHash{}).Error
+	})
+}
 
-	dao.db.Where("`tx_hash` in ?", dstHashes).Delete(&models.DstTransfer{})
-	dao.db.Where("`hash` in ?", dstHashes).Delete(&models.DstTransaction{})
-	return nil
+func (dao *BridgeDao) GetBlockHashes(chainId uint64, start uint64, end uint64) ([]*models.BlockHash, error) {
+	hashes := make([]*models.BlockHash, 0)
+	res := dao.db.Where("chain_id = ? and height >= ? and height <= ?", chainId, start, end).Order("height desc").Find(&hashes)
+	return hashes, res.Error
+}
+
+func (dao *BridgeDao) RemoveBlockHashes(chainId uint64, above uint64) error {
+	return dao.db.Where("chain_id = ? and height > ?", chainId, above).Delete(&models.BlockHash{}).Error
 }
 
 func (dao *BridgeDao) GetChain(chainId uint64) (*models.Chain, error) {
@@ -354,7 +434,7 @@ func (dao *BridgeDao) AggregateTokenBasicSrcTransfers(assetHashes [][]interface{
 		Sum   string
 		Count uint64
 	}
-	res := dao.db.Model(&models.SrcTransfer{}).Select("SUM(amount) as sum, COUNT(*) as count").Where("(chain_id, asset) in ? AND id > ? AND id <= ?", assetHashes, min, max).First(&v)
+	res := dao.db.Model(&models.SrcTransfer{}).Select(database.SumAmount(dao.db, "amount")+" as sum, COUNT(*) as count").Where("(chain_id, asset) in ? AND id > ? AND id <= ?", assetHashes, min, max).First(&v)
 	err = res.Error
 	if res.Error == nil {
 		sum := new(big.Float)
@@ -392,7 +472,7 @@ func (dao *BridgeDao) UpdateTokenAvailableAmount(hash string, chainId uint64, am
 
 func (dao *BridgeDao) CalculateInTokenStatistics(chainId uint64, hash string, lastId, nowId int64) (*models.TokenStatistic, error) {
 	tokenStatistic := new(models.TokenStatistic)
-	res := dao.db.Raw("select count(*) in_counter,  CONVERT(sum(amount), DECIMAL(37, 0)) as in_amount, chain_id as chain_id, asset as hash  from dst_transfers where  chain_id = ? and asset = ? and id > ? and id <= ? group by chain_id,hash", chainId, hash, lastId, nowId).
+	res := dao.db.Raw("select count(*) in_counter, "+database.SumAmount(dao.db, "amount")+" as in_amount, chain_id as chain_id, asset as hash from dst_transfers where chain_id = ? and asset = ? and id > ? and id <= ? group by chain_id, asset", chainId, hash, lastId, nowId).
 		First(tokenStatistic)
 	if errors.Is(res.Error, gorm.ErrRecordNotFound) {
 		return nil, nil
@@ -402,7 +482,7 @@ func (dao *BridgeDao) CalculateInTokenStatistics(chainId uint64, hash string, la
 
 func (dao *BridgeDao) CalculateOutTokenStatistics(chainId uint64, hash string, lastId, nowId int64) (*models.TokenStatistic, error) {
 	tokenStatistic := new(models.TokenStatistic)
-	res := dao.db.Raw("select count(*) out_counter,  CONVERT(sum(amount), DECIMAL(37, 0)) as out_amount, chain_id as chain_id, asset as hash from src_transfers where chain_id = ? and asset = ? and id > ? and id<= ? group by chain_id,hash", chainId, hash, lastId, nowId).
+	res := dao.db.Raw("select count(*) out_counter, "+database.SumAmount(dao.db, "amount")+" as out_amount, chain_id as chain_id, asset as hash from src_transfers where chain_id = ? and asset = ? and id > ? and id <= ? group by chain_id, asset", chainId, hash, lastId, nowId).
 		First(tokenStatistic)
 	if errors.Is(res.Error, gorm.ErrRecordNotFound) {
 		return nil, nil
@@ -426,32 +506,18 @@ func (dao *BridgeDao) GetNewDstTransfer() (*models.DstTransfer, error) {
 	res := dao.db.Last(transfer)
 	return transfer, res.Error
 }
-func (dao *BridgeDao) GetNewDstTransaction() (*models.DstTransaction, error) {
-	logs.Info("qChainStatistic,sqlGetNewDstTransaction-----")
-	transaction := &models.DstTransaction{}
-	res := dao.db.Last(transaction)
-	logs.Info("qChainStatistic,sqlGetNewDstTransaction,res.Error-----", res.Error)
-	return transaction, res.Error
-}
 func (dao *BridgeDao) GetNewSrcTransfer() (*models.SrcTransfer, error) {
 	srcTransfer := &models.SrcTransfer{}
 	res := dao.db.Debug().Last(srcTransfer)
 	fmt.Println("GetNewSrcTransfer:", *srcTransfer)
 	return srcTransfer, res.Error
 }
-func (dao *BridgeDao) GetNewSrcTransaction() (*models.SrcTransaction, error) {
-	transaction := &models.SrcTransaction{}
-	res := dao.db.Debug().Last(transaction)
-	fmt.Println("GetNewSrcTransaction:", *transaction)
-	return transaction, res.Error
-}
-func (dao *BridgeDao) GetNewChainSta() (*models.ChainStatistic, error) {
-	chainStatistic := &models.ChainStatistic{}
-	res := dao.db.Debug().Last(chainStatistic)
-	return chainStatistic, res.Error
-}
+
+// CalculateChainStatisticAssets counts the addresses of the whole history, the archived transfers included
 func (dao *BridgeDao) CalculateChainStatisticAssets(chainStatistics interface{}) error {
-	res := dao.db.Raw("select count(distinct addresses) as addresses, chain_id from (select  `from` as addresses, chain_id from src_transfers union select `to` as addresses, chain_id from dst_transfers) u group by chain_id").
+	from, to := clause.Column{Name: "from"}, clause.Column{Name: "to"}
+	res := dao.db.Raw("select count(distinct addresses) as addresses, chain_id from (select  ? as addresses, chain_id from src_transfers union select ? as addresses, chain_id from ? union select ? as addresses, chain_id from dst_transfers union select ? as addresses, chain_id from ?) u group by chain_id",
+		from, from, clause.Table{Name: database.ArchivePrefix + "src_transfers"}, to, to, clause.Table{Name: database.ArchivePrefix + "dst_transfers"}).
 		Scan(chainStatistics)
 	return res.Error
 }
@@ -465,19 +531,20 @@ func (dao *BridgeDao) GetChains() ([]*models.Chain, error) {
 	return chains, res.Error
 }
 
-func (dao *BridgeDao) GetPolyTransaction() (*models.PolyTransaction, error) {
-	polyTransaction := new(models.PolyTransaction)
-	res := dao.db.Last(&polyTransaction)
-	return polyTransaction, res.Error
+// GetLastId is the largest id of the rows of model, 0 for an empty table
+func (dao *BridgeDao) GetLastId(model interface{}) (int64, error) {
+	var id int64
+	res := dao.db.Model(model).Select("coalesce(max(id), 0)").Scan(&id)
+	return id, res.Error
 }
 
 func (dao *BridgeDao) CalculateInChainStatistics(lastId, nowId int64, chainStatistics interface{}) error {
-	res := dao.db.Raw("select count(*) as `in`, chain_id from dst_transactions where id > ? and id <= ? group by chain_id", lastId, nowId).
+	res := dao.db.Raw("select count(*) as ?, chain_id from dst_transactions where id > ? and id <= ? group by chain_id", clause.Column{Name: "in"}, lastId, nowId).
 		Scan(chainStatistics)
 	return res.Error
 }
 func (dao *BridgeDao) CalculateOutChainStatistics(lastId, nowId int64, chainStatistics interface{}) error {
-	res := dao.db.Raw("select count(*) as `out`, chain_id from src_transactions where id > ? and id <= ? group by chain_id", lastId, nowId).
+	res := dao.db.Raw("select count(*) as ?, chain_id from src_transactions where id > ? and id <= ? group by chain_id", clause.Column{Name: "out"}, lastId, nowId).
 		Scan(chainStatistics)
 	return res.Error
 }
@@ -488,9 +555,11 @@ func (dao *BridgeDao) CalculatePolyChainStatistic(lastId, nowId int64) (int64, e
 	return counter, res.Error
 }
 
+// SaveChainStatistics saves the counters with their id checkpoints in one transaction
 func (dao *BridgeDao) SaveChainStatistics(chainStatistics []*models.ChainStatistic) error {
-	res := dao.db.Debug().Save(chainStatistics)
-	return res.Error
+	return dao.db.Transaction(func(tx *gorm.DB) error {
+		return tx.Debug().Save(chainStatistics).Error
+	})
 }
 func (dao *BridgeDao) GetNewAssetSta() (*models.AssetStatistic, error) {
 	assetStatistic := new(models.AssetStatistic)
@@ -500,7 +569,7 @@ func (dao *BridgeDao) GetNewAssetSta() (*models.AssetStatistic, error) {
 }
 func (dao *BridgeDao) CalculateAssets(tokenBasicName string, lastId, nowId int64) ([]*models.AssetInfo, error) {
 	assetInfos := make([]*models.AssetInfo, 0)
-	err := dao.db.Debug().Raw("select CONVERT(sum(amount), DECIMAL(37, 0)) as amount, count(*) as txnum, b.token_basic_name, b.precision, c.price  from src_transfers a inner join tokens b on a.chain_id = b.chain_id and a.asset = b.hash left join token_basics c on c.name = b.token_basic_name where b.token_basic_name = ? and a.id > ? and a.id <= ? group by b.chain_id,b.`hash`", tokenBasicName, lastId, nowId).
+	err := dao.db.Debug().Raw("select "+database.SumAmount(dao.db, "a.amount")+" as amount, count(*) as txnum, b.token_basic_name, b.precision, c.price  from src_transfers a inner join tokens b on a.chain_id = b.chain_id and a.asset = b.hash left join token_basics c on c.name = b.token_basic_name where b.token_basic_name = ? and a.id > ? and a.id <= ? group by b.chain_id, b.hash, b.token_basic_name, b.precision, c.price", tokenBasicName, lastId, nowId).
 		Find(&assetInfos).Error
 	return assetInfos, err
 }
@@ -514,9 +583,13 @@ func (dao *BridgeDao) SaveAssetStatistic(assetStatistic *models.AssetStatistic)
 	err = dao.db.Save(assetStatistic).Error
 	return err
 }
+
+// CalculateAssetAdress counts the addresses of the whole history, the archived transfers included
 func (dao *BridgeDao) CalculateAssetAdress() ([]*models.AssetStatistic, error) {
 	assetStatistics := make([]*models.AssetStatistic, 0)
-	err := dao.db.Raw("select count(distinct `from`) as addressnum,b.token_basic_name from src_transfers a inner join tokens b on a.chain_id = b.chain_id and a.asset = b.hash  group by token_basic_name").
+	from := clause.Column{Name: "from"}
+	err := dao.db.Raw("select count(distinct ?) as addressnum,b.token_basic_name from (select chain_id, asset, ? from src_transfers union all select chain_id, asset, ? from ?) a inner join tokens b on a.chain_id = b.chain_id and a.asset = b.hash  group by token_basic_name",
+		clause.Column{Table: "a", Name: "from"}, from, from, clause.Table{Name: database.ArchivePrefix + "src_transfers"}).
 		Find(&assetStatistics).Error
 	return assetStatistics, err
 }
@@ -585,7 +658,7 @@ type TokenStatisticWithName struct {
 
 func (dao *BridgeDao) GetSourceTokenStatistics() ([]*TokenStatisticWithName, error) {
 	sourceTokenStatistics := make([]*TokenStatisticWithName, 0)
-	err := dao.db.Raw("SELECT b.token_basic_name,a.chain_id,a.in_amount,a.in_amount_usd from token_statistics a left join tokens b on a.chain_id=b.chain_id and a.`hash`=b.`hash` left join token_basics c on b.token_basic_name=c.`name` where c.chain_id=a.chain_id").
+	err := dao.db.Raw("SELECT b.token_basic_name,a.chain_id,a.in_amount,a.in_amount_usd from token_statistics a left join tokens b on a.chain_id=b.chain_id and a.hash=b.hash left join token_basics c on b.token_basic_name=c.name where c.chain_id=a.chain_id").
 		Find(&sourceTokenStatistics).Error
 	return sourceTokenStatistics, err
 }
@@ -639,7 +712,6 @@ func (dao *BridgeDao) WrapperTransactionCheckFee(wrapperTransactions []*models.W
 		chain2Fees[chainFee.ChainId] = chainFee
 	}
 	var curSrcTransaction *models.SrcTransaction
-	var feePayFloat64, feeMinFloat64, PaidGasFloat64 float64
 	for _, v := range wrapperTransactions {
 		wrapperTx := new(models.WrapperTransaction)
 		res := dao.db.Where("hash = ?", v.Hash).First(wrapperTx)
@@ -664,59 +736,7 @@ func (dao *BridgeDao) WrapperTransactionCheckFee(wrapperTransactions []*models.W
 			continue
 		}
 
-		chainFee, ok := chain2Fees[v.DstChainId]
-		if !ok {
-			v.IsPaid = false
-			logs.Info("check fee Wrapper_hash %s NOT_PAID,chainFee hasn't DstChainId's fee", v.Hash)
-			continue
-		}
-		//money paid in wrapper
-		feePay, feeMin, gasPay := fee.CheckFeeCal(chainFee, token, v.FeeAmount)
-		// get optimistic L1 fee on ethereum
-		if chainFee.ChainId == basedef.OPTIMISTIC_CROSSCHAIN_ID {
-			ethChainFee, ok := chain2Fees[basedef.ETHEREUM_CROSSCHAIN_ID]
-			if !ok {
-				v.IsPaid = false
-				logs.Info("check fee wrapper_hash %s NOT_PAID,chainFee hasn't ethereum fee", v.Hash)
-				continue
-			}
-
-			L1MinFee, _, _, err := fee.GetL1Fee(ethChainFee, chainFee.ChainId)
-			if err != nil {
-				v.IsPaid = false
-				logs.Info("check fee wrapper_hash %s NOT_PAID, get L1 fee failed. err=%v", v.Hash, err)
-				continue
-			}
-			feeMin = new(big.Float).Add(feeMin, L1MinFee)
-		}
-
-		if _, in := conf.EstimateProxy[strings.ToUpper(curSrcTransaction.Contract)]; in {
-			//is estimateGas proxy
-			if gasPay.Cmp(new(big.Float).SetInt64(0)) <= 0 {
-				v.IsPaid = false
-				continue
-			}
-			if minFee, in := conf.EstimateFeeMin[v.DstChainId]; in {
-				if minFee > 0 && minFee < 100 {
-					gasPay = new(big.Float).Mul(gasPay, new(big.Float).SetInt64(100))
-					gasPay = new(big.Float).Quo(gasPay, new(big.Float).SetInt64(minFee))
-				}
-			}
-			PaidGasFloat64, _ = gasPay.Float64()
-			PaidGas := decimal.NewFromFloat(PaidGasFloat64).Mul(decimal.NewFromInt(100))
-			v.PaidGas = models.NewBigInt(PaidGas.BigInt())
-			logs.Info("check fee wrapper_hash %s is EstimateProxy,PaidGas %v", v.Hash, v.PaidGas)
-			continue
-		}
-		feeMinFloat64, _ = feeMin.Float64()
-		feePayFloat64, _ = feePay.Float64()
-		if feePay.Cmp(feeMin) >= 0 {
-			v.IsPaid = true
-			logs.Info("check fee wrapper_hash %s PAID,feePay %v >= feeMin %v", v.Hash, feePayFloat64, feeMinFloat64)
-		} else {
-			v.IsPaid = false
-			logs.Info("check fee wrapper_hash %s NOT_PAID,feePay %v < feeMin %v", v.Hash, feePayFloat64, feeMinFloat64)
-		}
+		fee.CheckWrapperFee(v, curSrcTransaction, token, chain2Fees)
 	}
 	return nil
 }
@@ -733,36 +753,29 @@ func (dao *BridgeDao) GetWrapperTxsWithTime(startTime int64, limit int) ([]*mode
 	return wrapperTxs, err
 }
 
-func (dao *BridgeDao) GetSrcTxsWithTimeAndChains(startTime int64, chains []uint64, limit int) ([]*models.SrcTransaction, error) {
-	srcTxs := make([]*models.SrcTransaction, 0)
-	err := dao.db.Where("time >= ? and chain_id in ?", startTime, chains).
-		Preload("SrcTransfer").
-		Limit(limit).
-		Find(&srcTxs).
-		Error
-	return srcTxs, err
+// NewIterator loads the scan cursor of name on the bridge db
+func (dao *BridgeDao) NewIterator(name string, batch int) (*cursor.Iterator, error) {
+	return cursor.NewIterator(dao.db, name, batch)
 }
 
-func (dao *BridgeDao) GetSrcCountWithIdAndChains(startId int64, endId int64, chains []uint64) (count int64, err error) {
-	err = dao.db.Model(&models.SrcTransaction{}).Where("id  > ? and id <= ? and chain_id in ?", startId, endId, chains).
-		Count(&count).
+func (dao *BridgeDao) GetSrcTxWithId(id int64) (*models.SrcTransaction, error) {
+	srcTx := new(models.SrcTransaction)
+	err := dao.db.Where("id = ?", id).
+		First(srcTx).
 		Error
-	return
+	return srcTx, err
 }
 
-func (dao *BridgeDao) GetSrcTxsWithIdAndChains(startId int64, endId int64, chains []uint64, limit int, offset int) ([]*models.SrcTransaction, error) {
+// GetSrcTxsWithChains finds the next page of the src transactions of chains after the cursor, the ids not after minId are skipped
+func (dao *BridgeDao) GetSrcTxsWithChains(it *cursor.Iterator, minId int64, chains []uint64) ([]*models.SrcTransaction, error) {
 	srcTxs := make([]*models.SrcTransaction, 0)
-	err := dao.db.Where("id  > ? and id <= ? and chain_id in ?", startId, endId, chains).
-		Preload("SrcTransfer").
-		Limit(limit).Offset(offset).
-		Find(&srcTxs).
-		Error
+	_, err := it.Next(dao.db.Where("id > ? and chain_id in ?", minId, chains).Preload("SrcTransfer"), &srcTxs)
 	return srcTxs, err
 }
 
 func (dao *BridgeDao) GetWrapperTxsWithHashes(hashes []string) ([]*models.WrapperTransaction, error) {
 	wrapperTxs := make([]*models.WrapperTransaction, 0)
-	err := dao.db.Where("`hash` in  ?", hashes).
+	err := dao.db.Where("hash in  ?", hashes).
 		Find(&wrapperTxs).
 		Error
 	return wrapperTxs, err
@@ -770,7 +783,7 @@ func (dao *BridgeDao) GetWrapperTxsWithHashes(hashes []string) ([]*models.Wrappe
 
 func (dao *BridgeDao) GetToken(chain uint64, hash string) (*models.Token, error) {
 	token := new(models.Token)
-	err := dao.db.Where("chain_id = ? and `hash` = ?", chain, hash).Preload("TokenBasic").
+	err := dao.db.Where("chain_id = ? and hash = ?", chain, hash).Preload("TokenBasic").
 		First(token).
 		Error
 	return token, err
@@ -784,14 +797,6 @@ func (dao *BridgeDao) GetTokenPriceAvg(tokenBasicName string) (*models.TokenPric
 	return tokenPriceAvg, err
 }
 
-func (dao *BridgeDao) GetLastSrcTx() (*models.SrcTransaction, error) {
-	srcTx := new(models.SrcTransaction)
-	err := dao.db.Preload("SrcTransfer").
-		Last(srcTx).
-		Error
-	return srcTx, err
-}
-
 func (dao *BridgeDao) GetChainFeeTokens(chains []uint64) ([]*models.ChainFee, error) {
 	chainFees := make([]*models.ChainFee, 0)
 	err := dao.db.Model(&models.ChainFee{}).Where("chain_id in ?", chains).Preload("TokenBasic").
@@ -926,3 +931,104 @@ func (dao *BridgeDao) fillTxRipple(dstTransactions []*models.DstTransaction, rip
 	}
 	return
 }
+
+func (dao *BridgeDao) AddBackfillJob(job *models.BackfillJob) error {
+	if job == nil {
+		return fmt.Errorf("no value!")
+	}
+	return dao.db.Create(job).Error
+}
+
+func (dao *BridgeDao) GetBackfillJob(id int64) (*models.BackfillJob, error) {
+	job := new(models.BackfillJob)
+	res := dao.db.Where("id = ?", id).First(job)
+	if res.Error != nil {
+		return nil, res.Error
+	}
+	return job, nil
+}
+
+// GetBackfillJobs returns the jobs with the status, or all the jobs if status is empty
+func (dao *BridgeDao) GetBackfillJobs(status string) ([]*models.BackfillJob, error) {
+	jobs := make([]*models.BackfillJob, 0)
+	query := dao.db.Model(&models.BackfillJob{})
+	if status != "" {
+		query = query.Where("status = ?", status)
+	}
+	err := query.Order("id desc").Find(&jobs).Error
+	return jobs, err
+}
+
+// UpdateBackfillJobHeight saves the progress of a job, the status is left untouched so a pause is not overwritten
+func (dao *BridgeDao) UpdateBackfillJobHeight(id int64, height uint64, errMsg string) error {
+	res := dao.db.Model(&models.BackfillJob{}).Where("id = ?", id).
+		Updates(map[string]interface{}{"height": height, "error": errMsg, "update_time": time.Now().Unix()})
+	if res.Error != nil {
+		return res.Error
+	}
+	if res.RowsAffected == 0 {
+		return fmt.Errorf("no update!")
+	}
+	return nil
+}
+
+func (dao *BridgeDao) UpdateBackfillJobStatus(id int64, status string, errMsg string) error {
+	res := dao.db.Model(&models.BackfillJob{}).Where("id = ?", id).
+		Updates(map[string]interface{}{"status": status, "error": errMsg, "update_time": time.Now().Unix()})
+	if res.Error != nil {
+		return res.Error
+	}
+	if res.RowsAffected == 0 {
+		return fmt.Errorf("no update!")
+	}
+	return nil
+}
+
+// EnableEventOutbox makes UpdateEvents queue the normalized events in the outbox for the event sinks
+func (dao *BridgeDao) EnableEventOutbox() {
+	dao.outbox = true
+}
+
+// addEventOutbox queues the events in the transaction that saves them, so a saved event is never missing from the outbox
+func addEventOutbox(db *gorm.DB, wrapperTransactions []*models.WrapperTransaction, srcTransactions []*models.SrcTransaction, polyTransactions []*models.PolyTransaction, dstTransactions []*models.DstTransaction) error {
+	events := models.MakeCrossChainEvents(wrapperTransactions, srcTransactions, polyTransactions, dstTransactions)
+	if len(events) == 0 {
+		return nil
+	}
+	now := time.Now().Unix()
+	outbox := make([]*models.EventOutbox, 0, len(events))
+	for _, event := range events {
+		payload, err := json.Marshal(event)
+		if err != nil {
+			return err
+		}
+		outbox = append(outbox, &models.EventOutbox{
+			Type:       event.Type,
+			Hash:       event.Hash,
+			Payload:    string(payload),
+			Status:     basedef.OUTBOX_PENDING,
+			NextTime:   now,
+			CreateTime: now,
+		})
+	}
+	return db.Create(outbox).Error
+}
+
+func (dao *BridgeDao) GetEventOutbox(limit int) ([]*models.EventOutbox, error) {
+	outbox := make([]*models.EventOutbox, 0)
+	err := dao.db.Where("status = ? and next_time <= ?", basedef.OUTBOX_PENDING, time.Now().Unix()).
+		Order("id asc").Limit(limit).Find(&outbox).Error
+	return outbox, err
+}
+
+func (dao *BridgeDao) RemoveEventOutbox(ids []int64) error {
+	if len(ids) == 0 {
+		return nil
+	}
+	return dao.db.Where("id in ?", ids).Delete(&models.EventOutbox{}).Error
+}
+
+func (dao *BridgeDao) UpdateEventOutbox(outbox *models.EventOutbox) error {
+	return dao.db.Model(&models.EventOutbox{}).Where("id = ?", outbox.Id).
+		Updates(map[string]interface{}{"status": outbox.Status, "attempts": outbox.Attempts, "error": outbox.Error, "next_time": outbox.NextTime}).Error
+}
@@ -0,0 +1,235 @@
+/*
+ * Copyright (C) 2020 The poly network Authors
+ * This file is part of The poly network library.
+ *
+ * The  poly network  is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU Lesser General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * The  poly network  is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU Lesser General Public License for more details.
+ * You should have received a copy of the GNU Lesser General Public License
+ * along with The poly network .  If not, see <http://www.gnu.org/licenses/>.
+ */
+
+package bridgedao
+
+import (
+	"poly-bridge/basedef"
+	"poly-bridge/models"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// MatchRippleDstTransactions sets the poly hash of the ripple dst transactions by the sequence,
+// they are saved before the poly transaction is known if the poly hash is not found
+func MatchRippleDstTransactions(db *gorm.DB, dstTransactions []*models.DstTransaction) error {
+	for _, dstTransaction := range dstTransactions {
+		if dstTransaction.ChainId != basedef.RIPPLE_CROSSCHAIN_ID || dstTransaction.PolyHash != "" || dstTransaction.Sequence == 0 {
+			continue
+		}
+		polyTransactions := make([]*models.PolyTransaction, 0)
+		err := db.Where("dst_sequence = ? and dst_chain_id = ?", dstTransaction.Sequence, dstTransaction.ChainId).
+			Limit(1).Find(&polyTransactions).Error
+		if err != nil {
+			return err
+		}
+		if len(polyTransactions) > 0 {
+			dstTransaction.PolyHash = polyTransactions[0].Hash
+		}
+	}
+	return nil
+}
+
+// UpdateCrossChainTxs applies the saved events to the cross_chain_txs table, the legs may arrive in any order
+func UpdateCrossChainTxs(db *gorm.DB, wrapperTransactions []*models.WrapperTransaction, srcTransactions []*models.SrcTransaction, polyTransactions []*models.PolyTransaction, dstTransactions []*models.DstTransaction) error {
+	if len(wrapperTransactions) > 0 {
+		crossChainTxs := make([]*models.CrossChainTx, 0, len(wrapperTransactions))
+		for _, wrapperTransaction := range wrapperTransactions {
+			crossChainTxs = append(crossChainTxs, &models.CrossChainTx{
+				SrcHash:      wrapperTransaction.Hash,
+				SrcChainId:   wrapperTransaction.SrcChainId,
+				SrcHeight:    wrapperTransaction.BlockHeight,
+				DstChainId:   wrapperTransaction.DstChainId,
+				Standard:     wrapperTransaction.Standard,
+				WrapperId:    wrapperTransaction.Id,
+				FeeTokenHash: wrapperTransaction.FeeTokenHash,
+				Status:       wrapperTransaction.Status,
+				Time:         wrapperTransaction.Time,
+			})
+		}
+		err := db.Clauses(clause.OnConflict{
+			Columns:   []clause.Column{{Name: "src_hash"}},
+			DoUpdates: clause.AssignmentColumns([]string{"wrapper_id", "fee_token_hash", "status"}),
+		}).Create(&crossChainTxs).Error
+		if err != nil {
+			return err
+		}
+	}
+	if len(srcTransactions) > 0 {
+		crossChainTxs := make([]*models.CrossChainTx, 0, len(srcTransactions))
+		srcHashes := make([]string, 0, len(srcTransactions))
+		for _, srcTransaction := range srcTransactions {
+			crossChainTx := &models.CrossChainTx{
+				SrcHash:    srcTransaction.Hash,
+				SrcKey:     srcTransaction.Key,
+				SrcChainId: srcTransaction.ChainId,
+				SrcHeight:  srcTransaction.Height,
+				DstChainId: srcTransaction.DstChainId,
+				Standard:   srcTransaction.Standard,
+				Contract:   srcTransaction.Contract,
+				Time:       srcTransaction.Time,
+			}
+			if srcTransaction.SrcTransfer != nil {
+				crossChainTx.TokenHash = srcTransaction.SrcTransfer.Asset
+			}
+			crossChainTxs = append(crossChainTxs, crossChainTx)
+			srcHashes = append(srcHashes, srcTransaction.Hash)
+			if srcTransaction.Key != "" {
+				srcHashes = append(srcHashes, srcTransaction.Key)
+			}
+		}
+		err := db.Clauses(clause.OnConflict{
+			Columns:   []clause.Column{{Name: "src_hash"}},
+			DoUpdates: clause.AssignmentColumns([]string{"src_key", "src_chain_id", "src_height", "dst_chain_id", "standard", "contract", "token_hash", "time"}),
+		}).Create(&crossChainTxs).Error
+		if err != nil {
+			return err
+		}
+		// the poly transactions saved before the src ones
+		savedPolyTransactions := make([]*models.PolyTransaction, 0)
+		err = db.Where("src_hash in ?", srcHashes).Find(&savedPolyTransactions).Error
+		if err != nil {
+			return err
+		}
+		polyTransactions = append(savedPolyTransactions, polyTransactions...)
+	}
+	if err := updateCrossChainTxPolys(db, polyTransactions); err != nil {
+		return err
+	}
+	for _, dstTransaction := range dstTransactions {
+		if dstTransaction.PolyHash == "" {
+			continue
+		}
+		err := db.Model(&models.CrossChainTx{}).Where("poly_hash = ?", dstTransaction.PolyHash).
+			Updates(map[string]interface{}{"dst_hash": dstTransaction.Hash, "dst_height": dstTransaction.Height}).Error
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// updateCrossChainTxPolys sets the poly and dst legs of the cross chain txs of the poly transactions with a fixed
+// number of queries, the rows are written back in one upsert
+func updateCrossChainTxPolys(db *gorm.DB, polyTransactions []*models.PolyTransaction) error {
+	if len(polyTransactions) == 0 {
+		return nil
+	}
+	// the src hash of the poly transaction is the key of the src transaction before it is updated by the effect
+	srcHashes := make([]string, 0, len(polyTransactions))
+	polyHashes := make([]string, 0, len(polyTransactions))
+	for _, polyTransaction := range polyTransactions {
+		srcHashes = append(srcHashes, polyTransaction.SrcHash)
+		polyHashes = append(polyHashes, polyTransaction.Hash)
+	}
+	crossChainTxs := make([]*models.CrossChainTx, 0)
+	if err := db.Where("src_hash in ?", srcHashes).Find(&crossChainTxs).Error; err != nil {
+		return err
+	}
+	hash2CrossChainTxs := make(map[string]*models.CrossChainTx, len(crossChainTxs))
+	for _, crossChainTx := range crossChainTxs {
+		hash2CrossChainTxs[crossChainTx.SrcHash] = crossChainTx
+	}
+	keys := make([]string, 0)
+	for _, polyTransaction := range polyTransactions {
+		if _, ok := hash2CrossChainTxs[polyTransaction.SrcHash]; !ok {
+			keys = append(keys, polyTransaction.SrcHash)
+		}
+	}
+	type keyOfChain struct {
+		key     string
+		chainId uint64
+	}
+	key2CrossChainTxs := make(map[keyOfChain]*models.CrossChainTx)
+	if len(keys) > 0 {
+		keyCrossChainTxs := make([]*models.CrossChainTx, 0)
+		if err := db.Where("src_key in ?", keys).Find(&keyCrossChainTxs).Error; err != nil {
+			return err
+		}
+		for _, crossChainTx := range keyCrossChainTxs {
+			key2CrossChainTxs[keyOfChain{crossChainTx.SrcKey, crossChainTx.SrcChainId}] = crossChainTx
+		}
+	}
+
+	// the dst transactions saved before the poly ones
+	dstTransactions := make([]*models.DstTransaction, 0)
+	if err := db.Where("poly_hash in ?", polyHashes).Find(&dstTransactions).Error; err != nil {
+		return err
+	}
+	poly2DstTransactions := make(map[string]*models.DstTransaction, len(dstTransactions))
+	for _, dstTransaction := range dstTransactions {
+		poly2DstTransactions[dstTransaction.PolyHash] = dstTransaction
+	}
+	sequences := make([]uint64, 0)
+	for _, polyTransaction := range polyTransactions {
+		if _, ok := poly2DstTransactions[polyTransaction.Hash]; !ok && polyTransaction.DstChainId == basedef.RIPPLE_CROSSCHAIN_ID && polyTransaction.DstSequence > 0 {
+			sequences = append(sequences, polyTransaction.DstSequence)
+		}
+	}
+	sequence2DstTransactions := make(map[uint64]*models.DstTransaction)
+	if len(sequences) > 0 {
+		rippleTransactions := make([]*models.DstTransaction, 0)
+		err := db.Where("poly_hash = '' and chain_id = ? and sequence in ?", basedef.RIPPLE_CROSSCHAIN_ID, sequences).Find(&rippleTransactions).Error
+		if err != nil {
+			return err
+		}
+		for _, dstTransaction := range rippleTransactions {
+			sequence2DstTransactions[dstTransaction.Sequence] = dstTransaction
+		}
+	}
+
+	updated := make(map[string]*models.CrossChainTx)
+	for _, polyTransaction := range polyTransactions {
+		crossChainTx, ok := hash2CrossChainTxs[polyTransaction.SrcHash]
+		if !ok {
+			crossChainTx, ok = key2CrossChainTxs[keyOfChain{polyTransaction.SrcHash, polyTransaction.SrcChainId}]
+		}
+		if !ok {
+			continue
+		}
+		crossChainTx.PolyHash = polyTransaction.Hash
+		crossChainTx.DstSequence = polyTransaction.DstSequence
+		dstTransaction, ok := poly2DstTransactions[polyTransaction.Hash]
+		if !ok && polyTransaction.DstChainId == basedef.RIPPLE_CROSSCHAIN_ID && polyTransaction.DstSequence > 0 {
+			dstTransaction, ok = sequence2DstTransactions[polyTransaction.DstSequence]
+			if ok {
+				err := db.Model(&models.DstTransaction{}).Where("id = ?", dstTransaction.Id).Update("poly_hash", polyTransaction.Hash).Error
+				if err != nil {
+					return err
+				}
+				delete(sequence2DstTransactions, polyTransaction.DstSequence)
+			}
+		}
+		if ok {
+			crossChainTx.DstHash = dstTransaction.Hash
+			crossChainTx.DstHeight = dstTransaction.Height
+		}
+		updated[crossChainTx.SrcHash] = crossChainTx
+	}
+	if len(updated) == 0 {
+		return nil
+	}
+	crossChainTxs = make([]*models.CrossChainTx, 0, len(updated))
+	for _, crossChainTx := range updated {
+		crossChainTxs = append(crossChainTxs, crossChainTx)
+	}
+	return db.Clauses(clause.OnConflict{
+		Columns:   []clause.Column{{Name: "src_hash"}},
+		DoUpdates: clause.AssignmentColumns([]string{"poly_hash", "dst_sequence", "dst_hash", "dst_height"}),
+	}).Create(&crossChainTxs).Error
+}
@@ -0,0 +1,293 @@
+package bridgedao
+
+import (
+	"context"
+	"fmt"
+	"poly-bridge/basedef"
+	"poly-bridge/models"
+	"poly-bridge/utils/dbtest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+)
+
+func newTestBridgeDao(t *testing.T) *BridgeDao {
+	dao := NewBridgeDao(dbtest.Config(t, "polyswap.db"), false)
+	err := dao.db.AutoMigrate(
+		&models.WrapperTransaction{},
+		&models.SrcTransaction{},
+		&models.SrcTransfer{},
+		&models.PolyTransaction{},
+		&models.DstTransaction{},
+		&models.DstTransfer{},
+		&models.WrapperDetail{},
+		&models.PolyDetail{},
+		&models.CrossChainTx{},
+		&models.BlockHash{},
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return dao
+}
+
+func testSrcTransaction(hash, key string, chainId uint64) *models.SrcTransaction {
+	return &models.SrcTransaction{
+		Hash:       hash,
+		ChainId:    chainId,
+		Height:     100,
+		Time:       1650000000,
+		Fee:        models.NewBigIntFromInt(0),
+		DstChainId: basedef.BSC_CROSSCHAIN_ID,
+		Key:        key,
+		SrcTransfer: &models.SrcTransfer{
+			TxHash:     hash,
+			ChainId:    chainId,
+			Asset:      "0000000000000000000000000000000000000000",
+			Amount:     models.NewBigIntFromInt(100),
+			DstChainId: basedef.BSC_CROSSCHAIN_ID,
+		},
+	}
+}
+
+func testPolyTransaction(hash, srcHash string, srcChainId, dstChainId, dstSequence uint64) *models.PolyTransaction {
+	return &models.PolyTransaction{
+		Hash:        hash,
+		ChainId:     basedef.POLY_CROSSCHAIN_ID,
+		Fee:         models.NewBigIntFromInt(0),
+		SrcChainId:  srcChainId,
+		SrcHash:     srcHash,
+		DstChainId:  dstChainId,
+		DstSequence: dstSequence,
+	}
+}
+
+func testDstTransaction(hash, polyHash string, chainId, sequence uint64) *models.DstTransaction {
+	return &models.DstTransaction{
+		Hash:     hash,
+		ChainId:  chainId,
+		Height:   200,
+		Fee:      models.NewBigIntFromInt(0),
+		PolyHash: polyHash,
+		Sequence: sequence,
+	}
+}
+
+func getCrossChainTx(t *testing.T, dao *BridgeDao, srcHash string) *models.CrossChainTx {
+	crossChainTx := new(models.CrossChainTx)
+	assert.NoError(t, dao.db.Where("src_hash = ?", srcHash).First(crossChainTx).Error)
+	return crossChainTx
+}
+
+func TestUpdateCrossChainTxs(t *testing.T) {
+	dao := newTestBridgeDao(t)
+
+	wrapperTransaction := &models.WrapperTransaction{
+		Hash:         "01",
+		SrcChainId:   basedef.ETHEREUM_CROSSCHAIN_ID,
+		DstChainId:   basedef.BSC_CROSSCHAIN_ID,
+		FeeTokenHash: "0000000000000000000000000000000000000000",
+		FeeAmount:    models.NewBigIntFromInt(1),
+		PaidGas:      models.NewBigIntFromInt(0),
+		Status:       basedef.STATE_SOURCE_DONE,
+	}
+	err := dao.UpdateEvents([]*models.WrapperTransaction{wrapperTransaction}, []*models.SrcTransaction{testSrcTransaction("01", "", basedef.ETHEREUM_CROSSCHAIN_ID)}, nil, nil, nil, nil)
+	assert.NoError(t, err)
+	crossChainTx := getCrossChainTx(t, dao, "01")
+	assert.Equal(t, wrapperTransaction.Id, crossChainTx.WrapperId)
+	assert.Equal(t, "0000000000000000000000000000000000000000", crossChainTx.TokenHash)
+	assert.Equal(t, uint64(100), crossChainTx.SrcHeight)
+	assert.Equal(t, "", crossChainTx.PolyHash)
+
+	// the dst leg arrives before the poly one
+	err = dao.UpdateEvents(nil, nil, nil, []*models.DstTransaction{testDstTransaction("03", "02", basedef.BSC_CROSSCHAIN_ID, 0)}, nil, nil)
+	assert.NoError(t, err)
+	assert.Equal(t, "", getCrossChainTx(t, dao, "01").DstHash)
+	err = dao.UpdateEvents(nil, nil, []*models.PolyTransaction{testPolyTransaction("02", "01", basedef.ETHEREUM_CROSSCHAIN_ID, basedef.BSC_CROSSCHAIN_ID, 0)}, nil, nil, nil)
+	assert.NoError(t, err)
+	crossChainTx = getCrossChainTx(t, dao, "01")
+	assert.Equal(t, "02", crossChainTx.PolyHash)
+	assert.Equal(t, "03", crossChainTx.DstHash)
+	assert.Equal(t, uint64(200), crossChainTx.DstHeight)
+
+	// the poly leg refers the key of the src transaction, and arrives before it
+	err = dao.UpdateEvents(nil, nil, []*models.PolyTransaction{testPolyTransaction("12", "00000000ab", basedef.O3_CROSSCHAIN_ID, basedef.BSC_CROSSCHAIN_ID, 0)}, nil, nil, nil)
+	assert.NoError(t, err)
+	err = dao.UpdateEvents(nil, []*models.SrcTransaction{testSrcTransaction("11", "00000000ab", basedef.O3_CROSSCHAIN_ID)}, nil, nil, nil, nil)
+	assert.NoError(t, err)
+	assert.Equal(t, "12", getCrossChainTx(t, dao, "11").PolyHash)
+
+	assert.NoError(t, dao.RemoveEvents(nil, []string{"12"}, nil))
+	assert.Equal(t, "", getCrossChainTx(t, dao, "11").PolyHash)
+}
+
+func TestUpdateCrossChainTxsRipple(t *testing.T) {
+	dao := newTestBridgeDao(t)
+
+	err := dao.UpdateEvents(nil, []*models.SrcTransaction{testSrcTransaction("21", "", basedef.ETHEREUM_CROSSCHAIN_ID)}, nil, nil, nil, nil)
+	assert.NoError(t, err)
+	// the ripple dst transaction is matched by the sequence once the poly one is saved
+	err = dao.UpdateEvents(nil, nil, nil, []*models.DstTransaction{testDstTransaction("23", "", basedef.RIPPLE_CROSSCHAIN_ID, 7)}, nil, nil)
+	assert.NoError(t, err)
+	err = dao.UpdateEvents(nil, nil, []*models.PolyTransaction{testPolyTransaction("22", "21", basedef.ETHEREUM_CROSSCHAIN_ID, basedef.RIPPLE_CROSSCHAIN_ID, 7)}, nil, nil, nil)
+	assert.NoError(t, err)
+	crossChainTx := getCrossChainTx(t, dao, "21")
+	assert.Equal(t, "22", crossChainTx.PolyHash)
+	assert.Equal(t, "23", crossChainTx.DstHash)
+	dstTransaction := new(models.DstTransaction)
+	assert.NoError(t, dao.db.Where("hash = ?", "23").First(dstTransaction).Error)
+	assert.Equal(t, "22", dstTransaction.PolyHash)
+
+	// and the poly hash is set when the dst transaction is saved after the poly one
+	err = dao.UpdateEvents(nil, []*models.SrcTransaction{testSrcTransaction("31", "", basedef.ETHEREUM_CROSSCHAIN_ID)}, []*models.PolyTransaction{testPolyTransaction("32", "31", basedef.ETHEREUM_CROSSCHAIN_ID, basedef.RIPPLE_CROSSCHAIN_ID, 8)}, nil, nil, nil)
+	assert.NoError(t, err)
+	err = dao.UpdateEvents(nil, nil, nil, []*models.DstTransaction{testDstTransaction("33", "", basedef.RIPPLE_CROSSCHAIN_ID, 8)}, nil, nil)
+	assert.NoError(t, err)
+	assert.Equal(t, "33", getCrossChainTx(t, dao, "31").DstHash)
+	dstTransaction = new(models.DstTransaction)
+	assert.NoError(t, dao.db.Where("hash = ?", "33").First(dstTransaction).Error)
+	assert.Equal(t, "32", dstTransaction.PolyHash)
+}
+
+func TestRemoveEventsAbove(t *testing.T) {
+	dao := newTestBridgeDao(t)
+
+	wrapperTransaction := &models.WrapperTransaction{
+		Hash:         "41",
+		SrcChainId:   basedef.ETHEREUM_CROSSCHAIN_ID,
+		BlockHeight:  100,
+		DstChainId:   basedef.BSC_CROSSCHAIN_ID,
+		FeeTokenHash: "0000000000000000000000000000000000000000",
+		FeeAmount:    models.NewBigIntFromInt(1),
+		PaidGas:      models.NewBigIntFromInt(0),
+	}
+	err := dao.UpdateEvents([]*models.WrapperTransaction{wrapperTransaction},
+		[]*models.SrcTransaction{testSrcTransaction("41", "", basedef.ETHEREUM_CROSSCHAIN_ID), testSrcTransaction("51", "", basedef.BSC_CROSSCHAIN_ID)},
+		nil,
+		[]*models.DstTransaction{testDstTransaction("43", "", basedef.ETHEREUM_CROSSCHAIN_ID, 0)}, nil, nil)
+	assert.NoError(t, err)
+
+	// src at 100, dst at 200
+	assert.NoError(t, dao.RemoveEventsAbove(basedef.ETHEREUM_CROSSCHAIN_ID, 100))
+	var count int64
+	assert.NoError(t, dao.db.Model(&models.DstTransaction{}).Where("hash = ?", "43").Count(&count).Error)
+	assert.Equal(t, int64(0), count)
+	assert.NoError(t, dao.db.Model(&models.SrcTransaction{}).Count(&count).Error)
+	assert.Equal(t, int64(2), count)
+
+	assert.NoError(t, dao.RemoveEventsAbove(basedef.ETHEREUM_CROSSCHAIN_ID, 99))
+	assert.NoError(t, dao.db.Model(&models.WrapperTransaction{}).Count(&count).Error)
+	assert.Equal(t, int64(0), count)
+	assert.NoError(t, dao.db.Model(&models.SrcTransfer{}).Where("tx_hash = ?", "41").Count(&count).Error)
+	assert.Equal(t, int64(0), count)
+	hashes := make([]string, 0)
+	assert.NoError(t, dao.db.Model(&models.SrcTransaction{}).Pluck("hash", &hashes).Error)
+	assert.Equal(t, []string{"51"}, hashes, "the events of the other chains are kept")
+	assert.NoError(t, dao.db.Model(&models.CrossChainTx{}).Where("src_hash = ?", "41").Count(&count).Error)
+	assert.Equal(t, int64(0), count)
+}
+
+func TestCalculateAssets(t *testing.T) {
+	dao := newTestBridgeDao(t)
+	assert.NoError(t, dao.db.AutoMigrate(&models.Token{}, &models.TokenBasic{}))
+	assert.NoError(t, dao.db.Create(&models.TokenBasic{Name: "USDT", Precision: 6, Price: 100000000}).Error)
+	assert.NoError(t, dao.db.Create([]*models.Token{
+		{Hash: "a1", ChainId: basedef.ETHEREUM_CROSSCHAIN_ID, Name: "USDT", Precision: 6, TokenBasicName: "USDT"},
+		{Hash: "b1", ChainId: basedef.BSC_CROSSCHAIN_ID, Name: "USDT", Precision: 18, TokenBasicName: "USDT"},
+	}).Error)
+	transfer := func(hash string, chainId uint64, asset string, amount int64) *models.SrcTransfer {
+		return &models.SrcTransfer{TxHash: hash, ChainId: chainId, Asset: asset, Amount: models.NewBigIntFromInt(amount)}
+	}
+	assert.NoError(t, dao.db.Create([]*models.SrcTransfer{
+		transfer("61", basedef.ETHEREUM_CROSSCHAIN_ID, "a1", 1),
+		transfer("62", basedef.ETHEREUM_CROSSCHAIN_ID, "a1", 2),
+		transfer("63", basedef.BSC_CROSSCHAIN_ID, "b1", 5),
+	}).Error)
+
+	assetInfos, err := dao.CalculateAssets("USDT", 0, 3)
+	assert.NoError(t, err)
+	amounts := make(map[uint64]string)
+	for _, assetInfo := range assetInfos {
+		assert.Equal(t, int64(100000000), assetInfo.Price)
+		amounts[assetInfo.Precision] = fmt.Sprintf("%s/%d", assetInfo.Amount.String(), assetInfo.Txnum)
+	}
+	assert.Equal(t, map[uint64]string{6: "3/2", 18: "5/1"}, amounts)
+}
+
+func TestBlockHashes(t *testing.T) {
+	dao := newTestBridgeDao(t)
+
+	hashes := []*models.BlockHash{
+		{ChainId: basedef.ETHEREUM_CROSSCHAIN_ID, Height: 10, Hash: "a10", ParentHash: "a9"},
+		{ChainId: basedef.ETHEREUM_CROSSCHAIN_ID, Height: 11, Hash: "a11", ParentHash: "a10"},
+	}
+	assert.NoError(t, dao.SaveBlockHashes(basedef.ETHEREUM_CROSSCHAIN_ID, hashes, 0))
+	// a height saved again takes the new hash and the heights below pruneBelow are removed
+	assert.NoError(t, dao.SaveBlockHashes(basedef.ETHEREUM_CROSSCHAIN_ID, []*models.BlockHash{
+		{ChainId: basedef.ETHEREUM_CROSSCHAIN_ID, Height: 11, Hash: "b11", ParentHash: "a10"},
+		{ChainId: basedef.ETHEREUM_CROSSCHAIN_ID, Height: 12, Hash: "b12", ParentHash: "b11"},
+	}, 11))
+	saved, err := dao.GetBlockHashes(basedef.ETHEREUM_CROSSCHAIN_ID, 0, 100)
+	assert.NoError(t, err)
+	assert.Len(t, saved, 2)
+	assert.Equal(t, "b12", saved[0].Hash)
+	assert.Equal(t, "b11", saved[1].Hash)
+
+	assert.NoError(t, dao.RemoveBlockHashes(basedef.ETHEREUM_CROSSCHAIN_ID, 11))
+	saved, err = dao.GetBlockHashes(basedef.ETHEREUM_CROSSCHAIN_ID, 0, 100)
+	assert.NoError(t, err)
+	assert.Len(t, saved, 1)
+
+	assert.NoError(t, dao.RemoveEvents([]string{"1"}, []string{"2"}, []string{"3"}))
+	assert.NoError(t, dao.db.Migrator().DropTable(&models.DstTransfer{}))
+	assert.Error(t, dao.RemoveEvents([]string{"1"}, []string{"2"}, []string{"3"}), "a failed delete is reported")
+}
+
+func TestEventOutbox(t *testing.T) {
+	dao := newTestBridgeDao(t)
+	assert.NoError(t, dao.db.AutoMigrate(&models.EventOutbox{}))
+	dao.EnableEventOutbox()
+
+	assert.NoError(t, dao.UpdateEvents(nil, []*models.SrcTransaction{testSrcTransaction("31", "", basedef.ETHEREUM_CROSSCHAIN_ID)}, nil, nil, nil, nil))
+	outbox, err := dao.GetEventOutbox(10)
+	assert.NoError(t, err)
+	assert.Len(t, outbox, 1)
+
+	// the events are not saved when the outbox write fails
+	assert.NoError(t, dao.db.Migrator().DropTable(&models.EventOutbox{}))
+	assert.Error(t, dao.UpdateEvents(nil, []*models.SrcTransaction{testSrcTransaction("32", "", basedef.ETHEREUM_CROSSCHAIN_ID)}, nil, nil, nil, nil))
+	var count int64
+	assert.NoError(t, dao.db.Model(&models.SrcTransaction{}).Where("hash = ?", "32").Count(&count).Error)
+	assert.Equal(t, int64(0), count)
+}
+
+type countLogger struct {
+	logger.Interface
+	statements int
+}
+
+func (l *countLogger) Trace(ctx context.Context, begin time.Time, fc func() (string, int64), err error) {
+	l.statements++
+}
+
+func TestUpdateCrossChainTxPolysBatch(t *testing.T) {
+	dao := newTestBridgeDao(t)
+
+	srcTransactions := make([]*models.SrcTransaction, 0)
+	polyTransactions := make([]*models.PolyTransaction, 0)
+	for i := 0; i < 20; i++ {
+		srcTransactions = append(srcTransactions, testSrcTransaction(fmt.Sprintf("4%02d", i), "", basedef.ETHEREUM_CROSSCHAIN_ID))
+		polyTransactions = append(polyTransactions, testPolyTransaction(fmt.Sprintf("5%02d", i), fmt.Sprintf("4%02d", i), basedef.ETHEREUM_CROSSCHAIN_ID, basedef.BSC_CROSSCHAIN_ID, 0))
+	}
+	assert.NoError(t, dao.UpdateEvents(nil, srcTransactions, nil, nil, nil, nil))
+	counter := &countLogger{Interface: logger.Discard}
+	db := dao.db.Session(&gorm.Session{Logger: counter})
+	assert.NoError(t, UpdateCrossChainTxs(db, nil, nil, polyTransactions, nil))
+	assert.Equal(t, 3, counter.statements, "the poly legs are applied with a fixed number of queries")
+	for i := 0; i < 20; i++ {
+		assert.Equal(t, fmt.Sprintf("5%02d", i), getCrossChainTx(t, dao, fmt.Sprintf("4%02d", i)).PolyHash)
+	}
+}
@@ -32,6 +32,10 @@ type CrossChainDao interface {
 	FillTxSpecialChain(wrapperTransactions []*models.WrapperTransaction, srcTransactions []*models.SrcTransaction, polyTransactions []*models.PolyTransaction, dstTransactions []*models.DstTransaction, wrapperDetails []*models.WrapperDetail, polyDetails []*models.PolyDetail) ([]*models.WrapperTransaction, error)
 	UpdateEvents(wrapperTransactions []*models.WrapperTransaction, srcTransactions []*models.SrcTransaction, polyTransactions []*models.PolyTransaction, dstTransactions []*models.DstTransaction, wrapperDetails []*models.WrapperDetail, polyDetails []*models.PolyDetail) error
 	RemoveEvents(srcHashes []string, polyHashes []string, dstHashes []string) error
+	RemoveEventsAbove(chainId uint64, above uint64) error
+	SaveBlockHashes(chainId uint64, hashes []*models.BlockHash, pruneBelow uint64) error
+	GetBlockHashes(chainId uint64, start uint64, end uint64) ([]*models.BlockHash, error)
+	RemoveBlockHashes(chainId uint64, above uint64) error
 	GetChain(chainId uint64) (*models.Chain, error)
 	GetTokenBasicByHash(chainId uint64, hash string) (*models.Token, error)
 	GetDstTransactionByHash(hash string) (*models.DstTransaction, error)
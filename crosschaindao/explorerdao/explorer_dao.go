@@ -20,13 +20,12 @@ package explorerdao
 import (
 	"encoding/json"
 	"fmt"
-	"gorm.io/driver/mysql"
 	"gorm.io/gorm"
-	"gorm.io/gorm/logger"
 	"poly-bridge/basedef"
 	"poly-bridge/conf"
 	serverconf "poly-bridge/conf"
 	"poly-bridge/models"
+	"poly-bridge/utils/database"
 )
 
 type Chain struct {
@@ -166,12 +165,7 @@ func NewExplorerDao(dbCfg *conf.DBConfig, backup bool) *ExplorerDao {
 		dbCfg:  dbCfg,
 		backup: backup,
 	}
-	Logger := logger.Default
-	if dbCfg.Debug == true {
-		Logger = Logger.LogMode(logger.Info)
-	}
-	db, err := gorm.Open(mysql.Open(dbCfg.User+":"+dbCfg.Password+"@tcp("+dbCfg.URL+")/"+
-		dbCfg.Scheme+"?charset=utf8"), &gorm.Config{Logger: Logger})
+	db, err := database.Open(dbCfg)
 	if err != nil {
 		panic(err)
 	}
@@ -249,13 +243,48 @@ func (dao *ExplorerDao) UpdateEvents(wrapperTransactions []*models.WrapperTransa
 }
 
 func (dao *ExplorerDao) RemoveEvents(srcHashes []string, polyHashes []string, dstHashes []string) error {
-	dao.db.Where("`txhash` in ?", srcHashes).Delete(&SrcTransfer{})
-	dao.db.Where("`txhash` in ?", srcHashes).Delete(&SrcTransaction{})
+	return dao.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Where("txhash in ?", srcHashes).Delete(&SrcTransfer{}).Error; err != nil {
+			return err
+		}
+		if err := tx.Where("txhash in ?", srcHashes).Delete(&SrcTransaction{}).Error; err != nil {
+			return err
+		}
+		if err := tx.Where("txhash in ?", polyHashes).Delete(&PolyTransaction{}).Error; err != nil {
+			return err
+		}
+		if err := tx.Where("txhash in ?", dstHashes).Delete(&DstTransfer{}).Error; err != nil {
+			return err
+		}
+		return tx.Where("txhash in ?", dstHashes).Delete(&DstTransaction{}).Error
+	})
+}
 
-	dao.db.Where("`txhash` in ?", polyHashes).Delete(&PolyTransaction{})
+// RemoveEventsAbove removes the events the listener of the chain saved above the height, the orphaned blocks of a reorg
+func (dao *ExplorerDao) RemoveEventsAbove(chainId uint64, above uint64) error {
+	var srcHashes, polyHashes, dstHashes []string
+	if err := dao.db.Model(&SrcTransaction{}).Where("chain_id = ? and height > ?", chainId, above).Pluck("txhash", &srcHashes).Error; err != nil {
+		return err
+	}
+	if err := dao.db.Model(&PolyTransaction{}).Where("chain_id = ? and height > ?", chainId, above).Pluck("txhash", &polyHashes).Error; err != nil {
+		return err
+	}
+	if err := dao.db.Model(&DstTransaction{}).Where("chain_id = ? and height > ?", chainId, above).Pluck("txhash", &dstHashes).Error; err != nil {
+		return err
+	}
+	return dao.RemoveEvents(srcHashes, polyHashes, dstHashes)
+}
+
+// SaveBlockHashes does nothing, the explorer schema has no block hashes and its listeners do not detect reorgs
+func (dao *ExplorerDao) SaveBlockHashes(chainId uint64, hashes []*models.BlockHash, pruneBelow uint64) error {
+	return nil
+}
+
+func (dao *ExplorerDao) GetBlockHashes(chainId uint64, start uint64, end uint64) ([]*models.BlockHash, error) {
+	return nil, nil
+}
 
-	dao.db.Where("`txhash` in ?", dstHashes).Delete(&DstTransfer{})
-	dao.db.Where("`txhash` in ?", dstHashes).Delete(&DstTransaction{})
+func (dao *ExplorerDao) RemoveBlockHashes(chainId uint64, above uint64) error {
 	return nil
 }
 
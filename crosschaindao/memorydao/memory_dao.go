@@ -0,0 +1,480 @@
+/*
+ * Copyright (C) 2020 The poly network Authors
+ * This file is part of The poly network library.
+ *
+ * The  poly network  is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU Lesser General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * The  poly network  is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU Lesser General Public License for more details.
+ * You should have received a copy of the GNU Lesser General Public License
+ * along with The poly network .  If not, see <http://www.gnu.org/licenses/>.
+ */
+
+package memorydao
+
+import (
+	"fmt"
+	"math/big"
+	"poly-bridge/basedef"
+	serverconf "poly-bridge/conf"
+	"poly-bridge/models"
+	"poly-bridge/utils/fee"
+	"sort"
+	"strings"
+	"sync"
+)
+
+// MemoryDao keeps the chains, tokens and cross chain transactions in memory, it behaves like BridgeDao
+// so the listeners and the fee check can be run without a database.
+type MemoryDao struct {
+	mutex               sync.RWMutex
+	chains              map[uint64]*models.Chain
+	chainFees           map[uint64]*models.ChainFee
+	tokenBasics         map[string]*models.TokenBasic
+	tokens              map[string]*models.Token
+	tokenMaps           map[string]*models.TokenMap
+	wrapperTransactions map[string]*models.WrapperTransaction
+	srcTransactions     map[string]*models.SrcTransaction
+	polyTransactions    map[string]*models.PolyTransaction
+	dstTransactions     map[string]*models.DstTransaction
+	wrapperDetails      map[string]*models.WrapperDetail
+	polyDetails         map[string]*models.PolyDetail
+	blockHashes         map[uint64]map[uint64]*models.BlockHash
+}
+
+func NewMemoryDao() *MemoryDao {
+	return &MemoryDao{
+		chains:              make(map[uint64]*models.Chain),
+		chainFees:           make(map[uint64]*models.ChainFee),
+		tokenBasics:         make(map[string]*models.TokenBasic),
+		tokens:              make(map[string]*models.Token),
+		tokenMaps:           make(map[string]*models.TokenMap),
+		wrapperTransactions: make(map[string]*models.WrapperTransaction),
+		srcTransactions:     make(map[string]*models.SrcTransaction),
+		polyTransactions:    make(map[string]*models.PolyTransaction),
+		dstTransactions:     make(map[string]*models.DstTransaction),
+		wrapperDetails:      make(map[string]*models.WrapperDetail),
+		polyDetails:         make(map[string]*models.PolyDetail),
+		blockHashes:         make(map[uint64]map[uint64]*models.BlockHash),
+	}
+}
+
+func tokenKey(chainId uint64, hash string) string {
+	return fmt.Sprintf("%d:%s", chainId, strings.ToLower(hash))
+}
+
+func tokenMapKey(tokenMap *models.TokenMap) string {
+	return tokenKey(tokenMap.SrcChainId, tokenMap.SrcTokenHash) + "-" + tokenKey(tokenMap.DstChainId, tokenMap.DstTokenHash)
+}
+
+func (dao *MemoryDao) UpdateEvents(wrapperTransactions []*models.WrapperTransaction, srcTransactions []*models.SrcTransaction, polyTransactions []*models.PolyTransaction, dstTransactions []*models.DstTransaction, wrapperDetails []*models.WrapperDetail, polyDetails []*models.PolyDetail) error {
+	dao.mutex.Lock()
+	defer dao.mutex.Unlock()
+	for _, v := range wrapperTransactions {
+		tx := *v
+		dao.wrapperTransactions[v.Hash] = &tx
+	}
+	for _, v := range srcTransactions {
+		tx := *v
+		dao.srcTransactions[v.Hash] = &tx
+	}
+	for _, v := range polyTransactions {
+		tx := *v
+		dao.polyTransactions[v.Hash] = &tx
+	}
+	for _, v := range dstTransactions {
+		tx := *v
+		dao.dstTransactions[v.Hash] = &tx
+	}
+	for _, v := range wrapperDetails {
+		detail := *v
+		dao.wrapperDetails[v.Hash] = &detail
+	}
+	for _, v := range polyDetails {
+		detail := *v
+		dao.polyDetails[v.Hash] = &detail
+	}
+	return nil
+}
+
+func (dao *MemoryDao) RemoveEvents(srcHashes []string, polyHashes []string, dstHashes []string) error {
+	dao.mutex.Lock()
+	defer dao.mutex.Unlock()
+	for _, hash := range srcHashes {
+		delete(dao.srcTransactions, hash)
+		delete(dao.wrapperTransactions, hash)
+	}
+	for _, hash := range polyHashes {
+		delete(dao.polyTransactions, hash)
+	}
+	for _, hash := range dstHashes {
+		delete(dao.dstTransactions, hash)
+	}
+	return nil
+}
+
+func (dao *MemoryDao) RemoveEventsAbove(chainId uint64, above uint64) error {
+	dao.mutex.Lock()
+	defer dao.mutex.Unlock()
+	for hash, v := range dao.wrapperTransactions {
+		if v.SrcChainId == chainId && v.BlockHeight > above {
+			delete(dao.wrapperTransactions, hash)
+		}
+	}
+	for hash, v := range dao.srcTransactions {
+		if v.ChainId == chainId && v.Height > above {
+			delete(dao.srcTransactions, hash)
+		}
+	}
+	for hash, v := range dao.polyTransactions {
+		if v.ChainId == chainId && v.Height > above {
+			delete(dao.polyTransactions, hash)
+		}
+	}
+	for hash, v := range dao.dstTransactions {
+		if v.ChainId == chainId && v.Height > above {
+			delete(dao.dstTransactions, hash)
+		}
+	}
+	return nil
+}
+
+func (dao *MemoryDao) SaveBlockHashes(chainId uint64, hashes []*models.BlockHash, pruneBelow uint64) error {
+	dao.mutex.Lock()
+	defer dao.mutex.Unlock()
+	chainHashes, ok := dao.blockHashes[chainId]
+	if !ok {
+		chainHashes = make(map[uint64]*models.BlockHash)
+		dao.blockHashes[chainId] = chainHashes
+	}
+	for _, v := range hashes {
+		hash := *v
+		chainHashes[v.Height] = &hash
+	}
+	for height := range chainHashes {
+		if height < pruneBelow {
+			delete(chainHashes, height)
+		}
+	}
+	return nil
+}
+
+func (dao *MemoryDao) GetBlockHashes(chainId uint64, start uint64, end uint64) ([]*models.BlockHash, error) {
+	dao.mutex.RLock()
+	defer dao.mutex.RUnlock()
+	hashes := make([]*models.BlockHash, 0)
+	for height, v := range dao.blockHashes[chainId] {
+		if height >= start && height <= end {
+			hash := *v
+			hashes = append(hashes, &hash)
+		}
+	}
+	sort.Slice(hashes, func(i, j int) bool { return hashes[i].Height > hashes[j].Height })
+	return hashes, nil
+}
+
+func (dao *MemoryDao) RemoveBlockHashes(chainId uint64, above uint64) error {
+	dao.mutex.Lock()
+	defer dao.mutex.Unlock()
+	for height := range dao.blockHashes[chainId] {
+		if height > above {
+			delete(dao.blockHashes[chainId], height)
+		}
+	}
+	return nil
+}
+
+func (dao *MemoryDao) GetChain(chainId uint64) (*models.Chain, error) {
+	dao.mutex.RLock()
+	defer dao.mutex.RUnlock()
+	chain, ok := dao.chains[chainId]
+	if !ok {
+		return nil, fmt.Errorf("no record!")
+	}
+	result := *chain
+	result.HeightSwap = 0
+	return &result, nil
+}
+
+func (dao *MemoryDao) UpdateChain(chain *models.Chain) error {
+	if chain == nil {
+		return fmt.Errorf("no value!")
+	}
+	dao.mutex.Lock()
+	defer dao.mutex.Unlock()
+	if _, ok := dao.chains[chain.ChainId]; !ok {
+		return fmt.Errorf("no update!")
+	}
+	result := *chain
+	dao.chains[chain.ChainId] = &result
+	return nil
+}
+
+func (dao *MemoryDao) AddChains(chains []*models.Chain, chainFees []*models.ChainFee) error {
+	dao.mutex.Lock()
+	defer dao.mutex.Unlock()
+	for _, v := range chains {
+		chain := *v
+		dao.chains[v.ChainId] = &chain
+	}
+	for _, v := range chainFees {
+		chainFee := *v
+		dao.chainFees[v.ChainId] = &chainFee
+	}
+	return nil
+}
+
+// AddTokens saves the token basics with their tokens, the token maps between the tokens of a token basic
+// are added as BridgeDao does.
+func (dao *MemoryDao) AddTokens(tokenBasics []*models.TokenBasic, tokenMaps []*models.TokenMap, servercfg *serverconf.Config) error {
+	dao.mutex.Lock()
+	defer dao.mutex.Unlock()
+	for _, tokenBasic := range tokenBasics {
+		dao.tokenBasics[tokenBasic.Name] = tokenBasic
+		for _, token := range tokenBasic.Tokens {
+			token.TokenBasicName = tokenBasic.Name
+			token.TokenBasic = tokenBasic
+			dao.tokens[tokenKey(token.ChainId, token.Hash)] = token
+		}
+		for _, tokenSrc := range tokenBasic.Tokens {
+			for _, tokenDst := range tokenBasic.Tokens {
+				if tokenDst.ChainId != tokenSrc.ChainId {
+					tokenMap := &models.TokenMap{
+						SrcChainId:   tokenSrc.ChainId,
+						SrcTokenHash: tokenSrc.Hash,
+						DstChainId:   tokenDst.ChainId,
+						DstTokenHash: tokenDst.Hash,
+						Property:     1,
+					}
+					dao.tokenMaps[tokenMapKey(tokenMap)] = tokenMap
+				}
+			}
+		}
+	}
+	for _, tokenMap := range tokenMaps {
+		dao.tokenMaps[tokenMapKey(tokenMap)] = tokenMap
+	}
+	return nil
+}
+
+func (dao *MemoryDao) RemoveTokenMaps(tokenMaps []*models.TokenMap) error {
+	dao.mutex.Lock()
+	defer dao.mutex.Unlock()
+	for _, tokenMap := range tokenMaps {
+		if v, ok := dao.tokenMaps[tokenMapKey(tokenMap)]; ok {
+			v.Property = 0
+		}
+	}
+	return nil
+}
+
+func (dao *MemoryDao) RemoveTokens(tokens []string) error {
+	dao.mutex.Lock()
+	defer dao.mutex.Unlock()
+	for _, name := range tokens {
+		tokenBasic, ok := dao.tokenBasics[name]
+		if !ok {
+			return fmt.Errorf("token basic %s not found", name)
+		}
+		for _, token := range tokenBasic.Tokens {
+			key := tokenKey(token.ChainId, token.Hash)
+			delete(dao.tokens, key)
+			for k, tokenMap := range dao.tokenMaps {
+				if tokenKey(tokenMap.SrcChainId, tokenMap.SrcTokenHash) == key || tokenKey(tokenMap.DstChainId, tokenMap.DstTokenHash) == key {
+					delete(dao.tokenMaps, k)
+				}
+			}
+		}
+		delete(dao.tokenBasics, name)
+	}
+	return nil
+}
+
+func (dao *MemoryDao) Name() string {
+	return basedef.SERVER_POLY_BRIDGE
+}
+
+func (dao *MemoryDao) GetTokenBasicByHash(chainId uint64, hash string) (*models.Token, error) {
+	dao.mutex.RLock()
+	defer dao.mutex.RUnlock()
+	token, ok := dao.tokens[tokenKey(chainId, hash)]
+	if !ok {
+		return nil, fmt.Errorf("no record!")
+	}
+	return token, nil
+}
+
+func (dao *MemoryDao) GetDstTransactionByHash(hash string) (*models.DstTransaction, error) {
+	dao.mutex.RLock()
+	defer dao.mutex.RUnlock()
+	dstTransaction, ok := dao.dstTransactions[hash]
+	if !ok {
+		return nil, fmt.Errorf("no record!")
+	}
+	return dstTransaction, nil
+}
+
+// WrapperTransactionCheckFee checks the fee of the new wrapper transactions against the chain fees added by AddChains,
+// the wrapper transactions saved already keep their IsPaid and PaidGas.
+func (dao *MemoryDao) WrapperTransactionCheckFee(wrapperTransactions []*models.WrapperTransaction, srcTransactions []*models.SrcTransaction) error {
+	if len(wrapperTransactions) == 0 {
+		return nil
+	}
+	dao.mutex.RLock()
+	defer dao.mutex.RUnlock()
+	for _, v := range wrapperTransactions {
+		if wrapperTx, ok := dao.wrapperTransactions[v.Hash]; ok {
+			v.IsPaid = wrapperTx.IsPaid
+			v.PaidGas = wrapperTx.PaidGas
+			continue
+		}
+		var srcTransaction *models.SrcTransaction
+		for _, tx := range srcTransactions {
+			if tx.Hash == v.Hash {
+				srcTransaction = tx
+				break
+			}
+		}
+		if srcTransaction == nil {
+			continue
+		}
+		fee.CheckWrapperFee(v, srcTransaction, dao.tokens[tokenKey(v.SrcChainId, v.FeeTokenHash)], dao.chainFees)
+	}
+	return nil
+}
+
+// FillTxSpecialChain merges the ripple wrapper details into wrapper transactions and links the ripple
+// destination transactions to the poly transactions by sequence, as BridgeDao does.
+func (dao *MemoryDao) FillTxSpecialChain(wrapperTransactions []*models.WrapperTransaction, srcTransactions []*models.SrcTransaction, polyTransactions []*models.PolyTransaction, dstTransactions []*models.DstTransaction, wrapperDetails []*models.WrapperDetail, polyDetails []*models.PolyDetail) (detailWrapperTxs []*models.WrapperTransaction, err error) {
+	dao.mutex.RLock()
+	defer dao.mutex.RUnlock()
+	wrapperHashWds := make(map[string]map[string]*models.WrapperDetail)
+	for _, v := range wrapperDetails {
+		if v.SrcChainId != basedef.RIPPLE_CROSSCHAIN_ID {
+			continue
+		}
+		if _, ok := wrapperHashWds[v.WrapperHash]; !ok {
+			wrapperHashWds[v.WrapperHash] = make(map[string]*models.WrapperDetail)
+			for _, saved := range dao.wrapperDetails {
+				if saved.WrapperHash == v.WrapperHash {
+					wrapperHashWds[v.WrapperHash][saved.Hash] = saved
+				}
+			}
+		}
+		wrapperHashWds[v.WrapperHash][v.Hash] = v
+	}
+	for wrapperHash, wds := range wrapperHashWds {
+		feeAmount := big.NewInt(0)
+		var wrapperDetail *models.WrapperDetail
+		for _, v := range wds {
+			if wrapperDetail == nil {
+				wrapperDetail = v
+			}
+			feeAmount.Add(feeAmount, &v.FeeAmount.Int)
+		}
+		detailWrapperTxs = append(detailWrapperTxs, &models.WrapperTransaction{
+			Hash:         wrapperHash,
+			User:         wrapperDetail.User,
+			DstChainId:   wrapperDetail.DstChainId,
+			DstUser:      wrapperDetail.DstUser,
+			FeeTokenHash: wrapperDetail.FeeTokenHash,
+			FeeAmount:    models.NewBigInt(feeAmount),
+			ServerId:     wrapperDetail.ServerId,
+			Status:       wrapperDetail.Status,
+			Time:         wrapperDetail.Time,
+			BlockHeight:  wrapperDetail.BlockHeight,
+			SrcChainId:   wrapperDetail.SrcChainId,
+		})
+	}
+	for _, v := range dstTransactions {
+		if v.ChainId != basedef.RIPPLE_CROSSCHAIN_ID {
+			continue
+		}
+		for _, polyTransaction := range dao.polyTransactions {
+			if polyTransaction.DstChainId == basedef.RIPPLE_CROSSCHAIN_ID && polyTransaction.DstSequence == v.Sequence {
+				v.PolyHash = polyTransaction.Hash
+				v.SrcChainId = polyTransaction.SrcChainId
+				break
+			}
+		}
+	}
+	return
+}
+
+func (dao *MemoryDao) GetWrapperTransaction(hash string) (*models.WrapperTransaction, error) {
+	dao.mutex.RLock()
+	defer dao.mutex.RUnlock()
+	wrapperTransaction, ok := dao.wrapperTransactions[hash]
+	if !ok {
+		return nil, fmt.Errorf("no record!")
+	}
+	return wrapperTransaction, nil
+}
+
+func (dao *MemoryDao) GetSrcTransaction(hash string) (*models.SrcTransaction, error) {
+	dao.mutex.RLock()
+	defer dao.mutex.RUnlock()
+	srcTransaction, ok := dao.srcTransactions[hash]
+	if !ok {
+		return nil, fmt.Errorf("no record!")
+	}
+	return srcTransaction, nil
+}
+
+func (dao *MemoryDao) GetPolyTransaction(hash string) (*models.PolyTransaction, error) {
+	dao.mutex.RLock()
+	defer dao.mutex.RUnlock()
+	polyTransaction, ok := dao.polyTransactions[hash]
+	if !ok {
+		return nil, fmt.Errorf("no record!")
+	}
+	return polyTransaction, nil
+}
+
+// GetTransactionByHash joins the transactions of the source hash the same way the transaction controller does:
+// poly_transactions.src_hash = src_transactions.hash and dst_transactions.poly_hash = poly_transactions.hash.
+func (dao *MemoryDao) GetTransactionByHash(hash string) (*models.SrcPolyDstRelation, error) {
+	dao.mutex.RLock()
+	defer dao.mutex.RUnlock()
+	srcTransaction, ok := dao.srcTransactions[hash]
+	if !ok {
+		return nil, fmt.Errorf("transacion: %s does not exist", hash)
+	}
+	relation := &models.SrcPolyDstRelation{
+		SrcHash:        srcTransaction.Hash,
+		SrcTransaction: srcTransaction,
+		ChainId:        srcTransaction.ChainId,
+	}
+	if wrapperTransaction, ok := dao.wrapperTransactions[hash]; ok {
+		relation.WrapperTransaction = wrapperTransaction
+		relation.FeeTokenHash = wrapperTransaction.FeeTokenHash
+		relation.FeeToken = dao.tokens[tokenKey(srcTransaction.ChainId, wrapperTransaction.FeeTokenHash)]
+	}
+	if srcTransaction.SrcTransfer != nil {
+		relation.TokenHash = srcTransaction.SrcTransfer.Asset
+		relation.Token = dao.tokens[tokenKey(srcTransaction.ChainId, srcTransaction.SrcTransfer.Asset)]
+	}
+	for _, polyTransaction := range dao.polyTransactions {
+		if polyTransaction.SrcHash == srcTransaction.Hash {
+			relation.PolyHash = polyTransaction.Hash
+			relation.PolyTransaction = polyTransaction
+			break
+		}
+	}
+	if relation.PolyTransaction == nil {
+		return relation, nil
+	}
+	for _, dstTransaction := range dao.dstTransactions {
+		if dstTransaction.PolyHash == relation.PolyHash {
+			relation.DstHash = dstTransaction.Hash
+			relation.DstTransaction = dstTransaction
+			break
+		}
+	}
+	return relation, nil
+}
@@ -0,0 +1,110 @@
+package memorydao
+
+import (
+	"math/big"
+	"testing"
+
+	"poly-bridge/basedef"
+	"poly-bridge/models"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func newBigInt(s string) *models.BigInt {
+	value, _ := new(big.Int).SetString(s, 10)
+	return models.NewBigInt(value)
+}
+
+func newTestDao() *MemoryDao {
+	dao := NewMemoryDao()
+	eth := &models.TokenBasic{
+		Name:      "ETH",
+		Precision: 18,
+		Price:     2000 * basedef.PRICE_PRECISION,
+		Tokens: []*models.Token{
+			{Hash: "0000000000000000000000000000000000000000", ChainId: basedef.ETHEREUM_CROSSCHAIN_ID, Name: "ETH", Precision: 18},
+			{Hash: "2170ed0880ac9a755fd29b2688956bd959f933f8", ChainId: basedef.BSC_CROSSCHAIN_ID, Name: "ETH", Precision: 18},
+		},
+	}
+	dao.AddTokens([]*models.TokenBasic{eth}, nil, nil)
+	dao.AddChains([]*models.Chain{
+		{ChainId: basedef.ETHEREUM_CROSSCHAIN_ID, Name: "Ethereum", Height: 100},
+		{ChainId: basedef.BSC_CROSSCHAIN_ID, Name: "BSC", Height: 200},
+	}, []*models.ChainFee{
+		// min fee of 0.001 ETH on BSC
+		{ChainId: basedef.BSC_CROSSCHAIN_ID, TokenBasicName: "ETH", TokenBasic: eth, MinFee: newBigInt("100000000000000000000000")},
+	})
+	return dao
+}
+
+func TestWrapperTransactionCheckFee(t *testing.T) {
+	dao := newTestDao()
+	wrapper := func(hash string, dstChainId uint64, feeAmount string) *models.WrapperTransaction {
+		return &models.WrapperTransaction{Hash: hash, SrcChainId: basedef.ETHEREUM_CROSSCHAIN_ID, DstChainId: dstChainId,
+			FeeTokenHash: "0000000000000000000000000000000000000000", FeeAmount: newBigInt(feeAmount)}
+	}
+	wrapperTransactions := []*models.WrapperTransaction{
+		wrapper("paid", basedef.BSC_CROSSCHAIN_ID, "2000000000000000"),
+		wrapper("underpaid", basedef.BSC_CROSSCHAIN_ID, "500000000000000"),
+		wrapper("nofee", basedef.NEO_CROSSCHAIN_ID, "2000000000000000"),
+	}
+	srcTransactions := []*models.SrcTransaction{{Hash: "paid"}, {Hash: "underpaid"}, {Hash: "nofee"}}
+	assert.NoError(t, dao.WrapperTransactionCheckFee(wrapperTransactions, srcTransactions))
+	assert.True(t, wrapperTransactions[0].IsPaid)
+	assert.False(t, wrapperTransactions[1].IsPaid)
+	assert.False(t, wrapperTransactions[2].IsPaid)
+
+	// the saved wrapper transactions keep their result
+	assert.NoError(t, dao.UpdateEvents(wrapperTransactions, srcTransactions, nil, nil, nil, nil))
+	again := []*models.WrapperTransaction{wrapper("paid", basedef.BSC_CROSSCHAIN_ID, "0")}
+	assert.NoError(t, dao.WrapperTransactionCheckFee(again, nil))
+	assert.True(t, again[0].IsPaid)
+}
+
+func TestGetTransactionByHash(t *testing.T) {
+	dao := newTestDao()
+	err := dao.UpdateEvents(
+		[]*models.WrapperTransaction{{Hash: "src", FeeTokenHash: "0000000000000000000000000000000000000000"}},
+		[]*models.SrcTransaction{{Hash: "src", ChainId: basedef.ETHEREUM_CROSSCHAIN_ID, SrcTransfer: &models.SrcTransfer{Asset: "0000000000000000000000000000000000000000"}}},
+		[]*models.PolyTransaction{{Hash: "poly", SrcHash: "src"}},
+		[]*models.DstTransaction{{Hash: "dst", PolyHash: "poly", ChainId: basedef.BSC_CROSSCHAIN_ID}},
+		nil, nil)
+	assert.NoError(t, err)
+
+	relation, err := dao.GetTransactionByHash("src")
+	assert.NoError(t, err)
+	assert.Equal(t, "poly", relation.PolyHash)
+	assert.Equal(t, "dst", relation.DstHash)
+	assert.Equal(t, "ETH", relation.Token.TokenBasicName)
+	assert.Equal(t, "ETH", relation.FeeToken.TokenBasic.Name)
+
+	assert.NoError(t, dao.RemoveEvents(nil, nil, []string{"dst"}))
+	relation, err = dao.GetTransactionByHash("src")
+	assert.NoError(t, err)
+	assert.Equal(t, "poly", relation.PolyHash)
+	assert.Nil(t, relation.DstTransaction)
+
+	_, err = dao.GetTransactionByHash("unknown")
+	assert.Error(t, err)
+}
+
+func TestChainAndTokens(t *testing.T) {
+	dao := newTestDao()
+	chain, err := dao.GetChain(basedef.ETHEREUM_CROSSCHAIN_ID)
+	assert.NoError(t, err)
+	chain.Height = 150
+	assert.Equal(t, uint64(100), dao.chains[basedef.ETHEREUM_CROSSCHAIN_ID].Height, "the chain returned is a copy")
+	assert.NoError(t, dao.UpdateChain(chain))
+	chain, _ = dao.GetChain(basedef.ETHEREUM_CROSSCHAIN_ID)
+	assert.Equal(t, uint64(150), chain.Height)
+	assert.Error(t, dao.UpdateChain(&models.Chain{ChainId: basedef.NEO_CROSSCHAIN_ID}))
+
+	token, err := dao.GetTokenBasicByHash(basedef.BSC_CROSSCHAIN_ID, "2170ED0880AC9A755FD29B2688956BD959F933F8")
+	assert.NoError(t, err)
+	assert.Equal(t, "ETH", token.TokenBasic.Name)
+	assert.Len(t, dao.tokenMaps, 2)
+	assert.NoError(t, dao.RemoveTokens([]string{"ETH"}))
+	_, err = dao.GetTokenBasicByHash(basedef.BSC_CROSSCHAIN_ID, "2170ed0880ac9a755fd29b2688956bd959f933f8")
+	assert.Error(t, err)
+	assert.Empty(t, dao.tokenMaps)
+}
@@ -90,6 +90,22 @@ func (dao *StakeDao) RemoveEvents(srcHashes []string, polyHashes []string, dstHa
 	return nil
 }
 
+func (dao *StakeDao) RemoveEventsAbove(chainId uint64, above uint64) error {
+	return nil
+}
+
+func (dao *StakeDao) SaveBlockHashes(chainId uint64, hashes []*models.BlockHash, pruneBelow uint64) error {
+	return nil
+}
+
+func (dao *StakeDao) GetBlockHashes(chainId uint64, start uint64, end uint64) ([]*models.BlockHash, error) {
+	return nil, nil
+}
+
+func (dao *StakeDao) RemoveBlockHashes(chainId uint64, above uint64) error {
+	return nil
+}
+
 func (dao *StakeDao) GetTokenBasicByHash(chainId uint64, hash string) (*models.Token, error) {
 	return nil, nil
 }
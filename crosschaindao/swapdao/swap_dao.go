@@ -21,15 +21,15 @@ import (
 	"encoding/json"
 	"fmt"
 	"github.com/beego/beego/v2/core/logs"
-	"gorm.io/driver/mysql"
 	"gorm.io/gorm"
-	"gorm.io/gorm/logger"
+	"gorm.io/gorm/clause"
 	"math/big"
 	"poly-bridge/basedef"
 	"poly-bridge/coinpricelisten/coinmarketcap"
 	"poly-bridge/conf"
 	serverconf "poly-bridge/conf"
 	"poly-bridge/models"
+	"poly-bridge/utils/database"
 	"strings"
 	"time"
 )
@@ -45,12 +45,7 @@ func NewSwapDao(dbCfg *conf.DBConfig, backup bool) *SwapDao {
 		dbCfg:  dbCfg,
 		backup: backup,
 	}
-	Logger := logger.Default
-	if dbCfg.Debug == true {
-		Logger = Logger.LogMode(logger.Info)
-	}
-	db, err := gorm.Open(mysql.Open(dbCfg.User+":"+dbCfg.Password+"@tcp("+dbCfg.URL+")/"+
-		dbCfg.Scheme+"?charset=utf8"), &gorm.Config{Logger: Logger})
+	db, err := database.Open(dbCfg)
 	if err != nil {
 		panic(err)
 	}
@@ -118,15 +113,70 @@ func (dao *SwapDao) UpdateEvents(wrapperTransactions []*models.WrapperTransactio
 }
 
 func (dao *SwapDao) RemoveEvents(srcHashes []string, polyHashes []string, dstHashes []string) error {
-	dao.db.Where("`tx_hash` in ?", srcHashes).Delete(&models.SrcTransfer{})
-	dao.db.Where("`hash` in ?", srcHashes).Delete(&models.SrcTransaction{})
-	dao.db.Where("`hash` in ?", srcHashes).Delete(&models.WrapperTransaction{})
+	return dao.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Where("tx_hash in ?", srcHashes).Delete(&models.SrcTransfer{}).Error; err != nil {
+			return err
+		}
+		if err := tx.Where("hash in ?", srcHashes).Delete(&models.SrcTransaction{}).Error; err != nil {
+			return err
+		}
+		if err := tx.Where("hash in ?", srcHashes).Delete(&models.WrapperTransaction{}).Error; err != nil {
+			return err
+		}
+		if err := tx.Where("hash in ?", polyHashes).Delete(&models.PolyTransaction{}).Error; err != nil {
+			return err
+		}
+		if err := tx.Where("tx_hash in ?", dstHashes).Delete(&models.DstTransfer{}).Error; err != nil {
+			return err
+		}
+		if err := tx.Where("hash in ?", dstHashes).Delete(&models.DstTransaction{}).Error; err != nil {
+			return err
+		}
+		return nil
+	})
+}
 
-	dao.db.Where("`hash` in ?", polyHashes).Delete(&models.PolyTransaction{})
+// RemoveEventsAbove removes the events the listener of the chain saved above the height, the orphaned blocks of a reorg
+func (dao *SwapDao) RemoveEventsAbove(chainId uint64, above uint64) error {
+	var srcHashes, wrapperHashes, polyHashes, dstHashes []string
+	if err := dao.db.Model(&models.SrcTransaction{}).Where("chain_id = ? and height > ?", chainId, above).Pluck("hash", &srcHashes).Error; err != nil {
+		return err
+	}
+	if err := dao.db.Model(&models.WrapperTransaction{}).Where("src_chain_id = ? and block_height > ?", chainId, above).Pluck("hash", &wrapperHashes).Error; err != nil {
+		return err
+	}
+	if err := dao.db.Model(&models.PolyTransaction{}).Where("chain_id = ? and height > ?", chainId, above).Pluck("hash", &polyHashes).Error; err != nil {
+		return err
+	}
+	if err := dao.db.Model(&models.DstTransaction{}).Where("chain_id = ? and height > ?", chainId, above).Pluck("hash", &dstHashes).Error; err != nil {
+		return err
+	}
+	return dao.RemoveEvents(append(srcHashes, wrapperHashes...), polyHashes, dstHashes)
+}
 
-	dao.db.Where("`tx_hash` in ?", dstHashes).Delete(&models.DstTransfer{})
-	dao.db.Where("`hash` in ?", dstHashes).Delete(&models.DstTransaction{})
-	return nil
+func (dao *SwapDao) SaveBlockHashes(chainId uint64, hashes []*models.BlockHash, pruneBelow uint64) error {
+	return dao.db.Transaction(func(tx *gorm.DB) error {
+		if len(hashes) > 0 {
+			err := tx.Clauses(clause.OnConflict{
+				Columns:   []clause.Column{{Name: "chain_id"}, {Name: "height"}},
+				DoUpdates: clause.AssignmentColumns([]string{"hash", "parent_hash"}),
+			}).Create(hashes).Error
+			if err != nil {
+				return err
+			}
+		}
+		return tx.Where("chain_id = ? and height < ?", chainId, pruneBelow).Delete(&models.BlockHash{}).Error
+	})
+}
+
+func (dao *SwapDao) GetBlockHashes(chainId uint64, start uint64, end uint64) ([]*models.BlockHash, error) {
+	hashes := make([]*models.BlockHash, 0)
+	res := dao.db.Where("chain_id = ? and height >= ? and height <= ?", chainId, start, end).Order("height desc").Find(&hashes)
+	return hashes, res.Error
+}
+
+func (dao *SwapDao) RemoveBlockHashes(chainId uint64, above uint64) error {
+	return dao.db.Where("chain_id = ? and height > ?", chainId, above).Delete(&models.BlockHash{}).Error
 }
 
 func (dao *SwapDao) GetChain(chainId uint64) (*models.Chain, error) {
@@ -24,12 +24,13 @@ import (
 	"poly-bridge/cacheRedis"
 	"poly-bridge/conf"
 	"poly-bridge/models"
+	"poly-bridge/utils/cursor"
+	"poly-bridge/utils/database"
 	"time"
 
 	"github.com/beego/beego/v2/core/logs"
-	"gorm.io/driver/mysql"
 	"gorm.io/gorm"
-	"gorm.io/gorm/logger"
+	"gorm.io/gorm/clause"
 )
 
 var checkTime int = 0
@@ -39,35 +40,28 @@ type BridgeEffect struct {
 	dbCfg    *conf.DBConfig
 	cfg      *conf.EventEffectConfig
 	db       *gorm.DB
-	redis    *cacheRedis.RedisCache
+	redis    cacheRedis.Cache
 	redisCfg *conf.RedisConfig
 	chains   []*models.Chain
 	time     int64
+	statuses *statusCache
 }
 
-func NewBridgeEffect(cfg *conf.EventEffectConfig, dbCfg *conf.DBConfig, redisCfg *conf.RedisConfig) *BridgeEffect {
+func NewBridgeEffect(cfg *conf.EventEffectConfig, dbCfg *conf.DBConfig, redisCfg *conf.RedisConfig, cache cacheRedis.Cache) *BridgeEffect {
 	swapEffect := &BridgeEffect{
 		dbCfg:    dbCfg,
 		cfg:      cfg,
+		redis:    cache,
 		redisCfg: redisCfg,
 		chains:   nil,
 		time:     0,
+		statuses: newStatusCache(maxStatuses),
 	}
-	Logger := logger.Default
-	if dbCfg.Debug == true {
-		Logger = Logger.LogMode(logger.Info)
-	}
-	db, err := gorm.Open(mysql.Open(dbCfg.User+":"+dbCfg.Password+"@tcp("+dbCfg.URL+")/"+
-		dbCfg.Scheme+"?charset=utf8"), &gorm.Config{Logger: Logger})
+	db, err := database.Open(dbCfg)
 	if err != nil {
 		panic(err)
 	}
 	swapEffect.db = db
-	redis, err := cacheRedis.GetRedisClient(redisCfg)
-	if err != nil {
-		panic(err)
-	}
-	swapEffect.redis = redis
 	chains := make([]*models.Chain, 0)
 	res := db.Model(&models.Chain{}).Find(&chains)
 	if res.Error != nil || res.RowsAffected == 0 {
@@ -83,10 +77,6 @@ func (eff *BridgeEffect) Effect() error {
 	if err != nil {
 		logs.Error("update hash- err: %s", err)
 	}
-	err = eff.updateDstHash()
-	if err != nil {
-		logs.Error("update dsthash- err: %s", err)
-	}
 	/*
 		err = eff.checkStatus()
 		if err != nil {
@@ -126,7 +116,7 @@ func (eff *BridgeEffect) StartUpdateCrossCount() error {
 	if res.RowsAffected == 0 {
 		return fmt.Errorf("StartUpdateCrossCount counter err %w", res.Error)
 	}
-	err := eff.redis.SetCrossTxCounter(counter)
+	err := cacheRedis.SetCrossTxCounter(eff.redis, counter, eff.redisCfg.GetExpiration())
 	if err != nil {
 		return fmt.Errorf("StartUpdateCrossCount SetCrossTxCounter err %w", err)
 	}
@@ -140,66 +130,63 @@ func (eff *BridgeEffect) GetEffectSlot() int64 {
 	return eff.cfg.EffectSlot
 }
 
+// updateHash links the poly transactions saved with the src key to the src hash, the unmatched ones are retried by the next runs
 func (eff *BridgeEffect) updateHash() error {
-	batch := 500
-	index := 0
-
-	for {
-		polySrcRelations := make([]*models.PolySrcRelation, 0)
-		eff.db.Table("poly_transactions").Where("poly_transactions.src_hash like ? and poly_transactions.time > ?", "00000000%", 1622476800).Select("poly_transactions.hash as poly_hash, src_transactions.hash as src_hash").Joins("inner join src_transactions on poly_transactions.src_hash = src_transactions.key and poly_transactions.src_chain_id = src_transactions.chain_id").Preload("SrcTransaction").Preload("PolyTransaction").Limit(batch).Offset(batch * index).Order("poly_transactions.time desc").Find(&polySrcRelations)
-		updatePolyTransactions := make([]*models.PolyTransaction, 0)
-		for _, polySrcRelation := range polySrcRelations {
-			if polySrcRelation.SrcTransaction != nil {
-				polySrcRelation.PolyTransaction.Key = polySrcRelation.PolyTransaction.SrcHash
-				polySrcRelation.PolyTransaction.SrcHash = polySrcRelation.SrcHash
-				updatePolyTransactions = append(updatePolyTransactions, polySrcRelation.PolyTransaction)
-			}
-		}
-		if len(updatePolyTransactions) > 0 {
-			logs.Info("updateHash now min PolyTransaction.id", updatePolyTransactions[0].Id)
-			eff.db.Save(updatePolyTransactions)
-			index++
-		} else {
-			break
-		}
+	it, err := cursor.NewIterator(eff.db, "effect_update_hash", 500)
+	if err != nil {
+		return err
 	}
-	logs.Info("Update hash finished with at most %d * 500 checked", index+1)
-	return nil
-}
-
-func (eff *BridgeEffect) updateDstHash() error {
-	batch := 500
-	index := 0
-
-	for {
-		dstPolyRelations := make([]*models.DstPolyRelation, 0)
-		eff.db.Table("dst_transactions").
-			Where("dst_transactions.poly_hash = '' and dst_transactions.chain_id = ? and dst_transactions.sequence > 0", basedef.RIPPLE_CROSSCHAIN_ID).
-			Select("dst_transactions.hash as dst_hash, poly_transactions.hash as poly_hash").
-			Joins("inner join poly_transactions on dst_transactions.sequence = poly_transactions.dst_sequence and poly_transactions.dst_chain_id = dst_transactions.chain_id").
-			Preload("PolyTransaction").
-			Preload("DstTransaction").
-			Limit(batch).
-			Offset(batch * index).
-			Order("poly_transactions.id").
-			Find(&dstPolyRelations)
-		updateDstTransactions := make([]*models.DstTransaction, 0)
-		for _, dstPolyRelation := range dstPolyRelations {
-			if dstPolyRelation.PolyTransaction != nil && dstPolyRelation.DstTransaction != nil {
-				dstPolyRelation.DstTransaction.PolyHash = dstPolyRelation.PolyTransaction.Hash
-				updateDstTransactions = append(updateDstTransactions, dstPolyRelation.DstTransaction)
+	if !it.Saved() {
+		it.Seek(cursor.Position{Time: 1622476800})
+	}
+	it.Keep(cursor.Position{Time: uint64(time.Now().Unix() - cursor.Lag)})
+	checked, updated := 0, 0
+	for _, next := range []func(*gorm.DB, interface{}) (int, error){it.NextRetry, it.Next} {
+		for {
+			polyTransactions := make([]*models.PolyTransaction, 0)
+			n, err := next(eff.db.Where("src_hash like ?", "00000000%"), &polyTransactions)
+			if err != nil {
+				return err
+			}
+			if n == 0 {
+				break
+			}
+			checked += n
+			keys := make([]string, 0, len(polyTransactions))
+			for _, polyTransaction := range polyTransactions {
+				keys = append(keys, polyTransaction.SrcHash)
+			}
+			srcTransactions := make([]*models.SrcTransaction, 0)
+			err = eff.db.Where("? in ?", clause.Column{Name: "key"}, keys).Find(&srcTransactions).Error
+			if err != nil {
+				return err
+			}
+			key2Transactions := make(map[string]*models.SrcTransaction, len(srcTransactions))
+			for _, srcTransaction := range srcTransactions {
+				key2Transactions[fmt.Sprintf("%d:%s", srcTransaction.ChainId, srcTransaction.Key)] = srcTransaction
+			}
+			updatePolyTransactions := make([]*models.PolyTransaction, 0)
+			for _, polyTransaction := range polyTransactions {
+				srcTransaction, ok := key2Transactions[fmt.Sprintf("%d:%s", polyTransaction.SrcChainId, polyTransaction.SrcHash)]
+				if !ok {
+					it.Retry(cursor.Position{Time: polyTransaction.Time, Id: polyTransaction.Id})
+					continue
+				}
+				polyTransaction.Key = polyTransaction.SrcHash
+				polyTransaction.SrcHash = srcTransaction.Hash
+				updatePolyTransactions = append(updatePolyTransactions, polyTransaction)
+			}
+			if len(updatePolyTransactions) > 0 {
+				err = eff.db.Save(updatePolyTransactions).Error
+				if err != nil {
+					return err
+				}
+				updated += len(updatePolyTransactions)
 			}
-		}
-		if len(updateDstTransactions) > 0 {
-			logs.Info("updateHash now min DstTransaction.id", updateDstTransactions[0].Id)
-			eff.db.Save(updateDstTransactions)
-			index++
-		} else {
-			break
 		}
 	}
-	logs.Info("Update DstHash finished with at most %d * 500 checked", index+1)
-	return nil
+	logs.Info("Update hash finished with %d checked and %d updated", checked, updated)
+	return it.Save()
 }
 
 func (eff *BridgeEffect) checkStatus() error {
@@ -226,6 +213,8 @@ func (eff *BridgeEffect) checkStatus() error {
 	return nil
 }
 
+// updateStatus walks the unfinished wrapper transactions by (time, id), the legs and heights are kept in cross_chain_txs by UpdateEvents.
+// The cursor goes past the rows still unfinished, they are retried by the next runs.
 func (eff *BridgeEffect) updateStatus() error {
 	chains := make([]*models.Chain, 0)
 	id2Chains := make(map[uint64]*models.Chain)
@@ -234,54 +223,107 @@ func (eff *BridgeEffect) updateStatus() error {
 		id2Chains[chain.ChainId] = chain
 	}
 
-	batch := 500
-	index := 0
-	for {
-		wrapperPolyDstRelations := make([]*models.SrcPolyDstRelation, 0)
-		wrapperTransactions := make([]*models.WrapperTransaction, 0)
-		eff.db.Table("wrapper_transactions").Where("wrapper_transactions.status != ? and wrapper_transactions.time > 1622476800", basedef.STATE_FINISHED).Select("wrapper_transactions.hash as src_hash, poly_transactions.hash as poly_hash, dst_transactions.hash as dst_hash").Joins("left join poly_transactions on wrapper_transactions.hash = poly_transactions.src_hash").Joins("left join dst_transactions on poly_transactions.hash = dst_transactions.poly_hash").Preload("WrapperTransaction").Preload("DstTransaction").Limit(batch).Offset(batch * index).Order("wrapper_transactions.time desc").Find(&wrapperPolyDstRelations)
-		for _, wrapperPolyDstRelation := range wrapperPolyDstRelations {
-			wrapperTransaction := wrapperPolyDstRelation.WrapperTransaction
-			pending := wrapperTransaction.Status == basedef.STATE_SKIP || wrapperTransaction.Status == basedef.STATE_WAIT
-			if wrapperPolyDstRelation.PolyHash == "" {
-				chain, ok := id2Chains[wrapperPolyDstRelation.WrapperTransaction.SrcChainId]
-				if ok {
-					if chain.Height-wrapperPolyDstRelation.WrapperTransaction.BlockHeight >= chain.BackwardBlockNumber {
-						wrapperTransaction.Status = basedef.STATE_SOURCE_CONFIRMED
+	it, err := cursor.NewIterator(eff.db, "effect_update_status", 500)
+	if err != nil {
+		return err
+	}
+	if !it.Saved() {
+		it.Seek(cursor.Position{Time: 1622476800})
+	}
+	it.Keep(cursor.Position{Time: uint64(time.Now().Unix() - cursor.Lag)})
+	checked := 0
+	for _, next := range []func(*gorm.DB, interface{}) (int, error){it.NextRetry, it.Next} {
+		for {
+			savedTransactions := make([]*models.WrapperTransaction, 0)
+			n, err := next(eff.db.Where("status != ?", basedef.STATE_FINISHED), &savedTransactions)
+			if err != nil {
+				return err
+			}
+			if n == 0 {
+				break
+			}
+			checked += n
+			hashes := make([]string, 0, len(savedTransactions))
+			for _, wrapperTransaction := range savedTransactions {
+				hashes = append(hashes, wrapperTransaction.Hash)
+			}
+			crossChainTxs := make([]*models.CrossChainTx, 0)
+			err = eff.db.Where("src_hash in ?", hashes).Find(&crossChainTxs).Error
+			if err != nil {
+				return err
+			}
+			hash2CrossChainTxs := make(map[string]*models.CrossChainTx, len(crossChainTxs))
+			for _, crossChainTx := range crossChainTxs {
+				hash2CrossChainTxs[crossChainTx.SrcHash] = crossChainTx
+			}
+			wrapperTransactions := make([]*models.WrapperTransaction, 0)
+			changedTransactions := make([]*models.WrapperTransaction, 0)
+			status2Hashes := make(map[uint64][]string)
+			for _, wrapperTransaction := range savedTransactions {
+				crossChainTx, ok := hash2CrossChainTxs[wrapperTransaction.Hash]
+				if !ok {
+					it.Retry(cursor.Position{Time: wrapperTransaction.Time, Id: wrapperTransaction.Id})
+					continue
+				}
+				lastStatus, known := eff.statuses.get(wrapperTransaction.Hash)
+				savedStatus := wrapperTransaction.Status
+				pending := wrapperTransaction.Status == basedef.STATE_SKIP || wrapperTransaction.Status == basedef.STATE_WAIT
+				if crossChainTx.PolyHash == "" {
+					chain, ok := id2Chains[wrapperTransaction.SrcChainId]
+					if ok {
+						if chain.Height-wrapperTransaction.BlockHeight >= chain.BackwardBlockNumber {
+							wrapperTransaction.Status = basedef.STATE_SOURCE_CONFIRMED
+						} else {
+							wrapperTransaction.Status = basedef.STATE_SOURCE_DONE
+						}
 					} else {
 						wrapperTransaction.Status = basedef.STATE_SOURCE_DONE
 					}
+				} else if crossChainTx.DstHash == "" {
+					wrapperTransaction.Status = basedef.STATE_POLY_CONFIRMED
 				} else {
-					wrapperTransaction.Status = basedef.STATE_SOURCE_DONE
-				}
-			} else if wrapperPolyDstRelation.DstHash == "" {
-				wrapperTransaction.Status = basedef.STATE_POLY_CONFIRMED
-			} else {
-				chain, ok := id2Chains[wrapperPolyDstRelation.DstTransaction.ChainId]
-				if ok {
-					if chain.Height-wrapperPolyDstRelation.DstTransaction.Height >= 1 {
-						wrapperTransaction.Status = basedef.STATE_FINISHED
+					chain, ok := id2Chains[crossChainTx.DstChainId]
+					if ok {
+						if chain.Height-crossChainTx.DstHeight >= 1 {
+							wrapperTransaction.Status = basedef.STATE_FINISHED
+						} else {
+							wrapperTransaction.Status = basedef.STATE_DESTINATION_DONE
+						}
 					} else {
-						wrapperTransaction.Status = basedef.STATE_DESTINATION_DONE
+						wrapperTransaction.Status = basedef.STATE_FINISHED
 					}
-				} else {
-					wrapperTransaction.Status = basedef.STATE_FINISHED
+				}
+				if !pending || wrapperTransaction.Status == basedef.STATE_FINISHED {
+					wrapperTransactions = append(wrapperTransactions, wrapperTransaction)
+					if !known || lastStatus != wrapperTransaction.Status {
+						changedTransactions = append(changedTransactions, wrapperTransaction)
+					}
+					savedStatus = wrapperTransaction.Status
+				}
+				if crossChainTx.Status != savedStatus {
+					status2Hashes[savedStatus] = append(status2Hashes[savedStatus], wrapperTransaction.Hash)
+				}
+				if wrapperTransaction.Status != basedef.STATE_FINISHED {
+					it.Retry(cursor.Position{Time: wrapperTransaction.Time, Id: wrapperTransaction.Id})
 				}
 			}
-			if !pending || wrapperTransaction.Status == basedef.STATE_FINISHED {
-				wrapperTransactions = append(wrapperTransactions, wrapperTransaction)
+			if len(wrapperTransactions) > 0 {
+				if err := eff.db.Save(wrapperTransactions).Error; err != nil {
+					return err
+				}
+			}
+			for status, statusHashes := range status2Hashes {
+				if err := eff.db.Model(&models.CrossChainTx{}).Where("src_hash in ?", statusHashes).Update("status", status).Error; err != nil {
+					return err
+				}
+			}
+			if len(changedTransactions) > 0 {
+				eff.handleStatusChanges(changedTransactions)
 			}
 		}
-		if len(wrapperTransactions) > 0 {
-			eff.db.Save(wrapperTransactions)
-		}
-		if len(wrapperPolyDstRelations) == 0 {
-			break
-		}
-		index++
 	}
-	logs.Info("Update wrapper tx status finished with at most %d * 500 checked", index+1)
-	return nil
+	logs.Info("Update wrapper tx status finished with %d checked", checked)
+	return it.Save()
 }
 
 func (eff *BridgeEffect) checkChainListening() error {
@@ -0,0 +1,127 @@
+/*
+ * Copyright (C) 2020 The poly network Authors
+ * This file is part of The poly network library.
+ *
+ * The  poly network  is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU Lesser General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * The  poly network  is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU Lesser General Public License for more details.
+ * You should have received a copy of the GNU Lesser General Public License
+ * along with The poly network .  If not, see <http://www.gnu.org/licenses/>.
+ */
+
+package bridgeeffect
+
+import (
+	"container/list"
+	"encoding/json"
+	"poly-bridge/basedef"
+	"poly-bridge/cacheRedis"
+	"poly-bridge/conf"
+	"poly-bridge/models"
+
+	"github.com/beego/beego/v2/core/logs"
+)
+
+// statusWatched reports whether the status changes are consumed by the webhooks or the tx stream
+func (eff *BridgeEffect) statusWatched() bool {
+	return conf.GlobalConfig != nil && (conf.GlobalConfig.WebhookConfig != nil || conf.GlobalConfig.TxStreamConfig != nil)
+}
+
+// handleStatusChanges publishes the status changes to the tx stream and queues the webhook deliveries
+func (eff *BridgeEffect) handleStatusChanges(wrapperTransactions []*models.WrapperTransaction) {
+	if !eff.statusWatched() {
+		return
+	}
+	eff.publishStatus(wrapperTransactions)
+	if eff.notifyStatus(wrapperTransactions) {
+		eff.rememberStatus(wrapperTransactions)
+	}
+}
+
+func (eff *BridgeEffect) publishStatus(wrapperTransactions []*models.WrapperTransaction) {
+	streamConfig := conf.GlobalConfig.TxStreamConfig
+	if streamConfig == nil {
+		return
+	}
+	channel := streamConfig.Channel
+	if channel == "" {
+		channel = cacheRedis.TxStatusChannel
+	}
+	for _, wrapperTransaction := range wrapperTransactions {
+		message, _ := json.Marshal(&models.TxStatusChange{
+			Hash:    wrapperTransaction.Hash,
+			User:    wrapperTransaction.User,
+			DstUser: wrapperTransaction.DstUser,
+			Status:  wrapperTransaction.Status,
+		})
+		if err := eff.redis.Publish(channel, string(message)); err != nil {
+			logs.Error("publish status of %s err: %v", wrapperTransaction.Hash, err)
+		}
+	}
+}
+
+// rememberStatus keeps the notified status of the unfinished transactions, a failed round is notified again
+func (eff *BridgeEffect) rememberStatus(wrapperTransactions []*models.WrapperTransaction) {
+	for _, wrapperTransaction := range wrapperTransactions {
+		if wrapperTransaction.Status == basedef.STATE_FINISHED {
+			eff.statuses.remove(wrapperTransaction.Hash)
+		} else {
+			eff.statuses.set(wrapperTransaction.Hash, wrapperTransaction.Status)
+		}
+	}
+}
+
+const maxStatuses = 100000
+
+// statusCache holds the last notified status of at most size transactions, the oldest ones are evicted
+// first. An evicted transaction is seen as changed again, its webhook deliveries are still not duplicated.
+type statusCache struct {
+	size     int
+	statuses map[string]*list.Element
+	order    *list.List
+}
+
+type cachedStatus struct {
+	hash   string
+	status uint64
+}
+
+func newStatusCache(size int) *statusCache {
+	return &statusCache{
+		size:     size,
+		statuses: make(map[string]*list.Element),
+		order:    list.New(),
+	}
+}
+
+func (c *statusCache) get(hash string) (uint64, bool) {
+	elem, ok := c.statuses[hash]
+	if !ok {
+		return 0, false
+	}
+	return elem.Value.(*cachedStatus).status, true
+}
+
+func (c *statusCache) set(hash string, status uint64) {
+	if elem, ok := c.statuses[hash]; ok {
+		elem.Value.(*cachedStatus).status = status
+		return
+	}
+	c.statuses[hash] = c.order.PushBack(&cachedStatus{hash: hash, status: status})
+	for len(c.statuses) > c.size {
+		c.remove(c.order.Front().Value.(*cachedStatus).hash)
+	}
+}
+
+func (c *statusCache) remove(hash string) {
+	if elem, ok := c.statuses[hash]; ok {
+		c.order.Remove(elem)
+		delete(c.statuses, hash)
+	}
+}
@@ -0,0 +1,32 @@
+package bridgeeffect
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestStatusCache(t *testing.T) {
+	c := newStatusCache(2)
+	c.set("a", 1)
+	c.set("b", 1)
+	c.set("a", 2)
+	status, _ := c.get("a")
+	assert.Equal(t, uint64(2), status)
+	c.set("c", 1)
+	_, ok := c.get("a")
+	assert.False(t, ok, "the oldest hash is evicted")
+	status, ok = c.get("b")
+	assert.True(t, ok)
+	assert.Equal(t, uint64(1), status)
+
+	c.remove("b")
+	c.remove("c")
+	for i := 0; i < 3000; i++ {
+		c.set(fmt.Sprint(i), 1)
+		c.remove(fmt.Sprint(i))
+	}
+	assert.Empty(t, c.statuses)
+	assert.Equal(t, 0, c.order.Len(), "the removed hashes leave the order")
+}
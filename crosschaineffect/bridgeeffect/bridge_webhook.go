@@ -0,0 +1,164 @@
+/*
+ * Copyright (C) 2020 The poly network Authors
+ * This file is part of The poly network library.
+ *
+ * The  poly network  is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU Lesser General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * The  poly network  is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU Lesser General Public License for more details.
+ * You should have received a copy of the GNU Lesser General Public License
+ * along with The poly network .  If not, see <http://www.gnu.org/licenses/>.
+ */
+
+package bridgeeffect
+
+import (
+	"encoding/json"
+	"poly-bridge/basedef"
+	"poly-bridge/conf"
+	"poly-bridge/models"
+	"strings"
+	"time"
+
+	"github.com/beego/beego/v2/core/logs"
+	"gorm.io/gorm/clause"
+)
+
+const defaultWebhookPageSize = 100
+
+// notifyStatus queues a webhook delivery for every subscription matching a wrapper transaction whose status
+// changed since the last round. Only the subscriptions to the hashes, users and src tokens of the transactions
+// are loaded, page by page. A delivery is unique on (subscription, hash, state), so a status seen again after
+// a restart is not delivered twice. It returns false if the changes should be notified again.
+func (eff *BridgeEffect) notifyStatus(wrapperTransactions []*models.WrapperTransaction) bool {
+	if conf.GlobalConfig == nil || conf.GlobalConfig.WebhookConfig == nil || len(wrapperTransactions) == 0 {
+		return true
+	}
+	pageSize := conf.GlobalConfig.WebhookConfig.BatchSize
+	if pageSize <= 0 {
+		pageSize = defaultWebhookPageSize
+	}
+	assets, err := eff.getWebhookAssets(wrapperTransactions)
+	if err != nil {
+		logs.Error("get src transfers of webhook notifications err: %v", err)
+		return false
+	}
+	hashes, users, tokens := webhookTargets(wrapperTransactions, assets)
+	lastId := int64(0)
+	queued := 0
+	for {
+		subscriptions := make([]*models.WebhookSubscription, 0)
+		err := eff.db.Where("id > ? and (src_hash in ? or ? in ? or token in ?)", lastId, hashes, clause.Column{Name: "user"}, users, tokens).
+			Order("id asc").Limit(pageSize).Find(&subscriptions).Error
+		if err != nil {
+			logs.Error("get webhook subscriptions err: %v", err)
+			return false
+		}
+		if len(subscriptions) == 0 {
+			break
+		}
+		lastId = subscriptions[len(subscriptions)-1].Id
+		deliveries := makeWebhookDeliveries(subscriptions, wrapperTransactions, assets)
+		if len(deliveries) > 0 {
+			if err := eff.db.Clauses(clause.OnConflict{DoNothing: true}).Create(deliveries).Error; err != nil {
+				logs.Error("add webhook deliveries err: %v", err)
+				return false
+			}
+			queued += len(deliveries)
+		}
+		if len(subscriptions) < pageSize {
+			break
+		}
+	}
+	if queued > 0 {
+		logs.Info("queued %d webhook deliveries", queued)
+	}
+	return true
+}
+
+// webhookTargets lists the lower case hashes, users and src tokens the subscriptions of the transactions are made to
+func webhookTargets(wrapperTransactions []*models.WrapperTransaction, assets map[string]*models.SrcTransfer) ([]string, []string, []string) {
+	hashes := make([]string, 0, len(wrapperTransactions))
+	users := make([]string, 0, 2*len(wrapperTransactions))
+	tokens := make([]string, 0, len(assets))
+	for _, wrapperTransaction := range wrapperTransactions {
+		hashes = append(hashes, strings.ToLower(wrapperTransaction.Hash))
+		for _, user := range []string{wrapperTransaction.User, wrapperTransaction.DstUser} {
+			if user != "" {
+				users = append(users, strings.ToLower(user))
+			}
+		}
+	}
+	for _, srcTransfer := range assets {
+		tokens = append(tokens, strings.ToLower(srcTransfer.Asset))
+	}
+	return hashes, users, tokens
+}
+
+func (eff *BridgeEffect) getWebhookAssets(wrapperTransactions []*models.WrapperTransaction) (map[string]*models.SrcTransfer, error) {
+	hashes := make([]string, 0, len(wrapperTransactions))
+	for _, wrapperTransaction := range wrapperTransactions {
+		hashes = append(hashes, wrapperTransaction.Hash)
+	}
+	srcTransfers := make([]*models.SrcTransfer, 0)
+	if err := eff.db.Where("tx_hash in ?", hashes).Find(&srcTransfers).Error; err != nil {
+		return nil, err
+	}
+	assets := make(map[string]*models.SrcTransfer)
+	for _, srcTransfer := range srcTransfers {
+		assets[srcTransfer.TxHash] = srcTransfer
+	}
+	return assets, nil
+}
+
+func makeWebhookDeliveries(subscriptions []*models.WebhookSubscription, wrapperTransactions []*models.WrapperTransaction, assets map[string]*models.SrcTransfer) []*models.WebhookDelivery {
+	now := time.Now().Unix()
+	deliveries := make([]*models.WebhookDelivery, 0)
+	for _, wrapperTransaction := range wrapperTransactions {
+		for _, subscription := range subscriptions {
+			if !subscription.Accepts(wrapperTransaction.Status) || !webhookMatches(subscription, wrapperTransaction, assets[wrapperTransaction.Hash]) {
+				continue
+			}
+			payload, _ := json.Marshal(&models.TxStatusNotification{
+				SubscriptionId: subscription.Id,
+				Hash:           wrapperTransaction.Hash,
+				SrcChainId:     wrapperTransaction.SrcChainId,
+				DstChainId:     wrapperTransaction.DstChainId,
+				User:           wrapperTransaction.User,
+				DstUser:        wrapperTransaction.DstUser,
+				State:          wrapperTransaction.Status,
+				StateName:      basedef.GetStateName(int(wrapperTransaction.Status)),
+				Time:           now,
+			})
+			deliveries = append(deliveries, &models.WebhookDelivery{
+				SubscriptionId: subscription.Id,
+				SrcHash:        wrapperTransaction.Hash,
+				State:          wrapperTransaction.Status,
+				Payload:        string(payload),
+				Status:         basedef.DELIVERY_PENDING,
+				NextTime:       now,
+				CreateTime:     now,
+				UpdateTime:     now,
+			})
+		}
+	}
+	return deliveries
+}
+
+func webhookMatches(subscription *models.WebhookSubscription, wrapperTransaction *models.WrapperTransaction, srcTransfer *models.SrcTransfer) bool {
+	if subscription.SrcHash != "" {
+		return strings.EqualFold(subscription.SrcHash, wrapperTransaction.Hash)
+	}
+	if subscription.User != "" {
+		return strings.EqualFold(subscription.User, wrapperTransaction.User) || strings.EqualFold(subscription.User, wrapperTransaction.DstUser)
+	}
+	if subscription.Token != "" {
+		return srcTransfer != nil && srcTransfer.ChainId == subscription.ChainId && strings.EqualFold(subscription.Token, srcTransfer.Asset)
+	}
+	return false
+}
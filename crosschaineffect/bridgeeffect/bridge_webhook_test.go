@@ -0,0 +1,45 @@
+package bridgeeffect
+
+import (
+	"poly-bridge/basedef"
+	"poly-bridge/conf"
+	"poly-bridge/models"
+	"poly-bridge/utils/dbtest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestNotifyStatus(t *testing.T) {
+	globalConfig := conf.GlobalConfig
+	conf.GlobalConfig = &conf.Config{WebhookConfig: &conf.WebhookConfig{BatchSize: 1}}
+	defer func() { conf.GlobalConfig = globalConfig }()
+	eff := &BridgeEffect{db: dbtest.Open(t, &models.WebhookSubscription{}, &models.WebhookDelivery{}, &models.SrcTransfer{})}
+
+	states, _ := models.FormatWebhookStates(nil)
+	subscriptions := []*models.WebhookSubscription{
+		{Url: "1", SrcHash: "a1", States: states},
+		{Url: "2", User: "b1", States: states},
+		{Url: "3", User: "c1", States: states},
+		{Url: "4", ChainId: basedef.ETHEREUM_CROSSCHAIN_ID, Token: "d1", States: states},
+		{Url: "5", ChainId: basedef.BSC_CROSSCHAIN_ID, Token: "d1", States: states},
+		{Url: "6", User: "e1", States: states},
+	}
+	assert.NoError(t, eff.db.Create(subscriptions).Error)
+	assert.NoError(t, eff.db.Create(&models.SrcTransfer{TxHash: "a1", ChainId: basedef.ETHEREUM_CROSSCHAIN_ID, Asset: "D1", Amount: models.NewBigIntFromInt(1)}).Error)
+
+	wrapperTransactions := []*models.WrapperTransaction{
+		{Hash: "a1", User: "B1", DstUser: "c1", Status: basedef.STATE_FINISHED},
+		{Hash: "a2", User: "f1", Status: basedef.STATE_FINISHED},
+	}
+	assert.True(t, eff.notifyStatus(wrapperTransactions))
+	assert.True(t, eff.notifyStatus(wrapperTransactions), "notified again")
+
+	ids := make([]int64, 0)
+	assert.NoError(t, eff.db.Model(&models.WebhookDelivery{}).Order("subscription_id").Pluck("subscription_id", &ids).Error)
+	assert.Equal(t, []int64{subscriptions[0].Id, subscriptions[1].Id, subscriptions[2].Id, subscriptions[3].Id}, ids)
+
+	_, users, tokens := webhookTargets(wrapperTransactions[1:], nil)
+	assert.Equal(t, []string{"f1"}, users, "the empty dst user is not a target")
+	assert.Empty(t, tokens)
+}
@@ -18,14 +18,17 @@
 package main
 
 import (
+	"context"
 	"encoding/json"
 	"fmt"
 	"github.com/beego/beego/v2/core/logs"
 	"github.com/urfave/cli"
 	"os"
 	"os/signal"
+	"poly-bridge/cacheRedis"
 	"poly-bridge/common"
 	"poly-bridge/conf"
+	"poly-bridge/migration"
 	"poly-bridge/crosschaineffect"
 	"runtime"
 	"strings"
@@ -80,6 +83,9 @@ func setupApp() *cli.App {
 	return app
 }
 
+// serverCache is closed by stopServer, a reload opens a new one
+var serverCache cacheRedis.Cache
+
 func StartServer(ctx *cli.Context) {
 	for true {
 		startServer(ctx)
@@ -105,8 +111,16 @@ func startServer(ctx *cli.Context) {
 		conf, _ := json.Marshal(config)
 		logs.Info("%s\n", string(conf))
 	}
+	if err := migration.CheckSchema(config.DBConfig); err != nil {
+		panic(err)
+	}
 	common.SetupChainsSDK(config)
-	crosschaineffect.StartCrossChainEffect(config.Server, config.EventEffectConfig, config.DBConfig, config.RedisConfig)
+	cache, err := cacheRedis.NewCache(config.RedisConfig)
+	if err != nil {
+		panic(err)
+	}
+	serverCache = cache
+	crosschaineffect.StartCrossChainEffect(context.Background(), config.Server, config.EventEffectConfig, config.DBConfig, config.RedisConfig, cache)
 }
 
 func waitSignal() os.Signal {
@@ -128,6 +142,12 @@ func waitSignal() os.Signal {
 
 func stopServer() {
 	crosschaineffect.StopCrossChainEffect()
+	if serverCache != nil {
+		if err := serverCache.Close(); err != nil {
+			logs.Error("close cache err: %v", err)
+		}
+		serverCache = nil
+	}
 }
 
 func main() {
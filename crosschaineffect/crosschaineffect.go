@@ -18,13 +18,16 @@
 package crosschaineffect
 
 import (
+	"context"
 	"github.com/beego/beego/v2/core/logs"
 	"poly-bridge/basedef"
+	"poly-bridge/cacheRedis"
 	"poly-bridge/conf"
 	"poly-bridge/crosschaineffect/bridgeeffect"
 	"poly-bridge/crosschaineffect/explorereffect"
 	"poly-bridge/crosschaineffect/swapeffect"
 	"runtime/debug"
+	"sync"
 	"time"
 )
 
@@ -36,12 +39,12 @@ type Effect interface {
 
 var crossChainEffect *CrossChainEffect
 
-func StartCrossChainEffect(server string, effCfg *conf.EventEffectConfig, dbCfg *conf.DBConfig, redisCfg *conf.RedisConfig) {
-	effect := NewEffect(server, effCfg, dbCfg, redisCfg)
+func StartCrossChainEffect(ctx context.Context, server string, effCfg *conf.EventEffectConfig, dbCfg *conf.DBConfig, redisCfg *conf.RedisConfig, cache cacheRedis.Cache) {
+	effect := NewEffect(server, effCfg, dbCfg, redisCfg, cache)
 	if effect == nil {
 		panic("effect is not valid")
 	}
-	crossChainEffect = NewCrossChainEffect(effect)
+	crossChainEffect = NewCrossChainEffectWithContext(ctx, effect)
 	crossChainEffect.Start()
 }
 
@@ -51,9 +54,9 @@ func StopCrossChainEffect() {
 	}
 }
 
-func NewEffect(server string, effCfg *conf.EventEffectConfig, dbCfg *conf.DBConfig, redisCfg *conf.RedisConfig) Effect {
+func NewEffect(server string, effCfg *conf.EventEffectConfig, dbCfg *conf.DBConfig, redisCfg *conf.RedisConfig, cache cacheRedis.Cache) Effect {
 	if server == basedef.SERVER_POLY_BRIDGE {
-		return bridgeeffect.NewBridgeEffect(effCfg, dbCfg, redisCfg)
+		return bridgeeffect.NewBridgeEffect(effCfg, dbCfg, redisCfg, cache)
 	} else if server == basedef.SERVER_POLY_SWAP {
 		return swapeffect.NewSwapEffect(effCfg, dbCfg)
 	} else if server == basedef.SERVER_EXPLORER {
@@ -65,24 +68,36 @@ func NewEffect(server string, effCfg *conf.EventEffectConfig, dbCfg *conf.DBConf
 
 type CrossChainEffect struct {
 	effect Effect
-	exit   chan bool
+	ctx    context.Context
+	cancel context.CancelFunc
+	wg     sync.WaitGroup
 }
 
 func NewCrossChainEffect(monitor Effect) *CrossChainEffect {
+	return NewCrossChainEffectWithContext(context.Background(), monitor)
+}
+
+func NewCrossChainEffectWithContext(ctx context.Context, monitor Effect) *CrossChainEffect {
 	crossChainMonitor := &CrossChainEffect{
 		effect: monitor,
-		exit:   make(chan bool, 0),
 	}
+	crossChainMonitor.ctx, crossChainMonitor.cancel = context.WithCancel(ctx)
 	return crossChainMonitor
 }
 
 func (eff *CrossChainEffect) Start() {
 	logs.Info("start cross chain effect.")
-	go eff.Check()
+	eff.wg.Add(1)
+	go func() {
+		defer eff.wg.Done()
+		eff.Check()
+	}()
 }
 
+// Stop cancels the effect and waits for the running round to finish
 func (eff *CrossChainEffect) Stop() {
-	eff.exit <- true
+	eff.cancel()
+	eff.wg.Wait()
 	logs.Info("stop cross chain effect.")
 }
 
@@ -90,10 +105,13 @@ func (eff *CrossChainEffect) Check() {
 	for {
 		exit := eff.check()
 		if exit {
-			close(eff.exit)
 			break
 		}
-		time.Sleep(time.Second * 5)
+		select {
+		case <-time.After(time.Second * 5):
+		case <-eff.ctx.Done():
+			return
+		}
 	}
 }
 
@@ -106,6 +124,7 @@ func (eff *CrossChainEffect) check() (exit bool) {
 	}()
 	logs.Debug("cross chain effect, server: %s......", eff.effect.Name())
 	ticker := time.NewTicker(time.Second * time.Duration(eff.effect.GetEffectSlot()))
+	defer ticker.Stop()
 	for {
 		select {
 		case <-ticker.C:
@@ -113,7 +132,7 @@ func (eff *CrossChainEffect) check() (exit bool) {
 			if err != nil {
 				logs.Error("cross chain effect err: %v", err)
 			}
-		case <-eff.exit:
+		case <-eff.ctx.Done():
 			logs.Info("cross chain effect exit, server: %s......", eff.effect.Name())
 			return true
 		}
@@ -1,26 +1,28 @@
 package aptoslisten
 
 import (
+	"context"
 	"fmt"
-	"github.com/beego/beego/v2/core/logs"
+	"math/big"
 	"poly-bridge/basedef"
 	"poly-bridge/chainsdk"
 	"poly-bridge/conf"
-	"poly-bridge/crosschaindao"
 	"poly-bridge/models"
 	"strconv"
 )
 
+const defaultEventPageSize = 25
+
 type AptosChainListen struct {
 	aptosCfg *conf.ChainListenConfig
 	aptosSdk *chainsdk.AptosSdkPro
 }
 
-func NewAptosChainListen(cfg *conf.ChainListenConfig) *AptosChainListen {
+func NewAptosChainListen(ctx context.Context, cfg *conf.ChainListenConfig) *AptosChainListen {
 	aptosListen := &AptosChainListen{}
 	aptosListen.aptosCfg = cfg
 	urls := cfg.GetNodesUrl()
-	sdk := chainsdk.NewAptosSdkPro(urls, cfg.ListenSlot, cfg.ChainId)
+	sdk := chainsdk.NewAptosSdkProWithContext(ctx, urls, cfg.ListenSlot, cfg.ChainId)
 	aptosListen.aptosSdk = sdk
 	return aptosListen
 }
@@ -66,26 +68,11 @@ func (a *AptosChainListen) HandleNewBlock(height uint64) ([]*models.WrapperTrans
 	return nil, nil, nil, nil, nil, nil, 0, 0, nil
 }
 
-func (a *AptosChainListen) HandleEvent(db crosschaindao.CrossChainDao, crossChainSequenceNumber, executeTxSequenceNumber, limit uint64) ([]*models.WrapperTransaction, []*models.SrcTransaction, []*models.PolyTransaction, []*models.DstTransaction, []*models.WrapperDetail, []*models.PolyDetail, error) {
-	var dbChain *models.Chain
-	if db != nil {
-		chain, err := db.GetChain(a.GetChainId())
-		if err != nil {
-			return nil, nil, nil, nil, nil, nil, fmt.Errorf("get chain %d err: %v", a.GetChainId(), err)
-		}
-		crossChainSequenceNumber = chain.CrossChainSequenceNumber
-		executeTxSequenceNumber = chain.ExecuteTxSequenceNumber
-		dbChain = chain
-
-		height, err := a.aptosSdk.GetBlockCount()
-		if err != nil {
-			return nil, nil, nil, nil, nil, nil, fmt.Errorf("get aptos latest err: %v", a.GetChainId(), err)
-		}
-		dbChain.Height = height
-	}
-
+// HandleEvent fetches at most limit cross chain events and execute tx events starting from the given sequence numbers,
+// it returns the transactions and the sequence numbers to start from next time.
+func (a *AptosChainListen) HandleEvent(crossChainSequenceNumber, executeTxSequenceNumber, limit uint64) ([]*models.WrapperTransaction, []*models.SrcTransaction, []*models.PolyTransaction, []*models.DstTransaction, uint64, uint64, error) {
 	if limit == 0 {
-		limit = a.aptosCfg.BatchSize
+		limit = a.GetEventPageSize()
 	}
 
 	wrapperTransactions := make([]*models.WrapperTransaction, 0)
@@ -101,47 +88,40 @@ func (a *AptosChainListen) HandleEvent(db crosschaindao.CrossChainDao, crossChai
 	executeTxEventFilter.Query["start"] = executeTxSequenceNumber
 	crossChainEvents, err := a.aptosSdk.GetEvents(crossChainEventFilter)
 	if err != nil {
-		return nil, nil, nil, nil, nil, nil, fmt.Errorf("aptos get crossChainEvents failed. filter: %+v, err: %v", *crossChainEventFilter, err)
-	}
-	if len(crossChainEvents) != 0 {
-		logs.Info("aptos crossChainEvents=%+v", crossChainEvents)
+		return nil, nil, nil, nil, 0, 0, fmt.Errorf("aptos get crossChainEvents failed. filter: %+v, err: %v", *crossChainEventFilter, err)
 	}
 
 	executeTxEvents, err := a.aptosSdk.GetEvents(executeTxEventFilter)
 	if err != nil {
-		return nil, nil, nil, nil, nil, nil, fmt.Errorf("aptos get executeTxEvents failed. filter: %+v, err: %v", *crossChainEventFilter, err)
-	}
-	if len(executeTxEvents) != 0 {
-		logs.Info("aptos executeTxEvents=%+v", executeTxEvents)
+		return nil, nil, nil, nil, 0, 0, fmt.Errorf("aptos get executeTxEvents failed. filter: %+v, err: %v", *executeTxEventFilter, err)
 	}
 
-	var nextCrossChainSequenceNumber uint64
+	nextCrossChainSequenceNumber := crossChainSequenceNumber
 	for _, event := range crossChainEvents {
 		block, err := a.aptosSdk.GetBlockByVersion(uint64(event.Version))
 		if err != nil {
-			return nil, nil, nil, nil, nil, nil, fmt.Errorf("GetBlockByVersion failed. version:%s, err: %v", event.Version, err)
+			return nil, nil, nil, nil, 0, 0, fmt.Errorf("GetBlockByVersion failed. version:%d, err: %v", uint64(event.Version), err)
 		}
 		tx, err := a.aptosSdk.GetTxByVersion(uint64(event.Version))
 		if err != nil {
-			return nil, nil, nil, nil, nil, nil, fmt.Errorf("GetTxByVersion failed. version:%s, err: %v", event.Version, err)
+			return nil, nil, nil, nil, 0, 0, fmt.Errorf("GetTxByVersion failed. version:%d, err: %v", uint64(event.Version), err)
 		}
 		txTime, err := strconv.ParseUint(tx.Timestamp[:10], 0, 32)
 		if err != nil {
-			return nil, nil, nil, nil, nil, nil, fmt.Errorf("parse tx Timestamp failed. version:%s, err: %v", event.Version, err)
+			return nil, nil, nil, nil, 0, 0, fmt.Errorf("parse tx Timestamp failed. version:%d, err: %v", uint64(event.Version), err)
 		}
 
 		// source transaction
 		srcTx := &models.SrcTransaction{}
 		srcTx.ChainId = a.GetChainId()
-		srcTx.DstChainId, _ = strconv.ParseUint(event.Data["to_chain_id"].(string), 0, 32)
+		srcTx.DstChainId, _ = strconv.ParseUint(event.Data["to_chain_id"].(string), 0, 64)
 
 		srcTx.Hash = tx.Hash[2:]
 		srcTx.State = 1
-		gasUsed, _ := strconv.ParseInt(tx.GasUsed, 0, 32)
-		srcTx.Fee = models.NewBigIntFromInt(gasUsed)
+		srcTx.Fee = parseAmount(tx.GasUsed)
 		srcTx.Time = txTime
 
-		srcTx.Height, _ = strconv.ParseUint(block.BlockHeight, 0, 32)
+		srcTx.Height, _ = strconv.ParseUint(block.BlockHeight, 0, 64)
 		srcTx.User = tx.Sender
 		srcTx.Contract = event.GUID.AccountAddress[2:]
 		srcTx.Key = event.Data["tx_id"].(string)[2:]
@@ -152,13 +132,12 @@ func (a *AptosChainListen) HandleEvent(db crosschaindao.CrossChainDao, crossChai
 			srcTransfer := &models.SrcTransfer{}
 			srcTransfer.Time = txTime
 			srcTransfer.ChainId = a.GetChainId()
-			srcTransfer.DstChainId, _ = strconv.ParseUint(lockEvent.Data["to_chain_id"].(string), 0, 32)
+			srcTransfer.DstChainId, _ = strconv.ParseUint(lockEvent.Data["to_chain_id"].(string), 0, 64)
 			srcTransfer.TxHash = tx.Hash[2:]
 			srcTransfer.From = tx.Sender
 			srcTransfer.To = event.GUID.AccountAddress
 			srcTransfer.Asset = tx.Payload.TypeArguments[0]
-			amount, _ := strconv.ParseInt(tx.Payload.Arguments[0].(string), 0, 32)
-			srcTransfer.Amount = models.NewBigIntFromInt(amount)
+			srcTransfer.Amount = parseAmount(tx.Payload.Arguments[0].(string))
 
 			srcTransfer.DstAsset = lockEvent.Data["to_asset_hash"].(string)
 			if basedef.Has0xPrefix(srcTransfer.DstAsset) {
@@ -183,44 +162,44 @@ func (a *AptosChainListen) HandleEvent(db crosschaindao.CrossChainDao, crossChai
 			wrapperTx.SrcChainId = a.GetChainId()
 			wrapperTx.BlockHeight = srcTx.Height
 			wrapperTx.Time = txTime
-			wrapperTx.DstChainId, _ = strconv.ParseUint(lockWithFeeEvent.Data["to_chain_id"].(string), 0, 32)
+			wrapperTx.DstChainId, _ = strconv.ParseUint(lockWithFeeEvent.Data["to_chain_id"].(string), 0, 64)
 			wrapperTx.DstUser = models.FormatString(lockWithFeeEvent.Data["to_address"].(string))
 			wrapperTx.FeeTokenHash = "0x1::aptos_coin::AptosCoin"
-			feeAmount, _ := strconv.ParseInt(lockWithFeeEvent.Data["fee_amount"].(string), 0, 32)
-			wrapperTx.FeeAmount = models.NewBigIntFromInt(feeAmount)
+			wrapperTx.FeeAmount = parseAmount(lockWithFeeEvent.Data["fee_amount"].(string))
 			wrapperTx.Status = basedef.STATE_SOURCE_DONE
 			wrapperTransactions = append(wrapperTransactions, wrapperTx)
 		}
-		nextCrossChainSequenceNumber = uint64(event.SequenceNumber)
+		if uint64(event.SequenceNumber) >= nextCrossChainSequenceNumber {
+			nextCrossChainSequenceNumber = uint64(event.SequenceNumber) + 1
+		}
 	}
 
-	var nextExecuteTxSequenceNumber uint64
+	nextExecuteTxSequenceNumber := executeTxSequenceNumber
 	for _, event := range executeTxEvents {
 		block, err := a.aptosSdk.GetBlockByVersion(uint64(event.Version))
 		if err != nil {
-			return nil, nil, nil, nil, nil, nil, fmt.Errorf("GetBlockByVersion failed. version:%s, err: %v", event.Version, err)
+			return nil, nil, nil, nil, 0, 0, fmt.Errorf("GetBlockByVersion failed. version:%d, err: %v", uint64(event.Version), err)
 		}
 		tx, err := a.aptosSdk.GetTxByVersion(uint64(event.Version))
 		if err != nil {
-			return nil, nil, nil, nil, nil, nil, fmt.Errorf("GetTxByVersion failed. version:%s, err: %v", event.Version, err)
+			return nil, nil, nil, nil, 0, 0, fmt.Errorf("GetTxByVersion failed. version:%d, err: %v", uint64(event.Version), err)
 		}
 		txTime, err := strconv.ParseUint(tx.Timestamp[:10], 0, 32)
 		if err != nil {
-			return nil, nil, nil, nil, nil, nil, fmt.Errorf("parse tx Timestamp failed. version:%s, err: %v", event.Version, err)
+			return nil, nil, nil, nil, 0, 0, fmt.Errorf("parse tx Timestamp failed. version:%d, err: %v", uint64(event.Version), err)
 		}
 
 		// dst transaction
 		dstTx := &models.DstTransaction{}
-		//dstTx.DstTransfer = dstTransfers
 		dstTx.ChainId = a.GetChainId()
 		dstTx.Hash = tx.Hash[2:]
 		dstTx.State = 1
-		gasUsed, _ := strconv.ParseInt(tx.GasUsed, 0, 32)
-		dstTx.Fee = models.NewBigIntFromInt(gasUsed)
+		dstTx.Fee = parseAmount(tx.GasUsed)
 		dstTx.Time = txTime
-		dstTx.Height, _ = strconv.ParseUint(block.BlockHeight, 0, 32)
-		fmt.Println("aptos dst from_chain_id", event.Data["from_chain_id"])
-		//dstTx.SrcChainId, _ = strconv.ParseUint(event.Data["from_chain_id"].(string), 0, 32)
+		dstTx.Height, _ = strconv.ParseUint(block.BlockHeight, 0, 64)
+		if fromChainId, ok := event.Data["from_chain_id"].(string); ok {
+			dstTx.SrcChainId, _ = strconv.ParseUint(fromChainId, 0, 64)
+		}
 		dstTx.Contract = event.GUID.AccountAddress
 		dstTx.PolyHash = basedef.HexStringReverse(event.Data["cross_chain_tx_hash"].(string)[2:])
 
@@ -233,27 +212,30 @@ func (a *AptosChainListen) HandleEvent(db crosschaindao.CrossChainDao, crossChai
 			dstTransfer.From = event.GUID.AccountAddress
 			dstTransfer.To = models.FormatString(unLockEvent.Data["to_address"].(string))
 			dstTransfer.Asset = tx.Payload.TypeArguments[0]
-			amount, _ := strconv.ParseInt(unLockEvent.Data["amount"].(string), 0, 32)
-			dstTransfer.Amount = models.NewBigIntFromInt(amount)
+			dstTransfer.Amount = parseAmount(unLockEvent.Data["amount"].(string))
 			dstTx.DstTransfer = dstTransfer
 		}
-		nextExecuteTxSequenceNumber = uint64(event.SequenceNumber)
+		if uint64(event.SequenceNumber) >= nextExecuteTxSequenceNumber {
+			nextExecuteTxSequenceNumber = uint64(event.SequenceNumber) + 1
+		}
 		dstTransactions = append(dstTransactions, dstTx)
 	}
 
-	// update chain
-	if dbChain != nil {
-		if len(srcTransactions) > 0 && nextCrossChainSequenceNumber >= dbChain.CrossChainSequenceNumber {
-			dbChain.CrossChainSequenceNumber = nextCrossChainSequenceNumber + 1
-		}
-		if len(executeTxEvents) > 0 && nextExecuteTxSequenceNumber >= dbChain.ExecuteTxSequenceNumber {
-			dbChain.ExecuteTxSequenceNumber = nextExecuteTxSequenceNumber + 1
-		}
-		err = db.UpdateChain(dbChain)
-		if err != nil {
-			logs.Error("Aptos listen update chain err: %v", err)
-		}
+	return wrapperTransactions, srcTransactions, nil, dstTransactions, nextCrossChainSequenceNumber, nextExecuteTxSequenceNumber, nil
+}
+
+// GetEventPageSize returns the number of events fetched per page
+func (a *AptosChainListen) GetEventPageSize() uint64 {
+	if a.aptosCfg.BatchSize > 0 {
+		return a.aptosCfg.BatchSize
 	}
+	return defaultEventPageSize
+}
 
-	return wrapperTransactions, srcTransactions, nil, dstTransactions, nil, nil, nil
+func parseAmount(value string) *models.BigInt {
+	amount, ok := new(big.Int).SetString(value, 0)
+	if !ok {
+		amount = big.NewInt(0)
+	}
+	return models.NewBigInt(amount)
 }
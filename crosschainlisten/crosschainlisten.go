@@ -18,7 +18,7 @@
 package crosschainlisten
 
 import (
-	"encoding/json"
+	"context"
 	"fmt"
 	"math"
 	"poly-bridge/cacheRedis"
@@ -38,6 +38,7 @@ import (
 	"poly-bridge/basedef"
 	"poly-bridge/conf"
 	"poly-bridge/crosschaindao"
+	"poly-bridge/crosschaindao/bridgedao"
 	"poly-bridge/crosschainlisten/ethereumlisten"
 	"poly-bridge/crosschainlisten/neo3listen"
 	"poly-bridge/crosschainlisten/neolisten"
@@ -52,18 +53,26 @@ import (
 
 var chainListens = make([]*CrossChainListen, 0)
 
-func StartCrossChainListen(config *conf.Config) {
+func StartCrossChainListen(ctx context.Context, config *conf.Config, cache cacheRedis.Cache) {
 	dao := crosschaindao.NewCrossChainDao(config.Server, config.Backup, config.DBConfig)
 	if dao == nil {
 		panic("server is not valid")
 	}
+	if config.EventSinkConfig != nil && !config.Backup {
+		if bridgeDao, ok := dao.(*bridgedao.BridgeDao); ok {
+			bridgeDao.EnableEventOutbox()
+		}
+	}
+	chainListens = make([]*CrossChainListen, 0)
 	for _, cfg := range config.ChainListenConfig {
-		chainHandle := NewChainHandle(cfg)
+		chainCtx, cancel := context.WithCancel(ctx)
+		chainHandle := NewChainHandle(chainCtx, cfg)
 		if chainHandle == nil {
+			cancel()
 			logs.Error("chain %d handler is invalid", cfg.ChainId)
 			continue
 		}
-		chainListen := NewCrossChainListen(chainHandle, dao, config)
+		chainListen := newCrossChainListen(chainCtx, cancel, chainHandle, dao, config, cache)
 		chainListen.Start()
 		chainListens = append(chainListens, chainListen)
 	}
@@ -90,62 +99,122 @@ type ChainHandle interface {
 	GetBatchLength() (uint64, uint64)
 }
 
-func NewChainHandle(chainListenConfig *conf.ChainListenConfig) ChainHandle {
-	switch chainListenConfig.ChainId {
-	case basedef.POLY_CROSSCHAIN_ID:
-		return polylisten.NewPolyChainListen(chainListenConfig)
-	case basedef.ETHEREUM_CROSSCHAIN_ID, basedef.BSC_CROSSCHAIN_ID, basedef.PLT_CROSSCHAIN_ID, basedef.OK_CROSSCHAIN_ID,
-		basedef.HECO_CROSSCHAIN_ID, basedef.MATIC_CROSSCHAIN_ID, basedef.ARBITRUM_CROSSCHAIN_ID, basedef.XDAI_CROSSCHAIN_ID,
-		basedef.FANTOM_CROSSCHAIN_ID, basedef.AVAX_CROSSCHAIN_ID, basedef.OPTIMISTIC_CROSSCHAIN_ID, basedef.METIS_CROSSCHAIN_ID,
-		basedef.BOBA_CROSSCHAIN_ID, basedef.RINKEBY_CROSSCHAIN_ID, basedef.OASIS_CROSSCHAIN_ID, basedef.HARMONY_CROSSCHAIN_ID,
-		basedef.KCC_CROSSCHAIN_ID, basedef.BYTOM_CROSSCHAIN_ID, basedef.HSC_CROSSCHAIN_ID, basedef.KAVA_CROSSCHAIN_ID,
-		basedef.CUBE_CROSSCHAIN_ID, basedef.ZKSYNC_CROSSCHAIN_ID, basedef.CELO_CROSSCHAIN_ID, basedef.CLOVER_CROSSCHAIN_ID,
-		basedef.CONFLUX_CROSSCHAIN_ID, basedef.ASTAR_CROSSCHAIN_ID, basedef.BRISE_CROSSCHAIN_ID, basedef.DEXIT_CROSSCHAIN_ID,
-		basedef.CLOUDTX_CROSSCHAIN_ID:
-		return ethereumlisten.NewEthereumChainListen(chainListenConfig)
-	case basedef.NEO_CROSSCHAIN_ID:
-		return neolisten.NewNeoChainListen(chainListenConfig)
-	case basedef.ONT_CROSSCHAIN_ID:
-		return ontologylisten.NewOntologyChainListen(chainListenConfig)
-	case basedef.O3_CROSSCHAIN_ID:
-		return o3listen.NewO3ChainListen(chainListenConfig)
-	case basedef.SWITCHEO_CROSSCHAIN_ID:
-		return switcheolisten.NewSwitcheoChainListen(chainListenConfig)
-	case basedef.NEO3_CROSSCHAIN_ID:
-		return neo3listen.NewNeo3ChainListen(chainListenConfig)
-	case basedef.ZILLIQA_CROSSCHAIN_ID:
-		return zilliqalisten.NewZilliqaChainListen(chainListenConfig)
-	case basedef.STARCOIN_CROSSCHAIN_ID:
-		return starcoinlisten.NewStarcoinChainListen(chainListenConfig)
-	case basedef.RIPPLE_CROSSCHAIN_ID:
-		return ripplelisten.NewRippleChainListen(chainListenConfig)
-	case basedef.APTOS_CROSSCHAIN_ID:
-		return aptoslisten.NewAptosChainListen(chainListenConfig)
-
-	default:
+// EventCursorHandle is implemented by the chain handles which fetch cross chain events by sequence number
+// instead of by height, the sequence numbers are kept in Chain.CrossChainSequenceNumber and Chain.ExecuteTxSequenceNumber.
+type EventCursorHandle interface {
+	HandleEvent(crossChainSequenceNumber, executeTxSequenceNumber, limit uint64) ([]*models.WrapperTransaction, []*models.SrcTransaction, []*models.PolyTransaction, []*models.DstTransaction, uint64, uint64, error)
+	GetEventPageSize() uint64
+}
+
+const maxEventPagesPerRound = 10
+
+// ChainHandleFactory creates the chain handle of a chain family, the sdk pools of the handle are released when ctx is done
+type ChainHandleFactory func(ctx context.Context, chainListenConfig *conf.ChainListenConfig) ChainHandle
+
+var chainHandleFactories = map[string]ChainHandleFactory{
+	basedef.CHAIN_FAMILY_POLY: func(ctx context.Context, cfg *conf.ChainListenConfig) ChainHandle {
+		return polylisten.NewPolyChainListen(ctx, cfg)
+	},
+	basedef.CHAIN_FAMILY_EVM: func(ctx context.Context, cfg *conf.ChainListenConfig) ChainHandle {
+		return ethereumlisten.NewEthereumChainListen(ctx, cfg)
+	},
+	basedef.CHAIN_FAMILY_NEO: func(ctx context.Context, cfg *conf.ChainListenConfig) ChainHandle {
+		return neolisten.NewNeoChainListen(ctx, cfg)
+	},
+	basedef.CHAIN_FAMILY_ONTOLOGY: func(ctx context.Context, cfg *conf.ChainListenConfig) ChainHandle {
+		return ontologylisten.NewOntologyChainListen(ctx, cfg)
+	},
+	basedef.CHAIN_FAMILY_O3: func(ctx context.Context, cfg *conf.ChainListenConfig) ChainHandle {
+		return o3listen.NewO3ChainListen(ctx, cfg)
+	},
+	basedef.CHAIN_FAMILY_SWITCHEO: func(ctx context.Context, cfg *conf.ChainListenConfig) ChainHandle {
+		return switcheolisten.NewSwitcheoChainListen(ctx, cfg)
+	},
+	basedef.CHAIN_FAMILY_NEO3: func(ctx context.Context, cfg *conf.ChainListenConfig) ChainHandle {
+		return neo3listen.NewNeo3ChainListen(ctx, cfg)
+	},
+	basedef.CHAIN_FAMILY_ZILLIQA: func(ctx context.Context, cfg *conf.ChainListenConfig) ChainHandle {
+		return zilliqalisten.NewZilliqaChainListen(ctx, cfg)
+	},
+	basedef.CHAIN_FAMILY_STARCOIN: func(ctx context.Context, cfg *conf.ChainListenConfig) ChainHandle {
+		return starcoinlisten.NewStarcoinChainListen(ctx, cfg)
+	},
+	basedef.CHAIN_FAMILY_RIPPLE: func(ctx context.Context, cfg *conf.ChainListenConfig) ChainHandle {
+		return ripplelisten.NewRippleChainListen(ctx, cfg)
+	},
+	basedef.CHAIN_FAMILY_APTOS: func(ctx context.Context, cfg *conf.ChainListenConfig) ChainHandle {
+		return aptoslisten.NewAptosChainListen(ctx, cfg)
+	},
+}
+
+// RegisterChainHandle registers the chain handle factory of a chain family
+func RegisterChainHandle(family string, factory ChainHandleFactory) {
+	chainHandleFactories[family] = factory
+}
+
+func NewChainHandle(ctx context.Context, chainListenConfig *conf.ChainListenConfig) ChainHandle {
+	factory, ok := chainHandleFactories[chainListenConfig.GetChainFamily()]
+	if !ok {
 		return nil
 	}
+	return factory(ctx, chainListenConfig)
 }
 
 type CrossChainListen struct {
-	handle  ChainHandle
-	db      crosschaindao.CrossChainDao
-	exit    chan bool
-	height  uint64
-	config  *conf.Config
-	dingMux sync.Mutex
+	handle   ChainHandle
+	db       crosschaindao.CrossChainDao
+	ctx      context.Context
+	cancel   context.CancelFunc
+	wg       sync.WaitGroup
+	height   uint64
+	config   *conf.Config
+	dingMux  sync.Mutex
+	reorg    *reorgTracker
+	progress *rangeProgress
+	cache    cacheRedis.Cache
+	replay   bool
 }
 
-func NewCrossChainListen(handle ChainHandle, db crosschaindao.CrossChainDao, config *conf.Config) *CrossChainListen {
+func NewCrossChainListen(handle ChainHandle, db crosschaindao.CrossChainDao, config *conf.Config, cache cacheRedis.Cache) *CrossChainListen {
+	ctx, cancel := context.WithCancel(context.Background())
+	return newCrossChainListen(ctx, cancel, handle, db, config, cache)
+}
+
+func newCrossChainListen(ctx context.Context, cancel context.CancelFunc, handle ChainHandle, db crosschaindao.CrossChainDao, config *conf.Config, cache cacheRedis.Cache) *CrossChainListen {
 	crossChainListen := &CrossChainListen{
-		handle: handle,
-		db:     db,
-		exit:   make(chan bool, 0),
-		config: config,
+		handle:   handle,
+		db:       db,
+		ctx:      ctx,
+		cancel:   cancel,
+		config:   config,
+		progress: newRangeProgress(),
+		cache:    cache,
+	}
+	if _, ok := handle.(BlockHashHandle); ok {
+		var depth uint64
+		if cfg := config.GetChainListenConfig(handle.GetChainId()); cfg != nil {
+			depth = cfg.ReorgDepth
+		}
+		crossChainListen.reorg = newReorgTracker(depth)
 	}
 	return crossChainListen
 }
 
+// NewReplayListen handles the historical ranges passed to ReplayRange with the handlers of the listener, it does
+// not track the block hashes, alert on the large transactions or touch chain.Height.
+func NewReplayListen(ctx context.Context, handle ChainHandle, db crosschaindao.CrossChainDao, config *conf.Config) *CrossChainListen {
+	ctx, cancel := context.WithCancel(ctx)
+	return &CrossChainListen{
+		handle:   handle,
+		db:       db,
+		ctx:      ctx,
+		cancel:   cancel,
+		config:   config,
+		progress: newRangeProgress(),
+		replay:   true,
+	}
+}
+
 func (ccl *CrossChainListen) SetHeight(height uint64) {
 	ccl.height = height
 }
@@ -155,11 +224,17 @@ func (ccl *CrossChainListen) Start() {
 		return
 	}
 	logs.Info("start cross chain listen: %s", ccl.handle.GetChainName())
-	go ccl.ListenChain()
+	ccl.wg.Add(1)
+	go func() {
+		defer ccl.wg.Done()
+		ccl.ListenChain()
+	}()
 }
 
+// Stop cancels the listen context and waits for the in-flight round to finish committing
 func (ccl *CrossChainListen) Stop() {
-	ccl.exit <- true
+	ccl.cancel()
+	ccl.wg.Wait()
 	logs.Info("stop cross chain listen: %s", ccl.handle.GetChainName())
 }
 
@@ -167,10 +242,13 @@ func (ccl *CrossChainListen) ListenChain() {
 	for {
 		exit := ccl.listenChain()
 		if exit {
-			close(ccl.exit)
 			break
 		}
-		time.Sleep(time.Second * 5)
+		select {
+		case <-time.After(time.Second * 5):
+		case <-ccl.ctx.Done():
+			return
+		}
 	}
 }
 
@@ -223,6 +301,7 @@ func (ccl *CrossChainListen) listenChain() (exit bool) {
 	}
 	logs.Info("cross chain listen, chain: %s, dao: %s......", ccl.handle.GetChainName(), ccl.db.Name())
 	ticker := time.NewTicker(time.Second * time.Duration(ccl.handle.GetChainListenSlot()))
+	defer ticker.Stop()
 	for {
 		select {
 		case <-ticker.C:
@@ -256,8 +335,12 @@ func (ccl *CrossChainListen) listenChain() (exit bool) {
 				}
 				logs.Info("ListenChain - chain %s latest height is %d, listen height: %d", ccl.handle.GetChainName(), height, chain.Height)
 			}
-			if basedef.IsETHChain(ccl.handle.GetChainId()) && ccl.handle.GetChainId() != basedef.O3_CROSSCHAIN_ID {
-				for chain.Height < height-ccl.handle.GetDefer() {
+			if basedef.GetChainFamily(ccl.handle.GetChainId()) == basedef.CHAIN_FAMILY_EVM {
+				for chain.Height < height-ccl.handle.GetDefer() && ccl.ctx.Err() == nil {
+					if err := ccl.checkReorg(chain); err != nil {
+						logs.Error("checkReorg chain：%s, height: %d err: %v", ccl.handle.GetChainName(), chain.Height, err)
+						break
+					}
 					batchSize := ccl.handle.GetBatchSize() //concurrency size
 					if batchSize == 0 {
 						batchSize = 1
@@ -281,7 +364,11 @@ func (ccl *CrossChainListen) listenChain() (exit bool) {
 						batchSize = (height-chain.Height-ccl.handle.GetDefer()-1)/batchLength + 1
 					}
 
-					ch := make(chan bool, batchSize)
+					endheight := chain.Height + batchSize*batchLength
+					if endheight > height-ccl.handle.GetDefer() {
+						endheight = height - ccl.handle.GetDefer()
+					}
+					ranges := make([]*heightRange, 0, batchSize)
 					for i := uint64(1); i <= batchSize; i++ {
 						start := chain.Height + (i-1)*batchLength + 1
 						end := chain.Height + i*batchLength
@@ -291,87 +378,25 @@ func (ccl *CrossChainListen) listenChain() (exit bool) {
 						if end < start {
 							continue
 						}
-						go func(start uint64, end uint64) {
-							wrapperTransactions, srcTransactions, polyTransactions, dstTransactions, err := ccl.HandleNewBatchBlock(start, end)
-							if err != nil {
-								logs.Error("HandleNewBlock chain：%s, height: %d err: %v", ccl.handle.GetChainName(), height, err)
-								ch <- false
-								return
-							}
-							logs.Info("HandleNewBlock [chainName: %s, height: %d, start: %d, end: %d ]. "+
-								"len(wrapperTransactions)=%d, len(srcTransactions)=%d, len(polyTransactions)=%d, len(dstTransactions)=%d",
-								chain.Name, height, start, end, len(wrapperTransactions), len(srcTransactions), len(polyTransactions), len(dstTransactions))
-							err = ccl.db.WrapperTransactionCheckFee(wrapperTransactions, srcTransactions)
-							if err != nil {
-								logs.Error("check fee on block %d err: %v", height, err)
-								ch <- false
-							}
-							err = ccl.db.UpdateEvents(wrapperTransactions, srcTransactions, polyTransactions, dstTransactions, nil, nil)
-							if err != nil {
-								logs.Error("UpdateEvents on block %d err: %v", height, err)
-								ch <- false
-							} else {
-								if !ccl.config.Backup {
-									go ccl.checkLargeTransaction(srcTransactions)
-								}
-								ch <- true
-							}
-
-						}(start, end)
-					}
-					allTaskSuccess := true
-					for j := 0; j < int(batchSize); j++ {
-						ok := <-ch
-						if !ok {
-							allTaskSuccess = false
-						}
+						ranges = append(ranges, &heightRange{start: start, end: end})
 					}
-					close(ch)
-					if !allTaskSuccess {
+					ccl.handleRanges(ranges, func(start, end uint64) bool {
+						return ccl.handleBatchRange(chain, height, start, end)
+					})
+					if !ccl.advanceHeight(chain, endheight) {
 						break
 					}
-
-					flagChainHeight := chain.Height
-					endheight := chain.Height + batchSize*batchLength
-					if endheight > height-ccl.handle.GetDefer() {
-						endheight = height - ccl.handle.GetDefer()
-					}
-					chain.Height = endheight
-					if err := ccl.db.UpdateChain(chain); err != nil {
-						logs.Error("UpdateChain [chainId:%d, height:%d] err %v", chain.ChainId, chain.Height, err)
-						chain.Height = flagChainHeight
-					}
 				}
 			} else {
-				switch ccl.handle.GetChainId() {
-				case basedef.APTOS_CROSSCHAIN_ID:
-					if ccl.handle.GetChainId() == basedef.APTOS_CROSSCHAIN_ID {
-						h := ccl.handle
-						if aptos, ok := h.(*aptoslisten.AptosChainListen); ok {
-							wrapperTransactions, srcTransactions, polyTransactions, dstTransactions, _, _, err := aptos.HandleEvent(ccl.db, 0, 0, 0)
-							if err != nil {
-								logs.Error("aptos HandleNewBlock chain：%s, err: %v", ccl.handle.GetChainName(), err)
-								return
-							}
-
-							logs.Info("aptos log")
-							marshal, _ := json.Marshal(wrapperTransactions)
-							logs.Info("wrapperTransactions=%s", marshal)
-
-							marshal, _ = json.Marshal(srcTransactions)
-							logs.Info("srcTransactions=%s", marshal)
-
-							marshal, _ = json.Marshal(dstTransactions)
-							logs.Info("dstTransactions=%s", marshal)
-
-							err = ccl.db.UpdateEvents(wrapperTransactions, srcTransactions, polyTransactions, dstTransactions, nil, nil)
-							if err != nil {
-								logs.Error("aptos updateEvents on block %d err: %v", height, err)
-							}
-						}
-					}
+				switch handle := ccl.handle.(type) {
+				case EventCursorHandle:
+					ccl.handleNewEvents(chain, height, handle)
 				default:
-					for chain.Height < height-ccl.handle.GetDefer() {
+					for chain.Height < height-ccl.handle.GetDefer() && ccl.ctx.Err() == nil {
+						if err := ccl.checkReorg(chain); err != nil {
+							logs.Error("checkReorg chain：%s, height: %d err: %v", ccl.handle.GetChainName(), chain.Height, err)
+							break
+						}
 						batchSize := ccl.handle.GetBatchSize()
 						if batchSize == 0 {
 							batchSize = 1
@@ -379,77 +404,83 @@ func (ccl *CrossChainListen) listenChain() (exit bool) {
 						if batchSize > height-chain.Height-ccl.handle.GetDefer() {
 							batchSize = height - chain.Height - ccl.handle.GetDefer()
 						}
-
-						ch := make(chan bool, batchSize)
+						ranges := make([]*heightRange, 0, batchSize)
 						for i := uint64(1); i <= batchSize; i++ {
-							go func(height uint64) {
-								wrapperTransactions, srcTransactions, polyTransactions, dstTransactions, wrapperDetails, polyDetails, err := ccl.HandleNewBlock(height)
-								if err != nil {
-									logs.Error("HandleNewBlock chain：%s, height: %d err: %v", ccl.handle.GetChainName(), height, err)
-									ch <- false
-									return
-								}
-								logs.Info("HandleNewBlock [chainName: %s, height: %d]. "+
-									"len(wrapperTransactions)=%d, len(srcTransactions)=%d, len(polyTransactions)=%d, len(dstTransactions)=%d, len(wrapperDetails)=%d, len(polyDetails)=%d",
-									chain.Name, height, len(wrapperTransactions), len(srcTransactions), len(polyTransactions), len(dstTransactions), len(wrapperDetails), len(polyDetails))
-								detailWrapperTxs, err := ccl.db.FillTxSpecialChain(wrapperTransactions, srcTransactions, polyTransactions, dstTransactions, wrapperDetails, polyDetails)
-								if err != nil {
-									logs.Error("FillTxSpecialChain on block %d err: %v", height, err)
-									ch <- false
-								}
-								wrapperTransactions = append(wrapperTransactions, detailWrapperTxs...)
-
-								err = ccl.db.WrapperTransactionCheckFee(wrapperTransactions, srcTransactions)
-								if err != nil {
-									logs.Error("check fee on block %d err: %v", height, err)
-									ch <- false
-								}
-								err = ccl.db.UpdateEvents(wrapperTransactions, srcTransactions, polyTransactions, dstTransactions, wrapperDetails, polyDetails)
-								if err != nil {
-									logs.Error("UpdateEvents on block %d err: %v", height, err)
-									ch <- false
-								} else {
-									if !ccl.config.Backup {
-										go ccl.checkLargeTransaction(srcTransactions)
-									}
-									ch <- true
-								}
-
-							}(chain.Height + i)
-						}
-						allTaskSuccess := true
-						for j := 0; j < int(batchSize); j++ {
-							ok := <-ch
-							if !ok {
-								allTaskSuccess = false
-							}
+							ranges = append(ranges, &heightRange{start: chain.Height + i, end: chain.Height + i})
 						}
-						close(ch)
-						if !allTaskSuccess {
+						ccl.handleRanges(ranges, func(start, end uint64) bool {
+							return ccl.handleBlock(chain, height, start)
+						})
+						if !ccl.advanceHeight(chain, chain.Height+batchSize) {
 							break
 						}
-
-						chain.Height += batchSize
-						if err := ccl.db.UpdateChain(chain); err != nil {
-							logs.Error("UpdateChain [chainId:%d, height:%d] err %v", chain.ChainId, chain.Height, err)
-							chain.Height -= batchSize
-						}
 					}
 				}
 			}
-		case <-ccl.exit:
+		case <-ccl.ctx.Done():
 			logs.Info("cross chain listen exit, chain: %s, dao: %s......", ccl.handle.GetChainName(), ccl.db.Name())
 			return true
 		}
 	}
 }
 
+func (ccl *CrossChainListen) handleNewEvents(chain *models.Chain, height uint64, handle EventCursorHandle) {
+	for i := 0; i < maxEventPagesPerRound && ccl.ctx.Err() == nil; i++ {
+		limit := handle.GetEventPageSize()
+		wrapperTransactions, srcTransactions, polyTransactions, dstTransactions, crossChainSequenceNumber, executeTxSequenceNumber, err :=
+			handle.HandleEvent(chain.CrossChainSequenceNumber, chain.ExecuteTxSequenceNumber, limit)
+		if err != nil {
+			logs.Error("HandleEvent chain：%s, sequence: %d/%d err: %v", ccl.handle.GetChainName(), chain.CrossChainSequenceNumber, chain.ExecuteTxSequenceNumber, err)
+			return
+		}
+		logs.Info("HandleEvent [chainName: %s, sequence: %d/%d, next sequence: %d/%d]. "+
+			"len(wrapperTransactions)=%d, len(srcTransactions)=%d, len(polyTransactions)=%d, len(dstTransactions)=%d",
+			chain.Name, chain.CrossChainSequenceNumber, chain.ExecuteTxSequenceNumber, crossChainSequenceNumber, executeTxSequenceNumber,
+			len(wrapperTransactions), len(srcTransactions), len(polyTransactions), len(dstTransactions))
+		err = ccl.db.WrapperTransactionCheckFee(wrapperTransactions, srcTransactions)
+		if err != nil {
+			logs.Error("check fee on chain %s sequence %d err: %v", ccl.handle.GetChainName(), chain.CrossChainSequenceNumber, err)
+			return
+		}
+		err = ccl.db.UpdateEvents(wrapperTransactions, srcTransactions, polyTransactions, dstTransactions, nil, nil)
+		if err != nil {
+			logs.Error("UpdateEvents on chain %s sequence %d/%d err: %v", ccl.handle.GetChainName(), chain.CrossChainSequenceNumber, chain.ExecuteTxSequenceNumber, err)
+			return
+		}
+		if !ccl.config.Backup {
+			ccl.goCheckLargeTransaction(srcTransactions)
+		}
+
+		flagCrossChainSequenceNumber, flagExecuteTxSequenceNumber, flagChainHeight := chain.CrossChainSequenceNumber, chain.ExecuteTxSequenceNumber, chain.Height
+		chain.CrossChainSequenceNumber = crossChainSequenceNumber
+		chain.ExecuteTxSequenceNumber = executeTxSequenceNumber
+		chain.Height = height
+		if err := ccl.db.UpdateChain(chain); err != nil {
+			logs.Error("UpdateChain [chainId:%d, sequence:%d/%d] err %v", chain.ChainId, chain.CrossChainSequenceNumber, chain.ExecuteTxSequenceNumber, err)
+			chain.CrossChainSequenceNumber, chain.ExecuteTxSequenceNumber, chain.Height = flagCrossChainSequenceNumber, flagExecuteTxSequenceNumber, flagChainHeight
+			return
+		}
+		if uint64(len(srcTransactions)) < limit && uint64(len(dstTransactions)) < limit {
+			return
+		}
+	}
+}
+
+func (ccl *CrossChainListen) goCheckLargeTransaction(srcTransactions []*models.SrcTransaction) {
+	ccl.wg.Add(1)
+	go func() {
+		defer ccl.wg.Done()
+		ccl.checkLargeTransaction(srcTransactions)
+	}()
+}
+
 func (ccl *CrossChainListen) checkLargeTransaction(srcTransactions []*models.SrcTransaction) {
 	ccl.dingMux.Lock()
 	defer ccl.dingMux.Unlock()
+	cache := ccl.cache
 	if srcTransactions != nil && len(srcTransactions) > 0 {
 		for _, v := range srcTransactions {
-			if existed, err := cacheRedis.Redis.Exists(cacheRedis.LargeTxAlarmPrefix + strings.ToLower(v.Hash)); err == nil && existed {
+			if existed, err := cache.Exists(cacheRedis.LargeTxAlarmPrefix + strings.ToLower(v.Hash)); err == nil && existed {
 				logs.Info("large TX hash: %s alarm has been sent.", v.Hash)
 				return
 			}
@@ -469,13 +500,13 @@ func (ccl *CrossChainListen) checkLargeTransaction(srcTransactions []*models.Src
 
 					if amount.Cmp(decimal.NewFromInt(ccl.config.LargeTxAmount)) >= 0 {
 						//cacheRedis.Redis.Unlink(cacheRedis.LargeTxList)
-						if err := cacheRedis.Redis.RPush(cacheRedis.LargeTxList, v.Hash); err != nil {
+						if err := cache.RPush(cacheRedis.LargeTxList, v.Hash); err != nil {
 							logs.Error("Save LargeTx[hash: %s] err: %s", v.Hash, err)
 						}
 						if err := ccl.sendLargeTransactionDingAlarm(v, token, ccl.config.LargeTxAmount, amount); err != nil {
 							logs.Error("send LargeTxAmount alarm err:", err)
 						} else {
-							if _, err := cacheRedis.Redis.Set(cacheRedis.LargeTxAlarmPrefix+strings.ToLower(v.Hash), "done", time.Hour); err != nil {
+							if _, err := cache.Set(cacheRedis.LargeTxAlarmPrefix+strings.ToLower(v.Hash), "done", time.Hour); err != nil {
 								logs.Error("mark large TX hash: %s alarm done err: %s", v.Hash, err)
 							}
 						}
@@ -12,23 +12,27 @@ import (
 
 var handlerMap = make(map[uint64]ChainHandle, 0)
 
-func StartCrossChainListenPatch(config *conf.Config) {
+func StartCrossChainListenPatch(ctx context.Context, config *conf.Config) {
 	dao := bridgedao.NewBridgeDao(config.DBConfig, config.Backup)
 	if dao == nil {
 		panic("NewBridgeDao err")
 	}
+	handlerMap = make(map[uint64]ChainHandle, 0)
 	for _, cfg := range config.ChainListenConfig {
-		handlerMap[cfg.ChainId] = NewChainHandle(cfg)
+		handlerMap[cfg.ChainId] = NewChainHandle(ctx, cfg)
 	}
-	go startPatchWrapperMissingTx(dao)
+	go startPatchWrapperMissingTx(ctx, dao)
 }
 
-func startPatchWrapperMissingTx(dao *bridgedao.BridgeDao) {
+func startPatchWrapperMissingTx(ctx context.Context, dao *bridgedao.BridgeDao) {
 	ticker := time.NewTicker(time.Minute * 10)
+	defer ticker.Stop()
 	for {
 		select {
 		case <-ticker.C:
 			go patchWrapperMissingTx(dao)
+		case <-ctx.Done():
+			return
 		}
 	}
 }
@@ -0,0 +1,266 @@
+/*
+ * Copyright (C) 2020 The poly network Authors
+ * This file is part of The poly network library.
+ *
+ * The  poly network  is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU Lesser General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * The  poly network  is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU Lesser General Public License for more details.
+ * You should have received a copy of the GNU Lesser General Public License
+ * along with The poly network .  If not, see <http://www.gnu.org/licenses/>.
+ */
+
+package crosschainlisten
+
+import (
+	"sync"
+	"time"
+
+	"poly-bridge/basedef"
+	"poly-bridge/models"
+	"poly-bridge/utils/backoff"
+
+	"github.com/beego/beego/v2/core/logs"
+)
+
+const (
+	maxRangeAttempts = 3
+	rangeBackoff     = time.Second
+	maxRangeBackoff  = time.Second * 30
+)
+
+type heightRange struct {
+	start uint64
+	end   uint64
+}
+
+// rangeProgress keeps the ranges committed above chain.Height, so chain.Height can advance to the highest
+// contiguous committed height and only the failed ranges are fetched again.
+type rangeProgress struct {
+	mutex     sync.Mutex
+	completed map[uint64]uint64 // start -> end of committed ranges
+	failures  map[uint64]int    // start -> consecutive failures of the range
+}
+
+func newRangeProgress() *rangeProgress {
+	return &rangeProgress{
+		completed: make(map[uint64]uint64),
+		failures:  make(map[uint64]int),
+	}
+}
+
+func (p *rangeProgress) done(start, end uint64) bool {
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+	e, ok := p.completed[start]
+	return ok && e == end
+}
+
+func (p *rangeProgress) complete(start, end uint64) {
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+	p.completed[start] = end
+	delete(p.failures, start)
+}
+
+// fail records a failure of the range and returns the backoff before it is fetched again
+func (p *rangeProgress) fail(start uint64) time.Duration {
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+	p.failures[start]++
+	return backoff.Delay(p.failures[start], rangeBackoff, maxRangeBackoff)
+}
+
+// watermark returns the highest height reachable from height through committed ranges,
+// the ranges below it are dropped.
+func (p *rangeProgress) watermark(height uint64) uint64 {
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+	for {
+		end, ok := p.completed[height+1]
+		if !ok {
+			break
+		}
+		height = end
+	}
+	for start := range p.completed {
+		if start <= height {
+			delete(p.completed, start)
+		}
+	}
+	for start := range p.failures {
+		if start <= height {
+			delete(p.failures, start)
+		}
+	}
+	return height
+}
+
+func (p *rangeProgress) reset() {
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+	p.completed = make(map[uint64]uint64)
+	p.failures = make(map[uint64]int)
+}
+
+// handleRanges runs the ranges not committed yet concurrently, a failed range is retried with backoff
+// while the others go on.
+func (ccl *CrossChainListen) handleRanges(ranges []*heightRange, handle func(start, end uint64) bool) {
+	var wg sync.WaitGroup
+	for _, r := range ranges {
+		if ccl.progress.done(r.start, r.end) {
+			continue
+		}
+		wg.Add(1)
+		go func(start, end uint64) {
+			defer wg.Done()
+			for attempt := 1; ; attempt++ {
+				if handle(start, end) {
+					ccl.progress.complete(start, end)
+					return
+				}
+				delay := ccl.progress.fail(start)
+				if attempt >= maxRangeAttempts {
+					return
+				}
+				select {
+				case <-time.After(delay):
+				case <-ccl.ctx.Done():
+					return
+				}
+			}
+		}(r.start, r.end)
+	}
+	wg.Wait()
+}
+
+// advanceHeight moves chain.Height to the watermark of the committed ranges, it returns false if the
+// round did not reach end.
+func (ccl *CrossChainListen) advanceHeight(chain *models.Chain, end uint64) bool {
+	watermark := ccl.progress.watermark(chain.Height)
+	if watermark == chain.Height {
+		return false
+	}
+	if watermark != end {
+		logs.Warn("chain %s round to %d stopped at %d, the failed ranges are fetched again", ccl.handle.GetChainName(), end, watermark)
+	}
+	committed, err := ccl.commitBlockHashes(chain, watermark)
+	if err != nil {
+		logs.Error("commitBlockHashes chain：%s, height: %d err: %v", ccl.handle.GetChainName(), watermark, err)
+		return false
+	}
+	if !committed {
+		return false
+	}
+	flagChainHeight := chain.Height
+	chain.Height = watermark
+	if err := ccl.db.UpdateChain(chain); err != nil {
+		logs.Error("UpdateChain [chainId:%d, height:%d] err %v", chain.ChainId, chain.Height, err)
+		chain.Height = flagChainHeight
+		return false
+	}
+	return watermark == end
+}
+
+// ReplayRange handles (height, end] in ranges of batchLength heights concurrently, the evm chains by batch and
+// the others block by block. The committed ranges above height are kept, so a replay of the same heights only
+// fetches the failed ranges again. It returns the height committed contiguously from height.
+func (ccl *CrossChainListen) ReplayRange(height, end, batchLength uint64) uint64 {
+	if batchLength == 0 {
+		batchLength = 1
+	}
+	chain := &models.Chain{ChainId: ccl.handle.GetChainId(), Name: ccl.handle.GetChainName()}
+	ranges := make([]*heightRange, 0, (end-height+batchLength-1)/batchLength)
+	for start := height + 1; start <= end; start += batchLength {
+		rangeEnd := start + batchLength - 1
+		if rangeEnd > end {
+			rangeEnd = end
+		}
+		ranges = append(ranges, &heightRange{start: start, end: rangeEnd})
+	}
+	if basedef.GetChainFamily(chain.ChainId) == basedef.CHAIN_FAMILY_EVM {
+		ccl.handleRanges(ranges, func(start, end uint64) bool {
+			return ccl.handleBatchRange(chain, end, start, end)
+		})
+	} else {
+		ccl.handleRanges(ranges, func(start, end uint64) bool {
+			for h := start; h <= end; h++ {
+				if !ccl.handleBlock(chain, end, h) {
+					return false
+				}
+			}
+			return true
+		})
+	}
+	return ccl.progress.watermark(height)
+}
+
+func (ccl *CrossChainListen) handleBatchRange(chain *models.Chain, height, start, end uint64) bool {
+	if err := ccl.fetchBlockHashes(start, end, height-ccl.handle.GetDefer()); err != nil {
+		logs.Error("fetchBlockHashes chain：%s, start: %d, end: %d err: %v", ccl.handle.GetChainName(), start, end, err)
+		return false
+	}
+	wrapperTransactions, srcTransactions, polyTransactions, dstTransactions, err := ccl.HandleNewBatchBlock(start, end)
+	if err != nil {
+		logs.Error("HandleNewBlock chain：%s, height: %d, start: %d, end: %d err: %v", ccl.handle.GetChainName(), height, start, end, err)
+		return false
+	}
+	logs.Info("HandleNewBlock [chainName: %s, height: %d, start: %d, end: %d ]. "+
+		"len(wrapperTransactions)=%d, len(srcTransactions)=%d, len(polyTransactions)=%d, len(dstTransactions)=%d",
+		chain.Name, height, start, end, len(wrapperTransactions), len(srcTransactions), len(polyTransactions), len(dstTransactions))
+	err = ccl.db.WrapperTransactionCheckFee(wrapperTransactions, srcTransactions)
+	if err != nil {
+		logs.Error("check fee on block %d-%d err: %v", start, end, err)
+		return false
+	}
+	err = ccl.db.UpdateEvents(wrapperTransactions, srcTransactions, polyTransactions, dstTransactions, nil, nil)
+	if err != nil {
+		logs.Error("UpdateEvents on block %d-%d err: %v", start, end, err)
+		return false
+	}
+	if !ccl.config.Backup && !ccl.replay {
+		ccl.goCheckLargeTransaction(srcTransactions)
+	}
+	return true
+}
+
+func (ccl *CrossChainListen) handleBlock(chain *models.Chain, latest, height uint64) bool {
+	if err := ccl.fetchBlockHashes(height, height, latest-ccl.handle.GetDefer()); err != nil {
+		logs.Error("fetchBlockHashes chain：%s, height: %d err: %v", ccl.handle.GetChainName(), height, err)
+		return false
+	}
+	wrapperTransactions, srcTransactions, polyTransactions, dstTransactions, wrapperDetails, polyDetails, err := ccl.HandleNewBlock(height)
+	if err != nil {
+		logs.Error("HandleNewBlock chain：%s, height: %d err: %v", ccl.handle.GetChainName(), height, err)
+		return false
+	}
+	logs.Info("HandleNewBlock [chainName: %s, height: %d]. "+
+		"len(wrapperTransactions)=%d, len(srcTransactions)=%d, len(polyTransactions)=%d, len(dstTransactions)=%d, len(wrapperDetails)=%d, len(polyDetails)=%d",
+		chain.Name, height, len(wrapperTransactions), len(srcTransactions), len(polyTransactions), len(dstTransactions), len(wrapperDetails), len(polyDetails))
+	detailWrapperTxs, err := ccl.db.FillTxSpecialChain(wrapperTransactions, srcTransactions, polyTransactions, dstTransactions, wrapperDetails, polyDetails)
+	if err != nil {
+		logs.Error("FillTxSpecialChain on block %d err: %v", height, err)
+		return false
+	}
+	wrapperTransactions = append(wrapperTransactions, detailWrapperTxs...)
+
+	err = ccl.db.WrapperTransactionCheckFee(wrapperTransactions, srcTransactions)
+	if err != nil {
+		logs.Error("check fee on block %d err: %v", height, err)
+		return false
+	}
+	err = ccl.db.UpdateEvents(wrapperTransactions, srcTransactions, polyTransactions, dstTransactions, wrapperDetails, polyDetails)
+	if err != nil {
+		logs.Error("UpdateEvents on block %d err: %v", height, err)
+		return false
+	}
+	if !ccl.config.Backup && !ccl.replay {
+		ccl.goCheckLargeTransaction(srcTransactions)
+	}
+	return true
+}
@@ -0,0 +1,65 @@
+package crosschainlisten
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"poly-bridge/basedef"
+	"poly-bridge/conf"
+	"poly-bridge/crosschainlisten/fakelisten"
+	"poly-bridge/models"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRangeProgress(t *testing.T) {
+	progress := newRangeProgress()
+	progress.complete(101, 110)
+	progress.complete(121, 130)
+	assert.True(t, progress.done(101, 110))
+	assert.False(t, progress.done(111, 120))
+
+	assert.Equal(t, time.Second, progress.fail(111))
+	assert.Equal(t, time.Second*2, progress.fail(111))
+	assert.Equal(t, uint64(110), progress.watermark(100))
+	assert.False(t, progress.done(101, 110))
+
+	progress.complete(111, 120)
+	assert.Equal(t, uint64(130), progress.watermark(110))
+	assert.Empty(t, progress.completed)
+	assert.Empty(t, progress.failures)
+
+	progress.complete(141, 150)
+	progress.reset()
+	assert.Equal(t, uint64(140), progress.watermark(140))
+}
+
+func TestReplayRange(t *testing.T) {
+	cfg := &conf.ChainListenConfig{ChainId: basedef.ETHEREUM_CROSSCHAIN_ID, ChainName: "Ethereum", ReorgDepth: 10}
+	handle := fakelisten.NewFakeChainListen(cfg, &fakelisten.Script{
+		LatestHeight: 300,
+		Blocks: map[uint64]*fakelisten.Block{
+			160: {SrcTransactions: []*models.SrcTransaction{newSrcTransaction("src", 160, "1")}},
+		},
+		Failures: map[uint64]int{130: maxRangeAttempts},
+	})
+	dao := newTestDao()
+	ccl := NewReplayListen(context.Background(), handle, dao, &conf.Config{ChainListenConfig: []*conf.ChainListenConfig{cfg}})
+
+	assert.Equal(t, uint64(125), ccl.ReplayRange(100, 200, 25), "the ranges above the failed one are not contiguous")
+	assert.Equal(t, uint64(200), ccl.ReplayRange(125, 200, 25))
+	assert.Equal(t, 1, handle.Handled(126))
+	assert.Equal(t, 1, handle.Handled(151), "the committed ranges are not fetched again")
+	assert.Equal(t, 0, handle.Handled(201))
+
+	relation, err := dao.GetTransactionByHash("src")
+	assert.NoError(t, err)
+	assert.Equal(t, "src", relation.SrcHash)
+	chain, err := dao.GetChain(basedef.ETHEREUM_CROSSCHAIN_ID)
+	assert.NoError(t, err)
+	assert.Equal(t, uint64(100), chain.Height, "the listen height is not touched")
+	hashes, err := dao.GetBlockHashes(basedef.ETHEREUM_CROSSCHAIN_ID, 0, 300)
+	assert.NoError(t, err)
+	assert.Empty(t, hashes)
+}
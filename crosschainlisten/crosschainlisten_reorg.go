@@ -0,0 +1,235 @@
+/*
+ * Copyright (C) 2020 The poly network Authors
+ * This file is part of The poly network library.
+ *
+ * The  poly network  is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU Lesser General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * The  poly network  is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU Lesser General Public License for more details.
+ * You should have received a copy of the GNU Lesser General Public License
+ * along with The poly network .  If not, see <http://www.gnu.org/licenses/>.
+ */
+
+package crosschainlisten
+
+import (
+	"fmt"
+	"strings"
+	"sync"
+
+	"poly-bridge/models"
+
+	"github.com/beego/beego/v2/core/logs"
+)
+
+const defaultReorgDepth = 128
+
+// BlockHashHandle is implemented by the chain handles which can report block hashes,
+// the listener uses it to detect chain reorganizations.
+type BlockHashHandle interface {
+	GetBlockHash(height uint64) (hash string, parentHash string, err error)
+}
+
+// reorgTracker keeps the block hashes fetched for the heights not committed yet. The hashes of committed heights
+// are saved with the dao, so a restarted listener still detects a reorg below its height, and the orphaned events
+// are removed by their chain and height with the dao.
+type reorgTracker struct {
+	mutex   sync.Mutex
+	depth   uint64
+	pending map[uint64]*models.BlockHash
+}
+
+func newReorgTracker(depth uint64) *reorgTracker {
+	if depth == 0 {
+		depth = defaultReorgDepth
+	}
+	return &reorgTracker{
+		depth:   depth,
+		pending: make(map[uint64]*models.BlockHash),
+	}
+}
+
+func (t *reorgTracker) addHash(hash *models.BlockHash) {
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+	t.pending[hash.Height] = hash
+}
+
+// takeHashes removes the pending hashes of (from, to] and returns them from low to high,
+// the hashes of lower heights are dropped
+func (t *reorgTracker) takeHashes(from, to uint64) []*models.BlockHash {
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+	hashes := make([]*models.BlockHash, 0)
+	for h := from + 1; h <= to; h++ {
+		if hash, ok := t.pending[h]; ok {
+			hashes = append(hashes, hash)
+			delete(t.pending, h)
+		}
+	}
+	for h := range t.pending {
+		if h <= from {
+			delete(t.pending, h)
+		}
+	}
+	return hashes
+}
+
+func (t *reorgTracker) truncate(height uint64) {
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+	for h := range t.pending {
+		if h > height {
+			delete(t.pending, h)
+		}
+	}
+}
+
+// fetchBlockHashes fetches the hashes of the heights of [start, end] within the reorg depth of tip,
+// they are saved when the range is committed by advanceHeight
+func (ccl *CrossChainListen) fetchBlockHashes(start, end, tip uint64) error {
+	if ccl.reorg == nil || ccl.config.Backup {
+		return nil
+	}
+	if tip > ccl.reorg.depth && start+ccl.reorg.depth <= tip {
+		start = tip - ccl.reorg.depth + 1
+	}
+	for h := start; h <= end; h++ {
+		if err := ccl.fetchBlockHash(h); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (ccl *CrossChainListen) fetchBlockHash(height uint64) error {
+	hash, parentHash, err := ccl.handle.(BlockHashHandle).GetBlockHash(height)
+	if err != nil {
+		return fmt.Errorf("get block %d err: %v", height, err)
+	}
+	ccl.reorg.addHash(&models.BlockHash{
+		ChainId:    ccl.handle.GetChainId(),
+		Height:     height,
+		Hash:       strings.ToLower(hash),
+		ParentHash: strings.ToLower(parentHash),
+	})
+	return nil
+}
+
+// commitBlockHashes saves the hashes of (chain.Height, height] once they link to each other and to the saved
+// hash of chain.Height. A broken link means the chain reorganized while the heights were fetched, the events
+// written for them are removed and false is returned, so the heights are fetched again.
+func (ccl *CrossChainListen) commitBlockHashes(chain *models.Chain, height uint64) (bool, error) {
+	if ccl.reorg == nil || ccl.config.Backup {
+		return true, nil
+	}
+	hashes := ccl.reorg.takeHashes(chain.Height, height)
+	if len(hashes) == 0 || hashes[len(hashes)-1].Height != height {
+		if err := ccl.fetchBlockHash(height); err != nil {
+			return false, err
+		}
+		hashes = append(hashes, ccl.reorg.takeHashes(height-1, height)...)
+	}
+	saved, err := ccl.db.GetBlockHashes(chain.ChainId, chain.Height, chain.Height)
+	if err != nil {
+		return false, err
+	}
+	prev := ""
+	if len(saved) > 0 {
+		prev = saved[0].Hash
+	}
+	prevHeight := chain.Height
+	for _, hash := range hashes {
+		if prev != "" && hash.Height == prevHeight+1 && hash.ParentHash != prev {
+			logs.Warn("chain %s reorg detected at height %d while fetching %d-%d, parent hash: %s, fetched hash: %s",
+				ccl.handle.GetChainName(), hash.Height, chain.Height+1, height, hash.ParentHash, prev)
+			if err := ccl.db.RemoveEventsAbove(chain.ChainId, chain.Height); err != nil {
+				return false, fmt.Errorf("remove events above %d err: %v", chain.Height, err)
+			}
+			ccl.reorg.truncate(chain.Height)
+			ccl.progress.reset()
+			return false, nil
+		}
+		prev, prevHeight = hash.Hash, hash.Height
+	}
+	var pruneBelow uint64
+	if height > ccl.reorg.depth {
+		pruneBelow = height - ccl.reorg.depth
+	}
+	if err := ccl.db.SaveBlockHashes(chain.ChainId, hashes, pruneBelow); err != nil {
+		return false, fmt.Errorf("save block hashes of %d-%d err: %v", chain.Height+1, height, err)
+	}
+	return true, nil
+}
+
+// checkReorg compares the parent hash of the block after chain.Height with the saved hash of chain.Height.
+// If they differ, the chain is walked back to the common ancestor, the events written for the orphaned blocks
+// are removed and chain.Height is rewound to the ancestor.
+func (ccl *CrossChainListen) checkReorg(chain *models.Chain) error {
+	if ccl.reorg == nil || ccl.config.Backup {
+		return nil
+	}
+	var start uint64
+	if chain.Height > ccl.reorg.depth {
+		start = chain.Height - ccl.reorg.depth
+	}
+	saved, err := ccl.db.GetBlockHashes(chain.ChainId, start, chain.Height)
+	if err != nil {
+		return fmt.Errorf("get block hashes err: %v", err)
+	}
+	if len(saved) == 0 || saved[0].Height != chain.Height {
+		return nil
+	}
+	handle := ccl.handle.(BlockHashHandle)
+	_, parentHash, err := handle.GetBlockHash(chain.Height + 1)
+	if err != nil {
+		return fmt.Errorf("get block %d err: %v", chain.Height+1, err)
+	}
+	if strings.ToLower(parentHash) == saved[0].Hash {
+		return nil
+	}
+	logs.Warn("chain %s reorg detected at height %d, parent hash: %s, saved hash: %s", ccl.handle.GetChainName(), chain.Height+1, parentHash, saved[0].Hash)
+
+	// the block below the oldest saved hash, if none of the saved hashes is still on the chain
+	ancestor := saved[len(saved)-1].Height
+	if ancestor > 0 {
+		ancestor--
+	}
+	found := false
+	for _, v := range saved {
+		hash, _, err := handle.GetBlockHash(v.Height)
+		if err != nil {
+			return fmt.Errorf("get block %d err: %v", v.Height, err)
+		}
+		if strings.ToLower(hash) == v.Hash {
+			ancestor = v.Height
+			found = true
+			break
+		}
+	}
+	if !found {
+		logs.Error("chain %s reorg is deeper than the %d tracked heights, roll back to %d", ccl.handle.GetChainName(), ccl.reorg.depth, ancestor)
+	}
+
+	if err := ccl.db.RemoveEventsAbove(chain.ChainId, ancestor); err != nil {
+		return fmt.Errorf("remove orphaned events err: %v", err)
+	}
+	if err := ccl.db.RemoveBlockHashes(chain.ChainId, ancestor); err != nil {
+		return fmt.Errorf("remove orphaned block hashes err: %v", err)
+	}
+	flagChainHeight := chain.Height
+	chain.Height = ancestor
+	if err := ccl.db.UpdateChain(chain); err != nil {
+		chain.Height = flagChainHeight
+		return fmt.Errorf("UpdateChain [chainId:%d, height:%d] err %v", chain.ChainId, ancestor, err)
+	}
+	ccl.reorg.truncate(ancestor)
+	ccl.progress.reset()
+	logs.Info("chain %s rolled back from %d to common ancestor %d", ccl.handle.GetChainName(), flagChainHeight, ancestor)
+	return nil
+}
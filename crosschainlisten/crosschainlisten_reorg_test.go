@@ -0,0 +1,122 @@
+package crosschainlisten
+
+import (
+	"testing"
+
+	"poly-bridge/basedef"
+	"poly-bridge/cacheRedis"
+	"poly-bridge/conf"
+	"poly-bridge/crosschainlisten/fakelisten"
+	"poly-bridge/models"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestReorgTracker(t *testing.T) {
+	tracker := newReorgTracker(20)
+	for h := uint64(108); h <= 112; h++ {
+		tracker.addHash(&models.BlockHash{Height: h, Hash: fakelisten.BlockHash(h)})
+	}
+	hashes := tracker.takeHashes(109, 111)
+	assert.Len(t, hashes, 2)
+	assert.Equal(t, uint64(110), hashes[0].Height)
+	assert.Equal(t, uint64(111), hashes[1].Height)
+	assert.Len(t, tracker.takeHashes(111, 112), 1)
+	assert.Empty(t, tracker.takeHashes(100, 112), "the taken and lower hashes are dropped")
+
+	tracker.addHash(&models.BlockHash{Height: 120})
+	tracker.truncate(110)
+	assert.Empty(t, tracker.takeHashes(110, 120))
+}
+
+func TestCheckReorgAfterRestart(t *testing.T) {
+	cfg := &conf.ChainListenConfig{ChainId: basedef.ETHEREUM_CROSSCHAIN_ID, ChainName: "Ethereum", ReorgDepth: 10}
+	handle := fakelisten.NewFakeChainListen(cfg, &fakelisten.Script{
+		LatestHeight: 110,
+		Blocks: map[uint64]*fakelisten.Block{
+			99:  {Hash: "b99"},
+			100: {Hash: "b100", ParentHash: "b99"},
+			101: {ParentHash: "b100"},
+		},
+	})
+	dao := newTestDao()
+	// the hashes saved before the restart
+	hashes := make([]*models.BlockHash, 0)
+	for h := uint64(95); h <= 100; h++ {
+		hashes = append(hashes, &models.BlockHash{ChainId: basedef.ETHEREUM_CROSSCHAIN_ID, Height: h, Hash: fakelisten.BlockHash(h), ParentHash: fakelisten.BlockHash(h - 1)})
+	}
+	assert.NoError(t, dao.SaveBlockHashes(basedef.ETHEREUM_CROSSCHAIN_ID, hashes, 0))
+	// the events saved before the restart, the ones above the ancestor are orphaned
+	assert.NoError(t, dao.UpdateEvents(nil,
+		[]*models.SrcTransaction{newSrcTransaction("kept", 98, "1"), newSrcTransaction("orphaned", 99, "1")},
+		nil,
+		[]*models.DstTransaction{{Hash: "orphaned dst", ChainId: basedef.ETHEREUM_CROSSCHAIN_ID, Height: 100}},
+		nil, nil))
+
+	ccl := NewCrossChainListen(handle, dao, &conf.Config{ChainListenConfig: []*conf.ChainListenConfig{cfg}}, cacheRedis.NewMemoryCache())
+	chain, err := dao.GetChain(basedef.ETHEREUM_CROSSCHAIN_ID)
+	assert.NoError(t, err)
+	assert.NoError(t, ccl.checkReorg(chain))
+	assert.Equal(t, uint64(98), chain.Height)
+	saved, err := dao.GetBlockHashes(basedef.ETHEREUM_CROSSCHAIN_ID, 0, 200)
+	assert.NoError(t, err)
+	assert.Equal(t, uint64(98), saved[0].Height, "the orphaned hashes are removed")
+	_, err = dao.GetSrcTransaction("kept")
+	assert.NoError(t, err)
+	_, err = dao.GetSrcTransaction("orphaned")
+	assert.Error(t, err, "the events saved before the restart are removed")
+	_, err = dao.GetDstTransactionByHash("orphaned dst")
+	assert.Error(t, err)
+
+	// a reorg between the fetch of two heights of one round is not committed
+	handle.SetBlock(99, &fakelisten.Block{})
+	handle.SetBlock(100, &fakelisten.Block{})
+	handle.SetBlock(101, &fakelisten.Block{})
+	assert.NoError(t, ccl.fetchBlockHashes(99, 100, 100))
+	handle.SetBlock(101, &fakelisten.Block{ParentHash: "b100"})
+	assert.NoError(t, ccl.fetchBlockHashes(101, 101, 101))
+	committed, err := ccl.commitBlockHashes(chain, 101)
+	assert.NoError(t, err)
+	assert.False(t, committed)
+
+	assert.NoError(t, ccl.fetchBlockHashes(99, 101, 101))
+	committed, err = ccl.commitBlockHashes(chain, 101)
+	assert.NoError(t, err)
+	assert.False(t, committed, "block 101 still does not link to block 100")
+
+	handle.SetBlock(101, &fakelisten.Block{})
+	assert.NoError(t, ccl.fetchBlockHashes(99, 101, 101))
+	committed, err = ccl.commitBlockHashes(chain, 101)
+	assert.NoError(t, err)
+	assert.True(t, committed)
+	saved, err = dao.GetBlockHashes(basedef.ETHEREUM_CROSSCHAIN_ID, 99, 101)
+	assert.NoError(t, err)
+	assert.Len(t, saved, 3)
+}
+
+func TestCheckReorgFromGenesis(t *testing.T) {
+	cfg := &conf.ChainListenConfig{ChainId: basedef.ETHEREUM_CROSSCHAIN_ID, ChainName: "Ethereum", ReorgDepth: 10}
+	handle := fakelisten.NewFakeChainListen(cfg, &fakelisten.Script{
+		LatestHeight: 10,
+		Blocks: map[uint64]*fakelisten.Block{
+			0: {Hash: "b0"},
+			1: {Hash: "b1", ParentHash: "b0"},
+			2: {Hash: "b2", ParentHash: "b1"},
+			3: {ParentHash: "b2"},
+		},
+	})
+	dao := newTestDao()
+	hashes := make([]*models.BlockHash, 0)
+	for h := uint64(0); h <= 2; h++ {
+		hashes = append(hashes, &models.BlockHash{ChainId: basedef.ETHEREUM_CROSSCHAIN_ID, Height: h, Hash: fakelisten.BlockHash(h)})
+	}
+	assert.NoError(t, dao.SaveBlockHashes(basedef.ETHEREUM_CROSSCHAIN_ID, hashes, 0))
+	chain, err := dao.GetChain(basedef.ETHEREUM_CROSSCHAIN_ID)
+	assert.NoError(t, err)
+	chain.Height = 2
+	assert.NoError(t, dao.UpdateChain(chain))
+
+	ccl := NewCrossChainListen(handle, dao, &conf.Config{ChainListenConfig: []*conf.ChainListenConfig{cfg}}, cacheRedis.NewMemoryCache())
+	assert.NoError(t, ccl.checkReorg(chain))
+	assert.Equal(t, uint64(0), chain.Height, "no saved hash is on the chain, the oldest one is at height 0")
+}
@@ -0,0 +1,142 @@
+package crosschainlisten
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"poly-bridge/basedef"
+	"poly-bridge/cacheRedis"
+	"poly-bridge/conf"
+	"poly-bridge/crosschaindao/memorydao"
+	"poly-bridge/crosschainlisten/fakelisten"
+	"poly-bridge/models"
+
+	"github.com/polynetwork/bridge-common/metrics"
+	"github.com/stretchr/testify/assert"
+)
+
+var metricsOnce sync.Once
+
+const ethAsset = "0000000000000000000000000000000000000000"
+
+func newBigInt(s string) *models.BigInt {
+	value, _ := new(big.Int).SetString(s, 10)
+	return models.NewBigInt(value)
+}
+
+func newTestDao() *memorydao.MemoryDao {
+	dao := memorydao.NewMemoryDao()
+	eth := &models.TokenBasic{
+		Name:      "ETH",
+		Precision: 18,
+		Price:     2000 * basedef.PRICE_PRECISION,
+		Tokens:    []*models.Token{{Hash: ethAsset, ChainId: basedef.ETHEREUM_CROSSCHAIN_ID, Name: "ETH", Precision: 18}},
+	}
+	dao.AddTokens([]*models.TokenBasic{eth}, nil, nil)
+	dao.AddChains([]*models.Chain{
+		{ChainId: basedef.ETHEREUM_CROSSCHAIN_ID, Name: "Ethereum", Height: 100},
+		{ChainId: basedef.BSC_CROSSCHAIN_ID, Name: "BSC", Height: 200},
+	}, []*models.ChainFee{
+		{ChainId: basedef.BSC_CROSSCHAIN_ID, TokenBasicName: "ETH", TokenBasic: eth, MinFee: newBigInt("100000000000000000000000")},
+	})
+	return dao
+}
+
+func newSrcTransaction(hash string, height uint64, amount string) *models.SrcTransaction {
+	return &models.SrcTransaction{
+		Hash: hash, ChainId: basedef.ETHEREUM_CROSSCHAIN_ID, Height: height, DstChainId: basedef.BSC_CROSSCHAIN_ID, Time: 1608885420,
+		SrcTransfer: &models.SrcTransfer{TxHash: hash, ChainId: basedef.ETHEREUM_CROSSCHAIN_ID, Asset: ethAsset, Amount: newBigInt(amount), DstChainId: basedef.BSC_CROSSCHAIN_ID},
+	}
+}
+
+func TestListenChainReplay(t *testing.T) {
+	metricsOnce.Do(func() { metrics.Init("test") })
+	cfg := &conf.ChainListenConfig{ChainId: basedef.ETHEREUM_CROSSCHAIN_ID, ChainName: "Ethereum", ListenSlot: 1, Defer: 1, BatchSize: 2, MinBatchLength: 1, MaxBatchLength: 5}
+	handle := fakelisten.NewFakeChainListen(cfg, &fakelisten.Script{
+		LatestHeight: 130,
+		Blocks: map[uint64]*fakelisten.Block{
+			105: {
+				WrapperTransactions: []*models.WrapperTransaction{{Hash: "src", SrcChainId: basedef.ETHEREUM_CROSSCHAIN_ID, DstChainId: basedef.BSC_CROSSCHAIN_ID,
+					FeeTokenHash: ethAsset, FeeAmount: newBigInt("2000000000000000"), BlockHeight: 105}},
+				SrcTransactions: []*models.SrcTransaction{newSrcTransaction("src", 105, "1000000000000000000")},
+			},
+			112: {PolyTransactions: []*models.PolyTransaction{{Hash: "poly", SrcHash: "src", Height: 112}}},
+			118: {DstTransactions: []*models.DstTransaction{{Hash: "dst", PolyHash: "poly", ChainId: basedef.BSC_CROSSCHAIN_ID, Height: 118}}},
+		},
+		Failures: map[uint64]int{112: 1},
+	})
+	dao := newTestDao()
+	ccl := NewCrossChainListen(handle, dao, &conf.Config{ChainListenConfig: []*conf.ChainListenConfig{cfg}, LargeTxAmount: 1000000}, cacheRedis.NewMemoryCache())
+	ccl.Start()
+	assert.Eventually(t, func() bool {
+		chain, err := dao.GetChain(basedef.ETHEREUM_CROSSCHAIN_ID)
+		return err == nil && chain.Height == 129
+	}, time.Second*20, time.Millisecond*100)
+	ccl.Stop()
+
+	relation, err := dao.GetTransactionByHash("src")
+	assert.NoError(t, err)
+	assert.True(t, relation.WrapperTransaction.IsPaid)
+	assert.Equal(t, "poly", relation.PolyHash)
+	assert.Equal(t, "dst", relation.DstHash)
+	assert.Equal(t, 1, handle.Handled(112), "the failed range is fetched again")
+	assert.Equal(t, 1, handle.Handled(105))
+	assert.Equal(t, 0, handle.Handled(130), "the deferred block is not fetched")
+	hashes, err := dao.GetBlockHashes(basedef.ETHEREUM_CROSSCHAIN_ID, 0, 200)
+	assert.NoError(t, err)
+	assert.Len(t, hashes, 29, "the hash of every processed height is saved")
+}
+
+func TestCheckLargeTransaction(t *testing.T) {
+	titles := make(chan string, 4)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := ioutil.ReadAll(r.Body)
+		payload := struct {
+			ActionCard struct {
+				Title string `json:"title"`
+			} `json:"actionCard"`
+		}{}
+		json.Unmarshal(body, &payload)
+		titles <- payload.ActionCard.Title
+	}))
+	defer server.Close()
+	globalConfig := conf.GlobalConfig
+	conf.GlobalConfig = &conf.Config{BotConfig: &conf.BotConfig{LargeTxDingUrl: server.URL}}
+	defer func() { conf.GlobalConfig = globalConfig }()
+
+	cfg := &conf.ChainListenConfig{ChainId: basedef.ETHEREUM_CROSSCHAIN_ID, ChainName: "Ethereum", ListenSlot: 1}
+	cache := cacheRedis.NewMemoryCache()
+	ccl := NewCrossChainListen(fakelisten.NewFakeChainListen(cfg, nil), newTestDao(), &conf.Config{LargeTxAmount: 1000000}, cache)
+
+	// 100 ETH is 200k USD, below the threshold
+	ccl.checkLargeTransaction([]*models.SrcTransaction{newSrcTransaction("small", 101, "100000000000000000000")})
+	largeTxs, err := cache.LRange(cacheRedis.LargeTxList, 0, -1)
+	assert.NoError(t, err)
+	assert.Empty(t, largeTxs)
+
+	// 1000 ETH is 2m USD
+	ccl.checkLargeTransaction([]*models.SrcTransaction{newSrcTransaction("large", 101, "1000000000000000000000")})
+	select {
+	case title := <-titles:
+		assert.Equal(t, "Large transaction exceeding 100w USD (Ethereum->BSC)", title)
+	case <-time.After(time.Second * 5):
+		t.Fatal("no alarm sent")
+	}
+	largeTxs, _ = cache.LRange(cacheRedis.LargeTxList, 0, -1)
+	assert.Equal(t, []string{"large"}, largeTxs)
+	done, _ := cache.Exists(cacheRedis.LargeTxAlarmPrefix + strings.ToLower("large"))
+	assert.True(t, done)
+
+	// the alarm of a transaction is sent once
+	ccl.checkLargeTransaction([]*models.SrcTransaction{newSrcTransaction("large", 101, "1000000000000000000000")})
+	assert.Len(t, titles, 0)
+	largeTxs, _ = cache.LRange(cacheRedis.LargeTxList, 0, -1)
+	assert.Len(t, largeTxs, 1)
+}
@@ -18,15 +18,18 @@
 package main
 
 import (
+	"context"
 	"encoding/json"
 	"fmt"
 	"github.com/beego/beego/v2/core/logs"
 	"github.com/urfave/cli"
 	"os"
 	"os/signal"
+	"poly-bridge/cacheRedis"
 	"poly-bridge/conf"
 	"poly-bridge/crosschaindao"
 	"poly-bridge/crosschainlisten"
+	"poly-bridge/migration"
 	"runtime"
 	"strings"
 	"syscall"
@@ -34,6 +37,9 @@ import (
 
 var chainListen *crosschainlisten.CrossChainListen
 
+// serverCache is closed by stopServer, a reload opens a new one
+var serverCache cacheRedis.Cache
+
 var (
 	logLevelFlag = cli.UintFlag{
 		Name:  "loglevel",
@@ -124,6 +130,9 @@ func startServer(ctx *cli.Context) {
 	chain := ctx.GlobalUint64(getFlagName(chainFlag))
 	height := ctx.GlobalUint64(getFlagName(heightFlag))
 
+	if err := migration.CheckSchema(config.DBConfig); err != nil {
+		panic(err)
+	}
 	db := crosschaindao.NewCrossChainDao(config.Server, config.Backup, config.DBConfig)
 	if db == nil {
 		panic("server is invalid")
@@ -132,11 +141,16 @@ func startServer(ctx *cli.Context) {
 	if chainListenConfig == nil {
 		panic("chain is invalid")
 	}
-	chainHandler := crosschainlisten.NewChainHandle(chainListenConfig)
+	chainHandler := crosschainlisten.NewChainHandle(context.Background(), chainListenConfig)
 	if chainHandler == nil {
 		panic("chain handler is invalid")
 	}
-	chainListen = crosschainlisten.NewCrossChainListen(chainHandler, db, config)
+	cache, err := cacheRedis.NewCache(config.RedisConfig)
+	if err != nil {
+		panic(fmt.Sprintf("cache is invalid: %v", err))
+	}
+	serverCache = cache
+	chainListen = crosschainlisten.NewCrossChainListen(chainHandler, db, config, cache)
 	chainListen.SetHeight(height)
 	chainListen.Start()
 }
@@ -160,6 +174,12 @@ func waitSignal() os.Signal {
 
 func stopServer() {
 	chainListen.Stop()
+	if serverCache != nil {
+		if err := serverCache.Close(); err != nil {
+			logs.Error("close cache err: %v", err)
+		}
+		serverCache = nil
+	}
 }
 
 func main() {
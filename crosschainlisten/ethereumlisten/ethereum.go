@@ -65,13 +65,16 @@ type EthereumChainListen struct {
 	eventSwapperLockEventId              common.Hash
 }
 
-func NewEthereumChainListen(cfg *conf.ChainListenConfig) *EthereumChainListen {
+func NewEthereumChainListen(ctx context.Context, cfg *conf.ChainListenConfig) *EthereumChainListen {
 	ethListen := &EthereumChainListen{}
 	ethListen.ethCfg = cfg
 	//
 	urls := cfg.GetNodesUrl()
-	sdk := chainsdk.NewEthereumSdkPro(urls, cfg.ListenSlot, cfg.ChainId)
+	sdk := chainsdk.NewEthereumSdkProWithContext(ctx, urls, cfg.ListenSlot, cfg.ChainId)
 	ethListen.ethSdk = sdk
+	if cfg.Quorum > uint64(len(urls)) {
+		logs.Error("chain %s quorum %d is more than the %d nodes", cfg.ChainName, cfg.Quorum, len(urls))
+	}
 	ethListen.eventPolyWrapperLockId = common.HexToHash("0x2b0591052cc6602e870d3994f0a1b173fdac98c215cb3b0baf84eaca5a0aa81e")
 	ethListen.eventNftPolyWrapperLockId = common.HexToHash("0x3a15d8cf4b167dd8963989f8038f2333a4889f74033bb53bfb767a5cced072e2")
 	ethListen.eventCrossChainEventId = common.HexToHash("0x6ad3bf15c1988bc04bc153490cab16db8efb9a3990215bf1c64ea6e28be88483")
@@ -89,6 +92,9 @@ func NewEthereumChainListen(cfg *conf.ChainListenConfig) *EthereumChainListen {
 }
 
 func (this *EthereumChainListen) GetLatestHeight() (uint64, error) {
+	if this.quorumEnabled() {
+		return this.ethSdk.QuorumLatestHeight(int(this.ethCfg.Quorum))
+	}
 	return this.ethSdk.GetLatestHeight()
 }
 
@@ -116,6 +122,15 @@ func (this *EthereumChainListen) GetBatchLength() (uint64, uint64) {
 	return this.ethCfg.MinBatchLength, this.ethCfg.MaxBatchLength
 }
 
+func (this *EthereumChainListen) GetBlockHash(height uint64) (string, string, error) {
+	if this.quorumEnabled() {
+		hash, parentHash, dissenters, err := this.ethSdk.QuorumBlockHash(height, int(this.ethCfg.Quorum), this.quarantineSlot())
+		this.alarmQuorum(fmt.Sprintf("block hash of %d", height), dissenters, err)
+		return hash, parentHash, err
+	}
+	return this.ethSdk.GetBlockHashByNumber(height)
+}
+
 func (this *EthereumChainListen) getPLTUnlock(tx common.Hash) *models.ProxyUnlockEvent {
 	address, asset, amount, err := this.GetPaletteLockProxyUnlockEvent(tx)
 	if err != nil {
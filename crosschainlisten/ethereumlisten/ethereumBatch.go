@@ -6,7 +6,6 @@ import (
 	"github.com/beego/beego/v2/core/logs"
 	"github.com/ethereum/go-ethereum/common"
 	"github.com/ethereum/go-ethereum/core/types"
-	"math/big"
 	"poly-bridge/basedef"
 	"poly-bridge/go_abi/eccm_abi"
 	"poly-bridge/go_abi/lock_proxy_abi"
@@ -76,7 +75,7 @@ func (this *EthereumChainListen) HandleNewBatchBlock(start, end uint64) ([]*mode
 		filterContracts = append(filterContracts, swapContract)
 	}
 
-	contractlogs, err := this.ethSdk.FilterLog(big.NewInt(int64(start)), big.NewInt(int64(end)), filterContracts)
+	contractlogs, err := this.filterLog(start, end, filterContracts)
 	if err != nil {
 		return nil, nil, nil, nil, 0, 0, err
 	}
@@ -0,0 +1,75 @@
+/*
+ * Copyright (C) 2020 The poly network Authors
+ * This file is part of The poly network library.
+ *
+ * The  poly network  is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU Lesser General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * The  poly network  is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU Lesser General Public License for more details.
+ * You should have received a copy of the GNU Lesser General Public License
+ * along with The poly network .  If not, see <http://www.gnu.org/licenses/>.
+ */
+
+package ethereumlisten
+
+import (
+	"fmt"
+	"math/big"
+	"poly-bridge/chainsdk"
+	bcommon "poly-bridge/common"
+	"poly-bridge/conf"
+	"strings"
+	"time"
+
+	"github.com/beego/beego/v2/core/logs"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+)
+
+const defaultQuarantineSlot = 600
+
+// quorumEnabled reports whether heights, block hashes and logs are cross-checked against Quorum nodes
+func (this *EthereumChainListen) quorumEnabled() bool {
+	return this.ethCfg.Quorum > 1
+}
+
+func (this *EthereumChainListen) quarantineSlot() time.Duration {
+	slot := this.ethCfg.QuarantineSlot
+	if slot == 0 {
+		slot = defaultQuarantineSlot
+	}
+	return time.Second * time.Duration(slot)
+}
+
+func (this *EthereumChainListen) filterLog(start, end uint64, contracts []common.Address) ([]types.Log, error) {
+	if !this.quorumEnabled() {
+		return this.ethSdk.FilterLog(big.NewInt(int64(start)), big.NewInt(int64(end)), contracts)
+	}
+	contractlogs, dissenters, err := this.ethSdk.QuorumFilterLog(big.NewInt(int64(start)), big.NewInt(int64(end)), contracts, int(this.ethCfg.Quorum), this.quarantineSlot())
+	this.alarmQuorum(fmt.Sprintf("logs of %d-%d", start, end), dissenters, err)
+	return contractlogs, err
+}
+
+// alarmQuorum raises an alarm when the nodes returned different results
+func (this *EthereumChainListen) alarmQuorum(subject string, dissenters []string, err error) {
+	mismatch := len(dissenters) > 0
+	if quorumErr, ok := err.(*chainsdk.QuorumError); ok && quorumErr.Mismatch() {
+		mismatch = true
+	}
+	if !mismatch {
+		return
+	}
+	body := fmt.Sprintf("chain %s nodes disagree on %s, quarantined: [%s], err: %v", this.ethCfg.ChainName, subject, strings.Join(dissenters, ", "), err)
+	logs.Error(body)
+	if conf.GlobalConfig == nil || conf.GlobalConfig.BotConfig == nil || conf.GlobalConfig.BotConfig.NodeStatusDingUrl == "" {
+		return
+	}
+	if err := bcommon.PostDingtext(body, conf.GlobalConfig.BotConfig.NodeStatusDingUrl); err != nil {
+		logs.Error("post quorum alarm err: %v", err)
+	}
+}
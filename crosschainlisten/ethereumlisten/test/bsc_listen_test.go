@@ -18,9 +18,11 @@
 package test
 
 import (
+	"context"
 	"fmt"
 	"os"
 	"poly-bridge/basedef"
+	"poly-bridge/cacheRedis"
 	"poly-bridge/conf"
 	"poly-bridge/crosschaindao"
 	"poly-bridge/crosschainlisten"
@@ -46,8 +48,8 @@ func TestBscListen(t *testing.T) {
 	if bscListenConfig == nil {
 		panic("config is not valid")
 	}
-	chainHandle := crosschainlisten.NewChainHandle(bscListenConfig)
-	chainListen := crosschainlisten.NewCrossChainListen(chainHandle, dao, config)
+	chainHandle := crosschainlisten.NewChainHandle(context.Background(), bscListenConfig)
+	chainListen := crosschainlisten.NewCrossChainListen(chainHandle, dao, config, cacheRedis.NewMemoryCache())
 	chainListen.ListenChain()
 }
 
@@ -65,7 +67,7 @@ func TestBscListen2(t *testing.T) {
 	if bscListenConfig == nil {
 		panic("config is not valid")
 	}
-	ethListen := ethereumlisten.NewEthereumChainListen(bscListenConfig)
+	ethListen := ethereumlisten.NewEthereumChainListen(context.Background(), bscListenConfig)
 	wrapperTransactions, srcTransactions, polyTransactions, dstTransactions, _, _, err := ethListen.HandleNewBlock(6014032)
 	if err != nil {
 		panic(err)
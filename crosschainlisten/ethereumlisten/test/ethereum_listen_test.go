@@ -18,9 +18,11 @@
 package test
 
 import (
+	"context"
 	"fmt"
 	"os"
 	"poly-bridge/basedef"
+	"poly-bridge/cacheRedis"
 	"poly-bridge/conf"
 	"poly-bridge/crosschaindao"
 	"poly-bridge/crosschainlisten"
@@ -46,8 +48,8 @@ func TestEthereumListen(t *testing.T) {
 	if ethListenConfig == nil {
 		panic("config is not valid")
 	}
-	chainHandle := crosschainlisten.NewChainHandle(ethListenConfig)
-	chainListen := crosschainlisten.NewCrossChainListen(chainHandle, dao, config)
+	chainHandle := crosschainlisten.NewChainHandle(context.Background(), ethListenConfig)
+	chainListen := crosschainlisten.NewCrossChainListen(chainHandle, dao, config, cacheRedis.NewMemoryCache())
 	chainListen.ListenChain()
 }
 
@@ -69,8 +71,8 @@ func TestEthereumListen_StartStop(t *testing.T) {
 	if ethListenConfig == nil {
 		panic("config is not valid")
 	}
-	chainHandle := crosschainlisten.NewChainHandle(ethListenConfig)
-	chainListen := crosschainlisten.NewCrossChainListen(chainHandle, dao, config)
+	chainHandle := crosschainlisten.NewChainHandle(context.Background(), ethListenConfig)
+	chainListen := crosschainlisten.NewCrossChainListen(chainHandle, dao, config, cacheRedis.NewMemoryCache())
 	chainListen.Start()
 	time.Sleep(15 * time.Second)
 	//chainListen.Stop()
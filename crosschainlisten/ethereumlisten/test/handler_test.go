@@ -1,6 +1,7 @@
 package test
 
 import (
+	"context"
 	"poly-bridge/basedef"
 	"poly-bridge/conf"
 	"poly-bridge/crosschainlisten"
@@ -32,7 +33,7 @@ func TestHandleSingleBlock(t *testing.T) {
 	cfg := c.GetChainListenConfig(chainId)
 	assert.NotNil(t, cfg)
 
-	handler := crosschainlisten.NewChainHandle(cfg)
+	handler := crosschainlisten.NewChainHandle(context.Background(), cfg)
 
 	wpTxs, srcTxs, polyTxs, dstTxs, err := handler.HandleNewBlock(blockHeight)
 	assert.NoError(t, err)
@@ -18,9 +18,11 @@
 package test
 
 import (
+	"context"
 	"fmt"
 	"os"
 	"poly-bridge/basedef"
+	"poly-bridge/cacheRedis"
 	"poly-bridge/conf"
 	"poly-bridge/crosschaindao"
 	"poly-bridge/crosschainlisten"
@@ -45,7 +47,7 @@ func TestHecoListen(t *testing.T) {
 	if ethListenConfig == nil {
 		panic("config is not valid")
 	}
-	chainHandle := crosschainlisten.NewChainHandle(ethListenConfig)
-	chainListen := crosschainlisten.NewCrossChainListen(chainHandle, dao, config)
+	chainHandle := crosschainlisten.NewChainHandle(context.Background(), ethListenConfig)
+	chainListen := crosschainlisten.NewCrossChainListen(chainHandle, dao, config, cacheRedis.NewMemoryCache())
 	chainListen.ListenChain()
 }
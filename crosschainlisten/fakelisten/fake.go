@@ -0,0 +1,233 @@
+/*
+ * Copyright (C) 2020 The poly network Authors
+ * This file is part of The poly network library.
+ *
+ * The  poly network  is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU Lesser General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * The  poly network  is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU Lesser General Public License for more details.
+ * You should have received a copy of the GNU Lesser General Public License
+ * along with The poly network .  If not, see <http://www.gnu.org/licenses/>.
+ */
+
+package fakelisten
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"poly-bridge/conf"
+	"poly-bridge/models"
+	"sync"
+)
+
+// Block is a canned block replayed by FakeChainListen, the hashes default to ones derived from the height
+type Block struct {
+	Hash                string
+	ParentHash          string
+	WrapperTransactions []*models.WrapperTransaction
+	SrcTransactions     []*models.SrcTransaction
+	PolyTransactions    []*models.PolyTransaction
+	DstTransactions     []*models.DstTransaction
+	WrapperDetails      []*models.WrapperDetail
+	PolyDetails         []*models.PolyDetail
+}
+
+// Script is the chain replayed by FakeChainListen, Failures makes the height fail the given number of times
+type Script struct {
+	LatestHeight uint64
+	Blocks       map[uint64]*Block
+	Failures     map[uint64]int
+}
+
+// FakeChainListen is a ChainHandle replaying a Script, so CrossChainListen can be run without nodes
+type FakeChainListen struct {
+	cfg      *conf.ChainListenConfig
+	mutex    sync.Mutex
+	height   uint64
+	blocks   map[uint64]*Block
+	failures map[uint64]int
+	handled  map[uint64]int
+}
+
+func NewFakeChainListen(cfg *conf.ChainListenConfig, script *Script) *FakeChainListen {
+	fakeListen := &FakeChainListen{
+		cfg:      cfg,
+		blocks:   make(map[uint64]*Block),
+		failures: make(map[uint64]int),
+		handled:  make(map[uint64]int),
+	}
+	if script != nil {
+		fakeListen.height = script.LatestHeight
+		for height, block := range script.Blocks {
+			fakeListen.blocks[height] = block
+		}
+		for height, times := range script.Failures {
+			fakeListen.failures[height] = times
+		}
+	}
+	return fakeListen
+}
+
+// LoadScript reads a Script saved as json
+func LoadScript(path string) (*Script, error) {
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	script := new(Script)
+	err = json.Unmarshal(data, script)
+	if err != nil {
+		return nil, err
+	}
+	return script, nil
+}
+
+func BlockHash(height uint64) string {
+	return fmt.Sprintf("%064x", height)
+}
+
+// SetLatestHeight moves the chain head, the blocks above it are not replayed
+func (this *FakeChainListen) SetLatestHeight(height uint64) {
+	this.mutex.Lock()
+	defer this.mutex.Unlock()
+	this.height = height
+}
+
+// SetBlock replaces the block at height, replacing it with a different hash simulates a reorganization
+func (this *FakeChainListen) SetBlock(height uint64, block *Block) {
+	this.mutex.Lock()
+	defer this.mutex.Unlock()
+	this.blocks[height] = block
+}
+
+// Fail makes the next times fetches of height fail
+func (this *FakeChainListen) Fail(height uint64, times int) {
+	this.mutex.Lock()
+	defer this.mutex.Unlock()
+	this.failures[height] = times
+}
+
+// Handled returns how many times height has been fetched successfully
+func (this *FakeChainListen) Handled(height uint64) int {
+	this.mutex.Lock()
+	defer this.mutex.Unlock()
+	return this.handled[height]
+}
+
+func (this *FakeChainListen) GetLatestHeight() (uint64, error) {
+	this.mutex.Lock()
+	defer this.mutex.Unlock()
+	return this.height, nil
+}
+
+func (this *FakeChainListen) GetExtendLatestHeight() (uint64, error) {
+	return this.GetLatestHeight()
+}
+
+func (this *FakeChainListen) GetChainListenSlot() uint64 {
+	return this.cfg.ListenSlot
+}
+
+func (this *FakeChainListen) GetChainId() uint64 {
+	return this.cfg.ChainId
+}
+
+func (this *FakeChainListen) GetChainName() string {
+	return this.cfg.ChainName
+}
+
+func (this *FakeChainListen) GetDefer() uint64 {
+	return this.cfg.Defer
+}
+
+func (this *FakeChainListen) GetBatchSize() uint64 {
+	return this.cfg.BatchSize
+}
+
+func (this *FakeChainListen) GetBatchLength() (uint64, uint64) {
+	return this.cfg.MinBatchLength, this.cfg.MaxBatchLength
+}
+
+func (this *FakeChainListen) GetBlockHash(height uint64) (hash string, parentHash string, err error) {
+	this.mutex.Lock()
+	defer this.mutex.Unlock()
+	if height > this.height {
+		return "", "", fmt.Errorf("block %d not found, latest height %d", height, this.height)
+	}
+	hash, parentHash = BlockHash(height), BlockHash(height-1)
+	if block, ok := this.blocks[height]; ok {
+		if block.Hash != "" {
+			hash = block.Hash
+		}
+		if block.ParentHash != "" {
+			parentHash = block.ParentHash
+		}
+	}
+	return
+}
+
+// fetch fails if any height of [start, end] is scripted to fail, otherwise it marks the heights handled
+func (this *FakeChainListen) fetch(start, end uint64) ([]*Block, error) {
+	this.mutex.Lock()
+	defer this.mutex.Unlock()
+	if end > this.height {
+		return nil, fmt.Errorf("block %d not found, latest height %d", end, this.height)
+	}
+	for height := start; height <= end; height++ {
+		if this.failures[height] > 0 {
+			this.failures[height]--
+			return nil, fmt.Errorf("scripted failure of block %d", height)
+		}
+	}
+	blocks := make([]*Block, 0)
+	for height := start; height <= end; height++ {
+		this.handled[height]++
+		if block, ok := this.blocks[height]; ok {
+			blocks = append(blocks, block)
+		}
+	}
+	return blocks, nil
+}
+
+func (this *FakeChainListen) HandleNewBlock(height uint64) ([]*models.WrapperTransaction, []*models.SrcTransaction, []*models.PolyTransaction, []*models.DstTransaction, []*models.WrapperDetail, []*models.PolyDetail, int, int, error) {
+	blocks, err := this.fetch(height, height)
+	if err != nil {
+		return nil, nil, nil, nil, nil, nil, 0, 0, err
+	}
+	wrapperTransactions, srcTransactions, polyTransactions, dstTransactions := merge(blocks)
+	wrapperDetails, polyDetails := make([]*models.WrapperDetail, 0), make([]*models.PolyDetail, 0)
+	for _, block := range blocks {
+		wrapperDetails = append(wrapperDetails, block.WrapperDetails...)
+		polyDetails = append(polyDetails, block.PolyDetails...)
+	}
+	return wrapperTransactions, srcTransactions, polyTransactions, dstTransactions, wrapperDetails, polyDetails, len(srcTransactions), len(dstTransactions), nil
+}
+
+func (this *FakeChainListen) HandleNewBatchBlock(start, end uint64) ([]*models.WrapperTransaction, []*models.SrcTransaction, []*models.PolyTransaction, []*models.DstTransaction, int, int, error) {
+	blocks, err := this.fetch(start, end)
+	if err != nil {
+		return nil, nil, nil, nil, 0, 0, err
+	}
+	wrapperTransactions, srcTransactions, polyTransactions, dstTransactions := merge(blocks)
+	return wrapperTransactions, srcTransactions, polyTransactions, dstTransactions, len(srcTransactions), len(dstTransactions), nil
+}
+
+func merge(blocks []*Block) ([]*models.WrapperTransaction, []*models.SrcTransaction, []*models.PolyTransaction, []*models.DstTransaction) {
+	wrapperTransactions := make([]*models.WrapperTransaction, 0)
+	srcTransactions := make([]*models.SrcTransaction, 0)
+	polyTransactions := make([]*models.PolyTransaction, 0)
+	dstTransactions := make([]*models.DstTransaction, 0)
+	for _, block := range blocks {
+		wrapperTransactions = append(wrapperTransactions, block.WrapperTransactions...)
+		srcTransactions = append(srcTransactions, block.SrcTransactions...)
+		polyTransactions = append(polyTransactions, block.PolyTransactions...)
+		dstTransactions = append(dstTransactions, block.DstTransactions...)
+	}
+	return wrapperTransactions, srcTransactions, polyTransactions, dstTransactions
+}
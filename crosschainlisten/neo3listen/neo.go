@@ -18,6 +18,7 @@
 package neo3listen
 
 import (
+	"context"
 	"encoding/base64"
 	"encoding/hex"
 	"fmt"
@@ -47,11 +48,11 @@ type Neo3ChainListen struct {
 	neoSdk *chainsdk.Neo3SdkPro
 }
 
-func NewNeo3ChainListen(cfg *conf.ChainListenConfig) *Neo3ChainListen {
+func NewNeo3ChainListen(ctx context.Context, cfg *conf.ChainListenConfig) *Neo3ChainListen {
 	ethListen := &Neo3ChainListen{}
 	ethListen.neoCfg = cfg
 	urls := cfg.GetNodesUrl()
-	sdk := chainsdk.NewNeo3SdkPro(urls, cfg.ListenSlot, cfg.ChainId)
+	sdk := chainsdk.NewNeo3SdkProWithContext(ctx, urls, cfg.ListenSlot, cfg.ChainId)
 	ethListen.neoSdk = sdk
 	return ethListen
 }
@@ -18,6 +18,7 @@
 package neolisten
 
 import (
+	"context"
 	"encoding/hex"
 	"encoding/json"
 	"fmt"
@@ -47,11 +48,11 @@ type NeoChainListen struct {
 	neoSdk *chainsdk.NeoSdkPro
 }
 
-func NewNeoChainListen(cfg *conf.ChainListenConfig) *NeoChainListen {
+func NewNeoChainListen(ctx context.Context, cfg *conf.ChainListenConfig) *NeoChainListen {
 	ethListen := &NeoChainListen{}
 	ethListen.neoCfg = cfg
 	urls := cfg.GetNodesUrl()
-	sdk := chainsdk.NewNeoSdkPro(urls, cfg.ListenSlot, cfg.ChainId)
+	sdk := chainsdk.NewNeoSdkProWithContext(ctx, urls, cfg.ListenSlot, cfg.ChainId)
 	ethListen.neoSdk = sdk
 	return ethListen
 }
@@ -18,9 +18,11 @@
 package test
 
 import (
+	"context"
 	"fmt"
 	"os"
 	"poly-bridge/basedef"
+	"poly-bridge/cacheRedis"
 	"poly-bridge/conf"
 	"poly-bridge/crosschaindao"
 	"poly-bridge/crosschainlisten"
@@ -46,7 +48,7 @@ func TestNeoListen(t *testing.T) {
 	if neoListenConfig == nil {
 		panic("config is not valid")
 	}
-	chainHandle := crosschainlisten.NewChainHandle(neoListenConfig)
-	chainListen := crosschainlisten.NewCrossChainListen(chainHandle, dao, config)
+	chainHandle := crosschainlisten.NewChainHandle(context.Background(), neoListenConfig)
+	chainListen := crosschainlisten.NewCrossChainListen(chainHandle, dao, config, cacheRedis.NewMemoryCache())
 	chainListen.ListenChain()
 }
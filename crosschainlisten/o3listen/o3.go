@@ -52,12 +52,12 @@ type O3ChainListen struct {
 	ethSdk *chainsdk.EthereumSdkPro
 }
 
-func NewO3ChainListen(cfg *conf.ChainListenConfig) *O3ChainListen {
+func NewO3ChainListen(ctx context.Context, cfg *conf.ChainListenConfig) *O3ChainListen {
 	ethListen := &O3ChainListen{}
 	ethListen.ethCfg = cfg
 	//
 	urls := cfg.GetNodesUrl()
-	sdk := chainsdk.NewEthereumSdkPro(urls, cfg.ListenSlot, cfg.ChainId)
+	sdk := chainsdk.NewEthereumSdkProWithContext(ctx, urls, cfg.ListenSlot, cfg.ChainId)
 	ethListen.ethSdk = sdk
 	return ethListen
 }
@@ -18,6 +18,7 @@
 package ontologylisten
 
 import (
+	"context"
 	"encoding/hex"
 	"github.com/beego/beego/v2/core/logs"
 	"math/big"
@@ -40,11 +41,11 @@ type OntologyChainListen struct {
 	ontSdk *chainsdk.OntologySdkPro
 }
 
-func NewOntologyChainListen(cfg *conf.ChainListenConfig) *OntologyChainListen {
+func NewOntologyChainListen(ctx context.Context, cfg *conf.ChainListenConfig) *OntologyChainListen {
 	ontListen := &OntologyChainListen{}
 	ontListen.ontCfg = cfg
 	urls := cfg.GetNodesUrl()
-	sdk := chainsdk.NewOntologySdkPro(urls, cfg.ListenSlot, cfg.ChainId)
+	sdk := chainsdk.NewOntologySdkProWithContext(ctx, urls, cfg.ListenSlot, cfg.ChainId)
 	ontListen.ontSdk = sdk
 	return ontListen
 }
@@ -18,6 +18,7 @@
 package polylisten
 
 import (
+	"context"
 	"fmt"
 	"github.com/beego/beego/v2/core/logs"
 	"math/big"
@@ -32,11 +33,11 @@ type PolyChainListen struct {
 	polySdk *chainsdk.PolySDKPro
 }
 
-func NewPolyChainListen(cfg *conf.ChainListenConfig) *PolyChainListen {
+func NewPolyChainListen(ctx context.Context, cfg *conf.ChainListenConfig) *PolyChainListen {
 	polyListen := &PolyChainListen{}
 	polyListen.polyCfg = cfg
 	urls := cfg.GetNodesUrl()
-	sdk := chainsdk.NewPolySDKPro(urls, cfg.ListenSlot, cfg.ChainId)
+	sdk := chainsdk.NewPolySDKProWithContext(ctx, urls, cfg.ListenSlot, cfg.ChainId)
 	polyListen.polySdk = sdk
 	return polyListen
 }
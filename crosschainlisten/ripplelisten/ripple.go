@@ -1,6 +1,7 @@
 package ripplelisten
 
 import (
+	"context"
 	"encoding/hex"
 	"encoding/json"
 	"github.com/beego/beego/v2/core/logs"
@@ -19,11 +20,11 @@ type RippleChainListen struct {
 	rippleSdk *chainsdk.RippleSdkPro
 }
 
-func NewRippleChainListen(cfg *conf.ChainListenConfig) *RippleChainListen {
+func NewRippleChainListen(ctx context.Context, cfg *conf.ChainListenConfig) *RippleChainListen {
 	rippleListen := &RippleChainListen{}
 	rippleListen.rippleCfg = cfg
 	urls := cfg.GetNodesUrl()
-	sdk := chainsdk.NewRippleSdkPro(urls, cfg.ListenSlot, cfg.ChainId)
+	sdk := chainsdk.NewRippleSdkProWithContext(ctx, urls, cfg.ListenSlot, cfg.ChainId)
 	rippleListen.rippleSdk = sdk
 	return rippleListen
 }
@@ -1,6 +1,7 @@
 package starcoinlisten
 
 import (
+	"context"
 	"encoding/hex"
 	"encoding/json"
 	"fmt"
@@ -89,11 +90,11 @@ type UnlockEvent struct {
 	Amount      serde.Uint128
 }
 
-func NewStarcoinChainListen(cfg *conf.ChainListenConfig) *StarcoinChainListen {
+func NewStarcoinChainListen(ctx context.Context, cfg *conf.ChainListenConfig) *StarcoinChainListen {
 	starcoinListen := &StarcoinChainListen{}
 	starcoinListen.starcoinCfg = cfg
 	urls := cfg.GetNodesUrl()
-	sdk := chainsdk.NewStarcoinSdkPro(urls, cfg.ListenSlot, cfg.ChainId)
+	sdk := chainsdk.NewStarcoinSdkProWithContext(ctx, urls, cfg.ListenSlot, cfg.ChainId)
 	starcoinListen.starcoinSdk = sdk
 	return starcoinListen
 }
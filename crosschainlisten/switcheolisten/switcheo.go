@@ -1,6 +1,7 @@
 package switcheolisten
 
 import (
+	"context"
 	"encoding/hex"
 	"fmt"
 	"poly-bridge/utils/decimal"
@@ -27,11 +28,11 @@ type SwitcheoChainListen struct {
 	swthSdk *chainsdk.SwitcheoSdkPro
 }
 
-func NewSwitcheoChainListen(cfg *conf.ChainListenConfig) *SwitcheoChainListen {
+func NewSwitcheoChainListen(ctx context.Context, cfg *conf.ChainListenConfig) *SwitcheoChainListen {
 	swthListen := &SwitcheoChainListen{}
 	swthListen.swthCfg = cfg
 	urls := cfg.GetNodesUrl()
-	sdk := chainsdk.NewSwitcheoSdkPro(urls, cfg.ListenSlot, cfg.ChainId)
+	sdk := chainsdk.NewSwitcheoSdkProWithContext(ctx, urls, cfg.ListenSlot, cfg.ChainId)
 	swthListen.swthSdk = sdk
 	return swthListen
 }
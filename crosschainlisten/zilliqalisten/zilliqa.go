@@ -1,6 +1,7 @@
 package zilliqalisten
 
 import (
+	"context"
 	"encoding/hex"
 	"fmt"
 	"github.com/beego/beego/v2/core/logs"
@@ -25,11 +26,11 @@ type ZilliqaChainListen struct {
 	zliSdk *chainsdk.ZilliqaSdkPro
 }
 
-func NewZilliqaChainListen(cfg *conf.ChainListenConfig) *ZilliqaChainListen {
+func NewZilliqaChainListen(ctx context.Context, cfg *conf.ChainListenConfig) *ZilliqaChainListen {
 	zilListen := &ZilliqaChainListen{}
 	zilListen.zliCfg = cfg
 	urls := cfg.GetNodesUrl()
-	sdk := chainsdk.NewZilliqaSdkPro(urls, cfg.ListenSlot, cfg.ChainId)
+	sdk := chainsdk.NewZilliqaSdkProWithContext(ctx, urls, cfg.ListenSlot, cfg.ChainId)
 	zilListen.zliSdk = sdk
 	return zilListen
 }
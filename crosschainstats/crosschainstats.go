@@ -48,7 +48,7 @@ type Stats struct {
 var ccs *Stats
 
 // Start - Do stats aggregation/calculation
-func StartCrossChainStats(server string, cfg *conf.StatsConfig, dbCfg *conf.DBConfig, ipCfg *conf.IPPortConfig, chainCfg []*conf.ChainListenConfig) {
+func StartCrossChainStats(ctx context.Context, server string, cfg *conf.StatsConfig, dbCfg *conf.DBConfig, ipCfg *conf.IPPortConfig, chainCfg []*conf.ChainListenConfig) {
 	if server != basedef.SERVER_POLY_BRIDGE {
 		panic("CrossChainStats Only runs on bridge server")
 	}
@@ -57,7 +57,7 @@ func StartCrossChainStats(server string, cfg *conf.StatsConfig, dbCfg *conf.DBCo
 	}
 
 	dao := bridgedao.NewBridgeDao(dbCfg, false)
-	ctx, cancel := context.WithCancel(context.Background())
+	ctx, cancel := context.WithCancel(ctx)
 	ccs = &Stats{dao: dao, cfg: cfg, Context: ctx, cancel: cancel, ipCfg: ipCfg, chainCfg: chainCfg}
 	ccs.Start()
 }
@@ -70,8 +70,9 @@ func StopCrossChainStats() {
 }
 
 func (this *Stats) run(interval int64, f func() error) {
-	this.wg.Add(1)
+	defer this.wg.Done()
 	ticker := time.NewTicker(time.Second * time.Duration(interval))
+	defer ticker.Stop()
 	for {
 		select {
 		case <-ticker.C:
@@ -80,13 +81,13 @@ func (this *Stats) run(interval int64, f func() error) {
 				logs.Error("stats run error%s", err)
 			}
 		case <-this.Done():
-			break
+			return
 		}
 	}
-	this.wg.Done()
 }
 
 func (this *Stats) Start() {
+	this.wg.Add(8)
 	go this.run(this.cfg.TokenBasicStatsInterval, this.computeStats)
 	go this.run(this.cfg.TokenAmountCheckInterval, this.computeTokensStats)
 	go this.run(this.cfg.TokenStatisticInterval, this.computeTokenStatistics)
@@ -96,9 +97,11 @@ func (this *Stats) Start() {
 	go this.run(this.cfg.AssetStatisticInterval, this.computeAssetStatistics)
 	go this.run(this.cfg.AssetAdressInterval, this.computeAssetStatisticAdress)
 	if this.cfg.CensusTimeLinesInterval != 0 {
+		this.wg.Add(1)
 		go this.run(this.cfg.CensusTimeLinesInterval, this.censusTimeLines)
 	}
 	if this.cfg.CensusAssetLinesInterval != 0 {
+		this.wg.Add(1)
 		go this.run(this.cfg.CensusAssetLinesInterval, this.censusAssetLines)
 	}
 }
@@ -308,110 +311,123 @@ func (this *Stats) computeTokenStatistics() (err error) {
 	return nil
 }
 
+// computeChainStatistics counts the dst, src and poly transactions after the id checkpoints of the chain statistics,
+// the rows are only appended so a row saved late with an older time still gets a new id and is counted
 func (this *Stats) computeChainStatistics() (err error) {
 	logs.Info("computeChainStatistics,start_computeChainStatistics_computeChainStatistics")
-	nowChainStatistic, err := this.dao.GetNewChainSta()
+	chainStatistics := make([]*models.ChainStatistic, 0)
+	err = this.dao.GetChainStatistic(&chainStatistics)
 	if err != nil {
-		return fmt.Errorf("Failed to GetNewChainSta %w", err)
+		return fmt.Errorf("Failed to GetChainStatistic %w", err)
 	}
-	nowIn, err := this.dao.GetNewDstTransaction()
+	var lastInCheckId, lastOutCheckId, lastPolyCheckId int64
+	for _, chainStatistic := range chainStatistics {
+		if chainStatistic.ChainId == basedef.POLY_CROSSCHAIN_ID {
+			lastPolyCheckId = chainStatistic.LastInCheckId
+			continue
+		}
+		if chainStatistic.LastInCheckId > lastInCheckId {
+			lastInCheckId = chainStatistic.LastInCheckId
+		}
+		if chainStatistic.LastOutCheckId > lastOutCheckId {
+			lastOutCheckId = chainStatistic.LastOutCheckId
+		}
+	}
+	nowInId, err := this.dao.GetLastId(&models.DstTransaction{})
 	if err != nil {
-		return fmt.Errorf("Failed to GetNewDstTransfer %w", err)
+		return fmt.Errorf("Failed to GetLastId of dst transactions %w", err)
 	}
-	nowInId := nowIn.Id
-	nowOut, err := this.dao.GetNewSrcTransaction()
+	nowOutId, err := this.dao.GetLastId(&models.SrcTransaction{})
 	if err != nil {
-		return fmt.Errorf("Failed to GetNewSrcTransfer %w", err)
+		return fmt.Errorf("Failed to GetLastId of src transactions %w", err)
+	}
+	nowPolyId, err := this.dao.GetLastId(&models.PolyTransaction{})
+	if err != nil {
+		return fmt.Errorf("Failed to GetLastId of poly transactions %w", err)
+	}
+	if nowInId <= lastInCheckId && nowOutId <= lastOutCheckId && nowPolyId <= lastPolyCheckId {
+		return nil
 	}
-	nowOutId := nowOut.Id
 	inChainStatistics := make([]*models.ChainStatistic, 0)
-	if nowInId > nowChainStatistic.LastInCheckId {
-		err = this.dao.CalculateInChainStatistics(nowChainStatistic.LastInCheckId, nowInId, &inChainStatistics)
+	if nowInId > lastInCheckId {
+		err = this.dao.CalculateInChainStatistics(lastInCheckId, nowInId, &inChainStatistics)
 		if err != nil {
-			logs.Error("Failed to CalculateInTokenStatistics %w", err)
+			return fmt.Errorf("Failed to CalculateInChainStatistics %w", err)
 		}
+	} else {
+		nowInId = lastInCheckId
 	}
 	outChainStatistics := make([]*models.ChainStatistic, 0)
-	if nowOutId > nowChainStatistic.LastOutCheckId {
-		err = this.dao.CalculateOutChainStatistics(nowChainStatistic.LastOutCheckId, nowOutId, &outChainStatistics)
+	if nowOutId > lastOutCheckId {
+		err = this.dao.CalculateOutChainStatistics(lastOutCheckId, nowOutId, &outChainStatistics)
 		if err != nil {
-			logs.Error("Failed to CalculateInTokenStatistics %w", err)
+			return fmt.Errorf("Failed to CalculateOutChainStatistics %w", err)
 		}
+	} else {
+		nowOutId = lastOutCheckId
 	}
-	polyTransaction, err := this.dao.GetPolyTransaction()
-	if err != nil {
-		return fmt.Errorf("Failed to GetPolyTransaction %w", err)
-	}
-	polyCheckId := polyTransaction.Id
-	if nowInId > nowChainStatistic.LastInCheckId || nowOutId > nowChainStatistic.LastOutCheckId {
-		logs.Info("computeChainStatistics,nowInId > nowChainStatistic.LastInCheckId || nowOutId > nowChainStatistic.LastOutCheckId")
-		chainStatistics := make([]*models.ChainStatistic, 0)
-		err = this.dao.GetChainStatistic(&chainStatistics)
+	var polyCounter int64
+	if nowPolyId > lastPolyCheckId {
+		polyCounter, err = this.dao.CalculatePolyChainStatistic(lastPolyCheckId, nowPolyId)
 		if err != nil {
-			return fmt.Errorf("Failed to GetChainStatistic %w", err)
-		}
-		chainMap := make(map[uint64]bool)
-		chains, err := this.dao.GetChains()
-		if err != nil {
-			return fmt.Errorf("Failed to GetChains %w", err)
-		}
-		for _, v := range chains {
-			chainMap[v.ChainId] = false
+			return fmt.Errorf("Failed to CalculatePolyChainStatistic %w", err)
 		}
-		for _, v := range chainStatistics {
-			chainMap[v.ChainId] = true
+	} else {
+		nowPolyId = lastPolyCheckId
+	}
+	chainMap := make(map[uint64]bool)
+	chains, err := this.dao.GetChains()
+	if err != nil {
+		return fmt.Errorf("Failed to GetChains %w", err)
+	}
+	for _, v := range chains {
+		chainMap[v.ChainId] = false
+	}
+	for _, v := range chainStatistics {
+		chainMap[v.ChainId] = true
+	}
+	for k, v := range chainMap {
+		if v == false {
+			chainStatistic := new(models.ChainStatistic)
+			chainStatistic.ChainId = k
+			chainStatistic.In = 0
+			chainStatistic.Out = 0
+			chainStatistic.Addresses = 0
+			chainStatistics = append(chainStatistics, chainStatistic)
 		}
-		for k, v := range chainMap {
-			if v == false {
-				chainStatistic := new(models.ChainStatistic)
-				chainStatistic.ChainId = k
-				chainStatistic.In = 0
-				chainStatistic.Out = 0
-				chainStatistic.Addresses = 0
-				chainStatistic.LastInCheckId = nowInId
-				chainStatistic.LastOutCheckId = nowOutId
-				chainStatistics = append(chainStatistics, chainStatistic)
-			}
+	}
+	for _, chainStatistic := range chainStatistics {
+		if chainStatistic.ChainId == basedef.POLY_CROSSCHAIN_ID {
+			logs.Info("computeChainStatistics,polychainid:", chainStatistic.ChainId, "poly.In:", chainStatistic.In, "poly.Out:", chainStatistic.Out, "polycounter:", polyCounter)
+			chainStatistic.In = addDecimalInt64(polyCounter, chainStatistic.In)
+			chainStatistic.Out = addDecimalInt64(polyCounter, chainStatistic.Out)
+			chainStatistic.LastInCheckId = nowPolyId
+			chainStatistic.LastOutCheckId = nowPolyId
+			continue
 		}
-		for _, chainStatistic := range chainStatistics {
-			for _, in := range inChainStatistics {
-				if chainStatistic.ChainId == in.ChainId && chainStatistic.ChainId != basedef.POLY_CROSSCHAIN_ID {
-					chainStatistic.In = addDecimalInt64(chainStatistic.In, in.In)
-					break
-				}
-			}
-			for _, out := range outChainStatistics {
-				if chainStatistic.ChainId == out.ChainId && chainStatistic.ChainId != basedef.POLY_CROSSCHAIN_ID {
-					chainStatistic.Out = addDecimalInt64(chainStatistic.Out, out.Out)
-					break
-				}
-			}
-			if chainStatistic.ChainId != basedef.POLY_CROSSCHAIN_ID {
-				chainStatistic.LastInCheckId = nowInId
-				chainStatistic.LastOutCheckId = nowOutId
+		for _, in := range inChainStatistics {
+			if chainStatistic.ChainId == in.ChainId {
+				chainStatistic.In = addDecimalInt64(chainStatistic.In, in.In)
+				break
 			}
 		}
-		logs.Info("computeChainStatistics,poly_polyCheckId", polyCheckId)
-		for _, chainStatistic := range chainStatistics {
-			if chainStatistic.ChainId == basedef.POLY_CROSSCHAIN_ID {
-				counter, err := this.dao.CalculatePolyChainStatistic(chainStatistic.LastInCheckId, polyCheckId)
-				if err == nil {
-					logs.Info("computeChainStatistics,polychainid:", chainStatistic.ChainId, "poly.In:", chainStatistic.In, "poly.Out:", chainStatistic.Out, "poly.LastInCheckId1", chainStatistic.LastInCheckId, "polycounter:", counter)
-					chainStatistic.In = addDecimalInt64(counter, chainStatistic.In)
-					chainStatistic.Out = addDecimalInt64(counter, chainStatistic.Out)
-					chainStatistic.LastInCheckId = polyCheckId
-					chainStatistic.LastOutCheckId = polyCheckId
-				}
+		for _, out := range outChainStatistics {
+			if chainStatistic.ChainId == out.ChainId {
+				chainStatistic.Out = addDecimalInt64(chainStatistic.Out, out.Out)
 				break
 			}
 		}
-		err = this.dao.SaveChainStatistics(chainStatistics)
-		if err != nil {
-			logs.Error("qChainStatistic,computeChainStatisticAssets SaveChainStatistic error", err)
-		}
+		chainStatistic.LastInCheckId = nowInId
+		chainStatistic.LastOutCheckId = nowOutId
+	}
+	// the counters and their checkpoints are in the same rows, they are saved together
+	err = this.dao.SaveChainStatistics(chainStatistics)
+	if err != nil {
+		return fmt.Errorf("Failed to SaveChainStatistics %w", err)
 	}
 	return
 }
+
 func (this *Stats) computeChainStatisticAssets() (err error) {
 	logs.Info("computeChainStatisticAssets,start computeChainStatisticAssets")
 	computeChainStatistics := make([]*models.ChainStatistic, 0)
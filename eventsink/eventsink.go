@@ -0,0 +1,221 @@
+/*
+ * Copyright (C) 2020 The poly network Authors
+ * This file is part of The poly network library.
+ *
+ * The  poly network  is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU Lesser General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * The  poly network  is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU Lesser General Public License for more details.
+ * You should have received a copy of the GNU Lesser General Public License
+ * along with The poly network .  If not, see <http://www.gnu.org/licenses/>.
+ */
+
+package eventsink
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"poly-bridge/basedef"
+	"poly-bridge/conf"
+	"poly-bridge/crosschaindao/bridgedao"
+	"poly-bridge/models"
+	"poly-bridge/utils/backoff"
+	"sync"
+	"time"
+
+	"github.com/beego/beego/v2/core/logs"
+)
+
+const (
+	defaultInterval  = 5
+	defaultBatchSize = 100
+	maxRetryDelay    = 600
+)
+
+// EventSink receives the normalized cross chain events, Send may be called again with events it has already
+// received, so the consumers must be idempotent on (type, hash)
+type EventSink interface {
+	Name() string
+	Send(events []*models.CrossChainEvent) error
+	Close() error
+}
+
+func NewEventSink(cfg *conf.SinkConfig, redisConfig *conf.RedisConfig) (EventSink, error) {
+	switch cfg.Type {
+	case basedef.SINK_FILE:
+		return NewFileSink(cfg)
+	case basedef.SINK_WEBHOOK:
+		return NewWebhookSink(cfg)
+	case basedef.SINK_REDIS:
+		if cfg.RedisConfig != nil {
+			redisConfig = cfg.RedisConfig
+		}
+		return NewRedisSink(cfg, redisConfig)
+	default:
+		return nil, fmt.Errorf("unknown event sink type: %s", cfg.Type)
+	}
+}
+
+// EventRelay delivers the events queued in the event_outboxes table by UpdateEvents to the sinks,
+// an outbox row is removed only after all the sinks accepted it.
+type EventRelay struct {
+	context.Context
+	cancel context.CancelFunc
+	cfg    *conf.EventSinkConfig
+	dao    *bridgedao.BridgeDao
+	sinks  []EventSink
+	wg     sync.WaitGroup
+}
+
+var relay *EventRelay
+
+func StartEventSink(ctx context.Context, config *conf.Config) {
+	if config.Server != basedef.SERVER_POLY_BRIDGE {
+		panic("EventSink Only runs on bridge server")
+	}
+	if config.EventSinkConfig == nil || len(config.EventSinkConfig.Sinks) == 0 {
+		panic("Invalid EventSink config")
+	}
+	sinks := make([]EventSink, 0, len(config.EventSinkConfig.Sinks))
+	for _, cfg := range config.EventSinkConfig.Sinks {
+		sink, err := NewEventSink(cfg, config.RedisConfig)
+		if err != nil {
+			panic(err)
+		}
+		sinks = append(sinks, sink)
+	}
+	dao := bridgedao.NewBridgeDao(config.DBConfig, false)
+	relay = NewEventRelay(ctx, config.EventSinkConfig, dao, sinks)
+	relay.Start()
+}
+
+func StopEventSink() {
+	if relay != nil {
+		relay.Stop()
+	}
+}
+
+func NewEventRelay(ctx context.Context, cfg *conf.EventSinkConfig, dao *bridgedao.BridgeDao, sinks []EventSink) *EventRelay {
+	ctx, cancel := context.WithCancel(ctx)
+	return &EventRelay{
+		Context: ctx,
+		cancel:  cancel,
+		cfg:     cfg,
+		dao:     dao,
+		sinks:   sinks,
+	}
+}
+
+func (this *EventRelay) Start() {
+	logs.Info("start event sink")
+	this.wg.Add(1)
+	go this.run()
+}
+
+func (this *EventRelay) Stop() {
+	logs.Info("Stopping event sink")
+	this.cancel()
+	this.wg.Wait()
+	for _, sink := range this.sinks {
+		if err := sink.Close(); err != nil {
+			logs.Error("close event sink %s err: %v", sink.Name(), err)
+		}
+	}
+}
+
+func (this *EventRelay) run() {
+	defer this.wg.Done()
+	interval := this.cfg.Interval
+	if interval <= 0 {
+		interval = defaultInterval
+	}
+	ticker := time.NewTicker(time.Second * time.Duration(interval))
+	defer ticker.Stop()
+	for {
+		for this.Err() == nil && this.deliver() {
+		}
+		select {
+		case <-ticker.C:
+		case <-this.Done():
+			return
+		}
+	}
+}
+
+// deliver sends one batch of the outbox, it returns true if a full batch was delivered and more may be pending
+func (this *EventRelay) deliver() bool {
+	batchSize := this.cfg.BatchSize
+	if batchSize <= 0 {
+		batchSize = defaultBatchSize
+	}
+	outbox, err := this.dao.GetEventOutbox(batchSize)
+	if err != nil {
+		logs.Error("get event outbox err: %v", err)
+		return false
+	}
+	if len(outbox) == 0 {
+		return false
+	}
+	events := make([]*models.CrossChainEvent, 0, len(outbox))
+	ids := make([]int64, 0, len(outbox))
+	for _, item := range outbox {
+		event := new(models.CrossChainEvent)
+		if err := json.Unmarshal([]byte(item.Payload), event); err != nil {
+			logs.Error("event outbox %d payload err: %v", item.Id, err)
+			item.Status = basedef.OUTBOX_FAILED
+			item.Error = err.Error()
+			if err := this.dao.UpdateEventOutbox(item); err != nil {
+				logs.Error("update event outbox %d err: %v", item.Id, err)
+			}
+			continue
+		}
+		events = append(events, event)
+		ids = append(ids, item.Id)
+	}
+	for _, sink := range this.sinks {
+		if err := sink.Send(events); err != nil {
+			logs.Error("event sink %s send %d events err: %v", sink.Name(), len(events), err)
+			this.retryLater(outbox, err)
+			return false
+		}
+	}
+	if err := this.dao.RemoveEventOutbox(ids); err != nil {
+		logs.Error("remove event outbox err: %v", err)
+		return false
+	}
+	logs.Info("event sink delivered %d events", len(events))
+	return len(outbox) == batchSize
+}
+
+func (this *EventRelay) retryLater(outbox []*models.EventOutbox, sendErr error) {
+	errMsg := sendErr.Error()
+	if len(errMsg) > 512 {
+		errMsg = errMsg[:512]
+	}
+	for _, item := range outbox {
+		if item.Status != basedef.OUTBOX_PENDING {
+			continue
+		}
+		item.Attempts++
+		item.Error = errMsg
+		item.NextTime = time.Now().Unix() + retryDelay(item.Attempts)
+		if this.cfg.MaxAttempts > 0 && item.Attempts >= this.cfg.MaxAttempts {
+			item.Status = basedef.OUTBOX_FAILED
+			logs.Error("event outbox %d %s %s is parked after %d attempts", item.Id, item.Type, item.Hash, item.Attempts)
+		}
+		if err := this.dao.UpdateEventOutbox(item); err != nil {
+			logs.Error("update event outbox %d err: %v", item.Id, err)
+		}
+	}
+}
+
+// retryDelay doubles from 1 second up to maxRetryDelay seconds
+func retryDelay(attempts int) int64 {
+	return int64(backoff.Delay(attempts, time.Second, maxRetryDelay*time.Second) / time.Second)
+}
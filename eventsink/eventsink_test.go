@@ -0,0 +1,50 @@
+package eventsink
+
+import (
+	"bufio"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"poly-bridge/basedef"
+	"poly-bridge/conf"
+	"poly-bridge/models"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRetryDelay(t *testing.T) {
+	assert.Equal(t, int64(1), retryDelay(1))
+	assert.Equal(t, int64(8), retryDelay(4))
+	assert.Equal(t, int64(maxRetryDelay), retryDelay(20))
+}
+
+func TestFileSink(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "events.jsonl")
+	sink, err := NewEventSink(&conf.SinkConfig{Type: basedef.SINK_FILE, Path: path}, nil)
+	assert.NoError(t, err)
+	events := models.MakeCrossChainEvents(nil,
+		[]*models.SrcTransaction{{Hash: "s1", ChainId: 2, DstChainId: 6, SrcTransfer: &models.SrcTransfer{Asset: "a1", Amount: models.NewBigIntFromInt(100)}}},
+		[]*models.PolyTransaction{{Hash: "p1", ChainId: 0, SrcChainId: 2, DstChainId: 6, SrcHash: "s1"}},
+		nil)
+	assert.NoError(t, sink.Send(events))
+	assert.NoError(t, sink.Send(events[1:]))
+	assert.NoError(t, sink.Close())
+
+	file, err := os.Open(path)
+	assert.NoError(t, err)
+	defer file.Close()
+	lines := make([]*models.CrossChainEvent, 0)
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		event := new(models.CrossChainEvent)
+		assert.NoError(t, json.Unmarshal(scanner.Bytes(), event))
+		lines = append(lines, event)
+	}
+	assert.Len(t, lines, 3)
+	assert.Equal(t, basedef.EVENT_SRC_LOCK, lines[0].Type)
+	assert.Equal(t, "100", lines[0].Amount)
+	assert.Equal(t, basedef.EVENT_POLY_RELAY, lines[1].Type)
+	assert.Equal(t, "s1", lines[2].SrcHash)
+}
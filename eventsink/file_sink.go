@@ -0,0 +1,70 @@
+/*
+ * Copyright (C) 2020 The poly network Authors
+ * This file is part of The poly network library.
+ *
+ * The  poly network  is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU Lesser General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * The  poly network  is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU Lesser General Public License for more details.
+ * You should have received a copy of the GNU Lesser General Public License
+ * along with The poly network .  If not, see <http://www.gnu.org/licenses/>.
+ */
+
+package eventsink
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"os"
+	"poly-bridge/conf"
+	"poly-bridge/models"
+	"sync"
+)
+
+// FileSink appends the events to a file, one json object per line
+type FileSink struct {
+	path  string
+	file  *os.File
+	mutex sync.Mutex
+}
+
+func NewFileSink(cfg *conf.SinkConfig) (*FileSink, error) {
+	if cfg.Path == "" {
+		return nil, fmt.Errorf("file event sink path is empty")
+	}
+	file, err := os.OpenFile(cfg.Path, os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0644)
+	if err != nil {
+		return nil, err
+	}
+	return &FileSink{path: cfg.Path, file: file}, nil
+}
+
+func (sink *FileSink) Name() string {
+	return "file:" + sink.path
+}
+
+func (sink *FileSink) Send(events []*models.CrossChainEvent) error {
+	var buf bytes.Buffer
+	encoder := json.NewEncoder(&buf)
+	for _, event := range events {
+		if err := encoder.Encode(event); err != nil {
+			return err
+		}
+	}
+	sink.mutex.Lock()
+	defer sink.mutex.Unlock()
+	if _, err := sink.file.Write(buf.Bytes()); err != nil {
+		return err
+	}
+	return sink.file.Sync()
+}
+
+func (sink *FileSink) Close() error {
+	return sink.file.Close()
+}
@@ -0,0 +1,66 @@
+/*
+ * Copyright (C) 2020 The poly network Authors
+ * This file is part of The poly network library.
+ *
+ * The  poly network  is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU Lesser General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * The  poly network  is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU Lesser General Public License for more details.
+ * You should have received a copy of the GNU Lesser General Public License
+ * along with The poly network .  If not, see <http://www.gnu.org/licenses/>.
+ */
+
+package eventsink
+
+import (
+	"encoding/json"
+	"fmt"
+	"poly-bridge/cacheRedis"
+	"poly-bridge/conf"
+	"poly-bridge/models"
+)
+
+// RedisSink adds the events to a redis stream, consumers read it with consumer groups
+type RedisSink struct {
+	stream string
+	maxLen int64
+	redis  *cacheRedis.RedisCache
+}
+
+func NewRedisSink(cfg *conf.SinkConfig, redisConfig *conf.RedisConfig) (*RedisSink, error) {
+	if cfg.Stream == "" || redisConfig == nil {
+		return nil, fmt.Errorf("redis event sink stream or redis config is empty")
+	}
+	redis, err := cacheRedis.GetRedisClient(redisConfig)
+	if err != nil {
+		return nil, err
+	}
+	return &RedisSink{stream: cfg.Stream, maxLen: cfg.MaxLen, redis: redis}, nil
+}
+
+func (sink *RedisSink) Name() string {
+	return "redis:" + sink.stream
+}
+
+func (sink *RedisSink) Send(events []*models.CrossChainEvent) error {
+	for _, event := range events {
+		data, err := json.Marshal(event)
+		if err != nil {
+			return err
+		}
+		values := map[string]interface{}{"type": event.Type, "hash": event.Hash, "event": string(data)}
+		if err := sink.redis.XAdd(sink.stream, sink.maxLen, values); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (sink *RedisSink) Close() error {
+	return nil
+}
@@ -0,0 +1,86 @@
+/*
+ * Copyright (C) 2020 The poly network Authors
+ * This file is part of The poly network library.
+ *
+ * The  poly network  is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU Lesser General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * The  poly network  is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU Lesser General Public License for more details.
+ * You should have received a copy of the GNU Lesser General Public License
+ * along with The poly network .  If not, see <http://www.gnu.org/licenses/>.
+ */
+
+package eventsink
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"poly-bridge/conf"
+	"poly-bridge/models"
+	"time"
+)
+
+const defaultWebhookTimeout = 10
+
+// WebhookSink posts the events to an http endpoint as a json array, any non 2xx response is a failure
+type WebhookSink struct {
+	url     string
+	headers map[string]string
+	client  *http.Client
+}
+
+func NewWebhookSink(cfg *conf.SinkConfig) (*WebhookSink, error) {
+	if cfg.Url == "" {
+		return nil, fmt.Errorf("webhook event sink url is empty")
+	}
+	timeout := cfg.Timeout
+	if timeout <= 0 {
+		timeout = defaultWebhookTimeout
+	}
+	return &WebhookSink{
+		url:     cfg.Url,
+		headers: cfg.Headers,
+		client:  &http.Client{Timeout: time.Second * time.Duration(timeout)},
+	}, nil
+}
+
+func (sink *WebhookSink) Name() string {
+	return "webhook:" + sink.url
+}
+
+func (sink *WebhookSink) Send(events []*models.CrossChainEvent) error {
+	data, err := json.Marshal(events)
+	if err != nil {
+		return err
+	}
+	req, err := http.NewRequest("POST", sink.url, bytes.NewReader(data))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	for key, value := range sink.headers {
+		req.Header.Set(key, value)
+	}
+	resp, err := sink.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		body, _ := ioutil.ReadAll(resp.Body)
+		return fmt.Errorf("webhook response status: %d, body: %s", resp.StatusCode, string(body))
+	}
+	return nil
+}
+
+func (sink *WebhookSink) Close() error {
+	return nil
+}
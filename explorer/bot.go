@@ -77,7 +77,7 @@ func (c *BotController) BotPage() {
 		pageSize = 10
 	}
 
-	txs, count, err := transactions.GetStuckTxs(db, cacheRedis.Redis, pageSize, pageNo, from)
+	txs, count, err := transactions.GetStuckTxs(db, cache, pageSize, pageNo, from)
 	if err == nil {
 		// Check fee
 		hashes := make([]string, len(txs))
@@ -169,12 +169,12 @@ func (c *BotController) FinishTx() {
 	if token == conf.GlobalConfig.BotConfig.ApiToken {
 		switch status {
 		case "skip":
-			_, e := cacheRedis.Redis.Set(cacheRedis.MarkTxAsSkipPrefix+tx, "markAsSkipByBot", time.Hour*24*7)
+			_, e := cache.Set(cacheRedis.MarkTxAsSkipPrefix+tx, "markAsSkipByBot", time.Hour*24*7)
 			if e == nil {
 				resp = fmt.Sprintf("Success mark %s as skip", tx)
 			}
 		case "wait":
-			_, err = cacheRedis.Redis.Del(cacheRedis.MarkTxAsSkipPrefix + tx)
+			_, err = cache.Del(cacheRedis.MarkTxAsSkipPrefix + tx)
 			if err == nil {
 				resp = fmt.Sprintf("Success mark %s as wait", tx)
 			}
@@ -199,14 +199,14 @@ func (c *BotController) MarkUnMarkTxAsPaid() {
 	var err error
 	resp := ""
 	if token == conf.GlobalConfig.BotConfig.ApiToken {
-		exists, _ := cacheRedis.Redis.Exists(cacheRedis.MarkTxAsPaidPrefix + tx)
+		exists, _ := cache.Exists(cacheRedis.MarkTxAsPaidPrefix + tx)
 		if exists {
-			_, err = cacheRedis.Redis.Del(cacheRedis.MarkTxAsPaidPrefix + tx)
+			_, err = cache.Del(cacheRedis.MarkTxAsPaidPrefix + tx)
 			if err == nil {
 				resp = fmt.Sprintf("Success unmark %s as paid", tx)
 			}
 		} else {
-			_, e := cacheRedis.Redis.Set(cacheRedis.MarkTxAsPaidPrefix+tx, "markAsPaidByBot", time.Hour*12)
+			_, e := cache.Set(cacheRedis.MarkTxAsPaidPrefix+tx, "markAsPaidByBot", time.Hour*12)
 			if e == nil {
 				resp = fmt.Sprintf("Success mark %s as paid", tx)
 			}
@@ -325,16 +325,13 @@ func (c *BotController) checkFees(hashes []string) (fees map[string]models.Check
 			}
 		}
 
-		// get optimistic L1 fee on ethereum
-		if chainId == basedef.OPTIMISTIC_CROSSCHAIN_ID {
-			ethChainFee, ok2 := chain2Fees[basedef.ETHEREUM_CROSSCHAIN_ID]
-			if ok2 {
-				l1MinFee, _, _, e := fee.GetL1Fee(ethChainFee, chainId)
-				if e == nil {
-					minFee = new(big.Float).Add(minFee, l1MinFee)
-				}
-			}
+		// get the L1 data fee of a rollup
+		l1MinFee, _, _, e := fee.GetL1Fee(chainFee, fee.ChainFeesGetter(chain2Fees))
+		if e != nil {
+			logs.Error("Failed to get L1 fee for %d: %v", chainId, e)
+			continue
 		}
+		minFee = new(big.Float).Add(minFee, l1MinFee)
 
 		res := models.CheckFeeResult{}
 		if payFee.Cmp(minFee) >= 0 {
@@ -386,7 +383,7 @@ func (c *BotController) GetTxs() {
 		pageSize = 10
 	}
 
-	txs, count, err := transactions.GetStuckTxs(db, cacheRedis.Redis, pageSize, pageNo, from)
+	txs, count, err := transactions.GetStuckTxs(db, cache, pageSize, pageNo, from)
 	if err == nil {
 		// Check fee
 		hashes := make([]string, len(txs))
@@ -449,10 +446,10 @@ func (c *BotController) RunChecks() {
 	ticker := time.NewTicker(time.Second * time.Duration(interval))
 	for _ = range ticker.C {
 		var isCheckBot bool
-		botIp, err := cacheRedis.Redis.Get(cacheRedis.TxCheckBot)
+		botIp, err := cache.Get(cacheRedis.TxCheckBot)
 		if err != nil {
 			//lock
-			lock, e := cacheRedis.Redis.Lock(cacheRedis.TxCheckBot, LOCAL_IPV4, 2*time.Second*time.Duration(interval))
+			lock, e := cache.Lock(cacheRedis.TxCheckBot, LOCAL_IPV4, 2*time.Second*time.Duration(interval))
 			if e != nil {
 				return
 			}
@@ -460,7 +457,7 @@ func (c *BotController) RunChecks() {
 				isCheckBot = true
 			}
 		} else if botIp == LOCAL_IPV4 {
-			_, e := cacheRedis.Redis.Expire(cacheRedis.TxCheckBot, 2*time.Second*time.Duration(interval))
+			_, e := cache.Expire(cacheRedis.TxCheckBot, 2*time.Second*time.Duration(interval))
 			if e != nil {
 				return
 			}
@@ -486,7 +483,7 @@ func (c *BotController) checkTxs() (err error) {
 	from := conf.GlobalConfig.BotConfig.CheckFrom
 	pageSize := 20
 	pageNo := 0
-	txs, _, err := transactions.GetStuckTxs(db, cacheRedis.Redis, pageSize, pageNo, int(from))
+	txs, _, err := transactions.GetStuckTxs(db, cache, pageSize, pageNo, int(from))
 	if err != nil {
 		return err
 	}
@@ -505,7 +502,7 @@ func (c *BotController) checkTxs() (err error) {
 			continue
 		}
 		entry := models.ParseBotTx(srcPolyDstRelation, fees)
-		if existed, e2 := cacheRedis.Redis.Exists(cacheRedis.StuckTxAlarmHasSendPrefix + strings.ToLower(entry.Hash)); e2 == nil && existed {
+		if existed, e2 := cache.Exists(cacheRedis.StuckTxAlarmHasSendPrefix + strings.ToLower(entry.Hash)); e2 == nil && existed {
 			logs.Info("stuck TX alarm has been sent: %s", tx.SrcHash)
 			continue
 		}
@@ -554,7 +551,7 @@ func (c *BotController) checkTxs() (err error) {
 		if err != nil {
 			logs.Error("send tx stuck ding alarm error. hash: %s, err:", tx.SrcHash, err)
 		} else {
-			if _, e2 := cacheRedis.Redis.Set(cacheRedis.StuckTxAlarmHasSendPrefix+strings.ToLower(entry.Hash), "done", time.Hour*24*time.Duration(conf.GlobalConfig.BotConfig.CheckFrom)); e2 != nil {
+			if _, e2 := cache.Set(cacheRedis.StuckTxAlarmHasSendPrefix+strings.ToLower(entry.Hash), "done", time.Hour*24*time.Duration(conf.GlobalConfig.BotConfig.CheckFrom)); e2 != nil {
 				logs.Error("mark tx stuck alarm hash been sent error. hash: %s err: %s", entry.Hash, e2)
 			}
 		}
@@ -611,7 +608,7 @@ func (c *BotController) ListLargeTxPage() {
 	apiToken := c.Ctx.Input.Query("token")
 	largeTxs := make([]*basedef.LargeTx, 0)
 	if apiToken == conf.GlobalConfig.BotConfig.ApiToken {
-		ltxs, err := cacheRedis.Redis.LRange(cacheRedis.LargeTxList, -100, -1)
+		ltxs, err := cache.LRange(cacheRedis.LargeTxList, -100, -1)
 		if err == nil && len(ltxs) != 0 {
 			srcPolyDstRelations := make([]*models.SrcPolyDstRelation, 0)
 			if err = db.Debug().Table("src_transactions").
@@ -751,7 +748,7 @@ func (c *BotController) ListNodeStatusPage() {
 		nodeStatusesMap := make(map[string][]basedef.NodeStatus, 0)
 		chainNames := make([]string, 0)
 		for _, cfg := range conf.GlobalConfig.ChainNodes {
-			if dataStr, err := cacheRedis.Redis.Get(cacheRedis.NodeStatusPrefix + strconv.FormatUint(cfg.ChainId, 10)); err == nil {
+			if dataStr, err := cache.Get(cacheRedis.NodeStatusPrefix + strconv.FormatUint(cfg.ChainId, 10)); err == nil {
 				var nodeStatuses []basedef.NodeStatus
 				if e := json.Unmarshal([]byte(dataStr), &nodeStatuses); e != nil {
 					logs.Error("chain %s node status data Unmarshal error: ", cfg.ChainName, e)
@@ -819,12 +816,12 @@ func (c *BotController) IgnoreNodeStatusAlarm() {
 		dayNum, e := strconv.Atoi(day)
 		if e == nil && dayNum >= 0 {
 			if dayNum == 0 {
-				_, e = cacheRedis.Redis.Del(cacheRedis.IgnoreNodeStatusAlarmPrefix + node)
+				_, e = cache.Del(cacheRedis.IgnoreNodeStatusAlarmPrefix + node)
 				if e == nil {
 					resp = fmt.Sprintf("success cancel ignore alarm")
 				}
 			} else {
-				_, e2 := cacheRedis.Redis.Set(cacheRedis.IgnoreNodeStatusAlarmPrefix+node, "ignore", time.Hour*time.Duration(24*dayNum))
+				_, e2 := cache.Set(cacheRedis.IgnoreNodeStatusAlarmPrefix+node, "ignore", time.Hour*time.Duration(24*dayNum))
 				if e2 == nil {
 					resp = fmt.Sprintf("success ignore alarm for %d days", dayNum)
 				}
@@ -849,7 +846,7 @@ func (c *BotController) ListRelayerAccountStatus() {
 		accountStatusesMap := make(map[string][]basedef.RelayerAccountStatus, 0)
 		chainNames := make([]string, 0)
 		for _, cfg := range conf.GlobalConfig.ChainNodes {
-			if dataStr, err := cacheRedis.Redis.Get(cacheRedis.RelayerAccountStatusPrefix + cfg.ChainName); err == nil {
+			if dataStr, err := cache.Get(cacheRedis.RelayerAccountStatusPrefix + cfg.ChainName); err == nil {
 				var accountStatuses []basedef.RelayerAccountStatus
 				if e := json.Unmarshal([]byte(dataStr), &accountStatuses); e != nil {
 					logs.Error("%s relayer account status data Unmarshal error: ", cfg.ChainName, e)
@@ -23,7 +23,6 @@ import (
 	"fmt"
 	"github.com/beego/beego/v2/core/logs"
 	"github.com/beego/beego/v2/server/web"
-	"gorm.io/driver/mysql"
 	"gorm.io/gorm"
 	"gorm.io/gorm/logger"
 	"poly-bridge/basedef"
@@ -31,20 +30,23 @@ import (
 
 	"poly-bridge/conf"
 	"poly-bridge/models"
+	"poly-bridge/utils/database"
+	"poly-bridge/utils/transactions"
 	"strconv"
 )
 
 var db *gorm.DB
+var cache cacheRedis.Cache
 
-func Init() {
+func Init(c cacheRedis.Cache) {
+	cache = c
 	dbConfig := conf.GlobalConfig.DBConfig
 	Logger := logger.Default
 	if conf.GlobalConfig.RunMode == "dev" {
 		Logger = Logger.LogMode(logger.Info)
 	}
-	dbConn := fmt.Sprintf("%s:%s@tcp(%s)/%s?charset=utf8", dbConfig.User, dbConfig.Password, dbConfig.URL, dbConfig.Scheme)
 	var err error
-	db, err = gorm.Open(mysql.Open(dbConn), &gorm.Config{Logger: Logger})
+	db, err = database.OpenWithReplicas(dbConfig, Logger)
 	if err != nil {
 		panic(err)
 	}
@@ -113,11 +115,12 @@ func (c *ExplorerController) GetTokenTxList() {
 		c.Ctx.ResponseWriter.WriteHeader(400)
 		c.ServeJSON()
 	}
+	from, to := transferColumns()
 	transactionOnTokens := make([]*models.TransactionOnToken, 0)
-	res := db.Raw(`select a.hash, a.height, a.time, a.chain_id, b.from, b.to, b.amount, 1 as direct from src_transactions a inner join src_transfers b on a.hash = b.tx_hash where b.chain_id = ? and b.asset = ?
-		union select c.hash, c.height, c.time, c.chain_id, d.from, d.to, d.amount, 2 as direct from dst_transactions c inner join dst_transfers d on c.hash = d.tx_hash where d.chain_id = ? and d.asset = ?
-		order by height desc limit ?,?`,
-		tokenTxListReq.ChainId, tokenTxListReq.Token, tokenTxListReq.ChainId, tokenTxListReq.Token, (tokenTxListReq.PageNo-1)*tokenTxListReq.PageSize, tokenTxListReq.PageSize).
+	res := db.Raw(fmt.Sprintf(`select a.hash, a.height, a.time, a.chain_id, b.%[1]s, b.%[2]s, b.amount, 1 as direct from src_transactions a inner join src_transfers b on a.hash = b.tx_hash where b.chain_id = ? and b.asset = ?
+		union select c.hash, c.height, c.time, c.chain_id, d.%[1]s, d.%[2]s, d.amount, 2 as direct from dst_transactions c inner join dst_transfers d on c.hash = d.tx_hash where d.chain_id = ? and d.asset = ?
+		order by height desc limit ? offset ?`, from, to),
+		tokenTxListReq.ChainId, tokenTxListReq.Token, tokenTxListReq.ChainId, tokenTxListReq.Token, tokenTxListReq.PageSize, (tokenTxListReq.PageNo-1)*tokenTxListReq.PageSize).
 		Scan(&transactionOnTokens)
 	if res.RowsAffected == 0 {
 		c.Data["json"] = models.MakeErrorRsp(fmt.Sprintf("transactionOnTokens does not exist"))
@@ -155,19 +158,20 @@ func (c *ExplorerController) GetAddressTxList() {
 	}
 	addressTxListReq.Address, _ = basedef.Address2Hash(addressTxListReq.ChainId, addressTxListReq.Address)
 
+	from, to := transferColumns()
 	var res *gorm.DB
 	transactionOnAddresses := make([]*models.TransactionOnAddress, 0)
 	if addressTxListReq.ChainId == 0 {
-		res = db.Debug().Raw(`select a.hash, a.height, a.time, a.chain_id, b.from, b.to, b.amount, c.hash as token_hash, c.standard as token_standard, c.name as token_name, 1 as direct, m.precision, m.meta from src_transactions a left join src_transfers b on a.hash = b.tx_hash left join tokens c on b.asset = c.hash and b.chain_id = c.chain_id left JOIN token_basics m on c.token_basic_name = m.name where b.from = ? and c.standard = 0 
-		union select d.hash, d.height, d.time, d.chain_id, e.from, e.to, e.amount, f.hash as token_hash, f.standard as token_standard, f.name as token_name, 2 as direct, n.precision, n.meta from dst_transactions d left join dst_transfers e on d.hash = e.tx_hash left join tokens f on e.asset = f.hash and e.chain_id = f.chain_id left JOIN token_basics n on f.token_basic_name = n.name where e.to = ? and f.standard = 0
-		order by height desc limit ?,?`,
-			addressTxListReq.Address, addressTxListReq.Address, (addressTxListReq.PageNo-1)*addressTxListReq.PageSize, addressTxListReq.PageSize).
+		res = db.Debug().Raw(fmt.Sprintf(`select a.hash, a.height, a.time, a.chain_id, b.%[1]s, b.%[2]s, b.amount, c.hash as token_hash, c.standard as token_standard, c.name as token_name, 1 as direct, m.precision, m.meta from src_transactions a left join src_transfers b on a.hash = b.tx_hash left join tokens c on b.asset = c.hash and b.chain_id = c.chain_id left JOIN token_basics m on c.token_basic_name = m.name where b.%[1]s = ? and c.standard = 0 
+		union select d.hash, d.height, d.time, d.chain_id, e.%[1]s, e.%[2]s, e.amount, f.hash as token_hash, f.standard as token_standard, f.name as token_name, 2 as direct, n.precision, n.meta from dst_transactions d left join dst_transfers e on d.hash = e.tx_hash left join tokens f on e.asset = f.hash and e.chain_id = f.chain_id left JOIN token_basics n on f.token_basic_name = n.name where e.%[2]s = ? and f.standard = 0
+		order by height desc limit ? offset ?`, from, to),
+			addressTxListReq.Address, addressTxListReq.Address, addressTxListReq.PageSize, (addressTxListReq.PageNo-1)*addressTxListReq.PageSize).
 			Find(&transactionOnAddresses)
 	} else {
-		res = db.Debug().Raw(`select a.hash, a.height, a.time, a.chain_id, b.from, b.to, b.amount, c.hash as token_hash, c.standard as token_standard, c.name as token_name, 1 as direct, m.precision, m.meta from src_transactions a left join src_transfers b on a.hash = b.tx_hash left join tokens c on b.asset = c.hash and b.chain_id = c.chain_id left JOIN token_basics m on c.token_basic_name = m.name where b.from = ? and b.chain_id = ? and c.standard = 0
-		union select d.hash, d.height, d.time, d.chain_id, e.from, e.to, e.amount, f.hash as token_hash, f.standard as token_standard, f.name as token_name, 2 as direct, n.precision, n.meta from dst_transactions d left join dst_transfers e on d.hash = e.tx_hash left join tokens f on e.asset = f.hash and e.chain_id = f.chain_id left JOIN token_basics n on f.token_basic_name = n.name where e.to = ? and e.chain_id = ? and f.standard = 0
-		order by height desc limit ?,?`,
-			addressTxListReq.Address, addressTxListReq.ChainId, addressTxListReq.Address, addressTxListReq.ChainId, (addressTxListReq.PageNo-1)*addressTxListReq.PageSize, addressTxListReq.PageSize).
+		res = db.Debug().Raw(fmt.Sprintf(`select a.hash, a.height, a.time, a.chain_id, b.%[1]s, b.%[2]s, b.amount, c.hash as token_hash, c.standard as token_standard, c.name as token_name, 1 as direct, m.precision, m.meta from src_transactions a left join src_transfers b on a.hash = b.tx_hash left join tokens c on b.asset = c.hash and b.chain_id = c.chain_id left JOIN token_basics m on c.token_basic_name = m.name where b.%[1]s = ? and b.chain_id = ? and c.standard = 0
+		union select d.hash, d.height, d.time, d.chain_id, e.%[1]s, e.%[2]s, e.amount, f.hash as token_hash, f.standard as token_standard, f.name as token_name, 2 as direct, n.precision, n.meta from dst_transactions d left join dst_transfers e on d.hash = e.tx_hash left join tokens f on e.asset = f.hash and e.chain_id = f.chain_id left JOIN token_basics n on f.token_basic_name = n.name where e.%[2]s = ? and e.chain_id = ? and f.standard = 0
+		order by height desc limit ? offset ?`, from, to),
+			addressTxListReq.Address, addressTxListReq.ChainId, addressTxListReq.Address, addressTxListReq.ChainId, addressTxListReq.PageSize, (addressTxListReq.PageNo-1)*addressTxListReq.PageSize).
 			Find(&transactionOnAddresses)
 	}
 	if errors.Is(res.Error, gorm.ErrRecordNotFound) {
@@ -189,13 +193,13 @@ func (c *ExplorerController) GetAddressTxList() {
 		Counter int64
 	}{}
 	if addressTxListReq.ChainId == 0 {
-		res = db.Raw(`select sum(cnt) as counter from (select count(*) as cnt from src_transactions a left join src_transfers b on a.hash = b.tx_hash left join tokens c on b.asset = c.hash and b.chain_id = c.chain_id where b.from = ? and c.standard = 0 
-		union all select count(*) as cnt from dst_transactions d left join dst_transfers e on d.hash = e.tx_hash left join tokens f on e.asset = f.hash and e.chain_id = f.chain_id where e.to = ? and f.standard = 0) as u`,
+		res = db.Raw(fmt.Sprintf(`select sum(cnt) as counter from (select count(*) as cnt from src_transactions a left join src_transfers b on a.hash = b.tx_hash left join tokens c on b.asset = c.hash and b.chain_id = c.chain_id where b.%[1]s = ? and c.standard = 0 
+		union all select count(*) as cnt from dst_transactions d left join dst_transfers e on d.hash = e.tx_hash left join tokens f on e.asset = f.hash and e.chain_id = f.chain_id where e.%[2]s = ? and f.standard = 0) as u`, from, to),
 			addressTxListReq.Address, addressTxListReq.Address).
 			Find(&counter)
 	} else {
-		res = db.Raw(`select sum(cnt) as counter from (select count(*) as cnt from src_transactions a left join src_transfers b on a.hash = b.tx_hash left join tokens c on b.asset = c.hash and b.chain_id = c.chain_id where b.from = ? and b.chain_id = ? and c.standard = 0 
-		union all select count(*) as cnt from dst_transactions d left join dst_transfers e on d.hash = e.tx_hash left join tokens f on e.asset = f.hash and e.chain_id = f.chain_id where e.to = ? and e.chain_id = ? and f.standard = 0) as u`,
+		res = db.Raw(fmt.Sprintf(`select sum(cnt) as counter from (select count(*) as cnt from src_transactions a left join src_transfers b on a.hash = b.tx_hash left join tokens c on b.asset = c.hash and b.chain_id = c.chain_id where b.%[1]s = ? and b.chain_id = ? and c.standard = 0 
+		union all select count(*) as cnt from dst_transactions d left join dst_transfers e on d.hash = e.tx_hash left join tokens f on e.asset = f.hash and e.chain_id = f.chain_id where e.%[2]s = ? and e.chain_id = ? and f.standard = 0) as u`, from, to),
 			addressTxListReq.Address, addressTxListReq.ChainId, addressTxListReq.Address, addressTxListReq.ChainId).
 			Find(&counter)
 	}
@@ -210,6 +214,11 @@ func (c *ExplorerController) GetAddressTxList() {
 	c.ServeJSON()
 }
 
+// transferColumns returns the from and to column names of the transfers quoted for the dialect, both are reserved words
+func transferColumns() (string, string) {
+	return db.Statement.Quote("from"), db.Statement.Quote("to")
+}
+
 // TODO GetCrossTxList gets Cross transaction list from start to end (to be optimized)
 func (c *ExplorerController) GetCrossTxList() {
 	// get parameter
@@ -249,7 +258,7 @@ func (c *ExplorerController) GetCrossTxList() {
 	}
 
 	logs.Info("GetCrossTxList count counter")
-	counter, err := cacheRedis.Redis.GetCrossTxCounter()
+	counter, err := cacheRedis.GetCrossTxCounter(cache)
 	if err != nil {
 		logs.Info(err)
 		res := db.Debug().Model(&models.PolyTransaction{}).
@@ -262,7 +271,7 @@ func (c *ExplorerController) GetCrossTxList() {
 			c.ServeJSON()
 			return
 		}
-		err = cacheRedis.Redis.SetCrossTxCounter(counter)
+		err = cacheRedis.SetCrossTxCounter(cache, counter, conf.GlobalConfig.RedisConfig.GetExpiration())
 		if err != nil {
 			logs.Error(err)
 		}
@@ -282,42 +291,21 @@ func (c *ExplorerController) GetCrossTx() {
 	}
 	crossTxReq.TxHash = c.Ctx.Input.Query("txhash")
 	fmt.Println("crossTxReq.TxHash", crossTxReq.TxHash)
-	relations := make([]*models.PolyTxRelation, 0)
-	tx := &struct{ Hash string }{}
-	err := db.Raw(`select hash from src_transactions where hash=?
-		UNION select s.hash from src_transactions s left join poly_transactions p on p.src_hash=s.hash where p.hash=?
-		UNION select s.hash from src_transactions s left join poly_transactions p on p.src_hash=s.hash
-		left join dst_transactions d on d.poly_hash=p.hash where d.hash=?`,
-		crossTxReq.TxHash, crossTxReq.TxHash, crossTxReq.TxHash).First(tx).Error
-	if errors.Is(err, gorm.ErrRecordNotFound) || tx.Hash == "" {
+	crossChainTx, legs, err := transactions.FindCrossChainTx(db, "(src_hash = ? or poly_hash = ? or dst_hash = ?) and standard = ?",
+		crossTxReq.TxHash, crossTxReq.TxHash, crossTxReq.TxHash, 0)
+	if err != nil || crossChainTx == nil {
 		c.Data["json"] = models.MakeErrorRsp(fmt.Sprintf("relations does not exist"))
 		c.Ctx.ResponseWriter.WriteHeader(400)
 		c.ServeJSON()
 		return
 	}
-	res := db.Model(&models.SrcTransaction{}).
-		Select("src_transactions.hash as src_hash, poly_transactions.hash as poly_hash, dst_transactions.hash as dst_hash, src_transactions.chain_id as chain_id, src_transfers.asset as token_hash, src_transfers.dst_chain_id as to_chain_id, src_transfers.dst_asset as to_token_hash, dst_transfers.chain_id as dst_chain_id, dst_transfers.asset as dst_token_hash").
-		Where("src_transactions.hash = ? AND src_transactions.standard = ?", tx.Hash, 0).
-		Joins("left join src_transfers on src_transactions.hash = src_transfers.tx_hash").
-		Joins("left join poly_transactions on src_transactions.hash = poly_transactions.src_hash").
-		Joins("left join dst_transactions on poly_transactions.hash = dst_transactions.poly_hash").
-		Joins("left join dst_transfers on dst_transfers.tx_hash = dst_transactions.hash").
-		Find(&relations)
-	if errors.Is(res.Error, gorm.ErrRecordNotFound) {
-		c.Data["json"] = &models.AddressTxListResp{
-			Total: 0,
-		}
-		c.Ctx.ResponseWriter.WriteHeader(200)
-		c.ServeJSON()
-		return
-	}
-	if res.RowsAffected == 0 {
-		c.Data["json"] = models.MakeErrorRsp(fmt.Sprintf("relations does not exist"))
-		c.Ctx.ResponseWriter.WriteHeader(400)
-		c.ServeJSON()
-		return
+	relation := &models.PolyTxRelation{
+		SrcHash:   crossChainTx.SrcHash,
+		PolyHash:  crossChainTx.PolyHash,
+		DstHash:   crossChainTx.DstHash,
+		ChainId:   crossChainTx.SrcChainId,
+		TokenHash: crossChainTx.TokenHash,
 	}
-	relation := relations[0]
 	token := new(models.Token)
 	err = db.Where("hash = ? and chain_id =?", relation.TokenHash, relation.ChainId).
 		First(token).Error
@@ -331,7 +319,7 @@ func (c *ExplorerController) GetCrossTx() {
 		}
 	}
 	srcTransaction := new(models.SrcTransaction)
-	err = db.Where("hash = ?", relation.SrcHash).
+	err = legs.Where("hash = ?", relation.SrcHash).
 		Preload("SrcTransfer").
 		First(srcTransaction).Error
 	if err == nil {
@@ -339,14 +327,16 @@ func (c *ExplorerController) GetCrossTx() {
 		if srcTransaction.SrcTransfer == nil {
 			srcTransaction.SrcTransfer = new(models.SrcTransfer)
 		}
+		relation.ToChainId = srcTransaction.SrcTransfer.DstChainId
+		relation.ToTokenHash = srcTransaction.SrcTransfer.DstAsset
 	}
 	polyTransaction := new(models.PolyTransaction)
-	err = db.Where("hash=?", relation.PolyHash).First(polyTransaction).Error
+	err = legs.Where("hash=?", relation.PolyHash).First(polyTransaction).Error
 	if err == nil {
 		relation.PolyTransaction = polyTransaction
 	}
 	dstTransaction := new(models.DstTransaction)
-	err = db.Where("hash=?", relation.DstHash).
+	err = legs.Where("hash=?", relation.DstHash).
 		Preload("DstTransfer").
 		First(dstTransaction).Error
 	if err == nil {
@@ -354,6 +344,8 @@ func (c *ExplorerController) GetCrossTx() {
 		if dstTransaction.DstTransfer == nil {
 			dstTransaction.DstTransfer = new(models.DstTransfer)
 		}
+		relation.DstChainId = dstTransaction.DstTransfer.ChainId
+		relation.DstTokenHash = dstTransaction.DstTransfer.Asset
 	}
 
 	if srcTransaction.DstChainId == basedef.O3_CROSSCHAIN_ID {
@@ -432,7 +424,7 @@ func (c *ExplorerController) GetTransferStatistic() {
 	chainStatistics := make([]*models.ChainStatistic, 0)
 	chains := make([]*models.Chain, 0)
 	if transferStatisticReq.Chain == uint64(0) {
-		resp, err := cacheRedis.Redis.GetAllTransferResp()
+		resp, err := cacheRedis.GetAllTransferResp(cache)
 		if err == nil && resp != nil {
 			c.Data["json"] = resp
 			c.ServeJSON()
@@ -460,7 +452,7 @@ func (c *ExplorerController) GetTransferStatistic() {
 			return
 		}
 		resp = models.MakeTransferInfoResp(tokenStatistics, chainStatistics, chains)
-		err = cacheRedis.Redis.SetAllTransferResp(resp)
+		err = cacheRedis.SetAllTransferResp(cache, resp)
 		if err != nil {
 			logs.Error("redis.SetAllTransferResp err", err)
 		}
@@ -500,7 +492,7 @@ func (c *ExplorerController) GetTransferStatistic() {
 
 func (c *ExplorerController) GetLockTokenList() {
 	lockTokenResps := make([]*models.LockTokenResp, 0)
-	res := db.Raw("select  chain_id,CONVERT(sum(in_amount_usd), DECIMAL(37, 0)) as in_amount_usd,COUNT(DISTINCT(`hash`)) as token_num,COUNT(DISTINCT(`item_proxy`)) as proxy_num from lock_token_statistics where in_amount_usd >0 group by chain_id").
+	res := db.Raw("select  chain_id," + database.SumAmount(db, "in_amount_usd") + " as in_amount_usd,COUNT(DISTINCT(hash)) as token_num,COUNT(DISTINCT(item_proxy)) as proxy_num from lock_token_statistics where in_amount_usd not in ('', '0') group by chain_id").
 		Scan(&lockTokenResps)
 	if res.RowsAffected == 0 {
 		c.Data["json"] = models.MakeErrorRsp(fmt.Sprintf("GetLockTokenList does not exist"))
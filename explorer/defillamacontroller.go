@@ -15,7 +15,7 @@ type DefiLlamaController struct {
 }
 
 func getTVLAmount(chain uint64) (amount string, err error) {
-	amount, err = cacheRedis.Redis.GetChainTvl(chain)
+	amount, err = cacheRedis.GetChainTvl(cache, chain)
 	if err == nil {
 		logs.Info("getTVLAmount chain with Redis,chain:", chain, "amount:", amount)
 		return
@@ -28,7 +28,7 @@ func getTVLAmount(chain uint64) (amount string, err error) {
 		tVLChain.Add(tVLChain, &lockToken.InAmountUsd.Int)
 	}
 	amount = decimal.NewFromBigInt(tVLChain, -4).StringFixed(2)
-	err = cacheRedis.Redis.SetChainTvl(chain, amount)
+	err = cacheRedis.SetChainTvl(cache, chain, amount)
 	if err != nil {
 		logs.Error("getTVLAmount SetChainTvl err,chain:", chain, err)
 	}
@@ -37,7 +37,7 @@ func getTVLAmount(chain uint64) (amount string, err error) {
 
 func getTVLtotalAmount() (amount string, err error) {
 	totalChain := uint64(0)
-	amount, err = cacheRedis.Redis.GetChainTvl(totalChain)
+	amount, err = cacheRedis.GetChainTvl(cache, totalChain)
 	if err == nil {
 		logs.Info("getTVLtotalAmount chain with Redis,chain:", totalChain, "amount:", amount)
 		return
@@ -52,7 +52,7 @@ func getTVLtotalAmount() (amount string, err error) {
 		}
 	}
 	amount = decimal.NewFromBigInt(tVLChain, -4).StringFixed(2)
-	err = cacheRedis.Redis.SetChainTvl(totalChain, amount)
+	err = cacheRedis.SetChainTvl(cache, totalChain, amount)
 	if err != nil {
 		logs.Error("getTVLtotalAmount SetChainTvl err,chain:", totalChain, err)
 	}
@@ -33,7 +33,9 @@ require (
 	github.com/tendermint/tendermint v0.33.7
 	github.com/urfave/cli v1.22.4
 	gorm.io/driver/mysql v1.0.3
-	gorm.io/gorm v1.20.8
+	gorm.io/driver/postgres v1.0.8
+	gorm.io/driver/sqlite v1.1.4
+	gorm.io/gorm v1.20.12
 )
 
 replace (
@@ -0,0 +1,67 @@
+package http
+
+import (
+	"encoding/json"
+	"fmt"
+	"github.com/beego/beego/v2/server/web"
+	"gorm.io/gorm"
+	"poly-bridge/models"
+)
+
+type AuditController struct {
+	web.Controller
+}
+
+func (c *AuditController) TransferAuditFindings() {
+	var transferAuditFindingsReq models.TransferAuditFindingsReq
+	if err := json.Unmarshal(c.Ctx.Input.RequestBody, &transferAuditFindingsReq); err != nil || transferAuditFindingsReq.PageSize <= 0 {
+		c.Data["json"] = models.MakeErrorRsp(fmt.Sprintf("request parameter is invalid!"))
+		c.Ctx.ResponseWriter.WriteHeader(400)
+		c.ServeJSON()
+		return
+	}
+	query := func(tx *gorm.DB) *gorm.DB {
+		tx = tx.Model(&models.TransferAuditFinding{})
+		if transferAuditFindingsReq.Kind != "" {
+			tx = tx.Where("kind = ?", transferAuditFindingsReq.Kind)
+		}
+		if transferAuditFindingsReq.DstChainId != 0 {
+			tx = tx.Where("dst_chain_id = ?", transferAuditFindingsReq.DstChainId)
+		}
+		return tx
+	}
+	findings := make([]*models.TransferAuditFinding, 0)
+	query(db).Limit(transferAuditFindingsReq.PageSize).Offset(transferAuditFindingsReq.PageSize * transferAuditFindingsReq.PageNo).Order("id desc").Find(&findings)
+	var findingNum int64
+	query(db).Count(&findingNum)
+	c.Data["json"] = models.MakeTransferAuditFindingsRsp(transferAuditFindingsReq.PageSize, transferAuditFindingsReq.PageNo,
+		(int(findingNum)+transferAuditFindingsReq.PageSize-1)/transferAuditFindingsReq.PageSize, int(findingNum), findings)
+	c.ServeJSON()
+}
+
+func (c *AuditController) TransferAuditTotals() {
+	var transferAuditTotalsReq models.TransferAuditTotalsReq
+	if err := json.Unmarshal(c.Ctx.Input.RequestBody, &transferAuditTotalsReq); err != nil || transferAuditTotalsReq.PageSize <= 0 {
+		c.Data["json"] = models.MakeErrorRsp(fmt.Sprintf("request parameter is invalid!"))
+		c.Ctx.ResponseWriter.WriteHeader(400)
+		c.ServeJSON()
+		return
+	}
+	query := func(tx *gorm.DB) *gorm.DB {
+		tx = tx.Model(&models.TransferAuditTotal{})
+		if transferAuditTotalsReq.SrcChainId != 0 {
+			tx = tx.Where("src_chain_id = ?", transferAuditTotalsReq.SrcChainId)
+		}
+		if transferAuditTotalsReq.DstChainId != 0 {
+			tx = tx.Where("dst_chain_id = ?", transferAuditTotalsReq.DstChainId)
+		}
+		return tx
+	}
+	totals := make([]*models.TransferAuditTotal, 0)
+	query(db).Limit(transferAuditTotalsReq.PageSize).Offset(transferAuditTotalsReq.PageSize * transferAuditTotalsReq.PageNo).Order("id asc").Find(&totals)
+	var totalNum int64
+	query(db).Count(&totalNum)
+	c.Data["json"] = models.MakeTransferAuditTotalsRsp(transferAuditTotalsReq.PageSize, transferAuditTotalsReq.PageNo,
+		(int(totalNum)+transferAuditTotalsReq.PageSize-1)/transferAuditTotalsReq.PageSize, int(totalNum), totals)
+	c.ServeJSON()
+}
@@ -49,7 +49,7 @@ func (c *ChainHealthController) Health() {
 	for _, chainId := range chainHealthReq.ChainIds {
 		chainHealthRsp.Result[chainId] = true
 		var chainStatus basedef.ChainStatus
-		dataStr, err := cacheRedis.Redis.Get(cacheRedis.ChainStatusPrefix + strconv.FormatUint(chainId, 10))
+		dataStr, err := cache.Get(cacheRedis.ChainStatusPrefix + strconv.FormatUint(chainId, 10))
 		if err == nil {
 			err = json.Unmarshal([]byte(dataStr), &chainStatus)
 		}
@@ -31,6 +31,7 @@ import (
 
 	"github.com/beego/beego/v2/core/logs"
 	"github.com/beego/beego/v2/server/web"
+	"gorm.io/gorm/clause"
 )
 
 type FeeController struct {
@@ -52,105 +53,19 @@ func (c *FeeController) GetFee() {
 		c.Ctx.ResponseWriter.WriteHeader(400)
 		c.ServeJSON()
 	}
-	token := new(models.Token)
-	res := db.Where("hash = ? and chain_id = ?", getFeeReq.Hash, getFeeReq.SrcChainId).Preload("TokenBasic").First(token)
-	if res.RowsAffected == 0 {
-		c.Data["json"] = models.MakeErrorRsp(fmt.Sprintf("chain: %d does not have token: %s", getFeeReq.SrcChainId, getFeeReq.Hash))
+	calculation, err := calculateFee(&getFeeReq, nil)
+	if err != nil {
+		c.Data["json"] = models.MakeErrorRsp(err.Error())
 		c.Ctx.ResponseWriter.WriteHeader(400)
 		c.ServeJSON()
 		return
 	}
+	token, chainFee := calculation.token, calculation.chainFee
+	usdtFee, tokenFee, tokenFeeWithPrecision := calculation.usdtFee, calculation.tokenFee, calculation.tokenFeeWithPrecision
 	feeTokenPrecison := token.Precision
-	if token.TokenBasic.Price == 0 {
-		c.Data["json"] = models.MakeErrorRsp(fmt.Sprintf("token: %v price is 0", token.TokenBasic.Name))
-		c.Ctx.ResponseWriter.WriteHeader(400)
-		c.ServeJSON()
-		return
-	}
-	chainFee := new(models.ChainFee)
-	res = db.Where("chain_id = ?", getFeeReq.DstChainId).Preload("TokenBasic").First(chainFee)
-	if res.RowsAffected == 0 {
-		c.Data["json"] = models.MakeErrorRsp(fmt.Sprintf("chain: %d does not have fee", getFeeReq.DstChainId))
-		c.Ctx.ResponseWriter.WriteHeader(400)
-		c.ServeJSON()
-		return
-	}
-	//check if rank of src token is risky, if so, change the proxyFee value
-	proxyFee := new(big.Float).SetInt(&chainFee.ProxyFee.Int)
-	//check if any coin marked as dying in redis
-	if exists, _ := cacheRedis.Redis.Exists(cacheRedis.MarkTokenAsDying + token.TokenBasicName); exists {
-		logs.Info("this token is dying", token.TokenBasicName)
-		if val, err := cacheRedis.Redis.Get(cacheRedis.MarkTokenAsDying + token.TokenBasicName); err == nil {
-			manualRatio, ok := big.NewFloat(0.0).SetString(val)
-			if ok {
-				proxyFee.Mul(proxyFee, manualRatio)
-			} else {
-				logs.Error("get dying token manualRatio fail, tokenbasicname: %s", token.TokenBasicName)
-			}
-		}
-	} else {
-		if token.TokenBasic.Rank > riskyCoinRankThreshold {
-			proxyFee.Mul(proxyFee, riskyCoinRisingRate)
-		}
-	}
-	proxyFee = new(big.Float).Quo(proxyFee, new(big.Float).SetInt64(basedef.FEE_PRECISION))
-	proxyFee = new(big.Float).Quo(proxyFee, new(big.Float).SetInt64(basedef.Int64FromFigure(int(chainFee.TokenBasic.Precision))))
-	usdtFee := new(big.Float).Mul(proxyFee, new(big.Float).SetInt64(chainFee.TokenBasic.Price))
-	usdtFee = new(big.Float).Quo(usdtFee, new(big.Float).SetInt64(basedef.PRICE_PRECISION))
-	tokenFee := new(big.Float).Mul(usdtFee, new(big.Float).SetInt64(basedef.PRICE_PRECISION))
-	tokenFee = new(big.Float).Quo(tokenFee, new(big.Float).SetInt64(token.TokenBasic.Price))
-
-	isNftSwap := true
-	if len(getFeeReq.SwapTokenHash) != 0 {
-		swapToken := new(models.Token)
-		res := db.Where("hash = ? and chain_id = ?", getFeeReq.SwapTokenHash, getFeeReq.SrcChainId, getFeeReq.SrcChainId).First(swapToken)
-		if res.RowsAffected != 0 {
-			if swapToken.Standard == models.TokenTypeErc20 {
-				isNftSwap = false
-			}
-		}
-	}
-	if isNftSwap {
-		for _, cfg := range conf.GlobalConfig.FeeListenConfig {
-			if cfg.ChainId == getFeeReq.DstChainId {
-				if cfg.NftRatio > 0 {
-					nftRatio := new(big.Float).Quo(new(big.Float).SetInt64(cfg.NftRatio), new(big.Float).SetInt64(100))
-					usdtFee = new(big.Float).Mul(usdtFee, nftRatio)
-					tokenFee = new(big.Float).Mul(tokenFee, nftRatio)
-				}
-				break
-			}
-		}
-	}
-	tokenFeeWithPrecision := new(big.Float).Mul(tokenFee, new(big.Float).SetInt64(basedef.Int64FromFigure(int(token.Precision))))
-
 	isNative := false
 	nativeTokenAmount := new(big.Float).SetInt64(0)
-	// get optimistic L1 fee on ethereum
-	if basedef.OPTIMISTIC_CROSSCHAIN_ID == getFeeReq.DstChainId {
-		ethChainFee := new(models.ChainFee)
-		res = db.Where("chain_id = ?", basedef.ETHEREUM_CROSSCHAIN_ID).Preload("TokenBasic").First(ethChainFee)
-		if res.RowsAffected == 0 {
-			c.Data["json"] = models.MakeErrorRsp(fmt.Sprintf("chain: %d does not have fee", basedef.ETHEREUM_CROSSCHAIN_ID))
-			c.Ctx.ResponseWriter.WriteHeader(400)
-			c.ServeJSON()
-			return
-		}
-
-		_, l1UsdtFee, _, err := fee.GetL1Fee(ethChainFee, getFeeReq.DstChainId)
-		if err != nil {
-			c.Data["json"] = models.MakeErrorRsp(fmt.Sprintf("get ethereum L1 fee failed. err=%v", err))
-			c.Ctx.ResponseWriter.WriteHeader(400)
-			c.ServeJSON()
-			return
-		}
-
-		l1TokenFee := new(big.Float).Mul(l1UsdtFee, new(big.Float).SetInt64(basedef.PRICE_PRECISION))
-		l1TokenFee = new(big.Float).Quo(l1TokenFee, new(big.Float).SetInt64(token.TokenBasic.Price))
-		l1TokenFeeWithPrecision := new(big.Float).Mul(l1TokenFee, new(big.Float).SetInt64(basedef.Int64FromFigure(int(token.Precision))))
-		tokenFee = new(big.Float).Add(tokenFee, l1TokenFee)
-		tokenFeeWithPrecision = new(big.Float).Add(tokenFeeWithPrecision, l1TokenFeeWithPrecision)
-	}
+	quoteId, quoteServerId, quoteExpiry := issueFeeQuote(&getFeeReq, c.Ctx.Input.IP(), usdtFee, tokenFeeWithPrecision)
 
 	{
 		chainFeeJson, _ := json.Marshal(chainFee)
@@ -163,70 +78,34 @@ func (c *FeeController) GetFee() {
 
 	if getFeeReq.SwapTokenHash != "" {
 		//check cross native token
-		nativeChainFee := new(models.ChainFee)
-		res = db.Where("chain_id = ?", getFeeReq.SrcChainId).Preload("TokenBasic").
-			First(nativeChainFee)
-		if res.RowsAffected == 0 {
-			c.Data["json"] = models.MakeErrorRsp(fmt.Sprintf("chain: %d does not have fee", getFeeReq.SrcChainId))
+		isNative, nativeTokenAmount, err = calculateNativeFee(&getFeeReq, nil)
+		if err != nil {
+			c.Data["json"] = models.MakeErrorRsp(err.Error())
 			c.Ctx.ResponseWriter.WriteHeader(400)
 			c.ServeJSON()
 			return
 		}
-		preloadTokens := make([]*models.Token, 0)
-		res = db.Where("token_basic_name = ?", nativeChainFee.TokenBasicName).
-			Find(&preloadTokens)
-		nativeChainFee.TokenBasic.Tokens = preloadTokens
-		if nativeChainFee.TokenBasic != nil && nativeChainFee.TokenBasic.Tokens != nil {
-			for _, v := range nativeChainFee.TokenBasic.Tokens {
-				if v.ChainId == getFeeReq.SrcChainId && strings.EqualFold(v.Hash, getFeeReq.SwapTokenHash) {
-					isNative = true
-					nativeFeeAmount := new(big.Float).SetInt(&nativeChainFee.MaxFee.Int)
-					nativeFeeAmount = new(big.Float).Quo(nativeFeeAmount, new(big.Float).SetInt64(basedef.FEE_PRECISION))
-					nativeFeeAmount = new(big.Float).Quo(nativeFeeAmount, new(big.Float).SetInt64(basedef.Int64FromFigure(int(nativeChainFee.TokenBasic.Precision))))
-					if getFeeReq.SrcChainId == basedef.OPTIMISTIC_CROSSCHAIN_ID {
-						ethChainFee := new(models.ChainFee)
-						res = db.Where("chain_id = ?", basedef.ETHEREUM_CROSSCHAIN_ID).Preload("TokenBasic").First(ethChainFee)
-						if res.RowsAffected == 0 {
-							c.Data["json"] = models.MakeErrorRsp(fmt.Sprintf("chain: %d does not have fee", basedef.ETHEREUM_CROSSCHAIN_ID))
-							c.Ctx.ResponseWriter.WriteHeader(400)
-							c.ServeJSON()
-							return
-						}
-						_, _, l1FeeAmount, err := fee.GetL1Fee(ethChainFee, getFeeReq.SrcChainId)
-						if err != nil {
-							c.Data["json"] = models.MakeErrorRsp(fmt.Sprintf("get ethereum L1 fee failed. err=%v", err))
-							c.Ctx.ResponseWriter.WriteHeader(400)
-							c.ServeJSON()
-							return
-						}
-						nativeFeeAmount = new(big.Float).Add(nativeFeeAmount, l1FeeAmount)
-					}
-					nativeTokenAmount = nativeFeeAmount
-					break
-				}
-			}
-		}
 
 		tokenMap := new(models.TokenMap)
 		res := db.Where("src_token_hash = ? and src_chain_id = ? and dst_chain_id = ?", getFeeReq.SwapTokenHash, getFeeReq.SrcChainId, getFeeReq.DstChainId).Preload("DstToken").First(tokenMap)
 		if res.RowsAffected == 0 {
 			c.Data["json"] = models.MakeGetFeeRsp(getFeeReq.SrcChainId, getFeeReq.Hash, getFeeReq.DstChainId, usdtFee, tokenFee, tokenFeeWithPrecision,
-				getFeeReq.SwapTokenHash, new(big.Float).SetUint64(0), new(big.Float).SetUint64(0), isNative, nativeTokenAmount, feeTokenPrecison)
+				getFeeReq.SwapTokenHash, new(big.Float).SetUint64(0), new(big.Float).SetUint64(0), isNative, nativeTokenAmount, feeTokenPrecison).WithQuote(quoteId, quoteServerId, quoteExpiry)
 			c.ServeJSON()
 			return
 		}
 		if tokenMap.DstChainId != getFeeReq.DstChainId || tokenMap.DstToken == nil {
 			c.Data["json"] = models.MakeGetFeeRsp(getFeeReq.SrcChainId, getFeeReq.Hash, getFeeReq.DstChainId, usdtFee, tokenFee, tokenFeeWithPrecision,
-				getFeeReq.SwapTokenHash, new(big.Float).SetUint64(0), new(big.Float).SetUint64(0), isNative, nativeTokenAmount, feeTokenPrecison)
+				getFeeReq.SwapTokenHash, new(big.Float).SetUint64(0), new(big.Float).SetUint64(0), isNative, nativeTokenAmount, feeTokenPrecison).WithQuote(quoteId, quoteServerId, quoteExpiry)
 			c.ServeJSON()
 			return
 		}
 		tokenBalance, _ := new(big.Int).SetString("100000000000000000000000000000", 10)
 		if tokenMap.DstChainId != basedef.PLT_CROSSCHAIN_ID {
-			tokenBalance, err = cacheRedis.Redis.GetTokenBalance(tokenMap.SrcChainId, tokenMap.DstChainId, tokenMap.DstTokenHash)
+			tokenBalance, err = cacheRedis.GetTokenBalance(cache, tokenMap.SrcChainId, tokenMap.DstChainId, tokenMap.DstTokenHash)
 			if err != nil {
 				ethChains := make(map[uint64]struct{})
-				for _, chainId := range basedef.ETH_CHAINS {
+				for _, chainId := range basedef.EthChains() {
 					ethChains[chainId] = struct{}{}
 				}
 				if _, ok := ethChains[tokenMap.DstChainId]; ok {
@@ -254,19 +133,19 @@ func (c *FeeController) GetFee() {
 				}
 
 				if err != nil {
-					tokenBalance, err = cacheRedis.Redis.GetLongTokenBalance(tokenMap.SrcChainId, tokenMap.DstChainId, tokenMap.DstTokenHash)
+					tokenBalance, err = cacheRedis.GetLongTokenBalance(cache, tokenMap.SrcChainId, tokenMap.DstChainId, tokenMap.DstTokenHash)
 					if err != nil {
 						c.Data["json"] = models.MakeGetFeeRsp(getFeeReq.SrcChainId, getFeeReq.Hash, getFeeReq.DstChainId, usdtFee, tokenFee, tokenFeeWithPrecision,
-							getFeeReq.SwapTokenHash, new(big.Float).SetUint64(0), new(big.Float).SetUint64(0), isNative, nativeTokenAmount, feeTokenPrecison)
+							getFeeReq.SwapTokenHash, new(big.Float).SetUint64(0), new(big.Float).SetUint64(0), isNative, nativeTokenAmount, feeTokenPrecison).WithQuote(quoteId, quoteServerId, quoteExpiry)
 						c.ServeJSON()
 						return
 					}
 				}
-				setErr := cacheRedis.Redis.SetTokenBalance(tokenMap.SrcChainId, tokenMap.DstChainId, tokenMap.DstTokenHash, tokenBalance)
+				setErr := cacheRedis.SetTokenBalance(cache, tokenMap.SrcChainId, tokenMap.DstChainId, tokenMap.DstTokenHash, tokenBalance)
 				if setErr != nil {
 					logs.Error("qweasdredis SetTokenBalance err", setErr)
 				}
-				setErr1 := cacheRedis.Redis.SetLongTokenBalance(tokenMap.SrcChainId, tokenMap.DstChainId, tokenMap.DstTokenHash, tokenBalance)
+				setErr1 := cacheRedis.SetLongTokenBalance(cache, tokenMap.SrcChainId, tokenMap.DstChainId, tokenMap.DstTokenHash, tokenBalance)
 				if setErr1 != nil {
 					logs.Error("qweasdredis SetLongTokenBalance err", setErr1)
 				}
@@ -275,17 +154,17 @@ func (c *FeeController) GetFee() {
 		balance, result := new(big.Float).SetString(tokenBalance.String())
 		if !result {
 			c.Data["json"] = models.MakeGetFeeRsp(getFeeReq.SrcChainId, getFeeReq.Hash, getFeeReq.DstChainId, usdtFee, tokenFee, tokenFeeWithPrecision,
-				getFeeReq.SwapTokenHash, new(big.Float).SetUint64(0), new(big.Float).SetUint64(0), isNative, nativeTokenAmount, feeTokenPrecison)
+				getFeeReq.SwapTokenHash, new(big.Float).SetUint64(0), new(big.Float).SetUint64(0), isNative, nativeTokenAmount, feeTokenPrecison).WithQuote(quoteId, quoteServerId, quoteExpiry)
 			c.ServeJSON()
 			return
 		}
 		tokenBalanceWithoutPrecision := new(big.Float).Quo(balance, new(big.Float).SetInt64(basedef.Int64FromFigure(int(tokenMap.DstToken.Precision))))
 		c.Data["json"] = models.MakeGetFeeRsp(getFeeReq.SrcChainId, getFeeReq.Hash, getFeeReq.DstChainId, usdtFee, tokenFee, tokenFeeWithPrecision,
-			getFeeReq.SwapTokenHash, balance, tokenBalanceWithoutPrecision, isNative, nativeTokenAmount, feeTokenPrecison)
+			getFeeReq.SwapTokenHash, balance, tokenBalanceWithoutPrecision, isNative, nativeTokenAmount, feeTokenPrecison).WithQuote(quoteId, quoteServerId, quoteExpiry)
 		c.ServeJSON()
 	} else {
 		c.Data["json"] = models.MakeGetFeeRsp(getFeeReq.SrcChainId, getFeeReq.Hash, getFeeReq.DstChainId, usdtFee, tokenFee, tokenFeeWithPrecision,
-			getFeeReq.SwapTokenHash, new(big.Float).SetUint64(0), new(big.Float).SetUint64(0), isNative, nativeTokenAmount, feeTokenPrecison)
+			getFeeReq.SwapTokenHash, new(big.Float).SetUint64(0), new(big.Float).SetUint64(0), isNative, nativeTokenAmount, feeTokenPrecison).WithQuote(quoteId, quoteServerId, quoteExpiry)
 		c.ServeJSON()
 	}
 }
@@ -325,20 +204,11 @@ func (c *FeeController) OldGetFee() {
 	tokenFeeWithPrecision := new(big.Float).Mul(tokenFee, new(big.Float).SetInt64(basedef.Int64FromFigure(int(token.Precision))))
 	isNative := false
 	nativeTokenAmount := new(big.Float).SetInt64(0)
-	// get optimistic L1 fee on ethereum
-	if basedef.OPTIMISTIC_CROSSCHAIN_ID == getFeeReq.DstChainId {
-		ethChainFee := new(models.ChainFee)
-		res = db.Where("chain_id = ?", basedef.ETHEREUM_CROSSCHAIN_ID).Preload("TokenBasic").First(ethChainFee)
-		if res.RowsAffected == 0 {
-			c.Data["json"] = models.MakeErrorRsp(fmt.Sprintf("chain: %d does not have fee", basedef.ETHEREUM_CROSSCHAIN_ID))
-			c.Ctx.ResponseWriter.WriteHeader(400)
-			c.ServeJSON()
-			return
-		}
-
-		_, l1UsdtFee, _, err := fee.GetL1Fee(ethChainFee, getFeeReq.DstChainId)
+	// get the L1 data fee of a rollup
+	{
+		_, l1UsdtFee, _, err := fee.GetL1Fee(chainFee, dbChainFee)
 		if err != nil {
-			c.Data["json"] = models.MakeErrorRsp(fmt.Sprintf("get ethereum L1 fee failed. err=%v", err))
+			c.Data["json"] = models.MakeErrorRsp(fmt.Sprintf("get L1 fee failed. err=%v", err))
 			c.Ctx.ResponseWriter.WriteHeader(400)
 			c.ServeJSON()
 			return
@@ -377,7 +247,7 @@ func (c *FeeController) OldGetFee() {
 		}
 		tokenBalance, _ := new(big.Int).SetString("100000000000000000000000000000", 10)
 		if tokenMap.DstChainId != basedef.PLT_CROSSCHAIN_ID {
-			tokenBalance, err = cacheRedis.Redis.GetTokenBalance(tokenMap.SrcChainId, tokenMap.DstChainId, tokenMap.DstTokenHash)
+			tokenBalance, err = cacheRedis.GetTokenBalance(cache, tokenMap.SrcChainId, tokenMap.DstChainId, tokenMap.DstTokenHash)
 			if err != nil {
 				if tokenMap.SrcChainId == basedef.METIS_CROSSCHAIN_ID && (strings.EqualFold(tokenMap.SrcTokenHash, "deaddeaddeaddeaddeaddeaddeaddeaddead0000") || strings.EqualFold(tokenMap.SrcTokenHash, "F3eCc2FF57DF74aE638551b060864717EFE493d2")) && tokenMap.DstChainId == basedef.BSC_CROSSCHAIN_ID {
 					lockproxy := "960Ff3132b72E3F0b1B9F588e7122d78BB5C4946"
@@ -401,7 +271,7 @@ func (c *FeeController) OldGetFee() {
 					tokenBalance, err = common.GetBalance(tokenMap.DstChainId, tokenMap.DstTokenHash)
 				}
 				if err != nil {
-					tokenBalance, err = cacheRedis.Redis.GetLongTokenBalance(tokenMap.SrcChainId, tokenMap.DstChainId, tokenMap.DstTokenHash)
+					tokenBalance, err = cacheRedis.GetLongTokenBalance(cache, tokenMap.SrcChainId, tokenMap.DstChainId, tokenMap.DstTokenHash)
 					if err != nil {
 						c.Data["json"] = models.MakeGetFeeRsp(getFeeReq.SrcChainId, getFeeReq.Hash, getFeeReq.DstChainId, usdtFee, tokenFee, tokenFeeWithPrecision,
 							getFeeReq.SwapTokenHash, new(big.Float).SetUint64(0), new(big.Float).SetUint64(0), isNative, nativeTokenAmount, feeTokenPrecison)
@@ -409,11 +279,11 @@ func (c *FeeController) OldGetFee() {
 						return
 					}
 				}
-				setErr := cacheRedis.Redis.SetTokenBalance(tokenMap.SrcChainId, tokenMap.DstChainId, tokenMap.DstTokenHash, tokenBalance)
+				setErr := cacheRedis.SetTokenBalance(cache, tokenMap.SrcChainId, tokenMap.DstChainId, tokenMap.DstTokenHash, tokenBalance)
 				if setErr != nil {
 					logs.Error("qweasdredis SetTokenBalance err", setErr)
 				}
-				setErr1 := cacheRedis.Redis.SetLongTokenBalance(tokenMap.SrcChainId, tokenMap.DstChainId, tokenMap.DstTokenHash, tokenBalance)
+				setErr1 := cacheRedis.SetLongTokenBalance(cache, tokenMap.SrcChainId, tokenMap.DstChainId, tokenMap.DstTokenHash, tokenBalance)
 				if setErr1 != nil {
 					logs.Error("qweasdredis SetLongTokenBalance err", setErr1)
 				}
@@ -479,7 +349,7 @@ func (c *FeeController) checkFee(Checks []*models.CheckFeeReq) []*models.CheckFe
 		requestHashs = append(requestHashs, basedef.HexStringReverse(check.Hash))
 	}
 	srcTransactions := make([]*models.SrcTransaction, 0)
-	db.Model(&models.SrcTransaction{}).Where("(`key` in ? or `hash` in ?)", requestHashs, requestHashs).Find(&srcTransactions)
+	primaryDB.Model(&models.SrcTransaction{}).Where("(? in ? or hash in ?)", clause.Column{Name: "key"}, requestHashs, requestHashs).Find(&srcTransactions)
 	key2Txhash := make(map[string]string, 0)
 	isPolyProxy := make(map[string]bool, 0)
 
@@ -510,13 +380,13 @@ func (c *FeeController) checkFee(Checks []*models.CheckFeeReq) []*models.CheckFe
 		}
 	}
 	wrapperTransactionWithTokens := make([]*models.WrapperTransactionWithToken, 0)
-	db.Table("wrapper_transactions").Where("hash in ?", checkHashes).Preload("FeeToken").Preload("FeeToken.TokenBasic").Find(&wrapperTransactionWithTokens)
+	primaryDB.Table("wrapper_transactions").Where("hash in ?", checkHashes).Preload("FeeToken").Preload("FeeToken.TokenBasic").Find(&wrapperTransactionWithTokens)
 	txHash2WrapperTransaction := make(map[string]*models.WrapperTransactionWithToken, 0)
 	for _, wrapperTransactionWithToken := range wrapperTransactionWithTokens {
 		txHash2WrapperTransaction[wrapperTransactionWithToken.Hash] = wrapperTransactionWithToken
 	}
 	chainFees := make([]*models.ChainFee, 0)
-	db.Preload("TokenBasic").Find(&chainFees)
+	primaryDB.Preload("TokenBasic").Find(&chainFees)
 	chain2Fees := make(map[uint64]*models.ChainFee, 0)
 	for _, chainFee := range chainFees {
 		chain2Fees[chainFee.ChainId] = chainFee
@@ -581,6 +451,9 @@ func (c *FeeController) checkFee(Checks []*models.CheckFeeReq) []*models.CheckFe
 
 		if feePay.Cmp(feeMin) >= 0 {
 			checkFee.PayState = 1
+		} else if checkFeeQuote(check.Hash, wrapperTransactionWithToken, feePay, feeMin) {
+			checkFee.PayState = 1
+			logs.Info("check fee PayState = 1 by quote ChainId:%v Hash:%v feePay:%v < feeMin:%v", check.ChainId, check.Hash, feePay, feeMin)
 		} else {
 			checkFee.PayState = -1
 			logs.Info("check fee PayState = -1 ChainId:%v Hash:%v feePay:%v < feeMin:%v", check.ChainId, check.Hash, feePay, feeMin)
@@ -594,7 +467,7 @@ func (c *FeeController) checkFee(Checks []*models.CheckFeeReq) []*models.CheckFe
 
 func (c *FeeController) getSwapSrcTransactions(o3Hashs []string) (map[string]string, error) {
 	srcPolyDstRelations := make([]*models.SrcPolyDstRelation, 0)
-	res := db.Table("dst_transactions").
+	res := primaryDB.Table("dst_transactions").
 		Select("src_transactions.hash as src_hash, poly_transactions.hash as poly_hash, dst_transactions.hash as dst_hash").
 		Where("dst_transactions.hash in ?", o3Hashs).
 		Joins("inner join poly_transactions on dst_transactions.poly_hash = poly_transactions.hash").
@@ -619,7 +492,7 @@ func (c *FeeController) CheckSwapFee(Checks []*models.CheckFeeReq) []*models.Che
 		requestHashs = append(requestHashs, basedef.HexStringReverse(check.Hash))
 	}
 	srcTransactions := make([]*models.SrcTransaction, 0)
-	db.Model(&models.SrcTransaction{}).Where("(`key` in ? or `hash` in ?)", requestHashs, requestHashs).Find(&srcTransactions)
+	primaryDB.Model(&models.SrcTransaction{}).Where("(? in ? or hash in ?)", clause.Column{Name: "key"}, requestHashs, requestHashs).Find(&srcTransactions)
 	key2Txhash := make(map[string]string, 0)
 	o3Hashs := make([]string, 0)
 	for _, srcTransaction := range srcTransactions {
@@ -652,13 +525,13 @@ func (c *FeeController) CheckSwapFee(Checks []*models.CheckFeeReq) []*models.Che
 	}
 	//
 	wrapperTransactionWithTokens := make([]*models.WrapperTransactionWithToken, 0)
-	db.Table("wrapper_transactions").Where("hash in ?", checkHashes).Preload("FeeToken").Preload("FeeToken.TokenBasic").Find(&wrapperTransactionWithTokens)
+	primaryDB.Table("wrapper_transactions").Where("hash in ?", checkHashes).Preload("FeeToken").Preload("FeeToken.TokenBasic").Find(&wrapperTransactionWithTokens)
 	txHash2WrapperTransaction := make(map[string]*models.WrapperTransactionWithToken, 0)
 	for _, wrapperTransactionWithToken := range wrapperTransactionWithTokens {
 		txHash2WrapperTransaction[wrapperTransactionWithToken.Hash] = wrapperTransactionWithToken
 	}
 	chainFees := make([]*models.ChainFee, 0)
-	db.Preload("TokenBasic").Find(&chainFees)
+	primaryDB.Preload("TokenBasic").Find(&chainFees)
 	chain2Fees := make(map[uint64]*models.ChainFee, 0)
 	for _, chainFee := range chainFees {
 		chain2Fees[chainFee.ChainId] = chainFee
@@ -0,0 +1,216 @@
+package http
+
+import (
+	"encoding/json"
+	"fmt"
+	"math/big"
+	"poly-bridge/basedef"
+	"poly-bridge/cacheRedis"
+	"poly-bridge/conf"
+	"poly-bridge/models"
+	"poly-bridge/utils/fee"
+	"strings"
+
+	"github.com/beego/beego/v2/core/logs"
+)
+
+// feeCalculation is the fee GetFee charges in the fee token on the source chain
+type feeCalculation struct {
+	token                 *models.Token
+	chainFee              *models.ChainFee
+	usdtFee               *big.Float
+	tokenFee              *big.Float
+	tokenFeeWithPrecision *big.Float
+}
+
+// calculateFee prices the fee of getFeeReq from the chain fee of the destination chain, every step is recorded in detail if not nil
+func calculateFee(getFeeReq *models.GetFeeReq, detail *models.GetFeeDetailRsp) (*feeCalculation, error) {
+	token := new(models.Token)
+	res := db.Where("hash = ? and chain_id = ?", getFeeReq.Hash, getFeeReq.SrcChainId).Preload("TokenBasic").First(token)
+	if res.RowsAffected == 0 {
+		return nil, fmt.Errorf("chain: %d does not have token: %s", getFeeReq.SrcChainId, getFeeReq.Hash)
+	}
+	if token.TokenBasic.Price == 0 {
+		return nil, fmt.Errorf("token: %v price is 0", token.TokenBasic.Name)
+	}
+	chainFee := new(models.ChainFee)
+	res = db.Where("chain_id = ?", getFeeReq.DstChainId).Preload("TokenBasic").First(chainFee)
+	if res.RowsAffected == 0 {
+		return nil, fmt.Errorf("chain: %d does not have fee", getFeeReq.DstChainId)
+	}
+	detail.SetTokens(token, chainFee)
+	feeListenConfig := conf.GlobalConfig.GetFeeListenConfig(getFeeReq.DstChainId)
+	if feeListenConfig == nil {
+		feeListenConfig = &conf.FeeListenConfig{}
+	}
+
+	//check if rank of src token is risky, if so, change the proxyFee value
+	proxyFee := new(big.Float).SetInt(&chainFee.ProxyFee.Int)
+	detail.AddComponent("proxy_fee", true, "gas price * GasLimit * FEE_PRECISION * ProxyFee / 100 of the destination chain", proxyFee,
+		"ProxyFee", chainFee.ProxyFee, "GasLimit", feeListenConfig.GasLimit, "ProxyFeePercent", feeListenConfig.ProxyFee,
+		"MaxFeePerGas", chainFee.MaxFeePerGas, "FEE_PRECISION", basedef.FEE_PRECISION)
+	marginProxyFee := fee.ApplySafetyMargin(chainFee, proxyFee)
+	detail.AddComponent("safety_margin", marginProxyFee != proxyFee, "the fee priced from the predicted EIP-1559 max fee per gas is raised by SafetyMargin percent", marginProxyFee,
+		"SafetyMargin", feeListenConfig.SafetyMargin, "BaseFee", chainFee.BaseFee, "PriorityFee", chainFee.PriorityFee)
+	proxyFee = marginProxyFee
+	//check if any coin marked as dying in redis
+	if exists, _ := cache.Exists(cacheRedis.MarkTokenAsDying + token.TokenBasicName); exists {
+		logs.Info("this token is dying", token.TokenBasicName)
+		if val, err := cache.Get(cacheRedis.MarkTokenAsDying + token.TokenBasicName); err == nil {
+			manualRatio, ok := big.NewFloat(0.0).SetString(val)
+			if ok {
+				proxyFee.Mul(proxyFee, manualRatio)
+			} else {
+				logs.Error("get dying token manualRatio fail, tokenbasicname: %s", token.TokenBasicName)
+			}
+			detail.AddComponent("dying_token", ok, "the token is marked as dying, the proxy fee is multiplied by the manual ratio", proxyFee,
+				"ManualRatio", val)
+		}
+	} else {
+		risky := token.TokenBasic.Rank > riskyCoinRankThreshold
+		if risky {
+			proxyFee.Mul(proxyFee, riskyCoinRisingRate)
+		}
+		detail.AddComponent("risky_coin", risky, "the proxy fee of a token ranked after RiskyCoinRankThreshold is multiplied by RiskyCoinRisingRate", proxyFee,
+			"Rank", token.TokenBasic.Rank, "RiskyCoinRankThreshold", riskyCoinRankThreshold, "RiskyCoinRisingRate", riskyCoinRisingRate)
+	}
+	proxyFee = new(big.Float).Quo(proxyFee, new(big.Float).SetInt64(basedef.FEE_PRECISION))
+	proxyFee = new(big.Float).Quo(proxyFee, new(big.Float).SetInt64(basedef.Int64FromFigure(int(chainFee.TokenBasic.Precision))))
+	usdtFee := new(big.Float).Mul(proxyFee, new(big.Float).SetInt64(chainFee.TokenBasic.Price))
+	usdtFee = new(big.Float).Quo(usdtFee, new(big.Float).SetInt64(basedef.PRICE_PRECISION))
+	detail.AddComponent("usdt_fee", true, "proxy fee / FEE_PRECISION / 10^Precision * Price / PRICE_PRECISION of the destination chain fee token", usdtFee,
+		"Token", chainFee.TokenBasicName, "Precision", chainFee.TokenBasic.Precision, "Price", chainFee.TokenBasic.Price, "PRICE_PRECISION", basedef.PRICE_PRECISION)
+	tokenFee := new(big.Float).Mul(usdtFee, new(big.Float).SetInt64(basedef.PRICE_PRECISION))
+	tokenFee = new(big.Float).Quo(tokenFee, new(big.Float).SetInt64(token.TokenBasic.Price))
+	detail.AddComponent("token_fee", true, "usdt fee * PRICE_PRECISION / Price of the fee token", tokenFee,
+		"Token", token.TokenBasicName, "Price", token.TokenBasic.Price)
+
+	isNftSwap := true
+	if len(getFeeReq.SwapTokenHash) != 0 {
+		swapToken := new(models.Token)
+		res := db.Where("hash = ? and chain_id = ?", getFeeReq.SwapTokenHash, getFeeReq.SrcChainId, getFeeReq.SrcChainId).First(swapToken)
+		if res.RowsAffected != 0 {
+			if swapToken.Standard == models.TokenTypeErc20 {
+				isNftSwap = false
+			}
+		}
+	}
+	nftRatioApplied := false
+	if isNftSwap {
+		for _, cfg := range conf.GlobalConfig.FeeListenConfig {
+			if cfg.ChainId == getFeeReq.DstChainId {
+				if cfg.NftRatio > 0 {
+					nftRatio := new(big.Float).Quo(new(big.Float).SetInt64(cfg.NftRatio), new(big.Float).SetInt64(100))
+					usdtFee = new(big.Float).Mul(usdtFee, nftRatio)
+					tokenFee = new(big.Float).Mul(tokenFee, nftRatio)
+					nftRatioApplied = true
+				}
+				break
+			}
+		}
+	}
+	detail.AddComponent("nft_ratio", nftRatioApplied, "unless the swap token is an erc20 token, the fee is multiplied by NftRatio / 100 of the destination chain", tokenFee,
+		"NftRatio", feeListenConfig.NftRatio, "IsNftSwap", isNftSwap, "UsdtFee", usdtFee)
+	tokenFeeWithPrecision := new(big.Float).Mul(tokenFee, new(big.Float).SetInt64(basedef.Int64FromFigure(int(token.Precision))))
+
+	// get the L1 data fee of a rollup
+	if strategy, _ := fee.GetL1FeeStrategy(getFeeReq.DstChainId); strategy != nil {
+		_, l1UsdtFee, _, err := fee.GetL1Fee(chainFee, dbChainFee)
+		if err != nil {
+			return nil, fmt.Errorf("get %s L1 fee failed. err=%v", strategy.Name(), err)
+		}
+
+		l1TokenFee := new(big.Float).Mul(l1UsdtFee, new(big.Float).SetInt64(basedef.PRICE_PRECISION))
+		l1TokenFee = new(big.Float).Quo(l1TokenFee, new(big.Float).SetInt64(token.TokenBasic.Price))
+		l1TokenFeeWithPrecision := new(big.Float).Mul(l1TokenFee, new(big.Float).SetInt64(basedef.Int64FromFigure(int(token.Precision))))
+		tokenFee = new(big.Float).Add(tokenFee, l1TokenFee)
+		tokenFeeWithPrecision = new(big.Float).Add(tokenFeeWithPrecision, l1TokenFeeWithPrecision)
+		detail.AddComponent("l1_fee", true, "the L1 data fee priced by the L1FeeStrategy of the destination chain is added", tokenFee,
+			"L1FeeStrategy", strategy.Name(), "EthL1GasLimit", feeListenConfig.EthL1GasLimit, "L1DataFee", chainFee.L1DataFee,
+			"L1UsdtFee", l1UsdtFee, "L1TokenFee", l1TokenFee)
+	} else {
+		detail.AddComponent("l1_fee", false, "the destination chain has no L1FeeStrategy", tokenFee)
+	}
+	return &feeCalculation{
+		token:                 token,
+		chainFee:              chainFee,
+		usdtFee:               usdtFee,
+		tokenFee:              tokenFee,
+		tokenFeeWithPrecision: tokenFeeWithPrecision,
+	}, nil
+}
+
+// dbChainFee reads the chain fee with TokenBasic loaded for the L1 fee strategies
+func dbChainFee(chainId uint64) (*models.ChainFee, bool) {
+	chainFee := new(models.ChainFee)
+	res := db.Where("chain_id = ?", chainId).Preload("TokenBasic").First(chainFee)
+	return chainFee, res.RowsAffected != 0
+}
+
+// calculateNativeFee tells whether the swap token is the native token of the source chain and the max fee of it,
+// the step is recorded in detail if not nil
+func calculateNativeFee(getFeeReq *models.GetFeeReq, detail *models.GetFeeDetailRsp) (bool, *big.Float, error) {
+	nativeChainFee := new(models.ChainFee)
+	res := db.Where("chain_id = ?", getFeeReq.SrcChainId).Preload("TokenBasic").
+		First(nativeChainFee)
+	if res.RowsAffected == 0 {
+		return false, nil, fmt.Errorf("chain: %d does not have fee", getFeeReq.SrcChainId)
+	}
+	if nativeChainFee.TokenBasic == nil {
+		return false, new(big.Float).SetInt64(0), nil
+	}
+	preloadTokens := make([]*models.Token, 0)
+	db.Where("token_basic_name = ?", nativeChainFee.TokenBasicName).
+		Find(&preloadTokens)
+	for _, v := range preloadTokens {
+		if v.ChainId == getFeeReq.SrcChainId && strings.EqualFold(v.Hash, getFeeReq.SwapTokenHash) {
+			nativeFeeAmount := new(big.Float).SetInt(&nativeChainFee.MaxFee.Int)
+			nativeFeeAmount = new(big.Float).Quo(nativeFeeAmount, new(big.Float).SetInt64(basedef.FEE_PRECISION))
+			nativeFeeAmount = new(big.Float).Quo(nativeFeeAmount, new(big.Float).SetInt64(basedef.Int64FromFigure(int(nativeChainFee.TokenBasic.Precision))))
+			_, _, l1FeeAmount, err := fee.GetL1Fee(nativeChainFee, dbChainFee)
+			if err != nil {
+				return false, nil, fmt.Errorf("get L1 fee failed. err=%v", err)
+			}
+			nativeFeeAmount = new(big.Float).Add(nativeFeeAmount, l1FeeAmount)
+			detail.AddComponent("native_fee", true, "the swap token is the native token of the source chain, MaxFee / FEE_PRECISION / 10^Precision of it is charged", nativeFeeAmount,
+				"Token", nativeChainFee.TokenBasicName, "MaxFee", nativeChainFee.MaxFee, "Precision", nativeChainFee.TokenBasic.Precision, "L1FeeAmount", l1FeeAmount)
+			return true, nativeFeeAmount, nil
+		}
+	}
+	detail.AddComponent("native_fee", false, "the swap token is not the native token of the source chain", new(big.Float).SetInt64(0),
+		"Token", nativeChainFee.TokenBasicName)
+	return false, new(big.Float).SetInt64(0), nil
+}
+
+// GetFeeDetail returns the fee GetFee quotes together with every step and input it is priced from
+func (c *FeeController) GetFeeDetail() {
+	var getFeeReq models.GetFeeReq
+	if err := json.Unmarshal(c.Ctx.Input.RequestBody, &getFeeReq); err != nil {
+		c.Data["json"] = models.MakeErrorRsp(fmt.Sprintf("request parameter is invalid!"))
+		c.Ctx.ResponseWriter.WriteHeader(400)
+		c.ServeJSON()
+		return
+	}
+	detail := models.MakeGetFeeDetailRsp(&getFeeReq)
+	calculation, err := calculateFee(&getFeeReq, detail)
+	if err != nil {
+		c.Data["json"] = models.MakeErrorRsp(err.Error())
+		c.Ctx.ResponseWriter.WriteHeader(400)
+		c.ServeJSON()
+		return
+	}
+	isNative := false
+	nativeTokenAmount := new(big.Float).SetInt64(0)
+	if getFeeReq.SwapTokenHash != "" {
+		isNative, nativeTokenAmount, err = calculateNativeFee(&getFeeReq, detail)
+		if err != nil {
+			c.Data["json"] = models.MakeErrorRsp(err.Error())
+			c.Ctx.ResponseWriter.WriteHeader(400)
+			c.ServeJSON()
+			return
+		}
+	}
+	detail.SetTotals(calculation.usdtFee, calculation.tokenFee, calculation.tokenFeeWithPrecision, isNative, nativeTokenAmount)
+	c.Data["json"] = detail
+	c.ServeJSON()
+}
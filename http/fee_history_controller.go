@@ -0,0 +1,63 @@
+package http
+
+import (
+	"encoding/json"
+	"fmt"
+	"poly-bridge/models"
+	"time"
+
+	"github.com/beego/beego/v2/core/logs"
+)
+
+// maxFeeHistorySnapshots limits the snapshots returned for a chain, a longer range is read in several requests
+const maxFeeHistorySnapshots = 1000
+
+func (c *FeeController) FeeHistory() {
+	var feeHistoryReq models.FeeHistoryReq
+	if err := json.Unmarshal(c.Ctx.Input.RequestBody, &feeHistoryReq); err != nil {
+		c.Data["json"] = models.MakeErrorRsp(fmt.Sprintf("request parameter is invalid!"))
+		c.Ctx.ResponseWriter.WriteHeader(400)
+		c.ServeJSON()
+		return
+	}
+	if feeHistoryReq.EndTime == 0 {
+		feeHistoryReq.EndTime = time.Now().Unix()
+	}
+	if feeHistoryReq.StartTime > feeHistoryReq.EndTime {
+		c.Data["json"] = models.MakeErrorRsp(fmt.Sprintf("StartTime is after EndTime"))
+		c.Ctx.ResponseWriter.WriteHeader(400)
+		c.ServeJSON()
+		return
+	}
+	chainIds := feeHistoryReq.ChainIds
+	if len(chainIds) == 0 {
+		db.Model(&models.ChainFee{}).Order("chain_id asc").Pluck("chain_id", &chainIds)
+	}
+	history := make([]*models.ChainFeeHistory, 0)
+	for _, chainId := range chainIds {
+		// the snapshot in effect at the start time was taken before it
+		current := make([]*models.ChainFeeHistory, 0)
+		res := db.Where("chain_id = ? and time <= ?", chainId, feeHistoryReq.StartTime).Order("time desc").Limit(1).Find(&current)
+		if res.Error != nil {
+			logs.Error("get fee history of chain %d err: %v", chainId, res.Error)
+			c.Data["json"] = models.MakeErrorRsp(fmt.Sprintf("get fee history of chain %d failed", chainId))
+			c.Ctx.ResponseWriter.WriteHeader(500)
+			c.ServeJSON()
+			return
+		}
+		snapshots := make([]*models.ChainFeeHistory, 0)
+		res = db.Where("chain_id = ? and time > ? and time <= ?", chainId, feeHistoryReq.StartTime, feeHistoryReq.EndTime).
+			Order("time asc").Limit(maxFeeHistorySnapshots - len(current)).Find(&snapshots)
+		if res.Error != nil {
+			logs.Error("get fee history of chain %d err: %v", chainId, res.Error)
+			c.Data["json"] = models.MakeErrorRsp(fmt.Sprintf("get fee history of chain %d failed", chainId))
+			c.Ctx.ResponseWriter.WriteHeader(500)
+			c.ServeJSON()
+			return
+		}
+		history = append(history, current...)
+		history = append(history, snapshots...)
+	}
+	c.Data["json"] = models.MakeFeeHistoryRsp(history)
+	c.ServeJSON()
+}
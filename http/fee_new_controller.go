@@ -1,6 +1,7 @@
 package http
 
 import (
+	"gorm.io/gorm/clause"
 	"encoding/json"
 	"fmt"
 	"github.com/beego/beego/v2/core/logs"
@@ -61,7 +62,7 @@ func (c *FeeController) NewCheckFee() {
 	checkFeewrapperTransaction(srcHashs, mapCheckFeesReq)
 	//get chain fee
 	chainFees := make([]*models.ChainFee, 0)
-	db.Preload("TokenBasic").Find(&chainFees)
+	primaryDB.Preload("TokenBasic").Find(&chainFees)
 	chain2Fees := make(map[uint64]*models.ChainFee, 0)
 	for _, chainFee := range chainFees {
 		chain2Fees[chainFee.ChainId] = chainFee
@@ -69,7 +70,7 @@ func (c *FeeController) NewCheckFee() {
 	for k, v := range mapCheckFeesReq {
 		//check fee from cache（special case）
 		if v.SrcTransaction != nil {
-			exists, _ := cacheRedis.Redis.Exists(cacheRedis.MarkTxAsPaidPrefix + v.SrcTransaction.Hash)
+			exists, _ := cache.Exists(cacheRedis.MarkTxAsPaidPrefix + v.SrcTransaction.Hash)
 			if exists {
 				logs.Info("check fee poly_hash %s marked as paid", k)
 				v.Status = PAID
@@ -114,24 +115,11 @@ func (c *FeeController) NewCheckFee() {
 				logs.Info("find no fee token", k)
 				continue
 			}
-			feePay, feeMin, gasPay := fee.CheckFeeCal(chainFee, v.WrapperTransactionWithToken.FeeToken, v.WrapperTransactionWithToken.FeeAmount)
-
-			// get optimistic L1 fee on ethereum
-			if chainFee.ChainId == basedef.OPTIMISTIC_CROSSCHAIN_ID {
-				ethChainFee, ok := chain2Fees[basedef.ETHEREUM_CROSSCHAIN_ID]
-				if !ok {
-					v.Status = NOT_PAID
-					logs.Info("check fee poly_hash %s NOT_PAID,chainFee hasn't ethereum fee", k)
-					continue
-				}
-
-				L1MinFee, _, _, err := fee.GetL1Fee(ethChainFee, chainFee.ChainId)
-				if err != nil {
-					v.Status = NOT_PAID
-					logs.Info("check fee poly_hash %s NOT_PAID, get L1 fee failed. err=%v", k, err)
-					continue
-				}
-				feeMin = new(big.Float).Add(feeMin, L1MinFee)
+			feePay, feeMin, gasPay, err := fee.CheckFeeCal(chainFee, v.WrapperTransactionWithToken.FeeToken, v.WrapperTransactionWithToken.FeeAmount, fee.ChainFeesGetter(chain2Fees))
+			if err != nil {
+				v.Status = NOT_PAID
+				logs.Info("check fee poly_hash %s NOT_PAID, get L1 fee failed. err=%v", k, err)
+				continue
 			}
 
 			v.Paid, _ = feePay.Float64()
@@ -170,6 +158,9 @@ func (c *FeeController) NewCheckFee() {
 			if feePay.Cmp(feeMin) >= 0 {
 				v.Status = PAID
 				logs.Info("check fee poly_hash %s PAID,feePay %v >= feeMin %v", k, v.Paid, v.Min)
+			} else if checkFeeQuote(k, v.WrapperTransactionWithToken, feePay, feeMin) {
+				v.Status = PAID
+				logs.Info("check fee poly_hash %s PAID by quote,feePay %v < feeMin %v", k, v.Paid, v.Min)
 			} else {
 				v.Status = NOT_PAID
 				logs.Info("check fee poly_hash %s NOT_PAID,feePay %v < feeMin %v", k, v.Paid, v.Min)
@@ -185,19 +176,19 @@ func (c *FeeController) NewCheckFee() {
 func checkFeeSrcTransaction(chainId uint64, txId string) (*models.SrcTransaction, error) {
 	transaction := new(models.SrcTransaction)
 	if strings.Contains(txId, "00000000") {
-		res := db.Model(&models.SrcTransaction{}).
-			Where("chain_id=? and `key` =?", chainId, txId).
+		res := primaryDB.Model(&models.SrcTransaction{}).
+			Where("chain_id=? and ? =?", chainId, clause.Column{Name: "key"}, txId).
 			First(transaction)
 		if res.Error != nil {
 			return nil, res.Error
 		}
 	} else {
-		res := db.Model(&models.SrcTransaction{}).
-			Where("chain_id=? and `hash` =?", chainId, txId).
+		res := primaryDB.Model(&models.SrcTransaction{}).
+			Where("chain_id=? and hash =?", chainId, txId).
 			First(transaction)
 		if res.Error != nil {
-			res := db.Model(&models.SrcTransaction{}).
-				Where("chain_id=? and `hash` =?", chainId, basedef.HexStringReverse(txId)).First(transaction)
+			res := primaryDB.Model(&models.SrcTransaction{}).
+				Where("chain_id=? and hash =?", chainId, basedef.HexStringReverse(txId)).First(transaction)
 			if res.Error != nil {
 				return nil, res.Error
 			}
@@ -207,7 +198,7 @@ func checkFeeSrcTransaction(chainId uint64, txId string) (*models.SrcTransaction
 		return transaction, nil
 	}
 	srcTransaction := new(models.SrcTransaction)
-	res := db.Debug().Table("src_transactions").
+	res := primaryDB.Debug().Table("src_transactions").
 		Joins("inner join poly_transactions on src_transactions.hash = poly_transactions.src_hash").
 		Joins("inner join dst_transactions on poly_transactions.hash = dst_transactions.poly_hash").
 		Where("dst_transactions.hash = ?", transaction.Hash).
@@ -221,7 +212,7 @@ func checkFeeSrcTransaction(chainId uint64, txId string) (*models.SrcTransaction
 //checkFeewrapperTransaction fetch wrapper transaction record from db
 func checkFeewrapperTransaction(srcHashs []string, mapCheckFeesReq map[string]*models.CheckFeeRequest) {
 	wrapperTransactionWithTokens := make([]*models.WrapperTransactionWithToken, 0)
-	db.Table("wrapper_transactions").Where("hash in ?", srcHashs).Preload("FeeToken").Preload("FeeToken.TokenBasic").Find(&wrapperTransactionWithTokens)
+	primaryDB.Table("wrapper_transactions").Where("hash in ?", srcHashs).Preload("FeeToken").Preload("FeeToken.TokenBasic").Find(&wrapperTransactionWithTokens)
 	for _, v := range mapCheckFeesReq {
 		for _, wrapper := range wrapperTransactionWithTokens {
 			if v.SrcTransaction != nil && v.SrcTransaction.Hash == wrapper.Hash {
@@ -5,6 +5,7 @@ import (
 	"fmt"
 	"github.com/beego/beego/v2/core/logs"
 	"poly-bridge/basedef"
+	"poly-bridge/cacheRedis"
 	"poly-bridge/conf"
 	"poly-bridge/models"
 	"strings"
@@ -15,8 +16,12 @@ func TestFeeController_NewCheckFee(t *testing.T) {
 
 	//cfg := conf.NewConfig("../../config.json")
 
-	conf.NewConfig("../config.json")
-	Init()
+	config := conf.NewConfig("../config.json")
+	cache, err := cacheRedis.NewCache(config.RedisConfig)
+	if err != nil {
+		t.Fatal(err)
+	}
+	Init(cache)
 
 	var mapCheckFeesReq map[string]*models.CheckFeeRequest = make(map[string]*models.CheckFeeRequest)
 	mapCheckFeesReq["426e25c36f36b42377c04d75ca58f6a9422d1e9eb7999aca102b759ddf362ab6"] = &models.CheckFeeRequest{
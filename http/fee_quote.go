@@ -0,0 +1,182 @@
+package http
+
+import (
+	"fmt"
+	"math/big"
+	"poly-bridge/conf"
+	"poly-bridge/models"
+	"poly-bridge/utils/fee"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/beego/beego/v2/core/logs"
+)
+
+const (
+	defaultFeeQuoteExpiry    = 10 * 60
+	defaultFeeQuoteRetention = 24 * 60 * 60
+	defaultFeeQuoteInterval  = 10
+	feeQuotePurgeInterval    = 60
+)
+
+var (
+	feeQuoteKey       string
+	feeQuoteExpiry    int64
+	feeQuoteRetention int64
+	feeQuoteInterval  int64
+
+	feeQuotePurgeLock sync.Mutex
+	feeQuotePurged    int64
+)
+
+func SetFeeQuoteInfo(feeQuoteConfig *conf.FeeQuoteConfig) {
+	if feeQuoteConfig == nil {
+		feeQuoteKey = ""
+		return
+	}
+	feeQuoteKey = feeQuoteConfig.Key
+	feeQuoteExpiry = feeQuoteConfig.Expiry
+	if feeQuoteExpiry <= 0 {
+		feeQuoteExpiry = defaultFeeQuoteExpiry
+	}
+	feeQuoteRetention = feeQuoteConfig.Retention
+	if feeQuoteRetention <= 0 {
+		feeQuoteRetention = defaultFeeQuoteRetention
+	}
+	feeQuoteInterval = feeQuoteConfig.Interval
+	if feeQuoteInterval <= 0 {
+		feeQuoteInterval = defaultFeeQuoteInterval
+	}
+}
+
+// quoteUser is the sender as the wrapper transactions save it, lower case hex without 0x
+func quoteUser(user string) string {
+	user = strings.ToLower(user)
+	return strings.TrimPrefix(user, "0x")
+}
+
+// issueFeeQuote signs the fee returned by GetFee for the sender of the request and saves it, the quote id is empty
+// if no key is configured, no sender is given or the client ip was issued a quote within the interval
+func issueFeeQuote(getFeeReq *models.GetFeeReq, ip string, usdtFee *big.Float, tokenFeeWithPrecision *big.Float) (string, uint64, int64) {
+	if feeQuoteKey == "" || getFeeReq.User == "" || ip == "" {
+		return "", 0, 0
+	}
+	if ok, err := cache.Lock("FeeQuote_"+ip, 1, time.Duration(feeQuoteInterval)*time.Second); err != nil || !ok {
+		logs.Info("fee quote of ip %s is limited, err: %v", ip, err)
+		return "", 0, 0
+	}
+	now := time.Now().Unix()
+	purgeFeeQuotes(now)
+	quote := &fee.Quote{
+		User:                     quoteUser(getFeeReq.User),
+		SrcChainId:               getFeeReq.SrcChainId,
+		Hash:                     getFeeReq.Hash,
+		DstChainId:               getFeeReq.DstChainId,
+		UsdtAmount:               fmt.Sprintf("%v", usdtFee),
+		TokenAmountWithPrecision: fmt.Sprintf("%.*f", 0, tokenFeeWithPrecision),
+		Expiry:                   now + feeQuoteExpiry,
+	}
+	quoteId, err := fee.SignQuote(feeQuoteKey, quote)
+	if err != nil {
+		logs.Error("sign fee quote err: %v", err)
+		return "", 0, 0
+	}
+	serverId, err := fee.QuoteTag(quoteId)
+	if err != nil {
+		logs.Error("fee quote tag err: %v", err)
+		return "", 0, 0
+	}
+	err = primaryDB.Create(&models.FeeQuote{QuoteId: quoteId, ServerId: serverId, Expiry: quote.Expiry, Time: now}).Error
+	if err != nil {
+		logs.Error("save fee quote err: %v", err)
+		return "", 0, 0
+	}
+	return quoteId, serverId, quote.Expiry
+}
+
+// purgeFeeQuotes removes the quotes expired for longer than the retention, at most once a purge interval
+func purgeFeeQuotes(now int64) {
+	feeQuotePurgeLock.Lock()
+	defer feeQuotePurgeLock.Unlock()
+	if now-feeQuotePurged < feeQuotePurgeInterval {
+		return
+	}
+	feeQuotePurged = now
+	if err := primaryDB.Where("expiry < ?", now-feeQuoteRetention).Delete(&models.FeeQuote{}).Error; err != nil {
+		logs.Error("purge fee quotes err: %v", err)
+	}
+}
+
+// checkFeeQuote tells whether the wrapper paid the quote whose tag it passed as its server id, the quote must be signed,
+// issued to the sender of the wrapper for its chains and fee token, not expired at the wrapper time and not paid by another
+// wrapper. The quote is marked as paid by the wrapper, the drift between the quote and the fee computed from the current
+// prices is logged.
+func checkFeeQuote(hash string, wrapper *models.WrapperTransactionWithToken, feePay *big.Float, feeMin *big.Float) bool {
+	if feeQuoteKey == "" || wrapper.ServerId == 0 {
+		return false
+	}
+	saved := new(models.FeeQuote)
+	res := primaryDB.Where("server_id = ?", wrapper.ServerId).Limit(1).Find(saved)
+	if res.Error != nil {
+		logs.Error("check fee hash %s load quote %d err: %v", hash, wrapper.ServerId, res.Error)
+		return false
+	}
+	if res.RowsAffected == 0 {
+		logs.Info("check fee hash %s quote rejected: no quote %d", hash, wrapper.ServerId)
+		return false
+	}
+	quote, err := fee.VerifyQuote(feeQuoteKey, saved.QuoteId)
+	if err != nil {
+		logs.Info("check fee hash %s quote rejected: %v", hash, err)
+		return false
+	}
+	if quote.User != quoteUser(wrapper.User) {
+		logs.Info("check fee hash %s quote rejected: quote for user %s, wrapper user %s", hash, quote.User, wrapper.User)
+		return false
+	}
+	if quote.SrcChainId != wrapper.SrcChainId || quote.DstChainId != wrapper.DstChainId || !strings.EqualFold(quote.Hash, wrapper.FeeTokenHash) {
+		logs.Info("check fee hash %s quote rejected: quote for %d-%d %s, wrapper %d-%d %s", hash,
+			quote.SrcChainId, quote.DstChainId, quote.Hash, wrapper.SrcChainId, wrapper.DstChainId, wrapper.FeeTokenHash)
+		return false
+	}
+	if int64(wrapper.Time) > quote.Expiry {
+		logs.Info("check fee hash %s quote rejected: expired at %d, wrapper time %d", hash, quote.Expiry, wrapper.Time)
+		return false
+	}
+	quoteAmount, ok := new(big.Int).SetString(quote.TokenAmountWithPrecision, 10)
+	if !ok {
+		logs.Info("check fee hash %s quote rejected: invalid amount %s", hash, quote.TokenAmountWithPrecision)
+		return false
+	}
+	logs.Info("check fee hash %s quote drift: quoted usdt %s, paid usdt %v, current min usdt %v", hash, quote.UsdtAmount, feePay, feeMin)
+	if wrapper.FeeAmount == nil || wrapper.FeeAmount.Int.Cmp(quoteAmount) < 0 {
+		logs.Info("check fee hash %s quote not paid: fee amount %v < quoted %s", hash, wrapper.FeeAmount, quote.TokenAmountWithPrecision)
+		return false
+	}
+	if saved.UsedHash == wrapper.Hash {
+		return true
+	}
+	if saved.UsedHash != "" {
+		logs.Info("check fee hash %s quote rejected: quote %d paid by %s", hash, saved.ServerId, saved.UsedHash)
+		return false
+	}
+	res = primaryDB.Model(&models.FeeQuote{}).Where("id = ? and used_hash = ?", saved.Id, "").Update("used_hash", wrapper.Hash)
+	if res.Error != nil {
+		logs.Error("check fee hash %s mark quote %d paid err: %v", hash, saved.ServerId, res.Error)
+		return false
+	}
+	if res.RowsAffected == 1 {
+		return true
+	}
+	//marked meanwhile, by a concurrent check of the same wrapper or by another wrapper
+	if err := primaryDB.Where("id = ?", saved.Id).First(saved).Error; err != nil {
+		logs.Error("check fee hash %s reload quote %d err: %v", hash, saved.ServerId, err)
+		return false
+	}
+	if saved.UsedHash != wrapper.Hash {
+		logs.Info("check fee hash %s quote rejected: quote %d paid by %s", hash, saved.ServerId, saved.UsedHash)
+		return false
+	}
+	return true
+}
@@ -0,0 +1,88 @@
+package http
+
+import (
+	"math/big"
+	"poly-bridge/cacheRedis"
+	"poly-bridge/conf"
+	"poly-bridge/models"
+	"poly-bridge/utils/dbtest"
+	"poly-bridge/utils/fee"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestFeeQuote(t *testing.T) {
+	primaryDB = dbtest.Open(t, &models.FeeQuote{})
+	cache = cacheRedis.NewMemoryCache()
+	SetFeeQuoteInfo(&conf.FeeQuoteConfig{Key: "key"})
+	defer SetFeeQuoteInfo(nil)
+
+	getFeeReq := &models.GetFeeReq{SrcChainId: 2, Hash: "0000000000000000000000000000000000000000", DstChainId: 6}
+	quoteId, _, _ := issueFeeQuote(getFeeReq, "1.1.1.1", big.NewFloat(1.5), big.NewFloat(1500))
+	assert.Empty(t, quoteId, "no quote without a sender")
+
+	getFeeReq.User = "0xAbCdEf0000000000000000000000000000000001"
+	quoteId, serverId, expiry := issueFeeQuote(getFeeReq, "1.1.1.1", big.NewFloat(1.5), big.NewFloat(1500))
+	assert.NotEmpty(t, quoteId)
+	quote, err := fee.VerifyQuote("key", quoteId)
+	assert.NoError(t, err)
+	assert.Equal(t, "abcdef0000000000000000000000000000000001", quote.User)
+	tag, _ := fee.QuoteTag(quoteId)
+	assert.Equal(t, tag, serverId)
+	limited, _, _ := issueFeeQuote(getFeeReq, "1.1.1.1", big.NewFloat(1.5), big.NewFloat(1500))
+	assert.Empty(t, limited, "one quote per ip within the interval")
+
+	wrapper := func(hash string, user string, amount int64) *models.WrapperTransactionWithToken {
+		return &models.WrapperTransactionWithToken{
+			Hash:         hash,
+			User:         user,
+			SrcChainId:   2,
+			DstChainId:   6,
+			Time:         uint64(expiry),
+			ServerId:     serverId,
+			FeeTokenHash: getFeeReq.Hash,
+			FeeAmount:    models.NewBigIntFromInt(amount),
+		}
+	}
+	feePay, feeMin := big.NewFloat(1), big.NewFloat(2)
+	user := "abcdef0000000000000000000000000000000001"
+
+	assert.False(t, checkFeeQuote("a", wrapper("a", "abcdef0000000000000000000000000000000002", 1500), feePay, feeMin), "other sender")
+	assert.False(t, checkFeeQuote("a", wrapper("a", user, 1499), feePay, feeMin), "underpaid")
+	expired := wrapper("a", user, 1500)
+	expired.Time++
+	assert.False(t, checkFeeQuote("a", expired, feePay, feeMin), "expired")
+	other := wrapper("a", user, 1500)
+	other.DstChainId = 7
+	assert.False(t, checkFeeQuote("a", other, feePay, feeMin), "other chain")
+	unknown := wrapper("a", user, 1500)
+	unknown.ServerId++
+	assert.False(t, checkFeeQuote("a", unknown, feePay, feeMin), "unknown quote")
+
+	assert.True(t, checkFeeQuote("a", wrapper("a", user, 1500), feePay, feeMin))
+	assert.True(t, checkFeeQuote("a", wrapper("a", user, 1500), feePay, feeMin), "checked again")
+	assert.False(t, checkFeeQuote("b", wrapper("b", user, 1500), feePay, feeMin), "replayed")
+
+	saved := new(models.FeeQuote)
+	assert.NoError(t, primaryDB.Where("server_id = ?", serverId).First(saved).Error)
+	assert.Equal(t, "a", saved.UsedHash)
+
+	// a quote not signed with the key is rejected
+	forged, err := fee.SignQuote("other key", quote)
+	assert.NoError(t, err)
+	forgedTag, _ := fee.QuoteTag(forged)
+	assert.NoError(t, primaryDB.Create(&models.FeeQuote{QuoteId: forged, ServerId: forgedTag, Expiry: expiry}).Error)
+	forgedWrapper := wrapper("c", user, 1500)
+	forgedWrapper.ServerId = forgedTag
+	assert.False(t, checkFeeQuote("c", forgedWrapper, feePay, feeMin))
+
+	// the quotes expired for longer than the retention are purged
+	now := time.Now().Unix()
+	feeQuotePurged = 0
+	purgeFeeQuotes(now + feeQuoteExpiry + feeQuoteRetention + 1)
+	var count int64
+	assert.NoError(t, primaryDB.Model(&models.FeeQuote{}).Count(&count).Error)
+	assert.Equal(t, int64(0), count)
+}
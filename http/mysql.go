@@ -18,31 +18,36 @@
 package http
 
 import (
-	"fmt"
-	"gorm.io/driver/mysql"
 	"gorm.io/gorm"
 	"gorm.io/gorm/logger"
 	"poly-bridge/basedef"
+	"poly-bridge/cacheRedis"
 	"poly-bridge/conf"
+	"poly-bridge/utils/database"
 )
 
 var db *gorm.DB
+
+// primaryDB is db pinned to the primary, the fee checks follow the ingest of the txs closely and must not read a lagging replica
+var primaryDB *gorm.DB
+var cache cacheRedis.Cache
 var relayUrl string
 var contractCheck map[uint64]([]string)
 
-func Init() {
+func Init(c cacheRedis.Cache) {
+	cache = c
 	config := conf.GlobalConfig.DBConfig
 	Logger := logger.Default
 	if conf.GlobalConfig.RunMode == "dev" {
 		Logger = Logger.LogMode(logger.Info)
 	}
 
-	conn := fmt.Sprintf("%s:%s@tcp(%s)/%s?charset=utf8", config.User, config.Password, config.URL, config.Scheme)
 	var err error
-	db, err = gorm.Open(mysql.Open(conn), &gorm.Config{Logger: Logger})
+	db, err = database.OpenWithReplicas(config, Logger)
 	if err != nil {
 		panic(err)
 	}
+	primaryDB = database.Primary(db)
 
 	relayUrl = conf.GlobalConfig.RelayUrl
 	if relayUrl == "" {
@@ -24,6 +24,7 @@ import (
 
 func GetRouter(config *conf.Config) web.LinkNamespace {
 	SetCoinRankFilterInfo(config.RiskyCoinHandleConfig)
+	SetFeeQuoteInfo(config.FeeQuoteConfig)
 	ns := web.NSNamespace("/bridge",
 		web.NSRouter("/", &InfoController{}, "*:Get"),
 		web.NSRouter("/token/", &TokenController{}, "post:Token"),
@@ -33,9 +34,11 @@ func GetRouter(config *conf.Config) web.LinkNamespace {
 		web.NSRouter("/tokenmap/", &TokenMapController{}, "post:TokenMap"),
 		web.NSRouter("/tokenmapreverse/", &TokenMapController{}, "post:TokenMapReverse"),
 		web.NSRouter("/getfee/", &FeeController{}, "post:GetFee"),
+		web.NSRouter("/getfeedetail/", &FeeController{}, "post:GetFeeDetail"),
 		web.NSRouter("/oldgetfee/", &FeeController{}, "post:OldGetFee"),
 		web.NSRouter("/checkfee/", &FeeController{}, "post:CheckFee"),
 		web.NSRouter("/newcheckfee/", &FeeController{}, "post:NewCheckFee"),
+		web.NSRouter("/feehistory/", &FeeController{}, "post:FeeHistory"),
 		web.NSRouter("/checkswapfee/", &FeeController{}, "post:CheckSwapFee"),
 		web.NSRouter("/transactions/", &TransactionController{}, "post:Transactions"),
 		web.NSRouter("/transactionswithfilter/", &TransactionController{}, "post:TransactionsOfAddressWithFilter"),
@@ -53,6 +56,12 @@ func GetRouter(config *conf.Config) web.LinkNamespace {
 		web.NSRouter("/wrappercheck/", &WrapperController{}, "post:WrapperCheck"),
 		web.NSRouter("/airdropofaddress/", &AirDropController{}, "post:AirDropOfAddress"),
 		web.NSRouter("/airdropclaim/", &AirDropController{}, "post:AirDropClaim"),
+		web.NSRouter("/webhooksubscribe/", &WebhookController{}, "post:Subscribe"),
+		web.NSRouter("/webhookunsubscribe/", &WebhookController{}, "post:Unsubscribe"),
+		web.NSRouter("/webhookdeliveries/", &WebhookController{}, "post:Deliveries"),
+		web.NSRouter("/txstream/", &TxStreamController{}, "get:Stream"),
+		web.NSRouter("/transferauditfindings/", &AuditController{}, "post:TransferAuditFindings"),
+		web.NSRouter("/transferaudittotals/", &AuditController{}, "post:TransferAuditTotals"),
 	)
 	return ns
 }
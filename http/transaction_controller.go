@@ -25,12 +25,14 @@ import (
 	"poly-bridge/basedef"
 	"poly-bridge/cacheRedis"
 	"poly-bridge/models"
+	"poly-bridge/utils/transactions"
 	"strings"
 	"time"
 
 	"github.com/beego/beego/v2/core/logs"
 	"github.com/beego/beego/v2/server/web"
 	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
 )
 
 type TransactionController struct {
@@ -207,7 +209,7 @@ func (c *TransactionController) TransactionsOfAddress() {
 	db.Debug().Table("(?) as u", db.Model(&models.SrcTransfer{}).
 		Select("tx_hash as hash, asset as asset, fee_token_hash as fee_token_hash, src_transfers.chain_id as chain_id").
 		Joins("left join wrapper_transactions on src_transfers.tx_hash = wrapper_transactions.hash").
-		Where("`from` in ? or src_transfers.dst_user in ?", transactionsOfAddressReq.Addresses, transactionsOfAddressReq.Addresses).
+		Where("? in ? or src_transfers.dst_user in ?", clause.Column{Table: "src_transfers", Name: "from"}, transactionsOfAddressReq.Addresses, transactionsOfAddressReq.Addresses).
 		Where("wrapper_transactions.hash is NOT NULL or src_transfers.chain_id = ?", basedef.RIPPLE_CROSSCHAIN_ID)).
 		Where("src_transactions.standard = ?", 0).
 		Select("src_transactions.hash as src_hash, poly_transactions.hash as poly_hash, dst_transactions.hash as dst_hash, src_transactions.chain_id as chain_id, u.asset as token_hash, u.fee_token_hash as fee_token_hash").
@@ -230,7 +232,7 @@ func (c *TransactionController) TransactionsOfAddress() {
 	var transactionNum int64
 	db.Model(&models.SrcTransfer{}).
 		Joins("left join wrapper_transactions on src_transfers.tx_hash = wrapper_transactions.hash").
-		Where("src_transfers.`from` in ? or src_transfers.dst_user in ?", transactionsOfAddressReq.Addresses, transactionsOfAddressReq.Addresses).
+		Where("? in ? or src_transfers.dst_user in ?", clause.Column{Table: "src_transfers", Name: "from"}, transactionsOfAddressReq.Addresses, transactionsOfAddressReq.Addresses).
 		Where("wrapper_transactions.hash is NOT NULL or src_transfers.chain_id = ?", basedef.RIPPLE_CROSSCHAIN_ID).
 		Count(&transactionNum)
 	chains := make([]*models.Chain, 0)
@@ -245,57 +247,25 @@ func (c *TransactionController) TransactionsOfAddress() {
 }
 
 func (c *TransactionController) getTransactionByHash(hash string) (*models.SrcPolyDstRelation, error) {
-	srcPolyDstRelation := new(models.SrcPolyDstRelation)
-	res := db.Table("src_transactions").
-		Select("src_transactions.hash as src_hash, poly_transactions.hash as poly_hash, dst_transactions.hash as dst_hash, src_transactions.chain_id as chain_id, src_transfers.asset as token_hash, wrapper_transactions.fee_token_hash as fee_token_hash").
-		Where("src_transactions.hash = ?", hash).
-		Where("src_transactions.standard = ?", 0).
-		Joins("left join wrapper_transactions on src_transactions.hash = wrapper_transactions.hash").
-		Joins("left join src_transfers on src_transactions.hash = src_transfers.tx_hash").
-		Joins("left join poly_transactions on src_transactions.hash = poly_transactions.src_hash").
-		Joins("left join dst_transactions on poly_transactions.hash = dst_transactions.poly_hash").
-		Preload("WrapperTransaction").
-		Preload("SrcTransaction").
-		Preload("SrcTransaction.SrcTransfer").
-		Preload("PolyTransaction").
-		Preload("DstTransaction").
-		Preload("DstTransaction.DstTransfer").
-		Preload("Token").
-		Preload("Token.TokenBasic").
-		Preload("FeeToken").
-		Order("src_transactions.time desc").
-		Find(&srcPolyDstRelation)
-	if res.RowsAffected == 0 {
+	crossChainTx, legs, err := transactions.FindCrossChainTx(db, "src_hash = ? and standard = ?", hash, 0)
+	if err != nil {
+		return nil, err
+	}
+	if crossChainTx == nil {
 		return nil, fmt.Errorf("transacion: %s does not exist", hash)
 	}
-	return srcPolyDstRelation, nil
+	return transactions.GetCrossChainTxRelationIn(db, legs, crossChainTx)
 }
 
 func (c *TransactionController) getTransactionByDstHash(hash string) (*models.SrcPolyDstRelation, error) {
-	srcPolyDstRelation := new(models.SrcPolyDstRelation)
-	res := db.Table("dst_transactions").
-		Select("src_transactions.hash as src_hash, poly_transactions.hash as poly_hash, dst_transactions.hash as dst_hash, src_transactions.chain_id as chain_id, src_transfers.asset as token_hash, wrapper_transactions.fee_token_hash as fee_token_hash").
-		Where("dst_transactions.hash = ?", hash).
-		Where("dst_transactions.standard = ?", 0).
-		Joins("inner join poly_transactions on dst_transactions.poly_hash = poly_transactions.hash").
-		Joins("inner join src_transactions on poly_transactions.src_hash = src_transactions.hash").
-		Joins("left join wrapper_transactions on src_transactions.hash = wrapper_transactions.hash").
-		Joins("left join src_transfers on src_transactions.hash = src_transfers.tx_hash").
-		Preload("WrapperTransaction").
-		Preload("SrcTransaction").
-		Preload("SrcTransaction.SrcTransfer").
-		Preload("PolyTransaction").
-		Preload("DstTransaction").
-		Preload("DstTransaction.DstTransfer").
-		Preload("Token").
-		Preload("Token.TokenBasic").
-		Preload("FeeToken").
-		Order("src_transactions.time desc").
-		Find(&srcPolyDstRelation)
-	if res.RowsAffected == 0 {
+	crossChainTx, legs, err := transactions.FindCrossChainTxByDstHash(db, hash, "standard = ?", 0)
+	if err != nil {
+		return nil, err
+	}
+	if crossChainTx == nil {
 		return nil, fmt.Errorf("transacion: %s does not exist", hash)
 	}
-	return srcPolyDstRelation, nil
+	return transactions.GetCrossChainTxRelationIn(db, legs, crossChainTx)
 }
 
 func (c *TransactionController) TransactionOfHash() {
@@ -306,47 +276,43 @@ func (c *TransactionController) TransactionOfHash() {
 		c.Ctx.ResponseWriter.WriteHeader(400)
 		c.ServeJSON()
 	}
-	srcPolyDstRelation, err := c.getTransactionByHash(transactionOfHashReq.Hash)
+	resp, err := c.getTransactionRspByHash(transactionOfHashReq.Hash)
 	if err != nil {
 		c.Data["json"] = err.Error()
 		c.Ctx.ResponseWriter.WriteHeader(400)
 		c.ServeJSON()
 		return
 	}
-	if srcPolyDstRelation.SrcTransaction.DstChainId != basedef.O3_CROSSCHAIN_ID || srcPolyDstRelation.DstTransaction == nil {
-		chains := make([]*models.Chain, 0)
-		db.Model(&models.Chain{}).Find(&chains)
-		chainsMap := make(map[uint64]*models.Chain)
-		for _, chain := range chains {
-			chainsMap[chain.ChainId] = chain
-		}
-		resp := models.MakeTransactionRsp(srcPolyDstRelation, chainsMap)
-		if resp == nil {
-			c.Data["json"] = "transaction does not exist"
-			c.Ctx.ResponseWriter.WriteHeader(400)
-		} else {
-			c.Data["json"] = resp
-		}
-		c.ServeJSON()
-		return
-	}
-	srcPolyDstRelation2, err := c.getTransactionByHash(srcPolyDstRelation.DstHash)
+	c.Data["json"] = resp
+	c.ServeJSON()
+}
+
+// getTransactionRspByHash builds the TransactionRsp of a source hash, the o3 swaps are merged with their second leg
+func (c *TransactionController) getTransactionRspByHash(hash string) (*models.TransactionRsp, error) {
+	srcPolyDstRelation, err := c.getTransactionByHash(hash)
 	if err != nil {
-		c.Data["json"] = err.Error()
-		c.Ctx.ResponseWriter.WriteHeader(400)
-		c.ServeJSON()
-		return
+		return nil, err
+	}
+	// the src transaction is missing if only the wrapper transaction is saved
+	if srcPolyDstRelation.SrcTransaction != nil && srcPolyDstRelation.SrcTransaction.DstChainId == basedef.O3_CROSSCHAIN_ID && srcPolyDstRelation.DstTransaction != nil {
+		srcPolyDstRelation2, err := c.getTransactionByHash(srcPolyDstRelation.DstHash)
+		if err != nil {
+			return nil, err
+		}
+		srcPolyDstRelation.DstHash = srcPolyDstRelation2.DstHash
+		srcPolyDstRelation.DstTransaction = srcPolyDstRelation2.DstTransaction
 	}
-	srcPolyDstRelation.DstHash = srcPolyDstRelation2.DstHash
-	srcPolyDstRelation.DstTransaction = srcPolyDstRelation2.DstTransaction
 	chains := make([]*models.Chain, 0)
 	db.Model(&models.Chain{}).Find(&chains)
 	chainsMap := make(map[uint64]*models.Chain)
 	for _, chain := range chains {
 		chainsMap[chain.ChainId] = chain
 	}
-	c.Data["json"] = models.MakeTransactionRsp(srcPolyDstRelation, chainsMap)
-	c.ServeJSON()
+	resp := models.MakeTransactionRsp(srcPolyDstRelation, chainsMap)
+	if resp == nil {
+		return nil, fmt.Errorf("transaction does not exist")
+	}
+	return resp, nil
 }
 
 func (c *TransactionController) TransactionOfCurve() {
@@ -365,7 +331,7 @@ func (c *TransactionController) TransactionOfCurve() {
 		c.ServeJSON()
 		return
 	}
-	if srcPolyDstRelation1.SrcTransaction.DstChainId != basedef.O3_CROSSCHAIN_ID || srcPolyDstRelation1.DstTransaction == nil {
+	if srcPolyDstRelation1.WrapperTransaction == nil || srcPolyDstRelation1.SrcTransaction == nil || srcPolyDstRelation1.SrcTransaction.DstChainId != basedef.O3_CROSSCHAIN_ID || srcPolyDstRelation1.DstTransaction == nil {
 		c.Data["json"] = models.MakeErrorRsp(fmt.Sprintf("request parameter is invalid!"))
 		c.Ctx.ResponseWriter.WriteHeader(400)
 		c.ServeJSON()
@@ -533,7 +499,7 @@ func (c *TransactionController) GetManualTxData() {
 		return
 	}
 	manualTxDataResp := new(models.ManualTxDataResp)
-	manualData, err := cacheRedis.Redis.GetManualTx(manualTxDataReq.PolyHash)
+	manualData, err := cacheRedis.GetManualTx(cache, manualTxDataReq.PolyHash)
 	if err == nil {
 		if manualData == "" {
 			c.return400(fmt.Sprintf("%v getManualData loading", manualTxDataReq.PolyHash))
@@ -556,7 +522,7 @@ func (c *TransactionController) GetManualTxData() {
 		body, _ := ioutil.ReadAll(resp.Body)
 		json.Unmarshal(body, manualTxDataResp)
 		manualData = string(body)
-		cacheRedis.Redis.SetManualTx(manualTxDataReq.PolyHash, manualData)
+		cacheRedis.SetManualTx(cache, manualTxDataReq.PolyHash, manualData)
 
 		c.Data["json"] = manualTxDataResp
 		c.ServeJSON()
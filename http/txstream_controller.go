@@ -0,0 +1,197 @@
+package http
+
+import (
+	"encoding/json"
+	"fmt"
+	"poly-bridge/cacheRedis"
+	"poly-bridge/conf"
+	"poly-bridge/models"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/beego/beego/v2/core/logs"
+	"github.com/beego/beego/v2/server/web"
+)
+
+const (
+	defaultTxStreamHeartbeat = 15
+	defaultTxStreamMaxHashes = 20
+	txStreamBuffer           = 16
+)
+
+type txStreamClient struct {
+	hashes  map[string]bool
+	address string
+	updates chan *models.TransactionRsp
+}
+
+func (client *txStreamClient) watches(change *models.TxStatusChange) bool {
+	if client.hashes[strings.ToLower(change.Hash)] {
+		return true
+	}
+	return client.address != "" && (strings.EqualFold(client.address, change.User) || strings.EqualFold(client.address, change.DstUser))
+}
+
+// txStreamHub fans the status changes published by the effect service out to the stream clients of this
+// instance, every http instance subscribes the channel on its own
+type txStreamHub struct {
+	cfg     *conf.TxStreamConfig
+	mutex   sync.RWMutex
+	clients map[*txStreamClient]bool
+}
+
+var txStream *txStreamHub
+
+func InitTxStream(cfg *conf.TxStreamConfig) {
+	channel := cfg.Channel
+	if channel == "" {
+		channel = cacheRedis.TxStatusChannel
+	}
+	txStream = &txStreamHub{
+		cfg:     cfg,
+		clients: make(map[*txStreamClient]bool),
+	}
+	go txStream.run(cache.Subscribe(channel))
+}
+
+func (hub *txStreamHub) run(subscription cacheRedis.Subscription) {
+	for message := range subscription.Channel() {
+		change := new(models.TxStatusChange)
+		if err := json.Unmarshal([]byte(message.Payload), change); err != nil {
+			logs.Error("tx stream message %s err: %v", message.Payload, err)
+			continue
+		}
+		clients := hub.watchers(change)
+		if len(clients) == 0 {
+			continue
+		}
+		rsp, err := (&TransactionController{}).getTransactionRspByHash(change.Hash)
+		if err != nil {
+			logs.Error("tx stream get transaction %s err: %v", change.Hash, err)
+			continue
+		}
+		for _, client := range clients {
+			select {
+			case client.updates <- rsp:
+			default:
+				logs.Warn("tx stream client is too slow, update of %s is dropped", change.Hash)
+			}
+		}
+	}
+}
+
+func (hub *txStreamHub) watchers(change *models.TxStatusChange) []*txStreamClient {
+	hub.mutex.RLock()
+	defer hub.mutex.RUnlock()
+	clients := make([]*txStreamClient, 0)
+	for client := range hub.clients {
+		if client.watches(change) {
+			clients = append(clients, client)
+		}
+	}
+	return clients
+}
+
+func (hub *txStreamHub) add(client *txStreamClient) {
+	hub.mutex.Lock()
+	defer hub.mutex.Unlock()
+	hub.clients[client] = true
+}
+
+func (hub *txStreamHub) remove(client *txStreamClient) {
+	hub.mutex.Lock()
+	defer hub.mutex.Unlock()
+	delete(hub.clients, client)
+}
+
+type TxStreamController struct {
+	web.Controller
+}
+
+// Stream pushes the TransactionRsp of the watched transactions as server-sent events whenever their status
+// changes. The source hashes are given by the comma separated query "hashes", an address by "address".
+func (c *TxStreamController) Stream() {
+	if txStream == nil {
+		c.Data["json"] = models.MakeErrorRsp(fmt.Sprintf("tx stream is not enabled!"))
+		c.Ctx.ResponseWriter.WriteHeader(400)
+		c.ServeJSON()
+		return
+	}
+	maxHashes := txStream.cfg.MaxHashes
+	if maxHashes <= 0 {
+		maxHashes = defaultTxStreamMaxHashes
+	}
+	client := &txStreamClient{
+		hashes:  make(map[string]bool),
+		address: c.Ctx.Input.Query("address"),
+		updates: make(chan *models.TransactionRsp, txStreamBuffer),
+	}
+	for _, hash := range strings.Split(c.Ctx.Input.Query("hashes"), ",") {
+		if hash = strings.TrimSpace(hash); hash != "" {
+			client.hashes[strings.ToLower(hash)] = true
+		}
+	}
+	if (len(client.hashes) == 0 && client.address == "") || len(client.hashes) > maxHashes {
+		c.Data["json"] = models.MakeErrorRsp(fmt.Sprintf("request parameter is invalid!"))
+		c.Ctx.ResponseWriter.WriteHeader(400)
+		c.ServeJSON()
+		return
+	}
+	c.EnableRender = false
+	txStream.add(client)
+	defer txStream.remove(client)
+
+	w := c.Ctx.ResponseWriter
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.Header().Set("Connection", "keep-alive")
+	w.Header().Set("X-Accel-Buffering", "no")
+	w.WriteHeader(200)
+	for hash := range client.hashes {
+		if rsp, err := c.getTransactionRspByHash(hash); err == nil {
+			if err := c.writeEvent(rsp); err != nil {
+				return
+			}
+		}
+	}
+	w.Flush()
+
+	heartbeat := txStream.cfg.Heartbeat
+	if heartbeat <= 0 {
+		heartbeat = defaultTxStreamHeartbeat
+	}
+	ticker := time.NewTicker(time.Second * time.Duration(heartbeat))
+	defer ticker.Stop()
+	for {
+		select {
+		case rsp := <-client.updates:
+			if err := c.writeEvent(rsp); err != nil {
+				return
+			}
+		case <-ticker.C:
+			if _, err := w.Write([]byte(": keepalive\n\n")); err != nil {
+				return
+			}
+			w.Flush()
+		case <-c.Ctx.Request.Context().Done():
+			return
+		}
+	}
+}
+
+func (c *TxStreamController) getTransactionRspByHash(hash string) (*models.TransactionRsp, error) {
+	return (&TransactionController{}).getTransactionRspByHash(hash)
+}
+
+func (c *TxStreamController) writeEvent(rsp *models.TransactionRsp) error {
+	data, err := json.Marshal(rsp)
+	if err != nil {
+		return err
+	}
+	if _, err := fmt.Fprintf(c.Ctx.ResponseWriter, "event: transaction\ndata: %s\n\n", data); err != nil {
+		return err
+	}
+	c.Ctx.ResponseWriter.Flush()
+	return nil
+}
@@ -0,0 +1,21 @@
+package http
+
+import (
+	"poly-bridge/models"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestTxStreamWatchers(t *testing.T) {
+	hub := &txStreamHub{clients: make(map[*txStreamClient]bool)}
+	byHash := &txStreamClient{hashes: map[string]bool{"ab01": true}}
+	byAddress := &txStreamClient{hashes: map[string]bool{}, address: "0xUser"}
+	hub.add(byHash)
+	hub.add(byAddress)
+
+	assert.Equal(t, []*txStreamClient{byHash}, hub.watchers(&models.TxStatusChange{Hash: "AB01", User: "0xother"}))
+	assert.Equal(t, []*txStreamClient{byAddress}, hub.watchers(&models.TxStatusChange{Hash: "cd02", DstUser: "0xuser"}))
+	hub.remove(byAddress)
+	assert.Empty(t, hub.watchers(&models.TxStatusChange{Hash: "cd02", User: "0xuser"}))
+}
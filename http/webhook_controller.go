@@ -0,0 +1,146 @@
+package http
+
+import (
+	"crypto/rand"
+	"crypto/subtle"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"github.com/beego/beego/v2/server/web"
+	"poly-bridge/conf"
+	"poly-bridge/models"
+	"poly-bridge/webhook"
+	"strings"
+	"time"
+)
+
+type WebhookController struct {
+	web.Controller
+}
+
+// Subscribe needs the webhook api token in the X-Api-Token header
+func (c *WebhookController) Subscribe() {
+	cfg := conf.GlobalConfig.WebhookConfig
+	token := c.Ctx.Input.Header("X-Api-Token")
+	if cfg == nil || len(cfg.ApiToken) == 0 || subtle.ConstantTimeCompare([]byte(token), []byte(cfg.ApiToken)) != 1 {
+		c.Data["json"] = models.MakeErrorRsp(fmt.Sprintf("unauthorized!"))
+		c.Ctx.ResponseWriter.WriteHeader(401)
+		c.ServeJSON()
+		return
+	}
+	var webhookSubscribeReq models.WebhookSubscribeReq
+	if err := json.Unmarshal(c.Ctx.Input.RequestBody, &webhookSubscribeReq); err != nil {
+		c.Data["json"] = models.MakeErrorRsp(fmt.Sprintf("request parameter is invalid!"))
+		c.Ctx.ResponseWriter.WriteHeader(400)
+		c.ServeJSON()
+		return
+	}
+	targets := 0
+	for _, target := range []string{webhookSubscribeReq.SrcHash, webhookSubscribeReq.User, webhookSubscribeReq.Token} {
+		if target != "" {
+			targets++
+		}
+	}
+	if targets != 1 || (webhookSubscribeReq.Token != "" && webhookSubscribeReq.ChainId == 0) {
+		c.Data["json"] = models.MakeErrorRsp(fmt.Sprintf("one of SrcHash, User or ChainId and Token, and a http Url are required!"))
+		c.Ctx.ResponseWriter.WriteHeader(400)
+		c.ServeJSON()
+		return
+	}
+	if err := webhook.ValidateUrl(webhookSubscribeReq.Url, cfg.AllowPrivateUrl); err != nil {
+		c.Data["json"] = models.MakeErrorRsp(err.Error())
+		c.Ctx.ResponseWriter.WriteHeader(400)
+		c.ServeJSON()
+		return
+	}
+	states, err := models.FormatWebhookStates(webhookSubscribeReq.States)
+	if err != nil {
+		c.Data["json"] = models.MakeErrorRsp(err.Error())
+		c.Ctx.ResponseWriter.WriteHeader(400)
+		c.ServeJSON()
+		return
+	}
+	secret := make([]byte, 32)
+	if _, err := rand.Read(secret); err != nil {
+		c.Data["json"] = models.MakeErrorRsp(fmt.Sprintf("generate secret err: %v", err))
+		c.Ctx.ResponseWriter.WriteHeader(400)
+		c.ServeJSON()
+		return
+	}
+	subscription := &models.WebhookSubscription{
+		Url:        webhookSubscribeReq.Url,
+		Secret:     hex.EncodeToString(secret),
+		SrcHash:    strings.ToLower(webhookSubscribeReq.SrcHash),
+		User:       strings.ToLower(webhookSubscribeReq.User),
+		ChainId:    webhookSubscribeReq.ChainId,
+		Token:      strings.ToLower(webhookSubscribeReq.Token),
+		States:     states,
+		CreateTime: time.Now().Unix(),
+	}
+	if err := db.Create(subscription).Error; err != nil {
+		c.Data["json"] = models.MakeErrorRsp(fmt.Sprintf("add subscription err: %v", err))
+		c.Ctx.ResponseWriter.WriteHeader(400)
+		c.ServeJSON()
+		return
+	}
+	c.Data["json"] = models.MakeWebhookSubscriptionRsp(subscription)
+	c.ServeJSON()
+}
+
+func (c *WebhookController) Unsubscribe() {
+	var webhookIdReq models.WebhookIdReq
+	if err := json.Unmarshal(c.Ctx.Input.RequestBody, &webhookIdReq); err != nil {
+		c.Data["json"] = models.MakeErrorRsp(fmt.Sprintf("request parameter is invalid!"))
+		c.Ctx.ResponseWriter.WriteHeader(400)
+		c.ServeJSON()
+		return
+	}
+	subscription, err := c.getSubscription(webhookIdReq.Id, webhookIdReq.Secret)
+	if err != nil {
+		c.Data["json"] = models.MakeErrorRsp(err.Error())
+		c.Ctx.ResponseWriter.WriteHeader(400)
+		c.ServeJSON()
+		return
+	}
+	if err := db.Delete(subscription).Error; err != nil {
+		c.Data["json"] = models.MakeErrorRsp(fmt.Sprintf("remove subscription err: %v", err))
+		c.Ctx.ResponseWriter.WriteHeader(400)
+		c.ServeJSON()
+		return
+	}
+	c.Data["json"] = models.MakeWebhookSubscriptionRsp(subscription)
+	c.ServeJSON()
+}
+
+func (c *WebhookController) Deliveries() {
+	var webhookDeliveriesReq models.WebhookDeliveriesReq
+	if err := json.Unmarshal(c.Ctx.Input.RequestBody, &webhookDeliveriesReq); err != nil || webhookDeliveriesReq.PageSize <= 0 {
+		c.Data["json"] = models.MakeErrorRsp(fmt.Sprintf("request parameter is invalid!"))
+		c.Ctx.ResponseWriter.WriteHeader(400)
+		c.ServeJSON()
+		return
+	}
+	subscription, err := c.getSubscription(webhookDeliveriesReq.Id, webhookDeliveriesReq.Secret)
+	if err != nil {
+		c.Data["json"] = models.MakeErrorRsp(err.Error())
+		c.Ctx.ResponseWriter.WriteHeader(400)
+		c.ServeJSON()
+		return
+	}
+	deliveries := make([]*models.WebhookDelivery, 0)
+	db.Where("subscription_id = ?", subscription.Id).Limit(webhookDeliveriesReq.PageSize).Offset(webhookDeliveriesReq.PageSize * webhookDeliveriesReq.PageNo).Order("id desc").Find(&deliveries)
+	var deliveryNum int64
+	db.Model(&models.WebhookDelivery{}).Where("subscription_id = ?", subscription.Id).Count(&deliveryNum)
+	c.Data["json"] = models.MakeWebhookDeliveriesRsp(webhookDeliveriesReq.PageSize, webhookDeliveriesReq.PageNo,
+		(int(deliveryNum)+webhookDeliveriesReq.PageSize-1)/webhookDeliveriesReq.PageSize, int(deliveryNum), deliveries)
+	c.ServeJSON()
+}
+
+func (c *WebhookController) getSubscription(id int64, secret string) (*models.WebhookSubscription, error) {
+	subscription := new(models.WebhookSubscription)
+	res := db.Where("id = ?", id).First(subscription)
+	if res.Error != nil || subscription.Secret == "" || subtle.ConstantTimeCompare([]byte(subscription.Secret), []byte(secret)) != 1 {
+		return nil, fmt.Errorf("subscription does not exist")
+	}
+	return subscription, nil
+}
@@ -27,6 +27,7 @@ import (
 	"poly-bridge/conf"
 	"poly-bridge/explorer"
 	"poly-bridge/http"
+	"poly-bridge/migration"
 	"poly-bridge/nft_http"
 
 	"github.com/beego/beego/v2/core/logs"
@@ -73,6 +74,9 @@ func run(ctx *cli.Context) {
 	logs.SetLogger(logs.AdapterFile, fmt.Sprintf(`{"filename":"%s"}`, config.HttpLogFile))
 
 	basedef.ConfirmEnv(config.Env)
+	if err := migration.CheckSchema(config.DBConfig); err != nil {
+		panic(err)
+	}
 	common.SetupChainsSDK(config)
 
 	web.InsertFilter("*", web.BeforeRouter, cors.Allow(
@@ -85,12 +89,18 @@ func run(ctx *cli.Context) {
 		},
 	))
 
+	// redis, or the cache in the process if no redis is configured
+	cache, err := cacheRedis.NewCache(config.RedisConfig)
+	if err != nil {
+		panic(err)
+	}
 	// bridge http
-	http.Init()
+	http.Init(cache)
 	// explorer http
-	explorer.Init()
-	// redis
-	cacheRedis.Init()
+	explorer.Init(cache)
+	if config.TxStreamConfig != nil {
+		http.InitTxStream(config.TxStreamConfig)
+	}
 
 	// register http routers
 	web.AddNamespace(
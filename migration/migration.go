@@ -0,0 +1,201 @@
+/*
+ * Copyright (C) 2020 The poly network Authors
+ * This file is part of The poly network library.
+ *
+ * The  poly network  is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU Lesser General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * The  poly network  is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU Lesser General Public License for more details.
+ * You should have received a copy of the GNU Lesser General Public License
+ * along with The poly network .  If not, see <http://www.gnu.org/licenses/>.
+ */
+
+package migration
+
+import (
+	"fmt"
+	"poly-bridge/conf"
+	"poly-bridge/models"
+	"poly-bridge/utils/database"
+	"sort"
+	"time"
+
+	"github.com/beego/beego/v2/core/logs"
+	"gorm.io/gorm"
+)
+
+// Migration is a numbered schema change of the bridge database, Down is nil if it can not be reverted.
+// Up runs in one transaction with the record of its version unless NoTransaction is set, which lets a
+// long backfill commit in batches, such an Up must be safe to run again after a failure.
+type Migration struct {
+	Version       int64
+	Name          string
+	Up            func(db *gorm.DB) error
+	Down          func(db *gorm.DB) error
+	NoTransaction bool
+}
+
+type MigrationStatus struct {
+	Version   int64
+	Name      string
+	Applied   bool
+	AppliedAt int64
+}
+
+// Migrations returns the migrations known by this binary ordered by version
+func Migrations() []*Migration {
+	return migrations
+}
+
+func init() {
+	sort.Slice(migrations, func(i, j int) bool { return migrations[i].Version < migrations[j].Version })
+	for i := 1; i < len(migrations); i++ {
+		if migrations[i].Version == migrations[i-1].Version {
+			panic(fmt.Sprintf("duplicated migration version %d", migrations[i].Version))
+		}
+	}
+}
+
+func applied(db *gorm.DB) (map[int64]*models.SchemaMigration, error) {
+	if !db.Migrator().HasTable(&models.SchemaMigration{}) {
+		return map[int64]*models.SchemaMigration{}, nil
+	}
+	records := make([]*models.SchemaMigration, 0)
+	if err := db.Find(&records).Error; err != nil {
+		return nil, err
+	}
+	versions := make(map[int64]*models.SchemaMigration, len(records))
+	for _, record := range records {
+		versions[record.Version] = record
+	}
+	return versions, nil
+}
+
+// Status lists the known migrations and the applied ones which are unknown to this binary
+func Status(db *gorm.DB) ([]*MigrationStatus, error) {
+	versions, err := applied(db)
+	if err != nil {
+		return nil, err
+	}
+	status := make([]*MigrationStatus, 0, len(migrations))
+	for _, migration := range migrations {
+		item := &MigrationStatus{Version: migration.Version, Name: migration.Name}
+		if record, ok := versions[migration.Version]; ok {
+			item.Applied = true
+			item.AppliedAt = record.AppliedAt
+			delete(versions, migration.Version)
+		}
+		status = append(status, item)
+	}
+	for _, record := range versions {
+		status = append(status, &MigrationStatus{Version: record.Version, Name: record.Name, Applied: true, AppliedAt: record.AppliedAt})
+	}
+	sort.Slice(status, func(i, j int) bool { return status[i].Version < status[j].Version })
+	return status, nil
+}
+
+// Up applies the pending migrations up to the target version, all of them if target is 0
+func Up(db *gorm.DB, target int64) ([]*Migration, error) {
+	if err := db.AutoMigrate(&models.SchemaMigration{}); err != nil {
+		return nil, err
+	}
+	versions, err := applied(db)
+	if err != nil {
+		return nil, err
+	}
+	done := make([]*Migration, 0)
+	for _, migration := range migrations {
+		if target > 0 && migration.Version > target {
+			break
+		}
+		if _, ok := versions[migration.Version]; ok {
+			continue
+		}
+		logs.Info("migration up %d %s", migration.Version, migration.Name)
+		up := func(tx *gorm.DB) error {
+			if err := migration.Up(tx); err != nil {
+				return err
+			}
+			return tx.Create(&models.SchemaMigration{
+				Version:   migration.Version,
+				Name:      migration.Name,
+				AppliedAt: time.Now().Unix(),
+			}).Error
+		}
+		if migration.NoTransaction {
+			err = up(db)
+		} else {
+			err = db.Transaction(up)
+		}
+		if err != nil {
+			return done, fmt.Errorf("migration %d %s up: %v", migration.Version, migration.Name, err)
+		}
+		done = append(done, migration)
+	}
+	return done, nil
+}
+
+// Down reverts the latest steps applied migrations
+func Down(db *gorm.DB, steps int) ([]*Migration, error) {
+	versions, err := applied(db)
+	if err != nil {
+		return nil, err
+	}
+	done := make([]*Migration, 0)
+	for i := len(migrations) - 1; i >= 0 && len(done) < steps; i-- {
+		migration := migrations[i]
+		if _, ok := versions[migration.Version]; !ok {
+			continue
+		}
+		if migration.Down == nil {
+			return done, fmt.Errorf("migration %d %s can not be reverted", migration.Version, migration.Name)
+		}
+		logs.Info("migration down %d %s", migration.Version, migration.Name)
+		err = db.Transaction(func(tx *gorm.DB) error {
+			if err := migration.Down(tx); err != nil {
+				return err
+			}
+			return tx.Delete(&models.SchemaMigration{}, migration.Version).Error
+		})
+		if err != nil {
+			return done, fmt.Errorf("migration %d %s down: %v", migration.Version, migration.Name, err)
+		}
+		done = append(done, migration)
+	}
+	return done, nil
+}
+
+// Check returns an error if any migration known by this binary is not applied
+func Check(db *gorm.DB) error {
+	status, err := Status(db)
+	if err != nil {
+		return err
+	}
+	pending := make([]int64, 0)
+	for _, item := range status {
+		if !item.Applied {
+			pending = append(pending, item.Version)
+		}
+	}
+	if len(pending) > 0 {
+		return fmt.Errorf("schema is behind the binary, pending migrations %v, run bridge_tools migrate up", pending)
+	}
+	return nil
+}
+
+// CheckSchema is called on the start of the servers, which should refuse to run on an outdated schema
+func CheckSchema(dbCfg *conf.DBConfig) error {
+	db, err := database.Open(dbCfg)
+	if err != nil {
+		return err
+	}
+	if sqlDB, err := db.DB(); err == nil {
+		defer sqlDB.Close()
+	}
+	return Check(db)
+}
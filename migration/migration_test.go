@@ -0,0 +1,127 @@
+package migration
+
+import (
+	"poly-bridge/basedef"
+	"poly-bridge/models"
+	"poly-bridge/utils/dbtest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"gorm.io/gorm"
+)
+
+func TestUpDown(t *testing.T) {
+	db := dbtest.Open(t)
+	assert.Error(t, Check(db))
+
+	done, err := Up(db, 2)
+	assert.NoError(t, err)
+	assert.Len(t, done, 2)
+	assert.Error(t, Check(db))
+
+	done, err = Up(db, 0)
+	assert.NoError(t, err)
+	assert.Len(t, done, len(migrations)-2)
+	assert.NoError(t, Check(db))
+	assert.True(t, db.Migrator().HasTable(&models.WrapperDetail{}))
+
+	status, err := Status(db)
+	assert.NoError(t, err)
+	assert.Len(t, status, len(migrations))
+	for _, item := range status {
+		assert.True(t, item.Applied)
+	}
+
+	// the migrations are reverted down to the neo3 fix, which can not be reverted
+	done, err = Down(db, len(migrations))
+	assert.Error(t, err)
+	assert.Len(t, done, len(migrations)-4)
+	_, err = Up(db, 0)
+	assert.NoError(t, err)
+
+	migrations = append(migrations, &Migration{
+		Version: 1000,
+		Name:    "test",
+		Up: func(db *gorm.DB) error {
+			return db.Exec("create table migration_tests (id integer)").Error
+		},
+		Down: func(db *gorm.DB) error {
+			return db.Exec("drop table migration_tests").Error
+		},
+	})
+	defer func() { migrations = migrations[:len(migrations)-1] }()
+	assert.Error(t, Check(db))
+	done, err = Up(db, 0)
+	assert.NoError(t, err)
+	assert.Len(t, done, 1)
+	assert.True(t, db.Migrator().HasTable("migration_tests"))
+
+	done, err = Down(db, 1)
+	assert.NoError(t, err)
+	assert.Len(t, done, 1)
+	assert.False(t, db.Migrator().HasTable("migration_tests"))
+	assert.Error(t, Check(db))
+}
+
+func TestFixNeo3WrapperUsers(t *testing.T) {
+	db := dbtest.Open(t)
+	_, err := Up(db, 3)
+	assert.NoError(t, err)
+
+	wrapperTransactions := []*models.WrapperTransaction{
+		{Hash: "01", User: "bbaa", DstUser: "ddcc", SrcChainId: basedef.NEO3_CROSSCHAIN_ID, FeeAmount: models.NewBigIntFromInt(0), PaidGas: models.NewBigIntFromInt(0)},
+		{Hash: "02", User: "bbaa", DstUser: "ddcc", SrcChainId: basedef.NEO3_CROSSCHAIN_ID, FeeAmount: models.NewBigIntFromInt(0), PaidGas: models.NewBigIntFromInt(0)},
+	}
+	srcTransfers := []*models.SrcTransfer{
+		{TxHash: "01", From: "aabb", DstUser: "ccdd", Amount: models.NewBigIntFromInt(1)},
+		{TxHash: "02", From: "aabb", DstUser: "", Amount: models.NewBigIntFromInt(1)},
+	}
+	assert.NoError(t, db.Create(wrapperTransactions).Error)
+	assert.NoError(t, db.Create(srcTransfers).Error)
+
+	_, err = Up(db, 0)
+	assert.NoError(t, err)
+
+	wrapperTransactions = make([]*models.WrapperTransaction, 0)
+	assert.NoError(t, db.Order("hash").Find(&wrapperTransactions).Error)
+	for _, wrapperTransaction := range wrapperTransactions {
+		assert.Equal(t, "aabb", wrapperTransaction.User)
+		assert.Equal(t, "ccdd", wrapperTransaction.DstUser)
+	}
+	srcTransfer := new(models.SrcTransfer)
+	assert.NoError(t, db.Where("tx_hash = ?", "02").First(srcTransfer).Error)
+	assert.Equal(t, "ccdd", srcTransfer.DstUser)
+}
+
+func TestV1Tables(t *testing.T) {
+	current := []interface{}{
+		&models.BackfillJob{}, &models.EventOutbox{}, &models.WebhookSubscription{}, &models.WebhookDelivery{},
+		&models.ChainFee{}, &models.Chain{}, &models.DstSwap{}, &models.DstTransaction{}, &models.DstTransfer{},
+		&models.NFTProfile{}, &models.PolyTransaction{}, &models.PriceMarket{}, &models.SrcSwap{},
+		&models.SrcTransaction{}, &models.SrcTransfer{}, &models.TimeStatistic{}, &models.TokenBasic{},
+		&models.TokenMap{}, &models.Token{}, &models.WrapperTransaction{},
+	}
+	assert.Len(t, v1Tables, len(current))
+	db := dbtest.Open(t)
+	for i, value := range v1Tables {
+		stmt := &gorm.Statement{DB: db}
+		assert.NoError(t, stmt.Parse(value))
+		model := &gorm.Statement{DB: db}
+		assert.NoError(t, model.Parse(current[i]))
+		assert.Equal(t, model.Schema.Table, stmt.Schema.Table)
+		// the frozen columns are still in the models, the new ones are added by later migrations
+		for _, name := range stmt.Schema.DBNames {
+			assert.NotNil(t, model.Schema.LookUpField(name), "%s.%s", stmt.Schema.Table, name)
+		}
+	}
+
+	// the columns added after migration 1 are created by their migrations
+	_, err := Up(db, 1)
+	assert.NoError(t, err)
+	assert.False(t, db.Migrator().HasColumn(&models.PolyTransaction{}, "dst_sequence"))
+	assert.False(t, db.Migrator().HasColumn(&models.ChainFee{}, "l1_data_fee"))
+	_, err = Up(db, 0)
+	assert.NoError(t, err)
+	assert.True(t, db.Migrator().HasColumn(&models.PolyTransaction{}, "dst_sequence"))
+	assert.True(t, db.Migrator().HasColumn(&models.ChainFee{}, "l1_data_fee"))
+}
@@ -0,0 +1,363 @@
+/*
+ * Copyright (C) 2020 The poly network Authors
+ * This file is part of The poly network library.
+ *
+ * The  poly network  is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU Lesser General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * The  poly network  is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU Lesser General Public License for more details.
+ * You should have received a copy of the GNU Lesser General Public License
+ * along with The poly network .  If not, see <http://www.gnu.org/licenses/>.
+ */
+
+package migration
+
+import (
+	"poly-bridge/archive"
+	"poly-bridge/basedef"
+	"poly-bridge/crosschaindao/bridgedao"
+	"poly-bridge/models"
+	"poly-bridge/utils/database"
+
+	"github.com/beego/beego/v2/core/logs"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// migrations are applied by version, a new schema change is appended with the next version and never edits the applied ones
+var migrations = []*Migration{
+	{
+		Version: 1,
+		Name:    "create_tables",
+		Up: func(db *gorm.DB) error {
+			return db.AutoMigrate(v1Tables...)
+		},
+	},
+	{
+		Version: 2,
+		Name:    "create_lock_token_statistics",
+		Up: func(db *gorm.DB) error {
+			return db.AutoMigrate(&models.LockTokenStatistic{})
+		},
+		Down: func(db *gorm.DB) error {
+			return db.Migrator().DropTable(&models.LockTokenStatistic{})
+		},
+	},
+	{
+		Version: 3,
+		Name:    "ripple_tables",
+		Up: func(db *gorm.DB) error {
+			if err := addSequenceColumn(db, &models.PolyTransaction{}, "poly_transactions", "dst_sequence"); err != nil {
+				return err
+			}
+			if err := addSequenceColumn(db, &models.DstTransaction{}, "dst_transactions", "sequence"); err != nil {
+				return err
+			}
+			return db.AutoMigrate(&models.WrapperDetail{}, &models.PolyDetail{})
+		},
+		Down: func(db *gorm.DB) error {
+			if err := db.Migrator().DropTable(&models.WrapperDetail{}, &models.PolyDetail{}); err != nil {
+				return err
+			}
+			if err := db.Migrator().DropColumn(&models.DstTransaction{}, "sequence"); err != nil {
+				return err
+			}
+			return db.Migrator().DropColumn(&models.PolyTransaction{}, "dst_sequence")
+		},
+	},
+	{
+		Version: 4,
+		Name:    "neo3_wrapper_user_and_dst_user",
+		Up:      fixNeo3WrapperUsers,
+	},
+	{
+		Version: 5,
+		Name:    "create_cross_chain_txs",
+		// the backfill commits batch by batch, an interrupted run starts over on the saved rows
+		NoTransaction: true,
+		Up:            createCrossChainTxs,
+		Down: func(db *gorm.DB) error {
+			return db.Migrator().DropTable(&models.CrossChainTx{})
+		},
+	},
+	{
+		Version: 6,
+		Name:    "create_scan_cursors",
+		Up: func(db *gorm.DB) error {
+			if err := db.AutoMigrate(&models.ScanCursor{}); err != nil {
+				return err
+			}
+			for _, table := range scanTables {
+				index := "idx_" + table.name + "_time_id"
+				if db.Migrator().HasIndex(table.model, index) {
+					continue
+				}
+				err := db.Exec("CREATE INDEX ? ON ? (?, ?)", clause.Column{Name: index}, clause.Table{Name: table.name},
+					clause.Column{Name: "time"}, clause.Column{Name: "id"}).Error
+				if err != nil {
+					return err
+				}
+			}
+			return nil
+		},
+		Down: func(db *gorm.DB) error {
+			for _, table := range scanTables {
+				if err := db.Migrator().DropIndex(table.model, "idx_"+table.name+"_time_id"); err != nil {
+					return err
+				}
+			}
+			return db.Migrator().DropTable(&models.ScanCursor{})
+		},
+	},
+	{
+		Version: 7,
+		Name:    "create_archive_tables",
+		Up: func(db *gorm.DB) error {
+			archived, err := database.Archive(db)
+			if err != nil {
+				return err
+			}
+			return archived.AutoMigrate(archive.Models()...)
+		},
+		Down: func(db *gorm.DB) error {
+			archived, err := database.Archive(db)
+			if err != nil {
+				return err
+			}
+			values := archive.Models()
+			for i := len(values) - 1; i >= 0; i-- {
+				if err := archived.Migrator().DropTable(values[i]); err != nil {
+					return err
+				}
+			}
+			return nil
+		},
+	},
+	{
+		Version: 8,
+		Name:    "create_transfer_audit_tables",
+		Up: func(db *gorm.DB) error {
+			return db.AutoMigrate(&models.TransferAuditFinding{}, &models.TransferAuditTotal{})
+		},
+		Down: func(db *gorm.DB) error {
+			return db.Migrator().DropTable(&models.TransferAuditTotal{}, &models.TransferAuditFinding{})
+		},
+	},
+	{
+		Version: 9,
+		Name:    "chain_fee_components",
+		Up: func(db *gorm.DB) error {
+			for _, column := range chainFeeComponents {
+				if db.Migrator().HasColumn(&models.ChainFee{}, column) {
+					continue
+				}
+				if err := db.Migrator().AddColumn(&models.ChainFee{}, column); err != nil {
+					return err
+				}
+			}
+			return nil
+		},
+		Down: func(db *gorm.DB) error {
+			for _, column := range chainFeeComponents {
+				if err := db.Migrator().DropColumn(&models.ChainFee{}, column); err != nil {
+					return err
+				}
+			}
+			return nil
+		},
+	},
+	{
+		Version: 10,
+		Name:    "create_chain_fee_history",
+		Up: func(db *gorm.DB) error {
+			return db.AutoMigrate(&models.ChainFeeHistory{})
+		},
+		Down: func(db *gorm.DB) error {
+			return db.Migrator().DropTable(&models.ChainFeeHistory{})
+		},
+	},
+	{
+		Version: 11,
+		Name:    "chain_fee_l1_data_fee",
+		Up: func(db *gorm.DB) error {
+			for _, model := range []interface{}{&models.ChainFee{}, &models.ChainFeeHistory{}} {
+				if db.Migrator().HasColumn(model, "l1_data_fee") {
+					continue
+				}
+				if err := db.Migrator().AddColumn(model, "l1_data_fee"); err != nil {
+					return err
+				}
+			}
+			return nil
+		},
+		Down: func(db *gorm.DB) error {
+			for _, model := range []interface{}{&models.ChainFee{}, &models.ChainFeeHistory{}} {
+				if err := db.Migrator().DropColumn(model, "l1_data_fee"); err != nil {
+					return err
+				}
+			}
+			return nil
+		},
+	},
+	{
+		Version: 12,
+		Name:    "create_block_hashes",
+		Up: func(db *gorm.DB) error {
+			return db.AutoMigrate(&models.BlockHash{})
+		},
+		Down: func(db *gorm.DB) error {
+			return db.Migrator().DropTable(&models.BlockHash{})
+		},
+	},
+	{
+		Version: 13,
+		Name:    "create_scan_retries",
+		Up: func(db *gorm.DB) error {
+			return db.AutoMigrate(&models.ScanRetry{})
+		},
+		Down: func(db *gorm.DB) error {
+			return db.Migrator().DropTable(&models.ScanRetry{})
+		},
+	},
+	{
+		Version: 14,
+		Name:    "create_fee_quotes",
+		Up: func(db *gorm.DB) error {
+			return db.AutoMigrate(&models.FeeQuote{})
+		},
+		Down: func(db *gorm.DB) error {
+			return db.Migrator().DropTable(&models.FeeQuote{})
+		},
+	},
+	{
+		Version: 15,
+		Name:    "create_webhook_tables",
+		Up: func(db *gorm.DB) error {
+			return db.AutoMigrate(&models.WebhookSubscription{}, &models.WebhookDelivery{})
+		},
+		Down: func(db *gorm.DB) error {
+			return db.Migrator().DropTable(&models.WebhookDelivery{}, &models.WebhookSubscription{})
+		},
+	},
+	{
+		Version: 16,
+		Name:    "create_backfill_jobs",
+		Up: func(db *gorm.DB) error {
+			return db.AutoMigrate(&models.BackfillJob{})
+		},
+		Down: func(db *gorm.DB) error {
+			return db.Migrator().DropTable(&models.BackfillJob{})
+		},
+	},
+	{
+		Version: 17,
+		Name:    "create_event_outbox",
+		Up: func(db *gorm.DB) error {
+			return db.AutoMigrate(&models.EventOutbox{})
+		},
+		Down: func(db *gorm.DB) error {
+			return db.Migrator().DropTable(&models.EventOutbox{})
+		},
+	},
+}
+
+// addSequenceColumn adds a not null bigint column, its default fills the saved rows
+func addSequenceColumn(db *gorm.DB, model interface{}, table, column string) error {
+	if db.Migrator().HasColumn(model, column) {
+		return nil
+	}
+	return db.Exec("ALTER TABLE ? ADD ? bigint NOT NULL DEFAULT 0", clause.Table{Name: table}, clause.Column{Name: column}).Error
+}
+
+// chainFeeComponents are the EIP-1559 columns of chain_fees
+var chainFeeComponents = []string{"base_fee", "priority_fee", "max_fee_per_gas"}
+
+// scanTables are paged by (time, id) with the scan cursors
+var scanTables = []struct {
+	model interface{}
+	name  string
+}{
+	{&models.SrcTransaction{}, "src_transactions"},
+	{&models.PolyTransaction{}, "poly_transactions"},
+	{&models.DstTransaction{}, "dst_transactions"},
+	{&models.WrapperTransaction{}, "wrapper_transactions"},
+}
+
+// fixNeo3WrapperUsers takes the user and dst user of the neo3 wrapper transactions from the src transfers,
+// the old neo3 listener saved them reversed or without the dst user
+func fixNeo3WrapperUsers(db *gorm.DB) error {
+	wrapperTransactions := make([]*models.WrapperTransaction, 0)
+	return db.Where("src_chain_id = ?", basedef.NEO3_CROSSCHAIN_ID).FindInBatches(&wrapperTransactions, 1000, func(tx *gorm.DB, batch int) error {
+		hashes := make([]string, 0, len(wrapperTransactions))
+		for _, wrapperTransaction := range wrapperTransactions {
+			hashes = append(hashes, wrapperTransaction.Hash)
+		}
+		srcTransfers := make([]*models.SrcTransfer, 0)
+		if err := db.Where("tx_hash in ?", hashes).Find(&srcTransfers).Error; err != nil {
+			return err
+		}
+		hash2Transfer := make(map[string]*models.SrcTransfer, len(srcTransfers))
+		for _, srcTransfer := range srcTransfers {
+			hash2Transfer[srcTransfer.TxHash] = srcTransfer
+		}
+		for _, wrapperTransaction := range wrapperTransactions {
+			srcTransfer, ok := hash2Transfer[wrapperTransaction.Hash]
+			if !ok || (srcTransfer.From == wrapperTransaction.User && srcTransfer.DstUser == wrapperTransaction.DstUser) {
+				continue
+			}
+			if srcTransfer.From == basedef.HexStringReverse(wrapperTransaction.User) && srcTransfer.DstUser == basedef.HexStringReverse(wrapperTransaction.DstUser) {
+				err := db.Model(&models.WrapperTransaction{}).Where("id = ?", wrapperTransaction.Id).
+					Updates(map[string]interface{}{"user": srcTransfer.From, "dst_user": srcTransfer.DstUser}).Error
+				if err != nil {
+					return err
+				}
+			} else if srcTransfer.DstUser == "" {
+				dstUser := basedef.HexStringReverse(wrapperTransaction.DstUser)
+				err := db.Model(&models.WrapperTransaction{}).Where("id = ?", wrapperTransaction.Id).
+					Updates(map[string]interface{}{"user": srcTransfer.From, "dst_user": dstUser}).Error
+				if err != nil {
+					return err
+				}
+				err = db.Model(&models.SrcTransfer{}).Where("id = ?", srcTransfer.Id).Update("dst_user", dstUser).Error
+				if err != nil {
+					return err
+				}
+			} else {
+				logs.Warn("neo3 wrapper transaction %d %s does not match its src transfer", wrapperTransaction.Id, wrapperTransaction.Hash)
+			}
+		}
+		return nil
+	}).Error
+}
+
+const crossChainTxBatch = 1000
+
+// createCrossChainTxs fills the cross_chain_txs table with the saved events, the new ones are added by UpdateEvents.
+// Every batch is written in its own transaction, the rows are upserted so a rerun does not duplicate them.
+func createCrossChainTxs(db *gorm.DB) error {
+	if err := db.AutoMigrate(&models.CrossChainTx{}); err != nil {
+		return err
+	}
+	srcTransactions := make([]*models.SrcTransaction, 0)
+	err := db.Preload("SrcTransfer").FindInBatches(&srcTransactions, crossChainTxBatch, func(_ *gorm.DB, batch int) error {
+		logs.Info("migration create_cross_chain_txs src transactions batch %d", batch)
+		return db.Transaction(func(tx *gorm.DB) error {
+			return bridgedao.UpdateCrossChainTxs(tx, nil, srcTransactions, nil, nil)
+		})
+	}).Error
+	if err != nil {
+		return err
+	}
+	wrapperTransactions := make([]*models.WrapperTransaction, 0)
+	return db.FindInBatches(&wrapperTransactions, crossChainTxBatch, func(_ *gorm.DB, batch int) error {
+		logs.Info("migration create_cross_chain_txs wrapper transactions batch %d", batch)
+		return db.Transaction(func(tx *gorm.DB) error {
+			return bridgedao.UpdateCrossChainTxs(tx, wrapperTransactions, nil, nil, nil)
+		})
+	}).Error
+}
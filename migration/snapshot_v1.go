@@ -0,0 +1,373 @@
+/*
+ * Copyright (C) 2020 The poly network Authors
+ * This file is part of The poly network library.
+ *
+ * The  poly network  is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU Lesser General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * The  poly network  is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU Lesser General Public License for more details.
+ * You should have received a copy of the GNU Lesser General Public License
+ * along with The poly network .  If not, see <http://www.gnu.org/licenses/>.
+ */
+
+package migration
+
+import "poly-bridge/models"
+
+// The v1 structs freeze the tables created by migration 1, the later columns of the models are added by their
+// own migrations. They must never be changed, a new column goes to a new migration.
+
+type v1BackfillJob struct {
+	Id          int64  `gorm:"primaryKey;autoIncrement"`
+	ChainId     uint64 `gorm:"index;type:bigint;not null"`
+	StartHeight uint64 `gorm:"type:bigint;not null"`
+	EndHeight   uint64 `gorm:"type:bigint;not null"`
+	Height      uint64 `gorm:"type:bigint;not null"`
+	BatchLength uint64 `gorm:"type:bigint;not null"`
+	Concurrency uint64 `gorm:"type:bigint;not null"`
+	Status      string `gorm:"index;type:varchar(16);not null"`
+	Error       string `gorm:"type:varchar(512)"`
+	CreateTime  int64  `gorm:"type:bigint;not null"`
+	UpdateTime  int64  `gorm:"type:bigint;not null"`
+}
+
+func (v1BackfillJob) TableName() string { return "backfill_jobs" }
+
+type v1EventOutbox struct {
+	Id         int64  `gorm:"primaryKey;autoIncrement"`
+	Type       string `gorm:"type:varchar(16);not null"`
+	Hash       string `gorm:"type:varchar(66);not null"`
+	Payload    string `gorm:"type:varchar(4096);not null"`
+	Status     string `gorm:"index;type:varchar(16);not null"`
+	Attempts   int    `gorm:"type:int;not null"`
+	Error      string `gorm:"type:varchar(512)"`
+	NextTime   int64  `gorm:"index;type:bigint;not null"`
+	CreateTime int64  `gorm:"type:bigint;not null"`
+}
+
+func (v1EventOutbox) TableName() string { return "event_outboxes" }
+
+type v1WebhookSubscription struct {
+	Id         int64  `gorm:"primaryKey;autoIncrement"`
+	Url        string `gorm:"type:varchar(512);not null"`
+	Secret     string `gorm:"type:varchar(128);not null"`
+	SrcHash    string `gorm:"index;type:varchar(66);not null"`
+	User       string `gorm:"index;type:varchar(66);not null"`
+	ChainId    uint64 `gorm:"type:bigint;not null"`
+	Token      string `gorm:"index;type:varchar(120);not null"`
+	States     string `gorm:"type:varchar(64);not null"`
+	CreateTime int64  `gorm:"type:bigint;not null"`
+}
+
+func (v1WebhookSubscription) TableName() string { return "webhook_subscriptions" }
+
+type v1WebhookDelivery struct {
+	Id             int64  `gorm:"primaryKey;autoIncrement"`
+	SubscriptionId int64  `gorm:"uniqueIndex:idx_webhook_delivery;type:bigint;not null"`
+	SrcHash        string `gorm:"uniqueIndex:idx_webhook_delivery;type:varchar(66);not null"`
+	State          uint64 `gorm:"uniqueIndex:idx_webhook_delivery;type:bigint;not null"`
+	Payload        string `gorm:"type:varchar(2048);not null"`
+	Status         string `gorm:"index;type:varchar(16);not null"`
+	Attempts       int    `gorm:"type:int;not null"`
+	ResponseCode   int    `gorm:"type:int;not null"`
+	Error          string `gorm:"type:varchar(512)"`
+	NextTime       int64  `gorm:"index;type:bigint;not null"`
+	CreateTime     int64  `gorm:"type:bigint;not null"`
+	UpdateTime     int64  `gorm:"type:bigint;not null"`
+}
+
+func (v1WebhookDelivery) TableName() string { return "webhook_deliveries" }
+
+type v1TokenBasic struct {
+	Id              int64            `gorm:"primaryKey;autoIncrement"`
+	Name            string           `gorm:"uniqueIndex;size:64;not null"`
+	Precision       uint64           `gorm:"type:bigint;not null"`
+	Price           int64            `gorm:"size:64;not null"`
+	ChainId         uint64           `gorm:"type:bigint;not null"`
+	Ind             uint64           `gorm:"type:bigint;not null"`
+	Time            int64            `gorm:"type:bigint;not null"`
+	Property        int64            `gorm:"type:bigint;not null"`
+	Standard        uint8            `gorm:"type:int;not null"`
+	Meta            string           `gorm:"type:varchar(128)"`
+	TotalAmount     *models.BigInt   `gorm:"type:varchar(64)"`
+	TotalCount      uint64           `gorm:"type:bigint"`
+	StatsUpdateTime int64            `gorm:"type:bigint"`
+	SocialTwitter   string           `gorm:"type:varchar(256)"`
+	SocialTelegram  string           `gorm:"type:varchar(256)"`
+	SocialWebsite   string           `gorm:"type:varchar(256)"`
+	SocialOther     string           `gorm:"type:varchar(256)"`
+	MetaFetcherType int              `gorm:"type:int;not null"`
+	Rank            int              `gorm:"type:int;not null"`
+	PriceMarkets    []*v1PriceMarket `gorm:"foreignKey:TokenBasicName;references:Name"`
+	Tokens          []*v1Token       `gorm:"foreignKey:TokenBasicName;references:Name"`
+}
+
+func (v1TokenBasic) TableName() string { return "token_basics" }
+
+type v1PriceMarket struct {
+	Id             int64         `gorm:"primaryKey;autoIncrement"`
+	TokenBasicName string        `gorm:"uniqueIndex:idx_tokenmarket;size:64;not null"`
+	MarketName     string        `gorm:"uniqueIndex:idx_tokenmarket;size:64;not null"`
+	CoinMarketId   int           `gorm:"type:int"`
+	Name           string        `gorm:"size:64;not null"`
+	Price          int64         `gorm:"type:bigint;not null"`
+	Ind            uint64        `gorm:"type:bigint;not null"`
+	Time           int64         `gorm:"type:bigint;not null"`
+	Rank           int           `gorm:"type:int;not null"`
+	TokenBasic     *v1TokenBasic `gorm:"foreignKey:TokenBasicName;references:Name"`
+}
+
+func (v1PriceMarket) TableName() string { return "price_markets" }
+
+type v1ChainFee struct {
+	Id             int64          `gorm:"primaryKey;autoIncrement"`
+	ChainId        uint64         `gorm:"uniqueIndex;type:bigint;not null"`
+	TokenBasicName string         `gorm:"size:64;not null"`
+	TokenBasic     *v1TokenBasic  `gorm:"foreignKey:TokenBasicName;references:Name"`
+	MaxFee         *models.BigInt `gorm:"type:varchar(64);not null"`
+	MinFee         *models.BigInt `gorm:"type:varchar(64);not null"`
+	ProxyFee       *models.BigInt `gorm:"type:varchar(64);not null"`
+	Ind            uint64         `gorm:"type:bigint;not null"`
+	Time           int64          `gorm:"type:bigint;not null"`
+}
+
+func (v1ChainFee) TableName() string { return "chain_fees" }
+
+type v1Chain struct {
+	Id                       int64  `gorm:"primaryKey;autoIncrement"`
+	ChainId                  uint64 `gorm:"uniqueIndex;type:bigint;not null"`
+	Name                     string `gorm:"type:varchar(32)"`
+	Height                   uint64 `gorm:"type:bigint;not null"`
+	CrossChainSequenceNumber uint64 `gorm:"type:bigint;not null"`
+	ExecuteTxSequenceNumber  uint64 `gorm:"type:bigint;not null"`
+	HeightSwap               uint64 `gorm:"type:bigint;not null"`
+	BackwardBlockNumber      uint64 `gorm:"type:bigint;not null"`
+	ChainLogo                string `gorm:"type:varchar(128)"`
+	ChainExplorerUrl         string `gorm:"type:varchar(128)"`
+}
+
+func (v1Chain) TableName() string { return "chains" }
+
+type v1DstSwap struct {
+	Id         int64          `gorm:"primaryKey;autoIncrement"`
+	TxHash     string         `gorm:"uniqueIndex;size:66;not null"`
+	ChainId    uint64         `gorm:"type:bigint;not null"`
+	Time       uint64         `gorm:"type:bigint;not null"`
+	PoolId     uint64         `gorm:"type:bigint;not null"`
+	InAsset    string         `gorm:"type:varchar(120);not null"`
+	InAmount   *models.BigInt `gorm:"type:varchar(64);not null"`
+	OutAsset   string         `gorm:"type:varchar(120);not null"`
+	OutAmount  *models.BigInt `gorm:"type:varchar(64);not null"`
+	DstChainId uint64         `gorm:"type:bigint;not null"`
+	DstAsset   string         `gorm:"type:varchar(120);not null"`
+	DstUser    string         `gorm:"type:varchar(66);not null"`
+	Type       uint64         `gorm:"type:bigint;not null"`
+}
+
+func (v1DstSwap) TableName() string { return "dst_swaps" }
+
+type v1DstTransaction struct {
+	Id          int64          `gorm:"primaryKey;autoIncrement"`
+	Hash        string         `gorm:"uniqueIndex;size:66;not null"`
+	ChainId     uint64         `gorm:"type:bigint;not null"`
+	Standard    uint8          `gorm:"type:int;not null"`
+	State       uint64         `gorm:"type:bigint;not null"`
+	Time        uint64         `gorm:"type:bigint;not null"`
+	Fee         *models.BigInt `gorm:"type:varchar(64);not null"`
+	Height      uint64         `gorm:"type:bigint;not null"`
+	SrcChainId  uint64         `gorm:"type:bigint;not null"`
+	Contract    string         `gorm:"type:varchar(66);not null"`
+	PolyHash    string         `gorm:"index;size:66;not null"`
+	DstTransfer *v1DstTransfer `gorm:"foreignKey:TxHash;references:Hash"`
+	DstSwap     *v1DstSwap     `gorm:"foreignKey:TxHash;references:Hash"`
+}
+
+func (v1DstTransaction) TableName() string { return "dst_transactions" }
+
+type v1DstTransfer struct {
+	Id       int64          `gorm:"primaryKey;autoIncrement"`
+	TxHash   string         `gorm:"uniqueIndex;size:66;not null"`
+	ChainId  uint64         `gorm:"type:bigint;not null"`
+	Standard uint8          `gorm:"type:int;not null"`
+	Time     uint64         `gorm:"type:bigint;not null"`
+	Asset    string         `gorm:"type:varchar(120);not null"`
+	From     string         `gorm:"type:varchar(66);not null"`
+	To       string         `gorm:"type:varchar(66);not null"`
+	Amount   *models.BigInt `gorm:"type:varchar(80);not null"`
+}
+
+func (v1DstTransfer) TableName() string { return "dst_transfers" }
+
+type v1NFTProfile struct {
+	Id             int64  `gorm:"primaryKey;autoIncrement"`
+	TokenBasicName string `gorm:"uniqueIndex:idx_name_token;size:64;not null"`
+	NftTokenId     string `gorm:"uniqueIndex:idx_name_token;type:varchar(64);not null"`
+	Name           string `gorm:"size:64;not null"`
+	Url            string `gorm:"type:varchar(256)"`
+	Image          string `gorm:"type:varchar(256);not null"`
+	Description    string `gorm:"type:varchar(256)"`
+	Text           string `gorm:"type:text"`
+}
+
+func (v1NFTProfile) TableName() string { return "nft_profiles" }
+
+type v1PolyTransaction struct {
+	Id         int64          `gorm:"primaryKey;autoIncrement"`
+	Hash       string         `gorm:"uniqueIndex;size:66;not null"`
+	ChainId    uint64         `gorm:"type:bigint;not null"`
+	State      uint64         `gorm:"type:bigint;not null"`
+	Time       uint64         `gorm:"type:bigint;not null"`
+	Fee        *models.BigInt `gorm:"type:varchar(64);not null"`
+	Height     uint64         `gorm:"type:bigint;not null"`
+	SrcChainId uint64         `gorm:"type:bigint;not null"`
+	SrcHash    string         `gorm:"index;size:66;not null"`
+	DstChainId uint64         `gorm:"type:bigint;not null"`
+	Key        string         `gorm:"type:varchar(8192);not null"`
+}
+
+func (v1PolyTransaction) TableName() string { return "poly_transactions" }
+
+type v1SrcSwap struct {
+	Id         int64          `gorm:"primaryKey;autoIncrement"`
+	TxHash     string         `gorm:"uniqueIndex;size:66;not null"`
+	ChainId    uint64         `gorm:"type:bigint;not null"`
+	Time       uint64         `gorm:"type:bigint;not null"`
+	Asset      string         `gorm:"type:varchar(120);not null"`
+	From       string         `gorm:"type:varchar(66);not null"`
+	To         string         `gorm:"type:varchar(66);not null"`
+	Amount     *models.BigInt `gorm:"type:varchar(64);not null"`
+	PoolId     uint64         `gorm:"type:bigint;not null"`
+	DstChainId uint64         `gorm:"type:bigint;not null"`
+	DstAsset   string         `gorm:"type:varchar(120);not null"`
+	DstUser    string         `gorm:"type:varchar(66);not null"`
+	Type       uint64         `gorm:"type:bigint;not null"`
+}
+
+func (v1SrcSwap) TableName() string { return "src_swaps" }
+
+type v1SrcTransaction struct {
+	Id          int64          `gorm:"primaryKey;autoIncrement"`
+	Hash        string         `gorm:"uniqueIndex;size:66;not null"`
+	ChainId     uint64         `gorm:"type:bigint;not null"`
+	Standard    uint8          `gorm:"type:int;not null"`
+	State       uint64         `gorm:"type:bigint;not null"`
+	Time        uint64         `gorm:"type:bigint;not null"`
+	Fee         *models.BigInt `gorm:"type:varchar(64);not null"`
+	Height      uint64         `gorm:"type:bigint;not null"`
+	User        string         `gorm:"type:varchar(66);not null"`
+	DstChainId  uint64         `gorm:"type:bigint;not null"`
+	Contract    string         `gorm:"type:varchar(66);not null"`
+	Key         string         `gorm:"index;size:128;not null"`
+	Param       string         `gorm:"type:varchar(8192);not null"`
+	SrcTransfer *v1SrcTransfer `gorm:"foreignKey:TxHash;references:Hash"`
+	SrcSwap     *v1SrcSwap     `gorm:"foreignKey:TxHash;references:Hash"`
+}
+
+func (v1SrcTransaction) TableName() string { return "src_transactions" }
+
+type v1SrcTransfer struct {
+	Id         int64          `gorm:"primaryKey;autoIncrement"`
+	TxHash     string         `gorm:"uniqueIndex;size:66;not null"`
+	ChainId    uint64         `gorm:"type:bigint;not null"`
+	Standard   uint8          `gorm:"type:int;not null"`
+	Time       uint64         `gorm:"type:bigint;not null"`
+	Asset      string         `gorm:"type:varchar(120);not null"`
+	From       string         `gorm:"type:varchar(66);not null"`
+	To         string         `gorm:"type:varchar(66);not null"`
+	Amount     *models.BigInt `gorm:"type:varchar(80);not null"`
+	DstChainId uint64         `gorm:"type:bigint;not null"`
+	DstAsset   string         `gorm:"type:varchar(120);not null"`
+	DstUser    string         `gorm:"type:varchar(66);not null"`
+	Token      *v1Token       `gorm:"foreignKey:Hash,ChainId;references:Asset,ChainId"`
+}
+
+func (v1SrcTransfer) TableName() string { return "src_transfers" }
+
+type v1TimeStatistic struct {
+	Id         int64  `gorm:"primaryKey;autoIncrement"`
+	SrcChainId uint64 `gorm:"uniqueIndex:idx_chains;type:bigint;not null"`
+	DstChainId uint64 `gorm:"uniqueIndex:idx_chains;type:bigint;not null"`
+	Time       uint64 `gorm:"type:bigint;not null"`
+}
+
+func (v1TimeStatistic) TableName() string { return "time_statistics" }
+
+type v1TokenMap struct {
+	Id           int64    `gorm:"primaryKey;autoIncrement"`
+	SrcChainId   uint64   `gorm:"uniqueIndex:idx_token_map;type:bigint;not null"`
+	SrcTokenHash string   `gorm:"uniqueIndex:idx_token_map;size:120;not null"`
+	DstChainId   uint64   `gorm:"uniqueIndex:idx_token_map;type:bigint;not null"`
+	DstTokenHash string   `gorm:"uniqueIndex:idx_token_map;size:120;not null"`
+	SrcToken     *v1Token `gorm:"foreignKey:SrcTokenHash,SrcChainId;references:Hash,ChainId"`
+	DstToken     *v1Token `gorm:"foreignKey:DstTokenHash,DstChainId;references:Hash,ChainId"`
+	Standard     uint8    `gorm:"type:int;not null"`
+	Property     int64    `gorm:"type:bigint;not null"`
+}
+
+func (v1TokenMap) TableName() string { return "token_maps" }
+
+type v1Token struct {
+	Id              int64          `gorm:"primaryKey;autoIncrement"`
+	Hash            string         `gorm:"uniqueIndex:idx_token;size:120;not null"`
+	ChainId         uint64         `gorm:"uniqueIndex:idx_token;type:bigint;not null"`
+	Name            string         `gorm:"size:64;not null"`
+	Precision       uint64         `gorm:"type:bigint;not null"`
+	TokenBasicName  string         `gorm:"size:64;not null"`
+	Property        int64          `gorm:"type:bigint;not null"`
+	Standard        uint8          `gorm:"type:int;not null"`
+	TokenType       string         `gorm:"type:varchar(32)"`
+	AvailableAmount *models.BigInt `gorm:"type:varchar(64)"`
+	TokenBasic      *v1TokenBasic  `gorm:"foreignKey:TokenBasicName;references:Name"`
+	TokenMaps       []*v1TokenMap  `gorm:"foreignKey:SrcTokenHash,SrcChainId;references:Hash,ChainId"`
+}
+
+func (v1Token) TableName() string { return "tokens" }
+
+type v1WrapperTransaction struct {
+	Id           int64          `gorm:"primaryKey;autoIncrement"`
+	Hash         string         `gorm:"uniqueIndex;size:66;not null"`
+	User         string         `gorm:"type:varchar(66);not null"`
+	SrcChainId   uint64         `gorm:"type:bigint;not null"`
+	Standard     uint8          `gorm:"type:int;not null"`
+	BlockHeight  uint64         `gorm:"type:bigint;not null"`
+	Time         uint64         `gorm:"type:bigint;not null"`
+	DstChainId   uint64         `gorm:"type:bigint;not null"`
+	DstUser      string         `gorm:"type:varchar(66);not null"`
+	ServerId     uint64         `gorm:"type:bigint;not null"`
+	FeeTokenHash string         `gorm:"size:66;not null"`
+	FeeAmount    *models.BigInt `gorm:"type:varchar(64);not null"`
+	Status       uint64         `gorm:"type:bigint;not null"`
+	IsPaid       bool           `gorm:"not null"`
+	PaidGas      *models.BigInt `gorm:"type:varchar(64);not null"`
+}
+
+func (v1WrapperTransaction) TableName() string { return "wrapper_transactions" }
+
+// v1Tables are the tables of migration 1 in the order they were created
+var v1Tables = []interface{}{
+	&v1BackfillJob{},
+	&v1EventOutbox{},
+	&v1WebhookSubscription{},
+	&v1WebhookDelivery{},
+	&v1ChainFee{},
+	&v1Chain{},
+	&v1DstSwap{},
+	&v1DstTransaction{},
+	&v1DstTransfer{},
+	&v1NFTProfile{},
+	&v1PolyTransaction{},
+	&v1PriceMarket{},
+	&v1SrcSwap{},
+	&v1SrcTransaction{},
+	&v1SrcTransfer{},
+	&v1TimeStatistic{},
+	&v1TokenBasic{},
+	&v1TokenMap{},
+	&v1Token{},
+	&v1WrapperTransaction{},
+}
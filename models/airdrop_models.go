@@ -12,13 +12,13 @@ import (
 type AirDropInfo struct {
 	Id          int64  `gorm:"primaryKey;autoIncrement"`
 	User        string `gorm:"uniqueIndex;type:varchar(66);not null"`
-	ChainID     uint64 `gorm:"type:bigint(20);not null"`
-	IsEth       bool   `gorm:"type:int(8);not null"`
+	ChainID     uint64 `gorm:"type:bigint;not null"`
+	IsEth       bool   `gorm:"type:int;not null"`
 	BindAddr    string `gorm:"type:varchar(66);not null"`
-	BindChainId uint64 `gorm:"type:bigint(20);not null"`
-	Amount      int64  `gorm:"type:bigint(20);not null"`
-	SrcTxId     int64  `gorm:"index:airdropinfos_srctxid;type:bigint(20);not null"`
-	IsClaim     bool   `gorm:"type:int(8);not null"`
+	BindChainId uint64 `gorm:"type:bigint;not null"`
+	Amount      int64  `gorm:"type:bigint;not null"`
+	SrcTxId     int64  `gorm:"index:airdropinfos_srctxid;type:bigint;not null"`
+	IsClaim     bool   `gorm:"type:int;not null"`
 }
 
 type AirDropRank struct {
@@ -33,11 +33,11 @@ type AirDropRank struct {
 type TokenPriceAvg struct {
 	Id          int64  `gorm:"primaryKey;autoIncrement"`
 	Name        string `gorm:"uniqueIndex;size:64;not null"`
-	PriceAvg    int64  `gorm:"type:bigint(20);not null"`
-	UpdateTime  int64  `gorm:"type:bigint(20);not null"`
-	PriceTotal  int64  `gorm:"type:bigint(20);not null"`
-	PriceNumber int64  `gorm:"type:bigint(20);not null"`
-	PriceTime   int64  `gorm:"type:bigint(20);not null"`
+	PriceAvg    int64  `gorm:"type:bigint;not null"`
+	UpdateTime  int64  `gorm:"type:bigint;not null"`
+	PriceTotal  int64  `gorm:"type:bigint;not null"`
+	PriceNumber int64  `gorm:"type:bigint;not null"`
+	PriceTime   int64  `gorm:"type:bigint;not null"`
 }
 
 type AirDropReqData struct {
@@ -29,23 +29,23 @@ const (
 type TokenBasic struct {
 	Id              int64          `gorm:"primaryKey;autoIncrement"`
 	Name            string         `gorm:"uniqueIndex;size:64;not null"`
-	Precision       uint64         `gorm:"type:bigint(20);not null"`
+	Precision       uint64         `gorm:"type:bigint;not null"`
 	Price           int64          `gorm:"size:64;not null"`
-	ChainId         uint64         `gorm:"type:bigint(20);not null"` //该tokenbasicname的源链ID
-	Ind             uint64         `gorm:"type:bigint(20);not null"` // 显示价格是否可用
-	Time            int64          `gorm:"type:bigint(20);not null"`
-	Property        int64          `gorm:"type:bigint(20);not null"` // token是否上线, 1为上线
-	Standard        uint8          `gorm:"type:int(8);not null"`     // 0为erc20， 1为erc721
+	ChainId         uint64         `gorm:"type:bigint;not null"` //该tokenbasicname的源链ID
+	Ind             uint64         `gorm:"type:bigint;not null"` // 显示价格是否可用
+	Time            int64          `gorm:"type:bigint;not null"`
+	Property        int64          `gorm:"type:bigint;not null"` // token是否上线, 1为上线
+	Standard        uint8          `gorm:"type:int;not null"`    // 0为erc20， 1为erc721
 	Meta            string         `gorm:"type:varchar(128)"`
 	TotalAmount     *BigInt        `gorm:"type:varchar(64)"`
-	TotalCount      uint64         `gorm:"type:bigint(20)"`
-	StatsUpdateTime int64          `gorm:"type:bigint(20)"`
+	TotalCount      uint64         `gorm:"type:bigint"`
+	StatsUpdateTime int64          `gorm:"type:bigint"`
 	SocialTwitter   string         `gorm:"type:varchar(256)"`
 	SocialTelegram  string         `gorm:"type:varchar(256)"`
 	SocialWebsite   string         `gorm:"type:varchar(256)"`
 	SocialOther     string         `gorm:"type:varchar(256)"`
-	MetaFetcherType int            `gorm:"type:int(8);not null"` // nft meta profile fetcher type, e.g: unknown 0, opensea: 1, standard: 2,
-	Rank            int            `gorm:"type:int(8);not null"`
+	MetaFetcherType int            `gorm:"type:int;not null"` // nft meta profile fetcher type, e.g: unknown 0, opensea: 1, standard: 2,
+	Rank            int            `gorm:"type:int;not null"`
 	PriceMarkets    []*PriceMarket `gorm:"foreignKey:TokenBasicName;references:Name"`
 	Tokens          []*Token       `gorm:"foreignKey:TokenBasicName;references:Name"`
 }
@@ -54,25 +54,76 @@ type PriceMarket struct {
 	Id             int64       `gorm:"primaryKey;autoIncrement"`
 	TokenBasicName string      `gorm:"uniqueIndex:idx_tokenmarket;size:64;not null"`
 	MarketName     string      `gorm:"uniqueIndex:idx_tokenmarket;size:64;not null"`
-	CoinMarketId   int         `gorm:"type:int(32)"`
+	CoinMarketId   int         `gorm:"type:int"`
 	Name           string      `gorm:"size:64;not null"`
-	Price          int64       `gorm:"type:bigint(20);not null"`
-	Ind            uint64      `gorm:"type:bigint(20);not null"`
-	Time           int64       `gorm:"type:bigint(20);not null"`
-	Rank           int         `gorm:"type:int(8);not null"`
+	Price          int64       `gorm:"type:bigint;not null"`
+	Ind            uint64      `gorm:"type:bigint;not null"`
+	Time           int64       `gorm:"type:bigint;not null"`
+	Rank           int         `gorm:"type:int;not null"`
 	TokenBasic     *TokenBasic `gorm:"foreignKey:TokenBasicName;references:Name"`
 }
 
 type ChainFee struct {
 	Id             int64       `gorm:"primaryKey;autoIncrement"`
-	ChainId        uint64      `gorm:"uniqueIndex;type:bigint(20);not null"`
+	ChainId        uint64      `gorm:"uniqueIndex;type:bigint;not null"`
 	TokenBasicName string      `gorm:"size:64;not null"`
 	TokenBasic     *TokenBasic `gorm:"foreignKey:TokenBasicName;references:Name"`
 	MaxFee         *BigInt     `gorm:"type:varchar(64);not null"`
 	MinFee         *BigInt     `gorm:"type:varchar(64);not null"`
 	ProxyFee       *BigInt     `gorm:"type:varchar(64);not null"`
-	Ind            uint64      `gorm:"type:bigint(20);not null"`
-	Time           int64       `gorm:"type:bigint(20);not null"`
+	Ind            uint64      `gorm:"type:bigint;not null"`
+	Time           int64       `gorm:"type:bigint;not null"`
+	BaseFee        *BigInt     `gorm:"type:varchar(64)"` //predicted base fee per gas of the next block, 0 if the chain is not priced by EIP-1559
+	PriorityFee    *BigInt     `gorm:"type:varchar(64)"`
+	MaxFeePerGas   *BigInt     `gorm:"type:varchar(64)"` //BaseFee + PriorityFee, MaxFee/MinFee/ProxyFee are priced from it
+	L1DataFee      *BigInt     `gorm:"type:varchar(64)"` //L1 data fee of a cross chain tx measured by the L1 fee strategy of a rollup
+}
+
+// ChainFeeHistory is a snapshot of a chain fee appended at every fee update, so the fee quoted at any moment can be rebuilt
+type ChainFeeHistory struct {
+	Id             int64   `gorm:"primaryKey;autoIncrement"`
+	ChainId        uint64  `gorm:"index:idx_chain_fee_history_chain_time;type:bigint;not null"`
+	TokenBasicName string  `gorm:"size:64;not null"`
+	Price          int64   `gorm:"type:bigint;not null"` //price of the fee token at the snapshot
+	MaxFee         *BigInt `gorm:"type:varchar(64);not null"`
+	MinFee         *BigInt `gorm:"type:varchar(64);not null"`
+	ProxyFee       *BigInt `gorm:"type:varchar(64);not null"`
+	BaseFee        *BigInt `gorm:"type:varchar(64)"`
+	PriorityFee    *BigInt `gorm:"type:varchar(64)"`
+	MaxFeePerGas   *BigInt `gorm:"type:varchar(64)"`
+	L1DataFee      *BigInt `gorm:"type:varchar(64)"`
+	Time           int64   `gorm:"index:idx_chain_fee_history_chain_time;index;type:bigint;not null"`
+}
+
+func (ChainFeeHistory) TableName() string {
+	return "chain_fee_history"
+}
+
+// FeeQuote is a signed fee quote getfee issued, it is found by the tag the wrapper lock paying it passes as its id
+// and is paid by one wrapper transaction only
+type FeeQuote struct {
+	Id       int64  `gorm:"primaryKey;autoIncrement"`
+	QuoteId  string `gorm:"type:varchar(1024);not null"`      //the signed quote, see fee.SignQuote
+	ServerId uint64 `gorm:"uniqueIndex;type:bigint;not null"` //the tag of the quote id, see fee.QuoteTag
+	Expiry   int64  `gorm:"index;type:bigint;not null"`
+	UsedHash string `gorm:"size:66;not null"` //the wrapper transaction paying the quote, empty until it is paid
+	Time     int64  `gorm:"type:bigint;not null"`
+}
+
+func NewChainFeeHistory(fee *ChainFee, price int64) *ChainFeeHistory {
+	return &ChainFeeHistory{
+		ChainId:        fee.ChainId,
+		TokenBasicName: fee.TokenBasicName,
+		Price:          price,
+		MaxFee:         fee.MaxFee,
+		MinFee:         fee.MinFee,
+		ProxyFee:       fee.ProxyFee,
+		BaseFee:        fee.BaseFee,
+		PriorityFee:    fee.PriorityFee,
+		MaxFeePerGas:   fee.MaxFeePerGas,
+		L1DataFee:      fee.L1DataFee,
+		Time:           fee.Time,
+	}
 }
 
 type CheckFeeStatus int
@@ -92,12 +143,12 @@ type CheckFeeRequest struct {
 type Token struct {
 	Id              int64       `gorm:"primaryKey;autoIncrement"`
 	Hash            string      `gorm:"uniqueIndex:idx_token;size:120;not null"`
-	ChainId         uint64      `gorm:"uniqueIndex:idx_token;type:bigint(20);not null"`
+	ChainId         uint64      `gorm:"uniqueIndex:idx_token;type:bigint;not null"`
 	Name            string      `gorm:"size:64;not null"`
-	Precision       uint64      `gorm:"type:bigint(20);not null"`
+	Precision       uint64      `gorm:"type:bigint;not null"`
 	TokenBasicName  string      `gorm:"size:64;not null"`
-	Property        int64       `gorm:"type:bigint(20);not null"`
-	Standard        uint8       `gorm:"type:int(8);not null"`
+	Property        int64       `gorm:"type:bigint;not null"`
+	Standard        uint8       `gorm:"type:int;not null"`
 	TokenType       string      `gorm:"type:varchar(32)"`
 	AvailableAmount *BigInt     `gorm:"type:varchar(64)"`
 	TokenBasic      *TokenBasic `gorm:"foreignKey:TokenBasicName;references:Name"`
@@ -106,13 +157,13 @@ type Token struct {
 
 type TokenStatistic struct {
 	Id             int64   `gorm:"primaryKey;autoIncrement"`
-	Hash           string  `gorm:"uniqueIndex:idx_token;size:120;not null"`
-	ChainId        uint64  `gorm:"uniqueIndex:idx_token;type:bigint(20);not null"`
-	InCounter      int64   `gorm:"type:bigint(20)"`
+	Hash           string  `gorm:"uniqueIndex:idx_token_statistic;size:120;not null"`
+	ChainId        uint64  `gorm:"uniqueIndex:idx_token_statistic;type:bigint;not null"`
+	InCounter      int64   `gorm:"type:bigint"`
 	InAmount       *BigInt `gorm:"type:varchar(64)"`
 	InAmountBtc    *BigInt `gorm:"type:varchar(64)"`
 	InAmountUsd    *BigInt `gorm:"type:varchar(64)"`
-	OutCounter     int64   `gorm:"type:bigint(20)"`
+	OutCounter     int64   `gorm:"type:bigint"`
 	OutAmount      *BigInt `gorm:"type:varchar(64)"`
 	OutAmountBtc   *BigInt `gorm:"type:varchar(64)"`
 	OutAmountUsd   *BigInt `gorm:"type:varchar(64)"`
@@ -123,31 +174,31 @@ type TokenStatistic struct {
 
 type TokenMap struct {
 	Id           int64  `gorm:"primaryKey;autoIncrement"`
-	SrcChainId   uint64 `gorm:"uniqueIndex:idx_token_map;type:bigint(20);not null"`
+	SrcChainId   uint64 `gorm:"uniqueIndex:idx_token_map;type:bigint;not null"`
 	SrcTokenHash string `gorm:"uniqueIndex:idx_token_map;size:120;not null"`
-	DstChainId   uint64 `gorm:"uniqueIndex:idx_token_map;type:bigint(20);not null"`
+	DstChainId   uint64 `gorm:"uniqueIndex:idx_token_map;type:bigint;not null"`
 	DstTokenHash string `gorm:"uniqueIndex:idx_token_map;size:120;not null"`
 	SrcToken     *Token `gorm:"foreignKey:SrcTokenHash,SrcChainId;references:Hash,ChainId"`
 	DstToken     *Token `gorm:"foreignKey:DstTokenHash,DstChainId;references:Hash,ChainId"`
-	Standard     uint8  `gorm:"type:int(8);not null"`
-	Property     int64  `gorm:"type:bigint(20);not null"`
+	Standard     uint8  `gorm:"type:int;not null"`
+	Property     int64  `gorm:"type:bigint;not null"`
 }
 
 type WrapperTransactionWithToken struct {
 	Id           int64   `gorm:"primaryKey;autoIncrement"`
 	Hash         string  `gorm:"uniqueIndex;size:66;not null"`
 	User         string  `gorm:"size:64"`
-	SrcChainId   uint64  `gorm:"type:bigint(20);not null"`
-	BlockHeight  uint64  `gorm:"type:bigint(20);not null"`
-	Time         uint64  `gorm:"type:bigint(20);not null"`
-	DstChainId   uint64  `gorm:"type:bigint(20);not null"`
+	SrcChainId   uint64  `gorm:"type:bigint;not null"`
+	BlockHeight  uint64  `gorm:"type:bigint;not null"`
+	Time         uint64  `gorm:"type:bigint;not null"`
+	DstChainId   uint64  `gorm:"type:bigint;not null"`
 	DstUser      string  `gorm:"type:varchar(66);not null"`
-	ServerId     uint64  `gorm:"type:bigint(20);not null"`
+	ServerId     uint64  `gorm:"type:bigint;not null"`
 	FeeTokenHash string  `gorm:"size:120;not null"`
 	FeeToken     *Token  `gorm:"foreignKey:FeeTokenHash,SrcChainId;references:Hash,ChainId"`
 	FeeAmount    *BigInt `gorm:"type:varchar(64);not null"`
-	Status       uint64  `gorm:"type:bigint(20);not null"`
-	IsPaid       bool    `gorm:"type:tinyint(1);not null"`
+	Status       uint64  `gorm:"type:bigint;not null"`
+	IsPaid       bool    `gorm:"not null"`
 	PaidGas      *BigInt `gorm:"type:varchar(64);not null"`
 }
 
@@ -161,9 +212,9 @@ type CheckFee struct {
 
 type TimeStatistic struct {
 	Id         int64  `gorm:"primaryKey;autoIncrement"`
-	SrcChainId uint64 `gorm:"uniqueIndex:idx_chains;type:bigint(20);not null"`
-	DstChainId uint64 `gorm:"uniqueIndex:idx_chains;type:bigint(20);not null"`
-	Time       uint64 `gorm:"type:bigint(20);not null"`
+	SrcChainId uint64 `gorm:"uniqueIndex:idx_chains;type:bigint;not null"`
+	DstChainId uint64 `gorm:"uniqueIndex:idx_chains;type:bigint;not null"`
+	Time       uint64 `gorm:"type:bigint;not null"`
 }
 
 type NameAndmarketId struct {
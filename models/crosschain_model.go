@@ -19,23 +19,32 @@ package models
 
 type Chain struct {
 	Id                       int64  `gorm:"primaryKey;autoIncrement"`
-	ChainId                  uint64 `gorm:"uniqueIndex;type:bigint(20);not null"`
+	ChainId                  uint64 `gorm:"uniqueIndex;type:bigint;not null"`
 	Name                     string `gorm:"type:varchar(32)"`
-	Height                   uint64 `gorm:"type:bigint(20);not null"`
-	CrossChainSequenceNumber uint64 `gorm:"type:bigint(20);not null"`
-	ExecuteTxSequenceNumber  uint64 `gorm:"type:bigint(20);not null"`
-	HeightSwap               uint64 `gorm:"type:bigint(20);not null"`
-	BackwardBlockNumber      uint64 `gorm:"type:bigint(20);not null"`
+	Height                   uint64 `gorm:"type:bigint;not null"`
+	CrossChainSequenceNumber uint64 `gorm:"type:bigint;not null"`
+	ExecuteTxSequenceNumber  uint64 `gorm:"type:bigint;not null"`
+	HeightSwap               uint64 `gorm:"type:bigint;not null"`
+	BackwardBlockNumber      uint64 `gorm:"type:bigint;not null"`
 	ChainLogo                string `gorm:"type:varchar(128)"`
 	ChainExplorerUrl         string `gorm:"type:varchar(128)"`
 }
 
+// BlockHash is the hash of a height processed by a chain listener, the listener compares it with the chain to detect reorgs
+type BlockHash struct {
+	Id         int64  `gorm:"primaryKey;autoIncrement"`
+	ChainId    uint64 `gorm:"uniqueIndex:idx_block_hashes_chain_height;type:bigint;not null"`
+	Height     uint64 `gorm:"uniqueIndex:idx_block_hashes_chain_height;type:bigint;not null"`
+	Hash       string `gorm:"type:varchar(66);not null"`
+	ParentHash string `gorm:"type:varchar(66);not null"`
+}
+
 type ChainStatistic struct {
 	Id             int64  `gorm:"primaryKey;autoIncrement"`
-	ChainId        uint64 `gorm:"uniqueIndex;type:bigint(20);not null"`
-	Addresses      int64  `gorm:"type:bigint(20);not null"`
-	In             int64  `gorm:"type:bigint(20);not null"`
-	Out            int64  `gorm:"type:bigint(20);not null"`
+	ChainId        uint64 `gorm:"uniqueIndex;type:bigint;not null"`
+	Addresses      int64  `gorm:"type:bigint;not null"`
+	In             int64  `gorm:"type:bigint;not null"`
+	Out            int64  `gorm:"type:bigint;not null"`
 	LastInCheckId  int64  `gorm:"type:int"`
 	LastOutCheckId int64  `gorm:"type:int"`
 }
@@ -43,14 +52,14 @@ type ChainStatistic struct {
 type SrcTransaction struct {
 	Id          int64        `gorm:"primaryKey;autoIncrement"`
 	Hash        string       `gorm:"uniqueIndex;size:66;not null"`
-	ChainId     uint64       `gorm:"type:bigint(20);not null"`
-	Standard    uint8        `gorm:"type:int(8);not null"`
-	State       uint64       `gorm:"type:bigint(20);not null"`
-	Time        uint64       `gorm:"type:bigint(20);not null"`
+	ChainId     uint64       `gorm:"type:bigint;not null"`
+	Standard    uint8        `gorm:"type:int;not null"`
+	State       uint64       `gorm:"type:bigint;not null"`
+	Time        uint64       `gorm:"type:bigint;not null"`
 	Fee         *BigInt      `gorm:"type:varchar(64);not null"`
-	Height      uint64       `gorm:"type:bigint(20);not null"`
+	Height      uint64       `gorm:"type:bigint;not null"`
 	User        string       `gorm:"type:varchar(66);not null"`
-	DstChainId  uint64       `gorm:"type:bigint(20);not null"`
+	DstChainId  uint64       `gorm:"type:bigint;not null"`
 	Contract    string       `gorm:"type:varchar(66);not null"`
 	Key         string       `gorm:"index;size:128;not null"`
 	Param       string       `gorm:"type:varchar(8192);not null"`
@@ -61,14 +70,14 @@ type SrcTransaction struct {
 type SrcTransfer struct {
 	Id         int64   `gorm:"primaryKey;autoIncrement"`
 	TxHash     string  `gorm:"uniqueIndex;size:66;not null"`
-	ChainId    uint64  `gorm:"type:bigint(20);not null"`
-	Standard   uint8   `gorm:"type:int(8);not null"`
-	Time       uint64  `gorm:"type:bigint(20);not null"`
+	ChainId    uint64  `gorm:"type:bigint;not null"`
+	Standard   uint8   `gorm:"type:int;not null"`
+	Time       uint64  `gorm:"type:bigint;not null"`
 	Asset      string  `gorm:"type:varchar(120);not null"`
 	From       string  `gorm:"type:varchar(66);not null"`
 	To         string  `gorm:"type:varchar(66);not null"`
 	Amount     *BigInt `gorm:"type:varchar(80);not null"`
-	DstChainId uint64  `gorm:"type:bigint(20);not null"`
+	DstChainId uint64  `gorm:"type:bigint;not null"`
 	DstAsset   string  `gorm:"type:varchar(120);not null"`
 	DstUser    string  `gorm:"type:varchar(66);not null"`
 	Token      *Token  `gorm:"foreignKey:Hash,ChainId;references:Asset,ChainId"`
@@ -77,47 +86,47 @@ type SrcTransfer struct {
 type SrcSwap struct {
 	Id         int64   `gorm:"primaryKey;autoIncrement"`
 	TxHash     string  `gorm:"uniqueIndex;size:66;not null"`
-	ChainId    uint64  `gorm:"type:bigint(20);not null"`
-	Time       uint64  `gorm:"type:bigint(20);not null"`
+	ChainId    uint64  `gorm:"type:bigint;not null"`
+	Time       uint64  `gorm:"type:bigint;not null"`
 	Asset      string  `gorm:"type:varchar(120);not null"`
 	From       string  `gorm:"type:varchar(66);not null"`
 	To         string  `gorm:"type:varchar(66);not null"`
 	Amount     *BigInt `gorm:"type:varchar(64);not null"`
-	PoolId     uint64  `gorm:"type:bigint(20);not null"`
-	DstChainId uint64  `gorm:"type:bigint(20);not null"`
+	PoolId     uint64  `gorm:"type:bigint;not null"`
+	DstChainId uint64  `gorm:"type:bigint;not null"`
 	DstAsset   string  `gorm:"type:varchar(120);not null"`
 	DstUser    string  `gorm:"type:varchar(66);not null"`
-	Type       uint64  `gorm:"type:bigint(20);not null"`
+	Type       uint64  `gorm:"type:bigint;not null"`
 }
 
 type PolyTransaction struct {
 	Id          int64   `gorm:"primaryKey;autoIncrement"`
 	Hash        string  `gorm:"uniqueIndex;size:66;not null"`
-	ChainId     uint64  `gorm:"type:bigint(20);not null"`
-	State       uint64  `gorm:"type:bigint(20);not null"`
-	Time        uint64  `gorm:"type:bigint(20);not null"`
+	ChainId     uint64  `gorm:"type:bigint;not null"`
+	State       uint64  `gorm:"type:bigint;not null"`
+	Time        uint64  `gorm:"type:bigint;not null"`
 	Fee         *BigInt `gorm:"type:varchar(64);not null"`
-	Height      uint64  `gorm:"type:bigint(20);not null"`
-	SrcChainId  uint64  `gorm:"type:bigint(20);not null"`
+	Height      uint64  `gorm:"type:bigint;not null"`
+	SrcChainId  uint64  `gorm:"type:bigint;not null"`
 	SrcHash     string  `gorm:"index;size:66;not null"`
-	DstChainId  uint64  `gorm:"type:bigint(20);not null"`
+	DstChainId  uint64  `gorm:"type:bigint;not null"`
 	Key         string  `gorm:"type:varchar(8192);not null"`
-	DstSequence uint64  `gorm:"type:bigint(20);not null"`
+	DstSequence uint64  `gorm:"type:bigint;not null"`
 }
 
 type PolyDetail struct {
 	Id          int64   `gorm:"primaryKey;autoIncrement"`
 	Hash        string  `gorm:"uniqueIndex;size:66;not null"`
-	ChainId     uint64  `gorm:"type:bigint(20);not null"`
-	State       uint64  `gorm:"type:bigint(20);not null"`
-	Time        uint64  `gorm:"type:bigint(20);not null"`
+	ChainId     uint64  `gorm:"type:bigint;not null"`
+	State       uint64  `gorm:"type:bigint;not null"`
+	Time        uint64  `gorm:"type:bigint;not null"`
 	Fee         *BigInt `gorm:"type:varchar(64);not null"`
-	Height      uint64  `gorm:"type:bigint(20);not null"`
-	SrcChainId  uint64  `gorm:"type:bigint(20);not null"`
+	Height      uint64  `gorm:"type:bigint;not null"`
+	SrcChainId  uint64  `gorm:"type:bigint;not null"`
 	SrcHash     string  `gorm:"index;size:66;not null"`
-	DstChainId  uint64  `gorm:"type:bigint(20);not null"`
+	DstChainId  uint64  `gorm:"type:bigint;not null"`
 	Key         string  `gorm:"type:varchar(8192);not null"`
-	DstSequence uint64  `gorm:"type:bigint(20);not null"`
+	DstSequence uint64  `gorm:"type:bigint;not null"`
 }
 
 type PolySrcRelation struct {
@@ -137,16 +146,16 @@ type DstPolyRelation struct {
 type DstTransaction struct {
 	Id          int64        `gorm:"primaryKey;autoIncrement"`
 	Hash        string       `gorm:"uniqueIndex;size:66;not null"`
-	ChainId     uint64       `gorm:"type:bigint(20);not null"`
-	Standard    uint8        `gorm:"type:int(8);not null"`
-	State       uint64       `gorm:"type:bigint(20);not null"`
-	Time        uint64       `gorm:"type:bigint(20);not null"`
+	ChainId     uint64       `gorm:"type:bigint;not null"`
+	Standard    uint8        `gorm:"type:int;not null"`
+	State       uint64       `gorm:"type:bigint;not null"`
+	Time        uint64       `gorm:"type:bigint;not null"`
 	Fee         *BigInt      `gorm:"type:varchar(64);not null"`
-	Height      uint64       `gorm:"type:bigint(20);not null"`
-	SrcChainId  uint64       `gorm:"type:bigint(20);not null"`
+	Height      uint64       `gorm:"type:bigint;not null"`
+	SrcChainId  uint64       `gorm:"type:bigint;not null"`
 	Contract    string       `gorm:"type:varchar(66);not null"`
 	PolyHash    string       `gorm:"index;size:66;not null"`
-	Sequence    uint64       `gorm:"type:bigint(20);not null"`
+	Sequence    uint64       `gorm:"type:bigint;not null"`
 	DstTransfer *DstTransfer `gorm:"foreignKey:TxHash;references:Hash"`
 	DstSwap     *DstSwap     `gorm:"foreignKey:TxHash;references:Hash"`
 }
@@ -154,9 +163,9 @@ type DstTransaction struct {
 type DstTransfer struct {
 	Id       int64   `gorm:"primaryKey;autoIncrement"`
 	TxHash   string  `gorm:"uniqueIndex;size:66;not null"`
-	ChainId  uint64  `gorm:"type:bigint(20);not null"`
-	Standard uint8   `gorm:"type:int(8);not null"`
-	Time     uint64  `gorm:"type:bigint(20);not null"`
+	ChainId  uint64  `gorm:"type:bigint;not null"`
+	Standard uint8   `gorm:"type:int;not null"`
+	Time     uint64  `gorm:"type:bigint;not null"`
 	Asset    string  `gorm:"type:varchar(120);not null"`
 	From     string  `gorm:"type:varchar(66);not null"`
 	To       string  `gorm:"type:varchar(66);not null"`
@@ -166,17 +175,17 @@ type DstTransfer struct {
 type DstSwap struct {
 	Id         int64   `gorm:"primaryKey;autoIncrement"`
 	TxHash     string  `gorm:"uniqueIndex;size:66;not null"`
-	ChainId    uint64  `gorm:"type:bigint(20);not null"`
-	Time       uint64  `gorm:"type:bigint(20);not null"`
-	PoolId     uint64  `gorm:"type:bigint(20);not null"`
+	ChainId    uint64  `gorm:"type:bigint;not null"`
+	Time       uint64  `gorm:"type:bigint;not null"`
+	PoolId     uint64  `gorm:"type:bigint;not null"`
 	InAsset    string  `gorm:"type:varchar(120);not null"`
 	InAmount   *BigInt `gorm:"type:varchar(64);not null"`
 	OutAsset   string  `gorm:"type:varchar(120);not null"`
 	OutAmount  *BigInt `gorm:"type:varchar(64);not null"`
-	DstChainId uint64  `gorm:"type:bigint(20);not null"`
+	DstChainId uint64  `gorm:"type:bigint;not null"`
 	DstAsset   string  `gorm:"type:varchar(120);not null"`
 	DstUser    string  `gorm:"type:varchar(66);not null"`
-	Type       uint64  `gorm:"type:bigint(20);not null"`
+	Type       uint64  `gorm:"type:bigint;not null"`
 }
 
 type WrapperDetail struct {
@@ -184,33 +193,33 @@ type WrapperDetail struct {
 	WrapperHash  string  `gorm:"index:wrapper_details_wrapper_hash;size:66;not null"`
 	Hash         string  `gorm:"uniqueIndex;size:66;not null"`
 	User         string  `gorm:"type:varchar(66);not null"`
-	SrcChainId   uint64  `gorm:"type:bigint(20);not null"`
-	Standard     uint8   `gorm:"type:int(8);not null"`
-	BlockHeight  uint64  `gorm:"type:bigint(20);not null"`
-	Time         uint64  `gorm:"type:bigint(20);not null"`
-	DstChainId   uint64  `gorm:"type:bigint(20);not null"`
+	SrcChainId   uint64  `gorm:"type:bigint;not null"`
+	Standard     uint8   `gorm:"type:int;not null"`
+	BlockHeight  uint64  `gorm:"type:bigint;not null"`
+	Time         uint64  `gorm:"type:bigint;not null"`
+	DstChainId   uint64  `gorm:"type:bigint;not null"`
 	DstUser      string  `gorm:"type:varchar(66);not null"`
-	ServerId     uint64  `gorm:"type:bigint(20);not null"`
+	ServerId     uint64  `gorm:"type:bigint;not null"`
 	FeeTokenHash string  `gorm:"size:66;not null"`
 	FeeAmount    *BigInt `gorm:"type:varchar(64);not null"`
-	Status       uint64  `gorm:"type:bigint(20);not null"`
+	Status       uint64  `gorm:"type:bigint;not null"`
 }
 
 type WrapperTransaction struct {
 	Id           int64   `gorm:"primaryKey;autoIncrement"`
 	Hash         string  `gorm:"uniqueIndex;size:66;not null"`
 	User         string  `gorm:"type:varchar(66);not null"`
-	SrcChainId   uint64  `gorm:"type:bigint(20);not null"`
-	Standard     uint8   `gorm:"type:int(8);not null"`
-	BlockHeight  uint64  `gorm:"type:bigint(20);not null"`
-	Time         uint64  `gorm:"type:bigint(20);not null"`
-	DstChainId   uint64  `gorm:"type:bigint(20);not null"`
+	SrcChainId   uint64  `gorm:"type:bigint;not null"`
+	Standard     uint8   `gorm:"type:int;not null"`
+	BlockHeight  uint64  `gorm:"type:bigint;not null"`
+	Time         uint64  `gorm:"type:bigint;not null"`
+	DstChainId   uint64  `gorm:"type:bigint;not null"`
 	DstUser      string  `gorm:"type:varchar(66);not null"`
-	ServerId     uint64  `gorm:"type:bigint(20);not null"`
+	ServerId     uint64  `gorm:"type:bigint;not null"`
 	FeeTokenHash string  `gorm:"size:66;not null"`
 	FeeAmount    *BigInt `gorm:"type:varchar(64);not null"`
-	Status       uint64  `gorm:"type:bigint(20);not null"`
-	IsPaid       bool    `gorm:"type:tinyint(1);not null"`
+	Status       uint64  `gorm:"type:bigint;not null"`
+	IsPaid       bool    `gorm:"not null"`
 	PaidGas      *BigInt `gorm:"type:varchar(64);not null"`
 }
 
@@ -238,9 +247,9 @@ type PolyTxRelation struct {
 	PolyTransaction    *PolyTransaction `gorm:"foreignKey:PolyHash;references:Hash"`
 	DstHash            string
 	DstTransaction     *DstTransaction `gorm:"foreignKey:DstHash;references:Hash"`
-	ChainId            uint64          `gorm:"type:bigint(20);not null"`
-	ToChainId          uint64          `gorm:"type:bigint(20);not null"`
-	DstChainId         uint64          `gorm:"type:bigint(20);not null"`
+	ChainId            uint64          `gorm:"type:bigint;not null"`
+	ToChainId          uint64          `gorm:"type:bigint;not null"`
+	DstChainId         uint64          `gorm:"type:bigint;not null"`
 	TokenHash          string          `gorm:"type:varchar(66);not null"`
 	ToTokenHash        string          `gorm:"type:varchar(66);not null"`
 	DstTokenHash       string          `gorm:"type:varchar(66);not null"`
@@ -254,8 +263,8 @@ type PolyTxRelation struct {
 type AssetStatistic struct {
 	Id             int64       `gorm:"primaryKey;autoIncrement"`
 	Amount         *BigInt     `gorm:"type:varchar(64);not null"`
-	Txnum          uint64      `gorm:"type:bigint(20);not null"`
-	Addressnum     uint64      `gorm:"type:bigint(20);not null"`
+	Txnum          uint64      `gorm:"type:bigint;not null"`
+	Addressnum     uint64      `gorm:"type:bigint;not null"`
 	TokenBasicName string      `gorm:"uniqueIndex;size:64;not null"`
 	AmountBtc      *BigInt     `gorm:"type:varchar(64);not null"`
 	AmountUsd      *BigInt     `gorm:"type:varchar(64);not null"`
@@ -266,13 +275,13 @@ type AssetStatistic struct {
 type LockTokenStatistic struct {
 	Id          int64   `gorm:"primaryKey;autoIncrement"`
 	Hash        string  `gorm:"uniqueIndex:idx_locktoken;size:66;not null"`
-	ChainId     uint64  `gorm:"uniqueIndex:idx_locktoken;type:bigint(20);not null"`
+	ChainId     uint64  `gorm:"uniqueIndex:idx_locktoken;type:bigint;not null"`
 	ItemProxy   string  `gorm:"uniqueIndex:idx_locktoken;type:varchar(66);not null"`
 	ItemName    string  `gorm:"type:varchar(32);not null"`
 	InAmount    *BigInt `gorm:"type:varchar(64);not null"`
 	InAmountBtc *BigInt `gorm:"type:varchar(64);not null"`
 	InAmountUsd *BigInt `gorm:"type:varchar(64);not null"`
-	UpdateTime  uint64  `gorm:"type:bigint(20);not null"`
+	UpdateTime  uint64  `gorm:"type:bigint;not null"`
 	Token       *Token  `gorm:"foreignKey:Hash,ChainId;references:Hash,ChainId"`
 }
 
@@ -313,33 +322,180 @@ type TransactionOnAddress struct {
 
 type NftUser struct {
 	Id              int64   `gorm:"primaryKey;autoIncrement"`
-	ColChainId      uint64  `gorm:"type:bigint(20);not null"`
-	DfChainId       uint64  `gorm:"type:bigint(20)"`
+	ColChainId      uint64  `gorm:"type:bigint;not null"`
+	DfChainId       uint64  `gorm:"type:bigint"`
 	AddrHash        string  `gorm:"type:varchar(66);not null"`
 	ColAddress      string  `gorm:"uniqueIndex:nftusers_coladdress;type:varchar(66);not null"`
 	DfAddress       string  `gorm:"index:nftusers_dfaddress;type:varchar(66)"`
-	Txnum           uint64  `gorm:"type:bigint(20);not null"`
-	FirstTime       uint64  `gorm:"type:bigint(20);not null"`
+	Txnum           uint64  `gorm:"type:bigint;not null"`
+	FirstTime       uint64  `gorm:"type:bigint;not null"`
 	TxAmountUsd     *BigInt `gorm:"type:varchar(64);not null"`
 	EffectAmountUsd *BigInt `gorm:"type:varchar(64);not null"`
 	NftColId        int     `gorm:"index:nftusers_nftcolid;type:int;not null"`
 	NftDfId         int     `gorm:"index:nftusers_nftdfid;type:int"`
 	NftColsig       string  `gorm:"size:132;not null"`
 	NftDfsig        string  `gorm:"size:132"`
-	IsClaimCol      uint64  `gorm:"type:bigint(20);not null"`
-	IsClaimDf       uint64  `gorm:"type:bigint(20);not null"`
+	IsClaimCol      uint64  `gorm:"type:bigint;not null"`
+	IsClaimDf       uint64  `gorm:"type:bigint;not null"`
 }
 
 type AirDropNft struct {
 	Id          int64  `gorm:"primaryKey;autoIncrement"`
-	Amount      int64  `gorm:"type:bigint(20);not null"`
-	Rank        int64  `gorm:"type:bigint(20);not null"`
-	BindChainId uint64 `gorm:"type:bigint(20);not null"`
+	Amount      int64  `gorm:"type:bigint;not null"`
+	Rank        int64  `gorm:"type:bigint;not null"`
+	BindChainId uint64 `gorm:"type:bigint;not null"`
 	BindAddr    string `gorm:"uniqueIndex;type:varchar(66);not null"`
 	NftTbId     int64  `gorm:"index:nftusers_nftcolid;type:int;not null"`
 	NftDfId     int64  `gorm:"index:nftusers_nftdfid;type:int;not null"`
 	NftTbSig    string `gorm:"size:132;not null"`
 	NftDfSig    string `gorm:"size:132;not null"`
-	IsClaimTb   bool   `gorm:"type:int(8);not null"`
-	IsClaimDf   bool   `gorm:"type:int(8);not null"`
+	IsClaimTb   bool   `gorm:"type:int;not null"`
+	IsClaimDf   bool   `gorm:"type:int;not null"`
+}
+
+type BackfillJob struct {
+	Id          int64  `gorm:"primaryKey;autoIncrement"`
+	ChainId     uint64 `gorm:"index;type:bigint;not null"`
+	StartHeight uint64 `gorm:"type:bigint;not null"`
+	EndHeight   uint64 `gorm:"type:bigint;not null"`
+	Height      uint64 `gorm:"type:bigint;not null"`
+	BatchLength uint64 `gorm:"type:bigint;not null"`
+	Concurrency uint64 `gorm:"type:bigint;not null"`
+	Status      string `gorm:"index;type:varchar(16);not null"`
+	Error       string `gorm:"type:varchar(512)"`
+	CreateTime  int64  `gorm:"type:bigint;not null"`
+	UpdateTime  int64  `gorm:"type:bigint;not null"`
+}
+
+type EventOutbox struct {
+	Id         int64  `gorm:"primaryKey;autoIncrement"`
+	Type       string `gorm:"type:varchar(16);not null"`
+	Hash       string `gorm:"type:varchar(66);not null"`
+	Payload    string `gorm:"type:varchar(4096);not null"`
+	Status     string `gorm:"index;type:varchar(16);not null"`
+	Attempts   int    `gorm:"type:int;not null"`
+	Error      string `gorm:"type:varchar(512)"`
+	NextTime   int64  `gorm:"index;type:bigint;not null"`
+	CreateTime int64  `gorm:"type:bigint;not null"`
+}
+
+type WebhookSubscription struct {
+	Id         int64  `gorm:"primaryKey;autoIncrement"`
+	Url        string `gorm:"type:varchar(512);not null"`
+	Secret     string `gorm:"type:varchar(128);not null"`
+	SrcHash    string `gorm:"index;type:varchar(66);not null"`
+	User       string `gorm:"index;type:varchar(66);not null"`
+	ChainId    uint64 `gorm:"type:bigint;not null"`
+	Token      string `gorm:"index;type:varchar(120);not null"`
+	States     string `gorm:"type:varchar(64);not null"`
+	CreateTime int64  `gorm:"type:bigint;not null"`
+}
+
+type WebhookDelivery struct {
+	Id             int64  `gorm:"primaryKey;autoIncrement"`
+	SubscriptionId int64  `gorm:"uniqueIndex:idx_webhook_delivery;type:bigint;not null"`
+	SrcHash        string `gorm:"uniqueIndex:idx_webhook_delivery;type:varchar(66);not null"`
+	State          uint64 `gorm:"uniqueIndex:idx_webhook_delivery;type:bigint;not null"`
+	Payload        string `gorm:"type:varchar(2048);not null"`
+	Status         string `gorm:"index;type:varchar(16);not null"`
+	Attempts       int    `gorm:"type:int;not null"`
+	ResponseCode   int    `gorm:"type:int;not null"`
+	Error          string `gorm:"type:varchar(512)"`
+	NextTime       int64  `gorm:"index;type:bigint;not null"`
+	CreateTime     int64  `gorm:"type:bigint;not null"`
+	UpdateTime     int64  `gorm:"type:bigint;not null"`
+}
+
+type SchemaMigration struct {
+	Version   int64  `gorm:"primaryKey;autoIncrement:false"`
+	Name      string `gorm:"type:varchar(128);not null"`
+	AppliedAt int64  `gorm:"type:bigint;not null"`
+}
+
+// CrossChainTx is the src->poly->dst relation keyed by the source hash, it is updated by UpdateEvents as the legs arrive
+type CrossChainTx struct {
+	SrcHash      string `gorm:"primaryKey;size:66"`
+	SrcKey       string `gorm:"index;size:128;not null"`
+	SrcChainId   uint64 `gorm:"type:bigint;not null"`
+	SrcHeight    uint64 `gorm:"type:bigint;not null"`
+	DstChainId   uint64 `gorm:"type:bigint;not null"`
+	Standard     uint8  `gorm:"type:int;not null"`
+	Contract     string `gorm:"type:varchar(66);not null"`
+	TokenHash    string `gorm:"type:varchar(120);not null"`
+	WrapperId    int64  `gorm:"type:bigint;not null"`
+	FeeTokenHash string `gorm:"size:66;not null"`
+	Status       uint64 `gorm:"index;type:bigint;not null"`
+	Time         uint64 `gorm:"index;type:bigint;not null"`
+	PolyHash     string `gorm:"index;size:66;not null"`
+	DstSequence  uint64 `gorm:"type:bigint;not null"`
+	DstHash      string `gorm:"index;size:66;not null"`
+	DstHeight    uint64 `gorm:"type:bigint;not null"`
+}
+
+// ScanCursor is the saved (time, id) position of a batch scan, the scan goes on after it in the next run
+type ScanCursor struct {
+	Name       string `gorm:"primaryKey;size:64"`
+	Time       uint64 `gorm:"type:bigint;not null"`
+	Id         int64  `gorm:"type:bigint;not null"`
+	UpdateTime int64  `gorm:"type:bigint;not null"`
+}
+
+// ScanRetry is a row a batch scan has gone past before it was done, it is checked again by id in the next runs
+type ScanRetry struct {
+	Name       string `gorm:"primaryKey;size:64"`
+	RowId      int64  `gorm:"primaryKey;autoIncrement:false;type:bigint"`
+	Time       uint64 `gorm:"type:bigint;not null"`
+	UpdateTime int64  `gorm:"type:bigint;not null"`
+}
+
+// TableCopyProgress is the last primary key copied of a table by a copy job, a resumed job goes on after it
+type TableCopyProgress struct {
+	Job        string `gorm:"primaryKey;size:64"`
+	Table      string `gorm:"primaryKey;size:64"`
+	LastKey    string `gorm:"type:varchar(128);not null"`
+	Rows       int64  `gorm:"type:bigint;not null"`
+	Batches    int64  `gorm:"type:bigint;not null"`
+	Mismatches int64  `gorm:"type:bigint;not null"`
+	UpdateTime int64  `gorm:"type:bigint;not null"`
+}
+
+const (
+	TransferAuditMismatch        = "mismatch"
+	TransferAuditOrphan          = "orphan"
+	TransferAuditDoubleExecution = "double_execution"
+)
+
+// TransferAuditFinding is a dst transfer failing the lock/unlock reconciliation, one row per kind of failure
+type TransferAuditFinding struct {
+	Id             int64   `gorm:"primaryKey;autoIncrement"`
+	Kind           string  `gorm:"uniqueIndex:idx_transfer_audit_finding;type:varchar(32);not null"`
+	DstHash        string  `gorm:"uniqueIndex:idx_transfer_audit_finding;size:66;not null"`
+	PolyHash       string  `gorm:"size:66;not null"`
+	SrcHash        string  `gorm:"size:66;not null"`
+	SrcChainId     uint64  `gorm:"type:bigint;not null"`
+	DstChainId     uint64  `gorm:"type:bigint;not null"`
+	SrcAsset       string  `gorm:"type:varchar(120);not null"`
+	DstAsset       string  `gorm:"type:varchar(120);not null"`
+	SrcAmount      *BigInt `gorm:"type:varchar(80);not null"`
+	ExpectedAmount *BigInt `gorm:"type:varchar(80);not null"`
+	DstAmount      *BigInt `gorm:"type:varchar(80);not null"`
+	Detail         string  `gorm:"type:varchar(256);not null"`
+	Time           uint64  `gorm:"index;type:bigint;not null"`
+	CreateTime     int64   `gorm:"type:bigint;not null"`
+}
+
+// TransferAuditTotal sums the audited transfers of a token pair between two chains, InAmount is locked on the source
+// in the source precision, ExpectedAmount is InAmount in the destination precision and OutAmount is released on the destination
+type TransferAuditTotal struct {
+	Id             int64   `gorm:"primaryKey;autoIncrement"`
+	SrcChainId     uint64  `gorm:"uniqueIndex:idx_transfer_audit_total;type:bigint;not null"`
+	SrcAsset       string  `gorm:"uniqueIndex:idx_transfer_audit_total;type:varchar(120);not null"`
+	DstChainId     uint64  `gorm:"uniqueIndex:idx_transfer_audit_total;type:bigint;not null"`
+	DstAsset       string  `gorm:"uniqueIndex:idx_transfer_audit_total;type:varchar(120);not null"`
+	InCounter      int64   `gorm:"type:bigint;not null"`
+	InAmount       *BigInt `gorm:"type:varchar(80);not null"`
+	ExpectedAmount *BigInt `gorm:"type:varchar(80);not null"`
+	OutCounter     int64   `gorm:"type:bigint;not null"`
+	OutAmount      *BigInt `gorm:"type:varchar(80);not null"`
+	UpdateTime     int64   `gorm:"type:bigint;not null"`
 }
@@ -17,7 +17,10 @@
 
 package models
 
-import "math/big"
+import (
+	"math/big"
+	"poly-bridge/basedef"
+)
 
 type ECCMLockEvent struct {
 	Method   string
@@ -113,3 +116,118 @@ type UnSwapEvent struct {
 	ToAssetHash  string
 	ToAddress    string
 }
+
+// CrossChainEvent is the normalized form of the events published to the event sinks
+type CrossChainEvent struct {
+	Type       string `json:"type"`
+	ChainId    uint64 `json:"chain_id"`
+	SrcChainId uint64 `json:"src_chain_id"`
+	DstChainId uint64 `json:"dst_chain_id"`
+	Hash       string `json:"hash"`
+	SrcHash    string `json:"src_hash,omitempty"`
+	PolyHash   string `json:"poly_hash,omitempty"`
+	User       string `json:"user,omitempty"`
+	Asset      string `json:"asset,omitempty"`
+	Amount     string `json:"amount,omitempty"`
+	Status     uint64 `json:"status"`
+	Height     uint64 `json:"height"`
+	Time       uint64 `json:"time"`
+}
+
+func MakeCrossChainEvents(wrapperTransactions []*WrapperTransaction, srcTransactions []*SrcTransaction, polyTransactions []*PolyTransaction, dstTransactions []*DstTransaction) []*CrossChainEvent {
+	events := make([]*CrossChainEvent, 0, len(wrapperTransactions)+len(srcTransactions)+len(polyTransactions)+len(dstTransactions))
+	for _, tx := range srcTransactions {
+		event := &CrossChainEvent{
+			Type:       basedef.EVENT_SRC_LOCK,
+			ChainId:    tx.ChainId,
+			SrcChainId: tx.ChainId,
+			DstChainId: tx.DstChainId,
+			Hash:       tx.Hash,
+			User:       tx.User,
+			Status:     tx.State,
+			Height:     tx.Height,
+			Time:       tx.Time,
+		}
+		if tx.SrcTransfer != nil {
+			event.Asset = tx.SrcTransfer.Asset
+			event.Amount = amountString(tx.SrcTransfer.Amount)
+		}
+		events = append(events, event)
+	}
+	for _, tx := range polyTransactions {
+		events = append(events, &CrossChainEvent{
+			Type:       basedef.EVENT_POLY_RELAY,
+			ChainId:    tx.ChainId,
+			SrcChainId: tx.SrcChainId,
+			DstChainId: tx.DstChainId,
+			Hash:       tx.Hash,
+			SrcHash:    tx.SrcHash,
+			Status:     tx.State,
+			Height:     tx.Height,
+			Time:       tx.Time,
+		})
+	}
+	for _, tx := range dstTransactions {
+		event := &CrossChainEvent{
+			Type:       basedef.EVENT_DST_UNLOCK,
+			ChainId:    tx.ChainId,
+			SrcChainId: tx.SrcChainId,
+			DstChainId: tx.ChainId,
+			Hash:       tx.Hash,
+			PolyHash:   tx.PolyHash,
+			Status:     tx.State,
+			Height:     tx.Height,
+			Time:       tx.Time,
+		}
+		if tx.DstTransfer != nil {
+			event.User = tx.DstTransfer.To
+			event.Asset = tx.DstTransfer.Asset
+			event.Amount = amountString(tx.DstTransfer.Amount)
+		}
+		events = append(events, event)
+	}
+	for _, tx := range wrapperTransactions {
+		events = append(events, &CrossChainEvent{
+			Type:       basedef.EVENT_WRAPPER_FEE,
+			ChainId:    tx.SrcChainId,
+			SrcChainId: tx.SrcChainId,
+			DstChainId: tx.DstChainId,
+			Hash:       tx.Hash,
+			User:       tx.User,
+			Asset:      tx.FeeTokenHash,
+			Amount:     amountString(tx.FeeAmount),
+			Status:     tx.Status,
+			Height:     tx.BlockHeight,
+			Time:       tx.Time,
+		})
+	}
+	return events
+}
+
+func amountString(amount *BigInt) string {
+	if amount == nil {
+		return ""
+	}
+	return amount.String()
+}
+
+// TxStatusNotification is the payload posted to the webhook subscriptions
+type TxStatusNotification struct {
+	SubscriptionId int64  `json:"subscription_id"`
+	Hash           string `json:"hash"`
+	SrcChainId     uint64 `json:"src_chain_id"`
+	DstChainId     uint64 `json:"dst_chain_id"`
+	User           string `json:"user"`
+	DstUser        string `json:"dst_user"`
+	State          uint64 `json:"state"`
+	StateName      string `json:"state_name"`
+	Time           int64  `json:"time"`
+}
+
+// TxStatusChange is published by the effect service when the status of a wrapper transaction changes
+type TxStatusChange struct {
+	Hash    string
+	User    string
+	DstUser string
+	Status  uint64
+}
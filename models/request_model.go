@@ -317,6 +317,7 @@ type GetFeeReq struct {
 	Hash          string
 	DstChainId    uint64
 	SwapTokenHash string
+	User          string `json:",omitempty"` //sender of the transfer, a fee quote is issued to it if the quotes are enabled
 }
 
 type GetFeeRsp struct {
@@ -331,6 +332,9 @@ type GetFeeRsp struct {
 	BalanceWithPrecision     string
 	IsNative                 bool
 	NativeTokenAmount        string
+	QuoteId                  string `json:",omitempty"`
+	QuoteServerId            uint64 `json:",omitempty"` //the id the wrapper lock passes to pay the quote
+	QuoteExpiry              int64  `json:",omitempty"`
 }
 
 func MakeGetFeeRsp(srcChainId uint64, hash string, dstChainId uint64, usdtAmount *big.Float, tokenAmount *big.Float, tokenAmountWithPrecision *big.Float,
@@ -357,6 +361,16 @@ func MakeGetFeeRsp(srcChainId uint64, hash string, dstChainId uint64, usdtAmount
 	return getFeeRsp
 }
 
+// WithQuote attaches the signed fee quote, if any, to the response
+func (rsp *GetFeeRsp) WithQuote(quoteId string, quoteServerId uint64, quoteExpiry int64) *GetFeeRsp {
+	if quoteId != "" {
+		rsp.QuoteId = quoteId
+		rsp.QuoteServerId = quoteServerId
+		rsp.QuoteExpiry = quoteExpiry
+	}
+	return rsp
+}
+
 type CheckFeeReq struct {
 	Hash    string
 	ChainId uint64
@@ -1512,3 +1526,418 @@ func MakeTxWithoutWrapperRsp(pageSize int, pageNo int, srcTransfers []*SrcTransf
 	}
 	return txWithoutWrapperRes
 }
+
+type BackfillJobReq struct {
+	ChainId     uint64
+	StartHeight uint64
+	EndHeight   uint64
+	BatchLength uint64
+	Concurrency uint64
+}
+
+type BackfillJobIdReq struct {
+	Id int64
+}
+
+type BackfillJobsReq struct {
+	Status string
+}
+
+type BackfillJobRsp struct {
+	Id          int64
+	ChainId     uint64
+	StartHeight uint64
+	EndHeight   uint64
+	Height      uint64
+	BatchLength uint64
+	Concurrency uint64
+	Status      string
+	Error       string
+	CreateTime  int64
+	UpdateTime  int64
+}
+
+func MakeBackfillJobRsp(job *BackfillJob) *BackfillJobRsp {
+	return &BackfillJobRsp{
+		Id:          job.Id,
+		ChainId:     job.ChainId,
+		StartHeight: job.StartHeight,
+		EndHeight:   job.EndHeight,
+		Height:      job.Height,
+		BatchLength: job.BatchLength,
+		Concurrency: job.Concurrency,
+		Status:      job.Status,
+		Error:       job.Error,
+		CreateTime:  job.CreateTime,
+		UpdateTime:  job.UpdateTime,
+	}
+}
+
+type BackfillJobsRsp struct {
+	Jobs []*BackfillJobRsp
+}
+
+func MakeBackfillJobsRsp(jobs []*BackfillJob) *BackfillJobsRsp {
+	backfillJobsRsp := &BackfillJobsRsp{
+		Jobs: make([]*BackfillJobRsp, 0, len(jobs)),
+	}
+	for _, job := range jobs {
+		backfillJobsRsp.Jobs = append(backfillJobsRsp.Jobs, MakeBackfillJobRsp(job))
+	}
+	return backfillJobsRsp
+}
+
+type WebhookSubscribeReq struct {
+	Url     string
+	SrcHash string
+	User    string
+	ChainId uint64
+	Token   string
+	States  []uint64
+}
+
+type WebhookIdReq struct {
+	Id     int64
+	Secret string
+}
+
+type WebhookSubscriptionRsp struct {
+	Id      int64
+	Secret  string
+	Url     string
+	SrcHash string
+	User    string
+	ChainId uint64
+	Token   string
+	States  []uint64
+}
+
+func MakeWebhookSubscriptionRsp(subscription *WebhookSubscription) *WebhookSubscriptionRsp {
+	return &WebhookSubscriptionRsp{
+		Id:      subscription.Id,
+		Secret:  subscription.Secret,
+		Url:     subscription.Url,
+		SrcHash: subscription.SrcHash,
+		User:    subscription.User,
+		ChainId: subscription.ChainId,
+		Token:   subscription.Token,
+		States:  ParseWebhookStates(subscription.States),
+	}
+}
+
+type WebhookDeliveriesReq struct {
+	Id       int64
+	Secret   string
+	PageSize int
+	PageNo   int
+}
+
+type WebhookDeliveryRsp struct {
+	Id           int64
+	SrcHash      string
+	State        uint64
+	Payload      string
+	Status       string
+	Attempts     int
+	ResponseCode int
+	Error        string
+	CreateTime   int64
+	UpdateTime   int64
+}
+
+type WebhookDeliveriesRsp struct {
+	PageSize   int
+	PageNo     int
+	TotalPage  int
+	TotalCount int
+	Deliveries []*WebhookDeliveryRsp
+}
+
+func MakeWebhookDeliveriesRsp(pageSize int, pageNo int, totalPage int, totalCount int, deliveries []*WebhookDelivery) *WebhookDeliveriesRsp {
+	webhookDeliveriesRsp := &WebhookDeliveriesRsp{
+		PageSize:   pageSize,
+		PageNo:     pageNo,
+		TotalPage:  totalPage,
+		TotalCount: totalCount,
+		Deliveries: make([]*WebhookDeliveryRsp, 0, len(deliveries)),
+	}
+	for _, delivery := range deliveries {
+		webhookDeliveriesRsp.Deliveries = append(webhookDeliveriesRsp.Deliveries, &WebhookDeliveryRsp{
+			Id:           delivery.Id,
+			SrcHash:      delivery.SrcHash,
+			State:        delivery.State,
+			Payload:      delivery.Payload,
+			Status:       delivery.Status,
+			Attempts:     delivery.Attempts,
+			ResponseCode: delivery.ResponseCode,
+			Error:        delivery.Error,
+			CreateTime:   delivery.CreateTime,
+			UpdateTime:   delivery.UpdateTime,
+		})
+	}
+	return webhookDeliveriesRsp
+}
+
+type TransferAuditFindingsReq struct {
+	Kind       string
+	DstChainId uint64
+	PageSize   int
+	PageNo     int
+}
+
+type TransferAuditFindingRsp struct {
+	Kind           string
+	DstHash        string
+	PolyHash       string
+	SrcHash        string
+	SrcChainId     uint64
+	DstChainId     uint64
+	SrcAsset       string
+	DstAsset       string
+	SrcAmount      string
+	ExpectedAmount string
+	DstAmount      string
+	Detail         string
+	Time           uint64
+	CreateTime     int64
+}
+
+type TransferAuditFindingsRsp struct {
+	PageSize   int
+	PageNo     int
+	TotalPage  int
+	TotalCount int
+	Findings   []*TransferAuditFindingRsp
+}
+
+func MakeTransferAuditFindingsRsp(pageSize int, pageNo int, totalPage int, totalCount int, findings []*TransferAuditFinding) *TransferAuditFindingsRsp {
+	transferAuditFindingsRsp := &TransferAuditFindingsRsp{
+		PageSize:   pageSize,
+		PageNo:     pageNo,
+		TotalPage:  totalPage,
+		TotalCount: totalCount,
+		Findings:   make([]*TransferAuditFindingRsp, 0, len(findings)),
+	}
+	for _, finding := range findings {
+		transferAuditFindingsRsp.Findings = append(transferAuditFindingsRsp.Findings, &TransferAuditFindingRsp{
+			Kind:           finding.Kind,
+			DstHash:        finding.DstHash,
+			PolyHash:       finding.PolyHash,
+			SrcHash:        finding.SrcHash,
+			SrcChainId:     finding.SrcChainId,
+			DstChainId:     finding.DstChainId,
+			SrcAsset:       finding.SrcAsset,
+			DstAsset:       finding.DstAsset,
+			SrcAmount:      finding.SrcAmount.String(),
+			ExpectedAmount: finding.ExpectedAmount.String(),
+			DstAmount:      finding.DstAmount.String(),
+			Detail:         finding.Detail,
+			Time:           finding.Time,
+			CreateTime:     finding.CreateTime,
+		})
+	}
+	return transferAuditFindingsRsp
+}
+
+type TransferAuditTotalsReq struct {
+	SrcChainId uint64
+	DstChainId uint64
+	PageSize   int
+	PageNo     int
+}
+
+type TransferAuditTotalRsp struct {
+	SrcChainId     uint64
+	SrcAsset       string
+	DstChainId     uint64
+	DstAsset       string
+	InCounter      int64
+	InAmount       string
+	ExpectedAmount string
+	OutCounter     int64
+	OutAmount      string
+	UpdateTime     int64
+}
+
+type TransferAuditTotalsRsp struct {
+	PageSize   int
+	PageNo     int
+	TotalPage  int
+	TotalCount int
+	Totals     []*TransferAuditTotalRsp
+}
+
+func MakeTransferAuditTotalsRsp(pageSize int, pageNo int, totalPage int, totalCount int, totals []*TransferAuditTotal) *TransferAuditTotalsRsp {
+	transferAuditTotalsRsp := &TransferAuditTotalsRsp{
+		PageSize:   pageSize,
+		PageNo:     pageNo,
+		TotalPage:  totalPage,
+		TotalCount: totalCount,
+		Totals:     make([]*TransferAuditTotalRsp, 0, len(totals)),
+	}
+	for _, total := range totals {
+		transferAuditTotalsRsp.Totals = append(transferAuditTotalsRsp.Totals, &TransferAuditTotalRsp{
+			SrcChainId:     total.SrcChainId,
+			SrcAsset:       total.SrcAsset,
+			DstChainId:     total.DstChainId,
+			DstAsset:       total.DstAsset,
+			InCounter:      total.InCounter,
+			InAmount:       total.InAmount.String(),
+			ExpectedAmount: total.ExpectedAmount.String(),
+			OutCounter:     total.OutCounter,
+			OutAmount:      total.OutAmount.String(),
+			UpdateTime:     total.UpdateTime,
+		})
+	}
+	return transferAuditTotalsRsp
+}
+
+type FeeHistoryReq struct {
+	ChainIds  []uint64 //destination chains, all the chains with a fee if empty
+	StartTime int64    //the series start with the snapshot in effect at StartTime
+	EndTime   int64    //now if 0
+}
+
+type ChainFeeSnapshotRsp struct {
+	TokenBasicName string
+	Price          int64
+	MaxFee         string
+	MinFee         string
+	ProxyFee       string
+	BaseFee        string
+	PriorityFee    string
+	MaxFeePerGas   string
+	L1DataFee      string
+	Time           int64
+}
+
+type ChainFeeSeriesRsp struct {
+	ChainId uint64
+	Fees    []*ChainFeeSnapshotRsp
+}
+
+type FeeHistoryRsp struct {
+	Series []*ChainFeeSeriesRsp
+}
+
+// MakeFeeHistoryRsp groups the snapshots by chain, the snapshots of a chain are in time order
+func MakeFeeHistoryRsp(history []*ChainFeeHistory) *FeeHistoryRsp {
+	feeHistoryRsp := &FeeHistoryRsp{
+		Series: make([]*ChainFeeSeriesRsp, 0),
+	}
+	chain2Series := make(map[uint64]*ChainFeeSeriesRsp)
+	for _, snapshot := range history {
+		series, ok := chain2Series[snapshot.ChainId]
+		if !ok {
+			series = &ChainFeeSeriesRsp{
+				ChainId: snapshot.ChainId,
+				Fees:    make([]*ChainFeeSnapshotRsp, 0),
+			}
+			chain2Series[snapshot.ChainId] = series
+			feeHistoryRsp.Series = append(feeHistoryRsp.Series, series)
+		}
+		series.Fees = append(series.Fees, &ChainFeeSnapshotRsp{
+			TokenBasicName: snapshot.TokenBasicName,
+			Price:          snapshot.Price,
+			MaxFee:         bigIntString(snapshot.MaxFee),
+			MinFee:         bigIntString(snapshot.MinFee),
+			ProxyFee:       bigIntString(snapshot.ProxyFee),
+			BaseFee:        bigIntString(snapshot.BaseFee),
+			PriorityFee:    bigIntString(snapshot.PriorityFee),
+			MaxFeePerGas:   bigIntString(snapshot.MaxFeePerGas),
+			L1DataFee:      bigIntString(snapshot.L1DataFee),
+			Time:           snapshot.Time,
+		})
+	}
+	return feeHistoryRsp
+}
+
+func bigIntString(value *BigInt) string {
+	if value == nil {
+		return "0"
+	}
+	return value.String()
+}
+
+type FeeComponentRsp struct {
+	Name    string
+	Applied bool              //whether the rule fired
+	Rule    string            //what the step does
+	Inputs  map[string]string //the values the step read
+	Result  string            //the fee after the step
+}
+
+type GetFeeDetailRsp struct {
+	SrcChainId               uint64
+	Hash                     string
+	DstChainId               uint64
+	SwapTokenHash            string
+	FeeTokenName             string
+	FeeTokenPrecision        uint64
+	FeeTokenPrice            int64
+	ChainFeeTokenName        string
+	ChainFeeTokenPrecision   uint64
+	ChainFeeTokenPrice       int64
+	ChainFeeTime             int64
+	Components               []*FeeComponentRsp
+	UsdtAmount               string
+	TokenAmount              string
+	TokenAmountWithPrecision string
+	IsNative                 bool
+	NativeTokenAmount        string
+}
+
+func MakeGetFeeDetailRsp(getFeeReq *GetFeeReq) *GetFeeDetailRsp {
+	return &GetFeeDetailRsp{
+		SrcChainId:    getFeeReq.SrcChainId,
+		Hash:          getFeeReq.Hash,
+		DstChainId:    getFeeReq.DstChainId,
+		SwapTokenHash: getFeeReq.SwapTokenHash,
+		Components:    make([]*FeeComponentRsp, 0),
+	}
+}
+
+// SetTokens records the fee token and the chain fee the fee is priced from, a nil detail records nothing
+func (rsp *GetFeeDetailRsp) SetTokens(token *Token, chainFee *ChainFee) {
+	if rsp == nil {
+		return
+	}
+	rsp.FeeTokenName = token.TokenBasicName
+	rsp.FeeTokenPrecision = token.Precision
+	rsp.FeeTokenPrice = token.TokenBasic.Price
+	rsp.ChainFeeTokenName = chainFee.TokenBasicName
+	if chainFee.TokenBasic != nil {
+		rsp.ChainFeeTokenPrecision = chainFee.TokenBasic.Precision
+		rsp.ChainFeeTokenPrice = chainFee.TokenBasic.Price
+	}
+	rsp.ChainFeeTime = chainFee.Time
+}
+
+// AddComponent records a step of the fee, inputs are pairs of name and value
+func (rsp *GetFeeDetailRsp) AddComponent(name string, applied bool, rule string, result *big.Float, inputs ...interface{}) {
+	if rsp == nil {
+		return
+	}
+	component := &FeeComponentRsp{
+		Name:    name,
+		Applied: applied,
+		Rule:    rule,
+		Inputs:  make(map[string]string, len(inputs)/2),
+		Result:  fmt.Sprintf("%v", result),
+	}
+	for i := 0; i+1 < len(inputs); i += 2 {
+		component.Inputs[fmt.Sprintf("%v", inputs[i])] = fmt.Sprintf("%v", inputs[i+1])
+	}
+	rsp.Components = append(rsp.Components, component)
+}
+
+// SetTotals records the totals in the format of GetFeeRsp
+func (rsp *GetFeeDetailRsp) SetTotals(usdtAmount *big.Float, tokenAmount *big.Float, tokenAmountWithPrecision *big.Float, isNative bool, nativeTokenAmount *big.Float) {
+	getFeeRsp := MakeGetFeeRsp(rsp.SrcChainId, rsp.Hash, rsp.DstChainId, usdtAmount, tokenAmount, tokenAmountWithPrecision, rsp.SwapTokenHash,
+		new(big.Float).SetUint64(0), new(big.Float).SetUint64(0), isNative, nativeTokenAmount, rsp.FeeTokenPrecision)
+	rsp.UsdtAmount = getFeeRsp.UsdtAmount
+	rsp.TokenAmount = getFeeRsp.TokenAmount
+	rsp.TokenAmountWithPrecision = getFeeRsp.TokenAmountWithPrecision
+	rsp.IsNative = getFeeRsp.IsNative
+	rsp.NativeTokenAmount = getFeeRsp.NativeTokenAmount
+}
@@ -0,0 +1,31 @@
+package models
+
+import (
+	"math/big"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestGetFeeDetailRsp(t *testing.T) {
+	var nilDetail *GetFeeDetailRsp
+	nilDetail.AddComponent("proxy_fee", true, "", big.NewFloat(1))
+
+	detail := MakeGetFeeDetailRsp(&GetFeeReq{SrcChainId: 2, Hash: "0000000000000000000000000000000000000000", DstChainId: 6})
+	detail.SetTokens(&Token{TokenBasicName: "ETH", Precision: 18, TokenBasic: &TokenBasic{Price: 300000000000}},
+		&ChainFee{TokenBasicName: "BNB", TokenBasic: &TokenBasic{Precision: 18, Price: 30000000000}, Time: 100})
+	proxyFee := big.NewFloat(2)
+	detail.AddComponent("risky_coin", true, "rule", proxyFee, "Rank", 200, "RiskyCoinRisingRate", big.NewFloat(1.5))
+	proxyFee.Mul(proxyFee, big.NewFloat(10))
+	detail.SetTotals(big.NewFloat(1.5), big.NewFloat(0.0005), big.NewFloat(500000000000000), false, big.NewFloat(0))
+
+	if assert.Len(t, detail.Components, 1) {
+		// the result is taken when the step is recorded
+		assert.Equal(t, "2", detail.Components[0].Result)
+		assert.Equal(t, map[string]string{"Rank": "200", "RiskyCoinRisingRate": "1.5"}, detail.Components[0].Inputs)
+	}
+	assert.Equal(t, "ETH", detail.FeeTokenName)
+	assert.Equal(t, uint64(18), detail.ChainFeeTokenPrecision)
+	assert.Equal(t, "0.000500000000000000", detail.TokenAmount)
+	assert.Equal(t, "500000000000000", detail.TokenAmountWithPrecision)
+}
@@ -144,17 +144,34 @@ func (bigInt *BigInt) Value() (driver.Value, error) {
 }
 
 func (bigInt *BigInt) Scan(v interface{}) error {
-	value, ok := v.([]byte)
-	if !ok {
+	var str string
+	switch value := v.(type) {
+	case []byte:
+		str = string(value)
+	case string:
+		str = value
+	case int64:
+		bigInt.Int = *new(big.Int).SetInt64(value)
+		return nil
+	case float64:
+		// sqlite returns the sums exceeding int64 as real
+		str = strconv.FormatFloat(value, 'f', -1, 64)
+	case nil:
+		return nil
+	default:
 		return fmt.Errorf("type error, %v", v)
 	}
-	str := string(value)
 	if str == "null" || str == "nil" || str == "<nil>" || str == "" {
 		return nil
 	}
 	data, ok := new(big.Int).SetString(str, 10)
 	if !ok {
-		return fmt.Errorf("not a valid big integer: %s", value)
+		// the decimal sums may carry a fraction of zeros, like 100.000
+		value, ok := new(big.Float).SetPrec(512).SetString(str)
+		if !ok || !value.IsInt() {
+			return fmt.Errorf("not a valid big integer: %s", str)
+		}
+		data, _ = value.Int(nil)
 	}
 	bigInt.Int = *data
 	return nil
@@ -575,3 +592,49 @@ func jsonRequest(url string, data []byte) (result []byte, err error) {
 
 	return ioutil.ReadAll(resp.Body)
 }
+
+// WebhookStates are the wrapper transaction states a webhook subscription can be notified of
+var WebhookStates = []uint64{basedef.STATE_SOURCE_DONE, basedef.STATE_POLY_CONFIRMED, basedef.STATE_FINISHED}
+
+// FormatWebhookStates checks the subscribed states and joins them for WebhookSubscription.States,
+// no states means all of WebhookStates
+func FormatWebhookStates(states []uint64) (string, error) {
+	items := make([]string, 0, len(states))
+	for _, state := range states {
+		valid := false
+		for _, webhookState := range WebhookStates {
+			if state == webhookState {
+				valid = true
+				break
+			}
+		}
+		if !valid {
+			return "", fmt.Errorf("state %d can not be subscribed", state)
+		}
+		items = append(items, strconv.FormatUint(state, 10))
+	}
+	return strings.Join(items, ","), nil
+}
+
+func ParseWebhookStates(states string) []uint64 {
+	if states == "" {
+		return WebhookStates
+	}
+	items := strings.Split(states, ",")
+	result := make([]uint64, 0, len(items))
+	for _, item := range items {
+		if state, err := strconv.ParseUint(item, 10, 64); err == nil {
+			result = append(result, state)
+		}
+	}
+	return result
+}
+
+func (sub *WebhookSubscription) Accepts(state uint64) bool {
+	for _, s := range ParseWebhookStates(sub.States) {
+		if s == state {
+			return true
+		}
+	}
+	return false
+}
@@ -35,3 +35,18 @@ func TestGetL1BlockNumberOfArbitrumTx(t *testing.T) {
 		}
 	})
 }
+
+func TestBigIntScan(t *testing.T) {
+	for _, v := range []interface{}{[]byte("100000000000000000000"), "100000000000000000000", "100000000000000000000.000", float64(1e20)} {
+		bigInt := new(BigInt)
+		if err := bigInt.Scan(v); err != nil {
+			t.Fatal(err)
+		}
+		if bigInt.String() != "100000000000000000000" {
+			t.Fatalf("scan %v: %s", v, bigInt.String())
+		}
+	}
+	if err := new(BigInt).Scan("1.5"); err == nil {
+		t.Fatal("a fraction is not a big integer")
+	}
+}
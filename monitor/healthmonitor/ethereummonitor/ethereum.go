@@ -22,14 +22,14 @@ type EthereumHealthMonitor struct {
 	nodeHeight    map[string]uint64
 }
 
-func NewEthereumHealthMonitor(monitorConfig *conf.HealthMonitorConfig) *EthereumHealthMonitor {
+func NewEthereumHealthMonitor(monitorConfig *conf.HealthMonitorConfig, cache cacheRedis.Cache) *EthereumHealthMonitor {
 	ethMonitor := &EthereumHealthMonitor{}
 	ethMonitor.monitorConfig = monitorConfig
 	sdks := make(map[string]*chainsdk.EthereumSdk, 0)
 	for _, node := range monitorConfig.ChainNodes.Nodes {
 		sdk, err := chainsdk.NewEthereumSdk(node.Url)
 		if err != nil || sdk == nil || sdk.GetClient() == nil {
-			if _, e := cacheRedis.Redis.Set(cacheRedis.NodeStatusPrefix+node.Url, fmt.Sprintf("initial sdk error:%s", err), time.Hour*24); e != nil {
+			if _, e := cache.Set(cacheRedis.NodeStatusPrefix+node.Url, fmt.Sprintf("initial sdk error:%s", err), time.Hour*24); e != nil {
 				logs.Error("set %s node[%s] status error: %s", monitorConfig.ChainName, node.Url, e)
 			}
 			logs.Error("%s node: %s, NewEthereumSdk error: %s", monitorConfig.ChainName, node.Url, err)
@@ -4,7 +4,6 @@ import (
 	"encoding/json"
 	"fmt"
 	"github.com/beego/beego/v2/core/logs"
-	"gorm.io/driver/mysql"
 	"gorm.io/gorm"
 	"gorm.io/gorm/logger"
 	"poly-bridge/basedef"
@@ -18,6 +17,7 @@ import (
 	"poly-bridge/monitor/healthmonitor/polymonitor"
 	"poly-bridge/monitor/healthmonitor/ripplemonitor"
 	"poly-bridge/monitor/healthmonitor/zilliqamonitor"
+	"poly-bridge/utils/database"
 	"poly-bridge/utils/transactions"
 	"runtime/debug"
 	"strconv"
@@ -25,6 +25,7 @@ import (
 )
 
 var db *gorm.DB
+var cache cacheRedis.Cache
 var healthMonitorConfigMap = make(map[uint64]*conf.HealthMonitorConfig, 0)
 
 func Init() {
@@ -33,16 +34,16 @@ func Init() {
 		Logger = Logger.LogMode(logger.Info)
 	}
 	dbConfig := conf.GlobalConfig.DBConfig
-	dbConn := fmt.Sprintf("%s:%s@tcp(%s)/%s?charset=utf8", dbConfig.User, dbConfig.Password, dbConfig.URL, dbConfig.Scheme)
 	var err error
-	db, err = gorm.Open(mysql.Open(dbConn), &gorm.Config{Logger: Logger})
+	db, err = database.OpenWithLogger(dbConfig, Logger)
 	if err != nil {
 		panic(err)
 	}
 }
 
-func StartHealthMonitor(config *conf.Config, relayerConfig *conf.RelayerConfig) {
+func StartHealthMonitor(config *conf.Config, relayerConfig *conf.RelayerConfig, c cacheRedis.Cache) {
 	Init()
+	cache = c
 	for _, cfg := range config.ChainNodes {
 		monitorConfig := &conf.HealthMonitorConfig{ChainId: cfg.ChainId, ChainName: cfg.ChainName, ChainNodes: cfg}
 		healthMonitorConfigMap[cfg.ChainId] = monitorConfig
@@ -58,7 +59,7 @@ func StartHealthMonitor(config *conf.Config, relayerConfig *conf.RelayerConfig)
 		}
 	}
 	for _, monitorConfig := range healthMonitorConfigMap {
-		healthMonitorHandle := NewHealthMonitorHandle(monitorConfig)
+		healthMonitorHandle := NewHealthMonitorHandle(monitorConfig, cache)
 		if healthMonitorHandle == nil {
 			logs.Error("chain %s handler is nil", monitorConfig.ChainName)
 			continue
@@ -100,7 +101,7 @@ func (h *HealthMonitor) NodeMonitor(config *conf.Config) {
 			lastHighestNodeStatus := new(basedef.NodeStatus)
 			lastNodeStatusMap := make(map[string]*basedef.NodeStatus)
 
-			if dataStr, err := cacheRedis.Redis.Get(cacheRedis.NodeStatusPrefix + strconv.FormatUint(h.handle.GetChainId(), 10)); err == nil {
+			if dataStr, err := cache.Get(cacheRedis.NodeStatusPrefix + strconv.FormatUint(h.handle.GetChainId(), 10)); err == nil {
 				var lastNodeStatuses []basedef.NodeStatus
 				if err = json.Unmarshal([]byte(dataStr), &lastNodeStatuses); err != nil {
 					logs.Error("chain %s node status data Unmarshal error: ", h.handle.GetChainName(), err)
@@ -146,18 +147,18 @@ func (h *HealthMonitor) NodeMonitor(config *conf.Config) {
 							continue
 						}
 						if recoverAlarm {
-							if _, err = cacheRedis.Redis.Del(cacheRedis.NodeStatusAlarmPrefix + nodeStatus.Url); err != nil {
+							if _, err = cache.Del(cacheRedis.NodeStatusAlarmPrefix + nodeStatus.Url); err != nil {
 								logs.Error("clear %s node: %s alarm err: %s", h.handle.GetChainName(), nodeStatus.Url, err)
 							}
 						} else {
-							if _, err = cacheRedis.Redis.Set(cacheRedis.NodeStatusAlarmPrefix+nodeStatus.Url, "alarm has been sent", time.Second*time.Duration(config.BotConfig.ChainNodeStatusAlarmInterval)); err != nil {
+							if _, err = cache.Set(cacheRedis.NodeStatusAlarmPrefix+nodeStatus.Url, "alarm has been sent", time.Second*time.Duration(config.BotConfig.ChainNodeStatusAlarmInterval)); err != nil {
 								logs.Error("mark %s node: %s alarm has been sent error: %s", h.handle.GetChainName(), nodeStatus.Url, err)
 							}
 						}
 					}
 				}
 				nodeData, _ := json.Marshal(nodeStatuses)
-				_, err = cacheRedis.Redis.Set(cacheRedis.NodeStatusPrefix+strconv.FormatUint(h.handle.GetChainId(), 10), nodeData, time.Hour*24)
+				_, err = cache.Set(cacheRedis.NodeStatusPrefix+strconv.FormatUint(h.handle.GetChainId(), 10), nodeData, time.Hour*24)
 				if err != nil {
 					logs.Error("set %s node status error: %s", h.handle.GetChainName(), err)
 				}
@@ -173,7 +174,7 @@ func (h *HealthMonitor) dealChainAlarm(nodeStatuses []basedef.NodeStatus, lastHi
 	}
 
 	var lastChainStatus basedef.ChainStatus
-	dataStr, err := cacheRedis.Redis.Get(cacheRedis.ChainStatusPrefix + strconv.FormatUint(h.handle.GetChainId(), 10))
+	dataStr, err := cache.Get(cacheRedis.ChainStatusPrefix + strconv.FormatUint(h.handle.GetChainId(), 10))
 	if err == nil {
 		err = json.Unmarshal([]byte(dataStr), &lastChainStatus)
 		logs.Info("%s lastChainStatus:%+v", h.handle.GetChainName(), lastChainStatus)
@@ -203,7 +204,7 @@ func (h *HealthMonitor) dealChainAlarm(nodeStatuses []basedef.NodeStatus, lastHi
 		}
 	}
 
-	txs, _, err := transactions.GetStuckTxs(db, cacheRedis.Redis, 1000, 0, 0)
+	txs, _, err := transactions.GetStuckTxs(db, cache, 1000, 0, 0)
 	for _, tx := range txs {
 		if tx.SrcChainId == h.handle.GetChainId() {
 			relation, e := transactions.GetSrcPolyDstRelation(db, tx)
@@ -264,7 +265,7 @@ func (h *HealthMonitor) dealChainAlarm(nodeStatuses []basedef.NodeStatus, lastHi
 	}
 
 	chainData, _ := json.Marshal(chainStatus)
-	if _, e := cacheRedis.Redis.Set(cacheRedis.ChainStatusPrefix+strconv.FormatUint(h.handle.GetChainId(), 10), chainData, time.Hour*24); e != nil {
+	if _, e := cache.Set(cacheRedis.ChainStatusPrefix+strconv.FormatUint(h.handle.GetChainId(), 10), chainData, time.Hour*24); e != nil {
 		err = fmt.Errorf("set %s status error: %s", h.handle.GetChainName(), e)
 	}
 	return err
@@ -295,7 +296,7 @@ func (h *HealthMonitor) RelayerAccountMonitor(config *conf.Config) {
 					}
 				}
 				data, _ := json.Marshal(relayerAccountStatuses)
-				_, err = cacheRedis.Redis.Set(cacheRedis.RelayerAccountStatusPrefix+h.handle.GetChainName(), data, time.Hour*24)
+				_, err = cache.Set(cacheRedis.RelayerAccountStatusPrefix+h.handle.GetChainName(), data, time.Hour*24)
 				if err != nil {
 					logs.Error("set %s node status error: %s", h.handle.GetChainName(), err)
 				}
@@ -308,12 +309,12 @@ func (h *HealthMonitor) RelayerAccountMonitor(config *conf.Config) {
 						}
 						alarmKey := fmt.Sprintf("%s%s-%s", cacheRedis.RelayerAccountStatusAlarmPrefix, accountStatus.ChainName, accountStatus.Address)
 						if recoverAlarm {
-							if _, err = cacheRedis.Redis.Del(alarmKey); err != nil {
+							if _, err = cache.Del(alarmKey); err != nil {
 								logs.Error("clear %s relayer address: %s alarm err: %s", h.handle.GetChainName(), accountStatus.Address, err)
 							}
 							logs.Info("clear %s relayer address: %s alarm", h.handle.GetChainName(), accountStatus.Address)
 						} else {
-							if _, err = cacheRedis.Redis.Set(alarmKey, "alarm has been sent", time.Hour*12); err != nil {
+							if _, err = cache.Set(alarmKey, "alarm has been sent", time.Hour*12); err != nil {
 								logs.Error("mark %s relayer address: %s alarm has been sent error: %s", h.handle.GetChainName(), accountStatus.Address, err)
 							}
 							logs.Info("mark %s relayer address: %s alarm has been sent", h.handle.GetChainName(), accountStatus.Address)
@@ -326,7 +327,7 @@ func (h *HealthMonitor) RelayerAccountMonitor(config *conf.Config) {
 }
 
 func needSendNodeStatusAlarm(nodeStatus *basedef.NodeStatus) (send, recover bool) {
-	exist, err := cacheRedis.Redis.Exists(cacheRedis.NodeStatusAlarmPrefix + nodeStatus.Url)
+	exist, err := cache.Exists(cacheRedis.NodeStatusAlarmPrefix + nodeStatus.Url)
 	if err == nil {
 		if exist {
 			if len(nodeStatus.Status) == 0 {
@@ -343,7 +344,7 @@ func needSendNodeStatusAlarm(nodeStatus *basedef.NodeStatus) (send, recover bool
 	}
 
 	if send {
-		ignore, _ := cacheRedis.Redis.Exists(cacheRedis.IgnoreNodeStatusAlarmPrefix + nodeStatus.Url)
+		ignore, _ := cache.Exists(cacheRedis.IgnoreNodeStatusAlarmPrefix + nodeStatus.Url)
 		if ignore {
 			send = false
 			logs.Info("ignore %s node: %s alarm", nodeStatus.ChainName, nodeStatus.Url)
@@ -361,7 +362,7 @@ func needSendRelayerAccountStatusAlarm(relayerStatus *basedef.RelayerAccountStat
 		return false, false
 	}
 	alarmKey := fmt.Sprintf("%s%s-%s", cacheRedis.RelayerAccountStatusAlarmPrefix, relayerStatus.ChainName, relayerStatus.Address)
-	exist, err := cacheRedis.Redis.Exists(alarmKey)
+	exist, err := cache.Exists(alarmKey)
 	if err == nil {
 		if exist {
 			if relayerStatus.Status == basedef.StatusOk {
@@ -481,32 +482,47 @@ func sendRelayerAccountStatusDingAlarm(relayerStatus *basedef.RelayerAccountStat
 	return common.PostDingCard(title, body, buttons, conf.GlobalConfig.BotConfig.RelayerAccountStatusDingUrl)
 }
 
-func NewHealthMonitorHandle(monitorConfig *conf.HealthMonitorConfig) MonitorHandle {
-	switch monitorConfig.ChainId {
-	case basedef.POLY_CROSSCHAIN_ID:
+type MonitorHandleFactory func(monitorConfig *conf.HealthMonitorConfig, cache cacheRedis.Cache) MonitorHandle
+
+var monitorHandleFactories = map[string]MonitorHandleFactory{
+	basedef.CHAIN_FAMILY_POLY: func(monitorConfig *conf.HealthMonitorConfig, cache cacheRedis.Cache) MonitorHandle {
 		return polymonitor.NewPolyHealthMonitor(monitorConfig)
-	case basedef.ETHEREUM_CROSSCHAIN_ID, basedef.O3_CROSSCHAIN_ID, basedef.BSC_CROSSCHAIN_ID, basedef.PLT_CROSSCHAIN_ID,
-		basedef.OK_CROSSCHAIN_ID, basedef.HECO_CROSSCHAIN_ID, basedef.MATIC_CROSSCHAIN_ID, basedef.ARBITRUM_CROSSCHAIN_ID,
-		basedef.XDAI_CROSSCHAIN_ID, basedef.FANTOM_CROSSCHAIN_ID, basedef.AVAX_CROSSCHAIN_ID, basedef.OPTIMISTIC_CROSSCHAIN_ID,
-		basedef.METIS_CROSSCHAIN_ID, basedef.RINKEBY_CROSSCHAIN_ID, basedef.BOBA_CROSSCHAIN_ID, basedef.OASIS_CROSSCHAIN_ID,
-		basedef.HARMONY_CROSSCHAIN_ID, basedef.KCC_CROSSCHAIN_ID, basedef.BYTOM_CROSSCHAIN_ID, basedef.HSC_CROSSCHAIN_ID,
-		basedef.KAVA_CROSSCHAIN_ID, basedef.CUBE_CROSSCHAIN_ID, basedef.ZKSYNC_CROSSCHAIN_ID, basedef.CELO_CROSSCHAIN_ID,
-		basedef.CLOVER_CROSSCHAIN_ID, basedef.CONFLUX_CROSSCHAIN_ID, basedef.ASTAR_CROSSCHAIN_ID, basedef.BRISE_CROSSCHAIN_ID,
-		basedef.DEXIT_CROSSCHAIN_ID, basedef.CLOUDTX_CROSSCHAIN_ID:
-		return ethereummonitor.NewEthereumHealthMonitor(monitorConfig)
-	case basedef.NEO_CROSSCHAIN_ID:
-		return neomonitor.NewNeoHealthMonitor(monitorConfig)
-	case basedef.ONT_CROSSCHAIN_ID:
+	},
+	basedef.CHAIN_FAMILY_EVM: func(monitorConfig *conf.HealthMonitorConfig, cache cacheRedis.Cache) MonitorHandle {
+		return ethereummonitor.NewEthereumHealthMonitor(monitorConfig, cache)
+	},
+	basedef.CHAIN_FAMILY_O3: func(monitorConfig *conf.HealthMonitorConfig, cache cacheRedis.Cache) MonitorHandle {
+		return ethereummonitor.NewEthereumHealthMonitor(monitorConfig, cache)
+	},
+	basedef.CHAIN_FAMILY_NEO: func(monitorConfig *conf.HealthMonitorConfig, cache cacheRedis.Cache) MonitorHandle {
+		return neomonitor.NewNeoHealthMonitor(monitorConfig, cache)
+	},
+	basedef.CHAIN_FAMILY_ONTOLOGY: func(monitorConfig *conf.HealthMonitorConfig, cache cacheRedis.Cache) MonitorHandle {
 		return ontologymonitor.NewOntologyHealthMonitor(monitorConfig)
-	//case basedef.SWITCHEO_CROSSCHAIN_ID:
+	},
+	//basedef.CHAIN_FAMILY_SWITCHEO: func(monitorConfig *conf.HealthMonitorConfig, cache cacheRedis.Cache) MonitorHandle {
 	//	return switcheomonitor.NewSwitcheoHealthMonitor(monitorConfig)
-	case basedef.NEO3_CROSSCHAIN_ID:
-		return neo3monitor.NewNeo3HealthMonitor(monitorConfig)
-	case basedef.ZILLIQA_CROSSCHAIN_ID:
+	//},
+	basedef.CHAIN_FAMILY_NEO3: func(monitorConfig *conf.HealthMonitorConfig, cache cacheRedis.Cache) MonitorHandle {
+		return neo3monitor.NewNeo3HealthMonitor(monitorConfig, cache)
+	},
+	basedef.CHAIN_FAMILY_ZILLIQA: func(monitorConfig *conf.HealthMonitorConfig, cache cacheRedis.Cache) MonitorHandle {
 		return zilliqamonitor.NewZilliqaHealthMonitor(monitorConfig)
-	case basedef.RIPPLE_CROSSCHAIN_ID:
+	},
+	basedef.CHAIN_FAMILY_RIPPLE: func(monitorConfig *conf.HealthMonitorConfig, cache cacheRedis.Cache) MonitorHandle {
 		return ripplemonitor.NewRippleHealthMonitor(monitorConfig)
-	default:
+	},
+}
+
+// RegisterMonitorHandle registers the health monitor factory of a chain family
+func RegisterMonitorHandle(family string, factory MonitorHandleFactory) {
+	monitorHandleFactories[family] = factory
+}
+
+func NewHealthMonitorHandle(monitorConfig *conf.HealthMonitorConfig, cache cacheRedis.Cache) MonitorHandle {
+	factory, ok := monitorHandleFactories[basedef.GetChainFamily(monitorConfig.ChainId)]
+	if !ok {
 		return nil
 	}
+	return factory(monitorConfig, cache)
 }
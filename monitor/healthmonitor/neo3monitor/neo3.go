@@ -22,14 +22,14 @@ type Neo3Monitor struct {
 	nodeHeight    map[string]uint64
 }
 
-func NewNeo3HealthMonitor(monitorConfig *conf.HealthMonitorConfig) *Neo3Monitor {
+func NewNeo3HealthMonitor(monitorConfig *conf.HealthMonitorConfig, cache cacheRedis.Cache) *Neo3Monitor {
 	neo3Monitor := &Neo3Monitor{}
 	neo3Monitor.monitorConfig = monitorConfig
 	sdks := make(map[string]*chainsdk.Neo3Sdk, 0)
 	for _, node := range monitorConfig.ChainNodes.Nodes {
 		sdk := chainsdk.NewNeo3Sdk(node.Url)
 		if sdk.GetClient() == nil {
-			if _, err := cacheRedis.Redis.Set(cacheRedis.NodeStatusPrefix+node.Url, fmt.Sprintf("initial sdk error:sdk.client is nil"), time.Hour*24); err != nil {
+			if _, err := cache.Set(cacheRedis.NodeStatusPrefix+node.Url, fmt.Sprintf("initial sdk error:sdk.client is nil"), time.Hour*24); err != nil {
 				logs.Error("set %s node[%s] status error: %s", monitorConfig.ChainName, node.Url, err)
 			}
 			logs.Error("%s node: %s, initial sdk error: sdk.client is nil", monitorConfig.ChainName, node.Url)
@@ -19,14 +19,14 @@ type NeoMonitor struct {
 	nodeHeight    map[string]uint64
 }
 
-func NewNeoHealthMonitor(monitorConfig *conf.HealthMonitorConfig) *NeoMonitor {
+func NewNeoHealthMonitor(monitorConfig *conf.HealthMonitorConfig, cache cacheRedis.Cache) *NeoMonitor {
 	neoMonitor := &NeoMonitor{}
 	neoMonitor.monitorConfig = monitorConfig
 	sdks := make(map[string]*chainsdk.NeoSdk, 0)
 	for _, node := range monitorConfig.ChainNodes.Nodes {
 		sdk := chainsdk.NewNeoSdk(node.Url)
 		if sdk.GetClient() == nil {
-			if _, err := cacheRedis.Redis.Set(cacheRedis.NodeStatusPrefix+node.Url, fmt.Sprintf("initial sdk error:sdk.client is nil"), time.Hour*24); err != nil {
+			if _, err := cache.Set(cacheRedis.NodeStatusPrefix+node.Url, fmt.Sprintf("initial sdk error:sdk.client is nil"), time.Hour*24); err != nil {
 				logs.Error("set %s node[%s] status error: %s", monitorConfig.ChainName, node.Url, err)
 			}
 			logs.Error("%s node: %s, initial sdk error:sdk.client is nil", monitorConfig.ChainName, node.Url)
@@ -9,6 +9,7 @@ import (
 	"poly-bridge/basedef"
 	"poly-bridge/cacheRedis"
 	"poly-bridge/conf"
+	"poly-bridge/migration"
 	"poly-bridge/monitor/healthmonitor"
 	"runtime"
 	"syscall"
@@ -59,9 +60,15 @@ func StartServer(ctx *cli.Context) {
 		return
 	}
 	logs.SetLogger(logs.AdapterFile, fmt.Sprintf(`{"filename":"%s"}`, config.MonitorLogFile))
-	cacheRedis.Init()
+	cache, err := cacheRedis.NewCache(config.RedisConfig)
+	if err != nil {
+		panic(err)
+	}
 	basedef.ConfirmEnv(config.Env)
-	healthmonitor.StartHealthMonitor(config, relayerConfig)
+	if err := migration.CheckSchema(config.DBConfig); err != nil {
+		panic(err)
+	}
+	healthmonitor.StartHealthMonitor(config, relayerConfig, cache)
 	for true {
 		sig := waitSignal()
 		if sig != syscall.SIGHUP {
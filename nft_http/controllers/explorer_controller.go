@@ -4,6 +4,7 @@ import (
 	"errors"
 	"fmt"
 	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
 	"poly-bridge/basedef"
 	"poly-bridge/models"
 	"time"
@@ -85,7 +86,7 @@ func (c *ExplorerController) TransactionsOfAddress() {
 			Find(&relations)
 		db.Model(&models.SrcTransfer{}).
 			Joins("inner join wrapper_transactions on src_transfers.tx_hash = wrapper_transactions.hash").
-			Where("src_transfers.standard = ? and (`from` in ? or src_transfers.dst_user in ?)", models.TokenTypeErc721, req.Addresses, req.Addresses).
+			Where("src_transfers.standard = ? and (? in ? or src_transfers.dst_user in ?)", models.TokenTypeErc721, clause.Column{Table: "src_transfers", Name: "from"}, req.Addresses, req.Addresses).
 			Count(&transactionNum)
 	} else {
 		db.Raw("select wp.*, tr.amount as token_id, tr.asset as src_asset "+
@@ -98,8 +99,8 @@ func (c *ExplorerController) TransactionsOfAddress() {
 			Find(&relations)
 		db.Model(&models.SrcTransfer{}).
 			Joins("inner join wrapper_transactions on src_transfers.tx_hash = wrapper_transactions.hash").
-			Where("src_transfers.standard = ? and (`from` in ? or src_transfers.dst_user in ?) and (wrapper_transactions.src_chain_id = ? or wrapper_transactions.dst_chain_id = ?)",
-				models.TokenTypeErc721, req.Addresses, req.Addresses, req.ChainId, req.ChainId).
+			Where("src_transfers.standard = ? and (? in ? or src_transfers.dst_user in ?) and (wrapper_transactions.src_chain_id = ? or wrapper_transactions.dst_chain_id = ?)",
+				models.TokenTypeErc721, clause.Column{Table: "src_transfers", Name: "from"}, req.Addresses, req.Addresses, req.ChainId, req.ChainId).
 			Count(&transactionNum)
 	}
 
@@ -22,6 +22,7 @@ import (
 	"math/big"
 	"poly-bridge/basedef"
 	"poly-bridge/models"
+	"poly-bridge/utils/fee"
 
 	"github.com/beego/beego/v2/server/web"
 )
@@ -69,6 +70,29 @@ func (c *FeeController) GetFee() {
 	tokenFee := new(big.Float).Mul(usdtFee, new(big.Float).SetInt64(basedef.PRICE_PRECISION))
 	tokenFee = new(big.Float).Quo(tokenFee, new(big.Float).SetInt64(token.TokenBasic.Price))
 	tokenFeeWithPrecision := new(big.Float).Mul(tokenFee, new(big.Float).SetInt64(basedef.Int64FromFigure(int(token.Precision))))
+	// get the L1 data fee of a rollup
+	chainFees := make([]*models.ChainFee, 0)
+	if err := db.Preload("TokenBasic").Find(&chainFees).Error; err != nil {
+		c.Data["json"] = models.MakeErrorRsp(fmt.Sprintf("get chain fees failed. err=%v", err))
+		c.Ctx.ResponseWriter.WriteHeader(400)
+		c.ServeJSON()
+		return
+	}
+	chain2Fees := make(map[uint64]*models.ChainFee, len(chainFees))
+	for _, l1ChainFee := range chainFees {
+		chain2Fees[l1ChainFee.ChainId] = l1ChainFee
+	}
+	_, l1UsdtFee, _, err := fee.GetL1Fee(chainFee, fee.ChainFeesGetter(chain2Fees))
+	if err != nil {
+		c.Data["json"] = models.MakeErrorRsp(fmt.Sprintf("get L1 fee failed. err=%v", err))
+		c.Ctx.ResponseWriter.WriteHeader(400)
+		c.ServeJSON()
+		return
+	}
+	l1TokenFee := new(big.Float).Mul(l1UsdtFee, new(big.Float).SetInt64(basedef.PRICE_PRECISION))
+	l1TokenFee = new(big.Float).Quo(l1TokenFee, new(big.Float).SetInt64(token.TokenBasic.Price))
+	tokenFee = new(big.Float).Add(tokenFee, l1TokenFee)
+	tokenFeeWithPrecision = new(big.Float).Add(tokenFeeWithPrecision, new(big.Float).Mul(l1TokenFee, new(big.Float).SetInt64(basedef.Int64FromFigure(int(token.Precision)))))
 	c.Data["json"] = models.MakeGetFeeRsp(req.SrcChainId, req.Hash, req.DstChainId, usdtFee, tokenFee, tokenFeeWithPrecision, "", fzero, fzero, false, fzero, feeTokenPricison)
 	c.ServeJSON()
 }
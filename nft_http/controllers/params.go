@@ -492,7 +492,7 @@ type SrcPolyDstRelation struct {
 	PolyTransaction    *models.PolyTransaction `gorm:"foreignKey:PolyHash;references:Hash"`
 	DstHash            string
 	DstTransaction     *models.DstTransaction `gorm:"foreignKey:DstHash;references:Hash"`
-	ChainId            uint64                 `gorm:"type:bigint(20);not null"`
+	ChainId            uint64                 `gorm:"type:bigint;not null"`
 	SrcAssetHash       string                 `gorm:"type:varchar(66);not null"`
 	SrcAsset           *models.Token          `gorm:"foreignKey:SrcAssetHash,ChainId;references:Hash,ChainId"`
 	DstAssetHash       string                 `gorm:"type:varchar(66);not null"`
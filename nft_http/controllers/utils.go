@@ -26,6 +26,7 @@ import (
 	"poly-bridge/conf"
 	"poly-bridge/models"
 	"poly-bridge/nft_http/meta"
+	"poly-bridge/utils/database"
 	"regexp"
 	"strings"
 	"time"
@@ -34,7 +35,6 @@ import (
 	"github.com/beego/beego/v2/server/web"
 	"github.com/ethereum/go-ethereum/common"
 	lru "github.com/hashicorp/golang-lru"
-	"gorm.io/driver/mysql"
 	"gorm.io/gorm"
 	"gorm.io/gorm/logger"
 )
@@ -54,16 +54,11 @@ var (
 )
 
 func NewDB(cfg *conf.DBConfig) *gorm.DB {
-	user := cfg.User
-	password := cfg.Password
-	url := cfg.URL
-	scheme := cfg.Scheme
 	Logger := logger.Default
 	if cfg.Debug {
 		Logger = Logger.LogMode(logger.Info)
 	}
-	format := fmt.Sprintf("%s:%s@tcp(%s)/%s?charset=utf8", user, password, url, scheme)
-	db, err := gorm.Open(mysql.Open(format), &gorm.Config{Logger: Logger})
+	db, err := database.OpenWithReplicas(cfg, Logger)
 	if err != nil {
 		panic(err)
 	}
@@ -0,0 +1,402 @@
+/*
+ * Copyright (C) 2020 The poly network Authors
+ * This file is part of The poly network library.
+ *
+ * The  poly network  is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU Lesser General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * The  poly network  is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU Lesser General Public License for more details.
+ * You should have received a copy of the GNU Lesser General Public License
+ * along with The poly network .  If not, see <http://www.gnu.org/licenses/>.
+ */
+
+package tablecopy
+
+import (
+	"crypto/sha256"
+	"database/sql/driver"
+	"encoding/hex"
+	"fmt"
+	"poly-bridge/models"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/beego/beego/v2/core/logs"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+	"gorm.io/gorm/schema"
+)
+
+const (
+	defaultBatch = 1000
+	// maxDiffs is the max rows listed in the report of a table, the others are counted only
+	maxDiffs = 1000
+)
+
+const (
+	DiffMissing = "missing"
+	DiffExtra   = "extra"
+	DiffChanged = "changed"
+)
+
+// tables are the models which can be copied, they are found by their table names
+var tables = []interface{}{
+	&models.AirDropInfo{},
+	&models.AirDropNft{},
+	&models.AssetStatistic{},
+	&models.BackfillJob{},
+	&models.Chain{},
+	&models.ChainFee{},
+	&models.ChainStatistic{},
+	&models.CrossChainTx{},
+	&models.DstSwap{},
+	&models.DstTransaction{},
+	&models.DstTransfer{},
+	&models.EventOutbox{},
+	&models.LockTokenStatistic{},
+	&models.NFTProfile{},
+	&models.NftUser{},
+	&models.PolyTransaction{},
+	&models.PriceMarket{},
+	&models.ScanCursor{},
+	&models.SrcSwap{},
+	&models.SrcTransaction{},
+	&models.SrcTransfer{},
+	&models.TimeStatistic{},
+	&models.Token{},
+	&models.TokenBasic{},
+	&models.TokenMap{},
+	&models.TokenPriceAvg{},
+	&models.TokenStatistic{},
+	&models.TransferAuditFinding{},
+	&models.TransferAuditTotal{},
+	&models.WebhookDelivery{},
+	&models.WebhookSubscription{},
+	&models.WrapperTransaction{},
+}
+
+// Tables returns the names of the tables which can be copied
+func Tables(db *gorm.DB) ([]string, error) {
+	names := make([]string, 0, len(tables))
+	for _, model := range tables {
+		stmt := &gorm.Statement{DB: db}
+		if err := stmt.Parse(model); err != nil {
+			return nil, err
+		}
+		names = append(names, stmt.Schema.Table)
+	}
+	sort.Strings(names)
+	return names, nil
+}
+
+type Options struct {
+	Job    string // the progress is saved by job and table
+	Batch  int    // rows copied and compared in one batch
+	DryRun bool   // compare the source with the destination without writing anything
+	Resume bool   // go on after the last key saved by the job, the copy starts from the first row if not set
+}
+
+// Diff is a row different in the source and the destination
+type Diff struct {
+	Key     string
+	Kind    string
+	Columns []string
+}
+
+type Report struct {
+	Table      string
+	From       string // the key the copy went on after, empty from the first row
+	Rows       int64  // rows read from the source in this run
+	Batches    int64
+	Mismatches int64 // batches whose checksums disagree
+	Diffs      []*Diff
+	DiffCount  int64
+	SrcCount   int64
+	DstCount   int64
+}
+
+// Agreed tells whether the destination holds the same rows as the source
+func (r *Report) Agreed() bool {
+	return r.Mismatches == 0 && r.SrcCount == r.DstCount
+}
+
+// Copier copies tables from src to dst in batches ordered by primary key. Every batch is read back from dst and the
+// checksums of the row contents are compared, the rows which disagree are listed in the report. The last key of
+// each batch is saved in dst, so a failed copy is resumed after it.
+type Copier struct {
+	src  *gorm.DB
+	dst  *gorm.DB
+	opts Options
+}
+
+func NewCopier(src, dst *gorm.DB, opts Options) *Copier {
+	if opts.Job == "" {
+		opts.Job = "copy"
+	}
+	if opts.Batch <= 0 {
+		opts.Batch = defaultBatch
+	}
+	return &Copier{src: src, dst: dst, opts: opts}
+}
+
+func (c *Copier) model(table string) (interface{}, *schema.Schema, error) {
+	for _, model := range tables {
+		stmt := &gorm.Statement{DB: c.src}
+		if err := stmt.Parse(model); err != nil {
+			return nil, nil, err
+		}
+		if stmt.Schema.Table == table {
+			if len(stmt.Schema.PrimaryFields) != 1 {
+				return nil, nil, fmt.Errorf("table %s has %d primary keys, one is required", table, len(stmt.Schema.PrimaryFields))
+			}
+			return model, stmt.Schema, nil
+		}
+	}
+	return nil, nil, fmt.Errorf("unknown table %s", table)
+}
+
+// Copy copies the table, the report is returned with the error to tell how far the copy went
+func (c *Copier) Copy(table string) (*Report, error) {
+	report := &Report{Table: table}
+	model, sch, err := c.model(table)
+	if err != nil {
+		return report, err
+	}
+	hasTable := c.dst.Migrator().HasTable(model)
+	if !c.opts.DryRun {
+		if err := c.dst.AutoMigrate(model, &models.TableCopyProgress{}); err != nil {
+			return report, err
+		}
+		hasTable = true
+	}
+	progress := &models.TableCopyProgress{Job: c.opts.Job, Table: table}
+	if c.opts.Resume && c.dst.Migrator().HasTable(&models.TableCopyProgress{}) {
+		err := c.dst.Where("job = ? and ? = ?", c.opts.Job, clause.Column{Name: "table"}, table).Find(progress).Error
+		if err != nil {
+			return report, err
+		}
+	}
+	report.From = progress.LastKey
+	key := sch.PrioritizedPrimaryField
+	var lastKey interface{}
+	if progress.LastKey != "" {
+		if lastKey, err = parseKey(key, progress.LastKey); err != nil {
+			return report, err
+		}
+	}
+	keyColumn := clause.Column{Name: key.DBName}
+	for {
+		rows := newRows(sch)
+		query := c.src.Model(model)
+		if lastKey != nil {
+			query = query.Where("? > ?", keyColumn, lastKey)
+		}
+		err := query.Order(clause.OrderByColumn{Column: keyColumn}).Limit(c.opts.Batch).Find(rows.Interface()).Error
+		if err != nil {
+			return report, fmt.Errorf("read %s after %v err: %w", table, lastKey, err)
+		}
+		values := rows.Elem()
+		if values.Len() == 0 {
+			break
+		}
+		first, _ := key.ValueOf(values.Index(0).Elem())
+		last, _ := key.ValueOf(values.Index(values.Len() - 1).Elem())
+		if !c.opts.DryRun {
+			err := c.dst.Omit(clause.Associations).Clauses(clause.OnConflict{UpdateAll: true}).Create(rows.Interface()).Error
+			if err != nil {
+				return report, fmt.Errorf("write %s [%v, %v] err: %w", table, first, last, err)
+			}
+		}
+		dstRows := newRows(sch)
+		if hasTable {
+			err := c.dst.Model(model).Where("? >= ? and ? <= ?", keyColumn, first, keyColumn, last).
+				Order(clause.OrderByColumn{Column: keyColumn}).Find(dstRows.Interface()).Error
+			if err != nil {
+				return report, fmt.Errorf("read back %s [%v, %v] err: %w", table, first, last, err)
+			}
+		}
+		srcLines, dstLines := encodeRows(sch, values), encodeRows(sch, dstRows.Elem())
+		report.Rows += int64(values.Len())
+		report.Batches++
+		mismatched := checksum(srcLines) != checksum(dstLines)
+		if mismatched {
+			report.Mismatches++
+			report.addDiffs(srcLines, dstLines)
+			logs.Warn("table %s batch [%v, %v] checksums disagree", table, first, last)
+		}
+		lastKey = last
+		if !c.opts.DryRun {
+			progress.LastKey = fmt.Sprint(last)
+			progress.Rows += int64(values.Len())
+			progress.Batches++
+			if mismatched {
+				progress.Mismatches++
+			}
+			progress.UpdateTime = time.Now().Unix()
+			if err := c.dst.Save(progress).Error; err != nil {
+				return report, fmt.Errorf("save progress of %s err: %w", table, err)
+			}
+		}
+	}
+	if err := c.src.Model(model).Count(&report.SrcCount).Error; err != nil {
+		return report, err
+	}
+	if hasTable {
+		if err := c.dst.Model(model).Count(&report.DstCount).Error; err != nil {
+			return report, err
+		}
+	}
+	return report, nil
+}
+
+func newRows(sch *schema.Schema) reflect.Value {
+	return reflect.New(reflect.SliceOf(reflect.PtrTo(sch.ModelType)))
+}
+
+func parseKey(key *schema.Field, value string) (interface{}, error) {
+	switch key.DataType {
+	case schema.Int:
+		return strconv.ParseInt(value, 10, 64)
+	case schema.Uint:
+		return strconv.ParseUint(value, 10, 64)
+	default:
+		return value, nil
+	}
+}
+
+// row is a row encoded by column, the values are formatted the same way whatever the db driver
+type row struct {
+	key     string
+	columns []string
+	values  []string
+}
+
+func encodeRows(sch *schema.Schema, values reflect.Value) []*row {
+	rows := make([]*row, 0, values.Len())
+	for i := 0; i < values.Len(); i++ {
+		value := reflect.Indirect(values.Index(i))
+		key, _ := sch.PrioritizedPrimaryField.ValueOf(value)
+		r := &row{key: fmt.Sprint(key)}
+		for _, field := range sch.Fields {
+			if field.DBName == "" {
+				continue
+			}
+			v, _ := field.ValueOf(value)
+			r.columns = append(r.columns, field.DBName)
+			r.values = append(r.values, format(v))
+		}
+		rows = append(rows, r)
+	}
+	return rows
+}
+
+func format(v interface{}) string {
+	if v == nil {
+		return "NULL"
+	}
+	if rv := reflect.ValueOf(v); rv.Kind() == reflect.Ptr && rv.IsNil() {
+		return "NULL"
+	}
+	if valuer, ok := v.(driver.Valuer); ok {
+		value, err := valuer.Value()
+		if err != nil {
+			return fmt.Sprintf("!%v", err)
+		}
+		v = value
+	}
+	switch value := v.(type) {
+	case nil:
+		return "NULL"
+	case []byte:
+		return string(value)
+	case time.Time:
+		return value.UTC().Format(time.RFC3339Nano)
+	case *time.Time:
+		return value.UTC().Format(time.RFC3339Nano)
+	default:
+		return fmt.Sprint(reflect.Indirect(reflect.ValueOf(v)).Interface())
+	}
+}
+
+func checksum(rows []*row) string {
+	hash := sha256.New()
+	for _, r := range rows {
+		hash.Write([]byte(r.key))
+		for i := range r.columns {
+			hash.Write([]byte{0x1f})
+			hash.Write([]byte(r.columns[i] + "=" + r.values[i]))
+		}
+		hash.Write([]byte{'\n'})
+	}
+	return hex.EncodeToString(hash.Sum(nil))
+}
+
+func (r *Report) addDiffs(srcRows, dstRows []*row) {
+	key2DstRows := make(map[string]*row, len(dstRows))
+	for _, dstRow := range dstRows {
+		key2DstRows[dstRow.key] = dstRow
+	}
+	for _, srcRow := range srcRows {
+		dstRow, ok := key2DstRows[srcRow.key]
+		if !ok {
+			r.addDiff(&Diff{Key: srcRow.key, Kind: DiffMissing})
+			continue
+		}
+		delete(key2DstRows, srcRow.key)
+		columns := make([]string, 0)
+		for i := range srcRow.columns {
+			if srcRow.values[i] != dstRow.values[i] {
+				columns = append(columns, srcRow.columns[i])
+			}
+		}
+		if len(columns) > 0 {
+			r.addDiff(&Diff{Key: srcRow.key, Kind: DiffChanged, Columns: columns})
+		}
+	}
+	for _, dstRow := range dstRows {
+		if _, ok := key2DstRows[dstRow.key]; ok {
+			r.addDiff(&Diff{Key: dstRow.key, Kind: DiffExtra})
+		}
+	}
+}
+
+func (r *Report) addDiff(diff *Diff) {
+	r.DiffCount++
+	if len(r.Diffs) < maxDiffs {
+		r.Diffs = append(r.Diffs, diff)
+	}
+}
+
+// String is the diff report of the table
+func (r *Report) String() string {
+	b := new(strings.Builder)
+	fmt.Fprintf(b, "table %s: %d rows in %d batches", r.Table, r.Rows, r.Batches)
+	if r.From != "" {
+		fmt.Fprintf(b, " after key %s", r.From)
+	}
+	fmt.Fprintf(b, ", source %d rows, destination %d rows", r.SrcCount, r.DstCount)
+	if r.Agreed() {
+		b.WriteString(", agreed\n")
+		return b.String()
+	}
+	fmt.Fprintf(b, ", %d batches and %d rows disagree\n", r.Mismatches, r.DiffCount)
+	for _, diff := range r.Diffs {
+		fmt.Fprintf(b, "  %s %s", diff.Kind, diff.Key)
+		if len(diff.Columns) > 0 {
+			fmt.Fprintf(b, " %s", strings.Join(diff.Columns, ","))
+		}
+		b.WriteString("\n")
+	}
+	if r.DiffCount > int64(len(r.Diffs)) {
+		fmt.Fprintf(b, "  and %d more\n", r.DiffCount-int64(len(r.Diffs)))
+	}
+	return b.String()
+}
@@ -0,0 +1,77 @@
+package tablecopy
+
+import (
+	"fmt"
+	"poly-bridge/basedef"
+	"poly-bridge/models"
+	"poly-bridge/utils/dbtest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"gorm.io/gorm"
+)
+
+func createSrcTransfers(t *testing.T, db *gorm.DB, from, to int) {
+	for i := from; i <= to; i++ {
+		srcTransfer := &models.SrcTransfer{Id: int64(i), TxHash: fmt.Sprintf("hash%02d", i), ChainId: basedef.ETHEREUM_CROSSCHAIN_ID,
+			Time: uint64(1000 + i), Asset: "usdt", Amount: models.NewBigIntFromInt(int64(i * 100))}
+		if err := db.Create(srcTransfer).Error; err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+func TestCopy(t *testing.T) {
+	src, dst := dbtest.OpenNamed(t, "src.db"), dbtest.OpenNamed(t, "dst.db")
+	if err := src.AutoMigrate(&models.SrcTransfer{}); err != nil {
+		t.Fatal(err)
+	}
+	createSrcTransfers(t, src, 1, 5)
+
+	report, err := NewCopier(src, dst, Options{Batch: 2}).Copy("src_transfers")
+	assert.NoError(t, err)
+	assert.Equal(t, int64(5), report.Rows)
+	assert.Equal(t, int64(3), report.Batches)
+	assert.True(t, report.Agreed(), report.String())
+	copied := make([]*models.SrcTransfer, 0)
+	assert.NoError(t, dst.Order("id asc").Find(&copied).Error)
+	if assert.Len(t, copied, 5) {
+		assert.Equal(t, "hash05", copied[4].TxHash)
+		assert.Equal(t, "500", copied[4].Amount.String())
+	}
+
+	// the resumed copy goes on after the last key
+	createSrcTransfers(t, src, 6, 7)
+	report, err = NewCopier(src, dst, Options{Batch: 2, Resume: true}).Copy("src_transfers")
+	assert.NoError(t, err)
+	assert.Equal(t, "5", report.From)
+	assert.Equal(t, int64(2), report.Rows)
+	assert.True(t, report.Agreed(), report.String())
+	progress := new(models.TableCopyProgress)
+	assert.NoError(t, dst.Where("job = ?", "copy").First(progress).Error)
+	assert.Equal(t, "7", progress.LastKey)
+	assert.Equal(t, int64(7), progress.Rows)
+
+	// the dry run reports the rows which disagree and writes nothing
+	assert.NoError(t, dst.Model(&models.SrcTransfer{}).Where("id = ?", 2).Update("amount", models.NewBigIntFromInt(1)).Error)
+	assert.NoError(t, dst.Delete(&models.SrcTransfer{}, 3).Error)
+	assert.NoError(t, src.Delete(&models.SrcTransfer{}, 4).Error)
+	report, err = NewCopier(src, dst, Options{Batch: 10, DryRun: true}).Copy("src_transfers")
+	assert.NoError(t, err)
+	assert.False(t, report.Agreed())
+	assert.Equal(t, int64(1), report.Mismatches)
+	assert.Equal(t, []*Diff{
+		{Key: "2", Kind: DiffChanged, Columns: []string{"amount"}},
+		{Key: "3", Kind: DiffMissing},
+		{Key: "4", Kind: DiffExtra},
+	}, report.Diffs)
+	assert.Contains(t, report.String(), "changed 2 amount")
+	var counter int64
+	assert.NoError(t, dst.Model(&models.SrcTransfer{}).Count(&counter).Error)
+	assert.Equal(t, int64(6), counter)
+	assert.NoError(t, dst.Where("job = ?", "copy").First(progress).Error)
+	assert.Equal(t, "7", progress.LastKey, "the dry run saves no progress")
+
+	_, err = NewCopier(src, dst, Options{}).Copy("unknown")
+	assert.Error(t, err)
+}
@@ -0,0 +1,15 @@
+package backoff
+
+import "time"
+
+// Delay is the delay before the next of attempts failed tries, it doubles from base up to max
+func Delay(attempts int, base, max time.Duration) time.Duration {
+	delay := base
+	for i := 1; i < attempts && delay < max; i++ {
+		delay *= 2
+	}
+	if delay > max {
+		delay = max
+	}
+	return delay
+}
@@ -0,0 +1,16 @@
+package backoff
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestDelay(t *testing.T) {
+	assert.Equal(t, time.Second, Delay(0, time.Second, time.Minute))
+	assert.Equal(t, time.Second, Delay(1, time.Second, time.Minute))
+	assert.Equal(t, time.Second*8, Delay(4, time.Second, time.Minute))
+	assert.Equal(t, time.Minute, Delay(7, time.Second, time.Minute))
+	assert.Equal(t, time.Minute, Delay(1000, time.Second, time.Minute))
+}
@@ -0,0 +1,255 @@
+package cursor
+
+import (
+	"errors"
+	"fmt"
+	"poly-bridge/models"
+	"reflect"
+	"time"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// Lag is how long the listeners may take to save a row after its block time, the rows within it are scanned again
+// or left to the next run
+const Lag = 60 * 60
+
+// Position is a row in the (time, id) order of a table, the zero position is before all the rows
+type Position struct {
+	Time uint64
+	Id   int64
+}
+
+func (p Position) Less(o Position) bool {
+	return p.Time < o.Time || (p.Time == o.Time && p.Id < o.Id)
+}
+
+// before is the position right before p, so that the row at p is scanned again
+func (p Position) before() Position {
+	return Position{Time: p.Time, Id: p.Id - 1}
+}
+
+// After is the condition of the rows of table after pos, an empty table is the current table of the query
+func After(table string, pos Position) clause.Expr {
+	timeColumn, idColumn := columns(table)
+	return gorm.Expr("(? > ? or (? = ? and ? > ?))", timeColumn, pos.Time, timeColumn, pos.Time, idColumn, pos.Id)
+}
+
+// Until is the condition of the rows of table at or before pos
+func Until(table string, pos Position) clause.Expr {
+	timeColumn, idColumn := columns(table)
+	return gorm.Expr("(? < ? or (? = ? and ? <= ?))", timeColumn, pos.Time, timeColumn, pos.Time, idColumn, pos.Id)
+}
+
+func columns(table string) (clause.Column, clause.Column) {
+	if table == "" {
+		table = clause.CurrentTable
+	}
+	return clause.Column{Table: table, Name: "time"}, clause.Column{Table: table, Name: "id"}
+}
+
+// Iterator pages a query by (time, id) instead of offsets, so the rows changed or added under the scan do not shift the pages.
+// The position is saved in scan_cursors by name and the next run goes on after it.
+// The rows a scan goes past before they are done are kept in scan_retries and checked again by id, so they do not hold the cursor.
+type Iterator struct {
+	db        *gorm.DB
+	name      string
+	batch     int
+	saved     bool
+	start     Position
+	pos       Position
+	kept      *Position
+	settle    uint64
+	retryIds  []int64
+	retryNext int
+	retries   map[int64]uint64
+}
+
+// NewIterator loads the saved cursor of name, a new cursor starts before all the rows
+func NewIterator(db *gorm.DB, name string, batch int) (*Iterator, error) {
+	if batch <= 0 {
+		return nil, fmt.Errorf("invalid batch %d of cursor %s", batch, name)
+	}
+	it := &Iterator{db: db, name: name, batch: batch, retries: make(map[int64]uint64)}
+	scanCursor := new(models.ScanCursor)
+	err := db.Where("name = ?", name).First(scanCursor).Error
+	if err == nil {
+		it.saved = true
+		it.Seek(Position{Time: scanCursor.Time, Id: scanCursor.Id})
+	} else if !errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, fmt.Errorf("load cursor %s err: %w", name, err)
+	}
+	err = db.Model(&models.ScanRetry{}).Where("name = ?", name).Order("row_id").Pluck("row_id", &it.retryIds).Error
+	if err != nil {
+		return nil, fmt.Errorf("load retries of cursor %s err: %w", name, err)
+	}
+	return it, nil
+}
+
+// Saved tells whether the cursor was saved by a previous run
+func (it *Iterator) Saved() bool {
+	return it.saved
+}
+
+// Seek moves the cursor, it is used to start a new cursor from the old checkpoints
+func (it *Iterator) Seek(pos Position) {
+	it.start = pos
+	it.pos = pos
+}
+
+// Settle leaves the rows newer than the given time to the next run, a row saved late with an older time is still scanned
+// as long as it arrives within the settle window
+func (it *Iterator) Settle(until uint64) {
+	it.settle = until
+}
+
+// Position is the last row scanned
+func (it *Iterator) Position() Position {
+	return it.pos
+}
+
+// Next finds the next page of query into dest, a pointer to a slice of structs with Time and Id fields,
+// and returns the number of rows found, 0 at the end of the scan
+func (it *Iterator) Next(query *gorm.DB, dest interface{}) (int, error) {
+	timeColumn, idColumn := columns("")
+	query = query.Where(After("", it.pos))
+	if it.settle > 0 {
+		query = query.Where("? <= ?", timeColumn, it.settle)
+	}
+	err := query.Order(clause.OrderByColumn{Column: timeColumn}).Order(clause.OrderByColumn{Column: idColumn}).
+		Limit(it.batch).Find(dest).Error
+	if err != nil {
+		return 0, err
+	}
+	rows := reflect.Indirect(reflect.ValueOf(dest))
+	if rows.Len() == 0 {
+		return 0, nil
+	}
+	pos, err := position(rows.Index(rows.Len() - 1))
+	if err != nil {
+		return 0, err
+	}
+	it.pos = pos
+	return rows.Len(), nil
+}
+
+// NextRetry finds the next page of query into dest among the rows to retry, like Next. The rows after the start of the cursor
+// are left to Next, the ones not found any more by query are dropped from the retries on Save.
+func (it *Iterator) NextRetry(query *gorm.DB, dest interface{}) (int, error) {
+	for it.retryNext < len(it.retryIds) {
+		end := it.retryNext + it.batch
+		if end > len(it.retryIds) {
+			end = len(it.retryIds)
+		}
+		ids := it.retryIds[it.retryNext:end]
+		it.retryNext = end
+		timeColumn, idColumn := columns("")
+		err := query.Where("? in ?", idColumn, ids).Where(Until("", it.start)).
+			Order(clause.OrderByColumn{Column: timeColumn}).Order(clause.OrderByColumn{Column: idColumn}).Find(dest).Error
+		if err != nil {
+			return 0, err
+		}
+		if n := reflect.Indirect(reflect.ValueOf(dest)).Len(); n > 0 {
+			return n, nil
+		}
+	}
+	return 0, nil
+}
+
+// Retry has the row at pos checked again by the next runs with NextRetry, the cursor still goes past it
+func (it *Iterator) Retry(pos Position) {
+	it.retries[pos.Id] = pos.Time
+}
+
+// Keep has the rows from pos on scanned again by the next run, it is used for the rows within the listening lag
+func (it *Iterator) Keep(pos Position) {
+	pos = pos.before()
+	if it.kept == nil || pos.Less(*it.kept) {
+		it.kept = &pos
+	}
+}
+
+// Save saves the cursor at the last row scanned, or before the first row kept, with the rows to retry
+func (it *Iterator) Save() error {
+	pos := it.pos
+	if it.kept != nil && it.kept.Less(pos) {
+		pos = *it.kept
+	}
+	now := time.Now().Unix()
+	loaded := make(map[int64]bool, len(it.retryIds))
+	dropped := make([]int64, 0)
+	for _, id := range it.retryIds {
+		loaded[id] = true
+		if _, ok := it.retries[id]; !ok {
+			dropped = append(dropped, id)
+		}
+	}
+	scanRetries := make([]*models.ScanRetry, 0)
+	for id, t := range it.retries {
+		if !loaded[id] {
+			scanRetries = append(scanRetries, &models.ScanRetry{Name: it.name, RowId: id, Time: t, UpdateTime: now})
+		}
+	}
+	err := it.db.Transaction(func(tx *gorm.DB) error {
+		scanCursor := &models.ScanCursor{Name: it.name, Time: pos.Time, Id: pos.Id, UpdateTime: now}
+		err := tx.Clauses(clause.OnConflict{
+			Columns:   []clause.Column{{Name: "name"}},
+			DoUpdates: clause.AssignmentColumns([]string{"time", "id", "update_time"}),
+		}).Create(scanCursor).Error
+		if err != nil {
+			return err
+		}
+		for start := 0; start < len(dropped); start += retryBatch {
+			end := start + retryBatch
+			if end > len(dropped) {
+				end = len(dropped)
+			}
+			err = tx.Where("name = ? and row_id in ?", it.name, dropped[start:end]).Delete(&models.ScanRetry{}).Error
+			if err != nil {
+				return err
+			}
+		}
+		if len(scanRetries) > 0 {
+			return tx.Clauses(clause.OnConflict{DoNothing: true}).CreateInBatches(scanRetries, retryBatch).Error
+		}
+		return nil
+	})
+	if err != nil {
+		return fmt.Errorf("save cursor %s err: %w", it.name, err)
+	}
+	it.saved = true
+	return nil
+}
+
+// retryBatch is the number of retries deleted or saved in one statement
+const retryBatch = 500
+
+func position(row reflect.Value) (Position, error) {
+	row = reflect.Indirect(row)
+	if row.Kind() != reflect.Struct {
+		return Position{}, fmt.Errorf("cursor row %s is not a struct", row.Type())
+	}
+	timeField, idField := row.FieldByName("Time"), row.FieldByName("Id")
+	if !timeField.IsValid() || !idField.IsValid() {
+		return Position{}, fmt.Errorf("cursor row %s has no Time or Id", row.Type())
+	}
+	pos := Position{}
+	switch timeField.Kind() {
+	case reflect.Uint64, reflect.Uint32:
+		pos.Time = timeField.Uint()
+	case reflect.Int64, reflect.Int32, reflect.Int:
+		pos.Time = uint64(timeField.Int())
+	default:
+		return Position{}, fmt.Errorf("cursor row %s has an invalid Time", row.Type())
+	}
+	switch idField.Kind() {
+	case reflect.Int64, reflect.Int32, reflect.Int:
+		pos.Id = idField.Int()
+	case reflect.Uint64, reflect.Uint32:
+		pos.Id = int64(idField.Uint())
+	default:
+		return Position{}, fmt.Errorf("cursor row %s has an invalid Id", row.Type())
+	}
+	return pos, nil
+}
@@ -0,0 +1,101 @@
+package cursor
+
+import (
+	"fmt"
+	"poly-bridge/models"
+	"poly-bridge/utils/dbtest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"gorm.io/gorm"
+)
+
+func createSrcTransactions(t *testing.T, db *gorm.DB, times ...uint64) {
+	var count int64
+	db.Model(&models.SrcTransaction{}).Count(&count)
+	for i, tt := range times {
+		srcTransaction := &models.SrcTransaction{
+			Hash: fmt.Sprintf("%064x", count+int64(i)),
+			Key:  fmt.Sprintf("%064x", count+int64(i)),
+			Time: tt,
+			Fee:  models.NewBigIntFromInt(0),
+		}
+		if err := db.Create(srcTransaction).Error; err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+func scan(t *testing.T, it *Iterator, db *gorm.DB) []uint64 {
+	times := make([]uint64, 0)
+	for {
+		srcTransactions := make([]*models.SrcTransaction, 0)
+		n, err := it.Next(db.Model(&models.SrcTransaction{}), &srcTransactions)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if n == 0 {
+			return times
+		}
+		for _, srcTransaction := range srcTransactions {
+			times = append(times, srcTransaction.Time)
+		}
+	}
+}
+
+func TestIterator(t *testing.T) {
+	db := dbtest.Open(t, &models.ScanCursor{}, &models.ScanRetry{}, &models.SrcTransaction{})
+	createSrcTransactions(t, db, 30, 10, 20, 20, 50)
+
+	it, err := NewIterator(db, "test", 2)
+	assert.NoError(t, err)
+	assert.False(t, it.Saved())
+	assert.Equal(t, []uint64{10, 20, 20, 30, 50}, scan(t, it, db))
+	assert.Equal(t, Position{Time: 50, Id: 5}, it.Position())
+	it.Keep(Position{Time: 30, Id: 1})
+	assert.NoError(t, it.Save())
+
+	// the kept row is scanned again, the rows saved late within the settle window are not skipped
+	createSrcTransactions(t, db, 40, 60, 90)
+	it, err = NewIterator(db, "test", 2)
+	assert.NoError(t, err)
+	assert.True(t, it.Saved())
+	it.Settle(60)
+	assert.Equal(t, []uint64{30, 40, 50, 60}, scan(t, it, db))
+	assert.NoError(t, it.Save())
+
+	it, err = NewIterator(db, "test", 2)
+	assert.NoError(t, err)
+	assert.Equal(t, []uint64{90}, scan(t, it, db))
+}
+
+func TestRetry(t *testing.T) {
+	db := dbtest.Open(t, &models.ScanCursor{}, &models.ScanRetry{}, &models.SrcTransaction{})
+	createSrcTransactions(t, db, 10, 20, 30)
+
+	// the cursor goes past the row retried
+	it, err := NewIterator(db, "test", 2)
+	assert.NoError(t, err)
+	assert.Equal(t, []uint64{10, 20, 30}, scan(t, it, db))
+	it.Retry(Position{Time: 20, Id: 2})
+	assert.NoError(t, it.Save())
+
+	createSrcTransactions(t, db, 40)
+	it, err = NewIterator(db, "test", 2)
+	assert.NoError(t, err)
+	retried := make([]*models.SrcTransaction, 0)
+	n, err := it.NextRetry(db.Model(&models.SrcTransaction{}), &retried)
+	assert.NoError(t, err)
+	assert.Equal(t, 1, n)
+	assert.Equal(t, uint64(20), retried[0].Time)
+	n, err = it.NextRetry(db.Model(&models.SrcTransaction{}), &retried)
+	assert.NoError(t, err)
+	assert.Equal(t, 0, n)
+	assert.Equal(t, []uint64{40}, scan(t, it, db))
+	assert.NoError(t, it.Save())
+
+	// the row not retried again is dropped
+	var count int64
+	assert.NoError(t, db.Model(&models.ScanRetry{}).Where("name = ?", "test").Count(&count).Error)
+	assert.Equal(t, int64(0), count)
+}
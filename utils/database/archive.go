@@ -0,0 +1,67 @@
+package database
+
+import (
+	"database/sql"
+	"fmt"
+	"sync"
+
+	"gorm.io/driver/mysql"
+	"gorm.io/driver/postgres"
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+	"gorm.io/gorm/schema"
+)
+
+// ArchivePrefix is the table prefix of the archived transactions, archived_src_transactions keeps the rows of src_transactions
+const ArchivePrefix = "archived_"
+
+// archives are the archive handles by the connection pool of the main db, they are opened once and share the pool
+var archives sync.Map
+
+// Archive returns db on the archive tables, the models are mapped to their archived_ tables, the preloads included.
+// It shares the connection, or the transaction, of db, the reads of the archive are not routed to the replicas.
+func Archive(db *gorm.DB) (*gorm.DB, error) {
+	archived, ok := archives.Load(db.Config.ConnPool)
+	if !ok {
+		opened, err := openArchive(db)
+		if err != nil {
+			return nil, err
+		}
+		archived, _ = archives.LoadOrStore(db.Config.ConnPool, opened)
+	}
+	session := archived.(*gorm.DB).WithContext(db.Statement.Context)
+	session.Statement.ConnPool = db.Statement.ConnPool
+	return session, nil
+}
+
+// openArchive opens the archive handle on the pool of db without connecting again
+func openArchive(db *gorm.DB) (*gorm.DB, error) {
+	pool := db.Config.ConnPool
+	if stmtDB, ok := pool.(*gorm.PreparedStmtDB); ok {
+		pool = stmtDB.ConnPool
+	}
+	var dialector gorm.Dialector
+	switch origin := db.Dialector.(type) {
+	case *mysql.Dialector:
+		config := *origin.Config
+		config.Conn = pool
+		config.SkipInitializeWithVersion = true
+		dialector = mysql.New(config)
+	case *postgres.Dialector:
+		sqlDB, ok := pool.(*sql.DB)
+		if !ok {
+			return nil, fmt.Errorf("unsupported db pool %T of the archive", pool)
+		}
+		config := *origin.Config
+		config.Conn = sqlDB
+		dialector = postgres.New(config)
+	case *sqlite.Dialector:
+		dialector = &sqlite.Dialector{DriverName: origin.DriverName, DSN: origin.DSN, Conn: pool}
+	default:
+		return nil, fmt.Errorf("unsupported db dialect %s", db.Dialector.Name())
+	}
+	return gorm.Open(dialector, &gorm.Config{
+		Logger:         db.Logger,
+		NamingStrategy: schema.NamingStrategy{TablePrefix: ArchivePrefix},
+	})
+}
@@ -0,0 +1,68 @@
+package database
+
+import (
+	"fmt"
+	"net/url"
+	"poly-bridge/basedef"
+	"poly-bridge/conf"
+
+	"gorm.io/driver/mysql"
+	"gorm.io/driver/postgres"
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+)
+
+// Dialector returns the gorm dialector of dbCfg.Dialect, mysql is used if it is empty
+func Dialector(dbCfg *conf.DBConfig) (gorm.Dialector, error) {
+	switch dbCfg.Dialect {
+	case "", basedef.DIALECT_MYSQL:
+		return mysql.Open(dbCfg.User + ":" + dbCfg.Password + "@tcp(" + dbCfg.URL + ")/" + dbCfg.Scheme + "?charset=utf8"), nil
+	case basedef.DIALECT_POSTGRES:
+		sslMode := dbCfg.SSLMode
+		if sslMode == "" {
+			sslMode = "disable"
+		}
+		dsn := url.URL{
+			Scheme:   "postgres",
+			User:     url.UserPassword(dbCfg.User, dbCfg.Password),
+			Host:     dbCfg.URL,
+			Path:     "/" + dbCfg.Scheme,
+			RawQuery: "sslmode=" + sslMode,
+		}
+		return postgres.Open(dsn.String()), nil
+	case basedef.DIALECT_SQLITE:
+		return sqlite.Open(dbCfg.Scheme), nil
+	default:
+		return nil, fmt.Errorf("unsupported db dialect %s", dbCfg.Dialect)
+	}
+}
+
+// Open connects to the database of dbCfg, the sql statements are logged if dbCfg.Debug is set
+func Open(dbCfg *conf.DBConfig) (*gorm.DB, error) {
+	Logger := logger.Default
+	if dbCfg.Debug == true {
+		Logger = Logger.LogMode(logger.Info)
+	}
+	return OpenWithLogger(dbCfg, Logger)
+}
+
+func OpenWithLogger(dbCfg *conf.DBConfig, Logger logger.Interface) (*gorm.DB, error) {
+	dialector, err := Dialector(dbCfg)
+	if err != nil {
+		return nil, err
+	}
+	return gorm.Open(dialector, &gorm.Config{Logger: Logger})
+}
+
+// SumAmount returns the sql summing the integer amounts of a varchar column, mysql and postgres sum them as decimals
+func SumAmount(db *gorm.DB, column string) string {
+	switch db.Dialector.Name() {
+	case basedef.DIALECT_POSTGRES:
+		return fmt.Sprintf("sum(cast(%s as numeric))", column)
+	case basedef.DIALECT_SQLITE:
+		return fmt.Sprintf("sum(%s)", column)
+	default:
+		return fmt.Sprintf("CONVERT(sum(%s), DECIMAL(37, 0))", column)
+	}
+}
@@ -0,0 +1,155 @@
+package database
+
+import (
+	"path/filepath"
+	"poly-bridge/basedef"
+	"poly-bridge/conf"
+	"poly-bridge/models"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"gorm.io/driver/postgres"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+	"gorm.io/gorm/logger"
+)
+
+func TestDialector(t *testing.T) {
+	dbCfg := &conf.DBConfig{URL: "127.0.0.1:3306", User: "root", Password: "pwd", Scheme: "polyswap"}
+	dialector, err := Dialector(dbCfg)
+	assert.NoError(t, err)
+	assert.Equal(t, basedef.DIALECT_MYSQL, dialector.Name())
+
+	dbCfg.Dialect = basedef.DIALECT_POSTGRES
+	dialector, err = Dialector(dbCfg)
+	assert.NoError(t, err)
+	assert.Equal(t, basedef.DIALECT_POSTGRES, dialector.Name())
+	assert.Contains(t, dialector.(*postgres.Dialector).DSN, "sslmode=disable")
+	dbCfg.SSLMode = "verify-full"
+	dialector, err = Dialector(dbCfg)
+	assert.NoError(t, err)
+	assert.Contains(t, dialector.(*postgres.Dialector).DSN, "sslmode=verify-full")
+
+	dbCfg.Dialect = "oracle"
+	_, err = Dialector(dbCfg)
+	assert.Error(t, err)
+}
+
+func TestOpenSqlite(t *testing.T) {
+	dbCfg := &conf.DBConfig{Dialect: basedef.DIALECT_SQLITE, Scheme: filepath.Join(t.TempDir(), "polyswap.db")}
+	db, err := Open(dbCfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = db.AutoMigrate(
+		&models.ChainFee{},
+		&models.Chain{},
+		&models.DstTransaction{},
+		&models.DstTransfer{},
+		&models.PolyTransaction{},
+		&models.SrcTransaction{},
+		&models.SrcTransfer{},
+		&models.TokenBasic{},
+		&models.TokenMap{},
+		&models.Token{},
+		&models.TokenStatistic{},
+		&models.WrapperTransaction{},
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	srcTransaction := &models.SrcTransaction{
+		Hash:    "0000000000000000000000000000000000000000000000000000000000000e3c",
+		ChainId: basedef.ETHEREUM_CROSSCHAIN_ID,
+		Fee:     models.NewBigIntFromInt(0),
+		Key:     "0000000000000000000000000000000000000000000000000000000000000e3d",
+		SrcTransfer: &models.SrcTransfer{
+			TxHash:  "0000000000000000000000000000000000000000000000000000000000000e3c",
+			ChainId: basedef.ETHEREUM_CROSSCHAIN_ID,
+			From:    "5cd3143f91a13fe971043e1e4605c1c23b46bf44",
+			Amount:  models.NewBigIntFromInt(100),
+		},
+	}
+	assert.NoError(t, db.Create(srcTransaction).Error)
+
+	srcTransactions := make([]*models.SrcTransaction, 0)
+	err = db.Where("(? in ? or hash in ?)", clause.Column{Name: "key"}, []string{srcTransaction.Key}, []string{srcTransaction.Key}).
+		Find(&srcTransactions).Error
+	assert.NoError(t, err)
+	assert.Len(t, srcTransactions, 1)
+
+	var transferNum int64
+	err = db.Model(&models.SrcTransfer{}).
+		Where("? in ?", clause.Column{Table: "src_transfers", Name: "from"}, []string{srcTransaction.SrcTransfer.From}).
+		Count(&transferNum).Error
+	assert.NoError(t, err)
+	assert.Equal(t, int64(1), transferNum)
+
+	var sum struct {
+		Amount *models.BigInt
+	}
+	assert.NoError(t, db.Model(&models.SrcTransfer{}).Select(SumAmount(db, "amount")+" as amount").Scan(&sum).Error)
+	assert.Equal(t, int64(100), sum.Amount.Int64())
+}
+
+func TestOpenWithReplicas(t *testing.T) {
+	dir := t.TempDir()
+	dbCfg := &conf.DBConfig{
+		Dialect:  basedef.DIALECT_SQLITE,
+		Scheme:   filepath.Join(dir, "polyswap.db"),
+		Replicas: []string{filepath.Join(dir, "replica.db")},
+	}
+	replica, err := Open(&conf.DBConfig{Dialect: basedef.DIALECT_SQLITE, Scheme: dbCfg.Replicas[0]})
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.NoError(t, replica.AutoMigrate(&models.Chain{}))
+	assert.NoError(t, replica.Create(&models.Chain{ChainId: basedef.BSC_CROSSCHAIN_ID, Name: "replica"}).Error)
+
+	db, err := OpenWithReplicas(dbCfg, logger.Default)
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.NoError(t, Primary(db).AutoMigrate(&models.Chain{}))
+	assert.NoError(t, db.Create(&models.Chain{ChainId: basedef.ETHEREUM_CROSSCHAIN_ID, Name: "primary"}).Error)
+
+	chain := new(models.Chain)
+	assert.NoError(t, db.First(chain).Error)
+	assert.Equal(t, "replica", chain.Name)
+	var name string
+	assert.NoError(t, db.Raw("select name from chains").Scan(&name).Error)
+	assert.Equal(t, "replica", name)
+
+	chain = new(models.Chain)
+	assert.NoError(t, Primary(db).First(chain).Error)
+	assert.Equal(t, "primary", chain.Name)
+	assert.NoError(t, db.Transaction(func(tx *gorm.DB) error {
+		chain = new(models.Chain)
+		return tx.First(chain).Error
+	}))
+	assert.Equal(t, "primary", chain.Name)
+
+	// a query on the same statement does not send the write to the replica
+	query := db.Model(&models.Chain{}).Where("chain_id = ?", basedef.ETHEREUM_CROSSCHAIN_ID)
+	var count int64
+	assert.NoError(t, query.Count(&count).Error)
+	assert.Equal(t, int64(0), count)
+	assert.NoError(t, query.Update("name", "updated").Error)
+	chain = new(models.Chain)
+	assert.NoError(t, Primary(db).First(chain).Error)
+	assert.Equal(t, "updated", chain.Name)
+
+	// the reads fall back to the primary while the replica is unreachable
+	sqlDB, err := replica.DB()
+	assert.NoError(t, err)
+	resolver := db.Config.Plugins["database:replicas"].(*Resolver)
+	replicaDB, err := resolver.replicas[0].db.DB()
+	assert.NoError(t, err)
+	assert.NoError(t, replicaDB.Close())
+	assert.NoError(t, sqlDB.Close())
+	resolver.Check()
+	chain = new(models.Chain)
+	assert.NoError(t, db.First(chain).Error)
+	assert.Equal(t, "updated", chain.Name)
+}
@@ -0,0 +1,257 @@
+package database
+
+import (
+	"database/sql"
+	"fmt"
+	"poly-bridge/basedef"
+	"poly-bridge/conf"
+	"strconv"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/beego/beego/v2/core/logs"
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+)
+
+const (
+	primaryKey       = "database:primary"
+	replicaLagSlot   = 10 * time.Second
+	defaultMaxLagSec = 30
+)
+
+// Primary pins the queries of db to the primary, for the reads which must see the writes just made
+func Primary(db *gorm.DB) *gorm.DB {
+	return db.Set(primaryKey, true).Session(&gorm.Session{})
+}
+
+// OpenWithReplicas opens the primary of dbCfg and routes the reads to its read replicas,
+// the writes, the transactions and the queries pinned by Primary go to the primary, so do the schema changes run on Primary(db)
+func OpenWithReplicas(dbCfg *conf.DBConfig, Logger logger.Interface) (*gorm.DB, error) {
+	db, err := OpenWithLogger(dbCfg, Logger)
+	if err != nil || len(dbCfg.Replicas) == 0 {
+		return db, err
+	}
+	resolver, err := NewResolver(dbCfg, Logger)
+	if err != nil {
+		return nil, err
+	}
+	err = db.Use(resolver)
+	if err != nil {
+		return nil, err
+	}
+	go resolver.monitor()
+	return db, nil
+}
+
+// Resolver is a gorm plugin which sends the queries to the replicas in turn,
+// a replica is skipped while it is unreachable or lags behind the primary over the max lag
+type Resolver struct {
+	dialect  string
+	maxLag   int64
+	primary  gorm.ConnPool
+	replicas []*replica
+	next     uint32
+}
+
+type replica struct {
+	url     string
+	db      *gorm.DB
+	pool    gorm.ConnPool
+	healthy int32
+	lag     int64
+}
+
+// pinnedPool marks the statements pinned to the primary, so that their preloads are not routed again
+type pinnedPool struct {
+	gorm.ConnPool
+}
+
+func NewResolver(dbCfg *conf.DBConfig, Logger logger.Interface) (*Resolver, error) {
+	resolver := &Resolver{dialect: dbCfg.Dialect, maxLag: dbCfg.MaxReplicaLag}
+	if resolver.maxLag <= 0 {
+		resolver.maxLag = defaultMaxLagSec
+	}
+	for _, url := range dbCfg.Replicas {
+		replicaCfg := *dbCfg
+		replicaCfg.Replicas = nil
+		if dbCfg.Dialect == basedef.DIALECT_SQLITE {
+			replicaCfg.Scheme = url
+		} else {
+			replicaCfg.URL = url
+		}
+		db, err := OpenWithLogger(&replicaCfg, Logger)
+		if err != nil {
+			return nil, fmt.Errorf("open replica %s err: %w", url, err)
+		}
+		resolver.replicas = append(resolver.replicas, &replica{url: url, db: db, pool: db.ConnPool})
+	}
+	resolver.Check()
+	return resolver, nil
+}
+
+func (r *Resolver) Name() string {
+	return "database:replicas"
+}
+
+func (r *Resolver) Initialize(db *gorm.DB) error {
+	r.primary = db.ConnPool
+	err := db.Callback().Query().Before("gorm:query").Register("database:route_query", r.route)
+	if err != nil {
+		return err
+	}
+	err = db.Callback().Row().Before("gorm:row").Register("database:route_row", r.route)
+	if err != nil {
+		return err
+	}
+	err = db.Callback().Create().Before("gorm:begin_transaction").Register("database:restore_create", r.restore)
+	if err != nil {
+		return err
+	}
+	err = db.Callback().Update().Before("gorm:begin_transaction").Register("database:restore_update", r.restore)
+	if err != nil {
+		return err
+	}
+	err = db.Callback().Delete().Before("gorm:begin_transaction").Register("database:restore_delete", r.restore)
+	if err != nil {
+		return err
+	}
+	return db.Callback().Raw().Before("gorm:raw").Register("database:restore_raw", r.restore)
+}
+
+// route picks a healthy replica for the queries on the primary pool, the transactions keep their own connections
+func (r *Resolver) route(db *gorm.DB) {
+	if db.Statement.ConnPool != r.primary {
+		return
+	}
+	if pinned, ok := db.Get(primaryKey); ok && pinned == true {
+		db.Statement.ConnPool = &pinnedPool{ConnPool: r.primary}
+		return
+	}
+	if replica := r.pick(); replica != nil {
+		db.Statement.ConnPool = replica.pool
+	}
+}
+
+// restore sends the writes of a statement which has been routed by a query back to the primary
+func (r *Resolver) restore(db *gorm.DB) {
+	if _, ok := db.Statement.ConnPool.(*pinnedPool); ok {
+		db.Statement.ConnPool = r.primary
+		return
+	}
+	for _, replica := range r.replicas {
+		if db.Statement.ConnPool == replica.pool {
+			db.Statement.ConnPool = r.primary
+			return
+		}
+	}
+}
+
+func (r *Resolver) pick() *replica {
+	n := uint32(len(r.replicas))
+	start := atomic.AddUint32(&r.next, 1)
+	for i := uint32(0); i < n; i++ {
+		replica := r.replicas[(start+i)%n]
+		if atomic.LoadInt32(&replica.healthy) == 1 {
+			return replica
+		}
+	}
+	return nil
+}
+
+// Check measures the lag of the replicas, a replica is taken out or back in as its lag crosses the max lag
+func (r *Resolver) Check() {
+	wg := sync.WaitGroup{}
+	for _, rep := range r.replicas {
+		wg.Add(1)
+		go func(rep *replica) {
+			defer wg.Done()
+			lag, err := r.replicaLag(rep.db)
+			healthy := err == nil && lag <= r.maxLag
+			atomic.StoreInt64(&rep.lag, lag)
+			was := atomic.SwapInt32(&rep.healthy, boolToInt32(healthy)) == 1
+			if err != nil {
+				logs.Error("db replica %s check err: %s", rep.url, err)
+			} else {
+				logs.Debug("db replica %s lag %d seconds", rep.url, lag)
+			}
+			if was && !healthy {
+				logs.Warn("db replica %s is skipped, lag %d seconds, max %d", rep.url, lag, r.maxLag)
+			} else if !was && healthy {
+				logs.Info("db replica %s is back, lag %d seconds", rep.url, lag)
+			}
+		}(rep)
+	}
+	wg.Wait()
+	if r.pick() == nil {
+		logs.Warn("no db replica is available, the reads go to the primary")
+	}
+}
+
+func (r *Resolver) monitor() {
+	ticker := time.NewTicker(replicaLagSlot)
+	defer ticker.Stop()
+	for range ticker.C {
+		r.Check()
+	}
+}
+
+func (r *Resolver) replicaLag(db *gorm.DB) (int64, error) {
+	switch r.dialect {
+	case "", basedef.DIALECT_MYSQL:
+		return mysqlReplicaLag(db)
+	case basedef.DIALECT_POSTGRES:
+		var lag float64
+		err := db.Raw("select coalesce(extract(epoch from now() - pg_last_xact_replay_timestamp()), 0)").Row().Scan(&lag)
+		return int64(lag), err
+	default:
+		sqlDB, err := db.DB()
+		if err != nil {
+			return 0, err
+		}
+		return 0, sqlDB.Ping()
+	}
+}
+
+// mysqlReplicaLag reads Seconds_Behind_Master, which is NULL when the replication is stopped
+func mysqlReplicaLag(db *gorm.DB) (int64, error) {
+	rows, err := db.Raw("SHOW SLAVE STATUS").Rows()
+	if err != nil {
+		return 0, err
+	}
+	defer rows.Close()
+	columns, err := rows.Columns()
+	if err != nil {
+		return 0, err
+	}
+	if !rows.Next() {
+		return 0, fmt.Errorf("it is not a replica")
+	}
+	values := make([]sql.RawBytes, len(columns))
+	dest := make([]interface{}, len(columns))
+	for i := range values {
+		dest[i] = &values[i]
+	}
+	err = rows.Scan(dest...)
+	if err != nil {
+		return 0, err
+	}
+	for i, column := range columns {
+		if column != "Seconds_Behind_Master" {
+			continue
+		}
+		if values[i] == nil {
+			return 0, fmt.Errorf("replication is stopped")
+		}
+		return strconv.ParseInt(string(values[i]), 10, 64)
+	}
+	return 0, fmt.Errorf("no Seconds_Behind_Master in the slave status")
+}
+
+func boolToInt32(v bool) int32 {
+	if v {
+		return 1
+	}
+	return 0
+}
@@ -0,0 +1,36 @@
+// Package dbtest opens the sqlite dbs of the tests, each in the temp dir of its test
+package dbtest
+
+import (
+	"path/filepath"
+	"poly-bridge/basedef"
+	"poly-bridge/conf"
+	"poly-bridge/utils/database"
+	"testing"
+
+	"gorm.io/gorm"
+)
+
+// Config is the config of a new sqlite db file name in the temp dir of t, for the daos opened by config
+func Config(t *testing.T, name string) *conf.DBConfig {
+	return &conf.DBConfig{Dialect: basedef.DIALECT_SQLITE, Scheme: filepath.Join(t.TempDir(), name)}
+}
+
+// Open opens a new sqlite db of t and migrates the tables of models
+func Open(t *testing.T, models ...interface{}) *gorm.DB {
+	return OpenNamed(t, "polyswap.db", models...)
+}
+
+// OpenNamed opens the sqlite db file name of t, the tests with several dbs give them different names
+func OpenNamed(t *testing.T, name string, models ...interface{}) *gorm.DB {
+	db, err := database.Open(Config(t, name))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(models) > 0 {
+		if err := db.AutoMigrate(models...); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return db
+}
@@ -1,56 +1,35 @@
 package fee
 
 import (
-	"fmt"
 	"github.com/beego/beego/v2/core/logs"
 	"math/big"
 	"poly-bridge/basedef"
 	"poly-bridge/conf"
 	"poly-bridge/models"
+	"poly-bridge/utils/decimal"
+	"strings"
 )
 
-func GetL1Fee(ethChainFee *models.ChainFee, chainId uint64) (l1MinFee, l1ProxyFee, l1FeeAmount *big.Float, err error) {
-	var targetFeeListenConfig, ethFeeListenConfig *conf.FeeListenConfig
-	for _, fl := range conf.GlobalConfig.FeeListenConfig {
-		if fl.ChainId == chainId {
-			targetFeeListenConfig = fl
-			break
-		}
-		continue
+// ApplySafetyMargin raises the fee by the safety margin of the chain if the chain fee is priced from the predicted
+// EIP-1559 max fee per gas, which may still rise before the transaction is packed
+func ApplySafetyMargin(chainFee *models.ChainFee, fee *big.Float) *big.Float {
+	if chainFee.MaxFeePerGas == nil || chainFee.MaxFeePerGas.Sign() <= 0 {
+		return fee
 	}
-	for _, fl := range conf.GlobalConfig.FeeListenConfig {
-		if fl.ChainId == basedef.ETHEREUM_CROSSCHAIN_ID {
-			ethFeeListenConfig = fl
-			break
-		}
-		continue
+	if conf.GlobalConfig == nil {
+		return fee
 	}
-
-	if targetFeeListenConfig == nil || ethFeeListenConfig == nil {
-		err := fmt.Errorf("chain listen config is missing")
-		logs.Error("getOptimisticL1FeeMin error: %v", err)
-		return nil, nil, nil, err
+	cfg := conf.GlobalConfig.GetFeeListenConfig(chainFee.ChainId)
+	if cfg == nil || cfg.SafetyMargin <= 0 {
+		return fee
 	}
-
-	gasLimitScale := new(big.Float).Quo(new(big.Float).SetInt64(targetFeeListenConfig.EthL1GasLimit), new(big.Float).SetInt64(ethFeeListenConfig.GasLimit))
-	price := new(big.Float).SetInt64(ethChainFee.TokenBasic.Price)
-	precisionFactor := new(big.Float).Mul(new(big.Float).SetInt64(basedef.PRICE_PRECISION), new(big.Float).SetInt64(basedef.FEE_PRECISION))
-	precisionFactor = new(big.Float).Mul(precisionFactor, new(big.Float).SetInt64(basedef.Int64FromFigure(int(ethChainFee.TokenBasic.Precision))))
-
-	feeFactor := new(big.Float).Mul(gasLimitScale, price)
-	feeFactor = new(big.Float).Quo(feeFactor, precisionFactor)
-
-	l1MinFee = new(big.Float).Mul(new(big.Float).SetInt(&ethChainFee.MinFee.Int), feeFactor)
-	l1ProxyFee = new(big.Float).Mul(new(big.Float).SetInt(&ethChainFee.ProxyFee.Int), feeFactor)
-
-	l1FeeAmount = new(big.Float).Mul(new(big.Float).SetInt(&ethChainFee.MaxFee.Int), gasLimitScale)
-	l1FeeAmount = l1FeeAmount.Quo(l1FeeAmount, new(big.Float).SetInt64(basedef.FEE_PRECISION))
-	l1FeeAmount = l1FeeAmount.Quo(l1FeeAmount, new(big.Float).SetInt64(basedef.Int64FromFigure(int(ethChainFee.TokenBasic.Precision))))
-	logs.Info("chain:%d l1MinFee=%s, l1ProxyFee=%s, l1FeeAmount=%s", chainId, l1MinFee.String(), l1ProxyFee.String(), l1FeeAmount.String())
-	return
+	fee = new(big.Float).Mul(fee, new(big.Float).SetInt64(100+cfg.SafetyMargin))
+	return new(big.Float).Quo(fee, new(big.Float).SetInt64(100))
 }
 
-func CheckFeeCal(chainFee *models.ChainFee, feeToken *models.Token, feeAmount *models.BigInt) (feePay, feeMin, gasPay *big.Float) {
+// CheckFeeCal returns the fee paid and the min fee of the destination chain in usd, the L1 data fee of the destination
+// chain included, and the gas paid in the fee token of the destination chain
+func CheckFeeCal(chainFee *models.ChainFee, feeToken *models.Token, feeAmount *models.BigInt, getChainFee ChainFeeGetter) (feePay, feeMin, gasPay *big.Float, err error) {
 	x := new(big.Int).Mul(&feeAmount.Int, big.NewInt(feeToken.TokenBasic.Price))
 	feePay = new(big.Float).Quo(new(big.Float).SetInt(x), new(big.Float).SetInt64(basedef.Int64FromFigure(int(feeToken.Precision))))
 	gasPay = feePay
@@ -59,8 +38,65 @@ func CheckFeeCal(chainFee *models.ChainFee, feeToken *models.Token, feeAmount *m
 	feeMin = new(big.Float).Quo(new(big.Float).SetInt(x), new(big.Float).SetInt64(basedef.PRICE_PRECISION))
 	feeMin = new(big.Float).Quo(feeMin, new(big.Float).SetInt64(basedef.FEE_PRECISION))
 	feeMin = new(big.Float).Quo(feeMin, new(big.Float).SetInt64(basedef.Int64FromFigure(int(chainFee.TokenBasic.Precision))))
+	l1MinFee, _, _, err := GetL1Fee(chainFee, getChainFee)
+	if err != nil {
+		return nil, nil, nil, err
+	}
+	feeMin = new(big.Float).Add(feeMin, l1MinFee)
 
 	gasPay = new(big.Float).Quo(gasPay, new(big.Float).SetInt64(chainFee.TokenBasic.Price))
 	gasPay = new(big.Float).Mul(gasPay, new(big.Float).SetInt64(basedef.Int64FromFigure(int(chainFee.TokenBasic.Precision))))
 	return
 }
+
+// CheckWrapperFee sets IsPaid and PaidGas of the wrapper transaction by comparing the fee paid in the wrapper
+// with the min fee of the destination chain, chain2Fees holds the chain fees with TokenBasic loaded.
+func CheckWrapperFee(wrapper *models.WrapperTransaction, srcTransaction *models.SrcTransaction, token *models.Token, chain2Fees map[uint64]*models.ChainFee) {
+	if token == nil || token.TokenBasic == nil {
+		wrapper.IsPaid = false
+		logs.Info("check fee wrapper_hash %s NOT_PAID,feeToken %s hasn't price", wrapper.Hash, wrapper.FeeTokenHash)
+		return
+	}
+	var feePayFloat64, feeMinFloat64, PaidGasFloat64 float64
+	chainFee, ok := chain2Fees[wrapper.DstChainId]
+	if !ok {
+		wrapper.IsPaid = false
+		logs.Info("check fee Wrapper_hash %s NOT_PAID,chainFee hasn't DstChainId's fee", wrapper.Hash)
+		return
+	}
+	//money paid in wrapper
+	feePay, feeMin, gasPay, err := CheckFeeCal(chainFee, token, wrapper.FeeAmount, ChainFeesGetter(chain2Fees))
+	if err != nil {
+		wrapper.IsPaid = false
+		logs.Info("check fee wrapper_hash %s NOT_PAID, get L1 fee failed. err=%v", wrapper.Hash, err)
+		return
+	}
+
+	if _, in := conf.EstimateProxy[strings.ToUpper(srcTransaction.Contract)]; in {
+		//is estimateGas proxy
+		if gasPay.Cmp(new(big.Float).SetInt64(0)) <= 0 {
+			wrapper.IsPaid = false
+			return
+		}
+		if minFee, in := conf.EstimateFeeMin[wrapper.DstChainId]; in {
+			if minFee > 0 && minFee < 100 {
+				gasPay = new(big.Float).Mul(gasPay, new(big.Float).SetInt64(100))
+				gasPay = new(big.Float).Quo(gasPay, new(big.Float).SetInt64(minFee))
+			}
+		}
+		PaidGasFloat64, _ = gasPay.Float64()
+		PaidGas := decimal.NewFromFloat(PaidGasFloat64).Mul(decimal.NewFromInt(100))
+		wrapper.PaidGas = models.NewBigInt(PaidGas.BigInt())
+		logs.Info("check fee wrapper_hash %s is EstimateProxy,PaidGas %v", wrapper.Hash, wrapper.PaidGas)
+		return
+	}
+	feeMinFloat64, _ = feeMin.Float64()
+	feePayFloat64, _ = feePay.Float64()
+	if feePay.Cmp(feeMin) >= 0 {
+		wrapper.IsPaid = true
+		logs.Info("check fee wrapper_hash %s PAID,feePay %v >= feeMin %v", wrapper.Hash, feePayFloat64, feeMinFloat64)
+	} else {
+		wrapper.IsPaid = false
+		logs.Info("check fee wrapper_hash %s NOT_PAID,feePay %v < feeMin %v", wrapper.Hash, feePayFloat64, feeMinFloat64)
+	}
+}
@@ -0,0 +1,21 @@
+package fee
+
+import (
+	"math/big"
+	"poly-bridge/conf"
+	"poly-bridge/models"
+	"testing"
+)
+
+func TestApplySafetyMargin(t *testing.T) {
+	chainFee := &models.ChainFee{ChainId: 1, MaxFeePerGas: models.NewBigIntFromInt(100)}
+	globalConfig := conf.GlobalConfig
+	conf.GlobalConfig = nil
+	assertFloat(t, 10, ApplySafetyMargin(chainFee, big.NewFloat(10)))
+	conf.GlobalConfig = globalConfig
+
+	setFeeListenConfigs(t, &conf.FeeListenConfig{ChainId: 1, SafetyMargin: 20})
+	assertFloat(t, 12, ApplySafetyMargin(chainFee, big.NewFloat(10)))
+	chainFee.MaxFeePerGas = nil
+	assertFloat(t, 10, ApplySafetyMargin(chainFee, big.NewFloat(10)))
+}
@@ -0,0 +1,261 @@
+package fee
+
+import (
+	"context"
+	"fmt"
+	"math/big"
+	"poly-bridge/basedef"
+	"poly-bridge/conf"
+	"poly-bridge/models"
+	"sync"
+
+	"github.com/beego/beego/v2/core/logs"
+	"github.com/ethereum/go-ethereum"
+	"github.com/ethereum/go-ethereum/accounts/abi"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/crypto"
+)
+
+const (
+	L1_FEE_STATIC   = "static"
+	L1_FEE_OPTIMISM = "optimism"
+	L1_FEE_ARBITRUM = "arbitrum"
+	L1_FEE_ZKSYNC   = "zksync"
+
+	// defaultL1CalldataSize is the calldata bytes of a cross chain tx the oracle strategies price if L1CalldataSize is not set
+	defaultL1CalldataSize = 1024
+)
+
+var (
+	// optimismGasPriceOracle is the gas price oracle predeploy of Optimism and its forks, such as Metis and Boba
+	optimismGasPriceOracle = common.HexToAddress("0x420000000000000000000000000000000000000F")
+	// arbGasInfo is the ArbGasInfo precompile of Arbitrum
+	arbGasInfo = common.HexToAddress("0x000000000000000000000000000000000000006C")
+)
+
+// ChainFeeGetter returns the chain fee of a chain with TokenBasic loaded
+type ChainFeeGetter func(chainId uint64) (*models.ChainFee, bool)
+
+// ChainFeesGetter looks the chain fees up in chain2Fees
+func ChainFeesGetter(chain2Fees map[uint64]*models.ChainFee) ChainFeeGetter {
+	return func(chainId uint64) (*models.ChainFee, bool) {
+		chainFee, ok := chain2Fees[chainId]
+		return chainFee, ok
+	}
+}
+
+// ContractCaller is the eth_call of a chain node, ethclient.Client implements it
+type ContractCaller interface {
+	CallContract(ctx context.Context, call ethereum.CallMsg, blockNumber *big.Int) ([]byte, error)
+}
+
+// L1FeeStrategy prices the L1 data cost of a cross chain tx landing on a rollup
+type L1FeeStrategy interface {
+	Name() string
+	// L1DataFee measures the L1 data fee of a cross chain tx on the chain, in the smallest unit of the chain fee token.
+	// The fee listener saves it as the L1DataFee of the chain fee, nil if the strategy does not read the chain.
+	L1DataFee(caller ContractCaller, cfg *conf.FeeListenConfig) (*big.Int, error)
+	// L1Fee returns the L1 data fee in usd at the min fee and proxy fee rates of the chain, and l1FeeAmount in the chain fee token
+	L1Fee(chainFee *models.ChainFee, cfg *conf.FeeListenConfig, getChainFee ChainFeeGetter) (l1MinFee, l1ProxyFee, l1FeeAmount *big.Float, err error)
+}
+
+var (
+	l1FeeStrategies = map[string]L1FeeStrategy{
+		L1_FEE_STATIC:   staticL1Fee{},
+		L1_FEE_OPTIMISM: optimismL1Fee{},
+		L1_FEE_ARBITRUM: arbitrumL1Fee{},
+		L1_FEE_ZKSYNC:   zkSyncL1Fee{},
+	}
+	l1FeeStrategiesLock sync.RWMutex
+)
+
+// RegisterL1FeeStrategy registers a strategy selected by the L1FeeStrategy of the fee listen configs
+func RegisterL1FeeStrategy(strategy L1FeeStrategy) {
+	l1FeeStrategiesLock.Lock()
+	defer l1FeeStrategiesLock.Unlock()
+	l1FeeStrategies[strategy.Name()] = strategy
+}
+
+// GetL1FeeStrategy returns the strategy configured for the chain, nil if the chain has no L1 data fee.
+// A chain with EthL1GasLimit but without L1FeeStrategy uses the static ratio.
+func GetL1FeeStrategy(chainId uint64) (L1FeeStrategy, *conf.FeeListenConfig) {
+	if conf.GlobalConfig == nil {
+		return nil, nil
+	}
+	cfg := conf.GlobalConfig.GetFeeListenConfig(chainId)
+	if cfg == nil {
+		return nil, nil
+	}
+	name := cfg.L1FeeStrategy
+	if name == "" {
+		if cfg.EthL1GasLimit <= 0 {
+			return nil, cfg
+		}
+		name = L1_FEE_STATIC
+	}
+	l1FeeStrategiesLock.RLock()
+	defer l1FeeStrategiesLock.RUnlock()
+	return l1FeeStrategies[name], cfg
+}
+
+// GetL1Fee returns the L1 data fee of a tx to the chain of chainFee, all zero if the chain has no L1 data fee
+func GetL1Fee(chainFee *models.ChainFee, getChainFee ChainFeeGetter) (l1MinFee, l1ProxyFee, l1FeeAmount *big.Float, err error) {
+	strategy, cfg := GetL1FeeStrategy(chainFee.ChainId)
+	if strategy == nil {
+		zero := new(big.Float).SetInt64(0)
+		return zero, zero, zero, nil
+	}
+	l1MinFee, l1ProxyFee, l1FeeAmount, err = strategy.L1Fee(chainFee, cfg, getChainFee)
+	if err != nil {
+		logs.Error("chain: %d %s l1 fee error: %v", chainFee.ChainId, strategy.Name(), err)
+		return nil, nil, nil, err
+	}
+	logs.Info("chain:%d %s l1MinFee=%s, l1ProxyFee=%s, l1FeeAmount=%s", chainFee.ChainId, strategy.Name(), l1MinFee.String(), l1ProxyFee.String(), l1FeeAmount.String())
+	return
+}
+
+// staticL1Fee scales the ethereum fee by EthL1GasLimit / the ethereum GasLimit
+type staticL1Fee struct{}
+
+func (staticL1Fee) Name() string {
+	return L1_FEE_STATIC
+}
+
+func (staticL1Fee) L1DataFee(caller ContractCaller, cfg *conf.FeeListenConfig) (*big.Int, error) {
+	return nil, nil
+}
+
+func (staticL1Fee) L1Fee(chainFee *models.ChainFee, cfg *conf.FeeListenConfig, getChainFee ChainFeeGetter) (l1MinFee, l1ProxyFee, l1FeeAmount *big.Float, err error) {
+	if cfg.EthL1GasLimit <= 0 {
+		zero := new(big.Float).SetInt64(0)
+		return zero, zero, zero, nil
+	}
+	ethChainFee, ok := getChainFee(basedef.ETHEREUM_CROSSCHAIN_ID)
+	if !ok {
+		return nil, nil, nil, fmt.Errorf("chain: %d does not have fee", basedef.ETHEREUM_CROSSCHAIN_ID)
+	}
+	ethFeeListenConfig := conf.GlobalConfig.GetFeeListenConfig(basedef.ETHEREUM_CROSSCHAIN_ID)
+	if ethFeeListenConfig == nil || ethFeeListenConfig.GasLimit == 0 {
+		return nil, nil, nil, fmt.Errorf("chain listen config is missing")
+	}
+
+	gasLimitScale := new(big.Float).Quo(new(big.Float).SetInt64(cfg.EthL1GasLimit), new(big.Float).SetInt64(ethFeeListenConfig.GasLimit))
+	price := new(big.Float).SetInt64(ethChainFee.TokenBasic.Price)
+	precisionFactor := new(big.Float).Mul(new(big.Float).SetInt64(basedef.PRICE_PRECISION), new(big.Float).SetInt64(basedef.FEE_PRECISION))
+	precisionFactor = new(big.Float).Mul(precisionFactor, new(big.Float).SetInt64(basedef.Int64FromFigure(int(ethChainFee.TokenBasic.Precision))))
+
+	feeFactor := new(big.Float).Mul(gasLimitScale, price)
+	feeFactor = new(big.Float).Quo(feeFactor, precisionFactor)
+
+	l1MinFee = new(big.Float).Mul(new(big.Float).SetInt(&ethChainFee.MinFee.Int), feeFactor)
+	l1ProxyFee = new(big.Float).Mul(new(big.Float).SetInt(&ethChainFee.ProxyFee.Int), feeFactor)
+
+	l1FeeAmount = new(big.Float).Mul(new(big.Float).SetInt(&ethChainFee.MaxFee.Int), gasLimitScale)
+	l1FeeAmount = l1FeeAmount.Quo(l1FeeAmount, new(big.Float).SetInt64(basedef.FEE_PRECISION))
+	l1FeeAmount = l1FeeAmount.Quo(l1FeeAmount, new(big.Float).SetInt64(basedef.Int64FromFigure(int(ethChainFee.TokenBasic.Precision))))
+	return
+}
+
+// zkSyncL1Fee prices the pubdata zkSync publishes on ethereum for a cross chain tx by the static ratio, EthL1GasLimit
+// being the ethereum gas of the pubdata. The gas per pubdata byte is not read from the zkSync node.
+type zkSyncL1Fee struct {
+	staticL1Fee
+}
+
+func (zkSyncL1Fee) Name() string {
+	return L1_FEE_ZKSYNC
+}
+
+// optimismL1Fee reads getL1Fee of the gas price oracle for L1CalldataSize non zero bytes
+type optimismL1Fee struct{}
+
+func (optimismL1Fee) Name() string {
+	return L1_FEE_OPTIMISM
+}
+
+func (optimismL1Fee) L1DataFee(caller ContractCaller, cfg *conf.FeeListenConfig) (*big.Int, error) {
+	bytesType, err := abi.NewType("bytes", "", nil)
+	if err != nil {
+		return nil, err
+	}
+	calldata := make([]byte, l1CalldataSize(cfg))
+	for i := range calldata {
+		calldata[i] = 0xff
+	}
+	args, err := abi.Arguments{{Type: bytesType}}.Pack(calldata)
+	if err != nil {
+		return nil, err
+	}
+	result, err := caller.CallContract(context.Background(), ethereum.CallMsg{
+		To:   &optimismGasPriceOracle,
+		Data: append(crypto.Keccak256([]byte("getL1Fee(bytes)"))[:4], args...),
+	}, nil)
+	if err != nil {
+		return nil, err
+	}
+	if len(result) < 32 {
+		return nil, fmt.Errorf("invalid getL1Fee result: %x", result)
+	}
+	return new(big.Int).SetBytes(result[:32]), nil
+}
+
+func (optimismL1Fee) L1Fee(chainFee *models.ChainFee, cfg *conf.FeeListenConfig, getChainFee ChainFeeGetter) (l1MinFee, l1ProxyFee, l1FeeAmount *big.Float, err error) {
+	return measuredL1Fee(chainFee, cfg, getChainFee)
+}
+
+// arbitrumL1Fee prices L1CalldataSize bytes by getPricesInWei of ArbGasInfo, the per tx cost included
+type arbitrumL1Fee struct{}
+
+func (arbitrumL1Fee) Name() string {
+	return L1_FEE_ARBITRUM
+}
+
+func (arbitrumL1Fee) L1DataFee(caller ContractCaller, cfg *conf.FeeListenConfig) (*big.Int, error) {
+	result, err := caller.CallContract(context.Background(), ethereum.CallMsg{
+		To:   &arbGasInfo,
+		Data: crypto.Keccak256([]byte("getPricesInWei()"))[:4],
+	}, nil)
+	if err != nil {
+		return nil, err
+	}
+	// (perL2Tx, perL1CalldataByte, perStorageAllocation, perArbGasBase, perArbGasCongestion, perArbGasTotal)
+	if len(result) < 64 {
+		return nil, fmt.Errorf("invalid getPricesInWei result: %x", result)
+	}
+	perL2Tx := new(big.Int).SetBytes(result[:32])
+	perL1CalldataByte := new(big.Int).SetBytes(result[32:64])
+	l1DataFee := new(big.Int).Mul(perL1CalldataByte, big.NewInt(l1CalldataSize(cfg)))
+	return l1DataFee.Add(l1DataFee, perL2Tx), nil
+}
+
+func (arbitrumL1Fee) L1Fee(chainFee *models.ChainFee, cfg *conf.FeeListenConfig, getChainFee ChainFeeGetter) (l1MinFee, l1ProxyFee, l1FeeAmount *big.Float, err error) {
+	return measuredL1Fee(chainFee, cfg, getChainFee)
+}
+
+// measuredL1Fee prices the L1DataFee the fee listener saved with the MinFee and ProxyFee percents of the chain,
+// the static ratio is used until the fee listener has measured it
+func measuredL1Fee(chainFee *models.ChainFee, cfg *conf.FeeListenConfig, getChainFee ChainFeeGetter) (l1MinFee, l1ProxyFee, l1FeeAmount *big.Float, err error) {
+	if chainFee.L1DataFee == nil || chainFee.L1DataFee.Sign() <= 0 {
+		logs.Warn("chain: %d does not have l1 data fee, priced by the static ratio", chainFee.ChainId)
+		return staticL1Fee{}.L1Fee(chainFee, cfg, getChainFee)
+	}
+	if chainFee.TokenBasic == nil {
+		return nil, nil, nil, fmt.Errorf("chain: %d fee token is not loaded", chainFee.ChainId)
+	}
+	l1FeeAmount = new(big.Float).SetInt(&chainFee.L1DataFee.Int)
+	l1FeeAmount = l1FeeAmount.Quo(l1FeeAmount, new(big.Float).SetInt64(basedef.Int64FromFigure(int(chainFee.TokenBasic.Precision))))
+	usdtFee := new(big.Float).Mul(l1FeeAmount, new(big.Float).SetInt64(chainFee.TokenBasic.Price))
+	usdtFee = usdtFee.Quo(usdtFee, new(big.Float).SetInt64(basedef.PRICE_PRECISION))
+	l1MinFee = new(big.Float).Mul(usdtFee, new(big.Float).SetInt64(cfg.MinFee))
+	l1MinFee = l1MinFee.Quo(l1MinFee, new(big.Float).SetInt64(100))
+	l1ProxyFee = new(big.Float).Mul(usdtFee, new(big.Float).SetInt64(cfg.ProxyFee))
+	l1ProxyFee = l1ProxyFee.Quo(l1ProxyFee, new(big.Float).SetInt64(100))
+	return
+}
+
+func l1CalldataSize(cfg *conf.FeeListenConfig) int64 {
+	if cfg.L1CalldataSize > 0 {
+		return cfg.L1CalldataSize
+	}
+	return defaultL1CalldataSize
+}
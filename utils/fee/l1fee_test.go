@@ -0,0 +1,155 @@
+package fee
+
+import (
+	"context"
+	"math/big"
+	"poly-bridge/basedef"
+	"poly-bridge/conf"
+	"poly-bridge/models"
+	"testing"
+
+	"github.com/ethereum/go-ethereum"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/stretchr/testify/assert"
+)
+
+type fakeCaller struct {
+	msg    ethereum.CallMsg
+	result []byte
+}
+
+func (c *fakeCaller) CallContract(ctx context.Context, msg ethereum.CallMsg, blockNumber *big.Int) ([]byte, error) {
+	c.msg = msg
+	return c.result, nil
+}
+
+func words(values ...int64) []byte {
+	result := make([]byte, 0, 32*len(values))
+	for _, value := range values {
+		result = append(result, common.LeftPadBytes(big.NewInt(value).Bytes(), 32)...)
+	}
+	return result
+}
+
+func setFeeListenConfigs(t *testing.T, cfgs ...*conf.FeeListenConfig) {
+	globalConfig := conf.GlobalConfig
+	conf.GlobalConfig = &conf.Config{FeeListenConfig: cfgs}
+	t.Cleanup(func() { conf.GlobalConfig = globalConfig })
+}
+
+func TestGetL1FeeStrategy(t *testing.T) {
+	setFeeListenConfigs(t,
+		&conf.FeeListenConfig{ChainId: 1},
+		&conf.FeeListenConfig{ChainId: 2, EthL1GasLimit: 3000},
+		&conf.FeeListenConfig{ChainId: 3, L1FeeStrategy: L1_FEE_ARBITRUM},
+		&conf.FeeListenConfig{ChainId: 4, L1FeeStrategy: "unknown"},
+		&conf.FeeListenConfig{ChainId: 6, L1FeeStrategy: L1_FEE_ZKSYNC},
+	)
+	strategy, _ := GetL1FeeStrategy(1)
+	assert.Nil(t, strategy)
+	strategy, _ = GetL1FeeStrategy(2)
+	assert.Equal(t, L1_FEE_STATIC, strategy.Name())
+	strategy, cfg := GetL1FeeStrategy(3)
+	assert.Equal(t, L1_FEE_ARBITRUM, strategy.Name())
+	assert.Equal(t, uint64(3), cfg.ChainId)
+	strategy, _ = GetL1FeeStrategy(4)
+	assert.Nil(t, strategy)
+	strategy, _ = GetL1FeeStrategy(5)
+	assert.Nil(t, strategy)
+	strategy, _ = GetL1FeeStrategy(6)
+	assert.Equal(t, L1_FEE_ZKSYNC, strategy.Name())
+
+	l1MinFee, l1ProxyFee, l1FeeAmount, err := GetL1Fee(&models.ChainFee{ChainId: 1}, ChainFeesGetter(nil))
+	assert.NoError(t, err)
+	assert.Equal(t, 0, l1MinFee.Sign())
+	assert.Equal(t, 0, l1ProxyFee.Sign())
+	assert.Equal(t, 0, l1FeeAmount.Sign())
+}
+
+func TestOptimismL1DataFee(t *testing.T) {
+	caller := &fakeCaller{result: words(123456)}
+	l1DataFee, err := optimismL1Fee{}.L1DataFee(caller, &conf.FeeListenConfig{L1CalldataSize: 100})
+	assert.NoError(t, err)
+	assert.Equal(t, int64(123456), l1DataFee.Int64())
+	assert.Equal(t, optimismGasPriceOracle, *caller.msg.To)
+	assert.Equal(t, crypto.Keccak256([]byte("getL1Fee(bytes)"))[:4], caller.msg.Data[:4])
+	// offset, length and the calldata padded to words
+	assert.Len(t, caller.msg.Data, 4+32+32+128)
+
+	_, err = optimismL1Fee{}.L1DataFee(&fakeCaller{}, &conf.FeeListenConfig{})
+	assert.Error(t, err)
+}
+
+func TestArbitrumL1DataFee(t *testing.T) {
+	caller := &fakeCaller{result: words(1000, 16, 0, 0, 0, 0)}
+	l1DataFee, err := arbitrumL1Fee{}.L1DataFee(caller, &conf.FeeListenConfig{})
+	assert.NoError(t, err)
+	assert.Equal(t, int64(1000+16*defaultL1CalldataSize), l1DataFee.Int64())
+	assert.Equal(t, arbGasInfo, *caller.msg.To)
+	assert.Equal(t, crypto.Keccak256([]byte("getPricesInWei()"))[:4], caller.msg.Data)
+}
+
+func TestMeasuredL1Fee(t *testing.T) {
+	chainFee := &models.ChainFee{
+		ChainId:    3,
+		TokenBasic: &models.TokenBasic{Precision: 18, Price: 2000 * basedef.PRICE_PRECISION},
+		L1DataFee:  models.NewBigIntFromInt(1000000000000000),
+	}
+	cfg := &conf.FeeListenConfig{MinFee: 100, ProxyFee: 150}
+	l1MinFee, l1ProxyFee, l1FeeAmount, err := arbitrumL1Fee{}.L1Fee(chainFee, cfg, ChainFeesGetter(nil))
+	assert.NoError(t, err)
+	assertFloat(t, 2, l1MinFee)
+	assertFloat(t, 3, l1ProxyFee)
+	assertFloat(t, 0.001, l1FeeAmount)
+
+	// not measured yet, no static ratio
+	chainFee.L1DataFee = models.NewBigIntFromInt(0)
+	l1MinFee, l1ProxyFee, l1FeeAmount, err = optimismL1Fee{}.L1Fee(chainFee, cfg, ChainFeesGetter(nil))
+	assert.NoError(t, err)
+	assert.Equal(t, 0, l1MinFee.Sign())
+	assert.Equal(t, 0, l1ProxyFee.Sign())
+	assert.Equal(t, 0, l1FeeAmount.Sign())
+
+	// not measured yet, priced by the static ratio
+	setFeeListenConfigs(t, &conf.FeeListenConfig{ChainId: basedef.ETHEREUM_CROSSCHAIN_ID, GasLimit: 300000})
+	cfg.EthL1GasLimit = 3000
+	l1MinFee, l1ProxyFee, l1FeeAmount, err = optimismL1Fee{}.L1Fee(chainFee, cfg, ChainFeesGetter(map[uint64]*models.ChainFee{basedef.ETHEREUM_CROSSCHAIN_ID: ethChainFee()}))
+	assert.NoError(t, err)
+	assertFloat(t, 0.6, l1MinFee)
+	assertFloat(t, 0.6, l1ProxyFee)
+	assertFloat(t, 0.0003, l1FeeAmount)
+}
+
+func TestStaticL1Fee(t *testing.T) {
+	setFeeListenConfigs(t,
+		&conf.FeeListenConfig{ChainId: basedef.ETHEREUM_CROSSCHAIN_ID, GasLimit: 300000},
+		&conf.FeeListenConfig{ChainId: 1000, EthL1GasLimit: 3000},
+	)
+	getChainFee := ChainFeesGetter(map[uint64]*models.ChainFee{basedef.ETHEREUM_CROSSCHAIN_ID: ethChainFee()})
+	l1MinFee, l1ProxyFee, l1FeeAmount, err := GetL1Fee(&models.ChainFee{ChainId: 1000}, getChainFee)
+	assert.NoError(t, err)
+	assertFloat(t, 0.6, l1MinFee)
+	assertFloat(t, 0.6, l1ProxyFee)
+	assertFloat(t, 0.0003, l1FeeAmount)
+
+	_, _, _, err = GetL1Fee(&models.ChainFee{ChainId: 1000}, ChainFeesGetter(nil))
+	assert.Error(t, err)
+}
+
+// ethChainFee is 100 gwei * 300000 gas at 2000 usd
+func ethChainFee() *models.ChainFee {
+	ethFee := new(big.Int).Mul(big.NewInt(30000000000000000), big.NewInt(basedef.FEE_PRECISION))
+	return &models.ChainFee{
+		ChainId:    basedef.ETHEREUM_CROSSCHAIN_ID,
+		TokenBasic: &models.TokenBasic{Precision: 18, Price: 2000 * basedef.PRICE_PRECISION},
+		MaxFee:     models.NewBigInt(ethFee),
+		MinFee:     models.NewBigInt(ethFee),
+		ProxyFee:   models.NewBigInt(ethFee),
+	}
+}
+
+func assertFloat(t *testing.T, expected float64, actual *big.Float) {
+	value, _ := actual.Float64()
+	assert.InDelta(t, expected, value, 1e-9)
+}
@@ -0,0 +1,79 @@
+package fee
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/binary"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"strings"
+)
+
+// Quote is the fee GetFee promised to a sender for a transfer, the wrapper lock of the sender passing the tag of the
+// quote id as its id and paying TokenAmountWithPrecision before Expiry is accepted even if the prices have changed since
+type Quote struct {
+	User                     string //the sender, lower case hex without 0x as the wrapper transactions save it
+	SrcChainId               uint64
+	Hash                     string //the fee token on the source chain
+	DstChainId               uint64
+	UsdtAmount               string
+	TokenAmountWithPrecision string
+	Expiry                   int64 //unix seconds
+}
+
+// SignQuote returns the id of the quote, "<base64 url of the quote json>.<hex HMAC-SHA256 of it>"
+func SignQuote(key string, quote *Quote) (string, error) {
+	data, err := json.Marshal(quote)
+	if err != nil {
+		return "", err
+	}
+	payload := base64.RawURLEncoding.EncodeToString(data)
+	return payload + "." + quoteSignature(key, payload), nil
+}
+
+// VerifyQuote checks the signature of the quote id and returns the quote, the expiry is left to the caller
+// since it is compared with the time of the paying transaction rather than now
+func VerifyQuote(key string, id string) (*Quote, error) {
+	parts := strings.Split(id, ".")
+	if len(parts) != 2 {
+		return nil, fmt.Errorf("malformed quote id")
+	}
+	if !hmac.Equal([]byte(parts[1]), []byte(quoteSignature(key, parts[0]))) {
+		return nil, fmt.Errorf("invalid quote signature")
+	}
+	data, err := base64.RawURLEncoding.DecodeString(parts[0])
+	if err != nil {
+		return nil, fmt.Errorf("malformed quote payload: %v", err)
+	}
+	quote := new(Quote)
+	if err = json.Unmarshal(data, quote); err != nil {
+		return nil, fmt.Errorf("malformed quote payload: %v", err)
+	}
+	return quote, nil
+}
+
+// QuoteTag is the number the wrapper lock passes as its id for the quote id, the first 63 bits of its signature
+func QuoteTag(id string) (uint64, error) {
+	parts := strings.Split(id, ".")
+	if len(parts) != 2 {
+		return 0, fmt.Errorf("malformed quote id")
+	}
+	signature, err := hex.DecodeString(parts[1])
+	if err != nil || len(signature) < 8 {
+		return 0, fmt.Errorf("malformed quote signature")
+	}
+	// the server ids are saved in signed bigint columns
+	tag := binary.BigEndian.Uint64(signature[:8]) >> 1
+	if tag == 0 {
+		tag = 1
+	}
+	return tag, nil
+}
+
+func quoteSignature(key string, payload string) string {
+	mac := hmac.New(sha256.New, []byte(key))
+	mac.Write([]byte(payload))
+	return hex.EncodeToString(mac.Sum(nil))
+}
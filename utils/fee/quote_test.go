@@ -0,0 +1,46 @@
+package fee
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSignAndVerifyQuote(t *testing.T) {
+	quote := &Quote{
+		User:                     "abcdef0000000000000000000000000000000001",
+		SrcChainId:               2,
+		Hash:                     "0000000000000000000000000000000000000000",
+		DstChainId:               6,
+		UsdtAmount:               "1.5",
+		TokenAmountWithPrecision: "1500000000000000",
+		Expiry:                   1700000000,
+	}
+	id, err := SignQuote("key", quote)
+	assert.NoError(t, err)
+
+	verified, err := VerifyQuote("key", id)
+	assert.NoError(t, err)
+	assert.Equal(t, quote, verified)
+
+	_, err = VerifyQuote("other key", id)
+	assert.Error(t, err)
+
+	tampered, err := SignQuote("other key", &Quote{SrcChainId: 2, DstChainId: 6, TokenAmountWithPrecision: "1"})
+	assert.NoError(t, err)
+	_, err = VerifyQuote("key", tampered[:len(tampered)-64]+id[len(id)-64:])
+	assert.Error(t, err)
+
+	_, err = VerifyQuote("key", "malformed")
+	assert.Error(t, err)
+
+	tag, err := QuoteTag(id)
+	assert.NoError(t, err)
+	assert.NotZero(t, tag)
+	assert.Less(t, tag, uint64(1)<<63)
+	otherTag, err := QuoteTag(tampered)
+	assert.NoError(t, err)
+	assert.NotEqual(t, tag, otherTag)
+	_, err = QuoteTag("malformed")
+	assert.Error(t, err)
+}
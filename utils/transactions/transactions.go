@@ -7,10 +7,11 @@ import (
 	"poly-bridge/cacheRedis"
 	"poly-bridge/conf"
 	"poly-bridge/models"
+	"poly-bridge/utils/database"
 	"time"
 )
 
-func GetStuckTxs(db *gorm.DB, redis *cacheRedis.RedisCache, pageSize, pageNo, from int) ([]*models.TxHashChainIdPair, int, error) {
+func GetStuckTxs(db *gorm.DB, cache cacheRedis.Cache, pageSize, pageNo, from int) ([]*models.TxHashChainIdPair, int, error) {
 	tt := time.Now().Unix()
 	end := tt - conf.GlobalConfig.EventEffectConfig.HowOld
 	if from == 0 {
@@ -25,17 +26,14 @@ func GetStuckTxs(db *gorm.DB, redis *cacheRedis.RedisCache, pageSize, pageNo, fr
 	for k := range conf.PolyProxy {
 		polyProxies = append(polyProxies, k)
 	}
-	query := db.Debug().Table("src_transactions").
-		Select("src_transactions.hash as src_hash, src_transactions.chain_id as src_chain_id, src_transactions.dst_chain_id as dst_chain_id, poly_transactions.hash as poly_hash, dst_transactions.hash as dst_hash, wrapper_transactions.id as wrapper_id").
-		Where("UPPER(src_transactions.contract) in ?", polyProxies).
-		Where("src_transactions.time > ?", tt-24*60*60*int64(from)).
-		Where("(src_transactions.time < ?) OR (src_transactions.time < ? and ((src_transactions.chain_id = ? and src_transactions.dst_chain_id = ?) or (src_transactions.chain_id = ? and src_transactions.dst_chain_id = ?)))", end, endBsc, basedef.BSC_CROSSCHAIN_ID, basedef.HECO_CROSSCHAIN_ID, basedef.HECO_CROSSCHAIN_ID, basedef.BSC_CROSSCHAIN_ID).
-		Where("((select count(*) from poly_transactions where src_transactions.hash = poly_transactions.src_hash) = 0 OR (select count(*) from dst_transactions where poly_transactions.hash=dst_transactions.poly_hash) = 0)").
-		Joins("left join poly_transactions on src_transactions.hash = poly_transactions.src_hash").
-		Joins("left join dst_transactions on poly_transactions.hash = dst_transactions.poly_hash").
-		Joins("left join wrapper_transactions on src_transactions.hash = wrapper_transactions.hash")
+	query := db.Debug().Model(&models.CrossChainTx{}).
+		Select("src_hash, src_chain_id, dst_chain_id, poly_hash, dst_hash, wrapper_id").
+		Where("UPPER(contract) in ?", polyProxies).
+		Where("time > ?", tt-24*60*60*int64(from)).
+		Where("(time < ?) OR (time < ? and ((src_chain_id = ? and dst_chain_id = ?) or (src_chain_id = ? and dst_chain_id = ?)))", end, endBsc, basedef.BSC_CROSSCHAIN_ID, basedef.HECO_CROSSCHAIN_ID, basedef.HECO_CROSSCHAIN_ID, basedef.BSC_CROSSCHAIN_ID).
+		Where("poly_hash = '' or dst_hash = ''")
 
-	err := query.Limit(pageSize).Offset(pageSize * pageNo).Order("src_transactions.time desc").Find(&txs).Error
+	err := query.Limit(pageSize).Offset(pageSize * pageNo).Order("time desc").Find(&txs).Error
 	if err != nil {
 		return nil, 0, err
 	}
@@ -56,7 +54,7 @@ func GetStuckTxs(db *gorm.DB, redis *cacheRedis.RedisCache, pageSize, pageNo, fr
 			continue
 		}
 
-		exists, _ := redis.Exists(cacheRedis.MarkTxAsSkipPrefix + hash)
+		exists, _ := cache.Exists(cacheRedis.MarkTxAsSkipPrefix + hash)
 		if exists {
 			count--
 			txs = append(txs[:i], txs[i+1:]...)
@@ -71,35 +69,143 @@ func GetStuckTxs(db *gorm.DB, redis *cacheRedis.RedisCache, pageSize, pageNo, fr
 func GetSrcPolyDstRelation(db *gorm.DB, tx *models.TxHashChainIdPair) (*models.SrcPolyDstRelation, error) {
 	hash := tx.SrcHash
 	if tx.SrcChainId == basedef.O3_CROSSCHAIN_ID {
-		originTx := new(models.TxHashChainIdPair)
-		err := db.Debug().Table("src_transactions").
-			Select("src_transactions.hash as src_hash, src_transactions.chain_id as src_chain_id, poly_transactions.hash as poly_hash").
-			Where("dst_transactions.hash = ?", tx.SrcHash).
-			Joins("LEFT JOIN poly_transactions on src_transactions.hash=poly_transactions.src_hash").
-			Joins("LEFT JOIN dst_transactions on dst_transactions.poly_hash=poly_transactions.hash").
-			Order("src_transactions.time desc").Find(&originTx).Error
-		if err == nil {
+		originTx, _, err := FindCrossChainTxByDstHash(db, tx.SrcHash, "")
+		if err == nil && originTx != nil {
 			hash = originTx.SrcHash
 		}
 	}
-	srcPolyDstRelation := new(models.SrcPolyDstRelation)
-	err := db.Debug().Table("src_transactions").
-		Select("src_transactions.hash as src_hash, poly_transactions.hash as poly_hash, dst_transactions.hash as dst_hash, src_transactions.chain_id as chain_id, src_transfers.asset as token_hash, wrapper_transactions.fee_token_hash as fee_token_hash").
-		Where("src_transactions.hash = ?", hash).
-		Joins("left join src_transfers on src_transactions.hash = src_transfers.tx_hash").
-		Joins("left join poly_transactions on src_transactions.hash = poly_transactions.src_hash").
-		Joins("left join dst_transactions on poly_transactions.hash = dst_transactions.poly_hash").
-		Joins("left join wrapper_transactions on src_transactions.hash = wrapper_transactions.hash").
-		Preload("WrapperTransaction").
-		Preload("SrcTransaction").
-		Preload("SrcTransaction.SrcTransfer").
-		Preload("PolyTransaction").
-		Preload("DstTransaction").
-		Preload("DstTransaction.DstTransfer").
-		Preload("Token").
-		Preload("Token.TokenBasic").
-		Preload("FeeToken").
-		Order("src_transactions.time desc").
-		Find(&srcPolyDstRelation).Error
-	return srcPolyDstRelation, err
+	crossChainTx, legs, err := FindCrossChainTx(db, "src_hash = ?", hash)
+	if err != nil {
+		return new(models.SrcPolyDstRelation), err
+	}
+	if crossChainTx == nil {
+		return new(models.SrcPolyDstRelation), nil
+	}
+	return GetCrossChainTxRelationIn(db, legs, crossChainTx)
+}
+
+// FindCrossChainTx finds the cross chain tx by the conditions, in the archive if it is not found in the main tables.
+// It returns the db holding the legs of the tx, the tx is nil if it is not found.
+func FindCrossChainTx(db *gorm.DB, query interface{}, args ...interface{}) (*models.CrossChainTx, *gorm.DB, error) {
+	crossChainTx := new(models.CrossChainTx)
+	res := db.Where(query, args...).Order("time desc").Limit(1).Find(crossChainTx)
+	if res.Error != nil {
+		return nil, nil, res.Error
+	}
+	if res.RowsAffected > 0 {
+		return crossChainTx, db, nil
+	}
+	archived, err := database.Archive(db)
+	if err != nil {
+		return nil, nil, err
+	}
+	res = archived.Where(query, args...).Order("time desc").Limit(1).Find(crossChainTx)
+	if res.Error != nil {
+		return nil, nil, res.Error
+	}
+	if res.RowsAffected > 0 {
+		return crossChainTx, archived, nil
+	}
+	return nil, nil, nil
+}
+
+// FindCrossChainTxByDstHash finds the cross chain tx of a dst hash and the optional conditions, a tx whose dst leg is not
+// linked in cross_chain_txs yet is found by the poly hash of the leg, the dst transactions are looked up in the archive too
+func FindCrossChainTxByDstHash(db *gorm.DB, hash string, query string, args ...interface{}) (*models.CrossChainTx, *gorm.DB, error) {
+	where := func(column, value string) (string, []interface{}) {
+		if query == "" {
+			return column + " = ?", []interface{}{value}
+		}
+		return column + " = ? and " + query, append([]interface{}{value}, args...)
+	}
+	dstQuery, dstArgs := where("dst_hash", hash)
+	crossChainTx, legs, err := FindCrossChainTx(db, dstQuery, dstArgs...)
+	if err != nil || crossChainTx != nil {
+		return crossChainTx, legs, err
+	}
+	dstTransactions := make([]*models.DstTransaction, 0)
+	if err := db.Where("hash = ?", hash).Limit(1).Find(&dstTransactions).Error; err != nil {
+		return nil, nil, err
+	}
+	if len(dstTransactions) == 0 {
+		archived, err := database.Archive(db)
+		if err != nil {
+			return nil, nil, err
+		}
+		if err := archived.Where("hash = ?", hash).Limit(1).Find(&dstTransactions).Error; err != nil {
+			return nil, nil, err
+		}
+	}
+	if len(dstTransactions) == 0 || dstTransactions[0].PolyHash == "" {
+		return nil, nil, nil
+	}
+	polyQuery, polyArgs := where("poly_hash", dstTransactions[0].PolyHash)
+	return FindCrossChainTx(db, polyQuery, polyArgs...)
+}
+
+// GetCrossChainTxRelation loads the legs of the cross chain tx by their hashes
+func GetCrossChainTxRelation(db *gorm.DB, crossChainTx *models.CrossChainTx) (*models.SrcPolyDstRelation, error) {
+	return GetCrossChainTxRelationIn(db, db, crossChainTx)
+}
+
+// GetCrossChainTxRelationIn loads the legs of the cross chain tx from legs, the archive returned by FindCrossChainTx,
+// the tokens are loaded from db
+func GetCrossChainTxRelationIn(db *gorm.DB, legs *gorm.DB, crossChainTx *models.CrossChainTx) (*models.SrcPolyDstRelation, error) {
+	relation := &models.SrcPolyDstRelation{
+		SrcHash:      crossChainTx.SrcHash,
+		PolyHash:     crossChainTx.PolyHash,
+		DstHash:      crossChainTx.DstHash,
+		ChainId:      crossChainTx.SrcChainId,
+		TokenHash:    crossChainTx.TokenHash,
+		FeeTokenHash: crossChainTx.FeeTokenHash,
+	}
+	wrapperTransactions := make([]*models.WrapperTransaction, 0)
+	srcTransactions := make([]*models.SrcTransaction, 0)
+	polyTransactions := make([]*models.PolyTransaction, 0)
+	dstTransactions := make([]*models.DstTransaction, 0)
+	if crossChainTx.WrapperId > 0 {
+		if err := legs.Where("hash = ?", crossChainTx.SrcHash).Limit(1).Find(&wrapperTransactions).Error; err != nil {
+			return nil, err
+		}
+	}
+	if err := legs.Where("hash = ?", crossChainTx.SrcHash).Preload("SrcTransfer").Limit(1).Find(&srcTransactions).Error; err != nil {
+		return nil, err
+	}
+	if crossChainTx.PolyHash != "" {
+		if err := legs.Where("hash = ?", crossChainTx.PolyHash).Limit(1).Find(&polyTransactions).Error; err != nil {
+			return nil, err
+		}
+	}
+	if crossChainTx.DstHash != "" {
+		if err := legs.Where("hash = ?", crossChainTx.DstHash).Preload("DstTransfer").Limit(1).Find(&dstTransactions).Error; err != nil {
+			return nil, err
+		}
+	}
+	if len(wrapperTransactions) > 0 {
+		relation.WrapperTransaction = wrapperTransactions[0]
+	}
+	if len(srcTransactions) > 0 {
+		relation.SrcTransaction = srcTransactions[0]
+	}
+	if len(polyTransactions) > 0 {
+		relation.PolyTransaction = polyTransactions[0]
+	}
+	if len(dstTransactions) > 0 {
+		relation.DstTransaction = dstTransactions[0]
+	}
+	tokens := make([]*models.Token, 0)
+	err := db.Where("chain_id = ? and hash in ?", crossChainTx.SrcChainId, []string{crossChainTx.TokenHash, crossChainTx.FeeTokenHash}).
+		Preload("TokenBasic").Find(&tokens).Error
+	if err != nil {
+		return nil, err
+	}
+	for _, token := range tokens {
+		if token.Hash == crossChainTx.TokenHash {
+			relation.Token = token
+		}
+		if token.Hash == crossChainTx.FeeTokenHash {
+			relation.FeeToken = token
+		}
+	}
+	return relation, nil
 }
@@ -0,0 +1,278 @@
+/*
+ * Copyright (C) 2020 The poly network Authors
+ * This file is part of The poly network library.
+ *
+ * The  poly network  is free software: you can redistribute it and/or modify
+ * it under the terms of the GNU Lesser General Public License as published by
+ * the Free Software Foundation, either version 3 of the License, or
+ * (at your option) any later version.
+ *
+ * The  poly network  is distributed in the hope that it will be useful,
+ * but WITHOUT ANY WARRANTY; without even the implied warranty of
+ * MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+ * GNU Lesser General Public License for more details.
+ * You should have received a copy of the GNU Lesser General Public License
+ * along with The poly network .  If not, see <http://www.gnu.org/licenses/>.
+ */
+
+package webhook
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"poly-bridge/basedef"
+	"poly-bridge/conf"
+	"poly-bridge/models"
+	"poly-bridge/utils/backoff"
+	"poly-bridge/utils/database"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/beego/beego/v2/core/logs"
+	"gorm.io/gorm"
+)
+
+const (
+	defaultInterval    = 5
+	defaultBatchSize   = 100
+	defaultWorkers     = 8
+	defaultMaxAttempts = 10
+	defaultTimeout     = 5
+	retryDelayBase     = 5
+	maxRetryDelay      = 3600
+	maxErrorBody       = 4096
+)
+
+// Webhook posts the queued tx status deliveries to the subscribers. Every request carries
+// X-Bridge-Delivery, X-Bridge-Timestamp and X-Bridge-Signature, the hex HMAC-SHA256 of
+// "<timestamp>.<body>" keyed with the subscription secret.
+type Webhook struct {
+	context.Context
+	cancel context.CancelFunc
+	cfg    *conf.WebhookConfig
+	db     *gorm.DB
+	client *http.Client
+	wg     sync.WaitGroup
+}
+
+var webhook *Webhook
+
+func StartWebhook(ctx context.Context, config *conf.Config) {
+	if config.Server != basedef.SERVER_POLY_BRIDGE {
+		panic("Webhook Only runs on bridge server")
+	}
+	if config.WebhookConfig == nil {
+		panic("Invalid Webhook config")
+	}
+	dbCfg := config.DBConfig
+	db, err := database.Open(dbCfg)
+	if err != nil {
+		panic(err)
+	}
+	webhook = NewWebhook(ctx, config.WebhookConfig, db)
+	webhook.Start()
+}
+
+func StopWebhook() {
+	if webhook != nil {
+		webhook.Stop()
+	}
+}
+
+func NewWebhook(ctx context.Context, cfg *conf.WebhookConfig, db *gorm.DB) *Webhook {
+	timeout := cfg.Timeout
+	if timeout <= 0 {
+		timeout = defaultTimeout
+	}
+	ctx, cancel := context.WithCancel(ctx)
+	return &Webhook{
+		Context: ctx,
+		cancel:  cancel,
+		cfg:     cfg,
+		db:      db,
+		client: &http.Client{
+			Timeout:   time.Second * time.Duration(timeout),
+			Transport: &http.Transport{DialContext: newDialer(time.Second*time.Duration(timeout), cfg.AllowPrivateUrl).DialContext},
+		},
+	}
+}
+
+func (this *Webhook) Start() {
+	logs.Info("start webhook")
+	this.wg.Add(1)
+	go this.run()
+}
+
+func (this *Webhook) Stop() {
+	logs.Info("Stopping webhook")
+	this.cancel()
+	this.wg.Wait()
+}
+
+func (this *Webhook) run() {
+	defer this.wg.Done()
+	interval := this.cfg.Interval
+	if interval <= 0 {
+		interval = defaultInterval
+	}
+	ticker := time.NewTicker(time.Second * time.Duration(interval))
+	defer ticker.Stop()
+	for {
+		for this.Err() == nil && this.deliver() {
+		}
+		select {
+		case <-ticker.C:
+		case <-this.Done():
+			return
+		}
+	}
+}
+
+// deliver sends one batch of the pending deliveries, it returns true if the batch was full
+func (this *Webhook) deliver() bool {
+	batchSize := this.cfg.BatchSize
+	if batchSize <= 0 {
+		batchSize = defaultBatchSize
+	}
+	deliveries := make([]*models.WebhookDelivery, 0)
+	err := this.db.Where("status = ? and next_time <= ?", basedef.DELIVERY_PENDING, time.Now().Unix()).
+		Order("id asc").Limit(batchSize).Find(&deliveries).Error
+	if err != nil {
+		logs.Error("get webhook deliveries err: %v", err)
+		return false
+	}
+	if len(deliveries) == 0 {
+		return false
+	}
+	ids := make([]int64, 0, len(deliveries))
+	for _, delivery := range deliveries {
+		ids = append(ids, delivery.SubscriptionId)
+	}
+	subscriptions := make([]*models.WebhookSubscription, 0)
+	if err := this.db.Where("id in ?", ids).Find(&subscriptions).Error; err != nil {
+		logs.Error("get webhook subscriptions err: %v", err)
+		return false
+	}
+	id2Subscriptions := make(map[int64]*models.WebhookSubscription)
+	for _, subscription := range subscriptions {
+		id2Subscriptions[subscription.Id] = subscription
+	}
+	workers := this.cfg.Workers
+	if workers <= 0 {
+		workers = defaultWorkers
+	}
+	tasks := make(chan *models.WebhookDelivery)
+	wg := sync.WaitGroup{}
+	for i := 0; i < workers; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for delivery := range tasks {
+				this.handleDelivery(id2Subscriptions[delivery.SubscriptionId], delivery)
+			}
+		}()
+	}
+	for _, delivery := range deliveries {
+		if this.Err() != nil {
+			break
+		}
+		tasks <- delivery
+	}
+	close(tasks)
+	wg.Wait()
+	if this.Err() != nil {
+		return false
+	}
+	return len(deliveries) == batchSize
+}
+
+func (this *Webhook) handleDelivery(subscription *models.WebhookSubscription, delivery *models.WebhookDelivery) {
+	if subscription == nil {
+		delivery.Status = basedef.DELIVERY_FAILED
+		delivery.Error = "subscription is removed"
+	} else {
+		this.post(subscription, delivery)
+	}
+	delivery.UpdateTime = time.Now().Unix()
+	err := this.db.Model(&models.WebhookDelivery{}).Where("id = ?", delivery.Id).Updates(map[string]interface{}{
+		"status":        delivery.Status,
+		"attempts":      delivery.Attempts,
+		"response_code": delivery.ResponseCode,
+		"error":         delivery.Error,
+		"next_time":     delivery.NextTime,
+		"update_time":   delivery.UpdateTime,
+	}).Error
+	if err != nil {
+		logs.Error("update webhook delivery %d err: %v", delivery.Id, err)
+	}
+}
+
+func (this *Webhook) post(subscription *models.WebhookSubscription, delivery *models.WebhookDelivery) {
+	delivery.Attempts++
+	code, err := this.send(subscription, delivery)
+	delivery.ResponseCode = code
+	if err == nil {
+		delivery.Status = basedef.DELIVERY_DELIVERED
+		delivery.Error = ""
+		return
+	}
+	delivery.Error = err.Error()
+	if len(delivery.Error) > 512 {
+		delivery.Error = delivery.Error[:512]
+	}
+	maxAttempts := this.cfg.MaxAttempts
+	if maxAttempts <= 0 {
+		maxAttempts = defaultMaxAttempts
+	}
+	if delivery.Attempts >= maxAttempts {
+		delivery.Status = basedef.DELIVERY_FAILED
+		logs.Error("webhook delivery %d to %s failed after %d attempts: %v", delivery.Id, subscription.Url, delivery.Attempts, err)
+		return
+	}
+	delivery.NextTime = time.Now().Unix() + retryDelay(delivery.Attempts)
+}
+
+func (this *Webhook) send(subscription *models.WebhookSubscription, delivery *models.WebhookDelivery) (int, error) {
+	body := []byte(delivery.Payload)
+	timestamp := strconv.FormatInt(time.Now().Unix(), 10)
+	req, err := http.NewRequest("POST", subscription.Url, bytes.NewReader(body))
+	if err != nil {
+		return 0, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("X-Bridge-Delivery", strconv.FormatInt(delivery.Id, 10))
+	req.Header.Set("X-Bridge-Timestamp", timestamp)
+	req.Header.Set("X-Bridge-Signature", Sign(subscription.Secret, timestamp, body))
+	resp, err := this.client.Do(req)
+	if err != nil {
+		return 0, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		data, _ := ioutil.ReadAll(io.LimitReader(resp.Body, maxErrorBody))
+		return resp.StatusCode, fmt.Errorf("response status: %d, body: %s", resp.StatusCode, string(data))
+	}
+	return resp.StatusCode, nil
+}
+
+// Sign returns the hex HMAC-SHA256 of "<timestamp>.<body>", subscribers compute the same to verify a callback
+func Sign(secret string, timestamp string, body []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(timestamp))
+	mac.Write([]byte("."))
+	mac.Write(body)
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+// retryDelay doubles from retryDelayBase seconds up to maxRetryDelay seconds
+func retryDelay(attempts int) int64 {
+	return int64(backoff.Delay(attempts, retryDelayBase*time.Second, maxRetryDelay*time.Second) / time.Second)
+}
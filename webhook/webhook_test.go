@@ -0,0 +1,42 @@
+package webhook
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSign(t *testing.T) {
+	body := []byte(`{"hash":"0x01","state":0}`)
+	mac := hmac.New(sha256.New, []byte("secret"))
+	mac.Write([]byte("1700000000." + string(body)))
+	assert.Equal(t, hex.EncodeToString(mac.Sum(nil)), Sign("secret", "1700000000", body))
+	assert.NotEqual(t, Sign("secret", "1700000000", body), Sign("other", "1700000000", body))
+}
+
+func TestRetryDelay(t *testing.T) {
+	assert.Equal(t, int64(5), retryDelay(1))
+	assert.Equal(t, int64(40), retryDelay(4))
+	assert.Equal(t, int64(maxRetryDelay), retryDelay(30))
+}
+
+func TestValidateUrl(t *testing.T) {
+	assert.Error(t, ValidateUrl("ftp://8.8.8.8/hook", false))
+	assert.Error(t, ValidateUrl("http:///hook", false))
+	assert.Error(t, ValidateUrl("http://127.0.0.1:8080/hook", false))
+	assert.Error(t, ValidateUrl("http://10.1.2.3/hook", false))
+	assert.Error(t, ValidateUrl("http://169.254.169.254/latest/meta-data", false))
+	assert.Error(t, ValidateUrl("http://[::1]/hook", false))
+	assert.NoError(t, ValidateUrl("https://8.8.8.8/hook", false))
+	assert.NoError(t, ValidateUrl("http://127.0.0.1:8080/hook", true))
+}
+
+func TestDialerRefusesPrivateAddress(t *testing.T) {
+	_, err := newDialer(time.Second, false).Dial("tcp", "127.0.0.1:1")
+	assert.Error(t, err)
+	assert.Contains(t, err.Error(), "refused")
+}